@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// A entrada de início automático abre o cliente com -minimized, direto na bandeja. Ela é
+// a única fonte do estado: a caixa das configurações lê a entrada em vez da configuração.
+
+// autostartName identifica a entrada do GoVPN entre as de outros programas
+const autostartName = "GoVPN"
+
+// autostartCommand retorna o executável e os argumentos gravados na entrada
+func autostartCommand() ([]string, error) {
+	executable, err := appExecutable()
+	if err != nil {
+		return nil, err
+	}
+	return []string{executable, "-minimized"}, nil
+}
+
+// appExecutable retorna o caminho que o sistema deve abrir para iniciar o cliente. Numa
+// AppImage o executável fica numa montagem temporária, então vale o caminho da própria imagem.
+func appExecutable() (string, error) {
+	executable := os.Getenv("APPIMAGE")
+	if executable == "" {
+		var err error
+		if executable, err = os.Executable(); err != nil {
+			return "", err
+		}
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	return executable, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// autostartLabel identifica o LaunchAgent, como com.govpn.helper identifica o helper
+const autostartLabel = "com.govpn.client"
+
+// autostartPath é o LaunchAgent do usuário, carregado pelo launchd a cada login
+func autostartPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, "Library", "LaunchAgents", autostartLabel+".plist"), nil
+}
+
+// autostartEnabled diz se o LaunchAgent existe
+func autostartEnabled() bool {
+	path, err := autostartPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// setAutostart grava ou apaga o LaunchAgent
+func setAutostart(enabled bool) error {
+	path, err := autostartPath()
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove the autostart entry: %v", err)
+		}
+		return nil
+	}
+
+	command, err := autostartCommand()
+	if err != nil {
+		return err
+	}
+	var arguments bytes.Buffer
+	for _, arg := range command {
+		arguments.WriteString("\t\t<string>")
+		xml.EscapeText(&arguments, []byte(arg))
+		arguments.WriteString("</string>\n")
+	}
+
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>ProcessType</key>
+	<string>Interactive</string>
+</dict>
+</plist>
+`, autostartLabel, arguments.String())
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to write the autostart entry: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(plist), 0644); err != nil {
+		return fmt.Errorf("failed to write the autostart entry: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// autostartPath é a entrada de início automático do XDG, lida pelos ambientes de desktop
+// a cada login
+func autostartPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "autostart", "govpn.desktop"), nil
+}
+
+// autostartEnabled diz se a entrada existe
+func autostartEnabled() bool {
+	path, err := autostartPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// setAutostart grava ou apaga a entrada
+func setAutostart(enabled bool) error {
+	path, err := autostartPath()
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove the autostart entry: %v", err)
+		}
+		return nil
+	}
+
+	command, err := autostartCommand()
+	if err != nil {
+		return err
+	}
+	entry := fmt.Sprintf(`[Desktop Entry]
+Type=Application
+Name=%s
+Comment=Starts GoVPN in the system tray
+Exec=%s
+Terminal=false
+X-GNOME-Autostart-enabled=true
+`, autostartName, desktopExec(command))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to write the autostart entry: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(entry), 0644); err != nil {
+		return fmt.Errorf("failed to write the autostart entry: %v", err)
+	}
+	return nil
+}
+
+// desktopExec monta a linha Exec de uma desktop entry: cada argumento entre aspas, com
+// barra invertida antes de aspas, crase, cifrão e da própria barra. O arquivo ainda trata a
+// barra como escape de string, então ela é dobrada de novo, e % vira %%.
+func desktopExec(command []string) string {
+	quoteArg := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", `$`, `\$`)
+	escapeString := strings.NewReplacer(`\`, `\\`, "%", "%%")
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = escapeString.Replace(`"` + quoteArg.Replace(arg) + `"`)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// autostartKey é a chave Run do usuário, lida pelo Windows a cada login
+const autostartKey = `Software\Microsoft\Windows\CurrentVersion\Run`
+
+// autostartEnabled diz se a chave Run tem a entrada do GoVPN
+func autostartEnabled() bool {
+	key, err := registry.OpenKey(registry.CURRENT_USER, autostartKey, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer key.Close()
+
+	_, _, err = key.GetStringValue(autostartName)
+	return err == nil
+}
+
+// setAutostart grava ou apaga a entrada da chave Run
+func setAutostart(enabled bool) error {
+	key, _, err := registry.CreateKey(registry.CURRENT_USER, autostartKey, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open the Run registry key: %v", err)
+	}
+	defer key.Close()
+
+	if !enabled {
+		if err := key.DeleteValue(autostartName); err != nil && !errors.Is(err, registry.ErrNotExist) {
+			return fmt.Errorf("failed to remove the autostart entry: %v", err)
+		}
+		return nil
+	}
+
+	command, err := autostartCommand()
+	if err != nil {
+		return err
+	}
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = `"` + arg + `"`
+	}
+	if err := key.SetStringValue(autostartName, strings.Join(quoted, " ")); err != nil {
+		return fmt.Errorf("failed to write the autostart entry: %v", err)
+	}
+	return nil
+}
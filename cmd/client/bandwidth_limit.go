@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/cmd/client/dialogs"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// networkLimitItem returns the network menu entry that limits the upload to all its computers
+func (ui *UIManager) networkLimitItem(network data.Network) *fyne.MenuItem {
+	limit := ui.ConfigManager.GetConfig().BandwidthLimit(network.NetworkID)
+	return fyne.NewMenuItem(limitLabel("Bandwidth limit", limit.Upload), func() {
+		dialogs.ShowBandwidthLimitDialog("Bandwidth limit of "+network.NetworkName, "Total sent to the computers of this network", limit.Upload, func(kbps int) error {
+			return ui.setBandwidthLimit(network.NetworkID, "", kbps)
+		}, ui.MainWindow)
+	})
+}
+
+// peerLimitItem returns the computer menu entry that limits the upload to that computer, or nil
+// for this computer
+func (ui *UIManager) peerLimitItem(network data.Network, computer smodels.ComputerInfo) *fyne.MenuItem {
+	if computer.PublicKey == ui.VPN.PublicKeyStr {
+		return nil
+	}
+	kbps := ui.ConfigManager.GetConfig().BandwidthLimit(network.NetworkID).Peers[computer.PublicKey]
+	return fyne.NewMenuItem(limitLabel("Limit bandwidth", kbps), func() {
+		dialogs.ShowBandwidthLimitDialog("Bandwidth limit of "+computer.Name, "Sent to this computer, within the network limit", kbps, func(kbps int) error {
+			return ui.setBandwidthLimit(network.NetworkID, computer.PublicKey, kbps)
+		}, ui.MainWindow)
+	})
+}
+
+// setBandwidthLimit saves a limit, applied right away when the network is connected
+func (ui *UIManager) setBandwidthLimit(networkID, peerPublicKey string, kbps int) error {
+	var err error
+	if nm := ui.VPN.NetworkManager; nm != nil {
+		err = nm.SetBandwidthLimit(networkID, peerPublicKey, kbps)
+	} else {
+		err = ui.ConfigManager.SetBandwidthLimit(networkID, peerPublicKey, kbps)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save the bandwidth limit: %v", err)
+	}
+	ui.refreshNetworkList()
+	return nil
+}
+
+// limitLabel shows the current limit next to a menu entry
+func limitLabel(label string, kbps int) string {
+	if kbps == 0 {
+		return label + "…"
+	}
+	return fmt.Sprintf("%s (%g Mbit/s)…", label, float64(kbps)/1000)
+}
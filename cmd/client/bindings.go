@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/itxtoledo/govpn/cmd/client/bus"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+)
+
+// Bindings adapts the state topics of the data layer's bus into Fyne bindings, so widgets
+// can bind to them and listeners run when they change. The data layer itself has no Fyne
+// dependency; one-off reads should use its getters, which are never behind.
+type Bindings struct {
+	ConnectionState binding.Int
+	IsConnected     binding.Bool
+	ComputerName    binding.String
+	ComputerIP      binding.String
+	PublicIP        binding.String
+	NatType         binding.String
+	NetworkName     binding.String
+	PeerSessions    binding.Int
+	Traffic         binding.Untyped     // data.Traffic
+	Networks        binding.UntypedList // *data.Network items
+}
+
+// NewBindings creates the bindings and keeps them following the bus
+func NewBindings(b *bus.Bus) *Bindings {
+	bs := &Bindings{
+		ConnectionState: binding.NewInt(),
+		IsConnected:     binding.NewBool(),
+		ComputerName:    binding.NewString(),
+		ComputerIP:      binding.NewString(),
+		PublicIP:        binding.NewString(),
+		NatType:         binding.NewString(),
+		NetworkName:     binding.NewString(),
+		PeerSessions:    binding.NewInt(),
+		Traffic:         binding.NewUntyped(),
+		Networks:        binding.NewUntypedList(),
+	}
+
+	follow(b, data.TopicConnectionState, func(state data.ConnectionState) {
+		bs.ConnectionState.Set(int(state))
+		bs.IsConnected.Set(state == data.StateConnected)
+	})
+	follow(b, data.TopicComputerName, func(name string) { bs.ComputerName.Set(name) })
+	follow(b, data.TopicComputerIP, func(ip string) { bs.ComputerIP.Set(ip) })
+	follow(b, data.TopicPublicIP, func(ip string) { bs.PublicIP.Set(ip) })
+	follow(b, data.TopicNatType, func(natType string) { bs.NatType.Set(natType) })
+	follow(b, data.TopicNetworkName, func(name string) { bs.NetworkName.Set(name) })
+	follow(b, data.TopicPeerSessions, func(sessions int) { bs.PeerSessions.Set(sessions) })
+	follow(b, data.TopicTraffic, func(traffic data.Traffic) { bs.Traffic.Set(traffic) })
+	follow(b, data.TopicNetworks, func(networks []data.Network) {
+		items := make([]any, len(networks))
+		for i := range networks {
+			items[i] = &networks[i]
+		}
+		bs.Networks.Set(items)
+	})
+	return bs
+}
+
+// follow sets a binding from every value published on a state topic
+func follow[T any](b *bus.Bus, topic bus.Topic[T], set func(T)) {
+	sub := bus.Subscribe(b, topic)
+	crash.Go("binding "+topic.Name(), func() {
+		for value := range sub.C() {
+			set(value)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+// Package bus é um publish/subscribe tipado sobre canais, sem dependência do Fyne. A
+// camada de dados publica nele o estado do cliente e os eventos; a interface gráfica os
+// converte em bindings e o daemon da linha de comando os lê direto, e os testes podem
+// conferir o que o NetworkManager publica sem abrir janela nenhuma.
+//
+// Um tópico de estado guarda o último valor publicado: quem se inscreve recebe esse valor
+// na hora e, se demorar a ler, só o mais recente, já que os intermediários não importam. Um
+// tópico de eventos entrega cada publicação, até o limite da fila de cada inscrito; com a
+// fila cheia, o evento mais antigo dela é descartado para o novo entrar. Publicar nunca
+// bloqueia.
+package bus
+
+import (
+	"fmt"
+	"sync"
+)
+
+// eventBuffer é a fila de cada inscrito num tópico de eventos
+const eventBuffer = 64
+
+// Topic identifica um tópico e o tipo dos valores dele. O nome precisa ser único no Bus.
+type Topic[T any] struct {
+	name  string
+	state bool
+}
+
+// NewEvent cria um tópico de eventos
+func NewEvent[T any](name string) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// NewState cria um tópico de estado, que guarda o último valor
+func NewState[T any](name string) Topic[T] {
+	return Topic[T]{name: name, state: true}
+}
+
+// Name retorna o nome do tópico
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// subscriber é um inscrito de qualquer tópico, visto pelo Bus
+type subscriber interface {
+	deliver(value any)
+}
+
+// Bus distribui os valores publicados aos inscritos de cada tópico
+type Bus struct {
+	mu     sync.Mutex
+	subs   map[string][]subscriber
+	latest map[string]any
+}
+
+// New cria um Bus vazio
+func New() *Bus {
+	return &Bus{subs: make(map[string][]subscriber), latest: make(map[string]any)}
+}
+
+// Publish entrega value aos inscritos do tópico e, se ele for de estado, o guarda
+func Publish[T any](b *Bus, topic Topic[T], value T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if topic.state {
+		b.latest[topic.name] = value
+	}
+	for _, sub := range b.subs[topic.name] {
+		sub.deliver(value)
+	}
+}
+
+// Latest retorna o último valor de um tópico de estado, se algum já foi publicado
+func Latest[T any](b *Bus, topic Topic[T]) (T, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	value, ok := b.latest[topic.name]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	typed, ok := value.(T)
+	if !ok {
+		panic(fmt.Sprintf("bus: topic %q published with another type", topic.name))
+	}
+	return typed, true
+}
+
+// Subscription recebe os valores de um tópico até Close
+type Subscription[T any] struct {
+	bus   *Bus
+	topic string
+	ch    chan T
+}
+
+// Subscribe inscreve um novo leitor no tópico. Num tópico de estado, o último valor já
+// está no canal.
+func Subscribe[T any](b *Bus, topic Topic[T]) *Subscription[T] {
+	size := eventBuffer
+	if topic.state {
+		size = 1
+	}
+	sub := &Subscription[T]{bus: b, topic: topic.name, ch: make(chan T, size)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if value, ok := b.latest[topic.name]; ok {
+		sub.deliver(value)
+	}
+	b.subs[topic.name] = append(b.subs[topic.name], sub)
+	return sub
+}
+
+// C retorna o canal dos valores, fechado por Close
+func (s *Subscription[T]) C() <-chan T {
+	return s.ch
+}
+
+// Close cancela a inscrição e fecha o canal
+func (s *Subscription[T]) Close() {
+	b := s.bus
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := b.subs[s.topic]
+	for i, sub := range subs {
+		if sub == subscriber(s) {
+			b.subs[s.topic] = append(subs[:i:i], subs[i+1:]...)
+			close(s.ch)
+			return
+		}
+	}
+}
+
+// deliver põe o valor na fila, tirando o mais antigo se ela estiver cheia; chamado com o
+// mu do Bus travado, que é o que impede outro envio entre tirar e pôr
+func (s *Subscription[T]) deliver(value any) {
+	typed, ok := value.(T)
+	if !ok {
+		panic(fmt.Sprintf("bus: topic %q published with another type", s.topic))
+	}
+	select {
+	case s.ch <- typed:
+		return
+	default:
+	}
+	select {
+	case <-s.ch:
+	default:
+	}
+	select {
+	case s.ch <- typed:
+	default:
+	}
+}
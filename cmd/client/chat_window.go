@@ -1,7 +1,6 @@
 package main
 
 import (
-	"log"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -9,6 +8,7 @@ import (
 	"github.com/itxtoledo/govpn/cmd/client/data"
 	"github.com/itxtoledo/govpn/cmd/client/ui"
 	"github.com/itxtoledo/govpn/cmd/client/webrtc"
+	"github.com/itxtoledo/govpn/libs/logger"
 )
 
 // ChatWindow represents the chat window for a network
@@ -97,7 +97,7 @@ func (cw *ChatWindow) sendMessage() {
 	// Send the message using WebRTC
 	err := cw.webrtcManager.SendMessage(message)
 	if err != nil {
-		log.Printf("Error sending message: %v", err)
+		logger.Warn("Error sending message", "error", err)
 		cw.addMessage("Error sending: " + err.Error())
 	}
 }
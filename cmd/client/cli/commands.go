@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/libs/utils"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// connectServer conecta ao servidor de sinalização e busca as redes deste computador
+func connectServer(client *core.VPNClient) ([]data.Network, error) {
+	if err := client.Connect(DefaultServerAddress); err != nil {
+		return nil, err
+	}
+	return client.NetworkManager.RefreshNetworks()
+}
+
+// disconnect encerra a conexão com o servidor de um comando que não carrega tráfego
+func disconnect(client *core.VPNClient) {
+	client.NetworkManager.Disconnect()
+}
+
+// list mostra as redes deste computador
+func list(client *core.VPNClient) error {
+	networks, err := connectServer(client)
+	if err != nil {
+		return err
+	}
+	defer disconnect(client)
+
+	printNetworks(networks, client.PublicKeyStr)
+	return nil
+}
+
+// status mostra a identidade deste computador, o servidor e as redes
+func status(client *core.VPNClient) error {
+	config := client.ConfigManager.GetConfig()
+	tunnelMode := config.TunnelMode
+	if tunnelMode == core.TunnelModeAuto {
+		tunnelMode = "auto"
+	}
+
+	fmt.Printf("Computer:    %s\n", config.ComputerName)
+	fmt.Printf("Public key:  %s\n", client.PublicKeyStr)
+	fmt.Printf("Tunnel mode: %s\n", tunnelMode)
+	encryption := client.ConfigManager.Encryption()
+	if encryption == core.EncryptionNone {
+		encryption = "off"
+	}
+	fmt.Printf("Encryption:  %s\n", encryption)
+
+	networks, err := connectServer(client)
+	if err != nil {
+		fmt.Printf("Server:      unreachable\n")
+		return err
+	}
+	defer disconnect(client)
+
+	server := client.NetworkManager.RealtimeData.GetServerAddress()
+	fmt.Printf("Server:      %s\n", server)
+	if info, ok := client.NetworkManager.SignalingServer.ClientIPInfo(); ok {
+		fmt.Printf("Public IP:   %s\n", info.IP)
+	}
+	fmt.Println()
+	printNetworks(networks, client.PublicKeyStr)
+	return nil
+}
+
+// join entra numa rede sem conectar a ela; o tráfego começa com connect ou daemon
+func join(client *core.VPNClient, networkID, pin string) error {
+	networkID = strings.ToLower(networkID)
+	if !utils.ValidateNetworkID(networkID) {
+		return fmt.Errorf("%q is not a network ID", networkID)
+	}
+	if !utils.ValidatePIN(pin) {
+		return fmt.Errorf("the PIN must be exactly 4 digits")
+	}
+
+	if _, err := connectServer(client); err != nil {
+		return err
+	}
+	defer disconnect(client)
+
+	res, err := client.NetworkManager.SignalingServer.JoinNetwork(networkID, pin, client.ConfigManager.GetConfig().ComputerName)
+	if err != nil {
+		return fmt.Errorf("failed to join network: %v", err)
+	}
+	if err := client.ConfigManager.RememberNetworkPIN(networkID, pin); err != nil {
+		return fmt.Errorf("failed to save the network key: %v", err)
+	}
+	fmt.Printf("Joined %s (%s) with address %s\n", res.NetworkName, networkID, res.ComputerIP)
+	return nil
+}
+
+// joinInvite entra na rede de um link de convite. O PIN do argumento vale sobre o do link,
+// e um convite de outro servidor pede o -server, que troca o servidor salvo.
+func joinInvite(client *core.VPNClient, invite core.Invite, pin string) error {
+	if pin == "" {
+		pin = invite.PIN
+	}
+	if pin == "" {
+		return fmt.Errorf("the invite has no PIN, usage: govpn-cli join <link> <pin>")
+	}
+	config := client.ConfigManager.GetConfig()
+	server := config.ServerAddress
+	if server == "" {
+		server = DefaultServerAddress
+	}
+	// Com a escolha automática, os servidores da lista são tratados como um só
+	if invite.Server != "" && invite.Server != server && !config.AutoSelectServer {
+		return fmt.Errorf("the invite is for a network on %s, add -server %s", invite.Server, invite.Server)
+	}
+	return join(client, invite.NetworkID, pin)
+}
+
+// leave sai de uma rede
+func leave(client *core.VPNClient, networkID string) error {
+	if _, err := connectServer(client); err != nil {
+		return err
+	}
+	defer disconnect(client)
+
+	if err := client.NetworkManager.LeaveNetworkById(networkID); err != nil {
+		return err
+	}
+	fmt.Printf("Left network %s\n", networkID)
+	return nil
+}
+
+// call chama um método da API de controle do cliente em execução e mostra o resultado
+func call(client *core.VPNClient, method, params string) error {
+	var args interface{}
+	if params != "" {
+		if !json.Valid([]byte(params)) {
+			return fmt.Errorf("params must be a JSON object, like '{\"network_id\":\"...\"}'")
+		}
+		args = json.RawMessage(params)
+	}
+
+	result, err := core.CallControl(client.ConfigManager.GetDataPath(), method, args)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, result, "", "  "); err != nil {
+		return err
+	}
+	fmt.Println(out.String())
+	return nil
+}
+
+// doctor roda o diagnóstico da conexão e mostra o relatório, pronto para colar numa issue.
+// A conexão ao servidor permite detectar o NAT; sem ela os outros testes rodam mesmo assim.
+func doctor(client *core.VPNClient) error {
+	if _, err := connectServer(client); err != nil {
+		fmt.Fprintf(os.Stderr, "Not connected to the server: %v\n", err)
+	} else {
+		defer disconnect(client)
+	}
+
+	report := client.RunDiagnostics(Version, DefaultServerAddress, func(check core.DiagnosticCheck) {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", check.Name, check.Status)
+	})
+	fmt.Fprintln(os.Stderr)
+	fmt.Print(report.Text())
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(report.Checks))
+	}
+	return nil
+}
+
+// encrypt troca a cifra da configuração; no modo com senha, a senha nova vem da entrada
+func encrypt(client *core.VPNClient, mode string) error {
+	var source, passphrase string
+	switch mode {
+	case "system":
+		source = core.EncryptionSystem
+	case "passphrase":
+		source = core.EncryptionPassphrase
+		var err error
+		if passphrase, err = readPassphrase("New passphrase: "); err != nil {
+			return err
+		}
+	case "off":
+		source = core.EncryptionNone
+	default:
+		return fmt.Errorf("unknown mode %q, use system, passphrase or off", mode)
+	}
+
+	if err := client.ConfigManager.SetEncryption(source, passphrase); err != nil {
+		return err
+	}
+	switch source {
+	case core.EncryptionSystem:
+		fmt.Println("Configuration encrypted with a key in the system key store")
+	case core.EncryptionPassphrase:
+		fmt.Println("Configuration encrypted, set GOVPN_PASSPHRASE to use it")
+	default:
+		fmt.Println("Configuration saved without encryption")
+	}
+	return nil
+}
+
+// exportIdentity cifra o par de chaves com uma senha lida da entrada e grava o resultado
+// no arquivo ou, sem ele, mostra o texto e o QR code no terminal
+func exportIdentity(client *core.VPNClient, path string) error {
+	passphrase, err := readPassphrase("Passphrase for the exported identity: ")
+	if err != nil {
+		return err
+	}
+	exported, err := client.ConfigManager.ExportIdentity(passphrase)
+	if err != nil {
+		return err
+	}
+
+	if path != "" {
+		if err := os.WriteFile(path, []byte(exported+"\n"), 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Identity exported to %s\n", path)
+		return nil
+	}
+
+	code, err := qrcode.New(exported, qrcode.Medium)
+	if err != nil {
+		return err
+	}
+	fmt.Print(code.ToSmallString(false))
+	fmt.Println(exported)
+	return nil
+}
+
+// importIdentity troca a identidade deste computador pela exportada no arquivo
+func importIdentity(client *core.VPNClient, path string) error {
+	exported, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase("Passphrase of the identity: ")
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := client.ConfigManager.ImportIdentity(string(exported), passphrase)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported identity %s, restart a running daemon to use it\n", publicKey)
+	return nil
+}
+
+// readPassphrase lê uma senha da primeira linha da entrada, para ela não ficar no
+// histórico do shell nem na lista de processos
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading the passphrase: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// printNetworks mostra uma tabela com as redes, o endereço deste computador em cada uma e
+// quantos computadores estão online
+func printNetworks(networks []data.Network, publicKey string) {
+	if len(networks) == 0 {
+		fmt.Println("This computer has not joined any network")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NETWORK ID\tNAME\tADDRESS\tONLINE")
+	for _, network := range networks {
+		address, online := network.ComputerIP, 0
+		for _, computer := range network.Computers {
+			if computer.PublicKey == publicKey && address == "" {
+				address = computer.ComputerIP
+			}
+			if computer.IsOnline {
+				online++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\n", network.NetworkID, network.NetworkName, address, online, len(network.Computers))
+	}
+	w.Flush()
+}
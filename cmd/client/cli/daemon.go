@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// reconnectDelay é quanto o daemon espera antes de tentar de novo quando a primeira conexão
+// ao servidor ou à rede falha
+const reconnectDelay = 10 * time.Second
+
+// errInterrupted é retornado quando o comando recebe SIGINT ou SIGTERM
+var errInterrupted = errors.New("interrupted")
+
+// stay conecta ao servidor e à rede e carrega o tráfego até SIGINT ou SIGTERM. Depois de
+// conectado, o núcleo reconecta sozinho quando o servidor cai e volta à rede; se a primeira
+// conexão falhar ou a rede deixar de existir, connect termina com erro e daemon espera
+// reconnectDelay e começa de novo.
+func stay(client *core.VPNClient, networkID string, retry bool) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	// Outro programa pode trocar de rede pela API de controle; o daemon só volta à rede
+	// pedida na linha de comando quando reconecta
+	if ln, err := client.ListenControl(); err != nil {
+		logger.Warn("Control API unavailable", "error", err)
+	} else {
+		go client.ServeControl(ln)
+		defer ln.Close()
+	}
+
+	for {
+		err := session(client, networkID, stop)
+		client.NetworkManager.Disconnect()
+		if errors.Is(err, errInterrupted) {
+			return nil
+		}
+		if !retry || client.NetworkManager.SessionReplaced() {
+			return err
+		}
+
+		logger.Info("Session ended, retrying", "error", err, "delay", reconnectDelay)
+		fmt.Fprintf(os.Stderr, "govpn-cli: %v, retrying in %s\n", err, reconnectDelay)
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// session conecta uma vez e acompanha a conexão até ela cair ou o comando ser interrompido
+func session(client *core.VPNClient, networkID string, stop <-chan os.Signal) error {
+	nm := client.NetworkManager
+	if _, err := connectServer(client); err != nil {
+		return err
+	}
+	if err := connectNetwork(nm, networkID); err != nil {
+		return err
+	}
+
+	// Inscrito depois de conectar, os eventos da própria conexão já foram entregues
+	events := nm.RealtimeData.Subscribe()
+	defer events.Close()
+
+	reconnecting := false
+	for {
+		select {
+		case <-stop:
+			return errInterrupted
+		case event := <-events.C():
+			switch event.Type {
+			case data.EventConnectionStateChanged:
+				switch event.Data {
+				case data.StateConnecting:
+					if !reconnecting {
+						fmt.Fprintln(os.Stderr, "govpn-cli: connection to the server lost, reconnecting")
+					}
+					reconnecting = true
+				case data.StateDisconnected:
+					if nm.SessionReplaced() {
+						return errors.New("this key connected from another computer, not reconnecting")
+					}
+					return errors.New("disconnected from the server")
+				case data.StateConnected:
+					if reconnecting {
+						reconnecting = false
+						fmt.Fprintln(os.Stderr, "govpn-cli: reconnected to the server")
+					}
+				}
+			case data.EventNetworkLeft, data.EventNetworkDeleted:
+				if networkID != "" && event.Message == networkID {
+					return fmt.Errorf("network %s is no longer available", networkID)
+				}
+			case data.EventKicked, data.EventServerShutdown:
+				fmt.Fprintf(os.Stderr, "govpn-cli: %s\n", event.Message)
+			case data.EventServerNotice:
+				if notice, ok := event.Data.(smodels.ServerNoticeNotification); ok {
+					fmt.Fprintf(os.Stderr, "govpn-cli: %s\n", notice.Message)
+				}
+			case data.EventSubnetConflict:
+				if conflict, ok := event.Data.(core.SubnetConflict); ok {
+					fmt.Fprintf(os.Stderr, "govpn-cli: %s %s\n", event.Message, strings.Join(conflict.Conflicts, ", "))
+				}
+			case data.EventError:
+				fmt.Fprintf(os.Stderr, "govpn-cli: %s\n", event.Message)
+			}
+		}
+	}
+}
+
+// connectNetwork conecta à rede, se houver uma, e mostra o endereço recebido
+func connectNetwork(nm *core.NetworkManager, networkID string) error {
+	if networkID == "" {
+		fmt.Println("Connected to the server")
+		return nil
+	}
+	if err := nm.ConnectNetwork(networkID); err != nil {
+		return err
+	}
+
+	address := nm.RealtimeData.GetComputerIP()
+	fmt.Printf("Connected to network %s with address %s\n", networkID, address)
+	return nil
+}
@@ -0,0 +1,213 @@
+// Command govpn-cli é o cliente GoVPN sem interface gráfica, para servidores e Raspberry Pi
+// que não rodam o Fyne. Usa o mesmo núcleo, configuração e identidade do aplicativo; o
+// modo daemon pode ser instalado como serviço com os arquivos de dist/.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// DefaultServerAddress é o servidor usado quando a configuração não tem um. O build de
+// release o troca com -ldflags "-X main.DefaultServerAddress=...".
+var DefaultServerAddress = "wss://localhost:8080/ws"
+
+// Version é a versão no relatório do diagnóstico, trocada pelo build de release com
+// -ldflags "-X main.Version=..."
+var Version = "dev"
+
+const usage = `Usage: govpn-cli [flags] <command> [arguments]
+
+Commands:
+  list                     List the networks this computer joined
+  status                   Show this computer's identity, server and networks
+  join <network-id> <pin>  Join a network; a govpn:// invite link can replace both
+  leave <network-id>       Leave a network
+  connect <network-id>     Connect to a network and carry its traffic until interrupted
+  daemon [network-id]      Like connect, but reconnect whenever the server or network drops
+  call <method> [params]   Call the control API of the running app or daemon, params as JSON
+  doctor                   Test the server, STUN and TURN servers, NAT and WebRTC, printing a report
+  encrypt <mode>           Encrypt the configuration: system, passphrase (read from stdin) or off
+  export-identity [file]   Export the key pair with a passphrase read from stdin, as a QR code without a file
+  import-identity <file>   Replace this computer's key pair with an exported one
+
+Environment:
+  GOVPN_PASSPHRASE         Passphrase of a configuration encrypted with one
+
+Flags:
+`
+
+func main() {
+	var configPath, serverAddress, computerName string
+	var verbose bool
+	flag.StringVar(&configPath, "config", "", "Path to custom configuration directory")
+	flag.StringVar(&serverAddress, "server", "", "Signaling server address, saved in the configuration")
+	flag.StringVar(&computerName, "name", "", "Name of this computer in the networks, saved in the configuration")
+	flag.BoolVar(&verbose, "v", false, "Also write the log to stderr")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// O log vai para govpn-cli.log na pasta de dados, deixando a saída padrão para o
+	// resultado dos comandos; com -v vai também para stderr
+	var console io.Writer
+	if verbose {
+		console = os.Stderr
+	}
+	logger.Setup(logger.Options{Level: core.DefaultLogLevel, Console: console, StdLog: true})
+	configManager := core.NewConfigManager(configPath)
+	if err := configManager.SetupLog("govpn-cli.log", console); err != nil {
+		fmt.Fprintf(os.Stderr, "govpn-cli: cannot open log file: %v\n", err)
+	}
+	crash.Setup(configManager.GetDataPath(), Version)
+
+	if err := configManager.Locked(); err != nil {
+		if err := configManager.Unlock(os.Getenv("GOVPN_PASSPHRASE")); errors.Is(err, core.ErrConfigLocked) {
+			fatalf("%v, set it in GOVPN_PASSPHRASE", err)
+		} else if err != nil {
+			fatalf("opening the configuration: %v", err)
+		}
+		logger.SetLevel(configManager.GetConfig().MinLogLevel())
+	}
+
+	if serverAddress != "" {
+		if err := configManager.UpdateServerAddress(serverAddress); err != nil {
+			fatalf("saving server address: %v", err)
+		}
+	}
+	if computerName != "" {
+		if err := configManager.UpdateComputerName(computerName); err != nil {
+			fatalf("saving computer name: %v", err)
+		}
+	}
+
+	client := newClient(configManager)
+	if err := run(client, flag.Arg(0), flag.Args()[1:]); err != nil {
+		fatalf("%v", err)
+	}
+}
+
+// newClient monta o núcleo do cliente; sem interface gráfica, ninguém converte os tópicos
+// da camada de dados em bindings
+func newClient(configManager *core.ConfigManager) *core.VPNClient {
+	realtimeData := data.NewRealtimeDataLayer()
+	realtimeData.InitDefaults()
+
+	client := core.NewVPNClient(configManager, DefaultServerAddress, configManager.GetConfig().ComputerName)
+	client.LoadSettings(realtimeData)
+	client.SetupNetworkManager(realtimeData)
+	return client
+}
+
+// run executa um comando
+func run(client *core.VPNClient, command string, args []string) error {
+	switch command {
+	case "list":
+		if err := expectArgs(args, 0, "list"); err != nil {
+			return err
+		}
+		return list(client)
+	case "status":
+		if err := expectArgs(args, 0, "status"); err != nil {
+			return err
+		}
+		return status(client)
+	case "join":
+		if len(args) == 1 || len(args) == 2 {
+			if invite, err := core.ParseInvite(args[0]); err == nil {
+				pin := ""
+				if len(args) == 2 {
+					pin = args[1]
+				}
+				return joinInvite(client, invite, pin)
+			}
+		}
+		if err := expectArgs(args, 2, "join <network-id|invite-link> <pin>"); err != nil {
+			return err
+		}
+		return join(client, args[0], args[1])
+	case "leave":
+		if err := expectArgs(args, 1, "leave <network-id>"); err != nil {
+			return err
+		}
+		return leave(client, args[0])
+	case "connect":
+		if err := expectArgs(args, 1, "connect <network-id>"); err != nil {
+			return err
+		}
+		return stay(client, args[0], false)
+	case "daemon":
+		if len(args) > 1 {
+			return fmt.Errorf("usage: govpn-cli daemon [network-id]")
+		}
+		networkID := ""
+		if len(args) == 1 {
+			networkID = args[0]
+		}
+		return stay(client, networkID, true)
+	case "call":
+		if len(args) != 1 && len(args) != 2 {
+			return fmt.Errorf("usage: govpn-cli call <method> [params]")
+		}
+		params := ""
+		if len(args) == 2 {
+			params = args[1]
+		}
+		return call(client, args[0], params)
+	case "doctor":
+		if err := expectArgs(args, 0, "doctor"); err != nil {
+			return err
+		}
+		return doctor(client)
+	case "encrypt":
+		if err := expectArgs(args, 1, "encrypt <system|passphrase|off>"); err != nil {
+			return err
+		}
+		return encrypt(client, args[0])
+	case "export-identity":
+		if len(args) > 1 {
+			return fmt.Errorf("usage: govpn-cli export-identity [file]")
+		}
+		path := ""
+		if len(args) == 1 {
+			path = args[0]
+		}
+		return exportIdentity(client, path)
+	case "import-identity":
+		if err := expectArgs(args, 1, "import-identity <file>"); err != nil {
+			return err
+		}
+		return importIdentity(client, args[0])
+	default:
+		return fmt.Errorf("unknown command %q, run govpn-cli -h for the list", command)
+	}
+}
+
+// expectArgs confere o número de argumentos de um comando
+func expectArgs(args []string, n int, synopsis string) error {
+	if len(args) != n {
+		return fmt.Errorf("usage: govpn-cli %s", synopsis)
+	}
+	return nil
+}
+
+// fatalf mostra um erro e encerra o comando
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "govpn-cli: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// copyToClipboard puts text on the clipboard and confirms it with a notification naming what was copied
+func copyToClipboard(text, what string) {
+	fyne.CurrentApp().Clipboard().SetContent(text)
+	fyne.CurrentApp().SendNotification(&fyne.Notification{
+		Title:   "Copied!",
+		Content: what + " copied to clipboard.",
+	})
+}
+
+// computerCopyItems returns the context menu entries that copy the virtual IP, the host name
+// and the name of a computer. hostName is empty when names do not resolve.
+func computerCopyItems(computer smodels.ComputerInfo, hostName string) []*fyne.MenuItem {
+	var items []*fyne.MenuItem
+	if computer.ComputerIP != "" {
+		items = append(items, fyne.NewMenuItem("Copy IP "+computer.ComputerIP, func() {
+			copyToClipboard(computer.ComputerIP, "IP "+computer.ComputerIP)
+		}))
+	}
+	if hostName != "" {
+		items = append(items, fyne.NewMenuItem("Copy "+hostName, func() {
+			copyToClipboard(hostName, "Host name "+hostName)
+		}))
+	}
+	return append(items, fyne.NewMenuItem("Copy name", func() {
+		copyToClipboard(computer.Name, "Computer name")
+	}))
+}
+
+// hostNames returns the names computers of a network resolve to, by public key, or nothing
+// when name resolution is off in the settings
+func (ui *UIManager) hostNames(network data.Network) map[string]string {
+	if !ui.ConfigManager.GetConfig().PeerDNS {
+		return nil
+	}
+	return core.HostNames(network.Computers)
+}
+
+// networkCopyItems returns the context menu entries that copy the network ID, an invite link
+// and the IP of this computer in the network. The link carries no PIN, so any member can share
+// it; only the owner's invite dialog can include the PIN.
+func (ui *UIManager) networkCopyItems(network data.Network) []*fyne.MenuItem {
+	items := []*fyne.MenuItem{
+		fyne.NewMenuItem("Copy network ID", func() {
+			copyToClipboard(network.NetworkID, "Network ID")
+		}),
+		fyne.NewMenuItem("Copy invite link", func() {
+			link := core.Invite{NetworkID: network.NetworkID, Server: ui.currentServer()}.URL()
+			copyToClipboard(link, "Invite link")
+		}),
+	}
+	for _, computer := range network.Computers {
+		if computer.PublicKey == ui.VPN.PublicKeyStr && computer.ComputerIP != "" {
+			items = append(items, fyne.NewMenuItem("Copy my IP "+computer.ComputerIP, func() {
+				copyToClipboard(computer.ComputerIP, "IP "+computer.ComputerIP)
+			}))
+			break
+		}
+	}
+	return items
+}
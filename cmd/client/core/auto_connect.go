@@ -0,0 +1,71 @@
+package core
+
+import (
+	"slices"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// AutoConnect conecta, depois de conectar ao servidor, a uma das redes marcadas para
+// conexão automática. Só uma rede fica conectada por vez: a última usada é tentada
+// primeiro e as outras marcadas, na ordem da lista, até uma conectar. Fora das janelas de
+// Config.ConnectSchedule nada é conectado.
+func (v *VPNClient) AutoConnect() error {
+	config := v.ConfigManager.GetConfig()
+	if len(config.AutoConnectNetworks) == 0 || v.NetworkManager.NetworkID != "" {
+		return nil
+	}
+	if !config.InSchedule(time.Now()) {
+		logger.Info("Outside the connection schedule, not auto-connecting")
+		return nil
+	}
+
+	networks, err := v.NetworkManager.RefreshNetworks()
+	if err != nil {
+		return err
+	}
+
+	for _, networkID := range autoConnectOrder(config, networks) {
+		logger.Info("Auto-connecting to network", "networkID", networkID)
+		if err = v.NetworkManager.ConnectNetwork(networkID); err == nil {
+			v.autoNetwork.Store(networkID)
+			return nil
+		}
+		logger.Warn("Auto-connect to network failed", "networkID", networkID, "error", err)
+	}
+	return err
+}
+
+// autoConnectOrder lista as redes marcadas que este computador ainda tem, a última usada
+// primeiro
+func autoConnectOrder(config Config, networks []data.Network) []string {
+	var order []string
+	for _, network := range networks {
+		if !slices.Contains(config.AutoConnectNetworks, network.NetworkID) {
+			continue
+		}
+		if network.NetworkID == config.LastNetworkID {
+			order = slices.Insert(order, 0, network.NetworkID)
+		} else {
+			order = append(order, network.NetworkID)
+		}
+	}
+	return order
+}
+
+// forgetAutoConnect desmarca uma rede que este computador deixou ou que foi apagada
+func (nm *NetworkManager) forgetAutoConnect(networkID string) {
+	config := nm.ConfigManager.GetConfig()
+	if slices.Contains(config.AutoConnectNetworks, networkID) {
+		if err := nm.ConfigManager.SetAutoConnect(networkID, false); err != nil {
+			logger.Error("Failed to save the auto-connect networks", "error", err)
+		}
+	}
+	if config.LastNetworkID == networkID {
+		if err := nm.ConfigManager.UpdateLastNetwork(""); err != nil {
+			logger.Error("Failed to save the last network", "error", err)
+		}
+	}
+}
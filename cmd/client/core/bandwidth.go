@@ -0,0 +1,95 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	clientwebrtc_impl "github.com/itxtoledo/govpn/cmd/client/webrtc"
+)
+
+// TrafficSampleInterval is how often the traffic of the peer connections is sampled
+const TrafficSampleInterval = time.Second
+
+// trafficMeter adds up the bytes of every peer connection while connected to a network.
+// Connections come and go, so it keeps the last counters of each one and sums deltas.
+// The totals are also added to the daily history of the network.
+type trafficMeter struct {
+	nm        *NetworkManager
+	networkID string
+
+	last  map[*clientwebrtc_impl.WebRTCManager][2]uint64
+	peers map[string]data.PeerTraffic
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newTrafficMeter(nm *NetworkManager) *trafficMeter {
+	return &trafficMeter{
+		nm:        nm,
+		networkID: nm.NetworkID,
+		last:      make(map[*clientwebrtc_impl.WebRTCManager][2]uint64),
+		peers:     make(map[string]data.PeerTraffic),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run samples the traffic every TrafficSampleInterval until Close
+func (m *trafficMeter) Run() {
+	ticker := time.NewTicker(TrafficSampleInterval)
+	defer ticker.Stop()
+
+	previous := time.Now()
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.sample(now, now.Sub(previous))
+			previous = now
+		}
+	}
+}
+
+// sample records the bytes moved since the last sample and the resulting throughput
+func (m *trafficMeter) sample(now time.Time, elapsed time.Duration) {
+	var sent, received uint64
+	current := make(map[*clientwebrtc_impl.WebRTCManager][2]uint64)
+
+	m.nm.peersMu.Lock()
+	for publicKey, peer := range m.nm.peerConnections {
+		peerSent, peerReceived := peer.Traffic()
+		current[peer] = [2]uint64{peerSent, peerReceived}
+
+		last := m.last[peer]
+		totals := m.peers[publicKey]
+		totals.Sent += peerSent - last[0]
+		totals.Received += peerReceived - last[1]
+		m.peers[publicKey] = totals
+
+		sent += peerSent - last[0]
+		received += peerReceived - last[1]
+	}
+	m.nm.peersMu.Unlock()
+	m.last = current
+	m.nm.trafficLog.add(m.networkID, m.nm.networkName(m.networkID), now, sent, received)
+
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		seconds = TrafficSampleInterval.Seconds()
+	}
+	m.nm.RealtimeData.RecordTraffic(data.TrafficSample{
+		At:       now,
+		Sent:     float64(sent) / seconds,
+		Received: float64(received) / seconds,
+	}, m.peers)
+}
+
+// Close stops sampling and saves the history
+func (m *trafficMeter) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+		m.nm.trafficLog.flush()
+	})
+}
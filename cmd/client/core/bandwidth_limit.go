@@ -0,0 +1,129 @@
+package core
+
+import (
+	"maps"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/network"
+)
+
+// Limite de banda: o envio aos peers da rede conectada passa por um balde de fichas da rede
+// e outro do peer, para a VPN não ocupar todo o upload durante uma partida. Pacotes da
+// interface TUN acima do limite são descartados, como num roteador congestionado, e o TCP
+// dentro deles reduz a taxa; os fluxos do proxy e os arquivos esperam a vez.
+
+// maxShapingDelay é quanto um pacote da interface TUN pode esperar antes de ser descartado
+const maxShapingDelay = 50 * time.Millisecond
+
+// BandwidthLimit é o limite de envio de uma rede, em kbit/s; zero deixa sem limite
+type BandwidthLimit struct {
+	Upload int            `json:"upload_kbps,omitempty"` // Total enviado aos peers da rede
+	Peers  map[string]int `json:"peers,omitempty"`       // Enviado a cada peer, pela chave pública
+}
+
+// BandwidthLimit retorna o limite de envio de uma rede
+func (c Config) BandwidthLimit(networkID string) BandwidthLimit {
+	return c.BandwidthLimits[networkID]
+}
+
+// SetBandwidthLimit muda o limite de envio de uma rede ou, com peerPublicKey, de um peer
+// dela, em kbit/s; zero remove o limite
+func (cm *ConfigManager) SetBandwidthLimit(networkID, peerPublicKey string, kbps int) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	limit := cm.config.BandwidthLimits[networkID]
+	if peerPublicKey == "" {
+		limit.Upload = max(kbps, 0)
+	} else {
+		limit.Peers = maps.Clone(limit.Peers)
+		if kbps > 0 {
+			if limit.Peers == nil {
+				limit.Peers = make(map[string]int)
+			}
+			limit.Peers[peerPublicKey] = kbps
+		} else {
+			delete(limit.Peers, peerPublicKey)
+		}
+		if len(limit.Peers) == 0 {
+			limit.Peers = nil
+		}
+	}
+
+	all := maps.Clone(cm.config.BandwidthLimits)
+	if all == nil {
+		all = make(map[string]BandwidthLimit)
+	}
+	if limit.Upload == 0 && limit.Peers == nil {
+		delete(all, networkID)
+	} else {
+		all[networkID] = limit
+	}
+	cm.config.BandwidthLimits = all
+	return cm.SaveConfig()
+}
+
+// shaper guarda os baldes da rede conectada; é trocado inteiro quando os limites mudam
+type shaper struct {
+	network *network.TokenBucket
+	peers   map[string]*network.TokenBucket
+}
+
+// newShaper cria os baldes de um limite, ou retorna nil quando não há limite nenhum
+func newShaper(limit BandwidthLimit) *shaper {
+	if limit.Upload == 0 && len(limit.Peers) == 0 {
+		return nil
+	}
+	s := &shaper{peers: make(map[string]*network.TokenBucket, len(limit.Peers))}
+	if limit.Upload > 0 {
+		s.network = network.NewTokenBucket(limit.Upload * 1000 / 8)
+	}
+	for publicKey, kbps := range limit.Peers {
+		s.peers[publicKey] = network.NewTokenBucket(kbps * 1000 / 8)
+	}
+	return s
+}
+
+// wait segura o envio de n bytes ao peer até caberem nos limites. Com droppable, um envio
+// que esperaria mais de maxShapingDelay não tira fichas e retorna falso para ser descartado.
+func (s *shaper) wait(peerPublicKey string, n int, droppable bool) bool {
+	peer := s.peers[peerPublicKey]
+	delay := max(s.network.Delay(n), peer.Delay(n))
+	if droppable && delay > maxShapingDelay {
+		return false
+	}
+	s.network.Take(n)
+	peer.Take(n)
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return true
+}
+
+// SetBandwidthLimit salva o limite de envio de uma rede ou de um peer dela e, com a rede
+// conectada, passa a aplicá-lo na hora
+func (nm *NetworkManager) SetBandwidthLimit(networkID, peerPublicKey string, kbps int) error {
+	if err := nm.ConfigManager.SetBandwidthLimit(networkID, peerPublicKey, kbps); err != nil {
+		return err
+	}
+	if networkID == nm.NetworkID {
+		nm.applyBandwidthLimit()
+	}
+	return nil
+}
+
+// applyBandwidthLimit troca os baldes pelos do limite salvo para a rede atual
+func (nm *NetworkManager) applyBandwidthLimit() {
+	nm.shaper.Store(newShaper(nm.ConfigManager.GetConfig().BandwidthLimit(nm.NetworkID)))
+}
+
+// shapeFrame segura um frame até caber nos limites de envio, ou diz que ele deve ser
+// descartado. Só os pacotes da interface TUN são descartáveis.
+func (nm *NetworkManager) shapeFrame(peerPublicKey string, frame []byte) bool {
+	s := nm.shaper.Load()
+	if s == nil {
+		return true
+	}
+	frameType, _, _ := network.DecodeFrame(frame)
+	return s.wait(peerPublicKey, len(frame), frameType == network.FrameTypePacket)
+}
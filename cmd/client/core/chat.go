@@ -0,0 +1,319 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// Conversa direta entre dois computadores. As mensagens vão como texto JSON pelo canal de
+// dados, o mesmo de onWebRTCMessageReceived, cada uma com um ID que o destino confirma com
+// um ack. O histórico de cada peer fica em chats/ na pasta de dados, com a cifra da
+// configuração. O que ainda não teve ack é reenviado quando o canal do peer abre de novo, e
+// o destino descarta as repetidas pelo ID.
+
+const (
+	// chatDir é a pasta dos históricos dentro da pasta de dados
+	chatDir = "chats"
+	// maxChatHistory é quantas mensagens ficam guardadas por peer; as mais antigas saem
+	maxChatHistory = 500
+	// MaxChatMessageLength é o tamanho máximo do texto de uma mensagem, em caracteres
+	MaxChatMessageLength = 4000
+)
+
+// Tipos das mensagens de texto da conversa no canal de dados
+const (
+	chatTypeMessage = "chat"
+	chatTypeAck     = "chat_ack"
+)
+
+// ChatDeliveryState é o estado de entrega de uma mensagem enviada
+type ChatDeliveryState string
+
+const (
+	// ChatPending é uma mensagem que ainda não saiu, com o canal do peer fechado
+	ChatPending ChatDeliveryState = "pending"
+	// ChatSent é uma mensagem entregue ao canal de dados, sem ack do peer
+	ChatSent ChatDeliveryState = "sent"
+	// ChatDelivered é uma mensagem que o peer confirmou
+	ChatDelivered ChatDeliveryState = "delivered"
+)
+
+// ChatMessage é uma mensagem da conversa com um peer
+type ChatMessage struct {
+	ID       string            `json:"id"`
+	Text     string            `json:"text"`
+	Time     time.Time         `json:"time"`
+	Outgoing bool              `json:"outgoing"`
+	State    ChatDeliveryState `json:"state,omitempty"` // Só nas enviadas
+}
+
+// ChatEvent acompanha data.EventChatMessage: uma mensagem recebida ou uma enviada que mudou de estado
+type ChatEvent struct {
+	Peer    string
+	Message ChatMessage
+}
+
+// errEmptyChatMessage é retornado ao enviar uma mensagem sem texto
+var errEmptyChatMessage = errors.New("the message is empty")
+
+// chatWireMessage é uma mensagem ou um ack como vai pelo canal de dados
+type chatWireMessage struct {
+	Type   string    `json:"type"`
+	ID     string    `json:"id"`
+	Text   string    `json:"text,omitempty"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// chatHistory é o arquivo com a conversa de um peer
+type chatHistory struct {
+	Peer     string        `json:"peer"`
+	Messages []ChatMessage `json:"messages"`
+
+	unreadable bool // O arquivo existe mas não abriu; não é sobrescrito
+}
+
+// chatStore guarda as conversas, lidas da pasta de dados na primeira vez que são usadas
+type chatStore struct {
+	cm        *ConfigManager
+	histories map[string]*chatHistory
+	mu        sync.Mutex
+}
+
+// newChatStore cria o acesso às conversas guardadas
+func newChatStore(cm *ConfigManager) *chatStore {
+	return &chatStore{cm: cm, histories: make(map[string]*chatHistory)}
+}
+
+// chatFileName é o arquivo da conversa de um peer. O nome vem do hash da chave, que não cabe
+// nem é segura como nome de arquivo.
+func chatFileName(peerPublicKey string) string {
+	sum := sha256.Sum256([]byte(peerPublicKey))
+	return filepath.Join(chatDir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// history retorna a conversa de um peer, lendo-a do disco na primeira vez. Um arquivo que não
+// abre, como com a configuração trancada, é tentado de novo na próxima. Chamado com mu travado.
+func (cs *chatStore) history(peerPublicKey string) *chatHistory {
+	if history, ok := cs.histories[peerPublicKey]; ok {
+		return history
+	}
+
+	history := &chatHistory{Peer: peerPublicKey}
+	content, err := cs.cm.ReadDataFile(chatFileName(peerPublicKey))
+	if err == nil {
+		err = json.Unmarshal(content, history)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		logger.Warn("Cannot read chat history", "peer", peerPublicKey, "error", err)
+		return &chatHistory{Peer: peerPublicKey, unreadable: true}
+	}
+	cs.histories[peerPublicKey] = history
+	return history
+}
+
+// save grava a conversa de um peer. Chamado com mu travado.
+func (cs *chatStore) save(history *chatHistory) {
+	if history.unreadable {
+		return
+	}
+	content, err := json.Marshal(history)
+	if err == nil {
+		err = cs.cm.WriteDataFile(chatFileName(history.Peer), content)
+	}
+	if err != nil {
+		logger.Warn("Cannot write chat history", "peer", history.Peer, "error", err)
+	}
+}
+
+// messages retorna a conversa de um peer, da mais antiga à mais nova
+func (cs *chatStore) messages(peerPublicKey string) []ChatMessage {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	return append([]ChatMessage(nil), cs.history(peerPublicKey).Messages...)
+}
+
+// add acrescenta uma mensagem à conversa. Retorna false se ela já estava, como uma recebida
+// de novo porque o ack se perdeu.
+func (cs *chatStore) add(peerPublicKey string, message ChatMessage) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	history := cs.history(peerPublicKey)
+	for _, existing := range history.Messages {
+		if existing.ID == message.ID && existing.Outgoing == message.Outgoing {
+			return false
+		}
+	}
+	history.Messages = append(history.Messages, message)
+	if extra := len(history.Messages) - maxChatHistory; extra > 0 {
+		history.Messages = append([]ChatMessage(nil), history.Messages[extra:]...)
+	}
+	cs.save(history)
+	return true
+}
+
+// setState muda o estado de entrega de uma mensagem enviada. Um estado só avança; retorna
+// false quando nada mudou.
+func (cs *chatStore) setState(peerPublicKey, id string, state ChatDeliveryState) (ChatMessage, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	history := cs.history(peerPublicKey)
+	for i, message := range history.Messages {
+		if !message.Outgoing || message.ID != id {
+			continue
+		}
+		if message.State == state || message.State == ChatDelivered {
+			return message, false
+		}
+		history.Messages[i].State = state
+		cs.save(history)
+		return history.Messages[i], true
+	}
+	return ChatMessage{}, false
+}
+
+// undelivered retorna as mensagens enviadas a um peer que ainda esperam o ack
+func (cs *chatStore) undelivered(peerPublicKey string) []ChatMessage {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var messages []ChatMessage
+	for _, message := range cs.history(peerPublicKey).Messages {
+		if message.Outgoing && message.State != ChatDelivered {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// clear apaga a conversa de um peer, também do disco
+func (cs *chatStore) clear(peerPublicKey string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	delete(cs.histories, peerPublicKey)
+	err := os.Remove(filepath.Join(cs.cm.GetDataPath(), chatFileName(peerPublicKey)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// newMessageID gera o ID de uma mensagem de texto do canal de dados
+func newMessageID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// ChatHistory retorna a conversa com um peer, da mensagem mais antiga à mais nova
+func (nm *NetworkManager) ChatHistory(peerPublicKey string) []ChatMessage {
+	return nm.chats.messages(peerPublicKey)
+}
+
+// ClearChatHistory apaga a conversa com um peer
+func (nm *NetworkManager) ClearChatHistory(peerPublicKey string) error {
+	return nm.chats.clear(peerPublicKey)
+}
+
+// SendChatMessage envia uma mensagem a um peer. Com o canal do peer fechado ela fica
+// pendente no histórico e sai quando ele abrir.
+func (nm *NetworkManager) SendChatMessage(peerPublicKey, text string) (ChatMessage, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ChatMessage{}, errEmptyChatMessage
+	}
+	if utf8.RuneCountInString(text) > MaxChatMessageLength {
+		return ChatMessage{}, fmt.Errorf("the message is longer than %d characters", MaxChatMessageLength)
+	}
+
+	message := ChatMessage{
+		ID:       newMessageID(),
+		Text:     text,
+		Time:     time.Now(),
+		Outgoing: true,
+		State:    ChatPending,
+	}
+	nm.chats.add(peerPublicKey, message)
+
+	if err := nm.sendPeerMessage(peerPublicKey, chatWireMessage{Type: chatTypeMessage, ID: message.ID, Text: text, SentAt: message.Time}); err != nil {
+		// Peers retransmitidos pelo servidor não têm canal; a mensagem espera a conexão direta
+		logger.Debug("Chat message left pending", "peer", peerPublicKey, "error", err)
+		return message, nil
+	}
+	if sent, ok := nm.chats.setState(peerPublicKey, message.ID, ChatSent); ok {
+		message = sent
+	}
+	return message, nil
+}
+
+// resendChatMessages reenvia a um peer cujo canal abriu as mensagens ainda sem ack
+func (nm *NetworkManager) resendChatMessages(peerPublicKey string) {
+	for _, message := range nm.chats.undelivered(peerPublicKey) {
+		wire := chatWireMessage{Type: chatTypeMessage, ID: message.ID, Text: message.Text, SentAt: message.Time}
+		if err := nm.sendPeerMessage(peerPublicKey, wire); err != nil {
+			logger.Debug("Cannot resend chat message", "peer", peerPublicKey, "error", err)
+			return
+		}
+		if sent, ok := nm.chats.setState(peerPublicKey, message.ID, ChatSent); ok {
+			nm.RealtimeData.EmitEvent(data.EventChatMessage, "", ChatEvent{Peer: peerPublicKey, Message: sent})
+		}
+	}
+}
+
+// handleChatMessage trata uma mensagem de texto do canal de dados que seja da conversa:
+// guarda a mensagem e responde com o ack, ou marca a enviada como entregue. Retorna false
+// para as que não são da conversa.
+func (nm *NetworkManager) handleChatMessage(peerPublicKey, content string) bool {
+	var wire chatWireMessage
+	if json.Unmarshal([]byte(content), &wire) != nil || wire.ID == "" {
+		return false
+	}
+
+	switch wire.Type {
+	case chatTypeMessage:
+		// O ack vai mesmo para as repetidas, cujo primeiro ack se perdeu
+		if err := nm.sendPeerMessage(peerPublicKey, chatWireMessage{Type: chatTypeAck, ID: wire.ID, SentAt: time.Now()}); err != nil {
+			logger.Debug("Cannot acknowledge chat message", "peer", peerPublicKey, "error", err)
+		}
+
+		text := wire.Text
+		if utf8.RuneCountInString(text) > MaxChatMessageLength {
+			text = string([]rune(text)[:MaxChatMessageLength])
+		}
+		// A hora é a do envio, que numa mensagem pendente pode ser bem antes da chegada,
+		// mas nunca depois da chegada, mesmo com o relógio do peer adiantado
+		message := ChatMessage{ID: wire.ID, Text: text, Time: time.Now()}
+		if !wire.SentAt.IsZero() && wire.SentAt.Before(message.Time) {
+			message.Time = wire.SentAt
+		}
+		if !nm.chats.add(peerPublicKey, message) {
+			return true
+		}
+		// O texto fica fora da notificação, pois costuma levar senhas
+		notice := "New message from " + nm.computerName(nm.NetworkID, peerPublicKey)
+		nm.RealtimeData.EmitEvent(data.EventChatMessage, notice, ChatEvent{Peer: peerPublicKey, Message: message})
+	case chatTypeAck:
+		if delivered, ok := nm.chats.setState(peerPublicKey, wire.ID, ChatDelivered); ok {
+			nm.RealtimeData.EmitEvent(data.EventChatMessage, "", ChatEvent{Peer: peerPublicKey, Message: delivered})
+		}
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,554 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	clientwebrtc_impl "github.com/itxtoledo/govpn/cmd/client/webrtc"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// Config representa as configurações da aplicação
+type Config struct {
+	ComputerName  string `json:"computername"`
+	ServerAddress string `json:"server_address"`
+	Language      string `json:"language"`
+	PublicKey     string `json:"public_key"`
+	PrivateKey    string `json:"private_key,omitempty"` // Só nas versões antigas, hoje vai para o cofre em KeyStore
+	KeyStore      string `json:"key_store,omitempty"`   // Onde a chave privada está: KeyStoreSystem ou KeyStoreFile
+
+	// Lista de servidores de sinalização com regiões, para escolher o mais rápido
+	ServerListURL    string `json:"server_list_url,omitempty"`
+	AutoSelectServer bool   `json:"auto_select_server"` // Escolher o servidor mais rápido da lista ao iniciar
+
+	// Certificados fixados de cada servidor wss://, pelo host e porta, em server_pins.go
+	ServerPins map[string][]string `json:"server_pins,omitempty"`
+
+	// Transporte do tráfego: interface TUN ou, sem privilégios, redirecionamento de portas
+	TunnelMode   string                `json:"tunnel_mode,omitempty"`
+	PortForwards []network.PortForward `json:"port_forwards,omitempty"` // Portas locais levadas até portas dos peers
+	SharedPorts  []network.SharedPort  `json:"shared_ports,omitempty"`  // Portas locais que os peers podem alcançar pelo proxy
+	LANBroadcast bool                  `json:"lan_broadcast,omitempty"` // Replicar broadcasts e multicast para os peers, para jogos que se descobrem na LAN
+
+	// Divisão do túnel: os grupos de multicast e broadcast levados aos peers, no lugar de
+	// todos, e os programas que podem usar a interface virtual, só no Windows
+	TunnelRoutes []string `json:"tunnel_routes,omitempty"`
+	TunnelApps   []string `json:"tunnel_apps,omitempty"`
+
+	// Copiar aos peers o mDNS, o LLMNR e o NetBIOS, para a descoberta de pastas compartilhadas
+	// e partidas do sistema funcionar pela VPN
+	LANDiscovery bool `json:"lan_discovery,omitempty"`
+
+	// Resolver os nomes dos computadores da rede como <nome>.govpn, com o servidor DNS da VPN
+	PeerDNS bool `json:"peer_dns,omitempty"`
+
+	// Predefinições de jogos ativadas em cada rede, pelo ID da rede
+	GamePresets map[string][]NetworkPreset `json:"game_presets,omitempty"`
+
+	// Limites de envio de cada rede e dos peers dela, pelo ID da rede
+	BandwidthLimits map[string]BandwidthLimit `json:"bandwidth_limits,omitempty"`
+
+	// Chaves derivadas do PIN de cada rede, em base64 e a mais nova primeiro, que autenticam
+	// o handshake com os peers; pelo ID da rede, em network_keys.go
+	NetworkKeys map[string][]string `json:"network_keys,omitempty"`
+
+	// Permitir que os membros das redes procurem jogos e serviços nas portas deste computador
+	AllowServiceScan bool `json:"allow_service_scan,omitempty"`
+
+	// Permitir que os membros das redes acordem este computador pela rede (Wake-on-LAN)
+	AllowWake bool `json:"allow_wake,omitempty"`
+
+	// Servidores STUN/TURN usados para achar um caminho até os peers; vazio usa o STUN padrão
+	ICEServers   []clientwebrtc_impl.ICEServer `json:"ice_servers,omitempty"`
+	ICERelayOnly bool                          `json:"ice_relay_only,omitempty"` // Conectar só pelos relays TURN, sem revelar os endereços deste computador
+
+	// Conexão automática ao iniciar: as redes marcadas e a última conectada, tentada primeiro
+	AutoConnectNetworks []string `json:"auto_connect_networks,omitempty"`
+	LastNetworkID       string   `json:"last_network_id,omitempty"`
+
+	// Janelas da semana em que a conexão automática vale, vazio vale sempre, e minutos sem
+	// tráfego no túnel até desconectar a rede, zero nunca desconecta
+	ConnectSchedule       []ConnectWindow `json:"connect_schedule,omitempty"`
+	IdleDisconnectMinutes int             `json:"idle_disconnect_minutes,omitempty"`
+
+	// Eventos que o usuário desligou nas notificações da área de trabalho; os demais notificam
+	MutedNotifications []data.EventType `json:"muted_notifications,omitempty"`
+
+	// Aparência: tema claro ou escuro, cor de destaque (#rrggbb, vazia usa a padrão) e escala
+	// da interface, de 1 a MaxUIScale, para texto maior e alvos de clique maiores
+	Theme       string  `json:"theme,omitempty"`
+	AccentColor string  `json:"accent_color,omitempty"`
+	UIScale     float32 `json:"ui_scale,omitempty"`
+
+	// Ordem da lista de redes na janela principal, em network_sort.go; vazia ordena pelo nome
+	NetworkSort string `json:"network_sort,omitempty"`
+
+	// Nível mínimo do log (debug, info, warn, error); vazio usa DefaultLogLevel
+	LogLevel string `json:"log_level,omitempty"`
+}
+
+// NotificationEnabled diz se um evento gera notificação na área de trabalho
+func (c Config) NotificationEnabled(event data.EventType) bool {
+	return !slices.Contains(c.MutedNotifications, event)
+}
+
+// Temas da interface, escolhidos nas configurações
+const (
+	ThemeSystem = ""      // Claro ou escuro, como o sistema
+	ThemeDark   = "dark"  // Sempre escuro
+	ThemeLight  = "light" // Sempre claro
+)
+
+// MaxUIScale é a maior escala da interface aceita em UIScale
+const MaxUIScale = 2
+
+// InterfaceScale retorna a escala da interface, 1 quando a configuração não escolhe uma
+// válida
+func (c Config) InterfaceScale() float32 {
+	if c.UIScale < 1 || c.UIScale > MaxUIScale {
+		return 1
+	}
+	return c.UIScale
+}
+
+// WebRTCOptions retorna a configuração das conexões com os peers
+func (c Config) WebRTCOptions() clientwebrtc_impl.Options {
+	return clientwebrtc_impl.Options{
+		ICEServers: c.ICEServers,
+		RelayOnly:  c.ICERelayOnly,
+	}
+}
+
+// Network represents a VPN network
+
+// ConfigManager gerencia as configurações da aplicação
+type ConfigManager struct {
+	config   Config
+	dataPath string // Add dataPath field
+	mutex    sync.Mutex
+
+	sealer  fileSealer // Cifra de config.json e private.key
+	lockErr error      // Por que a configuração cifrada ainda não pôde ser lida
+}
+
+// NewConfigManager cria uma nova instância do gerenciador de configurações
+func NewConfigManager(customConfigPath string) *ConfigManager {
+	var dataPath string
+
+	if customConfigPath != "" {
+		dataPath = customConfigPath
+	} else {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			logger.Warn("Cannot get the user home directory", "error", err)
+			homeDir = "."
+		}
+		
+		// Use appropriate config directory for each OS:
+		// - Windows: %LOCALAPPDATA%/govpn
+		// - macOS: ~/Library/Application Support/govpn
+		// - Linux: ~/.local/share/govpn
+		switch runtime.GOOS {
+		case "windows":
+			dataPath = filepath.Join(os.Getenv("LOCALAPPDATA"), "govpn")
+		case "darwin": // macOS
+			dataPath = filepath.Join(homeDir, "Library", "Application Support", "govpn")
+		default: // Linux and others
+			dataPath = filepath.Join(homeDir, ".local", "share", "govpn")
+		}
+	}
+
+	cm := &ConfigManager{
+		dataPath: dataPath, // Initialize dataPath
+		config: Config{
+			ComputerName: "Computer",
+			// ServerAddress: "wss://govpn-k6ql.onrender.com:8080/ws",
+			ServerAddress: "wss://localhost:8080/ws",
+			Language:      "en",
+			ServerListURL: os.Getenv("SERVER_LIST_URL"),
+		},
+	}
+
+	// Cria o diretório de dados se não existir
+	err := os.MkdirAll(dataPath, 0755)
+	if err != nil {
+		logger.Error("Cannot create the data directory", "path", dataPath, "error", err)
+	}
+
+	// Carrega as configurações do arquivo
+	cm.LoadConfig()
+
+	return cm
+}
+
+// GetConfig retorna as configurações atuais
+func (cm *ConfigManager) GetConfig() Config {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	return cm.config
+}
+
+// UpdateConfig atualiza as configurações
+func (cm *ConfigManager) UpdateConfig(config Config) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.config = config
+	return cm.SaveConfig()
+}
+
+// UpdateComputerName atualiza o nome de usuário
+func (cm *ConfigManager) UpdateComputerName(computername string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.config.ComputerName = computername
+	return cm.SaveConfig()
+}
+
+// UpdateServerAddress atualiza o endereço do servidor
+func (cm *ConfigManager) UpdateServerAddress(address string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.config.ServerAddress = address
+	return cm.SaveConfig()
+}
+
+// SetAutoConnect marca ou desmarca a conexão automática a uma rede
+func (cm *ConfigManager) SetAutoConnect(networkID string, enabled bool) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if slices.Contains(cm.config.AutoConnectNetworks, networkID) == enabled {
+		return nil
+	}
+	if enabled {
+		cm.config.AutoConnectNetworks = append(slices.Clip(cm.config.AutoConnectNetworks), networkID)
+	} else {
+		cm.config.AutoConnectNetworks = slices.DeleteFunc(slices.Clone(cm.config.AutoConnectNetworks), func(id string) bool {
+			return id == networkID
+		})
+	}
+	return cm.SaveConfig()
+}
+
+// UpdateLastNetwork guarda a rede conectada agora, vazia depois de uma desconexão pedida
+func (cm *ConfigManager) UpdateLastNetwork(networkID string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.config.LastNetworkID == networkID {
+		return nil
+	}
+	cm.config.LastNetworkID = networkID
+	return cm.SaveConfig()
+}
+
+// UpdateLanguage atualiza o idioma
+func (cm *ConfigManager) UpdateLanguage(language string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.config.Language = language
+	return cm.SaveConfig()
+}
+
+// GetKeyPair retorna as chaves pública e privada
+func (cm *ConfigManager) GetKeyPair() (string, string) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	return cm.config.PublicKey, cm.config.PrivateKey
+}
+
+// LoadConfig carrega as configurações do arquivo
+func (cm *ConfigManager) LoadConfig() {
+	configPath := filepath.Join(cm.dataPath, "config.json")
+	logger.Debug("Loading config", "path", configPath)
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		// Se o arquivo não existe, cria com valores padrão
+		if os.IsNotExist(err) {
+			logger.Info("Config file doesn't exist, creating with default values", "path", configPath)
+			if cm.SaveConfig() == nil {
+				// Lido de volta, o arquivo novo ganha o par de chaves abaixo
+				cm.LoadConfig()
+			}
+		} else {
+			logger.Error("Cannot open the config file", "path", configPath, "error", err)
+		}
+		return
+	}
+
+	// Cifrada com a chave do cofre do sistema, abre sozinha; com senha, espera Unlock
+	if sealed, ok := parseSealed(content); ok && cm.sealer.key == nil {
+		sealer, err := openSealer(sealed, "")
+		if err != nil {
+			logger.Info("Config file is encrypted and stays locked", "error", err)
+			cm.lockErr = err
+			return
+		}
+		cm.sealer = sealer
+	}
+	if content, err = cm.sealer.open("config.json", content); err != nil {
+		logger.Error("Cannot decrypt the config file", "error", err)
+		cm.lockErr = err
+		return
+	}
+	cm.lockErr = nil
+
+	err = json.Unmarshal(content, &cm.config)
+	if err != nil {
+		logger.Error("Cannot decode the config file", "error", err)
+		return
+	}
+
+	// Log config details
+	logger.Debug("Config loaded", "computerName", cm.config.ComputerName, "language", cm.config.Language)
+
+	// Check for public/private keys
+	keyErr := cm.loadPrivateKey()
+	if cm.config.PublicKey != "" && cm.config.PrivateKey != "" {
+		logger.Debug("Key pair found in config", "publicKey", cm.config.PublicKey[:10]+"...")
+	} else if keyErr != nil && !errors.Is(keyErr, ErrKeyNotFound) {
+		// Com o cofre travado ou fora do ar, uma chave nova trocaria a identidade do computador
+		logger.Warn("Cannot read the private key, keeping the current identity", "keyStore", cm.config.KeyStore, "error", keyErr)
+	} else {
+		logger.Info("No key pair found in config, generating a new Ed25519 key pair",
+			"publicKeyEmpty", cm.config.PublicKey == "", "privateKeyEmpty", cm.config.PrivateKey == "")
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+
+		if err != nil {
+			logger.Error("Cannot generate a key pair", "error", err)
+		}
+
+		// Convert keys to string for storage
+		publicKeyStr := base64.StdEncoding.EncodeToString(publicKey)
+		privateKeyStr := base64.StdEncoding.EncodeToString(privateKey)
+
+		logger.Info("Generated a new key pair", "publicKey", publicKeyStr[:10]+"...")
+
+		// Update config with new keys
+		cm.config.PublicKey = publicKeyStr
+		cm.config.PrivateKey = privateKeyStr
+		cm.config.KeyStore = ""
+
+		if cm.storePrivateKey() != nil {
+			cm.SaveConfig()
+		}
+	}
+}
+
+// loadPrivateKey lê a chave privada do cofre indicado na configuração. Uma chave ainda no
+// config.json ou no arquivo vai para o cofre do sistema quando ele existe.
+func (cm *ConfigManager) loadPrivateKey() error {
+	if cm.config.PublicKey == "" {
+		return nil
+	}
+
+	if cm.config.KeyStore != "" {
+		store, err := cm.openKeyStore(cm.config.KeyStore)
+		if err == nil {
+			cm.config.PrivateKey, err = store.Get(cm.config.PublicKey)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if cm.config.PrivateKey != "" && cm.config.KeyStore != KeyStoreSystem {
+		cm.storePrivateKey()
+	}
+	return nil
+}
+
+// storePrivateKey tira a chave privada do config.json, guardando no cofre do sistema ou,
+// sem ele, no arquivo. A chave só sai do lugar antigo depois de lida de volta do novo.
+func (cm *ConfigManager) storePrivateKey() error {
+	previous := cm.config.KeyStore
+	publicKey, privateKey := cm.config.PublicKey, cm.config.PrivateKey
+
+	for _, name := range []string{KeyStoreSystem, KeyStoreFile} {
+		if name == previous {
+			return nil
+		}
+
+		store, err := cm.openKeyStore(name)
+		if err == nil {
+			err = store.Set(publicKey, privateKey)
+		}
+		if err == nil {
+			var stored string
+			if stored, err = store.Get(publicKey); err == nil && stored != privateKey {
+				err = errors.New("the stored key does not match")
+			}
+		}
+		if err != nil {
+			// Sem cofre do sistema, quem já usa o arquivo não precisa do aviso a cada início
+			if !errors.Is(err, ErrKeyStoreUnavailable) || previous != KeyStoreFile {
+				logger.Warn("Cannot store the private key", "keyStore", name, "error", err)
+			}
+			continue
+		}
+
+		cm.config.KeyStore = name
+		if err := cm.SaveConfig(); err != nil {
+			cm.config.KeyStore = previous
+			return err
+		}
+		if previous != "" {
+			if old, err := cm.openKeyStore(previous); err == nil {
+				old.Delete(publicKey)
+			}
+		}
+		logger.Info("Private key moved", "keyStore", name)
+		return nil
+	}
+
+	return errors.New("no key store could hold the private key")
+}
+
+// GetDataPath returns the data directory path
+func (cm *ConfigManager) GetDataPath() string {
+	return cm.dataPath
+}
+
+// SaveConfig salva as configurações no arquivo
+func (cm *ConfigManager) SaveConfig() error {
+	// Trancada, a configuração na memória é a padrão e apagaria a do arquivo
+	if cm.lockErr != nil {
+		return cm.lockErr
+	}
+
+	// Guardada num cofre, a chave privada fica só na memória
+	config := cm.config
+	if config.KeyStore != "" {
+		config.PrivateKey = ""
+	}
+
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		logger.Error("Cannot encode the config file", "error", err)
+		return err
+	}
+
+	configPath := filepath.Join(cm.dataPath, "config.json")
+	if err := cm.sealer.writeFile(configPath, append(content, '\n'), 0600); err != nil {
+		logger.Error("Cannot write the config file", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// Locked retorna por que a configuração cifrada ainda não foi lida: ErrConfigLocked
+// enquanto falta a senha, ou o erro do cofre do sistema. Nil depois de aberta.
+func (cm *ConfigManager) Locked() error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	return cm.lockErr
+}
+
+// Unlock abre a configuração cifrada com a senha e a carrega. No modo do cofre do sistema
+// a senha é ignorada e a leitura da chave é tentada de novo.
+func (cm *ConfigManager) Unlock(passphrase string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	content, err := os.ReadFile(filepath.Join(cm.dataPath, "config.json"))
+	if err != nil {
+		return err
+	}
+	if sealed, ok := parseSealed(content); ok {
+		sealer, err := openSealer(sealed, passphrase)
+		if err != nil {
+			return err
+		}
+		if _, err := sealer.open("config.json", content); err != nil {
+			if sealed.KeySource == EncryptionPassphrase {
+				return ErrWrongPassphrase
+			}
+			return err
+		}
+		cm.sealer = sealer
+	}
+
+	cm.LoadConfig()
+	return cm.lockErr
+}
+
+// Encryption retorna o modo de cifra da pasta de dados
+func (cm *ConfigManager) Encryption() string {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	return cm.sealer.source
+}
+
+// SetEncryption cifra de novo config.json, private.key e os arquivos de sealedDataDirs com o
+// modo pedido, ou os grava em texto puro com EncryptionNone. A senha só é usada em
+// EncryptionPassphrase.
+func (cm *ConfigManager) SetEncryption(source, passphrase string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.lockErr != nil {
+		return cm.lockErr
+	}
+
+	// O arquivo da chave é lido com a cifra antiga antes da troca
+	keyPath := filepath.Join(cm.dataPath, keyFileName)
+	keyContent, err := cm.sealer.readFile(keyPath)
+	hasKeyFile := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	sealer, err := newSealer(source, passphrase)
+	if err != nil {
+		return err
+	}
+	dataFiles := cm.readDataFiles()
+	previous := cm.sealer
+	cm.sealer = sealer
+
+	if hasKeyFile {
+		if err := sealer.writeFile(keyPath, keyContent, 0600); err != nil {
+			cm.sealer = previous
+			sealer.forget()
+			return err
+		}
+	}
+	if err := cm.SaveConfig(); err != nil {
+		cm.sealer = previous
+		if hasKeyFile {
+			previous.writeFile(keyPath, keyContent, 0600)
+		}
+		sealer.forget()
+		return err
+	}
+
+	for path, plain := range dataFiles {
+		if err := sealer.writeFile(path, plain, 0600); err != nil {
+			logger.Warn("Cannot write data file", "path", path, "error", err)
+		}
+	}
+
+	previous.forget()
+	logger.Info("Config encryption changed", "from", previous.source, "to", source)
+	return nil
+}
@@ -0,0 +1,285 @@
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// A pasta de dados pode ser cifrada: config.json e private.key passam a guardar um
+// envelope JSON com o conteúdo cifrado por XChaCha20-Poly1305. A chave vem de uma senha,
+// pedida a cada início, ou de uma chave aleatória guardada no cofre do sistema, que abre
+// sozinha na sessão do usuário. Arquivos ainda em texto puro continuam sendo lidos e são
+// cifrados ao ligar a cifra.
+
+// Modos de cifra da pasta de dados
+const (
+	EncryptionNone       = ""
+	EncryptionPassphrase = "passphrase"
+	EncryptionSystem     = "system"
+)
+
+// Parâmetros do Argon2id que derivam a chave da senha
+const (
+	argonTime    = 1
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+	argonSaltLen = 16
+)
+
+// sealedVersion é a versão do envelope, gravada em cada arquivo cifrado
+const sealedVersion = 1
+
+// configKeyAccount prefixa a conta da chave de cifra no cofre do sistema
+const configKeyAccount = "config:"
+
+// ErrConfigLocked é retornado enquanto a configuração cifrada com senha não foi aberta
+var ErrConfigLocked = errors.New("the configuration is encrypted with a passphrase")
+
+// ErrWrongPassphrase é retornado quando a senha não abre a configuração
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// sealedFile é o envelope de um arquivo cifrado
+type sealedFile struct {
+	Version    int    `json:"govpn_sealed"`
+	KeySource  string `json:"key_source"`       // EncryptionPassphrase ou EncryptionSystem
+	Salt       []byte `json:"salt,omitempty"`   // Sal do Argon2id, no modo com senha
+	KeyID      string `json:"key_id,omitempty"` // Conta da chave no cofre, no modo do sistema
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// parseSealed reconhece o envelope; arquivos em texto puro não têm a versão
+func parseSealed(content []byte) (sealedFile, bool) {
+	var sealed sealedFile
+	if json.Unmarshal(content, &sealed) != nil || sealed.Version == 0 {
+		return sealedFile{}, false
+	}
+	return sealed, true
+}
+
+// fileSealer cifra os arquivos da pasta de dados; sem modo, grava em texto puro
+type fileSealer struct {
+	source string
+	salt   []byte
+	keyID  string
+	key    []byte
+}
+
+// newSealer cria a cifra de um modo com sal ou chave novos. No modo do sistema a chave já
+// fica guardada no cofre.
+func newSealer(source, passphrase string) (fileSealer, error) {
+	switch source {
+	case EncryptionNone:
+		return fileSealer{}, nil
+	case EncryptionPassphrase:
+		if passphrase == "" {
+			return fileSealer{}, errors.New("the passphrase cannot be empty")
+		}
+		salt := make([]byte, argonSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return fileSealer{}, err
+		}
+		return fileSealer{source: source, salt: salt, key: deriveConfigKey(passphrase, salt)}, nil
+	case EncryptionSystem:
+		store, err := systemKeyStore()
+		if err != nil {
+			return fileSealer{}, err
+		}
+		id := make([]byte, 8)
+		key := make([]byte, chacha20poly1305.KeySize)
+		if _, err := rand.Read(id); err != nil {
+			return fileSealer{}, err
+		}
+		if _, err := rand.Read(key); err != nil {
+			return fileSealer{}, err
+		}
+		keyID := hex.EncodeToString(id)
+		if err := store.Set(configKeyAccount+keyID, base64.StdEncoding.EncodeToString(key)); err != nil {
+			return fileSealer{}, fmt.Errorf("storing the configuration key: %v", err)
+		}
+		return fileSealer{source: source, keyID: keyID, key: key}, nil
+	default:
+		return fileSealer{}, fmt.Errorf("unknown encryption mode %q", source)
+	}
+}
+
+// openSealer recupera a chave que cifrou um envelope. A senha só é usada no modo com senha;
+// vazia, o retorno é ErrConfigLocked.
+func openSealer(sealed sealedFile, passphrase string) (fileSealer, error) {
+	s := fileSealer{source: sealed.KeySource, salt: sealed.Salt, keyID: sealed.KeyID}
+	switch sealed.KeySource {
+	case EncryptionPassphrase:
+		if passphrase == "" {
+			return s, ErrConfigLocked
+		}
+		s.key = deriveConfigKey(passphrase, sealed.Salt)
+	case EncryptionSystem:
+		store, err := systemKeyStore()
+		if err != nil {
+			return s, err
+		}
+		encoded, err := store.Get(configKeyAccount + sealed.KeyID)
+		if err != nil {
+			return s, fmt.Errorf("reading the configuration key: %v", err)
+		}
+		if s.key, err = base64.StdEncoding.DecodeString(encoded); err != nil || len(s.key) != chacha20poly1305.KeySize {
+			return s, errors.New("invalid configuration key in the key store")
+		}
+	default:
+		return s, fmt.Errorf("unknown encryption mode %q", sealed.KeySource)
+	}
+	return s, nil
+}
+
+// deriveConfigKey deriva a chave de cifra de uma senha
+func deriveConfigKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, chacha20poly1305.KeySize)
+}
+
+// forget apaga do cofre do sistema a chave de um modo que deixou de ser usado
+func (s fileSealer) forget() {
+	if s.source != EncryptionSystem {
+		return
+	}
+	if store, err := systemKeyStore(); err == nil {
+		store.Delete(configKeyAccount + s.keyID)
+	}
+}
+
+// seal cifra o conteúdo de um arquivo. O nome entra como dado autenticado, para um arquivo
+// não passar pelo outro.
+func (s fileSealer) seal(name string, plain []byte) ([]byte, error) {
+	if s.source == EncryptionNone {
+		return plain, nil
+	}
+	aead, err := chacha20poly1305.NewX(s.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(sealedFile{
+		Version:    sealedVersion,
+		KeySource:  s.source,
+		Salt:       s.salt,
+		KeyID:      s.keyID,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plain, []byte(name)),
+	}, "", "  ")
+}
+
+// open decifra o conteúdo de um arquivo, que volta como está se estiver em texto puro
+func (s fileSealer) open(name string, content []byte) ([]byte, error) {
+	sealed, ok := parseSealed(content)
+	if !ok {
+		return content, nil
+	}
+	if s.key == nil || sealed.KeySource != s.source || sealed.KeyID != s.keyID || !bytes.Equal(sealed.Salt, s.salt) {
+		return nil, fmt.Errorf("%s is encrypted with another key", name)
+	}
+
+	aead, err := chacha20poly1305.NewX(s.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%s is damaged", name)
+	}
+	plain, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt %s", name)
+	}
+	return plain, nil
+}
+
+// readFile lê e decifra um arquivo da pasta de dados
+func (s fileSealer) readFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.open(filepath.Base(path), content)
+}
+
+// writeFile cifra e grava um arquivo da pasta de dados. A troca pelo arquivo temporário
+// evita que uma queda no meio deixe o arquivo pela metade.
+func (s fileSealer) writeFile(path string, plain []byte, perm os.FileMode) error {
+	content, err := s.seal(filepath.Base(path), plain)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// sealedDataDirs são as pastas da pasta de dados cujos arquivos seguem a cifra da configuração
+var sealedDataDirs = []string{chatDir, trafficDir, networksDir}
+
+// ReadDataFile lê um arquivo da pasta de dados, com o caminho relativo a ela, decifrando-o
+// com a cifra da configuração
+func (cm *ConfigManager) ReadDataFile(name string) ([]byte, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.lockErr != nil {
+		return nil, cm.lockErr
+	}
+	return cm.sealer.readFile(filepath.Join(cm.dataPath, name))
+}
+
+// WriteDataFile grava um arquivo da pasta de dados com a cifra da configuração, criando a
+// pasta dele se preciso
+func (cm *ConfigManager) WriteDataFile(name string, content []byte) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.lockErr != nil {
+		return cm.lockErr
+	}
+	path := filepath.Join(cm.dataPath, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return cm.sealer.writeFile(path, content, 0600)
+}
+
+// readDataFiles lê com a cifra atual os arquivos de sealedDataDirs, para gravá-los de novo
+// depois de uma troca de cifra. Os que não abrem ficam como estão.
+func (cm *ConfigManager) readDataFiles() map[string][]byte {
+	files := make(map[string][]byte)
+	for _, dir := range sealedDataDirs {
+		paths, _ := filepath.Glob(filepath.Join(cm.dataPath, dir, "*.json"))
+		for _, path := range paths {
+			plain, err := cm.sealer.readFile(path)
+			if err != nil {
+				logger.Warn("Cannot read data file", "path", path, "error", err)
+				continue
+			}
+			files[path] = plain
+		}
+	}
+	return files
+}
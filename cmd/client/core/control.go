@@ -0,0 +1,314 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// A API de controle deixa scripts, launchers de jogos e outros programas comandarem o
+// cliente em execução, seja o aplicativo ou o govpn-cli daemon. Ela fala JSON-RPC 2.0 num
+// socket local da pasta de dados (um named pipe no Windows), uma mensagem por linha:
+//
+//	{"jsonrpc":"2.0","id":1,"method":"connect","params":{"network_id":"..."}}
+//
+// Métodos: status, list, connect {network_id}, disconnect e open_invite {url}. Connect e
+// disconnect respondem com o status depois da mudança; open_invite mostra um link de
+// convite no aplicativo, que um segundo processo aberto pelo link repassa a ele.
+
+// ErrControlInUse é retornado quando outro cliente já atende a API com a mesma pasta de dados
+var ErrControlInUse = errors.New("another GoVPN client is already running with this configuration")
+
+// ErrControlUnavailable é retornado quando nenhum cliente atende a API com a pasta de dados
+var ErrControlUnavailable = errors.New("no GoVPN client is running with this configuration")
+
+// controlDialTimeout limita a espera pelo cliente em execução
+const controlDialTimeout = 2 * time.Second
+
+// Códigos de erro do JSON-RPC 2.0; controlServerError cobre as falhas do próprio cliente
+const (
+	controlParseError     = -32700
+	controlInvalidRequest = -32600
+	controlMethodNotFound = -32601
+	controlInvalidParams  = -32602
+	controlServerError    = -32000
+)
+
+// controlRequest é uma chamada; sem ID é uma notificação e não tem resposta
+type controlRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// controlResponse responde uma chamada com o resultado ou o erro
+type controlResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *ControlError   `json:"error,omitempty"`
+}
+
+// ControlError é o erro de uma chamada à API de controle
+type ControlError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ControlError) Error() string {
+	return e.Message
+}
+
+// ControlStatus é a resposta de status
+type ControlStatus struct {
+	ComputerName string `json:"computer_name"`
+	PublicKey    string `json:"public_key"`
+	Server       string `json:"server"`
+	ServerState  string `json:"server_state"` // connected, connecting ou disconnected
+	NetworkID    string `json:"network_id,omitempty"`
+	Address      string `json:"address,omitempty"`
+}
+
+// ControlNetwork é uma das redes deste computador na resposta de list
+type ControlNetwork struct {
+	NetworkID string `json:"network_id"`
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	Online    int    `json:"online"`
+	Computers int    `json:"computers"`
+	Connected bool   `json:"connected"`
+}
+
+// ListenControl abre o socket da API de controle na pasta de dados
+func (v *VPNClient) ListenControl() (net.Listener, error) {
+	return listenControl(ControlAddress(v.ConfigManager.GetDataPath()))
+}
+
+// ServeControl atende a API de controle em ln até ele ser fechado.
+// SetupNetworkManager precisa ter sido chamado antes.
+func (v *VPNClient) ServeControl(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		crash.Go("control connection", func() { v.serveControlConn(conn) })
+	}
+}
+
+// serveControlConn responde as chamadas de uma conexão até ela fechar
+func (v *VPNClient) serveControlConn(conn net.Conn) {
+	defer conn.Close()
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		var req controlRequest
+		if err := decoder.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) {
+				encoder.Encode(controlResponse{
+					JSONRPC: "2.0",
+					Error:   &ControlError{Code: controlParseError, Message: err.Error()},
+				})
+			}
+			return
+		}
+
+		res := v.handleControl(req)
+		if req.ID == nil {
+			continue
+		}
+		if err := encoder.Encode(res); err != nil {
+			return
+		}
+	}
+}
+
+// handleControl executa uma chamada
+func (v *VPNClient) handleControl(req controlRequest) controlResponse {
+	var result interface{}
+	var err error
+
+	switch {
+	case req.JSONRPC != "2.0" || req.Method == "":
+		err = &ControlError{Code: controlInvalidRequest, Message: "invalid JSON-RPC 2.0 request"}
+	case req.Method == "status":
+		result = v.controlStatus()
+	case req.Method == "list":
+		result = v.controlList()
+	case req.Method == "connect":
+		var params struct {
+			NetworkID string `json:"network_id"`
+		}
+		if json.Unmarshal(req.Params, &params) != nil || params.NetworkID == "" {
+			err = &ControlError{Code: controlInvalidParams, Message: "connect needs the network_id parameter"}
+		} else {
+			result, err = v.controlConnect(params.NetworkID)
+		}
+	case req.Method == "disconnect":
+		result, err = v.controlDisconnect()
+	case req.Method == "open_invite":
+		var params struct {
+			URL string `json:"url"`
+		}
+		if json.Unmarshal(req.Params, &params) != nil || params.URL == "" {
+			err = &ControlError{Code: controlInvalidParams, Message: "open_invite needs the url parameter"}
+		} else {
+			result, err = v.controlOpenInvite(params.URL)
+		}
+	default:
+		err = &ControlError{Code: controlMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+
+	res := controlResponse{JSONRPC: "2.0", ID: req.ID}
+	if err != nil {
+		var controlErr *ControlError
+		if !errors.As(err, &controlErr) {
+			controlErr = &ControlError{Code: controlServerError, Message: err.Error()}
+		}
+		logger.Warn("Control API call failed", "method", req.Method, "error", err)
+		res.Error = controlErr
+	} else {
+		res.Result = result
+	}
+	return res
+}
+
+// controlStatus descreve a conexão com o servidor e com a rede atual
+func (v *VPNClient) controlStatus() ControlStatus {
+	realtimeData := v.NetworkManager.RealtimeData
+	computerName := realtimeData.GetComputerName()
+	server := realtimeData.GetServerAddress()
+
+	status := ControlStatus{
+		ComputerName: computerName,
+		PublicKey:    v.PublicKeyStr,
+		Server:       server,
+	}
+	switch realtimeData.GetConnectionState() {
+	case data.StateConnected:
+		status.ServerState = "connected"
+	case data.StateConnecting:
+		status.ServerState = "connecting"
+	default:
+		status.ServerState = "disconnected"
+	}
+	if networkID := v.NetworkManager.NetworkID; networkID != "" {
+		status.NetworkID = networkID
+		status.Address = realtimeData.GetComputerIP()
+	}
+	return status
+}
+
+// controlList lista as redes conhecidas, sem consultar o servidor
+func (v *VPNClient) controlList() []ControlNetwork {
+	networks := v.NetworkManager.RealtimeData.GetNetworks()
+	list := make([]ControlNetwork, 0, len(networks))
+	for _, network := range networks {
+		entry := ControlNetwork{
+			NetworkID: network.NetworkID,
+			Name:      network.NetworkName,
+			Address:   network.ComputerIP,
+			Computers: len(network.Computers),
+			Connected: network.NetworkID == v.NetworkManager.NetworkID,
+		}
+		for _, computer := range network.Computers {
+			if computer.PublicKey == v.PublicKeyStr && entry.Address == "" {
+				entry.Address = computer.ComputerIP
+			}
+			if computer.IsOnline {
+				entry.Online++
+			}
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+// controlConnect conecta a uma rede, saindo antes da rede atual se for outra
+func (v *VPNClient) controlConnect(networkID string) (ControlStatus, error) {
+	v.controlMu.Lock()
+	defer v.controlMu.Unlock()
+
+	nm := v.NetworkManager
+	if current := nm.NetworkID; current != networkID {
+		if current != "" {
+			if err := nm.DisconnectNetwork(current); err != nil {
+				return ControlStatus{}, err
+			}
+		}
+		if err := nm.ConnectNetwork(networkID); err != nil {
+			return ControlStatus{}, err
+		}
+	}
+	return v.controlStatus(), nil
+}
+
+// controlDisconnect desconecta da rede atual, se houver uma
+func (v *VPNClient) controlDisconnect() (ControlStatus, error) {
+	v.controlMu.Lock()
+	defer v.controlMu.Unlock()
+
+	if current := v.NetworkManager.NetworkID; current != "" {
+		if err := v.NetworkManager.DisconnectNetwork(current); err != nil {
+			return ControlStatus{}, err
+		}
+	}
+	return v.controlStatus(), nil
+}
+
+// controlOpenInvite entrega um link de convite à interface
+func (v *VPNClient) controlOpenInvite(link string) (map[string]string, error) {
+	if v.OnInvite == nil {
+		return nil, errors.New("this client has no window to open invites in")
+	}
+	invite, err := ParseInvite(link)
+	if err != nil {
+		return nil, &ControlError{Code: controlInvalidParams, Message: err.Error()}
+	}
+	v.OnInvite(invite)
+	return map[string]string{"network_id": invite.NetworkID}, nil
+}
+
+// CallControl chama um método da API de controle do cliente que roda com a pasta de dados
+// dataPath e retorna o resultado sem decodificar. params pode ser nil.
+func CallControl(dataPath, method string, params interface{}) (json.RawMessage, error) {
+	conn, err := dialControl(ControlAddress(dataPath))
+	if err != nil {
+		return nil, ErrControlUnavailable
+	}
+	defer conn.Close()
+
+	req := controlRequest{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: method}
+	if params != nil {
+		if req.Params, err = json.Marshal(params); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Result json.RawMessage `json:"result"`
+		Error  *ControlError   `json:"error"`
+	}
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		return nil, fmt.Errorf("invalid control API response: %v", err)
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return res.Result, nil
+}
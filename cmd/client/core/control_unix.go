@@ -0,0 +1,40 @@
+//go:build !windows
+
+package core
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// ControlAddress é o socket da API de controle do cliente que usa a pasta de dados dataPath
+func ControlAddress(dataPath string) string {
+	return filepath.Join(dataPath, "control.sock")
+}
+
+// listenControl abre o socket, acessível só ao usuário dono da pasta de dados. Um socket
+// que sobrou de um cliente encerrado sem fechá-lo é substituído.
+func listenControl(address string) (net.Listener, error) {
+	if conn, err := dialControl(address); err == nil {
+		conn.Close()
+		return nil, ErrControlInUse
+	}
+	if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", address)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(address, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+func dialControl(address string) (net.Conn, error) {
+	return net.DialTimeout("unix", address, controlDialTimeout)
+}
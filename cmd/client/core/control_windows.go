@@ -0,0 +1,33 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// ControlAddress é o named pipe da API de controle do cliente que usa a pasta de dados
+// dataPath. O nome vem de um hash da pasta, já que pipes não ficam dentro de pastas.
+func ControlAddress(dataPath string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(filepath.Clean(dataPath))))
+	return `\\.\pipe\govpn-control-` + hex.EncodeToString(sum[:8])
+}
+
+// listenControl abre o named pipe com o descritor padrão do processo, que dá acesso só ao
+// próprio usuário, ao sistema e aos administradores
+func listenControl(address string) (net.Listener, error) {
+	if conn, err := dialControl(address); err == nil {
+		conn.Close()
+		return nil, ErrControlInUse
+	}
+	return winio.ListenPipe(address, nil)
+}
+
+func dialControl(address string) (net.Conn, error) {
+	timeout := controlDialTimeout
+	return winio.DialPipe(address, &timeout)
+}
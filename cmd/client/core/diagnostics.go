@@ -0,0 +1,267 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+	"time"
+
+	clientwebrtc_impl "github.com/itxtoledo/govpn/cmd/client/webrtc"
+	"github.com/itxtoledo/govpn/libs/logger"
+	sclient "github.com/itxtoledo/govpn/libs/signaling/client"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// Diagnóstico da conexão: testa em ordem o servidor de sinalização, cada servidor STUN e
+// TURN, o tipo de NAT e um canal de dados local, e monta um relatório em texto para colar
+// em issues. Um teste que falha não impede os seguintes. Os endereços públicos entram no
+// relatório pela metade, o bastante para ver se mudam sem expor o computador.
+
+const (
+	// diagnosticTimeout limita cada teste do diagnóstico
+	diagnosticTimeout = 5 * time.Second
+	// loopbackTimeout limita o teste do canal de dados, que junta candidatos dos dois lados
+	loopbackTimeout = 15 * time.Second
+)
+
+// DiagnosticStatus é o resultado de um teste do diagnóstico
+type DiagnosticStatus string
+
+const (
+	DiagnosticPass DiagnosticStatus = "PASS"
+	DiagnosticWarn DiagnosticStatus = "WARN" // Funciona, mas limita as conexões diretas
+	DiagnosticFail DiagnosticStatus = "FAIL"
+	DiagnosticSkip DiagnosticStatus = "SKIP" // Não se aplica ou depende de algo ausente
+)
+
+// DiagnosticCheck é um teste do diagnóstico com o resultado
+type DiagnosticCheck struct {
+	Name   string
+	Status DiagnosticStatus
+	Detail string
+	Hint   string // O que fazer, quando o resultado não é PASS
+}
+
+// DiagnosticReport é o resultado de todos os testes
+type DiagnosticReport struct {
+	Version string
+	Started time.Time
+	Checks  []DiagnosticCheck
+}
+
+// Failed conta os testes que falharam
+func (r DiagnosticReport) Failed() int {
+	failed := 0
+	for _, check := range r.Checks {
+		if check.Status == DiagnosticFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Text formata o relatório para colar numa issue
+func (r DiagnosticReport) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "goVPN diagnostics\n")
+	fmt.Fprintf(&b, "Version: %s (%s/%s)\n", r.Version, runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "Date:    %s\n\n", r.Started.UTC().Format("2006-01-02 15:04:05 UTC"))
+	for _, check := range r.Checks {
+		fmt.Fprintf(&b, "[%s] %s: %s\n", check.Status, check.Name, check.Detail)
+		if check.Hint != "" && check.Status != DiagnosticPass {
+			fmt.Fprintf(&b, "       %s\n", check.Hint)
+		}
+	}
+	fmt.Fprintf(&b, "\n%d of %d checks failed\n", r.Failed(), len(r.Checks))
+	return b.String()
+}
+
+// RunDiagnostics roda os testes da conexão, chamando progress a cada teste terminado.
+// defaultWebsocketURL é o servidor quando a configuração não tem um, como em Connect.
+func (v *VPNClient) RunDiagnostics(version, defaultWebsocketURL string, progress func(DiagnosticCheck)) DiagnosticReport {
+	report := DiagnosticReport{Version: version, Started: time.Now()}
+	add := func(check DiagnosticCheck) {
+		logger.Info("Diagnostic check", "name", check.Name, "status", check.Status, "detail", check.Detail)
+		report.Checks = append(report.Checks, check)
+		if progress != nil {
+			progress(check)
+		}
+	}
+
+	config := v.ConfigManager.GetConfig()
+	add(v.diagnoseSignaling(config.ServerAddress, defaultWebsocketURL))
+
+	options := config.WebRTCOptions()
+	servers := options.ICEServers
+	if len(servers) == 0 {
+		servers = clientwebrtc_impl.DefaultICEServers
+	}
+	hasTURN := false
+	for _, server := range servers {
+		hasTURN = hasTURN || server.IsTURN()
+		add(diagnoseICEServer(server))
+	}
+	if !hasTURN {
+		add(DiagnosticCheck{
+			Name:   "TURN allocation",
+			Status: DiagnosticSkip,
+			Detail: "no TURN server configured",
+			Hint:   "Peers that cannot connect directly go through the server relay, when it offers one.",
+		})
+	}
+
+	add(v.diagnoseNAT())
+	add(diagnoseLoopback(options))
+	return report
+}
+
+// diagnoseSignaling mede o tempo até o servidor de sinalização e confere a sessão aberta
+func (v *VPNClient) diagnoseSignaling(configured, defaultWebsocketURL string) DiagnosticCheck {
+	serverAddress := configured
+	connected := false
+	if nm := v.NetworkManager; nm != nil && nm.SignalingServer != nil && nm.SignalingServer.IsConnected() {
+		serverAddress = nm.SignalingServer.ServerAddress
+		connected = true
+	}
+	if serverAddress == "" {
+		serverAddress = defaultWebsocketURL
+	}
+
+	check := DiagnosticCheck{Name: "Signaling server"}
+	latency, err := sclient.PingServer(serverAddress, diagnosticTimeout)
+	if err != nil {
+		check.Status = DiagnosticFail
+		check.Detail = fmt.Sprintf("%s unreachable: %v", serverAddress, err)
+		check.Hint = "Check the server address in Settings and whether a firewall or proxy blocks it."
+		if connected {
+			// A sessão aberta mostra que o servidor responde; só o /ping falhou
+			check.Status = DiagnosticWarn
+			check.Detail = fmt.Sprintf("%s connected, but the ping failed: %v", serverAddress, err)
+			check.Hint = "The server may be older than this client or behind a proxy that only passes WebSockets."
+		}
+		return check
+	}
+
+	check.Status = DiagnosticPass
+	check.Detail = fmt.Sprintf("%s reachable in %d ms", serverAddress, latency.Milliseconds())
+	if connected {
+		check.Detail += ", session open"
+	}
+	return check
+}
+
+// diagnoseICEServer confere se um servidor STUN devolve o endereço público ou se um TURN
+// reserva um endereço de relay
+func diagnoseICEServer(server clientwebrtc_impl.ICEServer) DiagnosticCheck {
+	check := DiagnosticCheck{Name: "STUN " + server.URL}
+	if server.IsTURN() {
+		check.Name = "TURN " + server.URL
+	}
+
+	address, err := clientwebrtc_impl.ProbeICEServer(server, diagnosticTimeout)
+	switch {
+	case err != nil && server.IsTURN():
+		check.Status = DiagnosticFail
+		check.Detail = err.Error()
+		check.Hint = "Check the URL, username and password of the TURN server in Settings."
+	case err != nil:
+		check.Status = DiagnosticFail
+		check.Detail = err.Error()
+		check.Hint = "UDP to the STUN server may be blocked; direct connections will likely fail."
+	case server.IsTURN():
+		check.Status = DiagnosticPass
+		check.Detail = "relayed address " + redactAddress(address)
+	default:
+		check.Status = DiagnosticPass
+		check.Detail = "public address " + redactAddress(address)
+	}
+	return check
+}
+
+// diagnoseNAT classifica o NAT com a sonda do servidor, que só é conhecida com a sessão aberta
+func (v *VPNClient) diagnoseNAT() DiagnosticCheck {
+	check := DiagnosticCheck{Name: "NAT type", Status: DiagnosticSkip}
+	nm := v.NetworkManager
+	if nm == nil || nm.SignalingServer == nil || !nm.SignalingServer.IsConnected() {
+		check.Detail = "not connected to the server"
+		check.Hint = "Connect to the server and run the diagnostics again to detect the NAT type."
+		return check
+	}
+
+	detection, err := nm.SignalingServer.DetectNAT(natProbeTimeout)
+	if errors.Is(err, sclient.ErrNoNATProbe) {
+		check.Detail = "the server has no NAT probe"
+		return check
+	}
+	if err != nil {
+		check.Status = DiagnosticFail
+		check.Detail = err.Error()
+		return check
+	}
+
+	check.Detail = NATTypeLabel(detection.Type)
+	if detection.MappedAddress != "" {
+		check.Detail += ", seen as " + redactAddress(detection.MappedAddress)
+	}
+	switch detection.Type {
+	case smodels.NatTypeSymmetric:
+		check.Status = DiagnosticWarn
+		check.Hint = "Direct connections to other symmetric or port restricted NATs will fail; they need TURN or the server relay."
+	case smodels.NatTypeUDPBlocked:
+		check.Status = DiagnosticFail
+		check.Hint = "UDP is blocked, so peers can only be reached through TURN over TCP or the server relay."
+	case smodels.NatTypeUnknown:
+		check.Status = DiagnosticWarn
+	default:
+		check.Status = DiagnosticPass
+	}
+	return check
+}
+
+// diagnoseLoopback abre um canal de dados entre duas conexões locais e mede o eco
+func diagnoseLoopback(options clientwebrtc_impl.Options) DiagnosticCheck {
+	check := DiagnosticCheck{Name: "Data channel loopback"}
+	rtt, err := clientwebrtc_impl.Loopback(options, loopbackTimeout)
+	if err != nil {
+		check.Status = DiagnosticFail
+		check.Detail = err.Error()
+		check.Hint = "A firewall or security tool may block the local UDP sockets WebRTC needs."
+		if options.RelayOnly {
+			check.Hint = "With \"relay only\" the loopback goes through TURN; check the TURN servers above."
+		}
+		return check
+	}
+
+	check.Status = DiagnosticPass
+	check.Detail = fmt.Sprintf("echo in %.2f ms", float64(rtt.Microseconds())/1000)
+	if options.RelayOnly {
+		check.Detail += " through TURN"
+	}
+	return check
+}
+
+// redactAddress esconde a metade final de um IP, mantendo a porta
+func redactAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, ""
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+	case ip.To4() != nil:
+		parts := strings.Split(ip.To4().String(), ".")
+		host = parts[0] + "." + parts[1] + ".x.x"
+	default:
+		parts := strings.Split(ip.String(), ":")
+		host = strings.Join(parts[:min(2, len(parts))], ":") + ":…"
+	}
+
+	if port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// HostNames dá a cada computador da rede o nome resolvido pelo servidor DNS da VPN, como
+// "maria-pc.govpn", pela chave pública. Computadores com o mesmo nome recebem todos o fim
+// do IP virtual, como "pc-3.govpn" e "pc-7.govpn", para nenhum depender da ordem de entrada.
+// Os sem IP ou sem letras e dígitos no nome ficam de fora.
+func HostNames(computers []smodels.ComputerInfo) map[string]string {
+	owners := make(map[string]int)
+	for _, computer := range computers {
+		if computer.ComputerIP != "" {
+			owners[network.DNSLabel(computer.Name)]++
+		}
+	}
+
+	names := make(map[string]string)
+	for _, computer := range computers {
+		label := network.DNSLabel(computer.Name)
+		ip := net.ParseIP(computer.ComputerIP).To4()
+		if label == "" || ip == nil {
+			continue
+		}
+		if owners[label] > 1 {
+			label = fmt.Sprintf("%s-%d", label, ip[3])
+		}
+		names[computer.PublicKey] = label + "." + network.DNSDomain
+	}
+	return names
+}
+
+// syncDNSRecords atualiza o servidor DNS com os computadores da rede atual
+func syncDNSRecords(dns *network.DNSServer, computers []smodels.ComputerInfo) {
+	names := HostNames(computers)
+	records := make(map[string]string, len(names))
+	for _, computer := range computers {
+		if name, ok := names[computer.PublicKey]; ok {
+			records[strings.TrimSuffix(name, "."+network.DNSDomain)] = computer.ComputerIP
+		}
+	}
+	dns.SetRecords(records)
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// secureSession retorna a sessão cifrada com um peer, criando-a no primeiro uso. A sessão
+// vive tanto quanto o caminho até o peer: trocar ou fechar a conexão WebRTC, ou passar a
+// retransmitir pelo servidor, a descarta.
+func (nm *NetworkManager) secureSession(peerPublicKey string) (*network.SecureSession, error) {
+	if nm.identity == nil {
+		return nil, errors.New("no identity key to authenticate the handshake")
+	}
+
+	nm.peersMu.Lock()
+	defer nm.peersMu.Unlock()
+
+	if session, ok := nm.sessions[peerPublicKey]; ok {
+		return session, nil
+	}
+
+	peerKey, err := base64.StdEncoding.DecodeString(peerPublicKey)
+	if err != nil || len(peerKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key of peer %s", peerPublicKey)
+	}
+
+	networkKeys := nm.ConfigManager.GetConfig().PINKeys(nm.NetworkID)
+	if len(networkKeys) == 0 {
+		return nil, fmt.Errorf("no key for the PIN of network %s, join it again with its PIN", nm.NetworkID)
+	}
+
+	session := network.NewSecureSession(nm.identity, ed25519.PublicKey(peerKey), nm.NetworkID, networkKeys, nm.isOfferer(peerPublicKey), func(frame []byte) error {
+		return nm.sendPeerFrame(peerPublicKey, frame)
+	})
+	nm.sessions[peerPublicKey] = session
+	return session, nil
+}
+
+// forgetSession descarta a sessão cifrada de um peer e o MTU do caminho antigo, que é
+// sondado de novo. Callers must hold peersMu.
+func (nm *NetworkManager) forgetSession(peerPublicKey string) {
+	delete(nm.sessions, peerPublicKey)
+
+	nm.tunnelMu.Lock()
+	prober := nm.mtu
+	nm.tunnelMu.Unlock()
+	if prober != nil {
+		prober.Reset(peerPublicKey)
+		nm.RealtimeData.SetPeerMTU(peerPublicKey, 0)
+	}
+}
+
+// startSecureSession começa o handshake com um peer assim que o canal de dados abre ou o
+// tráfego passa a ser retransmitido
+func (nm *NetworkManager) startSecureSession(peerPublicKey string) {
+	if !nm.isOfferer(peerPublicKey) {
+		return
+	}
+
+	session, err := nm.secureSession(peerPublicKey)
+	if err != nil {
+		logger.Error("Cannot encrypt traffic with peer", "peer", peerPublicKey, "error", err)
+		return
+	}
+	if err := session.Start(); err != nil {
+		logger.Warn("Failed to start handshake with peer", "peer", peerPublicKey, "error", err)
+	}
+}
+
+// sendPeerFrame entrega um frame ao canal de dados do peer, ou ao servidor quando o peer
+// é retransmitido, sem cifrar
+func (nm *NetworkManager) sendPeerFrame(peerPublicKey string, frame []byte) error {
+	if nm.IsRelayed(peerPublicKey) {
+		return nm.SignalingServer.SendRelayFrame(peerPublicKey, frame)
+	}
+	peer, ok := nm.peerConnection(peerPublicKey)
+	if !ok {
+		return network.ErrPeerUnreachable
+	}
+	return peer.SendPacket(frame)
+}
+
+// sendPeerFileFrame entrega um frame de transferência de arquivo pelo canal dos arquivos do
+// peer, ou ao servidor quando o peer é retransmitido
+func (nm *NetworkManager) sendPeerFileFrame(peerPublicKey string, frame []byte) error {
+	if nm.IsRelayed(peerPublicKey) {
+		return nm.SignalingServer.SendRelayFrame(peerPublicKey, frame)
+	}
+	peer, ok := nm.peerConnection(peerPublicKey)
+	if !ok {
+		return network.ErrPeerUnreachable
+	}
+	return peer.SendFile(frame)
+}
+
+// sendPeerDatagram entrega um frame que pode se perder pelo canal sem retransmissão do peer,
+// ou ao servidor quando o peer é retransmitido
+func (nm *NetworkManager) sendPeerDatagram(peerPublicKey string, frame []byte) error {
+	if nm.IsRelayed(peerPublicKey) {
+		return nm.SignalingServer.SendRelayFrame(peerPublicKey, frame)
+	}
+	peer, ok := nm.peerConnection(peerPublicKey)
+	if !ok {
+		return network.ErrPeerUnreachable
+	}
+	return peer.SendUnreliable(frame)
+}
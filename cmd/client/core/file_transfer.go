@@ -0,0 +1,141 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/cmd/client/network"
+)
+
+const (
+	// fileBufferLimit é quanto de um arquivo pode esperar na fila do canal de dados. Um
+	// limite baixo mantém a memória sob controle e impede que os frames do canal dos
+	// arquivos se adiantem demais aos dos outros canais, além da janela de replay.
+	fileBufferLimit = 256 * 1024
+	// fileBufferWait é quanto o envio espera a fila esvaziar antes de desistir do peer
+	fileBufferWait = 30 * time.Second
+)
+
+// errFileTransferStalled é retornado quando a fila do canal não esvazia em fileBufferWait
+var errFileTransferStalled = errors.New("the connection to the peer stalled")
+
+// errNoFileTransfers é retornado fora de uma rede, quando não há com quem trocar arquivos
+var errNoFileTransfers = errors.New("not connected to a network")
+
+// DownloadFolder é onde os arquivos recebidos dos peers são gravados: a pasta Downloads
+// do usuário ou, sem ela, a pasta downloads dentro da pasta de dados
+func (nm *NetworkManager) DownloadFolder() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		dir := filepath.Join(home, "Downloads")
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return filepath.Join(nm.ConfigManager.GetDataPath(), "downloads")
+}
+
+// fileTransfers retorna as transferências da rede atual, nil quando não conectado
+func (nm *NetworkManager) fileTransfers() *network.FileTransfers {
+	nm.tunnelMu.Lock()
+	defer nm.tunnelMu.Unlock()
+
+	return nm.files
+}
+
+// SendFile oferece um arquivo a um computador da rede atual
+func (nm *NetworkManager) SendFile(peerPublicKey, path string) (network.Transfer, error) {
+	files := nm.fileTransfers()
+	if files == nil {
+		return network.Transfer{}, errNoFileTransfers
+	}
+	if !nm.meshMembers()[peerPublicKey] {
+		return network.Transfer{}, fmt.Errorf("%s is not online", nm.computerName(nm.NetworkID, peerPublicKey))
+	}
+	return files.SendFile(peerPublicKey, path)
+}
+
+// AcceptFile aceita um arquivo oferecido, retomando o que já foi recebido dele
+func (nm *NetworkManager) AcceptFile(peerPublicKey string, id uint32) error {
+	files := nm.fileTransfers()
+	if files == nil {
+		return errNoFileTransfers
+	}
+	return files.Accept(peerPublicKey, id)
+}
+
+// DeclineFile recusa um arquivo oferecido
+func (nm *NetworkManager) DeclineFile(peerPublicKey string, id uint32) {
+	if files := nm.fileTransfers(); files != nil {
+		files.Decline(peerPublicKey, id)
+	}
+}
+
+// CancelTransfer interrompe uma transferência em andamento
+func (nm *NetworkManager) CancelTransfer(transfer network.Transfer) {
+	if files := nm.fileTransfers(); files != nil {
+		files.Cancel(transfer.Peer, transfer.ID, transfer.Sending)
+	}
+}
+
+// FileTransfers lista as transferências desde a conexão à rede atual
+func (nm *NetworkManager) FileTransfers() []network.Transfer {
+	files := nm.fileTransfers()
+	if files == nil {
+		return nil
+	}
+	return files.List()
+}
+
+// ClearFinishedTransfers tira da lista as transferências terminadas
+func (nm *NetworkManager) ClearFinishedTransfers() {
+	if files := nm.fileTransfers(); files != nil {
+		files.ClearFinished()
+	}
+}
+
+// sendFileFrame entrega um frame de transferência ao peer, esperando antes que a fila do
+// canal dos arquivos baixe de fileBufferLimit
+func (nm *NetworkManager) sendFileFrame(peerPublicKey string, frame []byte) error {
+	deadline := time.Now().Add(fileBufferWait)
+	for {
+		peer, ok := nm.peerConnection(peerPublicKey)
+		if !ok || peer.FileBufferedAmount() <= fileBufferLimit {
+			break
+		}
+		if time.Now().After(deadline) {
+			return errFileTransferStalled
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nm.sendTunnelFrame(peerPublicKey, frame)
+}
+
+// handleFileOffer avisa a interface de um arquivo oferecido, que espera AcceptFile ou DeclineFile
+func (nm *NetworkManager) handleFileOffer(transfer network.Transfer) {
+	message := fmt.Sprintf("%s wants to send you %s", nm.computerName(nm.NetworkID, transfer.Peer), transfer.Name)
+	nm.RealtimeData.EmitEvent(data.EventFileOffered, message, transfer)
+}
+
+// handleTransferFinished avisa a interface do fim de uma transferência
+func (nm *NetworkManager) handleTransferFinished(transfer network.Transfer) {
+	peerName := nm.computerName(nm.NetworkID, transfer.Peer)
+	var message string
+	switch {
+	case transfer.State == network.TransferDone && transfer.Sending:
+		message = fmt.Sprintf("%s received %s", peerName, transfer.Name)
+	case transfer.State == network.TransferDone:
+		message = fmt.Sprintf("Received %s from %s", filepath.Base(transfer.Path), peerName)
+	case transfer.State == network.TransferDeclined && transfer.Sending:
+		message = fmt.Sprintf("%s declined %s", peerName, transfer.Name)
+	case transfer.State == network.TransferFailed:
+		message = fmt.Sprintf("Transfer of %s failed: %s", transfer.Name, transfer.Error)
+	default:
+		message = fmt.Sprintf("Transfer of %s %s", transfer.Name, strings.ToLower(transfer.State.String()))
+	}
+	nm.RealtimeData.EmitEvent(data.EventFileTransferFinished, message, transfer)
+}
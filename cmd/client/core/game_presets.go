@@ -0,0 +1,118 @@
+package core
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/itxtoledo/govpn/cmd/client/network"
+)
+
+// Predefinições de jogos: as portas e se o jogo se descobre por broadcast na LAN. Ativadas
+// por rede, elas somam às configurações do transporte ao conectar: as portas passam a ser
+// compartilhadas com os peers, os broadcasts são replicados quando o jogo precisa e, com um
+// computador anfitrião escolhido, as portas locais são levadas até ele no modo sem TUN.
+
+// GamePreset é a predefinição de um jogo
+type GamePreset struct {
+	ID        string
+	Name      string
+	Ports     []network.SharedPort
+	Broadcast bool // O jogo acha as partidas por broadcast ou multicast na LAN
+}
+
+// GamePresets são os jogos conhecidos, por nome
+var GamePresets = []GamePreset{
+	{ID: "7dtd", Name: "7 Days to Die", Ports: []network.SharedPort{tcpPort(26900), udpPort(26900), udpPort(26901), udpPort(26902)}, Broadcast: true},
+	{ID: "aoe2", Name: "Age of Empires II", Ports: []network.SharedPort{udpPort(2300), tcpPort(2300), tcpPort(47624)}, Broadcast: true},
+	{ID: "cs", Name: "Counter-Strike / Source games", Ports: []network.SharedPort{udpPort(27015), tcpPort(27015)}, Broadcast: true},
+	{ID: "dst", Name: "Don't Starve Together", Ports: []network.SharedPort{udpPort(10999)}, Broadcast: true},
+	{ID: "factorio", Name: "Factorio", Ports: []network.SharedPort{udpPort(34197)}, Broadcast: true},
+	{ID: "minecraft-bedrock", Name: "Minecraft Bedrock", Ports: []network.SharedPort{udpPort(19132)}, Broadcast: true},
+	{ID: "minecraft", Name: "Minecraft Java", Ports: []network.SharedPort{tcpPort(25565)}, Broadcast: true},
+	{ID: "openttd", Name: "OpenTTD", Ports: []network.SharedPort{tcpPort(3979), udpPort(3979)}, Broadcast: true},
+	{ID: "zomboid", Name: "Project Zomboid", Ports: []network.SharedPort{udpPort(16261), udpPort(16262)}},
+	{ID: "stardew", Name: "Stardew Valley", Ports: []network.SharedPort{udpPort(24642)}, Broadcast: true},
+	{ID: "starcraft", Name: "StarCraft", Ports: []network.SharedPort{udpPort(6112)}, Broadcast: true},
+	{ID: "terraria", Name: "Terraria", Ports: []network.SharedPort{tcpPort(7777)}},
+	{ID: "valheim", Name: "Valheim", Ports: []network.SharedPort{udpPort(2456), udpPort(2457)}},
+	{ID: "warcraft3", Name: "Warcraft III", Ports: []network.SharedPort{tcpPort(6112), udpPort(6112)}, Broadcast: true},
+}
+
+// tcpPort e udpPort encurtam as portas da lista de predefinições
+func tcpPort(port int) network.SharedPort { return network.SharedPort{Protocol: "tcp", Port: port} }
+func udpPort(port int) network.SharedPort { return network.SharedPort{Protocol: "udp", Port: port} }
+
+// FindGamePreset procura uma predefinição pelo ID
+func FindGamePreset(id string) (GamePreset, bool) {
+	i := slices.IndexFunc(GamePresets, func(preset GamePreset) bool { return preset.ID == id })
+	if i < 0 {
+		return GamePreset{}, false
+	}
+	return GamePresets[i], true
+}
+
+// NetworkPreset é uma predefinição ativada numa rede
+type NetworkPreset struct {
+	Preset string `json:"preset"`
+	Host   string `json:"host,omitempty"` // IP virtual do computador que hospeda as partidas; vazio é este ou nenhum
+}
+
+// NetworkPresets retorna as predefinições ativadas numa rede
+func (c Config) NetworkPresets(networkID string) []NetworkPreset {
+	return c.GamePresets[networkID]
+}
+
+// tunnelSettings soma às configurações do transporte as predefinições ativadas na rede:
+// as portas compartilhadas, os redirecionamentos até os anfitriões e o broadcast. Os
+// redirecionamentos do usuário têm preferência sobre os das predefinições na mesma porta.
+func (c Config) tunnelSettings(networkID string) (shared []network.SharedPort, forwards []network.PortForward, broadcast bool) {
+	shared = slices.Clone(c.SharedPorts)
+	forwards = slices.Clone(c.PortForwards)
+	broadcast = c.LANBroadcast
+
+	for _, enabled := range c.NetworkPresets(networkID) {
+		preset, ok := FindGamePreset(enabled.Preset)
+		if !ok {
+			continue
+		}
+		broadcast = broadcast || preset.Broadcast
+		for _, port := range preset.Ports {
+			if !slices.Contains(shared, port) {
+				shared = append(shared, port)
+			}
+			if enabled.Host == "" || slices.ContainsFunc(forwards, func(f network.PortForward) bool {
+				return f.Protocol == port.Protocol && f.LocalPort == port.Port
+			}) {
+				continue
+			}
+			forwards = append(forwards, network.PortForward{Protocol: port.Protocol, LocalPort: port.Port, PeerIP: enabled.Host, RemotePort: port.Port})
+		}
+	}
+	return shared, forwards, broadcast
+}
+
+// SetNetworkPreset ativa uma predefinição numa rede, ou troca o anfitrião dela, ou a
+// desativa quando enabled é falso
+func (cm *ConfigManager) SetNetworkPreset(networkID string, preset NetworkPreset, enabled bool) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	presets := slices.DeleteFunc(slices.Clone(cm.config.GamePresets[networkID]), func(p NetworkPreset) bool {
+		return p.Preset == preset.Preset
+	})
+	if enabled {
+		presets = append(presets, preset)
+	}
+
+	all := maps.Clone(cm.config.GamePresets)
+	if all == nil {
+		all = make(map[string][]NetworkPreset)
+	}
+	if len(presets) == 0 {
+		delete(all, networkID)
+	} else {
+		all[networkID] = presets
+	}
+	cm.config.GamePresets = all
+	return cm.SaveConfig()
+}
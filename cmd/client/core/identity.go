@@ -0,0 +1,128 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// A identidade do computador é o par de chaves Ed25519: é ela que o servidor conhece como
+// membro das redes. Para levá-la a outro computador, a semente da chave privada é cifrada
+// com uma senha e vira um texto curto, que cabe num arquivo, na área de transferência ou
+// num QR code:
+//
+//	govpn-identity:<base64url de versão, sal, nonce e semente cifrada>
+
+// identityPrefix abre o texto de uma identidade exportada
+const identityPrefix = "govpn-identity:"
+
+// identityVersion é a versão do formato, o primeiro byte do texto decodificado
+const identityVersion = 1
+
+// identitySize é o tamanho do texto decodificado
+const identitySize = 1 + argonSaltLen + chacha20poly1305.NonceSizeX + ed25519.SeedSize + chacha20poly1305.Overhead
+
+// ErrInvalidIdentity é retornado quando o texto não é uma identidade exportada
+var ErrInvalidIdentity = errors.New("not a GoVPN identity")
+
+// ExportIdentity cifra a chave privada com a senha e retorna o texto da identidade
+func (cm *ConfigManager) ExportIdentity(passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", errors.New("the passphrase cannot be empty")
+	}
+
+	_, privateKeyStr := cm.GetKeyPair()
+	privateKey, err := base64.StdEncoding.DecodeString(privateKeyStr)
+	if err != nil || len(privateKey) != ed25519.PrivateKeySize {
+		return "", errors.New("this computer has no private key to export")
+	}
+
+	blob := make([]byte, 1+argonSaltLen+chacha20poly1305.NonceSizeX, identitySize)
+	blob[0] = identityVersion
+	salt := blob[1 : 1+argonSaltLen]
+	nonce := blob[1+argonSaltLen:]
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	aead, err := chacha20poly1305.NewX(deriveConfigKey(passphrase, salt))
+	if err != nil {
+		return "", err
+	}
+	blob = aead.Seal(blob, nonce, ed25519.PrivateKey(privateKey).Seed(), []byte(identityPrefix))
+	return identityPrefix + base64.RawURLEncoding.EncodeToString(blob), nil
+}
+
+// ImportIdentity decifra uma identidade exportada e a torna a deste computador, guardando
+// a chave privada como uma chave gerada aqui. As redes marcadas para conexão automática
+// eram da identidade anterior e são esquecidas. Retorna a chave pública importada; o
+// cliente em execução só passa a usá-la ao iniciar de novo.
+func (cm *ConfigManager) ImportIdentity(exported, passphrase string) (string, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(exported), identityPrefix)
+	if !ok {
+		return "", ErrInvalidIdentity
+	}
+	blob, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(blob) != identitySize {
+		return "", ErrInvalidIdentity
+	}
+	if blob[0] != identityVersion {
+		return "", fmt.Errorf("unsupported identity version %d", blob[0])
+	}
+
+	salt := blob[1 : 1+argonSaltLen]
+	nonce := blob[1+argonSaltLen : 1+argonSaltLen+chacha20poly1305.NonceSizeX]
+	aead, err := chacha20poly1305.NewX(deriveConfigKey(passphrase, salt))
+	if err != nil {
+		return "", err
+	}
+	seed, err := aead.Open(nil, nonce, blob[1+argonSaltLen+chacha20poly1305.NonceSizeX:], []byte(identityPrefix))
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	publicKeyStr := base64.StdEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.lockErr != nil {
+		return "", cm.lockErr
+	}
+	if cm.config.PublicKey == publicKeyStr {
+		return publicKeyStr, nil
+	}
+
+	previous := cm.config
+	cm.config.PublicKey = publicKeyStr
+	cm.config.PrivateKey = base64.StdEncoding.EncodeToString(privateKey)
+	cm.config.KeyStore = ""
+	cm.config.AutoConnectNetworks = nil
+	cm.config.LastNetworkID = ""
+
+	if cm.storePrivateKey() != nil {
+		if err := cm.SaveConfig(); err != nil {
+			cm.config = previous
+			return "", err
+		}
+	}
+
+	// A chave anterior não tem mais uso; quem quiser mantê-la a exporta antes
+	if previous.KeyStore != "" {
+		if old, err := cm.openKeyStore(previous.KeyStore); err == nil {
+			old.Delete(previous.PublicKey)
+		}
+	}
+	logger.Info("Imported identity", "publicKey", publicKeyStr[:10]+"...", "previous", previous.PublicKey[:min(10, len(previous.PublicKey))]+"...")
+	return publicKeyStr, nil
+}
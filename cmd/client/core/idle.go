@@ -0,0 +1,91 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// Desconexão por inatividade: com Config.IdleDisconnectMinutes, a rede é desconectada
+// quando nada passa pelo túnel nesse tempo, em nenhum sentido. Pings, sondas de MTU e os
+// broadcasts e multicasts que os sistemas enviam sozinhos não contam como uso.
+
+// idleCheckInterval é de quanto em quanto tempo a inatividade é conferida
+const idleCheckInterval = 30 * time.Second
+
+// idleMonitor desconecta a rede atual depois de timeout sem tráfego no túnel
+type idleMonitor struct {
+	nm        *NetworkManager
+	networkID string
+	timeout   time.Duration
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newIdleMonitor(nm *NetworkManager, timeout time.Duration) *idleMonitor {
+	nm.lastActivity.Store(time.Now().UnixNano())
+	return &idleMonitor{
+		nm:        nm,
+		networkID: nm.NetworkID,
+		timeout:   timeout,
+		done:      make(chan struct{}),
+	}
+}
+
+// Run confere a inatividade a cada idleCheckInterval até Close ou até desconectar
+func (m *idleMonitor) Run() {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			idle := now.Sub(time.Unix(0, m.nm.lastActivity.Load()))
+			if idle >= m.timeout {
+				crash.Go("idle disconnect", func() { m.disconnect(idle) })
+				return
+			}
+		}
+	}
+}
+
+// disconnect desconecta a rede e avisa o motivo
+func (m *idleMonitor) disconnect(idle time.Duration) {
+	if m.nm.NetworkID != m.networkID {
+		return
+	}
+	logger.Info("Disconnecting idle network", "networkID", m.networkID, "idle", idle.Round(time.Second))
+	if err := m.nm.DisconnectNetwork(m.networkID); err != nil {
+		logger.Warn("Failed to disconnect idle network", "networkID", m.networkID, "error", err)
+		return
+	}
+	m.nm.RealtimeData.EmitEvent(data.EventServerNotice, "idle disconnect", smodels.ServerNoticeNotification{
+		ID:      "idle-disconnect",
+		Message: fmt.Sprintf("Disconnected from %s after %d minutes without traffic.", m.nm.networkName(m.networkID), int(m.timeout.Minutes())),
+		Level:   smodels.NoticeLevelInfo,
+		SentAt:  time.Now(),
+	})
+}
+
+// Close para a conferência
+func (m *idleMonitor) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+}
+
+// markActivity registra o uso do túnel por um frame enviado ou recebido
+func (nm *NetworkManager) markActivity(frame []byte) {
+	if !network.Background(frame) {
+		nm.lastActivity.Store(time.Now().UnixNano())
+	}
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Um convite é um link govpn:// com o ID da rede, o servidor onde ela existe e,
+// se o dono quiser, o PIN:
+//
+//	govpn://join?network=<id>&server=<ws://host:port/ws>&pin=<pin>
+//
+// Com o esquema registrado no sistema, clicar no link abre o cliente com a janela de
+// entrada já preenchida.
+
+// InviteScheme é o esquema dos links de convite
+const InviteScheme = "govpn"
+
+// ErrInvalidInvite é retornado quando o texto não é um link de convite
+var ErrInvalidInvite = errors.New("not a GoVPN invite link")
+
+// Invite é o conteúdo de um link de convite
+type Invite struct {
+	NetworkID string
+	Server    string // Vazio quando o link não diz o servidor
+	PIN       string // Vazio quando o dono não incluiu o PIN
+}
+
+// URL monta o link do convite
+func (i Invite) URL() string {
+	query := url.Values{}
+	query.Set("network", i.NetworkID)
+	if i.Server != "" {
+		query.Set("server", i.Server)
+	}
+	if i.PIN != "" {
+		query.Set("pin", i.PIN)
+	}
+	return (&url.URL{Scheme: InviteScheme, Host: "join", RawQuery: query.Encode()}).String()
+}
+
+// ParseInvite lê um link de convite. Aceita também govpn:join?..., a forma que alguns
+// sistemas passam ao programa.
+func ParseInvite(link string) (Invite, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || !strings.EqualFold(u.Scheme, InviteScheme) {
+		return Invite{}, ErrInvalidInvite
+	}
+	action := u.Host
+	if action == "" {
+		action = u.Opaque
+	}
+	if !strings.EqualFold(action, "join") {
+		return Invite{}, ErrInvalidInvite
+	}
+
+	query := u.Query()
+	invite := Invite{
+		NetworkID: strings.TrimSpace(query.Get("network")),
+		Server:    query.Get("server"),
+		PIN:       query.Get("pin"),
+	}
+	if invite.NetworkID == "" {
+		return Invite{}, errors.New("the invite link has no network ID")
+	}
+	return invite, nil
+}
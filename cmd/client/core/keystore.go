@@ -0,0 +1,111 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A chave privada do computador não fica no config.json: ela vai para o cofre de senhas
+// do sistema (Keychain no macOS, Gerenciador de Credenciais no Windows, Secret Service no
+// Linux) e, onde não há um, para um arquivo só do usuário na pasta de dados. A
+// configuração guarda apenas em qual dos dois ela está.
+
+// Onde a chave privada está guardada, gravado em Config.KeyStore
+const (
+	KeyStoreSystem = "system"
+	KeyStoreFile   = "file"
+)
+
+// keyStoreService identifica as chaves do GoVPN no cofre do sistema
+const keyStoreService = "GoVPN"
+
+// keyFileName é o arquivo da chave quando não há cofre do sistema
+const keyFileName = "private.key"
+
+// ErrKeyNotFound é retornado quando o cofre não tem a chave pedida
+var ErrKeyNotFound = errors.New("key not found in the key store")
+
+// ErrKeyStoreUnavailable é retornado quando o sistema não tem um cofre de senhas utilizável
+var ErrKeyStoreUnavailable = errors.New("no system key store available")
+
+// KeyStore guarda segredos em base64 por conta: a chave privada fica na conta com a chave
+// pública do computador, a chave de cifra da configuração em config:<id>
+type KeyStore interface {
+	Get(account string) (string, error)
+	Set(account, secret string) error
+	Delete(account string) error
+}
+
+// keyStoreLabel é o nome de uma conta mostrado nos gerenciadores de senhas
+func keyStoreLabel(account string) string {
+	if strings.HasPrefix(account, configKeyAccount) {
+		return "GoVPN configuration key"
+	}
+	return "GoVPN private key"
+}
+
+// openKeyStore abre o cofre com o nome gravado na configuração
+func (cm *ConfigManager) openKeyStore(name string) (KeyStore, error) {
+	switch name {
+	case KeyStoreSystem:
+		return systemKeyStore()
+	case KeyStoreFile:
+		return fileKeyStore{path: filepath.Join(cm.dataPath, keyFileName), sealer: &cm.sealer}, nil
+	default:
+		return nil, fmt.Errorf("unknown key store %q", name)
+	}
+}
+
+// fileKeyStore guarda a chave num arquivo legível só pelo usuário, cifrado junto com a
+// configuração quando ela é
+type fileKeyStore struct {
+	path   string
+	sealer *fileSealer
+}
+
+// keyFile é o conteúdo do arquivo da chave
+type keyFile struct {
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key"`
+}
+
+func (s fileKeyStore) Get(publicKey string) (string, error) {
+	content, err := s.sealer.readFile(s.path)
+	if os.IsNotExist(err) {
+		return "", ErrKeyNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var key keyFile
+	if err := json.Unmarshal(content, &key); err != nil {
+		return "", fmt.Errorf("invalid key file: %v", err)
+	}
+	if key.PublicKey != publicKey || key.PrivateKey == "" {
+		return "", ErrKeyNotFound
+	}
+	return key.PrivateKey, nil
+}
+
+func (s fileKeyStore) Set(publicKey, privateKey string) error {
+	content, err := json.MarshalIndent(keyFile{PublicKey: publicKey, PrivateKey: privateKey}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.sealer.writeFile(s.path, content, 0600)
+}
+
+func (s fileKeyStore) Delete(publicKey string) error {
+	if _, err := s.Get(publicKey); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	}
+	return os.Remove(s.path)
+}
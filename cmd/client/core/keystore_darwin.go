@@ -0,0 +1,62 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityItemNotFound é o código de saída do security quando o item não existe
+const securityItemNotFound = 44
+
+// keychainStore guarda a chave no Keychain do usuário pela ferramenta security
+type keychainStore struct {
+	security string
+}
+
+// systemKeyStore retorna o Keychain do usuário
+func systemKeyStore() (KeyStore, error) {
+	security, err := exec.LookPath("security")
+	if err != nil {
+		return nil, ErrKeyStoreUnavailable
+	}
+	return keychainStore{security: security}, nil
+}
+
+func (s keychainStore) Get(account string) (string, error) {
+	output, err := exec.Command(s.security, "find-generic-password", "-s", keyStoreService, "-a", account, "-w").Output()
+	if err != nil {
+		return "", securityError(err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (s keychainStore) Set(account, value string) error {
+	// O comando vai pela entrada do modo interativo para o segredo não aparecer na lista de
+	// processos. Contas e segredos em base64 não precisam de aspas.
+	cmd := exec.Command(s.security, "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keyStoreService, account, value))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (s keychainStore) Delete(account string) error {
+	err := exec.Command(s.security, "delete-generic-password", "-s", keyStoreService, "-a", account).Run()
+	if err := securityError(err); err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// securityError traduz o código de saída do security
+func securityError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityItemNotFound {
+		return ErrKeyNotFound
+	}
+	return err
+}
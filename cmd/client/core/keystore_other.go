@@ -0,0 +1,251 @@
+//go:build !windows && !darwin
+
+package core
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Nomes da API Secret Service, atendida pelo GNOME Keyring, KWallet e KeePassXC
+const (
+	secretServiceName       = "org.freedesktop.secrets"
+	secretServicePath       = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretDefaultCollection = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	secretServiceIface      = "org.freedesktop.Secret.Service"
+	secretCollectionIface   = "org.freedesktop.Secret.Collection"
+	secretItemIface         = "org.freedesktop.Secret.Item"
+	secretPromptIface       = "org.freedesktop.Secret.Prompt"
+)
+
+// Limites de espera: secretServiceTimeout para as chamadas e secretPromptTimeout para o
+// usuário destravar o cofre quando ele pede a senha
+const (
+	secretServiceTimeout = 10 * time.Second
+	secretPromptTimeout  = 2 * time.Minute
+)
+
+// noPrompt é o caminho retornado quando a operação não precisa do usuário
+const noPrompt = dbus.ObjectPath("/")
+
+// secret é a estrutura Secret da API
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// secretServiceStore guarda a chave na coleção padrão do Secret Service da sessão
+type secretServiceStore struct{}
+
+// secretSession é uma conexão com o Secret Service e a sessão aberta nela
+type secretSession struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+}
+
+// systemKeyStore retorna o Secret Service se a sessão tiver um
+func systemKeyStore() (KeyStore, error) {
+	s, err := openSecretSession()
+	if err != nil {
+		return nil, err
+	}
+	s.close()
+	return secretServiceStore{}, nil
+}
+
+// openSecretSession conecta ao barramento da sessão, sem iniciar um, e abre uma sessão
+// sem cifra, já que a chave não sai da máquina
+func openSecretSession() (*secretSession, error) {
+	conn, err := dbus.SessionBusPrivateNoAutoStartup()
+	if err != nil {
+		return nil, ErrKeyStoreUnavailable
+	}
+	if err := conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, ErrKeyStoreUnavailable
+	}
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, ErrKeyStoreUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretServiceTimeout)
+	defer cancel()
+
+	// O serviço pode não estar rodando e ser iniciado pelo barramento no primeiro uso
+	var running bool
+	var activatable []string
+	conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.NameHasOwner", 0, secretServiceName).Store(&running)
+	conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.ListActivatableNames", 0).Store(&activatable)
+	if !running && !slices.Contains(activatable, secretServiceName) {
+		conn.Close()
+		return nil, ErrKeyStoreUnavailable
+	}
+
+	s := &secretSession{conn: conn}
+	var output dbus.Variant
+	if err := s.call(secretServicePath, secretServiceIface+".OpenSession", "plain", dbus.MakeVariant("")).Store(&output, &s.session); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// withSecretSession roda fn numa sessão aberta só para ela
+func withSecretSession(fn func(s *secretSession) error) error {
+	s, err := openSecretSession()
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	return fn(s)
+}
+
+// close fecha a conexão, o que encerra a sessão no serviço
+func (s *secretSession) close() {
+	s.conn.Close()
+}
+
+// call chama um método de um objeto do serviço
+func (s *secretSession) call(path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	ctx, cancel := context.WithTimeout(context.Background(), secretServiceTimeout)
+	defer cancel()
+	return s.conn.Object(secretServiceName, path).CallWithContext(ctx, method, 0, args...)
+}
+
+// search procura os itens de uma chave, destravando os que estiverem travados
+func (s *secretSession) search(account string) ([]dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	if err := s.call(secretServicePath, secretServiceIface+".SearchItems", secretAttributes(account)).Store(&unlocked, &locked); err != nil {
+		return nil, err
+	}
+	if len(locked) > 0 {
+		if err := s.unlock(locked...); err != nil {
+			return nil, err
+		}
+	}
+	return append(unlocked, locked...), nil
+}
+
+// unlock destrava objetos do cofre, pedindo a senha ao usuário se o serviço exigir
+func (s *secretSession) unlock(paths ...dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := s.call(secretServicePath, secretServiceIface+".Unlock", paths).Store(&unlocked, &prompt); err != nil {
+		return err
+	}
+	return s.prompt(prompt)
+}
+
+// prompt mostra o pedido do serviço ao usuário e espera a resposta
+func (s *secretSession) prompt(path dbus.ObjectPath) error {
+	if path == noPrompt {
+		return nil
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(secretPromptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	defer s.conn.RemoveMatchSignal(match...)
+
+	signals := make(chan *dbus.Signal, 1)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.call(path, secretPromptIface+".Prompt", "").Err; err != nil {
+		return err
+	}
+
+	timeout := time.After(secretPromptTimeout)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Path != path || len(signal.Body) == 0 {
+				continue
+			}
+			if dismissed, _ := signal.Body[0].(bool); dismissed {
+				return errors.New("the key store prompt was dismissed")
+			}
+			return nil
+		case <-timeout:
+			return errors.New("timed out waiting for the key store prompt")
+		}
+	}
+}
+
+// secretAttributes são os atributos que identificam a chave de um computador
+func secretAttributes(account string) map[string]string {
+	return map[string]string{"service": keyStoreService, "account": account}
+}
+
+func (secretServiceStore) Get(account string) (string, error) {
+	var stored string
+	err := withSecretSession(func(s *secretSession) error {
+		items, err := s.search(account)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return ErrKeyNotFound
+		}
+
+		var value secret
+		if err := s.call(items[0], secretItemIface+".GetSecret", s.session).Store(&value); err != nil {
+			return err
+		}
+		stored = string(value.Value)
+		return nil
+	})
+	return stored, err
+}
+
+func (secretServiceStore) Set(account, value string) error {
+	return withSecretSession(func(s *secretSession) error {
+		if err := s.unlock(secretDefaultCollection); err != nil {
+			return err
+		}
+
+		properties := map[string]dbus.Variant{
+			secretItemIface + ".Label":      dbus.MakeVariant(keyStoreLabel(account)),
+			secretItemIface + ".Attributes": dbus.MakeVariant(secretAttributes(account)),
+		}
+		content := secret{Session: s.session, Value: []byte(value), ContentType: "text/plain"}
+
+		var item, prompt dbus.ObjectPath
+		if err := s.call(secretDefaultCollection, secretCollectionIface+".CreateItem", properties, content, true).Store(&item, &prompt); err != nil {
+			return err
+		}
+		return s.prompt(prompt)
+	})
+}
+
+func (secretServiceStore) Delete(account string) error {
+	return withSecretSession(func(s *secretSession) error {
+		items, err := s.search(account)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			var prompt dbus.ObjectPath
+			if err := s.call(item, secretItemIface+".Delete").Store(&prompt); err != nil {
+				return err
+			}
+			if err := s.prompt(prompt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,30 @@
+package core
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/cmd/client/network"
+)
+
+// handleLinkStats publishes the measurements of a ping round and redraws the ping column
+func (nm *NetworkManager) handleLinkStats(stats map[string]network.LinkStats) {
+	links := make(map[string]data.PeerLink, len(stats))
+	for publicKey, s := range stats {
+		links[publicKey] = data.PeerLink{RTT: s.RTT, Jitter: s.Jitter, Loss: s.Loss}
+	}
+	nm.RealtimeData.SetPeerLinks(links)
+	if len(links) > 0 {
+		nm.refreshNetworkList()
+	}
+}
+
+// PingLabel formats a link measurement for the network list, with the loss when there is any
+func PingLabel(link data.PeerLink) string {
+	label := fmt.Sprintf("%d ms", link.RTT.Milliseconds())
+	if loss := math.Round(link.Loss * 100); loss > 0 {
+		label += fmt.Sprintf(" %.0f%% loss", loss)
+	}
+	return label
+}
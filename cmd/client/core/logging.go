@@ -0,0 +1,36 @@
+package core
+
+import (
+	"io"
+	"path/filepath"
+	"slices"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// DefaultLogLevel é o nível do log quando a configuração não escolhe outro; as mensagens de
+// debug ficam de fora
+const DefaultLogLevel = "info"
+
+// LogLevels são os níveis aceitos em Config.LogLevel, do mais ao menos detalhado
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// MinLogLevel retorna o nível mínimo do log escolhido na configuração
+func (c Config) MinLogLevel() string {
+	if !slices.Contains(LogLevels, c.LogLevel) {
+		return DefaultLogLevel
+	}
+	return c.LogLevel
+}
+
+// SetupLog manda o log para o console, se houver, e para um arquivo na pasta de dados, que
+// é rotacionado ao passar de logger.DefaultMaxSize. Com a configuração ainda trancada vale
+// o nível padrão, até SetLevel receber o da configuração.
+func (cm *ConfigManager) SetupLog(fileName string, console io.Writer) error {
+	return logger.Setup(logger.Options{
+		Level:   cm.GetConfig().MinLogLevel(),
+		Console: console,
+		File:    filepath.Join(cm.dataPath, fileName),
+		StdLog:  true,
+	})
+}
@@ -0,0 +1,238 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	clientwebrtc_impl "github.com/itxtoledo/govpn/cmd/client/webrtc"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+	"github.com/pion/webrtc/v4"
+)
+
+const (
+	// meshRetryDelay é quanto o lado que oferece espera para discar de novo um peer cuja conexão falhou
+	meshRetryDelay = 5 * time.Second
+	// iceRestartDelay é quanto uma conexão fica desconectada antes de o lado que oferece
+	// reiniciar o ICE, já que quedas curtas se recuperam sozinhas
+	iceRestartDelay = 2 * time.Second
+)
+
+// isOfferer diz se este computador envia a oferta para o peer. Os dois lados comparam as
+// mesmas duas chaves, então só um deles disca e as ofertas nunca se cruzam.
+func (nm *NetworkManager) isOfferer(peerPublicKey string) bool {
+	return nm.ConfigManager.GetConfig().PublicKey < peerPublicKey
+}
+
+// meshMembers retorna os computadores online da rede atual, tirando este
+func (nm *NetworkManager) meshMembers() map[string]bool {
+	members := make(map[string]bool)
+	if nm.NetworkID == "" {
+		return members
+	}
+
+	publicKey := nm.ConfigManager.GetConfig().PublicKey
+	for _, network := range nm.RealtimeData.GetNetworks() {
+		if network.NetworkID != nm.NetworkID {
+			continue
+		}
+		for _, computer := range network.Computers {
+			if computer.IsOnline && computer.PublicKey != publicKey {
+				members[computer.PublicKey] = true
+			}
+		}
+		break
+	}
+	return members
+}
+
+// syncMesh mantém uma conexão WebRTC com cada computador online da rede atual: disca os
+// peers para os quais este computador é quem oferece e fecha as conexões com quem saiu,
+// desconectou ou pertence a outra rede. Os outros peers discam para cá e os peers
+// retransmitidos pelo servidor não são discados de novo.
+func (nm *NetworkManager) syncMesh() {
+	members := nm.meshMembers()
+	nm.syncPingPeers()
+
+	nm.peersMu.Lock()
+	var stale []string
+	for peerPublicKey := range nm.peerConnections {
+		if !members[peerPublicKey] {
+			stale = append(stale, peerPublicKey)
+		}
+	}
+	for peerPublicKey := range nm.relayed {
+		if !members[peerPublicKey] {
+			delete(nm.relayed, peerPublicKey)
+			nm.forgetSession(peerPublicKey)
+		}
+	}
+	nm.peersMu.Unlock()
+
+	for _, peerPublicKey := range stale {
+		logger.Info("Peer is no longer online in the current network, closing its connection", "peer", peerPublicKey)
+		nm.closePeer(peerPublicKey)
+	}
+
+	for peerPublicKey := range members {
+		if !nm.isOfferer(peerPublicKey) || nm.IsRelayed(peerPublicKey) {
+			continue
+		}
+		if err := nm.ConnectToPeer(peerPublicKey); err != nil {
+			logger.Warn("Failed to dial peer", "peer", peerPublicKey, "error", err)
+		}
+	}
+}
+
+// newPeer cria a conexão WebRTC com um peer, com os callbacks e o canal de dados deste lado
+func (nm *NetworkManager) newPeer(peerPublicKey string) (*clientwebrtc_impl.WebRTCManager, error) {
+	peer, err := clientwebrtc_impl.NewWebRTCManager(nm.ConfigManager.GetConfig().WebRTCOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WebRTC manager for peer %s: %w", peerPublicKey, err)
+	}
+
+	peer.SetOnICECandidate(func(c *webrtc.ICECandidate) {
+		nm.handleICECandidate(c, peerPublicKey)
+	})
+	peer.SetOnConnectionStateChange(func(s webrtc.PeerConnectionState) {
+		nm.handlePeerConnectionStateChange(peerPublicKey, peer, s)
+	})
+	peer.SetOnICEConnectionStateChange(func(s webrtc.ICEConnectionState) {
+		nm.handlePeerICEConnectionStateChange(peerPublicKey, peer, s)
+	})
+	peer.SetOnDataChannelOpen(func() {
+		nm.handlePeerDataChannelOpen(peerPublicKey)
+	})
+	peer.SetOnDataChannelMessage(func(msg []byte) {
+		defer crash.Recover("peer message")
+		nm.handlePeerDataChannelMessage(peerPublicKey, msg)
+	})
+	peer.SetOnPacket(func(frame []byte) {
+		defer crash.Recover("peer packet")
+		nm.handlePeerPacket(peerPublicKey, frame)
+	})
+
+	if err := peer.CreateDataChannel(); err != nil {
+		peer.Close()
+		return nil, fmt.Errorf("failed to create data channel for peer %s: %w", peerPublicKey, err)
+	}
+	return peer, nil
+}
+
+// closePeer fecha e esquece a conexão com um peer, direta ou retransmitida, se houver
+func (nm *NetworkManager) closePeer(peerPublicKey string) {
+	nm.peersMu.Lock()
+	peer, ok := nm.peerConnections[peerPublicKey]
+	delete(nm.peerConnections, peerPublicKey)
+	delete(nm.relayed, peerPublicKey)
+	nm.forgetSession(peerPublicKey)
+	nm.peersMu.Unlock()
+
+	if !ok {
+		return
+	}
+	if err := peer.Close(); err != nil {
+		logger.Debug("Error closing WebRTC manager for peer", "peer", peerPublicKey, "error", err)
+	}
+}
+
+// closeAllPeers fecha todas as conexões WebRTC
+func (nm *NetworkManager) closeAllPeers() {
+	nm.peersMu.Lock()
+	peers := nm.peerConnections
+	nm.peerConnections = make(map[string]*clientwebrtc_impl.WebRTCManager)
+	nm.sessions = make(map[string]*network.SecureSession)
+	nm.relayed = make(map[string]bool)
+	nm.peersMu.Unlock()
+
+	for peerPublicKey, peer := range peers {
+		if err := peer.Close(); err != nil {
+			logger.Debug("Error closing WebRTC manager for peer", "peer", peerPublicKey, "error", err)
+		}
+	}
+}
+
+// dropFailedPeer esquece uma conexão que falhou, a menos que já tenha sido substituída.
+// Se o servidor retransmite frames o tráfego passa por ele; senão, quando este computador
+// é quem oferece, uma nova discagem é agendada.
+func (nm *NetworkManager) dropFailedPeer(peerPublicKey string, peer *clientwebrtc_impl.WebRTCManager) {
+	relay := nm.relayAvailable.Load() && nm.meshMembers()[peerPublicKey]
+
+	nm.peersMu.Lock()
+	current, ok := nm.peerConnections[peerPublicKey]
+	if !ok || current != peer {
+		nm.peersMu.Unlock()
+		return
+	}
+	if relay {
+		nm.peersMu.Unlock()
+		nm.markRelayed(peerPublicKey)
+		return
+	}
+	delete(nm.peerConnections, peerPublicKey)
+	nm.forgetSession(peerPublicKey)
+	nm.peersMu.Unlock()
+
+	peer.Close()
+	if nm.isOfferer(peerPublicKey) {
+		time.AfterFunc(meshRetryDelay, nm.syncMesh)
+	}
+}
+
+// restartICE pede novos caminhos para todas as conexões depois de uma mudança na rede
+// local. Só o lado que oferece reinicia; o outro lado percebe a desconexão e espera a
+// nova oferta, que renegocia a conexão existente sem perder a sessão cifrada.
+func (nm *NetworkManager) restartICE(reason string) {
+	nm.peersMu.Lock()
+	peers := make(map[string]*clientwebrtc_impl.WebRTCManager, len(nm.peerConnections))
+	for peerPublicKey, peer := range nm.peerConnections {
+		peers[peerPublicKey] = peer
+	}
+	nm.peersMu.Unlock()
+
+	logger.Info("Network changed, restarting ICE", "reason", reason, "peers", len(peers))
+	for peerPublicKey, peer := range peers {
+		if nm.isOfferer(peerPublicKey) {
+			nm.restartPeerICE(peerPublicKey, peer)
+		}
+	}
+}
+
+// restartPeerICE envia a um peer uma oferta com novas credenciais ICE
+func (nm *NetworkManager) restartPeerICE(peerPublicKey string, peer *clientwebrtc_impl.WebRTCManager) {
+	offer, err := peer.CreateOffer(true)
+	if err != nil {
+		logger.Warn("Failed to create ICE restart offer for peer", "peer", peerPublicKey, "error", err)
+		return
+	}
+
+	err = nm.SignalingServer.SendSignal(smodels.TypeSdpOffer, smodels.SdpOffer{
+		SenderPublicKey: nm.ConfigManager.GetConfig().PublicKey,
+		TargetPublicKey: peerPublicKey,
+		SDP:             offer.SDP,
+	})
+	if err != nil {
+		logger.Warn("Failed to send ICE restart offer to peer", "peer", peerPublicKey, "error", err)
+	}
+}
+
+// restartDisconnectedPeer reinicia o ICE de uma conexão que continua desconectada depois
+// de iceRestartDelay, quando a mudança de rede aconteceu do lado do peer
+func (nm *NetworkManager) restartDisconnectedPeer(peerPublicKey string, peer *clientwebrtc_impl.WebRTCManager) {
+	if !nm.isOfferer(peerPublicKey) {
+		return
+	}
+
+	time.AfterFunc(iceRestartDelay, func() {
+		if current, ok := nm.peerConnection(peerPublicKey); !ok || current != peer {
+			return
+		}
+		if peer.ICEConnectionState() != webrtc.ICEConnectionStateDisconnected {
+			return
+		}
+		logger.Info("Peer still disconnected, restarting ICE", "peer", peerPublicKey)
+		nm.restartPeerICE(peerPublicKey, peer)
+	})
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// natProbeTimeout bounds each exchange of the NAT detection
+const natProbeTimeout = 3 * time.Second
+
+// detectNAT classifies our NAT with the server's UDP probe and shares the result with
+// network peers, so both sides can tell whether a direct connection is likely to work
+func (nm *NetworkManager) detectNAT() {
+	detection, err := nm.SignalingServer.DetectNAT(natProbeTimeout)
+	if err != nil {
+		logger.Info("NAT detection unavailable", "error", err)
+		return
+	}
+
+	logger.Info("Detected NAT type", "type", detection.Type, "mappedAddress", detection.MappedAddress)
+	nm.RealtimeData.SetNatType(string(detection.Type))
+	nm.refreshNetworkList()
+
+	if _, err := nm.SignalingServer.ReportNAT(detection.Type); err != nil {
+		logger.Warn("Failed to report NAT type", "error", err)
+	}
+}
+
+// NATTypeLabel returns a readable name for a NAT type
+func NATTypeLabel(t smodels.NatType) string {
+	switch t {
+	case smodels.NatTypeOpen:
+		return "Open"
+	case smodels.NatTypeRestrictedCone:
+		return "Cone"
+	case smodels.NatTypePortRestrictedCone:
+		return "Port restricted"
+	case smodels.NatTypeSymmetric:
+		return "Symmetric"
+	case smodels.NatTypeUDPBlocked:
+		return "UDP blocked"
+	}
+	return "Unknown"
+}
+
+// DirectConnectionHint tells whether a direct connection to a peer is likely to work,
+// empty while either NAT type is unknown
+func DirectConnectionHint(own, peer smodels.NatType) string {
+	if own == "" || peer == "" || own == smodels.NatTypeUnknown || peer == smodels.NatTypeUnknown {
+		return ""
+	}
+	if own.DirectLikely(peer) {
+		return "P2P"
+	}
+	return "Relay"
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// A última lista de redes recebida do servidor, com os membros, os IPs e quando cada membro
+// foi visto online, fica num arquivo da pasta de dados com a cifra da configuração. Ao abrir,
+// o cliente mostra essa lista como offline até o servidor enviar a atual, em vez de começar
+// vazio. A lista é da identidade que a recebeu; com outra identidade o arquivo é ignorado.
+
+// networksDir é a pasta da lista guardada dentro da pasta de dados
+const networksDir = "networks"
+
+// networksFileName é o arquivo da lista guardada
+var networksFileName = filepath.Join(networksDir, "snapshot.json")
+
+// networkSnapshot é o conteúdo do arquivo
+type networkSnapshot struct {
+	PublicKey string                          `json:"public_key"` // Identidade que recebeu a lista
+	Saved     time.Time                       `json:"saved"`
+	Networks  []data.Network                  `json:"networks"`
+	LastSeen  map[string]map[string]time.Time `json:"last_seen,omitempty"` // Por rede e chave pública
+}
+
+// networkStore guarda a última lista de redes no disco
+type networkStore struct {
+	cm       *ConfigManager
+	lastSeen map[string]map[string]time.Time
+	online   map[string]map[string]bool // Quem estava online na última lista gravada
+	mu       sync.Mutex
+}
+
+// newNetworkStore cria o acesso à lista guardada
+func newNetworkStore(cm *ConfigManager) *networkStore {
+	return &networkStore{cm: cm, lastSeen: make(map[string]map[string]time.Time), online: make(map[string]map[string]bool)}
+}
+
+// load lê a lista guardada pela identidade publicKey
+func (ns *networkStore) load(publicKey string) (networkSnapshot, bool) {
+	var snapshot networkSnapshot
+	content, err := ns.cm.ReadDataFile(networksFileName)
+	if err == nil {
+		err = json.Unmarshal(content, &snapshot)
+	}
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("Cannot read the saved networks", "error", err)
+		}
+		return snapshot, false
+	}
+	if snapshot.PublicKey != publicKey {
+		logger.Info("Ignoring the saved networks of another identity")
+		return snapshot, false
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if snapshot.LastSeen != nil {
+		ns.lastSeen = snapshot.LastSeen
+	}
+	return snapshot, true
+}
+
+// save grava a lista recebida do servidor e retorna quando cada membro foi visto online. Um
+// membro online agora, ou que estava online na lista anterior, foi visto agora.
+func (ns *networkStore) save(publicKey string, networks []data.Network, now time.Time) map[string]map[string]time.Time {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	online := make(map[string]map[string]bool, len(networks))
+	lastSeen := make(map[string]map[string]time.Time, len(networks))
+	for i := range networks {
+		network := &networks[i]
+		// Sem a versão, a primeira lista depois de abrir vem inteira do servidor, já que os
+		// membros mudam na memória sem a versão acompanhar
+		network.Version = ""
+
+		online[network.NetworkID] = make(map[string]bool)
+		lastSeen[network.NetworkID] = make(map[string]time.Time)
+		for _, computer := range network.Computers {
+			seen, known := ns.lastSeen[network.NetworkID][computer.PublicKey]
+			if computer.IsOnline || ns.online[network.NetworkID][computer.PublicKey] {
+				seen, known = now, true
+			}
+			if known {
+				lastSeen[network.NetworkID][computer.PublicKey] = seen
+			}
+			if computer.IsOnline {
+				online[network.NetworkID][computer.PublicKey] = true
+			}
+		}
+	}
+	ns.online, ns.lastSeen = online, lastSeen
+
+	content, err := json.Marshal(networkSnapshot{PublicKey: publicKey, Saved: now, Networks: networks, LastSeen: lastSeen})
+	if err == nil {
+		err = ns.cm.WriteDataFile(networksFileName, content)
+	}
+	if err != nil {
+		logger.Warn("Cannot save the networks", "error", err)
+	}
+	return lastSeen
+}
+
+// LoadNetworks mostra a lista de redes guardada como offline, até o servidor enviar a atual.
+// Não faz nada se já houver uma lista na memória.
+func (nm *NetworkManager) LoadNetworks() {
+	if len(nm.RealtimeData.GetNetworks()) > 0 {
+		return
+	}
+	snapshot, ok := nm.networkCache.load(nm.ConfigManager.GetConfig().PublicKey)
+	if !ok {
+		return
+	}
+
+	logger.Info("Showing the saved networks until the server sends them", "networks", len(snapshot.Networks), "saved", snapshot.Saved)
+	nm.RealtimeData.SetLastSeen(snapshot.LastSeen)
+	nm.RealtimeData.SetNetworks(snapshot.Networks)
+	nm.RealtimeData.MarkNetworksOffline()
+}
+
+// saveNetworks grava a lista de redes atual, se ela veio do servidor
+func (nm *NetworkManager) saveNetworks() {
+	if nm.connectionState != ConnectionStateConnected || nm.RealtimeData.NetworksOffline() {
+		return
+	}
+	lastSeen := nm.networkCache.save(nm.ConfigManager.GetConfig().PublicKey, nm.RealtimeData.GetNetworks(), time.Now())
+	nm.RealtimeData.SetLastSeen(lastSeen)
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"encoding/base64"
+	"maps"
+	"slices"
+
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// O handshake com os peers usa uma chave derivada do PIN da rede (network.NetworkKey), para
+// que o servidor de sinalização não consiga se passar por um membro. O servidor não devolve
+// o PIN, então a chave é guardada na configuração ao criar a rede, ao entrar nela e ao trocar
+// o PIN. Quem entrou antes de uma troca continua com a chave antiga; o dono guarda as duas e
+// segue falando com todos, e os outros membros entram de novo com o PIN novo para pegar a
+// nova. Uma rede sem chave guardada, de antes desta versão, também pede entrar de novo.
+
+// maxNetworkKeys é quantas chaves de PINs anteriores cada rede guarda
+const maxNetworkKeys = 4
+
+// PINKeys retorna as chaves do PIN de uma rede, decodificadas e a mais nova primeiro
+func (c Config) PINKeys(networkID string) [][]byte {
+	var keys [][]byte
+	for _, encoded := range c.NetworkKeys[networkID] {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != network.NetworkKeySize {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// RememberNetworkPIN guarda a chave derivada do PIN com que este computador criou uma rede,
+// entrou nela ou que definiu para ela, na frente das anteriores
+func (cm *ConfigManager) RememberNetworkPIN(networkID, pin string) error {
+	encoded := base64.StdEncoding.EncodeToString(network.NetworkKey(networkID, pin))
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	keys := slices.DeleteFunc(slices.Clone(cm.config.NetworkKeys[networkID]), func(key string) bool {
+		return key == encoded
+	})
+	keys = slices.Insert(keys, 0, encoded)
+	if len(keys) > maxNetworkKeys {
+		keys = keys[:maxNetworkKeys]
+	}
+
+	all := maps.Clone(cm.config.NetworkKeys)
+	if all == nil {
+		all = make(map[string][]string)
+	}
+	all[networkID] = keys
+	cm.config.NetworkKeys = all
+	return cm.SaveConfig()
+}
+
+// ForgetNetworkKeys apaga as chaves de uma rede que este computador deixou ou que foi apagada
+func (cm *ConfigManager) ForgetNetworkKeys(networkID string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if _, ok := cm.config.NetworkKeys[networkID]; !ok {
+		return nil
+	}
+	all := maps.Clone(cm.config.NetworkKeys)
+	delete(all, networkID)
+	cm.config.NetworkKeys = all
+	return cm.SaveConfig()
+}
+
+// rememberNetworkPIN guarda a chave do PIN de uma rede, registrando a falha
+func (nm *NetworkManager) rememberNetworkPIN(networkID, pin string) {
+	if err := nm.ConfigManager.RememberNetworkPIN(networkID, pin); err != nil {
+		logger.Error("Failed to save the network key", "networkID", networkID, "error", err)
+	}
+}
+
+// forgetNetworkKeys apaga as chaves de uma rede deixada ou apagada, registrando a falha
+func (nm *NetworkManager) forgetNetworkKeys(networkID string) {
+	if err := nm.ConfigManager.ForgetNetworkKeys(networkID); err != nil {
+		logger.Error("Failed to delete the network keys", "networkID", networkID, "error", err)
+	}
+}
@@ -0,0 +1,1447 @@
+// Package core é o cliente GoVPN sem interface: configuração, sinalização, conexões com os
+// peers e transporte do tráfego, usado tanto pelo aplicativo Fyne quanto pelo govpn-cli
+package core
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	clientwebrtc_impl "github.com/itxtoledo/govpn/cmd/client/webrtc"
+	"github.com/itxtoledo/govpn/libs/logger"
+	sclient "github.com/itxtoledo/govpn/libs/signaling/client"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+	"github.com/pion/webrtc/v4"
+)
+
+// NetworkInterface define a interface mínima para a rede virtual
+type NetworkInterface interface {
+	GetLocalIP() string
+	GetComputerCount() int
+}
+
+// ConnectionState represents the state of the connection
+type ConnectionState int
+
+const (
+	ConnectionStateDisconnected ConnectionState = iota
+	ConnectionStateConnecting
+	ConnectionStateConnected
+)
+
+// NetworkManager handles the VPN network
+// OnWebRTCMessageReceived is a callback function for incoming WebRTC messages
+type OnWebRTCMessageReceived func(peerPublicKey string, message string)
+
+// NetworkManager handles the VPN network
+type NetworkManager struct {
+	peerConnections map[string]*clientwebrtc_impl.WebRTCManager // Map of peer public key to their WebRTC manager
+	sessions        map[string]*network.SecureSession           // Encryption of the frames sent to each peer
+	relayed         map[string]bool                             // Peers whose frames go through the signaling server
+	peersMu         sync.Mutex
+	identity        ed25519.PrivateKey // Signs the key exchange with peers
+
+	defrag *network.Defragmenter // Reassembles the frames peers split to fit the path MTU
+
+	relayAvailable   atomic.Bool  // Whether the server relays frames when P2P fails
+	relayQuotaNotice atomic.Int64 // When the last relay quota notice was shown, in Unix nanoseconds
+	sessionReplaced  atomic.Bool  // Whether the server closed this session for a newer one with the same key
+
+	// Transporte do tráfego da rede atual: interface TUN, DNS dos nomes dos computadores,
+	// proxy de portas, transferência de arquivos, medição dos enlaces e do MTU, contagem de
+	// bytes, caminhos do ICE, mudanças da rede local e inatividade, nil quando não conectado
+	tunnel   *network.Router
+	dns      *network.DNSServer
+	proxy    *network.Proxy
+	files    *network.FileTransfers
+	pinger   *network.Pinger
+	mtu      *network.MTUProber
+	traffic  *trafficMeter
+	paths    *pathMonitor
+	watcher  *network.NetworkWatcher
+	idle     *idleMonitor
+	tunnelMu sync.Mutex
+
+	shaper       atomic.Pointer[shaper] // Limites de banda da rede atual, em bandwidth_limit.go
+	lastActivity atomic.Int64           // Último frame de dados no túnel, em Unix nanos, em idle.go
+
+	VirtualNetwork    NetworkInterface
+	SignalingServer   *sclient.SignalingClient
+	NetworkID         string
+	connectionState   ConnectionState
+	ReconnectAttempts int
+
+	// Pedidos feitos durante a reconexão, em reconnect.go
+	offlineQueue []offlineRequest
+	reconnectMu  sync.Mutex
+
+	chats      *chatStore    // Conversas diretas com os peers, em chat.go
+	scans      *scanConsents // Consultas da procura de serviços, em service_scan.go
+	trafficLog *trafficStore // Bytes trocados por dia em cada rede, em traffic_history.go
+
+	networkCache *networkStore // Última lista de redes do servidor, em network_cache.go
+	subnetWarned string        // Última sub-rede avisada de conflito, com tunnelMu, em subnet_conflict.go
+	wakeInfo     string        // Último endereço de Wake-on-LAN informado ao servidor, com wakeMu, em wake.go
+	wakeMu       sync.Mutex
+
+	// Dependencies
+	RealtimeData            *data.RealtimeDataLayer
+	ConfigManager           *ConfigManager
+	onWebRTCMessageReceived OnWebRTCMessageReceived
+}
+
+// NewNetworkManager creates a new instance of NetworkManager. It knows nothing about the
+// interface: state and events go to the data layer, and redraws are asked for on its bus.
+func NewNetworkManager(realtimeData *data.RealtimeDataLayer, configManager *ConfigManager, onWebRTCMessageReceived OnWebRTCMessageReceived) *NetworkManager {
+	nm := &NetworkManager{
+		peerConnections:         make(map[string]*clientwebrtc_impl.WebRTCManager),
+		sessions:                make(map[string]*network.SecureSession),
+		relayed:                 make(map[string]bool),
+		defrag:                  network.NewDefragmenter(),
+		connectionState:         ConnectionStateDisconnected,
+		ReconnectAttempts:       0,
+		RealtimeData:            realtimeData,
+		ConfigManager:           configManager,
+		onWebRTCMessageReceived: onWebRTCMessageReceived,
+	}
+	nm.chats = newChatStore(configManager)
+	nm.trafficLog = newTrafficStore(configManager)
+	nm.networkCache = newNetworkStore(configManager)
+	nm.scans = &scanConsents{waiting: make(map[string]chan bool)}
+
+	return nm
+}
+
+// refreshNetworkList asks the interface to redraw the network list. Every change to the list
+// goes through here, so it is also saved for the next start.
+func (nm *NetworkManager) refreshNetworkList() {
+	nm.saveNetworks()
+	nm.RealtimeData.RequestRefresh(data.RefreshNetworkList)
+}
+
+// refreshUI asks the interface to redraw the connection state
+func (nm *NetworkManager) refreshUI() {
+	nm.RealtimeData.RequestRefresh(data.RefreshUI)
+}
+
+// Connect connects to the VPN network
+func (nm *NetworkManager) Connect(serverAddress string) error {
+	// Set state to connecting
+	nm.connectionState = ConnectionStateConnecting
+	nm.sessionReplaced.Store(false)
+	// Update data layer
+	nm.RealtimeData.SetConnectionState(data.StateConnecting)
+	nm.RealtimeData.SetStatusMessage("Connecting...")
+
+	// Update UI
+	nm.refreshUI()
+
+	// Initialize signaling server
+	// Get public key from ConfigManager
+	publicKey, privateKeyStr := nm.ConfigManager.GetKeyPair()
+
+	// Create a handler function for signaling client messages
+	signalingHandler := func(messageType smodels.MessageType, payload []byte) {
+		defer crash.Recover("signaling handler")
+		// O conteúdo não entra no log: traz códigos de recuperação e, nos frames
+		// retransmitidos, o tráfego da rede
+		if messageType != smodels.TypeRelayFrame {
+			logger.Debug("Received message", "type", messageType)
+		}
+		switch messageType {
+		case smodels.TypeError:
+			var errorPayload smodels.ErrorResponse
+			if err := json.Unmarshal(payload, &errorPayload); err == nil && errorPayload.Code == smodels.ErrRelayQuotaExceeded {
+				nm.handleRelayQuotaExceeded(errorPayload)
+			} else if err == nil && errorPayload.Error != "" {
+				logger.Warn("Server error", "error", errorPayload.Error, "fields", errorPayload.Fields)
+				nm.RealtimeData.EmitEvent(data.EventError, errorPayload.Error, errorPayload)
+			}
+		case smodels.TypeNetworkDisconnected:
+			var networkDisconnectedResponse smodels.DisconnectNetworkResponse
+			if err := json.Unmarshal(payload, &networkDisconnectedResponse); err != nil {
+				logger.Warn("Failed to unmarshal network disconnected response", "error", err)
+				return
+			}
+
+			// Find the network in RealtimeData and update its LastConnected time
+			networks := nm.RealtimeData.GetNetworks()
+			for i, network := range networks {
+				if network.NetworkID == networkDisconnectedResponse.NetworkID {
+					network.LastConnected = time.Now() // Update last connected time
+					nm.RealtimeData.UpdateNetwork(i, network)
+					break
+				}
+			}
+			nm.refreshNetworkList()
+		case smodels.TypeNetworkJoined:
+			nm.refreshNetworkList()
+		case smodels.TypeNetworkCreated:
+			var createNetworkResponse smodels.CreateNetworkResponse
+			if err := json.Unmarshal(payload, &createNetworkResponse); err != nil {
+				logger.Warn("Failed to unmarshal create network response", "error", err)
+				return
+			}
+			nm.RealtimeData.AddNetwork(data.Network{
+				NetworkID:     createNetworkResponse.NetworkID,
+				NetworkName:   createNetworkResponse.NetworkName,
+				Computers:     createNetworkResponse.Computers,
+				LastConnected: time.Now(),
+			})
+		case smodels.TypeLeaveNetwork:
+			nm.refreshNetworkList()
+		case smodels.TypeKicked:
+			var notification smodels.KickedNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal kicked notification", "error", err)
+				return
+			}
+
+			logger.Info("Kicked from network", "networkID", notification.NetworkID, "reason", notification.Reason)
+			message := fmt.Sprintf("You were removed from network %s.", nm.networkName(notification.NetworkID))
+			if notification.Reason != "" {
+				message += " Reason: " + notification.Reason
+			}
+			nm.RealtimeData.EmitEvent(data.EventKicked, message, notification)
+			nm.refreshNetworkList()
+		case smodels.TypeNetworkDeleted:
+			var notification smodels.NetworkDeletedNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal network deleted notification", "error", err)
+				return
+			}
+			nm.HandleNetworkDeleted(notification.NetworkID)
+		case smodels.TypeServerShutdown:
+			var notification smodels.ServerShutdownNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal server shutdown notification", "error", err)
+				return
+			}
+
+			logger.Warn("Server shutting down", "in", time.Duration(notification.ShutdownIn)*time.Second, "message", notification.Message)
+			message := notification.Message
+			if notification.ShutdownIn > 0 {
+				message = fmt.Sprintf("%s in %d seconds.", strings.TrimSuffix(message, "."), notification.ShutdownIn)
+			}
+			if notification.RestartInfo != "" {
+				message += " " + notification.RestartInfo
+			}
+			nm.RealtimeData.EmitEvent(data.EventServerShutdown, message, notification)
+		case smodels.TypeComputerJoined:
+			var computerJoinedNotification smodels.ComputerJoinedNotification
+			if err := json.Unmarshal(payload, &computerJoinedNotification); err != nil {
+				logger.Warn("Failed to unmarshal computer joined notification", "error", err)
+				return
+			}
+
+			logger.Info("Computer joined network", "computerName", computerJoinedNotification.ComputerName, "ip", computerJoinedNotification.ComputerIP, "networkID", computerJoinedNotification.NetworkID)
+
+			// Find the network and add the new computer
+			networks := nm.RealtimeData.GetNetworks()
+			for i, network := range networks {
+				if network.NetworkID == computerJoinedNotification.NetworkID {
+					// Check if computer already exists to avoid duplicates
+					computerExists := false
+					for _, computer := range network.Computers {
+						if computer.PublicKey == computerJoinedNotification.PublicKey {
+							computerExists = true
+							break
+						}
+					}
+
+					if !computerExists {
+						network.Computers = append(network.Computers, smodels.ComputerInfo{
+							Name:       computerJoinedNotification.ComputerName,
+							ComputerIP: computerJoinedNotification.ComputerIP,
+							PublicKey:  computerJoinedNotification.PublicKey,
+						})
+						nm.RealtimeData.UpdateNetwork(i, network)
+						logger.Debug("Added computer to network", "computerName", computerJoinedNotification.ComputerName, "network", network.NetworkName)
+						nm.RealtimeData.EmitEvent(data.EventComputerJoined, fmt.Sprintf("Computer %s joined network %s", computerJoinedNotification.ComputerName, network.NetworkName), computerJoinedNotification)
+					}
+					break
+				}
+			}
+			nm.refreshNetworkList()
+		case smodels.TypeComputerLeft:
+			var computerLeftNotification smodels.ComputerLeftNotification
+			if err := json.Unmarshal(payload, &computerLeftNotification); err != nil {
+				logger.Warn("Failed to unmarshal computer left notification", "error", err)
+				return
+			}
+
+			logger.Info("Computer left network", "publicKey", computerLeftNotification.PublicKey, "networkID", computerLeftNotification.NetworkID)
+
+			// Find the network and remove the computer
+			networks := nm.RealtimeData.GetNetworks()
+			for i, network := range networks {
+				if network.NetworkID == computerLeftNotification.NetworkID {
+					updatedComputers := []smodels.ComputerInfo{}
+					leftName := ""
+					for _, computer := range network.Computers {
+						if computer.PublicKey != computerLeftNotification.PublicKey {
+							updatedComputers = append(updatedComputers, computer)
+						} else {
+							leftName = computer.Name
+						}
+					}
+					network.Computers = updatedComputers
+					nm.RealtimeData.UpdateNetwork(i, network)
+					logger.Debug("Removed computer from network", "publicKey", computerLeftNotification.PublicKey, "network", network.NetworkName)
+					if leftName != "" {
+						nm.RealtimeData.EmitEvent(data.EventComputerLeft, fmt.Sprintf("Computer %s left network %s", leftName, network.NetworkName), computerLeftNotification)
+					}
+					break
+				}
+			}
+			nm.syncMesh()
+			nm.refreshNetworkList()
+		case smodels.TypeComputerNetworks:
+			// For TypeComputerNetworks, we need to unmarshal the payload to update the networks list
+			var computerNetworksResponse smodels.ComputerNetworksResponse
+			if err := json.Unmarshal(payload, &computerNetworksResponse); err != nil {
+				logger.Warn("Failed to unmarshal computer networks response in handler", "error", err)
+				return
+			}
+
+			logger.Debug("Received networks update", "networks", len(computerNetworksResponse.Networks))
+			for _, network := range computerNetworksResponse.Networks {
+				logger.Debug("Network", "name", network.NetworkName, "networkID", network.NetworkID, "computers", len(network.Computers))
+				for _, computer := range network.Computers {
+					logger.Debug("Computer", "name", computer.Name, "ip", computer.ComputerIP, "publicKey", computer.PublicKey, "online", computer.IsOnline)
+				}
+			}
+
+			// A paged or partial response needs the remaining pages and our cached copies
+			if computerNetworksResponse.NextCursor != "" || hasUnchangedNetworks(computerNetworksResponse.Networks) {
+				go func(first smodels.ComputerNetworksResponse) {
+					defer crash.Recover("network list pages")
+					networks, err := nm.SignalingServer.CompleteComputerNetworks(&first, nm.RealtimeData.GetNetworks())
+					if err != nil {
+						logger.Warn("Failed to fetch remaining networks", "error", err)
+						return
+					}
+					nm.RealtimeData.SetNetworks(networks)
+					nm.refreshNetworkList()
+				}(computerNetworksResponse)
+				return
+			}
+
+			// Update the RealtimeDataLayer with the new networks list
+			nm.RealtimeData.SetNetworks(computerNetworksResponse.Networks)
+			nm.refreshNetworkList()
+		case smodels.TypeComputerConnected:
+			var notification smodels.ComputerConnectedNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal computer connected notification", "error", err)
+				return
+			}
+
+			logger.Info("Computer connected to network", "computerName", notification.ComputerName, "ip", notification.ComputerIP, "networkID", notification.NetworkID)
+
+			// Find the network and update the computer's online status
+			networks := nm.RealtimeData.GetNetworks()
+			for i, network := range networks {
+				if network.NetworkID == notification.NetworkID {
+					for j, computer := range network.Computers {
+						if computer.PublicKey == notification.PublicKey {
+							network.Computers[j].IsOnline = true
+							if notification.NatType != "" {
+								network.Computers[j].NatType = notification.NatType
+							}
+							nm.RealtimeData.UpdateNetwork(i, network)
+							logger.Debug("Updated computer online status", "network", network.NetworkName)
+							nm.RealtimeData.EmitEvent(data.EventComputerConnected, fmt.Sprintf("Computer %s connected to network %s", notification.ComputerName, network.NetworkName), notification)
+							break
+						}
+					}
+					break
+				}
+			}
+			nm.syncMesh()
+			nm.refreshNetworkList()
+		case smodels.TypeNetworkMembers:
+			var notification smodels.NetworkMembersNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal network members notification", "error", err)
+				return
+			}
+
+			logger.Debug("Received network members", "count", len(notification.Computers), "networkID", notification.NetworkID)
+
+			// Merge the member list into the network, keeping our own entry untouched
+			networks := nm.RealtimeData.GetNetworks()
+			for i, network := range networks {
+				if network.NetworkID == notification.NetworkID {
+					for _, member := range notification.Computers {
+						found := false
+						for j, computer := range network.Computers {
+							if computer.PublicKey == member.PublicKey {
+								network.Computers[j] = member
+								found = true
+								break
+							}
+						}
+						if !found {
+							network.Computers = append(network.Computers, member)
+						}
+					}
+					nm.RealtimeData.UpdateNetwork(i, network)
+					break
+				}
+			}
+			nm.refreshNetworkList()
+		case smodels.TypeNetworkRoster:
+			var roster smodels.NetworkRosterNotification
+			if err := json.Unmarshal(payload, &roster); err != nil {
+				logger.Warn("Failed to unmarshal network roster", "error", err)
+				return
+			}
+
+			logger.Debug("Received network roster", "members", len(roster.Members), "networkID", roster.NetworkID)
+
+			// O roster é a lista completa, substitui a que montamos a partir dos eventos
+			networks := nm.RealtimeData.GetNetworks()
+			for i, network := range networks {
+				if network.NetworkID == roster.NetworkID {
+					network.Computers = make([]smodels.ComputerInfo, 0, len(roster.Members))
+					for _, member := range roster.Members {
+						network.Computers = append(network.Computers, member.ComputerInfo)
+					}
+					network.AdminPublicKey = roster.OwnerPublicKey
+					nm.RealtimeData.UpdateNetwork(i, network)
+					break
+				}
+			}
+			nm.syncTunnelPeers()
+			nm.syncMesh()
+			nm.refreshNetworkList()
+		case smodels.TypeNetworkOwnerChanged:
+			var notification smodels.NetworkOwnerChangedNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal network owner changed notification", "error", err)
+				return
+			}
+
+			logger.Info("Network owner changed", "networkID", notification.NetworkID, "owner", notification.OwnerPublicKey)
+
+			networks := nm.RealtimeData.GetNetworks()
+			for i, network := range networks {
+				if network.NetworkID == notification.NetworkID {
+					network.AdminPublicKey = notification.OwnerPublicKey
+					nm.RealtimeData.UpdateNetwork(i, network)
+					break
+				}
+			}
+			nm.refreshNetworkList()
+		case smodels.TypeNetworkSubnetChanged:
+			var notification smodels.NetworkSubnetChangedNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal network subnet changed notification", "error", err)
+				return
+			}
+
+			logger.Info("Network subnet changed", "networkID", notification.NetworkID, "subnet", notification.Subnet)
+			nm.handleSubnetChanged(notification)
+		case smodels.TypeWakeRelay:
+			var notification smodels.WakeRelayNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal wake relay notification", "error", err)
+				return
+			}
+
+			nm.handleWakeRelay(notification)
+		case smodels.TypeNetworkExpiryWarning:
+			var warning smodels.NetworkExpiryWarningNotification
+			if err := json.Unmarshal(payload, &warning); err != nil {
+				logger.Warn("Failed to unmarshal network expiry warning", "error", err)
+				return
+			}
+
+			logger.Info("Network will be deleted for inactivity", "networkID", warning.NetworkID, "deletesAt", warning.DeletesAt)
+			nm.RealtimeData.EmitEvent(data.EventNetworkExpiring,
+				fmt.Sprintf("Network %s has been inactive and will be deleted on %s.", warning.NetworkName, warning.DeletesAt.Local().Format("Jan 2, 15:04")),
+				warning)
+		case smodels.TypeServerCapabilities:
+			var caps smodels.ServerCapabilitiesNotification
+			if err := json.Unmarshal(payload, &caps); err != nil {
+				logger.Warn("Failed to unmarshal server capabilities", "error", err)
+				return
+			}
+			nm.setRelayAvailable(caps)
+
+			// Avisar quando o servidor fala outra versão do protocolo
+			if mismatch := sclient.ProtocolMismatch(caps.ProtocolVersion); mismatch != "" {
+				nm.RealtimeData.EmitEvent(data.EventServerNotice, mismatch, smodels.ServerNoticeNotification{
+					ID:      fmt.Sprintf("protocol-%d", caps.ProtocolVersion),
+					Message: fmt.Sprintf("Server %s uses protocol version %d and this client %d: %s.", caps.ServerVersion, caps.ProtocolVersion, smodels.ProtocolVersion, mismatch),
+					Level:   smodels.NoticeLevelWarning,
+					SentAt:  time.Now(),
+				})
+			}
+		case smodels.TypeServerNotice:
+			var notice smodels.ServerNoticeNotification
+			if err := json.Unmarshal(payload, &notice); err != nil {
+				logger.Warn("Failed to unmarshal server notice", "error", err)
+				return
+			}
+
+			logger.Info("Server notice", "id", notice.ID, "message", notice.Message)
+			nm.RealtimeData.EmitEvent(data.EventServerNotice, notice.Message, notice)
+		case smodels.TypeSessionReplaced:
+			var notification smodels.SessionReplacedNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal session replaced notification", "error", err)
+				return
+			}
+
+			// Não reconectar sozinho, senão duas máquinas com a mesma chave se derrubam em loop
+			logger.Warn("Session replaced by a new connection", "from", notification.ReplacedFrom)
+			nm.sessionReplaced.Store(true)
+			nm.connectionState = ConnectionStateDisconnected
+			nm.RealtimeData.SetConnectionState(data.StateDisconnected)
+			nm.RealtimeData.SetStatusMessage("Signed in elsewhere")
+			nm.RealtimeData.MarkNetworksOffline()
+			nm.stopTunnel()
+			nm.closeAllPeers()
+			nm.RealtimeData.EmitEvent(data.EventServerNotice, "session replaced", smodels.ServerNoticeNotification{
+				ID:      "session-replaced",
+				Message: fmt.Sprintf("This key connected again from %s, so this session was closed. Reconnect to take it back.", notification.ReplacedFrom),
+				Level:   smodels.NoticeLevelWarning,
+				SentAt:  time.Now(),
+			})
+			nm.refreshUI()
+		case smodels.TypeClientIPInfo:
+			var info smodels.ClientIPInfoNotification
+			if err := json.Unmarshal(payload, &info); err != nil {
+				logger.Warn("Failed to unmarshal client IP info", "error", err)
+				return
+			}
+
+			logger.Debug("Server sees this computer", "ip", info.IP, "ipVersion", info.IPVersion, "forwarded", info.Forwarded)
+			nm.RealtimeData.SetPublicIP(info.IP)
+		case smodels.TypeComputerNatType:
+			var notification smodels.ComputerNatTypeNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal computer NAT type notification", "error", err)
+				return
+			}
+
+			logger.Debug("Computer NAT type", "publicKey", notification.PublicKey, "networkID", notification.NetworkID, "natType", notification.NatType)
+
+			networks := nm.RealtimeData.GetNetworks()
+			for i, network := range networks {
+				if network.NetworkID != notification.NetworkID {
+					continue
+				}
+				for j, computer := range network.Computers {
+					if computer.PublicKey == notification.PublicKey {
+						network.Computers[j].NatType = notification.NatType
+						nm.RealtimeData.UpdateNetwork(i, network)
+						break
+					}
+				}
+				break
+			}
+			nm.refreshNetworkList()
+		case smodels.TypeComputerDisconnected:
+			var notification smodels.ComputerDisconnectedNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal computer disconnected notification", "error", err, "payload", string(payload))
+				return
+			}
+
+			logger.Info("Computer disconnected from network", "publicKey", notification.PublicKey, "networkID", notification.NetworkID)
+
+			// Find the network and update the computer's online status
+			networks := nm.RealtimeData.GetNetworks()
+			for i, network := range networks {
+				if network.NetworkID == notification.NetworkID {
+					for j, computer := range network.Computers {
+						if computer.PublicKey == notification.PublicKey {
+							network.Computers[j].IsOnline = false
+							nm.RealtimeData.UpdateNetwork(i, network)
+							logger.Debug("Updated computer online status", "network", network.NetworkName)
+							break
+						}
+					}
+					break
+				}
+			}
+			nm.syncMesh()
+			nm.refreshNetworkList()
+		case smodels.TypeComputerRenamed:
+			var notification smodels.ComputerRenamedNotification
+			if err := json.Unmarshal(payload, &notification); err != nil {
+				logger.Warn("Failed to unmarshal computer renamed notification", "error", err)
+				return
+			}
+
+			logger.Info("Computer renamed", "publicKey", notification.PublicKey, "networkID", notification.NetworkID, "name", notification.NewComputerName)
+
+			// Find the network and update the computer's name
+			networks := nm.RealtimeData.GetNetworks()
+			for i, network := range networks {
+				if network.NetworkID == notification.NetworkID {
+					for j, computer := range network.Computers {
+						if computer.PublicKey == notification.PublicKey {
+							network.Computers[j].Name = notification.NewComputerName
+							nm.RealtimeData.UpdateNetwork(i, network)
+							logger.Debug("Updated computer name", "network", network.NetworkName)
+							break
+						}
+					}
+					break
+				}
+			}
+			nm.refreshNetworkList()
+		case smodels.TypeSdpOffer:
+			var offer smodels.SdpOffer
+			if err := json.Unmarshal(payload, &offer); err != nil {
+				logger.Warn("Failed to unmarshal sdp offer", "error", err)
+				return
+			}
+
+			answer, err := nm.answerOffer(offer)
+			if err != nil {
+				logger.Warn("Failed to answer offer from peer", "peer", offer.SenderPublicKey, "error", err)
+				return
+			}
+			if answer == nil {
+				return
+			}
+
+			err = nm.SignalingServer.SendSignal(smodels.TypeSdpAnswer, smodels.SdpAnswer{
+				SenderPublicKey: nm.ConfigManager.GetConfig().PublicKey,
+				TargetPublicKey: offer.SenderPublicKey,
+				SDP:             answer.SDP,
+			})
+			if err != nil {
+				logger.Warn("Failed to send SDP answer to peer", "peer", offer.SenderPublicKey, "error", err)
+				return
+			}
+		case smodels.TypeSdpAnswer:
+			var answer smodels.SdpAnswer
+			if err := json.Unmarshal(payload, &answer); err != nil {
+				logger.Warn("Failed to unmarshal sdp answer", "error", err)
+				return
+			}
+
+			peerWebRTCManager, ok := nm.peerConnection(answer.SenderPublicKey)
+			if !ok {
+				logger.Debug("No connection to peer for the received answer", "peer", answer.SenderPublicKey)
+				return
+			}
+
+			if err := peerWebRTCManager.HandleAnswer(webrtc.SessionDescription{
+				Type: webrtc.SDPTypeAnswer,
+				SDP:  answer.SDP,
+			}); err != nil {
+				logger.Warn("Failed to set remote description for peer", "peer", answer.SenderPublicKey, "error", err)
+				return
+			}
+		case smodels.TypeRelayFrame:
+			var frame smodels.RelayFrame
+			if err := json.Unmarshal(payload, &frame); err != nil {
+				logger.Warn("Failed to unmarshal relay frame", "error", err)
+				return
+			}
+			nm.handleRelayFrame(frame)
+		case smodels.TypeIceCandidate:
+			var candidate smodels.IceCandidate
+			if err := json.Unmarshal(payload, &candidate); err != nil {
+				logger.Warn("Failed to unmarshal ice candidate", "error", err)
+				return
+			}
+
+			peerWebRTCManager, ok := nm.peerConnection(candidate.SenderPublicKey)
+			if !ok {
+				logger.Debug("No connection to peer for the received ICE candidate", "peer", candidate.SenderPublicKey)
+				return
+			}
+
+			if err := peerWebRTCManager.AddICECandidate(webrtc.ICECandidateInit{
+				Candidate:     candidate.Candidate,
+				SDPMid:        &candidate.SDPMid,
+				SDPMLineIndex: &candidate.SDPMLineIndex,
+			}); err != nil {
+				logger.Warn("Failed to add ICE candidate for peer", "peer", candidate.SenderPublicKey, "error", err)
+				return
+			}
+		}
+	}
+	// Um cliente anterior ainda reconectando disputaria a sessão com este
+	if nm.SignalingServer != nil {
+		nm.SignalingServer.StopReconnect()
+	}
+	nm.SignalingServer = sclient.NewSignalingClient(publicKey, signalingHandler, sclient.WithReconnect(sclient.DefaultReconnectPolicy))
+	nm.SignalingServer.DisconnectHandler = nm.handleConnectionLost
+	nm.SignalingServer.OnReconnecting = nm.handleReconnecting
+	nm.SignalingServer.OnReconnected = nm.handleReconnected
+	nm.SignalingServer.Pins = nm.ConfigManager.GetConfig().serverPins(serverAddress)
+
+	// The private key answers the server authentication challenge
+	if privateKeyBytes, err := base64.StdEncoding.DecodeString(privateKeyStr); err == nil && len(privateKeyBytes) == ed25519.PrivateKeySize {
+		nm.SignalingServer.SetPrivateKey(ed25519.PrivateKey(privateKeyBytes))
+		nm.identity = ed25519.PrivateKey(privateKeyBytes)
+	} else {
+		logger.Warn("Invalid private key in config, connecting without authentication")
+	}
+
+	// Connect to signaling server
+	err := nm.SignalingServer.Connect(serverAddress)
+	if err != nil {
+		nm.connectionState = ConnectionStateDisconnected
+		nm.RealtimeData.SetConnectionState(data.StateDisconnected)
+		nm.RealtimeData.SetStatusMessage("Connection failed")
+		return fmt.Errorf("failed to connect to signaling server: %w", err)
+	}
+
+	nm.connected()
+	return nil
+}
+
+// connected marks the connection as up, after Connect or a reconnect, and starts the
+// probes that depend on it
+func (nm *NetworkManager) connected() {
+	nm.connectionState = ConnectionStateConnected
+	nm.RealtimeData.SetConnectionState(data.StateConnected)
+	nm.RealtimeData.SetStatusMessage("Connected")
+
+	logger.Debug("Awaiting network list from server")
+
+	// Probing takes a few round trips, don't hold up the connection for it
+	crash.Go("NAT detection", nm.detectNAT)
+	// O endereço público pode ter mudado, e o servidor guarda o de Wake-on-LAN só na memória
+	nm.resetWakeInfo()
+	crash.Go("wake info", nm.ReportWakeInfo)
+}
+
+// SessionReplaced reports whether the server dropped the connection because this key
+// connected again from somewhere else, in which case reconnecting would take turns with it
+func (nm *NetworkManager) SessionReplaced() bool {
+	return nm.sessionReplaced.Load()
+}
+
+// GetConnectionState returns the connection state
+func (nm *NetworkManager) GetConnectionState() ConnectionState {
+	return nm.connectionState
+}
+
+// UpdateClientInfo envia as informações do cliente para o servidor
+func (nm *NetworkManager) UpdateClientInfo() {
+	if nm.connectionState != ConnectionStateConnected {
+		logger.Debug("Cannot update client info: not connected to server")
+		return
+	}
+
+	config := nm.ConfigManager.GetConfig()
+	clientName := config.ComputerName
+
+	logger.Debug("Sending client info to server", "computerName", clientName)
+
+	// Criar a mensagem
+	msg := smodels.UpdateClientInfoRequest{
+		ClientName: clientName,
+		BaseRequest: smodels.BaseRequest{
+			PublicKey: config.PublicKey,
+		},
+	}
+
+	// Enviar a mensagem
+	_, err := nm.SignalingServer.SendMessage(smodels.TypeUpdateClientInfo, msg)
+	if err != nil {
+		logger.Warn("Failed to send client info", "error", err)
+	}
+}
+
+// CreateNetwork creates a new network
+func (nm *NetworkManager) CreateNetwork(name string, pin string) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return sclient.ErrNotConnected
+	}
+
+	// Create network
+	res, err := nm.SignalingServer.CreateNetwork(name, pin, nm.ConfigManager.GetConfig().ComputerName)
+	if err != nil {
+		return fmt.Errorf("failed to create network: %w", err)
+	}
+
+	// Check if the network was successfully created and has a valid ID
+	if res == nil || res.NetworkID == "" {
+		return fmt.Errorf("failed to create network: invalid server response")
+	}
+
+	logger.Info("Network created", "networkID", res.NetworkID, "name", name)
+	nm.rememberNetworkPIN(res.NetworkID, pin)
+
+	// Store network information for current connection
+	nm.NetworkID = res.NetworkID
+
+	// Update data layer
+	nm.RealtimeData.SetNetworkInfo(res.NetworkID)
+	nm.RealtimeData.EmitEvent(data.EventNetworkJoined, res.NetworkID, nil)
+
+	nm.startTunnel(nm.ownComputerIP(res.Computers))
+	nm.syncMesh()
+
+	// Update UI
+	nm.refreshUI()
+
+	return nil
+}
+
+// JoinNetwork joins a network
+func (nm *NetworkManager) JoinNetwork(networkID string, pin string, computername string) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return sclient.ErrNotConnected
+	}
+
+	// Join network
+	res, err := nm.SignalingServer.JoinNetwork(networkID, pin, computername)
+	if err != nil {
+		return fmt.Errorf("failed to join network: %w", err)
+	}
+
+	// Use networkName from the response
+	networkName := res.NetworkName
+
+	logger.Info("Network joined", "networkID", networkID, "name", networkName)
+	nm.rememberNetworkPIN(networkID, pin)
+
+	// Store network information for current connection
+	nm.NetworkID = networkID
+
+	// Update data layer
+	nm.RealtimeData.SetNetworkInfo(networkID)
+	nm.RealtimeData.SetComputerIP(res.ComputerIP)
+	nm.RealtimeData.EmitEvent(data.EventNetworkJoined, networkID, nil)
+
+	nm.startTunnel(res.ComputerIP)
+	nm.syncMesh()
+
+	if err := nm.ConfigManager.UpdateLastNetwork(networkID); err != nil {
+		logger.Error("Failed to save the last network", "error", err)
+	}
+
+	// Update UI
+	nm.refreshUI()
+
+	return nil
+}
+
+// ConnectNetwork connects to a previously joined network
+func (nm *NetworkManager) ConnectNetwork(networkID string) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return nm.whenOffline("connect to network "+networkID, func() error { return nm.ConnectNetwork(networkID) })
+	}
+
+	// Get computer name from config
+	config := nm.ConfigManager.GetConfig()
+	computerName := config.ComputerName
+
+	// Connect to network
+	res, err := nm.SignalingServer.ConnectNetwork(networkID, computerName)
+	if err != nil {
+		return fmt.Errorf("failed to connect to network: %w", err)
+	}
+
+	// Use networkName from the response
+	networkName := res.NetworkName
+
+	// Find the network in memory to update lastConnected time
+	networkExists := false
+	for i, existingNetwork := range nm.RealtimeData.GetNetworks() {
+		if existingNetwork.NetworkID == networkID {
+			networkExists = true
+
+			// Update lastConnected time
+			existingNetwork.LastConnected = time.Now()
+			nm.RealtimeData.UpdateNetwork(i, existingNetwork)
+			break
+		}
+	}
+
+	if !networkExists {
+		return fmt.Errorf("network not found in local storage")
+	}
+
+	logger.Info("Network connected", "networkID", networkID, "name", networkName)
+
+	// Store network information for current connection
+	nm.NetworkID = networkID
+
+	// Update data layer (without password since we don't store it)
+	nm.RealtimeData.SetNetworkInfo(networkID)
+	nm.RealtimeData.SetComputerIP(res.ComputerIP)
+	nm.RealtimeData.EmitEvent(data.EventNetworkJoined, networkID, nil)
+
+	nm.startTunnel(res.ComputerIP)
+	nm.syncMesh()
+
+	if err := nm.ConfigManager.UpdateLastNetwork(networkID); err != nil {
+		logger.Error("Failed to save the last network", "error", err)
+	}
+
+	// Refresh network list now that we have re-connected to the network
+	nm.refreshNetworkList()
+
+	// Update UI
+	nm.refreshUI()
+
+	return nil
+}
+
+// DisconnectNetwork disconnects from a network without leaving it
+func (nm *NetworkManager) DisconnectNetwork(networkID string) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return nm.whenOffline("disconnect from network "+networkID, func() error { return nm.DisconnectNetwork(networkID) })
+	}
+
+	logger.Info("Disconnecting from network", "networkID", networkID)
+
+	// Disconnect from network
+	_, err := nm.SignalingServer.DisconnectNetwork(networkID)
+	if err != nil {
+		logger.Warn("Failed to disconnect from network", "networkID", networkID, "error", err)
+		return fmt.Errorf("failed to disconnect from network: %w", err)
+	}
+
+	// If we're disconnecting from the current network, clear our network information
+	if nm.NetworkID == networkID {
+		nm.NetworkID = ""
+		if err := nm.ConfigManager.UpdateLastNetwork(""); err != nil {
+			logger.Error("Failed to save the last network", "error", err)
+		}
+		nm.stopTunnel()
+		nm.closeAllPeers()
+
+		// Update data layer
+		nm.RealtimeData.SetNetworkInfo("Not connected")
+		nm.RealtimeData.SetComputerIP("0.0.0.0")
+		nm.RealtimeData.EmitEvent(data.EventNetworkDisconnected, networkID, nil)
+	}
+
+	// Update the IsOnline status of all computers in the RealtimeData networks list
+	networks := nm.RealtimeData.GetNetworks()
+	for i, network := range networks {
+		if network.NetworkID == networkID {
+			for j := range network.Computers {
+
+				network.Computers[j].IsOnline = false
+				nm.RealtimeData.UpdateNetwork(i, network)
+
+			}
+			break
+		}
+	}
+
+	// Refresh the network list UI
+	nm.refreshNetworkList()
+
+	// Update UI
+	nm.refreshUI()
+
+	return nil
+}
+
+// LeaveNetwork leaves the current network
+func (nm *NetworkManager) LeaveNetwork() error {
+	if nm.connectionState != ConnectionStateConnected {
+		networkID := nm.NetworkID
+		return nm.whenOffline("leave network "+networkID, func() error { return nm.LeaveNetworkById(networkID) })
+	}
+
+	logger.Info("Leaving network", "networkID", nm.NetworkID)
+
+	// Store network ID before clearing
+	networkID := nm.NetworkID
+
+	// Leave network
+	_, err := nm.SignalingServer.LeaveNetwork(networkID)
+	if err != nil {
+		logger.Warn("Failed to leave network", "error", err)
+		return fmt.Errorf("failed to leave network: %w", err)
+	}
+
+	// Remove the network from memory
+	nm.RealtimeData.RemoveNetwork(networkID)
+	nm.forgetAutoConnect(networkID)
+	nm.forgetNetworkKeys(networkID)
+
+	// Clear network information
+	nm.NetworkID = ""
+	nm.stopTunnel()
+	nm.closeAllPeers()
+
+	// Update data layer
+	nm.RealtimeData.SetNetworkInfo("Not connected")
+	nm.RealtimeData.SetComputerIP("0.0.0.0")
+	nm.RealtimeData.EmitEvent(data.EventNetworkLeft, networkID, nil)
+
+	// Refresh the network list UI
+	nm.refreshNetworkList()
+
+	// Update UI
+	nm.refreshUI()
+
+	return nil
+}
+
+// RefreshNetworks asks the server for the networks this computer joined and stores them,
+// instead of waiting for the list the server pushes after connecting
+func (nm *NetworkManager) RefreshNetworks() ([]data.Network, error) {
+	if nm.connectionState != ConnectionStateConnected {
+		return nil, sclient.ErrNotConnected
+	}
+
+	networks, err := nm.SignalingServer.CompleteComputerNetworks(nil, nm.RealtimeData.GetNetworks())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	nm.RealtimeData.SetNetworks(networks)
+	nm.refreshNetworkList()
+	return networks, nil
+}
+
+// KeepNetworkAlive marks an owned network as active so the server does not delete it
+func (nm *NetworkManager) KeepNetworkAlive(networkID string) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return nm.whenOffline("keep network "+networkID+" alive", func() error { return nm.KeepNetworkAlive(networkID) })
+	}
+
+	res, err := nm.SignalingServer.KeepNetworkAlive(networkID)
+	if err != nil {
+		return fmt.Errorf("failed to keep network alive: %w", err)
+	}
+
+	logger.Info("Network kept alive", "networkID", res.NetworkID, "deletesAt", res.DeletesAt)
+	return nil
+}
+
+// GetNetworkStats fetches the activity counters of an owned network
+func (nm *NetworkManager) GetNetworkStats(networkID string) (*smodels.NetworkStatsResponse, error) {
+	if nm.connectionState != ConnectionStateConnected {
+		return nil, sclient.ErrNotConnected
+	}
+
+	stats, err := nm.SignalingServer.GetNetworkStats(networkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network statistics: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ChangeNetworkPIN replaces the PIN of a network owned by this computer
+func (nm *NetworkManager) ChangeNetworkPIN(networkID, pin string) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return nm.whenOffline("change the PIN of network "+networkID, func() error { return nm.ChangeNetworkPIN(networkID, pin) })
+	}
+
+	if _, err := nm.SignalingServer.ChangePIN(networkID, pin); err != nil {
+		return fmt.Errorf("failed to change network PIN: %w", err)
+	}
+
+	logger.Info("Changed network PIN", "networkID", networkID)
+	nm.rememberNetworkPIN(networkID, pin)
+	return nil
+}
+
+// KickComputer removes a member from an owned network; it needs the PIN to join again
+func (nm *NetworkManager) KickComputer(networkID, publicKey string) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return nm.whenOffline("kick a computer from network "+networkID, func() error { return nm.KickComputer(networkID, publicKey) })
+	}
+
+	if _, err := nm.SignalingServer.KickMember(networkID, publicKey); err != nil {
+		return fmt.Errorf("failed to kick computer: %w", err)
+	}
+
+	logger.Info("Kicked computer", "networkID", networkID, "publicKey", publicKey)
+	return nil
+}
+
+// BanComputer removes a member from an owned network and keeps its key from joining again.
+// The reason, which may be empty, is shown to the banned computer.
+func (nm *NetworkManager) BanComputer(networkID, publicKey, reason string) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return nm.whenOffline("ban a computer from network "+networkID, func() error { return nm.BanComputer(networkID, publicKey, reason) })
+	}
+
+	if _, err := nm.SignalingServer.BanMember(networkID, publicKey, reason); err != nil {
+		return fmt.Errorf("failed to ban computer: %w", err)
+	}
+
+	logger.Info("Banned computer", "networkID", networkID, "publicKey", publicKey)
+	return nil
+}
+
+// SetIPReserved keeps the current IP of a member for it, or releases it. The roster the
+// server sends afterwards updates the member list.
+func (nm *NetworkManager) SetIPReserved(networkID, publicKey string, reserved bool) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return nm.whenOffline("change an IP reservation of network "+networkID, func() error { return nm.SetIPReserved(networkID, publicKey, reserved) })
+	}
+
+	resp, err := nm.SignalingServer.ReserveIP(networkID, publicKey, !reserved)
+	if err != nil {
+		return fmt.Errorf("failed to change IP reservation: %w", err)
+	}
+
+	logger.Info("Changed IP reservation", "networkID", networkID, "ip", resp.IP, "reserved", resp.Reserved)
+	return nil
+}
+
+// TransferOwnership hands an owned network to another member; we stay in it as a regular member
+func (nm *NetworkManager) TransferOwnership(networkID, publicKey string) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return nm.whenOffline("transfer ownership of network "+networkID, func() error { return nm.TransferOwnership(networkID, publicKey) })
+	}
+
+	if _, err := nm.SignalingServer.TransferOwnership(networkID, publicKey); err != nil {
+		return fmt.Errorf("failed to transfer ownership: %w", err)
+	}
+
+	logger.Info("Transferred network ownership", "networkID", networkID, "to", publicKey)
+	return nil
+}
+
+// LeaveNetworkById leaves a specific network by ID
+func (nm *NetworkManager) LeaveNetworkById(networkID string) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return nm.whenOffline("leave network "+networkID, func() error { return nm.LeaveNetworkById(networkID) })
+	}
+
+	logger.Info("Leaving network", "networkID", networkID)
+
+	// Leave network
+	_, err := nm.SignalingServer.LeaveNetwork(networkID)
+	if err != nil {
+		logger.Warn("Failed to leave network", "error", err)
+		return fmt.Errorf("failed to leave network: %w", err)
+	}
+
+	// Remove network from memory
+	nm.RealtimeData.RemoveNetwork(networkID)
+	nm.forgetAutoConnect(networkID)
+	nm.forgetNetworkKeys(networkID)
+
+	// If we're leaving the current network, clear our network information
+	if nm.NetworkID == networkID {
+		nm.NetworkID = ""
+		nm.stopTunnel()
+		nm.closeAllPeers()
+
+		// Update data layer
+		nm.RealtimeData.SetNetworkInfo("Not connected")
+	}
+
+	// Emit the network left event regardless
+	nm.RealtimeData.EmitEvent(data.EventNetworkLeft, networkID, nil)
+
+	// Refresh the network list UI
+	nm.refreshNetworkList()
+
+	// Update UI
+	nm.refreshUI()
+
+	return nil
+}
+
+// networkName retorna o nome de uma rede da lista, ou o ID quando ela não está lá
+func (nm *NetworkManager) networkName(networkID string) string {
+	for _, network := range nm.RealtimeData.GetNetworks() {
+		if network.NetworkID == networkID && network.NetworkName != "" {
+			return network.NetworkName
+		}
+	}
+	return networkID
+}
+
+// computerName retorna o nome de um membro da rede, ou o começo da chave quando ele não
+// está na lista
+func (nm *NetworkManager) computerName(networkID, publicKey string) string {
+	for _, network := range nm.RealtimeData.GetNetworks() {
+		if network.NetworkID != networkID {
+			continue
+		}
+		for _, computer := range network.Computers {
+			if computer.PublicKey == publicKey && computer.Name != "" {
+				return computer.Name
+			}
+		}
+	}
+	return publicKey[:min(10, len(publicKey))]
+}
+
+// HandleNetworkDeleted handles when a network has been deleted
+func (nm *NetworkManager) HandleNetworkDeleted(networkID string) error {
+	logger.Info("Network deleted", "networkID", networkID)
+
+	// If we're in this network, clear our network data
+	if nm.NetworkID == networkID {
+		nm.NetworkID = ""
+		nm.stopTunnel()
+		nm.closeAllPeers()
+
+		// Update data layer
+		nm.RealtimeData.SetNetworkInfo("Not connected")
+	}
+
+	// Remove network from memory, keeping its name for the event
+	networkName := nm.networkName(networkID)
+	nm.RealtimeData.RemoveNetwork(networkID)
+	nm.forgetAutoConnect(networkID)
+	nm.forgetNetworkKeys(networkID)
+
+	// Emit the event
+	nm.RealtimeData.EmitEvent(data.EventNetworkDeleted, networkID, networkName)
+
+	// Refresh the network list UI
+	nm.refreshNetworkList()
+
+	// Update UI
+	nm.refreshUI()
+
+	return nil
+}
+
+// handleICECandidate handles a new ICE candidate
+func (nm *NetworkManager) handleICECandidate(c *webrtc.ICECandidate, targetPublicKey string) {
+	if c == nil {
+		return
+	}
+
+	err := nm.SignalingServer.SendSignal(smodels.TypeIceCandidate, smodels.IceCandidate{
+		SenderPublicKey: nm.ConfigManager.GetConfig().PublicKey,
+		TargetPublicKey: targetPublicKey,
+		Candidate:       c.ToJSON().Candidate,
+		SDPMid:          *c.ToJSON().SDPMid,
+		SDPMLineIndex:   *c.ToJSON().SDPMLineIndex,
+	})
+	if err != nil {
+		logger.Warn("Failed to send ICE candidate", "peer", targetPublicKey, "error", err)
+	}
+}
+
+// handlePeerConnectionStateChange handles changes in a peer's WebRTC connection state
+func (nm *NetworkManager) handlePeerConnectionStateChange(peerPublicKey string, peer *clientwebrtc_impl.WebRTCManager, s webrtc.PeerConnectionState) {
+	logger.Debug("Peer connection state changed", "peer", peerPublicKey, "state", s.String())
+
+	// Uma conexão já substituída não interessa a quem acompanha os peers
+	if current, ok := nm.peerConnection(peerPublicKey); !ok || current != peer {
+		return
+	}
+	switch s {
+	case webrtc.PeerConnectionStateConnected:
+		nm.RealtimeData.EmitEvent(data.EventPeerConnected,
+			fmt.Sprintf("Direct connection to %s established", nm.computerName(nm.NetworkID, peerPublicKey)), peerPublicKey)
+	case webrtc.PeerConnectionStateFailed:
+		nm.RealtimeData.EmitEvent(data.EventPeerLost,
+			fmt.Sprintf("Direct connection to %s lost", nm.computerName(nm.NetworkID, peerPublicKey)), peerPublicKey)
+		nm.dropFailedPeer(peerPublicKey, peer)
+	}
+}
+
+// handlePeerICEConnectionStateChange handles changes in a peer's ICE connection state
+func (nm *NetworkManager) handlePeerICEConnectionStateChange(peerPublicKey string, peer *clientwebrtc_impl.WebRTCManager, s webrtc.ICEConnectionState) {
+	logger.Debug("Peer ICE connection state changed", "peer", peerPublicKey, "state", s.String())
+	if s == webrtc.ICEConnectionStateDisconnected {
+		nm.restartDisconnectedPeer(peerPublicKey, peer)
+	}
+}
+
+// handlePeerDataChannelOpen handles the event when a data channel opens for a peer
+func (nm *NetworkManager) handlePeerDataChannelOpen(peerPublicKey string) {
+	logger.Debug("Data channel opened", "peer", peerPublicKey)
+	nm.startSecureSession(peerPublicKey)
+	crash.Go("resend chat messages", func() { nm.resendChatMessages(peerPublicKey) })
+}
+
+// handlePeerDataChannelMessage handles incoming data channel messages from a peer
+func (nm *NetworkManager) handlePeerDataChannelMessage(peerPublicKey string, msg []byte) {
+	logger.Debug("Message from peer", "peer", peerPublicKey, "message", string(msg))
+	if nm.onWebRTCMessageReceived != nil {
+		nm.onWebRTCMessageReceived(peerPublicKey, string(msg))
+	}
+}
+
+// sendPeerMessage sends a JSON message as text over the data channel of a peer. Relayed
+// peers have no data channel, so it fails for them.
+func (nm *NetworkManager) sendPeerMessage(peerPublicKey string, message interface{}) error {
+	peer, ok := nm.peerConnection(peerPublicKey)
+	if !ok || nm.IsRelayed(peerPublicKey) {
+		return network.ErrPeerUnreachable
+	}
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return peer.SendMessage(string(content))
+}
+
+// peerConnection returns the WebRTC manager of a peer, if a connection was started
+func (nm *NetworkManager) peerConnection(peerPublicKey string) (*clientwebrtc_impl.WebRTCManager, bool) {
+	nm.peersMu.Lock()
+	defer nm.peersMu.Unlock()
+
+	peer, ok := nm.peerConnections[peerPublicKey]
+	return peer, ok
+}
+
+// setPeerConnection records the WebRTC manager of a peer. A relayed peer only offers
+// again after restarting, so its traffic goes back to the new connection.
+func (nm *NetworkManager) setPeerConnection(peerPublicKey string, peer *clientwebrtc_impl.WebRTCManager) {
+	nm.peersMu.Lock()
+	defer nm.peersMu.Unlock()
+
+	nm.peerConnections[peerPublicKey] = peer
+	delete(nm.relayed, peerPublicKey)
+	nm.forgetSession(peerPublicKey)
+}
+
+// ConnectToPeer initiates a WebRTC connection with a peer
+func (nm *NetworkManager) ConnectToPeer(peerPublicKey string) error {
+	peerWebRTCManager, err := nm.newPeer(peerPublicKey)
+	if err != nil {
+		return err
+	}
+
+	// Check if a connection already exists for this peer, the mesh may be dialing it too
+	nm.peersMu.Lock()
+	if _, ok := nm.peerConnections[peerPublicKey]; ok {
+		nm.peersMu.Unlock()
+		peerWebRTCManager.Close()
+		return nil
+	}
+	nm.peerConnections[peerPublicKey] = peerWebRTCManager
+	nm.forgetSession(peerPublicKey)
+	nm.peersMu.Unlock()
+
+	// Create offer for this peer
+	offer, err := peerWebRTCManager.CreateOffer(false)
+	if err != nil {
+		nm.closePeer(peerPublicKey)
+		return fmt.Errorf("failed to create offer for peer %s: %w", peerPublicKey, err)
+	}
+
+	// Send offer via signaling server
+	err = nm.SignalingServer.SendSignal(smodels.TypeSdpOffer, smodels.SdpOffer{
+		SenderPublicKey: nm.ConfigManager.GetConfig().PublicKey,
+		TargetPublicKey: peerPublicKey,
+		SDP:             offer.SDP,
+	})
+	if err != nil {
+		nm.closePeer(peerPublicKey)
+		return fmt.Errorf("failed to send sdp offer for peer %s: %w", peerPublicKey, err)
+	}
+
+	logger.Debug("Initiated WebRTC connection with peer", "peer", peerPublicKey)
+	return nil
+}
+
+// answerOffer applies an offer from a peer and returns the answer to send back. It returns
+// no answer when the offer lost the glare rule: we are the offerer and already dialed.
+func (nm *NetworkManager) answerOffer(offer smodels.SdpOffer) (*webrtc.SessionDescription, error) {
+	description := webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer.SDP}
+
+	peerWebRTCManager, ok := nm.peerConnection(offer.SenderPublicKey)
+	if ok && nm.isOfferer(offer.SenderPublicKey) {
+		logger.Debug("Ignoring offer from peer, our own offer takes precedence", "peer", offer.SenderPublicKey)
+		return nil, nil
+	}
+
+	// A renegotiation reuses the connection, an offer it rejects comes from a restarted peer
+	if ok {
+		answer, err := peerWebRTCManager.HandleOfferAndCreateAnswer(description)
+		if err == nil {
+			return answer, nil
+		}
+		logger.Info("Replacing connection to peer after a new offer", "peer", offer.SenderPublicKey, "error", err)
+		nm.closePeer(offer.SenderPublicKey)
+	}
+
+	logger.Debug("Creating connection to peer for the received offer", "peer", offer.SenderPublicKey)
+	peerWebRTCManager, err := nm.newPeer(offer.SenderPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	nm.setPeerConnection(offer.SenderPublicKey, peerWebRTCManager)
+
+	return peerWebRTCManager.HandleOfferAndCreateAnswer(description)
+}
+
+// Disconnect disconnects from the VPN network
+func (nm *NetworkManager) Disconnect() error {
+	nm.stopReconnect()
+	if nm.connectionState == ConnectionStateDisconnected {
+		return nil
+	}
+
+	// Explicitly disconnect from all joined networks before disconnecting from the signaling server
+	// This ensures the server is notified of our disconnection from each network.
+	networksToDisconnect := nm.RealtimeData.GetNetworks()
+	for _, network := range networksToDisconnect {
+		logger.Debug("Disconnecting from network before disconnecting from the server", "networkID", network.NetworkID)
+		err := nm.DisconnectNetwork(network.NetworkID)
+		if err != nil {
+			logger.Warn("Failed to disconnect from network", "networkID", network.NetworkID, "error", err)
+		}
+	}
+
+	// Disconnect from signaling server
+	if nm.SignalingServer != nil {
+		err := nm.SignalingServer.Disconnect()
+		if err != nil {
+			logger.Warn("Failed to disconnect from the signaling server", "error", err)
+		}
+	}
+
+	// Stop virtual network
+	if nm.VirtualNetwork != nil {
+		nm.VirtualNetwork = nil
+	}
+
+	nm.stopTunnel()
+
+	// Close all WebRTC connections
+	nm.closeAllPeers()
+
+	// Set state to disconnected
+	nm.connectionState = ConnectionStateDisconnected
+	nm.RealtimeData.SetConnectionState(data.StateDisconnected)
+	nm.RealtimeData.SetStatusMessage("Disconnected")
+	nm.RealtimeData.SetComputerIP("0.0.0.0") // Clear the IP when disconnected
+	nm.RealtimeData.SetPublicIP("")
+	nm.RealtimeData.SetNatType("")
+	nm.ReconnectAttempts = 0
+	// Keep showing the networks as last known until the server sends them again
+	nm.RealtimeData.MarkNetworksOffline()
+
+	// Update UI
+	nm.refreshUI()
+	nm.refreshNetworkList() // Added this line
+
+	return nil
+}
+
+// hasUnchangedNetworks reports whether a networks response refers to versions we already have
+func hasUnchangedNetworks(networks []smodels.ComputerNetworkInfo) bool {
+	for _, network := range networks {
+		if network.Unchanged {
+			return true
+		}
+	}
+	return false
+}
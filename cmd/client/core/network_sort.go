@@ -0,0 +1,60 @@
+package core
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/itxtoledo/govpn/cmd/client/data"
+)
+
+// Ordens da lista de redes, escolhidas na janela principal
+const (
+	NetworkSortName          = ""               // Pelo nome, de A a Z
+	NetworkSortLastConnected = "last_connected" // A conectada mais recentemente primeiro
+	NetworkSortMembers       = "members"        // A com mais membros primeiro
+	NetworkSortOnline        = "online"         // A com mais membros online primeiro
+)
+
+// SortNetworks ordena as redes na ordem escolhida; empates e ordens desconhecidas ficam
+// pelo nome
+func SortNetworks(networks []data.Network, order string) {
+	byName := func(a, b data.Network) int {
+		return cmp.Or(
+			cmp.Compare(strings.ToLower(a.NetworkName), strings.ToLower(b.NetworkName)),
+			cmp.Compare(a.NetworkID, b.NetworkID),
+		)
+	}
+	slices.SortStableFunc(networks, func(a, b data.Network) int {
+		var c int
+		switch order {
+		case NetworkSortLastConnected:
+			c = b.LastConnected.Compare(a.LastConnected)
+		case NetworkSortMembers:
+			c = cmp.Compare(len(b.Computers), len(a.Computers))
+		case NetworkSortOnline:
+			c = cmp.Compare(onlineComputers(b), onlineComputers(a))
+		}
+		return cmp.Or(c, byName(a, b))
+	})
+}
+
+// onlineComputers conta os membros online de uma rede
+func onlineComputers(network data.Network) int {
+	online := 0
+	for _, computer := range network.Computers {
+		if computer.IsOnline {
+			online++
+		}
+	}
+	return online
+}
+
+// SetNetworkSort guarda a ordem da lista de redes
+func (cm *ConfigManager) SetNetworkSort(order string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.config.NetworkSort = order
+	return cm.SaveConfig()
+}
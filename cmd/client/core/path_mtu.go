@@ -0,0 +1,27 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// handlePathMTU publishes the MTU discovered to a peer and redraws the network list
+func (nm *NetworkManager) handlePathMTU(peerPublicKey string, pathMTU int) {
+	mtu := network.PacketMTU(pathMTU)
+	if mtu < network.DefaultMTU {
+		logger.Info("Path MTU to peer found, fragmenting larger packets", "peer", peerPublicKey, "pathMTU", pathMTU, "mtu", mtu)
+	}
+	nm.RealtimeData.SetPeerMTU(peerPublicKey, min(mtu, network.DefaultMTU))
+	nm.refreshNetworkList()
+}
+
+// MTULabel formats the MTU to a peer for the network list, empty when full-size packets
+// get through without fragmentation
+func MTULabel(mtu int) string {
+	if mtu >= network.DefaultMTU {
+		return ""
+	}
+	return fmt.Sprintf("MTU %d", mtu)
+}
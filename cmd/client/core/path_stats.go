@@ -0,0 +1,118 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	clientwebrtc_impl "github.com/itxtoledo/govpn/cmd/client/webrtc"
+)
+
+// pathStatsInterval is how often the ICE stats of the peer connections are read
+const pathStatsInterval = 5 * time.Second
+
+// pathMonitor reads the selected candidate pair of every peer connection, so the network
+// list shows the path traffic actually takes instead of what the NAT types predict
+type pathMonitor struct {
+	nm *NetworkManager
+
+	last map[*clientwebrtc_impl.WebRTCManager]clientwebrtc_impl.PathStats
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newPathMonitor(nm *NetworkManager) *pathMonitor {
+	return &pathMonitor{
+		nm:   nm,
+		last: make(map[*clientwebrtc_impl.WebRTCManager]clientwebrtc_impl.PathStats),
+		done: make(chan struct{}),
+	}
+}
+
+// Run reads the stats every pathStatsInterval until Close
+func (m *pathMonitor) Run() {
+	ticker := time.NewTicker(pathStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.sample()
+		}
+	}
+}
+
+// sample publishes the path of each peer connection and its bitrate since the last read
+func (m *pathMonitor) sample() {
+	m.nm.peersMu.Lock()
+	peers := make(map[string]*clientwebrtc_impl.WebRTCManager, len(m.nm.peerConnections))
+	for publicKey, peer := range m.nm.peerConnections {
+		peers[publicKey] = peer
+	}
+	m.nm.peersMu.Unlock()
+
+	paths := make(map[string]data.PeerPath, len(peers))
+	current := make(map[*clientwebrtc_impl.WebRTCManager]clientwebrtc_impl.PathStats, len(peers))
+	for publicKey, peer := range peers {
+		stats, ok := peer.PathStats()
+		if !ok {
+			continue
+		}
+		current[peer] = stats
+
+		path := data.PeerPath{
+			LocalCandidate:  stats.LocalCandidateType.String(),
+			RemoteCandidate: stats.RemoteCandidateType.String(),
+			Relayed:         stats.Relayed(),
+			Retransmissions: stats.Retransmissions,
+		}
+		if last, ok := m.last[peer]; ok {
+			moved := stats.BytesSent - last.BytesSent + stats.BytesReceived - last.BytesReceived
+			path.Bitrate = float64(moved*8) / pathStatsInterval.Seconds()
+		}
+		paths[publicKey] = path
+	}
+	m.last = current
+
+	m.nm.RealtimeData.SetPeerPaths(paths)
+	if len(paths) > 0 {
+		m.nm.refreshNetworkList()
+	}
+}
+
+// Close stops reading the stats
+func (m *pathMonitor) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+}
+
+// PathLabel formats the path to a peer for the network list: whether it is direct or
+// through a TURN relay, the candidate types, the bitrate and the ICE retransmissions
+func PathLabel(path data.PeerPath) string {
+	kind := "Direct"
+	if path.Relayed {
+		kind = "TURN relay"
+	}
+	label := fmt.Sprintf("%s (%s/%s) %s", kind, path.LocalCandidate, path.RemoteCandidate, formatBitrate(path.Bitrate))
+	if path.Retransmissions > 0 {
+		label += fmt.Sprintf(" %d retx", path.Retransmissions)
+	}
+	return label
+}
+
+// formatBitrate formats bits per second with a decimal unit
+func formatBitrate(bps float64) string {
+	switch {
+	case bps >= 1e6:
+		return fmt.Sprintf("%.1f Mbit/s", bps/1e6)
+	case bps >= 1e3:
+		return fmt.Sprintf("%.0f kbit/s", bps/1e3)
+	default:
+		return fmt.Sprintf("%.0f bit/s", bps)
+	}
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// O botão "Ping" de cada peer testa a conexão na hora, sem esperar a próxima rodada de
+// medição. Com a interface TUN, vai um eco ICMP ao IP virtual do peer, que passa pelo
+// mesmo caminho dos jogos e confere também a interface do outro lado. Sem a interface, ou
+// quando o firewall do peer descarta o ICMP, vai um ping pelo canal de dados.
+
+// Esperas de cada tentativa do PingPeer
+const (
+	echoTimeout  = 2 * time.Second
+	probeTimeout = 3 * time.Second
+)
+
+// PeerPing é o resultado de PingPeer
+type PeerPing struct {
+	RTT    time.Duration
+	ICMP   bool // Respondeu ao eco ICMP pela interface
+	NoICMP bool // Há interface, mas o peer só respondeu pelo canal de dados
+}
+
+// Label descreve o resultado para a interface
+func (p PeerPing) Label() string {
+	label := fmt.Sprintf("%d ms", p.RTT.Milliseconds())
+	if p.RTT < time.Millisecond {
+		label = "<1 ms"
+	}
+	switch {
+	case p.ICMP:
+		return label + " (ICMP through the tunnel)"
+	case p.NoICMP:
+		return label + " (no ICMP reply, a firewall on it may block ping)"
+	}
+	return label + " (over the data channel)"
+}
+
+// PingPeer mede agora o tempo de ida e volta até um computador da rede conectada
+func (nm *NetworkManager) PingPeer(publicKey string) (PeerPing, error) {
+	var peerIP string
+	for _, network := range nm.RealtimeData.GetNetworks() {
+		if network.NetworkID != nm.NetworkID {
+			continue
+		}
+		for _, computer := range network.Computers {
+			if computer.PublicKey == publicKey {
+				peerIP = computer.ComputerIP
+			}
+		}
+	}
+	if nm.NetworkID == "" || peerIP == "" {
+		return PeerPing{}, errors.New("the computer is not in the connected network")
+	}
+
+	nm.tunnelMu.Lock()
+	router, pinger := nm.tunnel, nm.pinger
+	nm.tunnelMu.Unlock()
+
+	if router != nil {
+		rtt, err := router.Echo(peerIP, echoTimeout)
+		if err == nil {
+			logger.Debug("Peer answered the echo", "peer", publicKey, "rtt", rtt)
+			return PeerPing{RTT: rtt, ICMP: true}, nil
+		}
+		logger.Debug("Peer did not answer the echo", "peer", publicKey, "error", err)
+	}
+	if pinger == nil {
+		return PeerPing{}, errors.New("not connected to a network")
+	}
+
+	rtt, err := pinger.Probe(publicKey, probeTimeout)
+	switch {
+	case errors.Is(err, network.ErrPeerUnreachable):
+		return PeerPing{}, errors.New("there is no connection with the computer yet")
+	case errors.Is(err, network.ErrEchoTimeout):
+		return PeerPing{}, fmt.Errorf("no reply in %s", probeTimeout)
+	case err != nil:
+		return PeerPing{}, err
+	}
+	return PeerPing{RTT: rtt, NoICMP: router != nil}, nil
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/libs/logger"
+	sclient "github.com/itxtoledo/govpn/libs/signaling/client"
+)
+
+// Quando a conexão com o servidor de sinalização cai sozinha, o cliente da biblioteca tenta
+// de novo sem limite, com espera exponencial e aleatória, até conectar ou o usuário
+// desconectar. Os peers e o túnel continuam no ar nesse meio tempo, e a lista de redes fica
+// como a última conhecida, marcada como offline. Depois de reconectar, a lista que o servidor
+// envia substitui a guardada, a rede ativa é conectada de novo e os pedidos feitos sem
+// servidor são enviados na ordem.
+
+// offlineQueueSize limita os pedidos guardados enquanto o servidor está fora
+const offlineQueueSize = 32
+
+// ErrQueuedOffline é retornado pelos pedidos feitos durante a reconexão, que vão para a
+// fila e são enviados quando o servidor voltar
+var ErrQueuedOffline = errors.New("server unreachable, the request will be sent after reconnecting")
+
+// offlineRequest é um pedido feito durante a reconexão
+type offlineRequest struct {
+	description string
+	run         func() error
+}
+
+// handleConnectionLost mostra a reconexão que a biblioteca começou quando a conexão caiu
+// sem o usuário pedir
+func (nm *NetworkManager) handleConnectionLost(err error) {
+	// Com a sessão substituída a biblioteca não reconecta, o aviso já foi tratado
+	if nm.sessionReplaced.Load() || nm.connectionState == ConnectionStateDisconnected {
+		return
+	}
+
+	logger.Warn("Connection to the signaling server lost", "error", err)
+	nm.connectionState = ConnectionStateConnecting
+	nm.RealtimeData.SetConnectionState(data.StateConnecting)
+	nm.RealtimeData.MarkNetworksOffline()
+	nm.refreshUI()
+}
+
+// handleReconnecting mostra quando será a próxima tentativa
+func (nm *NetworkManager) handleReconnecting(attempt int, delay time.Duration) {
+	nm.ReconnectAttempts = attempt
+	logger.Info("Reconnecting", "server", nm.SignalingServer.ServerAddress, "delay", delay, "attempt", attempt)
+	nm.RealtimeData.SetStatusMessage(fmt.Sprintf("Reconnecting in %s...", delay.Round(time.Second)))
+	nm.refreshUI()
+}
+
+// handleReconnected conecta de novo à rede ativa, que o servidor esqueceu junto com a
+// sessão anterior, e envia os pedidos da fila
+func (nm *NetworkManager) handleReconnected() {
+	defer crash.Recover("reconnected")
+
+	logger.Info("Reconnected to the signaling server", "attempts", nm.ReconnectAttempts)
+	nm.ReconnectAttempts = 0
+	nm.connected()
+	nm.UpdateClientInfo()
+
+	if activeNetwork := nm.NetworkID; activeNetwork != "" {
+		if _, err := nm.RefreshNetworks(); err != nil {
+			logger.Warn("Failed to refresh networks after reconnecting", "error", err)
+		}
+		if err := nm.ConnectNetwork(activeNetwork); err != nil {
+			logger.Warn("Failed to reconnect to network", "networkID", activeNetwork, "error", err)
+
+			// Fora da rede no servidor, o túnel não tem mais como achar os peers
+			nm.NetworkID = ""
+			nm.stopTunnel()
+			nm.closeAllPeers()
+			nm.RealtimeData.SetNetworkInfo("Not connected")
+			nm.RealtimeData.SetComputerIP("0.0.0.0")
+			nm.RealtimeData.EmitEvent(data.EventNetworkDisconnected, activeNetwork, nil)
+			nm.RealtimeData.EmitEvent(data.EventError, fmt.Sprintf("Could not reconnect to the network: %v", err), nil)
+			nm.refreshNetworkList()
+		}
+	}
+
+	nm.flushOfflineQueue()
+}
+
+// reconnecting diz se uma reconexão está em andamento
+func (nm *NetworkManager) reconnecting() bool {
+	return nm.SignalingServer != nil && nm.SignalingServer.Reconnecting()
+}
+
+// stopReconnect cancela a reconexão em andamento e descarta os pedidos na fila
+func (nm *NetworkManager) stopReconnect() {
+	if nm.SignalingServer != nil {
+		nm.SignalingServer.StopReconnect()
+	}
+
+	nm.reconnectMu.Lock()
+	defer nm.reconnectMu.Unlock()
+	if len(nm.offlineQueue) > 0 {
+		logger.Info("Dropping requests queued while offline", "count", len(nm.offlineQueue))
+		nm.offlineQueue = nil
+	}
+}
+
+// whenOffline trata um pedido feito sem conexão com o servidor: durante a reconexão ele
+// vai para a fila e o retorno é ErrQueuedOffline; fora dela o pedido falha
+func (nm *NetworkManager) whenOffline(description string, run func() error) error {
+	nm.reconnectMu.Lock()
+	defer nm.reconnectMu.Unlock()
+
+	if !nm.reconnecting() {
+		return sclient.ErrNotConnected
+	}
+	if len(nm.offlineQueue) >= offlineQueueSize {
+		return fmt.Errorf("not connected to server, and %d requests are already waiting", offlineQueueSize)
+	}
+
+	logger.Info("Server unreachable, request queued", "request", description)
+	nm.offlineQueue = append(nm.offlineQueue, offlineRequest{description: description, run: run})
+	return ErrQueuedOffline
+}
+
+// flushOfflineQueue envia os pedidos feitos durante a reconexão
+func (nm *NetworkManager) flushOfflineQueue() {
+	nm.reconnectMu.Lock()
+	queue := nm.offlineQueue
+	nm.offlineQueue = nil
+	nm.reconnectMu.Unlock()
+
+	for _, req := range queue {
+		logger.Debug("Sending request queued while offline", "request", req.description)
+		if err := req.run(); err != nil {
+			logger.Warn("Queued request failed", "request", req.description, "error", err)
+			nm.RealtimeData.EmitEvent(data.EventError, fmt.Sprintf("Failed to %s: %v", req.description, err), nil)
+		}
+	}
+}
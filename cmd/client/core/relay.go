@@ -0,0 +1,84 @@
+package core
+
+import (
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// relayFeature é o recurso anunciado pelos servidores que retransmitem frames entre peers
+const relayFeature = "relay_fallback"
+
+// relayQuotaNoticeInterval espaça os avisos de cota esgotada, que chega a cada frame recusado
+const relayQuotaNoticeInterval = time.Minute
+
+// setRelayAvailable guarda se o servidor atual retransmite frames, a partir das capacidades
+// enviadas na conexão
+func (nm *NetworkManager) setRelayAvailable(caps smodels.ServerCapabilitiesNotification) {
+	available := caps.HasFeature(relayFeature) && caps.Limits.RelayQuotaBytes > 0
+	nm.relayAvailable.Store(available)
+	logger.Debug("Relay fallback", "available", available, "quotaBytesPerMinute", caps.Limits.RelayQuotaBytes)
+}
+
+// IsRelayed diz se o tráfego com um peer passa pelo servidor de sinalização
+func (nm *NetworkManager) IsRelayed(peerPublicKey string) bool {
+	nm.peersMu.Lock()
+	defer nm.peersMu.Unlock()
+
+	return nm.relayed[peerPublicKey]
+}
+
+// markRelayed passa a mandar o tráfego de um peer pelo servidor: fecha a conexão WebRTC,
+// que falhou, e recomeça o handshake pelo novo caminho. Os frames continuam cifrados de
+// ponta a ponta, o servidor só os repassa. Retorna false se o peer já era retransmitido.
+func (nm *NetworkManager) markRelayed(peerPublicKey string) bool {
+	nm.peersMu.Lock()
+	if nm.relayed[peerPublicKey] {
+		nm.peersMu.Unlock()
+		return false
+	}
+	nm.relayed[peerPublicKey] = true
+	peer, ok := nm.peerConnections[peerPublicKey]
+	delete(nm.peerConnections, peerPublicKey)
+	nm.forgetSession(peerPublicKey)
+	nm.peersMu.Unlock()
+
+	logger.Info("Relaying traffic with peer through the signaling server", "peer", peerPublicKey)
+	if ok {
+		peer.Close()
+	}
+	nm.startSecureSession(peerPublicKey)
+	nm.refreshNetworkList()
+	return true
+}
+
+// handleRelayFrame entrega um frame retransmitido pelo servidor. O primeiro frame de um
+// peer mostra que ele desistiu da conexão direta, então este lado também passa a retransmitir.
+func (nm *NetworkManager) handleRelayFrame(frame smodels.RelayFrame) {
+	if !nm.meshMembers()[frame.SenderPublicKey] {
+		logger.Debug("Dropping relayed frame, sender is not an online member of the current network", "sender", frame.SenderPublicKey)
+		return
+	}
+	nm.markRelayed(frame.SenderPublicKey)
+	nm.handlePeerPacket(frame.SenderPublicKey, frame.Data)
+}
+
+// handleRelayQuotaExceeded avisa no banner que a cota de retransmissão da rede acabou; os
+// frames são perdidos até a cota renovar
+func (nm *NetworkManager) handleRelayQuotaExceeded(errorPayload smodels.ErrorResponse) {
+	now := time.Now()
+	last := nm.relayQuotaNotice.Load()
+	if now.UnixNano()-last < int64(relayQuotaNoticeInterval) || !nm.relayQuotaNotice.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+
+	logger.Warn("Relay quota exceeded", "error", errorPayload.Error)
+	nm.RealtimeData.EmitEvent(data.EventServerNotice, errorPayload.Error, smodels.ServerNoticeNotification{
+		ID:      "relay-quota",
+		Message: "Some computers can only be reached through the server and this network used up its relay quota; traffic to them is paused for up to a minute.",
+		Level:   smodels.NoticeLevelWarning,
+		SentAt:  now,
+	})
+}
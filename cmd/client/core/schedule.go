@@ -0,0 +1,217 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// Agenda da conexão automática: com Config.ConnectSchedule, as redes marcadas só conectam
+// sozinhas dentro de uma das janelas, como "mon-fri 18:00-23:30". Quando uma janela abre
+// com o cliente rodando a conexão automática é tentada, e quando todas fecham a rede que
+// ela conectou é desconectada; as conexões feitas pelo usuário ficam como estão.
+
+// scheduleCheckInterval é de quanto em quanto tempo a agenda é conferida
+const scheduleCheckInterval = time.Minute
+
+// weekdayNames são os dias aceitos nas janelas, na ordem de time.Weekday
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ConnectWindow é um horário da semana em que a conexão automática vale. Quando End não
+// passa de Start a janela termina no dia seguinte, e iguais valem 24 horas.
+type ConnectWindow struct {
+	Days  [7]bool       // Dias em que a janela abre, por time.Weekday
+	Start time.Duration // Desde a meia-noite
+	End   time.Duration
+}
+
+// ParseConnectWindow lê uma janela como "mon-fri 18:00-23:30", "sat,sun 10:00-02:00" ou
+// só "19:00-23:00", que vale todos os dias. Também aceita "daily", "weekdays" e "weekends".
+func ParseConnectWindow(s string) (ConnectWindow, error) {
+	var w ConnectWindow
+	fields := strings.Fields(strings.ToLower(s))
+	var days, hours string
+	switch len(fields) {
+	case 1:
+		days, hours = "daily", fields[0]
+	case 2:
+		days, hours = fields[0], fields[1]
+	default:
+		return w, fmt.Errorf("invalid schedule %q, expected days and hours like mon-fri 18:00-23:30", s)
+	}
+
+	if err := w.parseDays(days); err != nil {
+		return w, err
+	}
+	start, end, ok := strings.Cut(hours, "-")
+	if !ok {
+		return w, fmt.Errorf("invalid hours %q, expected start-end like 18:00-23:30", hours)
+	}
+	var err error
+	if w.Start, err = parseClock(start); err != nil {
+		return w, err
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return w, err
+	}
+	return w, nil
+}
+
+// parseDays marca os dias de uma lista separada por vírgulas, com dias ou intervalos
+func (w *ConnectWindow) parseDays(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		switch part {
+		case "daily":
+			w.Days = [7]bool{true, true, true, true, true, true, true}
+			continue
+		case "weekdays":
+			part = "mon-fri"
+		case "weekends":
+			part = "sat-sun"
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := parseWeekday(from)
+		if err != nil {
+			return err
+		}
+		last := first
+		if isRange {
+			if last, err = parseWeekday(to); err != nil {
+				return err
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			w.Days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseWeekday lê um dia pelo nome em inglês, de três letras em diante, como "mon" ou "monday"
+func parseWeekday(s string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if len(s) >= 3 && strings.HasPrefix(strings.ToLower(day.String()), s) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown day %q, expected mon, tue, wed, thu, fri, sat or sun", s)
+}
+
+// parseClock lê uma hora como "18:00" ou "7:30", de 00:00 a 24:00
+func parseClock(s string) (time.Duration, error) {
+	var hour, minute int
+	if n, err := fmt.Sscanf(s, "%d:%d", &hour, &minute); err != nil || n != 2 || hour < 0 || minute < 0 || minute > 59 || hour > 24 || hour == 24 && minute > 0 {
+		return 0, fmt.Errorf("invalid time %q, expected hours and minutes like 18:00", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// Contains diz se o horário t está dentro da janela
+func (w ConnectWindow) Contains(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	clock := t.Sub(midnight)
+	today, yesterday := t.Weekday(), (t.Weekday()+6)%7
+
+	if w.End > w.Start {
+		return w.Days[today] && clock >= w.Start && clock < w.End
+	}
+	// A janela cruza a meia-noite: começou hoje ou começou ontem e ainda não terminou
+	return w.Days[today] && clock >= w.Start || w.Days[yesterday] && clock < w.End
+}
+
+// String escreve a janela no formato de ParseConnectWindow, com os dias seguidos em
+// intervalos a partir da segunda, como "mon-fri" e "sat-sun"
+func (w ConnectWindow) String() string {
+	var runs []string
+	for i := 0; i < 7; {
+		day := (i + 1) % 7
+		if !w.Days[day] {
+			i++
+			continue
+		}
+		j := i
+		for j+1 < 7 && w.Days[(j+2)%7] {
+			j++
+		}
+		if last := (j + 1) % 7; last != day {
+			runs = append(runs, weekdayNames[day]+"-"+weekdayNames[last])
+		} else {
+			runs = append(runs, weekdayNames[day])
+		}
+		i = j + 1
+	}
+	list := strings.Join(runs, ",")
+	if w.Days == [7]bool{true, true, true, true, true, true, true} {
+		list = "daily"
+	}
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%s %s-%s", list, clock(w.Start), clock(w.End))
+}
+
+// MarshalText guarda a janela na configuração como texto
+func (w ConnectWindow) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
+// UnmarshalText lê a janela guardada na configuração
+func (w *ConnectWindow) UnmarshalText(text []byte) error {
+	parsed, err := ParseConnectWindow(string(text))
+	if err != nil {
+		return err
+	}
+	*w = parsed
+	return nil
+}
+
+// InSchedule diz se a conexão automática vale no horário t: sem janelas, sempre
+func (c Config) InSchedule(t time.Time) bool {
+	if len(c.ConnectSchedule) == 0 {
+		return true
+	}
+	for _, w := range c.ConnectSchedule {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// followSchedule confere a agenda a cada scheduleCheckInterval, conectando as redes marcadas
+// quando uma janela abre e desconectando a que a conexão automática conectou quando fecha
+func (v *VPNClient) followSchedule() {
+	config := v.ConfigManager.GetConfig()
+	inside := config.InSchedule(time.Now())
+
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		config = v.ConfigManager.GetConfig()
+		wasInside := inside
+		inside = config.InSchedule(now)
+
+		switch {
+		case inside && !wasInside:
+			logger.Info("Connection schedule window opened")
+			if err := v.AutoConnect(); err != nil {
+				logger.Warn("Scheduled auto-connect failed", "error", err)
+			}
+		case !inside && wasInside:
+			networkID := v.NetworkManager.NetworkID
+			if networkID == "" || v.autoNetwork.Load() != networkID {
+				continue
+			}
+			logger.Info("Connection schedule window closed, disconnecting", "networkID", networkID)
+			if err := v.NetworkManager.DisconnectNetwork(networkID); err != nil {
+				logger.Warn("Failed to disconnect at the end of the schedule", "networkID", networkID, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"maps"
+	"net/url"
+	"strings"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+	sclient "github.com/itxtoledo/govpn/libs/signaling/client"
+)
+
+// Certificados fixados dos servidores wss://: cada servidor, pelo host e porta do endereço,
+// só é aceito com um certificado que bata com um dos seus pins, mesmo que outra autoridade
+// confiável assine um certificado para o mesmo nome. Servidores sem pins usam a verificação
+// comum do sistema.
+
+// pinKey é a chave de um servidor em Config.ServerPins
+func pinKey(serverAddress string) string {
+	u, err := url.Parse(serverAddress)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(serverAddress)
+	}
+	return strings.ToLower(u.Host)
+}
+
+// PinsFor retorna os pins guardados para o servidor do endereço
+func (c Config) PinsFor(serverAddress string) []string {
+	return c.ServerPins[pinKey(serverAddress)]
+}
+
+// WithPins retorna uma cópia dos pins de todos os servidores com os do servidor do endereço
+// trocados; sem pins, o servidor sai da lista
+func (c Config) WithPins(serverAddress string, pins []string) map[string][]string {
+	all := maps.Clone(c.ServerPins)
+	if all == nil {
+		all = make(map[string][]string)
+	}
+	if len(pins) == 0 {
+		delete(all, pinKey(serverAddress))
+	} else {
+		all[pinKey(serverAddress)] = pins
+	}
+	if len(all) == 0 {
+		return nil
+	}
+	return all
+}
+
+// serverPins lê os pins do servidor, que as configurações já validaram
+func (c Config) serverPins(serverAddress string) []sclient.Pin {
+	var pins []sclient.Pin
+	for _, s := range c.PinsFor(serverAddress) {
+		pin, err := sclient.ParsePin(s)
+		if err != nil {
+			logger.Warn("Ignoring invalid certificate pin", "server", serverAddress, "error", err)
+			continue
+		}
+		pins = append(pins, pin)
+	}
+	return pins
+}
+
+// ParseServerPin valida um pin digitado nas configurações e o retorna no formato guardado
+func ParseServerPin(s string) (string, error) {
+	pin, err := sclient.ParsePin(s)
+	if err != nil {
+		return "", err
+	}
+	return pin.String(), nil
+}
+
+// FetchServerPin busca o pin da chave pública do certificado que o servidor apresenta agora,
+// sem verificá-lo, para o usuário fixar numa rede confiável
+func FetchServerPin(serverAddress string) (string, error) {
+	pin, err := sclient.FetchServerPin(serverAddress)
+	if err != nil {
+		return "", err
+	}
+	return pin.String(), nil
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+	sclient "github.com/itxtoledo/govpn/libs/signaling/client"
+)
+
+// serverPingTimeout bounds each ping to a server of the server list
+const serverPingTimeout = 3 * time.Second
+
+// RankServerList fetches a server list and pings its servers, fastest first
+func RankServerList(listURL string) ([]sclient.ServerLatency, error) {
+	list, err := sclient.FetchServerList(listURL)
+	if err != nil {
+		return nil, err
+	}
+	return sclient.RankServers(list.Servers, serverPingTimeout), nil
+}
+
+// FormatServerChoice describes a ranked server for the server picker
+func FormatServerChoice(r sclient.ServerLatency) string {
+	label := r.Server.Name
+	if r.Server.Region != "" {
+		label += " (" + r.Server.Region + ")"
+	}
+	if r.Err != nil {
+		return label + " - unreachable"
+	}
+	return fmt.Sprintf("%s - %d ms", label, r.Latency.Milliseconds())
+}
+
+// selectFastestServer picks the fastest reachable server of the configured list and
+// stores it as the server address. It returns the address to connect to.
+func (v *VPNClient) selectFastestServer(listURL string) (string, error) {
+	ranked, err := RankServerList(listURL)
+	if err != nil {
+		return "", err
+	}
+	if ranked[0].Err != nil {
+		return "", fmt.Errorf("no server on the list answered: %v", ranked[0].Err)
+	}
+
+	best := ranked[0]
+	logger.Info("Fastest server selected", "name", best.Server.Name, "region", best.Server.Region, "server", best.Server.Address, "latency", best.Latency)
+	if err := v.ConfigManager.UpdateServerAddress(best.Server.Address); err != nil {
+		logger.Error("Error storing selected server", "error", err)
+	}
+	return best.Server.Address, nil
+}
@@ -0,0 +1,232 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// Procura de jogos e serviços nos computadores da rede: tenta conexões TCP nas portas de
+// ScanPorts no IP virtual de cada peer online. Antes o peer é consultado pelo canal de
+// dados e só entra na procura se permitir (AllowServiceScan nas configurações, desligado
+// por padrão); os que recusam ou não respondem, como versões antigas, ficam de fora.
+
+const (
+	// scanConsentTimeout é quanto a procura espera o peer dizer se permite
+	scanConsentTimeout = 3 * time.Second
+	// scanDialTimeout limita a tentativa de conexão a cada porta
+	scanDialTimeout = time.Second
+)
+
+// Tipos das mensagens de texto da consulta no canal de dados
+const (
+	scanTypeRequest = "scan_request"
+	scanTypeReply   = "scan_reply"
+)
+
+// ScanPort é uma porta procurada e o jogo ou serviço que costuma usá-la
+type ScanPort struct {
+	Port int
+	Name string
+}
+
+// ScanPorts são as portas TCP procuradas, em ordem. Jogos que só usam UDP não aceitam
+// conexões e não aparecem na procura.
+var ScanPorts = []ScanPort{
+	{22, "SSH"},
+	{80, "Web server"},
+	{445, "File sharing (SMB)"},
+	{3389, "Remote Desktop"},
+	{3979, "OpenTTD"},
+	{5900, "VNC"},
+	{6112, "Warcraft III"},
+	{7777, "Terraria"},
+	{8080, "Web server"},
+	{21025, "Starbound"},
+	{25565, "Minecraft Java"},
+	{25575, "Minecraft RCON"},
+	{26900, "7 Days to Die"},
+	{27015, "Source server (CS, Garry's Mod, TF2)"},
+	{28016, "Rust RCON"},
+	{64738, "Mumble"},
+}
+
+// ServiceScanStatus diz se um peer permitiu a procura
+type ServiceScanStatus string
+
+const (
+	ScanAllowed  ServiceScanStatus = "allowed"
+	ScanDeclined ServiceScanStatus = "declined"
+	ScanNoAnswer ServiceScanStatus = "no_answer"
+)
+
+// ServiceScanResult é o que a procura achou num peer
+type ServiceScanResult struct {
+	PublicKey string
+	Name      string
+	IP        string
+	Status    ServiceScanStatus
+	Services  []ScanPort // Portas que aceitaram conexão, só quando Status é ScanAllowed
+}
+
+// errScanNeedsTunnel é retornado sem a interface TUN, quando os IPs virtuais dos peers não são alcançáveis
+var errScanNeedsTunnel = errors.New("finding services needs the virtual network interface, which port forwarding mode does not create")
+
+// scanWireMessage é uma consulta ou a resposta dela no canal de dados
+type scanWireMessage struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Allowed bool   `json:"allowed,omitempty"`
+}
+
+// scanConsents guarda as consultas que esperam a resposta do peer, por peer e ID
+type scanConsents struct {
+	waiting map[string]chan bool
+	mu      sync.Mutex
+}
+
+// ScanServices procura serviços nos peers online da rede atual, que são consultados ao
+// mesmo tempo. progress recebe cada peer ao terminar, de uma goroutine por vez; o retorno
+// tem todos, por nome.
+func (nm *NetworkManager) ScanServices(progress func(ServiceScanResult)) ([]ServiceScanResult, error) {
+	if nm.NetworkID == "" {
+		return nil, errNoFileTransfers
+	}
+	nm.tunnelMu.Lock()
+	hasTunnel := nm.tunnel != nil
+	nm.tunnelMu.Unlock()
+	if !hasTunnel {
+		return nil, errScanNeedsTunnel
+	}
+
+	online := nm.meshMembers()
+	var peers []ServiceScanResult
+	for _, network := range nm.RealtimeData.GetNetworks() {
+		if network.NetworkID != nm.NetworkID {
+			continue
+		}
+		for _, computer := range network.Computers {
+			if online[computer.PublicKey] && computer.ComputerIP != "" {
+				peers = append(peers, ServiceScanResult{PublicKey: computer.PublicKey, Name: computer.Name, IP: computer.ComputerIP})
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+	for i := range peers {
+		wg.Add(1)
+		go func(result *ServiceScanResult) {
+			defer wg.Done()
+			defer crash.Recover("service scan peer")
+			result.Status = nm.askScanConsent(result.PublicKey)
+			if result.Status == ScanAllowed {
+				result.Services = probeServices(result.IP)
+			}
+			logger.Debug("Service scan of peer finished", "peer", result.PublicKey, "status", result.Status, "services", len(result.Services))
+			if progress != nil {
+				progressMu.Lock()
+				progress(*result)
+				progressMu.Unlock()
+			}
+		}(&peers[i])
+	}
+	wg.Wait()
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+	return peers, nil
+}
+
+// askScanConsent pergunta a um peer se ele permite a procura
+func (nm *NetworkManager) askScanConsent(peerPublicKey string) ServiceScanStatus {
+	id := newMessageID()
+	key := peerPublicKey + "/" + id
+	reply := make(chan bool, 1)
+	nm.scans.mu.Lock()
+	nm.scans.waiting[key] = reply
+	nm.scans.mu.Unlock()
+	defer func() {
+		nm.scans.mu.Lock()
+		delete(nm.scans.waiting, key)
+		nm.scans.mu.Unlock()
+	}()
+
+	if err := nm.sendPeerMessage(peerPublicKey, scanWireMessage{Type: scanTypeRequest, ID: id}); err != nil {
+		logger.Debug("Cannot ask peer for a service scan", "peer", peerPublicKey, "error", err)
+		return ScanNoAnswer
+	}
+	select {
+	case allowed := <-reply:
+		if allowed {
+			return ScanAllowed
+		}
+		return ScanDeclined
+	case <-time.After(scanConsentTimeout):
+		return ScanNoAnswer
+	}
+}
+
+// probeServices tenta todas as portas de ScanPorts num IP ao mesmo tempo
+func probeServices(ip string) []ScanPort {
+	open := make([]bool, len(ScanPorts))
+	var wg sync.WaitGroup
+	for i, port := range ScanPorts {
+		wg.Add(1)
+		go func(i int, port ScanPort) {
+			defer wg.Done()
+			defer crash.Recover("service scan port")
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(port.Port)), scanDialTimeout)
+			if err == nil {
+				conn.Close()
+				open[i] = true
+			}
+		}(i, port)
+	}
+	wg.Wait()
+
+	var services []ScanPort
+	for i, port := range ScanPorts {
+		if open[i] {
+			services = append(services, port)
+		}
+	}
+	return services
+}
+
+// handleScanMessage responde às consultas dos peers com AllowServiceScan e entrega as
+// respostas às consultas feitas. Retorna false para as mensagens que não são da procura.
+func (nm *NetworkManager) handleScanMessage(peerPublicKey, content string) bool {
+	var wire scanWireMessage
+	if json.Unmarshal([]byte(content), &wire) != nil || wire.ID == "" {
+		return false
+	}
+
+	switch wire.Type {
+	case scanTypeRequest:
+		allowed := nm.ConfigManager.GetConfig().AllowServiceScan
+		logger.Info("Peer asked to find services on this computer", "peer", peerPublicKey, "allowed", allowed)
+		if err := nm.sendPeerMessage(peerPublicKey, scanWireMessage{Type: scanTypeReply, ID: wire.ID, Allowed: allowed}); err != nil {
+			logger.Debug("Cannot answer service scan request", "peer", peerPublicKey, "error", err)
+		}
+	case scanTypeReply:
+		nm.scans.mu.Lock()
+		reply, ok := nm.scans.waiting[peerPublicKey+"/"+wire.ID]
+		nm.scans.mu.Unlock()
+		if ok {
+			select {
+			case reply <- wire.Allowed:
+			default:
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	sclient "github.com/itxtoledo/govpn/libs/signaling/client"
+)
+
+// As configurações podem ser levadas a outro computador num arquivo JSON. O arquivo não leva
+// a identidade: as chaves ficam neste computador e vão à parte, cifradas, em identity.go.
+// Também não leva a última rede conectada, que é do uso e não das preferências, nem as chaves
+// dos PINs das redes, que são das participações da identidade.
+
+// settingsFormat identifica um arquivo de configurações exportado
+const settingsFormat = "govpn-settings"
+
+// settingsVersion é a versão do formato; arquivos de versões mais novas são recusados
+const settingsVersion = 1
+
+// ErrInvalidSettings é retornado quando o arquivo não é de configurações exportadas
+var ErrInvalidSettings = errors.New("not a GoVPN settings file")
+
+// settingsExport é o conteúdo do arquivo exportado
+type settingsExport struct {
+	Format   string    `json:"format"`
+	Version  int       `json:"version"`
+	Exported time.Time `json:"exported"`
+	Settings Config    `json:"settings"`
+}
+
+// ExportSettings retorna as configurações salvas, sem a identidade, no formato do arquivo
+func (cm *ConfigManager) ExportSettings() ([]byte, error) {
+	settings := cm.GetConfig()
+	settings.PublicKey = ""
+	settings.PrivateKey = ""
+	settings.KeyStore = ""
+	settings.LastNetworkID = ""
+	settings.NetworkKeys = nil
+
+	return json.MarshalIndent(settingsExport{
+		Format:   settingsFormat,
+		Version:  settingsVersion,
+		Exported: time.Now().UTC(),
+		Settings: settings,
+	}, "", "  ")
+}
+
+// ImportSettings lê um arquivo exportado e retorna as configurações dele com a identidade
+// e a última rede deste computador, para salvar e aplicar como as do formulário
+func (cm *ConfigManager) ImportSettings(content []byte) (Config, error) {
+	var file settingsExport
+	if err := json.Unmarshal(content, &file); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return Config{}, ErrInvalidSettings
+		}
+		return Config{}, fmt.Errorf("invalid settings file: %v", err)
+	}
+	if file.Format != settingsFormat {
+		return Config{}, ErrInvalidSettings
+	}
+	if file.Version > settingsVersion {
+		return Config{}, fmt.Errorf("the settings file is from a newer version of GoVPN (format %d)", file.Version)
+	}
+
+	// Os pins são conferidos ao conectar; um inválido aqui deixaria o servidor sem eles
+	for server, pins := range file.Settings.ServerPins {
+		for _, pin := range pins {
+			if _, err := sclient.ParsePin(pin); err != nil {
+				return Config{}, fmt.Errorf("server %s: %w", server, err)
+			}
+		}
+	}
+
+	current := cm.GetConfig()
+	imported := file.Settings
+	imported.PublicKey = current.PublicKey
+	imported.PrivateKey = current.PrivateKey
+	imported.KeyStore = current.KeyStore
+	imported.LastNetworkID = current.LastNetworkID
+	imported.NetworkKeys = current.NetworkKeys
+	return imported, nil
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	"github.com/itxtoledo/govpn/libs/logger"
+	sclient "github.com/itxtoledo/govpn/libs/signaling/client"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// Antes de subir a interface TUN, a sub-rede da rede é comparada com as redes locais. Uma
+// sobreposição não impede a conexão, mas deixa parte da LAN ou de outra VPN inalcançável,
+// então o usuário é avisado uma vez por sub-rede e, se for o dono, pode pedir ao servidor
+// outra faixa. A troca renumera todos os membros e chega a cada um como
+// NetworkSubnetChanged, quando a interface é recriada com o novo IP.
+
+// maxAvoidedSubnets é quantas redes locais o servidor aceita evitar
+const maxAvoidedSubnets = 64
+
+// SubnetConflict é o conteúdo do evento EventSubnetConflict
+type SubnetConflict struct {
+	NetworkID string
+	Subnet    string   // Sub-rede da rede, como 10.10.0.0/24
+	Conflicts []string // Redes locais sobrepostas, como "10.10.0.0/16 (eth0)"
+	Owner     bool     // Este computador é o dono e pode pedir outra sub-rede
+}
+
+// checkSubnetConflicts avisa quando a sub-rede do IP virtual se sobrepõe a uma rede local
+func (nm *NetworkManager) checkSubnetConflicts(computerIP string) {
+	locals, err := network.LocalNetworks()
+	if err != nil {
+		logger.Debug("Checking subnet conflicts without the route table", "error", err)
+	}
+	conflicts := network.SubnetConflicts(computerIP, locals)
+	if len(conflicts) == 0 {
+		return
+	}
+
+	subnet := subnetOf(computerIP)
+	nm.tunnelMu.Lock()
+	warned := nm.subnetWarned == nm.NetworkID+" "+subnet
+	nm.subnetWarned = nm.NetworkID + " " + subnet
+	nm.tunnelMu.Unlock()
+
+	conflict := SubnetConflict{NetworkID: nm.NetworkID, Subnet: subnet, Owner: nm.ownsNetwork(nm.NetworkID)}
+	for _, local := range conflicts {
+		conflict.Conflicts = append(conflict.Conflicts, local.String())
+	}
+	logger.Warn("The network subnet overlaps a local network", "networkID", conflict.NetworkID, "subnet", subnet, "conflicts", conflict.Conflicts)
+	if warned {
+		return
+	}
+	nm.RealtimeData.EmitEvent(data.EventSubnetConflict,
+		fmt.Sprintf("The network uses %s, which overlaps %d local network(s) of this computer.", subnet, len(conflicts)),
+		conflict)
+}
+
+// ownsNetwork diz se este computador é o dono da rede
+func (nm *NetworkManager) ownsNetwork(networkID string) bool {
+	publicKey := nm.ConfigManager.GetConfig().PublicKey
+	for _, network := range nm.RealtimeData.GetNetworks() {
+		if network.NetworkID == networkID {
+			return network.AdminPublicKey == publicKey
+		}
+	}
+	return false
+}
+
+// RequestAlternateSubnet pede ao servidor que mude uma rede própria para uma sub-rede que
+// não se sobreponha a nenhuma rede local deste computador e retorna a nova sub-rede
+func (nm *NetworkManager) RequestAlternateSubnet(networkID string) (string, error) {
+	if nm.connectionState != ConnectionStateConnected {
+		return "", sclient.ErrNotConnected
+	}
+
+	locals, err := network.LocalNetworks()
+	if err != nil {
+		logger.Warn("Requesting another subnet without the route table", "error", err)
+	}
+	var avoid []string
+	seen := make(map[string]bool)
+	for _, local := range locals {
+		cidr := local.Subnet.String()
+		if !seen[cidr] && len(avoid) < maxAvoidedSubnets {
+			seen[cidr] = true
+			avoid = append(avoid, cidr)
+		}
+	}
+
+	resp, err := nm.SignalingServer.ChangeSubnet(networkID, avoid)
+	if err != nil {
+		return "", fmt.Errorf("failed to change subnet: %v", err)
+	}
+
+	logger.Info("Changed network subnet", "networkID", networkID, "subnet", resp.Subnet)
+	return resp.Subnet, nil
+}
+
+// handleSubnetChanged renumera a rede com a nova sub-rede e, se for a rede atual, recria a
+// interface TUN com o novo IP deste computador. O roster que o servidor envia em seguida
+// confirma os IPs dos outros membros.
+func (nm *NetworkManager) handleSubnetChanged(notification smodels.NetworkSubnetChangedNotification) {
+	_, subnet, err := net.ParseCIDR(notification.Subnet)
+	if err != nil {
+		logger.Warn("Ignoring invalid network subnet", "subnet", notification.Subnet, "error", err)
+		return
+	}
+
+	computerIP := ""
+	for i, network := range nm.RealtimeData.GetNetworks() {
+		if network.NetworkID != notification.NetworkID {
+			continue
+		}
+		network.Subnet = subnet.String()
+		network.ComputerIP = renumberIP(network.ComputerIP, subnet)
+		for j := range network.Computers {
+			network.Computers[j].ComputerIP = renumberIP(network.Computers[j].ComputerIP, subnet)
+		}
+		nm.RealtimeData.UpdateNetwork(i, network)
+		computerIP = nm.ownComputerIP(network.Computers)
+		break
+	}
+	nm.refreshNetworkList()
+
+	if notification.NetworkID != nm.NetworkID || computerIP == "" {
+		return
+	}
+	nm.RealtimeData.SetComputerIP(computerIP)
+	nm.startTunnel(computerIP)
+	nm.RealtimeData.EmitEvent(data.EventServerNotice, "subnet changed", smodels.ServerNoticeNotification{
+		ID:      "subnet-" + notification.NetworkID,
+		Message: fmt.Sprintf("The network moved to %s; this computer is now %s.", subnet, computerIP),
+		Level:   smodels.NoticeLevelInfo,
+		SentAt:  time.Now(),
+	})
+}
+
+// subnetOf retorna a sub-rede /24 de um IP virtual
+func subnetOf(computerIP string) string {
+	ip := net.ParseIP(computerIP).To4()
+	if ip == nil {
+		return smodels.DefaultSubnet
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(network.DefaultPrefixLen, 32)), Mask: net.CIDRMask(network.DefaultPrefixLen, 32)}).String()
+}
+
+// renumberIP leva um IP para a sub-rede mantendo o último octeto, como faz o servidor
+func renumberIP(ip string, subnet *net.IPNet) string {
+	parsed := net.ParseIP(ip).To4()
+	base := subnet.IP.To4()
+	if parsed == nil || base == nil {
+		return ip
+	}
+	return net.IPv4(base[0], base[1], base[2], parsed[3]).String()
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"cmp"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// Histórico de tráfego: os bytes que o trafficMeter mede se somam por dia e por rede num
+// arquivo da pasta de dados, com a cifra da configuração, para a janela de histórico mostrar
+// quanto cada rede consumiu. Os dias são os do fuso local, e cada rede guarda os últimos
+// maxTrafficDays dias com tráfego.
+
+const (
+	// trafficDir é a pasta do histórico dentro da pasta de dados
+	trafficDir = "traffic"
+	// maxTrafficDays é quantos dias com tráfego ficam guardados por rede; os mais antigos saem
+	maxTrafficDays = 90
+	// trafficSaveInterval é de quanto em quanto tempo o histórico vai para o disco enquanto
+	// conectado; ao desconectar ele é gravado na hora
+	trafficSaveInterval = time.Minute
+	// trafficDateLayout é o formato das datas do histórico
+	trafficDateLayout = time.DateOnly
+)
+
+// trafficFileName é o arquivo do histórico
+var trafficFileName = filepath.Join(trafficDir, "history.json")
+
+// TrafficDay são os bytes trocados com os peers de uma rede num dia
+type TrafficDay struct {
+	Date     string `json:"date"` // AAAA-MM-DD
+	Sent     uint64 `json:"sent"`
+	Received uint64 `json:"received"`
+}
+
+// NetworkTraffic é o histórico de uma rede, do dia mais antigo ao mais recente
+type NetworkTraffic struct {
+	NetworkID string       `json:"network_id"`
+	Name      string       `json:"name"` // Último nome conhecido, para redes que o usuário já deixou
+	Days      []TrafficDay `json:"days"`
+}
+
+// trafficStore guarda o histórico, lido da pasta de dados na primeira vez que é usado
+type trafficStore struct {
+	cm         *ConfigManager
+	networks   map[string]*NetworkTraffic
+	loaded     bool
+	unreadable bool // O arquivo existe mas não abriu; não é sobrescrito
+	dirty      bool
+	lastSave   time.Time
+	mu         sync.Mutex
+}
+
+// newTrafficStore cria o acesso ao histórico guardado
+func newTrafficStore(cm *ConfigManager) *trafficStore {
+	return &trafficStore{cm: cm, networks: make(map[string]*NetworkTraffic)}
+}
+
+// load lê o histórico do disco na primeira vez. Um arquivo que não abre, como com a
+// configuração trancada, é tentado de novo na próxima. Chamado com mu travado.
+func (ts *trafficStore) load() {
+	if ts.loaded {
+		return
+	}
+
+	var networks []*NetworkTraffic
+	content, err := ts.cm.ReadDataFile(trafficFileName)
+	if err == nil {
+		err = json.Unmarshal(content, &networks)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		logger.Warn("Cannot read traffic history", "error", err)
+		ts.unreadable = true
+		return
+	}
+
+	// O que foi somado antes da leitura se junta ao que estava no disco
+	for _, stored := range networks {
+		if current, ok := ts.networks[stored.NetworkID]; ok {
+			for _, day := range current.Days {
+				stored.Days = addTrafficDay(stored.Days, day)
+			}
+			stored.Name = current.Name
+		}
+		ts.networks[stored.NetworkID] = stored
+	}
+	ts.loaded, ts.unreadable = true, false
+}
+
+// addTrafficDay soma um dia à lista ordenada de dias, descartando os que passam do limite
+func addTrafficDay(days []TrafficDay, add TrafficDay) []TrafficDay {
+	i, found := slices.BinarySearchFunc(days, add.Date, func(day TrafficDay, date string) int {
+		return cmp.Compare(day.Date, date)
+	})
+	if found {
+		days[i].Sent += add.Sent
+		days[i].Received += add.Received
+	} else {
+		days = slices.Insert(days, i, add)
+	}
+	if len(days) > maxTrafficDays {
+		days = slices.Delete(days, 0, len(days)-maxTrafficDays)
+	}
+	return days
+}
+
+// add soma bytes ao dia de at na rede, gravando o histórico a cada trafficSaveInterval.
+// Enquanto o arquivo não abre, os bytes se acumulam na memória e se juntam a ele depois.
+func (ts *trafficStore) add(networkID, name string, at time.Time, sent, received uint64) {
+	if networkID == "" || sent == 0 && received == 0 {
+		return
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	network, ok := ts.networks[networkID]
+	if !ok {
+		network = &NetworkTraffic{NetworkID: networkID}
+		ts.networks[networkID] = network
+	}
+	if name != "" {
+		network.Name = name
+	}
+	network.Days = addTrafficDay(network.Days, TrafficDay{Date: at.Format(trafficDateLayout), Sent: sent, Received: received})
+	ts.dirty = true
+
+	if at.Sub(ts.lastSave) >= trafficSaveInterval {
+		ts.save()
+		ts.lastSave = at
+	}
+}
+
+// flush grava o que ainda não foi para o disco
+func (ts *trafficStore) flush() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.save()
+	ts.lastSave = time.Now()
+}
+
+// save grava o histórico. Chamado com mu travado.
+func (ts *trafficStore) save() {
+	if !ts.dirty {
+		return
+	}
+	if ts.load(); ts.unreadable {
+		return
+	}
+	content, err := json.Marshal(ts.list())
+	if err == nil {
+		err = ts.cm.WriteDataFile(trafficFileName, content)
+	}
+	if err != nil {
+		logger.Warn("Cannot write traffic history", "error", err)
+		return
+	}
+	ts.dirty = false
+}
+
+// list copia o histórico, pelo nome das redes. Chamado com mu travado.
+func (ts *trafficStore) list() []NetworkTraffic {
+	networks := make([]NetworkTraffic, 0, len(ts.networks))
+	for _, network := range ts.networks {
+		copied := *network
+		copied.Days = slices.Clone(network.Days)
+		networks = append(networks, copied)
+	}
+	slices.SortFunc(networks, func(a, b NetworkTraffic) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.NetworkID, b.NetworkID))
+	})
+	return networks
+}
+
+// history retorna o histórico de todas as redes
+func (ts *trafficStore) history() []NetworkTraffic {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.load()
+	return ts.list()
+}
+
+// clear apaga o histórico, da memória e do disco
+func (ts *trafficStore) clear() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.networks = make(map[string]*NetworkTraffic)
+	ts.loaded, ts.unreadable, ts.dirty = true, false, false
+	err := os.Remove(filepath.Join(ts.cm.GetDataPath(), trafficFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// TrafficHistory retorna os bytes trocados por dia em cada rede, pelo nome das redes
+func (nm *NetworkManager) TrafficHistory() []NetworkTraffic {
+	return nm.trafficLog.history()
+}
+
+// ClearTrafficHistory apaga o histórico de tráfego
+func (nm *NetworkManager) ClearTrafficHistory() error {
+	return nm.trafficLog.clear()
+}
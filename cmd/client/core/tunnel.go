@@ -0,0 +1,430 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	clientwebrtc_impl "github.com/itxtoledo/govpn/cmd/client/webrtc"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// Modos de transporte do tráfego, escolhidos nas configurações
+const (
+	TunnelModeAuto      = ""          // Interface TUN, com redirecionamento de portas se faltar privilégio
+	TunnelModeTUN       = "tun"       // Só a interface TUN
+	TunnelModeUserspace = "userspace" // Só redirecionamento de portas, sem privilégios
+)
+
+// startTunnel começa a transportar o tráfego da rede atual: cria a interface TUN com o IP
+// atribuído ou, sem privilégios de administrador, abre os redirecionamentos de portas.
+// As portas compartilhadas ficam acessíveis aos peers nos dois modos. As predefinições de
+// jogos ativadas na rede somam portas, redirecionamentos e broadcast às configurações.
+func (nm *NetworkManager) startTunnel(computerIP string) {
+	nm.stopTunnel()
+
+	if computerIP == "" {
+		logger.Warn("No virtual IP assigned, not creating TUN device")
+		return
+	}
+
+	config := nm.ConfigManager.GetConfig()
+	shared, forwards, broadcast := config.tunnelSettings(nm.NetworkID)
+	proxy := network.NewProxy(nm.sendTunnelFrame, shared)
+	files := network.NewFileTransfers(nm.sendFileFrame, nm.DownloadFolder(), nm.handleFileOffer, nm.handleTransferFinished)
+	pinger := network.NewPinger(nm.sendPing, nm.handleLinkStats)
+	prober := network.NewMTUProber(nm.sendPing, nm.handlePathMTU)
+	traffic := newTrafficMeter(nm)
+	paths := newPathMonitor(nm)
+	watcher := network.NewNetworkWatcher(computerIP, nm.restartICE)
+	var idle *idleMonitor
+	if config.IdleDisconnectMinutes > 0 {
+		idle = newIdleMonitor(nm, time.Duration(config.IdleDisconnectMinutes)*time.Minute)
+		crash.Go("idle monitor", idle.Run)
+	}
+	nm.tunnelMu.Lock()
+	nm.proxy, nm.files, nm.pinger, nm.mtu, nm.traffic, nm.paths, nm.watcher, nm.idle = proxy, files, pinger, prober, traffic, paths, watcher, idle
+	nm.tunnelMu.Unlock()
+	nm.applyBandwidthLimit()
+	nm.syncPingPeers()
+	crash.Go("file transfers", files.Run)
+	crash.Go("peer pings", pinger.Run)
+	crash.Go("path MTU probes", prober.Run)
+	crash.Go("traffic meter", traffic.Run)
+	crash.Go("path monitor", paths.Run)
+	crash.Go("network watcher", watcher.Run)
+
+	if config.TunnelMode == TunnelModeUserspace {
+		nm.startPortForwards(proxy, forwards)
+		nm.syncTunnelPeers()
+		return
+	}
+
+	nm.checkSubnetConflicts(computerIP)
+
+	dev, err := openTunnelDevice(network.Config{Address: computerIP, Broadcast: broadcast, Routes: config.TunnelRoutes, Apps: config.TunnelApps, DNS: config.PeerDNS, Discovery: config.LANDiscovery})
+	if err != nil {
+		logger.Error("Failed to bring up TUN device", "error", err)
+		message := fmt.Sprintf("Could not create the virtual network interface, install the GoVPN helper or run GoVPN as administrator to carry traffic: %v", err)
+		if config.TunnelMode == TunnelModeAuto {
+			nm.startPortForwards(proxy, forwards)
+			message = fmt.Sprintf("Could not create the virtual network interface, only the port forwards from the settings reach other computers: %v", err)
+		}
+		nm.RealtimeData.EmitEvent(data.EventServerNotice, "tunnel unavailable", smodels.ServerNoticeNotification{
+			ID:      "tunnel-unavailable",
+			Message: message,
+			Level:   smodels.NoticeLevelWarning,
+			SentAt:  time.Now(),
+		})
+		nm.syncTunnelPeers()
+		return
+	}
+
+	router, err := network.NewRouter(dev, computerIP, nm.sendTunnelFrame)
+	if err != nil {
+		logger.Error("Failed to create packet router", "error", err)
+		dev.Close()
+		return
+	}
+	if broadcast {
+		router.EnableBroadcast(tunnelGroups(config.TunnelRoutes))
+	}
+	if config.LANDiscovery {
+		router.EnableDiscovery()
+	}
+
+	var dns *network.DNSServer
+	if config.PeerDNS {
+		if dns, err = network.ListenDNS(computerIP); err != nil {
+			logger.Warn("Failed to start the DNS server, computer names will not resolve", "error", err)
+		} else {
+			crash.Go("DNS server", dns.Run)
+		}
+	}
+
+	nm.tunnelMu.Lock()
+	nm.tunnel, nm.dns = router, dns
+	nm.tunnelMu.Unlock()
+
+	logger.Info("TUN device up", "device", dev.Name(), "address", computerIP)
+	nm.syncTunnelPeers()
+	crash.Go("packet router", router.Run)
+}
+
+// tunnelGroups lê as rotas da divisão do túnel, que as configurações já validaram
+func tunnelGroups(routes []string) []*net.IPNet {
+	var groups []*net.IPNet
+	for _, route := range routes {
+		group, err := network.ParseTunnelRoute(route)
+		if err != nil {
+			logger.Warn("Ignoring invalid tunnel route", "route", route, "error", err)
+			continue
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// openTunnelDevice pede a interface TUN ao helper privilegiado e, sem helper, tenta criá-la
+// diretamente, o que só funciona quando o cliente roda como administrador
+func openTunnelDevice(cfg network.Config) (network.Device, error) {
+	dev, err := network.OpenHelperDevice(cfg)
+	if errors.Is(err, network.ErrHelperUnavailable) {
+		logger.Debug("Helper unavailable, creating TUN device directly", "error", err)
+		return network.OpenDevice(cfg)
+	}
+	return dev, err
+}
+
+// startPortForwards abre as portas locais que levam até os peers
+func (nm *NetworkManager) startPortForwards(proxy *network.Proxy, forwards []network.PortForward) {
+	if err := proxy.Listen(forwards); err != nil {
+		logger.Warn("Some port forwards could not be opened", "error", err)
+		nm.RealtimeData.EmitEvent(data.EventError, fmt.Sprintf("Some port forwards could not be opened: %v", err), nil)
+	}
+	logger.Info("Forwarding local ports to peers", "count", len(forwards))
+}
+
+// stopTunnel derruba a interface TUN, o servidor DNS, o proxy de portas, as transferências
+// de arquivos, as medições, a sondagem do MTU e o observador da rede, se houver
+func (nm *NetworkManager) stopTunnel() {
+	nm.tunnelMu.Lock()
+	router, dns, proxy, files, pinger, prober, traffic, paths, watcher, idle := nm.tunnel, nm.dns, nm.proxy, nm.files, nm.pinger, nm.mtu, nm.traffic, nm.paths, nm.watcher, nm.idle
+	nm.tunnel, nm.dns, nm.proxy, nm.files, nm.pinger, nm.mtu, nm.traffic, nm.paths, nm.watcher, nm.idle = nil, nil, nil, nil, nil, nil, nil, nil, nil, nil
+	nm.tunnelMu.Unlock()
+	nm.shaper.Store(nil)
+
+	if dns != nil {
+		dns.Close()
+	}
+	if idle != nil {
+		idle.Close()
+	}
+	if files != nil {
+		files.Close()
+	}
+	if pinger != nil {
+		pinger.Close()
+		nm.RealtimeData.SetPeerLinks(nil)
+	}
+	if prober != nil {
+		prober.Close()
+		nm.RealtimeData.ResetPeerMTUs()
+	}
+	if traffic != nil {
+		traffic.Close()
+		nm.RealtimeData.ResetTraffic()
+	}
+	if paths != nil {
+		paths.Close()
+		nm.RealtimeData.SetPeerPaths(nil)
+	}
+	if watcher != nil {
+		watcher.Close()
+	}
+	if proxy != nil {
+		proxy.Close()
+	}
+	if router == nil {
+		return
+	}
+	if err := router.Close(); err != nil {
+		logger.Warn("Error closing TUN device", "error", err)
+	}
+}
+
+// syncTunnelPeers atualiza as rotas e os nomes do DNS com os computadores da rede atual
+func (nm *NetworkManager) syncTunnelPeers() {
+	nm.tunnelMu.Lock()
+	router, dns, proxy := nm.tunnel, nm.dns, nm.proxy
+	nm.tunnelMu.Unlock()
+
+	if router == nil && proxy == nil {
+		return
+	}
+
+	peers := make(map[string]string)
+	for _, network := range nm.RealtimeData.GetNetworks() {
+		if network.NetworkID != nm.NetworkID {
+			continue
+		}
+		for _, computer := range network.Computers {
+			if computer.ComputerIP != "" {
+				peers[computer.ComputerIP] = computer.PublicKey
+			}
+		}
+		if dns != nil {
+			syncDNSRecords(dns, network.Computers)
+		}
+		break
+	}
+	if router != nil {
+		router.SetPeers(peers)
+	}
+	if proxy != nil {
+		proxy.SetPeers(peers)
+	}
+}
+
+// sendTunnelFrame entrega um frame ao peer, discando para ele no primeiro envio quando
+// este computador é quem oferece; do contrário a conexão chega pela malha do outro lado.
+// Enquanto o canal de dados não abre o frame é recusado com network.ErrPeerUnreachable.
+// Os frames passam antes pelo limite de banda da rede, que pode descartar pacotes.
+func (nm *NetworkManager) sendTunnelFrame(peerPublicKey string, frame []byte) error {
+	if _, ok := nm.peerConnection(peerPublicKey); !ok && !nm.IsRelayed(peerPublicKey) {
+		if nm.isOfferer(peerPublicKey) {
+			if err := nm.ConnectToPeer(peerPublicKey); err != nil {
+				return err
+			}
+		}
+		return network.ErrPeerUnreachable
+	}
+	nm.markActivity(frame)
+	if !nm.shapeFrame(peerPublicKey, frame) {
+		return nil
+	}
+	return nm.sendSealed(peerPublicKey, frame)
+}
+
+// sendSealed cifra e entrega um frame pelo caminho já aberto até o peer. Nada sai sem
+// cifra: até o handshake terminar o peer conta como inalcançável.
+func (nm *NetworkManager) sendSealed(peerPublicKey string, frame []byte) error {
+	session, err := nm.secureSession(peerPublicKey)
+	if err != nil {
+		return err
+	}
+	sealed, err := session.Seal(frame)
+	if errors.Is(err, network.ErrNoSessionKey) {
+		return network.ErrPeerUnreachable
+	}
+	if err != nil {
+		return err
+	}
+
+	// Frames maiores que o MTU do caminho seguem em pedaços, remontados antes de decifrar
+	frames := [][]byte{sealed}
+	if pathMTU := nm.pathMTU(peerPublicKey); pathMTU > 0 && len(sealed) > pathMTU {
+		if frames, err = network.Fragment(sealed, pathMTU); err != nil {
+			return err
+		}
+	}
+
+	// Pacotes UDP vão pelo canal sem retransmissão, onde a janela de replay aceita a
+	// desordem, e os arquivos pelo canal deles
+	send := nm.sendPeerFrame
+	if network.Unreliable(frame) {
+		send = nm.sendPeerDatagram
+	} else if network.IsFileTransfer(frame) {
+		send = nm.sendPeerFileFrame
+	}
+	for _, f := range frames {
+		err = send(peerPublicKey, f)
+		if errors.Is(err, clientwebrtc_impl.ErrDataChannelNotOpen) {
+			return network.ErrPeerUnreachable
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pathMTU retorna o maior frame que chega ao peer sem se perder, ou 0 enquanto a sondagem
+// não terminou
+func (nm *NetworkManager) pathMTU(peerPublicKey string) int {
+	nm.tunnelMu.Lock()
+	prober := nm.mtu
+	nm.tunnelMu.Unlock()
+
+	if prober == nil {
+		return 0
+	}
+	return prober.MTU(peerPublicKey)
+}
+
+// sendPing envia um ping só pelos caminhos já abertos, sem discar para o peer
+func (nm *NetworkManager) sendPing(peerPublicKey string, frame []byte) error {
+	if _, ok := nm.peerConnection(peerPublicKey); !ok && !nm.IsRelayed(peerPublicKey) {
+		return network.ErrPeerUnreachable
+	}
+	return nm.sendSealed(peerPublicKey, frame)
+}
+
+// syncPingPeers mede o enlace e o MTU dos computadores online da rede atual
+func (nm *NetworkManager) syncPingPeers() {
+	nm.tunnelMu.Lock()
+	pinger, prober := nm.pinger, nm.mtu
+	nm.tunnelMu.Unlock()
+
+	if pinger == nil {
+		return
+	}
+	var peers []string
+	for publicKey := range nm.meshMembers() {
+		peers = append(peers, publicKey)
+	}
+	pinger.SetPeers(peers)
+	if prober != nil {
+		prober.SetPeers(peers)
+	}
+}
+
+// handlePeerPacket remonta e decifra um frame recebido de um peer e o entrega à interface
+// TUN ou ao proxy de portas. Os frames de handshake vão para a sessão cifrada e os demais
+// sem cifra são descartados.
+func (nm *NetworkManager) handlePeerPacket(peerPublicKey string, frame []byte) {
+	frameType, payload, err := network.DecodeFrame(frame)
+	if err != nil {
+		logger.Debug("Dropping frame from peer", "peer", peerPublicKey, "error", err)
+		return
+	}
+
+	if frameType == network.FrameTypeFragment {
+		frame, err = nm.defrag.Add(peerPublicKey, payload)
+		if err == nil && frame == nil {
+			return
+		}
+		if err == nil {
+			frameType, payload, err = network.DecodeFrame(frame)
+		}
+		if err == nil && frameType == network.FrameTypeFragment {
+			err = errors.New("nested fragment")
+		}
+		if err != nil {
+			logger.Debug("Dropping fragment from peer", "peer", peerPublicKey, "error", err)
+			return
+		}
+	}
+
+	session, err := nm.secureSession(peerPublicKey)
+	if err != nil {
+		logger.Debug("Dropping frame from peer", "peer", peerPublicKey, "error", err)
+		return
+	}
+
+	switch frameType {
+	case network.FrameTypeHandshake:
+		if err := session.HandleHandshake(payload); err != nil {
+			logger.Warn("Handshake with peer failed", "peer", peerPublicKey, "error", err)
+		}
+		return
+	case network.FrameTypeSealed:
+		frame, err = session.Open(frame)
+		if err == nil {
+			frameType, _, err = network.DecodeFrame(frame)
+		}
+		if err != nil {
+			logger.Debug("Dropping frame from peer", "peer", peerPublicKey, "error", err)
+			return
+		}
+	default:
+		logger.Debug("Dropping unencrypted frame from peer", "peer", peerPublicKey, "frameType", frameType)
+		return
+	}
+
+	nm.tunnelMu.Lock()
+	router, proxy, files, pinger, prober := nm.tunnel, nm.proxy, nm.files, nm.pinger, nm.mtu
+	nm.tunnelMu.Unlock()
+
+	if frameType.IsPing() {
+		if pinger != nil {
+			err = pinger.HandleFrame(peerPublicKey, frame)
+		}
+	} else if frameType.IsMTU() {
+		if prober != nil {
+			err = prober.HandleFrame(peerPublicKey, frame)
+		}
+	} else if frameType.IsStream() {
+		nm.markActivity(frame)
+		if proxy != nil {
+			err = proxy.HandleFrame(peerPublicKey, frame)
+		}
+	} else if frameType.IsFile() {
+		nm.markActivity(frame)
+		if files != nil {
+			err = files.HandleFrame(peerPublicKey, frame)
+		}
+	} else if router != nil {
+		nm.markActivity(frame)
+		err = router.HandleFrame(peerPublicKey, frame)
+	}
+	if err != nil {
+		logger.Debug("Dropping frame from peer", "peer", peerPublicKey, "error", err)
+	}
+}
+
+// ownComputerIP retorna o IP virtual deste computador numa lista de membros
+func (nm *NetworkManager) ownComputerIP(computers []smodels.ComputerInfo) string {
+	publicKey := nm.ConfigManager.GetConfig().PublicKey
+	for _, computer := range computers {
+		if computer.PublicKey == publicKey {
+			return computer.ComputerIP
+		}
+	}
+	return ""
+}
@@ -0,0 +1,220 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+
+	clientwebrtc_impl "github.com/itxtoledo/govpn/cmd/client/webrtc"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// VPNClient é a estrutura principal do cliente VPN
+type VPNClient struct {
+	PrivateKey     interface{}
+	PublicKey      interface{}
+	PublicKeyStr   string // Identificador principal do cliente
+	ComputerName   string
+	CurrentNetwork string
+	IsConnected    bool
+	NetworkManager *NetworkManager
+	ConfigManager  *ConfigManager
+	WebRTCManager  *clientwebrtc_impl.WebRTCManager
+
+	// OnInvite abre um link de convite recebido pela API de controle; nil no cliente sem
+	// interface, que não tem onde mostrá-lo
+	OnInvite func(invite Invite)
+
+	controlMu sync.Mutex // Serializa as trocas de rede pedidas pela API de controle
+
+	// Agenda da conexão automática, acompanhada uma vez só, e a rede que ela conectou por
+	// último, em schedule.go
+	scheduleOnce sync.Once
+	autoNetwork  atomic.Value
+}
+
+// NewVPNClient creates a new VPN client
+func NewVPNClient(configManager *ConfigManager, defaultWebsocketURL string, computername string) *VPNClient {
+	var privateKey ed25519.PrivateKey
+	var publicKey ed25519.PublicKey
+	var publicKeyStr string
+
+	logger.Debug("Initializing VPN client")
+
+	// Load existing keys from config
+	publicKeyStr, privateKeyStr := configManager.GetKeyPair()
+
+	// Decode public key from base64
+	publicKeyBytes, err := base64.StdEncoding.DecodeString(publicKeyStr)
+	if err != nil {
+		logger.Error("Cannot decode the public key", "error", err)
+	} else {
+		publicKey = ed25519.PublicKey(publicKeyBytes)
+	}
+
+	// Decode private key from base66
+	privateKeyBytes, err := base64.StdEncoding.DecodeString(privateKeyStr)
+	if err != nil {
+		logger.Error("Cannot decode the private key", "error", err)
+	} else {
+		privateKey = ed25519.PrivateKey(privateKeyBytes)
+	}
+
+	// Create VPN client
+	client := &VPNClient{
+		IsConnected:   false,
+		PrivateKey:    privateKey,
+		PublicKey:     publicKey,
+		PublicKeyStr:  publicKeyStr,
+		ComputerName:  computername,
+		ConfigManager: configManager,
+	}
+
+	// Initialize WebRTCManager
+	webrtcManager, err := clientwebrtc_impl.NewWebRTCManager(configManager.GetConfig().WebRTCOptions())
+	if err != nil {
+		logger.Fatal("Failed to create WebRTCManager", "error", err)
+	}
+	client.WebRTCManager = webrtcManager
+
+	// TODO client.PublicKeyStr esta vazio
+	if client.PublicKeyStr == "" {
+		logger.Warn("VPN client initialized without a public key")
+	} else {
+		logger.Info("VPN client initialized", "publicKey", client.PublicKeyStr[:min(10, len(client.PublicKeyStr))]+"...")
+	}
+
+	return client
+}
+
+// SetupNetworkManager creates and configures the NetworkManager for the VPN client
+func (v *VPNClient) SetupNetworkManager(realtimeData *data.RealtimeDataLayer) {
+	v.NetworkManager = NewNetworkManager(realtimeData, v.ConfigManager, v.handleWebRTCMessageReceived)
+}
+
+// handleWebRTCMessageReceived handles incoming WebRTC messages from NetworkManager. Direct
+// chat messages and service scan requests stay in the NetworkManager; the others go to the
+// network chat.
+func (v *VPNClient) handleWebRTCMessageReceived(peerPublicKey string, message string) {
+	if v.NetworkManager.handleChatMessage(peerPublicKey, message) || v.NetworkManager.handleScanMessage(peerPublicKey, message) {
+		return
+	}
+	v.WebRTCManager.ReceiveMessage(message)
+}
+
+// LoadSettings carrega as configurações salvas na camada de dados
+func (v *VPNClient) LoadSettings(realtimeData *data.RealtimeDataLayer) {
+	// Carrega as configurações de usuário do config manager
+	config := v.ConfigManager.GetConfig()
+
+	// Atualiza o nome de usuário na camada de dados em tempo real
+	realtimeData.SetComputerName(config.ComputerName)
+
+	// Atualiza o endereço do servidor na camada de dados em tempo real
+	realtimeData.SetServerAddress(config.ServerAddress)
+
+	// Atualiza a chave pública na camada de dados em tempo real
+	realtimeData.SetPublicKey(v.PublicKeyStr)
+
+	// Configura o idioma (se implementado)
+	if config.Language != "" {
+		realtimeData.SetLanguage(config.Language)
+	}
+
+	logger.Debug("Settings loaded", "computerName", config.ComputerName, "language", config.Language,
+		"server", config.ServerAddress, "publicKey", v.PublicKeyStr)
+}
+
+// Run inicia o cliente VPN
+func (v *VPNClient) Run(defaultWebsocketURL string, realtimeData *data.RealtimeDataLayer) {
+	logger.Info("Starting goVPN client")
+
+	// Setup the network manager first
+	if v.NetworkManager == nil {
+		v.SetupNetworkManager(realtimeData)
+	}
+
+	// Attempt to connect to the backend in a background goroutine, then to the
+	// auto-connect network
+	crash.Go("connect", func() {
+		if v.Connect(defaultWebsocketURL) != nil {
+			return
+		}
+		if err := v.AutoConnect(); err != nil {
+			logger.Warn("Auto-connect failed", "error", err)
+			v.NetworkManager.RealtimeData.EmitEvent(data.EventError, fmt.Sprintf("Auto-connect failed: %v", err), nil)
+		}
+		v.scheduleOnce.Do(func() {
+			crash.Go("connection schedule", v.followSchedule)
+		})
+	})
+}
+
+// Connect escolhe o servidor, conecta à sinalização e envia as informações do cliente.
+// Bloqueia até o fim da tentativa; SetupNetworkManager precisa ter sido chamado antes.
+func (v *VPNClient) Connect(defaultWebsocketURL string) error {
+	realtimeData := v.NetworkManager.RealtimeData
+
+	// Defina o estado inicial na camada de dados
+	realtimeData.SetConnectionState(data.StateDisconnected)
+	realtimeData.SetStatusMessage("Starting...")
+
+	// Obter o endereço do servidor das configurações
+	config := v.ConfigManager.GetConfig()
+	serverAddress := config.ServerAddress
+
+	// Escolher o servidor mais rápido da lista, se configurado
+	if config.AutoSelectServer && config.ServerListURL != "" {
+		realtimeData.SetStatusMessage("Choosing server...")
+		if selected, err := v.selectFastestServer(config.ServerListURL); err != nil {
+			logger.Warn("Server selection failed", "server", serverAddress, "error", err)
+		} else {
+			serverAddress = selected
+			realtimeData.SetServerAddress(selected)
+		}
+	}
+
+	// Usar endereço padrão se não estiver definido
+	if serverAddress == "" {
+		serverAddress = defaultWebsocketURL
+		logger.Info("No server address configured, using the default from the build", "server", serverAddress)
+	}
+
+	// Tentativa de conexão ao servidor de backend
+	logger.Info("Connecting to the signaling server", "server", serverAddress)
+	realtimeData.SetStatusMessage("Connecting to backend...")
+
+	// Conectar ao servidor
+	if err := v.NetworkManager.Connect(serverAddress); err != nil {
+		logger.Warn("Background connection attempt failed", "server", serverAddress, "error", err)
+		realtimeData.SetStatusMessage("Connection failed")
+		realtimeData.EmitEvent(data.EventError, fmt.Sprintf("Connection failed: %v", err), nil)
+		return err
+	}
+
+	logger.Info("Connected to the signaling server", "server", serverAddress)
+	realtimeData.SetStatusMessage("Connected")
+
+	// Enviar informações do cliente para o servidor
+	v.NetworkManager.UpdateClientInfo()
+
+	v.NetworkManager.refreshNetworkList()
+	return nil
+}
+
+// GetIdentifier retorna o identificador do cliente
+func (v *VPNClient) GetIdentifier() string {
+	return v.PublicKeyStr
+}
+
+// GetConfig obtém uma configuração do ambiente ou retorna um valor padrão
+func (c *VPNClient) GetConfig(key string) string {
+	value := os.Getenv(key)
+	return value
+}
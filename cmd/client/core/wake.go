@@ -0,0 +1,83 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	"github.com/itxtoledo/govpn/libs/logger"
+	sclient "github.com/itxtoledo/govpn/libs/signaling/client"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// Com AllowWake ligado, o cliente informa ao servidor o MAC e a sub-rede da placa de rede
+// deste computador. Depois de desligado, um membro da rede pode pedir para acordá-lo: o
+// servidor escolhe outro membro online atrás do mesmo IP público, que envia o pacote mágico
+// na LAN. Desligar a opção apaga o endereço do servidor.
+
+// ErrNoWakeRelay é retornado quando nenhum membro online está na LAN do computador
+var ErrNoWakeRelay = errors.New("no online member of the network is on the same LAN as the computer")
+
+// ReportWakeInfo informa ao servidor como acordar este computador, ou que ele não pode ser
+// acordado, quando isso mudou desde o último envio
+func (nm *NetworkManager) ReportWakeInfo() {
+	if nm.connectionState != ConnectionStateConnected {
+		return
+	}
+
+	var mac, subnet string
+	if nm.ConfigManager.GetConfig().AllowWake {
+		var err error
+		if mac, subnet, err = network.WakeInterface(); err != nil {
+			logger.Warn("This computer cannot be woken up over the LAN", "error", err)
+		}
+	}
+
+	// wakeInfo vazio nunca é igual, nem com a opção desligada, e o primeiro envio sempre vai
+	nm.wakeMu.Lock()
+	defer nm.wakeMu.Unlock()
+	if nm.wakeInfo == mac+" "+subnet {
+		return
+	}
+	if _, err := nm.SignalingServer.ReportWakeInfo(mac, subnet); err != nil {
+		logger.Warn("Failed to report wake info", "error", err)
+		return
+	}
+	nm.wakeInfo = mac + " " + subnet
+	logger.Info("Reported wake info", "mac", mac, "subnet", subnet)
+}
+
+// resetWakeInfo faz o próximo ReportWakeInfo enviar o endereço mesmo sem mudança, como
+// depois de conectar, já que o servidor pode ter sido reiniciado ou o IP público mudado
+func (nm *NetworkManager) resetWakeInfo() {
+	nm.wakeMu.Lock()
+	defer nm.wakeMu.Unlock()
+	nm.wakeInfo = ""
+}
+
+// WakeComputer pede ao servidor para acordar um membro offline da rede conectada
+func (nm *NetworkManager) WakeComputer(networkID, publicKey string) error {
+	if nm.connectionState != ConnectionStateConnected {
+		return errors.New("not connected to the server")
+	}
+
+	if _, err := nm.SignalingServer.Wake(networkID, publicKey); err != nil {
+		if sclient.ErrorCode(err) == smodels.ErrNoWakeRelay {
+			return ErrNoWakeRelay
+		}
+		return fmt.Errorf("failed to wake computer: %v", err)
+	}
+
+	logger.Info("Asked to wake computer", "networkID", networkID, "publicKey", publicKey)
+	return nil
+}
+
+// handleWakeRelay envia na LAN o pacote mágico de um membro que outro pediu para acordar
+func (nm *NetworkManager) handleWakeRelay(notification smodels.WakeRelayNotification) {
+	if err := network.SendMagicPacket(notification.MAC, notification.Subnet); err != nil {
+		logger.Warn("Failed to send the magic packet", "target", notification.TargetPublicKey, "error", err)
+		return
+	}
+	logger.Info("Sent the magic packet", "networkID", notification.NetworkID, "target", notification.TargetPublicKey,
+		"requester", notification.RequesterPublicKey)
+}
@@ -0,0 +1,231 @@
+// Package crash recupera os panics das goroutines do cliente e guarda um relatório de cada
+// um. Em vez de o aplicativo inteiro fechar sem aviso quando um handler falha, a goroutine
+// termina, o relatório fica em <dados>/crashes com a pilha, a versão e o sistema, e a
+// interface oferece abrir uma issue já preenchida com ele.
+//
+// Um panic na goroutine principal, onde roda a interface, não tem como ser contornado: o
+// relatório é gravado, o cliente fecha como antes e o relatório é oferecido ao abrir de novo.
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// reportsDir é a pasta dos relatórios, dentro da pasta de dados
+const reportsDir = "crashes"
+
+// pendingFile guarda o relatório de um panic que fechou o cliente, até ele abrir de novo
+const pendingFile = "pending.json"
+
+// maxReports é quantos relatórios ficam guardados; os mais antigos são apagados
+const maxReports = 20
+
+// maxIssueStack limita a pilha levada à issue, para o endereço caber no navegador; o
+// relatório completo fica no arquivo
+const maxIssueStack = 4000
+
+// maxIssueTitle limita o título da issue, que leva a mensagem do panic
+const maxIssueTitle = 100
+
+// Report descreve um panic recuperado
+type Report struct {
+	Time      time.Time `json:"time"`
+	Where     string    `json:"where"` // Goroutine ou handler em que o panic aconteceu
+	Panic     string    `json:"panic"`
+	Stack     string    `json:"stack"`
+	Version   string    `json:"version"`
+	OS        string    `json:"os"`
+	GoVersion string    `json:"go_version"`
+	Path      string    `json:"path,omitempty"` // Arquivo do relatório, vazio quando não foi gravado
+}
+
+var (
+	mu       sync.Mutex
+	dataPath string
+	version  string
+	handler  func(Report)
+	reported = make(map[string]bool) // Lugares que já geraram relatório nesta execução
+)
+
+// Setup define onde os relatórios são gravados e a versão que eles citam. Sem Setup, os
+// panics são recuperados e só vão para o log.
+func Setup(path, appVersion string) {
+	mu.Lock()
+	defer mu.Unlock()
+	dataPath = path
+	version = appVersion
+}
+
+// SetHandler define quem é avisado de cada relatório, como a interface; o handler roda na
+// goroutine que falhou
+func SetHandler(fn func(Report)) {
+	mu.Lock()
+	defer mu.Unlock()
+	handler = fn
+}
+
+// Go roda fn numa goroutine nova que recupera um panic dela
+func Go(where string, fn func()) {
+	go func() {
+		defer Recover(where)
+		fn()
+	}()
+}
+
+// Recover, adiado no início de uma goroutine ou de um handler, recupera um panic dele e
+// grava o relatório. Um lugar que falha de novo na mesma execução só vai para o log, para um
+// handler chamado a cada pacote não encher a pasta e a tela.
+func Recover(where string) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	stack := string(debug.Stack())
+	logger.Error("Recovered from a panic", "where", where, "panic", fmt.Sprint(value), "stack", stack)
+
+	mu.Lock()
+	if reported[where] {
+		mu.Unlock()
+		return
+	}
+	reported[where] = true
+	report := newReport(where, value, stack)
+	notify := handler
+	mu.Unlock()
+
+	if notify != nil {
+		notify(report)
+	}
+}
+
+// Fatal, adiado na goroutine principal, grava o relatório de um panic que vai fechar o
+// cliente, o deixa para ser oferecido na próxima abertura e segue com o panic
+func Fatal() {
+	value := recover()
+	if value == nil {
+		return
+	}
+
+	mu.Lock()
+	report := newReport("main", value, string(debug.Stack()))
+	if report.Path != "" {
+		if content, err := json.Marshal(report); err == nil {
+			os.WriteFile(filepath.Join(dataPath, reportsDir, pendingFile), content, 0600)
+		}
+	}
+	mu.Unlock()
+
+	panic(value)
+}
+
+// Pending retorna o relatório do panic que fechou o cliente da última vez, uma vez só
+func Pending() (Report, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var report Report
+	if dataPath == "" {
+		return report, false
+	}
+	path := filepath.Join(dataPath, reportsDir, pendingFile)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return report, false
+	}
+	os.Remove(path)
+	if err := json.Unmarshal(content, &report); err != nil {
+		logger.Warn("Ignoring unreadable crash report", "path", path, "error", err)
+		return report, false
+	}
+	return report, true
+}
+
+// newReport monta o relatório e o grava na pasta de dados; chamado com mu travado
+func newReport(where string, value any, stack string) Report {
+	report := Report{
+		Time:      time.Now(),
+		Where:     where,
+		Panic:     fmt.Sprint(value),
+		Stack:     stack,
+		Version:   version,
+		OS:        runtime.GOOS + "/" + runtime.GOARCH,
+		GoVersion: runtime.Version(),
+	}
+	if dataPath == "" {
+		return report
+	}
+
+	dir := filepath.Join(dataPath, reportsDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		logger.Error("Cannot create the crash reports directory", "path", dir, "error", err)
+		return report
+	}
+	path := filepath.Join(dir, "crash-"+report.Time.Format("20060102-150405.000")+".txt")
+	if err := os.WriteFile(path, []byte(report.Text()), 0600); err != nil {
+		logger.Error("Cannot write the crash report", "path", path, "error", err)
+		return report
+	}
+	report.Path = path
+	logger.Error("Crash report saved", "path", path)
+	prune(dir)
+	return report
+}
+
+// prune apaga os relatórios mais antigos além de maxReports
+func prune(dir string) {
+	reports, err := filepath.Glob(filepath.Join(dir, "crash-*.txt"))
+	if err != nil || len(reports) <= maxReports {
+		return
+	}
+	// O nome leva a data, então a ordem dos nomes é a ordem de gravação
+	slices.Sort(reports)
+	for _, path := range reports[:len(reports)-maxReports] {
+		os.Remove(path)
+	}
+}
+
+// Text escreve o relatório como ele fica no arquivo
+func (r Report) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "GoVPN crash report\n\n")
+	fmt.Fprintf(&b, "Time:    %s\n", r.Time.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Version: %s\n", r.Version)
+	fmt.Fprintf(&b, "OS:      %s\n", r.OS)
+	fmt.Fprintf(&b, "Go:      %s\n", r.GoVersion)
+	fmt.Fprintf(&b, "Where:   %s\n", r.Where)
+	fmt.Fprintf(&b, "Panic:   %s\n\n", r.Panic)
+	b.WriteString(r.Stack)
+	return b.String()
+}
+
+// IssueURL retorna o endereço de uma issue nova no repositório, preenchida com o relatório
+func (r Report) IssueURL(repository string) string {
+	stack := r.Stack
+	if len(stack) > maxIssueStack {
+		stack = stack[:maxIssueStack] + "\n… (the full stack is in the crash report file)"
+	}
+	body := fmt.Sprintf("**What were you doing when it happened?**\n\n\n\n"+
+		"**Crash report**\n\n- Version: %s\n- OS: %s\n- Go: %s\n- Where: %s\n- Panic: `%s`\n\n```\n%s\n```\n",
+		r.Version, r.OS, r.GoVersion, r.Where, r.Panic, stack)
+
+	query := url.Values{}
+	title := "Crash in " + r.Where + ": " + r.Panic
+	if len(title) > maxIssueTitle {
+		title = title[:maxIssueTitle] + "…"
+	}
+	query.Set("title", title)
+	query.Set("body", body)
+	return strings.TrimSuffix(repository, "/") + "/issues/new?" + query.Encode()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/dialogs"
+)
+
+// showCrashReport tells the user that part of the app failed and was recovered, or that the
+// app closed the last time it ran, and offers to open an issue prefilled with the report.
+// It can be called from any goroutine; the dialog waits in the main window when it is hidden.
+func (ui *UIManager) showCrashReport(report crash.Report) {
+	message := fmt.Sprintf("Part of %s stopped after an unexpected error (%s). The rest kept running, but restart the app if something no longer works.", AppName, report.Where)
+	if report.Where == "main" {
+		message = fmt.Sprintf("%s closed unexpectedly the last time it ran.", AppName)
+	}
+	if report.Path != "" {
+		message += "\n\nThe crash report was saved to " + report.Path
+	}
+
+	fyne.Do(func() {
+		label := widget.NewLabel(message + "\n\nReporting it on GitHub helps fix it. The issue is prefilled with the report; nothing is sent until you submit it.")
+		label.Wrapping = fyne.TextWrapWord
+		copyButton := widget.NewButtonWithIcon("Copy report", theme.ContentCopyIcon(), func() {
+			copyToClipboard(report.Text(), "Crash report")
+		})
+
+		confirm := dialog.NewCustomConfirm("Something went wrong", "Report issue", "Close",
+			container.NewVBox(label, container.NewHBox(copyButton)),
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				issueURL, err := url.Parse(report.IssueURL(AppRepository))
+				if err == nil {
+					err = ui.App.OpenURL(issueURL)
+				}
+				if err != nil {
+					dialogs.ShowError(err, ui.MainWindow)
+				}
+			}, ui.MainWindow)
+		confirm.Resize(fyne.NewSize(420, 0))
+		confirm.Show()
+
+		if report.Where != "main" {
+			ui.App.SendNotification(&fyne.Notification{
+				Title:   "Something went wrong",
+				Content: fmt.Sprintf("%s recovered from an error. Open the window to report it.", AppName),
+			})
+		}
+	})
+}
@@ -2,13 +2,13 @@ package main
 
 import (
 	"errors"
-	"fmt"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
 	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
 	"github.com/itxtoledo/govpn/libs/utils"
 	"github.com/itxtoledo/govpn/cmd/client/ui"
@@ -74,20 +74,13 @@ func (rw *NetworkWindow) Show() {
 	confirmPINEntry.PlaceHolder = "Repeat 4-digit PIN"
 	ui.ConfigurePINEntry(confirmPINEntry)
 
-	// Add keyboard shortcuts
+	// Enter passa ao campo seguinte e, no último, cria a rede
 	nameEntry.OnSubmitted = func(text string) {
-		pinEntry.FocusGained()
+		rw.BaseWindow.Window.Canvas().Focus(pinEntry)
 	}
 
 	pinEntry.OnSubmitted = func(text string) {
-		confirmPINEntry.FocusGained()
-	}
-
-	confirmPINEntry.OnSubmitted = func(text string) {
-		// Trigger create button when Enter is pressed on pin field
-		if nameEntry.Text != "" && utils.ValidatePIN(text) {
-			// Will be triggered by the create button logic
-		}
+		rw.BaseWindow.Window.Canvas().Focus(confirmPINEntry)
 	}
 
 	// Create compact form with better spacing
@@ -130,7 +123,7 @@ func (rw *NetworkWindow) Show() {
 		createButton.Disable()
 
 		// Create network in a goroutine
-		go func() {
+		crash.Go("create network", func() {
 			// Send create network command to backend
 			res, err := rw.CreateNetwork(name, pin)
 
@@ -139,7 +132,7 @@ func (rw *NetworkWindow) Show() {
 				createButton.Enable()
 
 				if err != nil {
-					dialog.ShowError(fmt.Errorf("failed to create network: %v", err), rw.BaseWindow.Window)
+					dialog.ShowError(requestError("create network", err), rw.BaseWindow.Window)
 					return
 				}
 
@@ -157,13 +150,19 @@ func (rw *NetworkWindow) Show() {
 					dialog.ShowError(errors.New("failed to create network: no network ID returned"), rw.BaseWindow.Window)
 				}
 			})
-		}()
+		})
 	})
 
 	cancelButton := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
 		rw.BaseWindow.Close()
 	})
 
+	confirmPINEntry.OnSubmitted = func(text string) {
+		if !createButton.Disabled() {
+			createButton.OnTapped()
+		}
+	}
+
 	// Style buttons
 	createButton.Importance = widget.HighImportance
 
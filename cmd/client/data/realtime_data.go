@@ -1,10 +1,12 @@
 package data
 
 import (
-	"log"
+	"slices"
 	"sync"
+	"time"
 
-	"fyne.io/fyne/v2/data/binding"
+	"github.com/itxtoledo/govpn/cmd/client/bus"
+	"github.com/itxtoledo/govpn/libs/logger"
 	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
 )
 
@@ -38,6 +40,28 @@ const (
 	EventComputerJoined      EventType = "computer_joined"    // Add this constant for computer joined event
 	EventComputerConnected   EventType = "computer_connected" // Add this constant for computer connected event
 	EventSettingsChanged     EventType = "settings_changed"
+	// EventComputerLeft é emitido quando um computador sai de uma sala
+	EventComputerLeft EventType = "computer_left"
+	// EventKicked é emitido quando o dono remove este computador de uma sala
+	EventKicked EventType = "kicked"
+	// EventServerShutdown é emitido quando o servidor avisa que vai desligar
+	EventServerShutdown EventType = "server_shutdown"
+	// EventPeerConnected é emitido quando a conexão direta com um peer se estabelece
+	EventPeerConnected EventType = "peer_connected"
+	// EventPeerLost é emitido quando a conexão direta com um peer falha ou cai
+	EventPeerLost EventType = "peer_lost"
+	// EventNetworkExpiring é emitido quando uma rede própria está perto de ser excluída por inatividade
+	EventNetworkExpiring EventType = "network_expiring"
+	// EventServerNotice é emitido quando o servidor envia um aviso do operador
+	EventServerNotice EventType = "server_notice"
+	// EventFileOffered é emitido quando um peer oferece um arquivo, que espera ser aceito ou recusado
+	EventFileOffered EventType = "file_offered"
+	// EventFileTransferFinished é emitido quando uma transferência de arquivo termina, bem ou mal
+	EventFileTransferFinished EventType = "file_transfer_finished"
+	// EventChatMessage é emitido quando chega uma mensagem direta de um peer ou muda o estado de entrega de uma enviada
+	EventChatMessage EventType = "chat_message"
+	// EventSubnetConflict é emitido quando a sub-rede da rede se sobrepõe a uma rede local
+	EventSubnetConflict EventType = "subnet_conflict"
 	// EventError é emitido quando ocorre um erro
 	EventError EventType = "error"
 )
@@ -55,66 +79,79 @@ type Event struct {
 	Data    interface{}
 }
 
-// RealtimeDataLayer é a camada centralizada de dados em tempo real
+// RealtimeDataLayer é a camada centralizada de dados em tempo real. O estado do cliente e
+// os eventos vão para Bus, nos tópicos de topics.go; a interface os converte em bindings do
+// Fyne e o daemon lê os mesmos tópicos, então a camada não depende da interface gráfica.
+// Os dados de cada peer, consultados a cada redesenho da lista, ficam nos mapas abaixo.
 type RealtimeDataLayer struct {
-	// Dados de conexão
-	ConnectionState binding.Int
-	IsConnected     binding.Bool
-	StatusMessage   binding.String
+	Bus *bus.Bus
 
-	// Dados de usuário
-	ComputerName binding.String
-	ComputerIP   binding.String
+	// Lista de redes do usuário, publicada inteira em TopicNetworks a cada mudança
+	networks []Network
 
-	// Dados de configuração
-	ServerAddress binding.String
-	Language      binding.String
+	// Qualidade do enlace com cada computador da rede atual, pela chave pública
+	peerLinks map[string]PeerLink
 
-	// Dados de rede
-	ComputersCount   binding.Int
-	NetworkLatency   binding.Float
-	TransferredBytes binding.Float
-	ReceivedBytes    binding.Float
-	PublicKey        binding.String // Public key identifier
+	// Caminho escolhido pelo ICE até cada computador da rede atual
+	peerPaths map[string]PeerPath
 
-	// Dados de sala
-	NetworkName binding.String
-	Networks    binding.UntypedList // Lista de salas do usuário
+	// Maior pacote IP que chega inteiro a cada computador da rede atual, sem fragmentar
+	peerMTUs map[string]int
 
-	// Canal de eventos
-	eventChan   chan Event
-	subscribers []chan Event
-	mu          sync.Mutex
+	// Tráfego da sessão na rede atual: vazão recente e totais por computador
+	trafficHistory []TrafficSample
+	peerTraffic    map[string]PeerTraffic
+
+	// A lista de redes é a última recebida do servidor, mantida enquanto ele está fora
+	networksOffline bool
+
+	// Última vez que cada computador foi visto online, por rede e chave pública
+	lastSeen map[string]map[string]time.Time
+
+	mu sync.Mutex
+}
+
+// TrafficHistorySize é quantas amostras de vazão, uma por segundo, ficam guardadas
+const TrafficHistorySize = 60
+
+// TrafficSample é a vazão de um segundo, em bytes por segundo
+type TrafficSample struct {
+	At       time.Time
+	Sent     float64
+	Received float64
+}
+
+// PeerTraffic são os bytes trocados com um computador desde a conexão à rede
+type PeerTraffic struct {
+	Sent     uint64
+	Received uint64
+}
+
+// PeerLink é a qualidade medida do enlace com um computador
+type PeerLink struct {
+	RTT    time.Duration
+	Jitter time.Duration
+	Loss   float64 // Fração dos pings recentes sem resposta, de 0 a 1
+}
+
+// PeerPath é o caminho que o ICE escolheu até um computador
+type PeerPath struct {
+	LocalCandidate  string  // Tipo do candidato local: host, srflx, prflx ou relay
+	RemoteCandidate string  // Tipo do candidato do computador
+	Relayed         bool    // Se o tráfego passa por um servidor TURN
+	Bitrate         float64 // Vazão recente nos dois sentidos, em bits por segundo
+	Retransmissions uint64  // Verificações ICE retransmitidas no par
 }
 
 // NewRealtimeDataLayer cria uma nova instância da camada de dados em tempo real
 func NewRealtimeDataLayer() *RealtimeDataLayer {
-	rdl := &RealtimeDataLayer{
-		// Inicialização dos bindings
-		ConnectionState:  binding.NewInt(),
-		IsConnected:      binding.NewBool(),
-		StatusMessage:    binding.NewString(),
-		ComputerName:     binding.NewString(),
-		ComputerIP:       binding.NewString(),
-		ServerAddress:    binding.NewString(),
-		Language:         binding.NewString(),
-		ComputersCount:   binding.NewInt(),
-		NetworkLatency:   binding.NewFloat(),
-		TransferredBytes: binding.NewFloat(),
-		ReceivedBytes:    binding.NewFloat(),
-		PublicKey:        binding.NewString(),
-		NetworkName:      binding.NewString(),
-		Networks:         binding.NewUntypedList(),
-
-		// Canal de eventos
-		eventChan:   make(chan Event, 100),
-		subscribers: make([]chan Event, 0),
+	return &RealtimeDataLayer{
+		Bus:         bus.New(),
+		peerLinks:   make(map[string]PeerLink),
+		peerPaths:   make(map[string]PeerPath),
+		peerMTUs:    make(map[string]int),
+		peerTraffic: make(map[string]PeerTraffic),
 	}
-
-	// Iniciar o processamento de eventos
-	go rdl.processEvents()
-
-	return rdl
 }
 
 // InitDefaults inicializa os valores padrão
@@ -124,133 +161,367 @@ func (rdl *RealtimeDataLayer) InitDefaults() {
 	rdl.SetStatusMessage("Not connected")
 	rdl.SetComputerName("Computer")
 	rdl.SetComputerIP("0.0.0.0")
+	rdl.SetPublicIP("")
+	rdl.SetNatType("")
 	rdl.SetServerAddress("ws://localhost:8080")
 	rdl.SetLanguage("en")
+	rdl.SetPublicKey("")
 	rdl.SetNetworkInfo("Not connected")
-	rdl.UpdateNetworkStats(0, 0.0, 0.0, 0.0)
+	bus.Publish(rdl.Bus, TopicLatency, 0)
+	bus.Publish(rdl.Bus, TopicPeerSessions, 0)
+	bus.Publish(rdl.Bus, TopicTraffic, Traffic{})
+	rdl.mu.Lock()
+	rdl.publishNetworks()
+	rdl.mu.Unlock()
+}
+
+// latest retorna o valor atual de um tópico de estado, ou o valor zero antes do primeiro
+func latest[T any](rdl *RealtimeDataLayer, topic bus.Topic[T]) T {
+	value, _ := bus.Latest(rdl.Bus, topic)
+	return value
 }
 
 // SetConnectionState define o estado da conexão
 func (rdl *RealtimeDataLayer) SetConnectionState(state ConnectionState) {
-	rdl.ConnectionState.Set(int(state))
-	rdl.IsConnected.Set(state == StateConnected)
+	bus.Publish(rdl.Bus, TopicConnectionState, state)
 
 	// Emitir evento
 	rdl.EmitEvent(EventConnectionStateChanged, "", state)
 }
 
+// GetConnectionState retorna o estado da conexão
+func (rdl *RealtimeDataLayer) GetConnectionState() ConnectionState {
+	return latest(rdl, TopicConnectionState)
+}
+
+// IsConnected diz se o cliente está conectado ao servidor
+func (rdl *RealtimeDataLayer) IsConnected() bool {
+	return rdl.GetConnectionState() == StateConnected
+}
+
 // SetStatusMessage define a mensagem de status
 func (rdl *RealtimeDataLayer) SetStatusMessage(message string) {
-	rdl.StatusMessage.Set(message)
+	bus.Publish(rdl.Bus, TopicStatusMessage, message)
+}
+
+// GetStatusMessage retorna a mensagem de status
+func (rdl *RealtimeDataLayer) GetStatusMessage() string {
+	return latest(rdl, TopicStatusMessage)
 }
 
 // SetComputerName define o nome de usuário
 func (rdl *RealtimeDataLayer) SetComputerName(computername string) {
-	rdl.ComputerName.Set(computername)
+	bus.Publish(rdl.Bus, TopicComputerName, computername)
 
 	// Update the computer name in the Networks list for the local client
-	localPublicKey, _ := rdl.PublicKey.Get()
+	localPublicKey := rdl.GetPublicKey()
 	if localPublicKey == "" {
-		log.Println("SetComputerName: Local public key not set, cannot update network list.")
+		logger.Debug("Local public key not set, not updating the computer name in the networks")
 		return
 	}
 
-	currentNetworks, _ := rdl.Networks.Get()
-	updatedNetworks := make([]interface{}, len(currentNetworks))
-	changed := false
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
 
-	for i, r := range currentNetworks {
-		if networkPtr, ok := r.(*Network); ok {
-			// Create a copy to modify
-			tempNetwork := *networkPtr
-			tempComputers := make([]ComputerInfo, len(tempNetwork.Computers))
-			copy(tempComputers, tempNetwork.Computers)
-
-			for j, computer := range tempComputers {
-				if computer.PublicKey == localPublicKey {
-					if computer.Name != computername {
-						tempComputers[j].Name = computername
-						changed = true
-					}
-				}
+	changed := false
+	for i := range rdl.networks {
+		computers := rdl.networks[i].Computers
+		for j, computer := range computers {
+			if computer.PublicKey == localPublicKey && computer.Name != computername {
+				// Copy before changing, the old slice may have been handed out by GetNetworks
+				computers = slices.Clone(computers)
+				computers[j].Name = computername
+				rdl.networks[i].Computers = computers
+				changed = true
 			}
-			tempNetwork.Computers = tempComputers
-			updatedNetworks[i] = &tempNetwork
-		} else {
-			updatedNetworks[i] = r // Keep as is if type assertion fails
 		}
 	}
 
 	if changed {
-		rdl.Networks.Set(updatedNetworks)
+		rdl.publishNetworks()
 		rdl.EmitEvent(EventNetworksChanged, "Local computer name updated in networks", nil)
 	}
 }
 
+// GetComputerName retorna o nome do computador
+func (rdl *RealtimeDataLayer) GetComputerName() string {
+	return latest(rdl, TopicComputerName)
+}
+
 // SetComputerIP define o IP do usuário
 func (rdl *RealtimeDataLayer) SetComputerIP(ip string) {
-	rdl.ComputerIP.Set(ip)
+	bus.Publish(rdl.Bus, TopicComputerIP, ip)
+}
+
+// GetComputerIP retorna o IP virtual do computador na rede atual
+func (rdl *RealtimeDataLayer) GetComputerIP() string {
+	return latest(rdl, TopicComputerIP)
+}
+
+// SetPublicIP define o endereço público do usuário, como visto pelo servidor
+func (rdl *RealtimeDataLayer) SetPublicIP(ip string) {
+	bus.Publish(rdl.Bus, TopicPublicIP, ip)
+}
+
+// GetPublicIP retorna o endereço público visto pelo servidor
+func (rdl *RealtimeDataLayer) GetPublicIP() string {
+	return latest(rdl, TopicPublicIP)
+}
+
+// SetNatType define o tipo de NAT detectado
+func (rdl *RealtimeDataLayer) SetNatType(natType string) {
+	bus.Publish(rdl.Bus, TopicNatType, natType)
+}
+
+// GetNatType retorna o tipo de NAT detectado
+func (rdl *RealtimeDataLayer) GetNatType() string {
+	return latest(rdl, TopicNatType)
 }
 
 // SetServerAddress define o endereço do servidor
 func (rdl *RealtimeDataLayer) SetServerAddress(address string) {
-	rdl.ServerAddress.Set(address)
+	bus.Publish(rdl.Bus, TopicServerAddress, address)
+}
+
+// GetServerAddress retorna o endereço do servidor
+func (rdl *RealtimeDataLayer) GetServerAddress() string {
+	return latest(rdl, TopicServerAddress)
 }
 
 // SetLanguage define o idioma da interface
 func (rdl *RealtimeDataLayer) SetLanguage(lang string) {
-	rdl.Language.Set(lang)
+	bus.Publish(rdl.Bus, TopicLanguage, lang)
+}
+
+// SetPublicKey define a chave pública deste computador
+func (rdl *RealtimeDataLayer) SetPublicKey(publicKey string) {
+	bus.Publish(rdl.Bus, TopicPublicKey, publicKey)
+}
+
+// GetPublicKey retorna a chave pública deste computador
+func (rdl *RealtimeDataLayer) GetPublicKey() string {
+	return latest(rdl, TopicPublicKey)
+}
+
+// SetPeerLinks substitui as medições de enlace dos computadores e atualiza a latência
+// média da rede, em milissegundos
+func (rdl *RealtimeDataLayer) SetPeerLinks(links map[string]PeerLink) {
+	rdl.mu.Lock()
+	rdl.peerLinks = make(map[string]PeerLink, len(links))
+	var total time.Duration
+	for publicKey, link := range links {
+		rdl.peerLinks[publicKey] = link
+		total += link.RTT
+	}
+	rdl.mu.Unlock()
+
+	latency := 0.0
+	if len(links) > 0 {
+		latency = float64(total.Microseconds()) / 1000 / float64(len(links))
+	}
+	bus.Publish(rdl.Bus, TopicLatency, latency)
+}
+
+// GetPeerLink retorna a medição do enlace com um computador, se houver
+func (rdl *RealtimeDataLayer) GetPeerLink(publicKey string) (PeerLink, bool) {
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
+
+	link, ok := rdl.peerLinks[publicKey]
+	return link, ok
+}
+
+// SetPeerPaths substitui os caminhos até os computadores
+func (rdl *RealtimeDataLayer) SetPeerPaths(paths map[string]PeerPath) {
+	rdl.mu.Lock()
+	rdl.peerPaths = make(map[string]PeerPath, len(paths))
+	for publicKey, path := range paths {
+		rdl.peerPaths[publicKey] = path
+	}
+	rdl.mu.Unlock()
+
+	bus.Publish(rdl.Bus, TopicPeerSessions, len(paths))
+}
+
+// GetPeerPath retorna o caminho até um computador, se a conexão já foi estabelecida
+func (rdl *RealtimeDataLayer) GetPeerPath(publicKey string) (PeerPath, bool) {
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
+
+	path, ok := rdl.peerPaths[publicKey]
+	return path, ok
+}
+
+// GetPeerSessions retorna quantos computadores da rede atual têm a conexão estabelecida
+func (rdl *RealtimeDataLayer) GetPeerSessions() int {
+	return latest(rdl, TopicPeerSessions)
+}
+
+// SetPeerMTU guarda o MTU descoberto até um computador; 0 esquece o computador
+func (rdl *RealtimeDataLayer) SetPeerMTU(publicKey string, mtu int) {
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
+
+	if mtu == 0 {
+		delete(rdl.peerMTUs, publicKey)
+		return
+	}
+	rdl.peerMTUs[publicKey] = mtu
 }
 
-// UpdateNetworkStats atualiza as estatísticas de rede
-func (rdl *RealtimeDataLayer) UpdateNetworkStats(computersCount int, latency, sent, received float64) {
-	rdl.ComputersCount.Set(computersCount)
-	rdl.NetworkLatency.Set(latency)
-	rdl.TransferredBytes.Set(sent)
-	rdl.ReceivedBytes.Set(received)
+// ResetPeerMTUs esquece o MTU de todos os computadores
+func (rdl *RealtimeDataLayer) ResetPeerMTUs() {
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
+
+	rdl.peerMTUs = make(map[string]int)
+}
+
+// GetPeerMTU retorna o MTU até um computador, se já foi descoberto
+func (rdl *RealtimeDataLayer) GetPeerMTU(publicKey string) (int, bool) {
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
+
+	mtu, ok := rdl.peerMTUs[publicKey]
+	return mtu, ok
+}
+
+// RecordTraffic guarda uma amostra de vazão e os totais por computador, e atualiza os
+// totais da sessão com a soma deles
+func (rdl *RealtimeDataLayer) RecordTraffic(sample TrafficSample, peers map[string]PeerTraffic) {
+	rdl.mu.Lock()
+	rdl.trafficHistory = append(rdl.trafficHistory, sample)
+	if len(rdl.trafficHistory) > TrafficHistorySize {
+		rdl.trafficHistory = rdl.trafficHistory[len(rdl.trafficHistory)-TrafficHistorySize:]
+	}
+	rdl.peerTraffic = make(map[string]PeerTraffic, len(peers))
+	var sent, received uint64
+	for publicKey, traffic := range peers {
+		rdl.peerTraffic[publicKey] = traffic
+		sent += traffic.Sent
+		received += traffic.Received
+	}
+	rdl.mu.Unlock()
+
+	bus.Publish(rdl.Bus, TopicTraffic, Traffic{Sent: sent, Received: received, SendRate: sample.Sent, ReceiveRate: sample.Received})
+}
+
+// ResetTraffic esquece a vazão e os totais, ao sair da rede
+func (rdl *RealtimeDataLayer) ResetTraffic() {
+	rdl.mu.Lock()
+	rdl.trafficHistory = nil
+	rdl.peerTraffic = make(map[string]PeerTraffic)
+	rdl.mu.Unlock()
+
+	bus.Publish(rdl.Bus, TopicTraffic, Traffic{})
+}
+
+// TrafficHistory retorna as amostras de vazão recentes, as mais antigas primeiro
+func (rdl *RealtimeDataLayer) TrafficHistory() []TrafficSample {
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
+
+	return append([]TrafficSample(nil), rdl.trafficHistory...)
+}
+
+// GetTraffic retorna os totais da sessão e a vazão da última amostra
+func (rdl *RealtimeDataLayer) GetTraffic() Traffic {
+	return latest(rdl, TopicTraffic)
+}
+
+// GetPeerTraffic retorna os bytes trocados com um computador na sessão, se houver
+func (rdl *RealtimeDataLayer) GetPeerTraffic(publicKey string) (PeerTraffic, bool) {
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
+
+	traffic, ok := rdl.peerTraffic[publicKey]
+	return traffic, ok
 }
 
 // SetNetworkInfo define as informações da sala
 func (rdl *RealtimeDataLayer) SetNetworkInfo(name string) {
-	rdl.NetworkName.Set(name)
+	bus.Publish(rdl.Bus, TopicNetworkName, name)
+}
+
+// GetNetworkName retorna a sala atual
+func (rdl *RealtimeDataLayer) GetNetworkName() string {
+	return latest(rdl, TopicNetworkName)
+}
+
+// publishNetworks publica uma cópia da lista de redes; chamado com mu travado
+func (rdl *RealtimeDataLayer) publishNetworks() {
+	bus.Publish(rdl.Bus, TopicNetworks, slices.Clone(rdl.networks))
 }
 
-// SetNetworks define a lista completa de salas
+// SetNetworks define a lista completa de salas, recebida do servidor
 func (rdl *RealtimeDataLayer) SetNetworks(networks []Network) {
 	rdl.mu.Lock()
 	defer rdl.mu.Unlock()
 
-	log.Printf("SetNetworks: Received %d networks to set.", len(networks))
-	for i, net := range networks {
-		log.Printf("SetNetworks: Network %d: ID=%s, Name=%s, Computers=%d", i, net.NetworkID, net.NetworkName, len(net.Computers))
-	}
+	rdl.networksOffline = false
 
-	// Convert []Network to []interface{} of *Network
-	var untypedNetworks []interface{}
-	for i := range networks {
-		untypedNetworks = append(untypedNetworks, &networks[i])
+	logger.Debug("Setting networks", "count", len(networks))
+	for _, net := range networks {
+		logger.Debug("Network", "networkID", net.NetworkID, "name", net.NetworkName, "computers", len(net.Computers))
 	}
-	rdl.Networks.Set(untypedNetworks)
+
+	rdl.networks = slices.Clone(networks)
+	rdl.publishNetworks()
 	rdl.EmitEvent(EventNetworksChanged, "Networks list updated", nil)
 }
 
+// MarkNetworksOffline mantém a lista de redes atual como a última conhecida enquanto o
+// servidor está fora, até a próxima lista dele a substituir
+func (rdl *RealtimeDataLayer) MarkNetworksOffline() {
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
+
+	if rdl.networksOffline {
+		return
+	}
+	rdl.networksOffline = true
+	rdl.EmitEvent(EventNetworksChanged, "Networks list offline", nil)
+}
+
+// NetworksOffline diz se a lista de redes é a última conhecida, e não a atual do servidor
+func (rdl *RealtimeDataLayer) NetworksOffline() bool {
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
+	return rdl.networksOffline
+}
+
+// SetLastSeen define quando cada computador foi visto online pela última vez, por rede e
+// chave pública
+func (rdl *RealtimeDataLayer) SetLastSeen(lastSeen map[string]map[string]time.Time) {
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
+	rdl.lastSeen = lastSeen
+}
+
+// LastSeen retorna quando o computador foi visto online pela última vez na rede
+func (rdl *RealtimeDataLayer) LastSeen(networkID, publicKey string) (time.Time, bool) {
+	rdl.mu.Lock()
+	defer rdl.mu.Unlock()
+	seen, ok := rdl.lastSeen[networkID][publicKey]
+	return seen, ok
+}
+
 // AddNetwork adiciona uma nova sala à lista
 func (rdl *RealtimeDataLayer) AddNetwork(network Network) {
 	rdl.mu.Lock()
 	defer rdl.mu.Unlock()
 
-	currentNetworks, _ := rdl.Networks.Get()
-	for _, existing := range currentNetworks {
-		if n, ok := existing.(*Network); ok && n.NetworkID == network.NetworkID {
+	for _, existing := range rdl.networks {
+		if existing.NetworkID == network.NetworkID {
 			// Network already exists, do not add
 			return
 		}
 	}
 
-	newNetworks := append(currentNetworks, &network)
-	rdl.Networks.Set(newNetworks)
+	rdl.networks = append(rdl.networks, network)
+	rdl.publishNetworks()
 	rdl.EmitEvent(EventNetworksChanged, "Network added", nil)
-	log.Printf("AddNetwork: Networks binding length after Set: %d", rdl.Networks.Length())
+	logger.Debug("Network added", "networkID", network.NetworkID, "networks", len(rdl.networks))
 }
 
 // RemoveNetwork remove uma sala da lista pelo ID
@@ -258,14 +529,10 @@ func (rdl *RealtimeDataLayer) RemoveNetwork(networkID string) {
 	rdl.mu.Lock()
 	defer rdl.mu.Unlock()
 
-	currentNetworks, _ := rdl.Networks.Get()
-	var updatedNetworks []interface{}
-	for _, r := range currentNetworks {
-		if networkPtr, ok := r.(*Network); ok && networkPtr.NetworkID != networkID {
-			updatedNetworks = append(updatedNetworks, networkPtr)
-		}
-	}
-	rdl.Networks.Set(updatedNetworks)
+	rdl.networks = slices.DeleteFunc(slices.Clone(rdl.networks), func(network Network) bool {
+		return network.NetworkID == networkID
+	})
+	rdl.publishNetworks()
 	rdl.EmitEvent(EventNetworksChanged, "Network removed", nil)
 }
 
@@ -274,23 +541,19 @@ func (rdl *RealtimeDataLayer) UpdateNetwork(index int, network Network) {
 	rdl.mu.Lock()
 	defer rdl.mu.Unlock()
 
-	currentNetworks, _ := rdl.Networks.Get()
-	if index >= 0 && index < len(currentNetworks) {
-		if existingNetworkPtr, ok := currentNetworks[index].(*Network); ok {
-			// Update the fields of the existing network struct
-			existingNetworkPtr.NetworkID = network.NetworkID
-			existingNetworkPtr.NetworkName = network.NetworkName
-			existingNetworkPtr.JoinedAt = network.JoinedAt
-			existingNetworkPtr.LastConnected = network.LastConnected
-			existingNetworkPtr.ComputerIP = network.ComputerIP
-			existingNetworkPtr.AdminPublicKey = network.AdminPublicKey
-			existingNetworkPtr.Computers = network.Computers // This will replace the entire slice
-			// Notify the binding that the item has changed
-			rdl.Networks.Set(currentNetworks) // Re-setting the list to trigger UI refresh
-		} else {
-			log.Printf("UpdateNetwork: Type assertion failed for network at index %d", index)
-		}
+	if index < 0 || index >= len(rdl.networks) {
+		return
 	}
+	existing := &rdl.networks[index]
+	existing.NetworkID = network.NetworkID
+	existing.NetworkName = network.NetworkName
+	existing.JoinedAt = network.JoinedAt
+	existing.LastConnected = network.LastConnected
+	existing.ComputerIP = network.ComputerIP
+	existing.AdminPublicKey = network.AdminPublicKey
+	existing.Subnet = network.Subnet
+	existing.Computers = network.Computers // This will replace the entire slice
+	rdl.publishNetworks()
 }
 
 // GetNetworks retorna a lista atual de salas
@@ -298,72 +561,24 @@ func (rdl *RealtimeDataLayer) GetNetworks() []Network {
 	rdl.mu.Lock()
 	defer rdl.mu.Unlock()
 
-	currentNetworks, _ := rdl.Networks.Get()
-	log.Printf("GetNetworks: Retrieved %d networks", len(currentNetworks))
-	networks := make([]Network, len(currentNetworks))
-	for i, r := range currentNetworks {
-		if networkPtr, ok := r.(*Network); ok {
-			networks[i] = *networkPtr
-		} else {
-			log.Printf("GetNetworks: Type assertion failed for network at index %d", i)
-		}
-	}
-	return networks
+	return slices.Clone(rdl.networks)
 }
 
-// Subscribe inscreve um novo assinante para eventos
-func (rdl *RealtimeDataLayer) Subscribe() chan Event {
-	rdl.mu.Lock()
-	defer rdl.mu.Unlock()
-
-	ch := make(chan Event, 10)
-	rdl.subscribers = append(rdl.subscribers, ch)
-	return ch
+// RequestRefresh pede à interface para redesenhar uma parte
+func (rdl *RealtimeDataLayer) RequestRefresh(refresh Refresh) {
+	bus.Publish(rdl.Bus, TopicRefresh, refresh)
 }
 
-// Unsubscribe cancela a inscrição de um assinante
-func (rdl *RealtimeDataLayer) Unsubscribe(ch chan Event) {
-	rdl.mu.Lock()
-	defer rdl.mu.Unlock()
-
-	for i, subscriber := range rdl.subscribers {
-		if subscriber == ch {
-			rdl.subscribers = append(rdl.subscribers[:i], rdl.subscribers[i+1:]...)
-			close(ch)
-			return
-		}
-	}
+// Subscribe inscreve um novo leitor dos eventos, até Close na inscrição
+func (rdl *RealtimeDataLayer) Subscribe() *bus.Subscription[Event] {
+	return bus.Subscribe(rdl.Bus, TopicEvents)
 }
 
 // EmitEvent emite um evento para todos os assinantes
 func (rdl *RealtimeDataLayer) EmitEvent(eventType EventType, message string, data interface{}) {
-	event := Event{
+	bus.Publish(rdl.Bus, TopicEvents, Event{
 		Type:    eventType,
 		Message: message,
 		Data:    data,
-	}
-
-	// Enviar ao canal de processamento
-	rdl.eventChan <- event
-}
-
-// processEvents processa eventos e os distribui para assinantes
-func (rdl *RealtimeDataLayer) processEvents() {
-	for event := range rdl.eventChan {
-		rdl.mu.Lock()
-		subscribers := make([]chan Event, len(rdl.subscribers))
-		copy(subscribers, rdl.subscribers)
-		rdl.mu.Unlock()
-
-		// Distribuir para todos os assinantes
-		for _, subscriber := range subscribers {
-			// Envio não bloqueante
-			select {
-			case subscriber <- event:
-				// Evento enviado com sucesso
-			default:
-				// Canal cheio, ignorar
-			}
-		}
-	}
+	})
 }
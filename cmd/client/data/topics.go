@@ -0,0 +1,45 @@
+package data
+
+import "github.com/itxtoledo/govpn/cmd/client/bus"
+
+// Tópicos do Bus da camada de dados. Os de estado guardam o último valor, que os getters da
+// RealtimeDataLayer retornam; TopicEvents leva os eventos de EmitEvent.
+var (
+	TopicConnectionState = bus.NewState[ConnectionState]("connection_state")
+	TopicStatusMessage   = bus.NewState[string]("status_message")
+	TopicComputerName    = bus.NewState[string]("computer_name")
+	TopicComputerIP      = bus.NewState[string]("computer_ip")
+	TopicPublicIP        = bus.NewState[string]("public_ip") // Endereço público visto pelo servidor
+	TopicNatType         = bus.NewState[string]("nat_type")  // Tipo de NAT detectado com a sonda UDP do servidor
+	TopicServerAddress   = bus.NewState[string]("server_address")
+	TopicLanguage        = bus.NewState[string]("language")
+	TopicPublicKey       = bus.NewState[string]("public_key")
+	TopicNetworkName     = bus.NewState[string]("network_name") // ID da rede conectada, ou "Not connected"
+	TopicNetworks        = bus.NewState[[]Network]("networks")
+	TopicLatency         = bus.NewState[float64]("latency")   // Média do RTT dos computadores da rede atual, em ms
+	TopicPeerSessions    = bus.NewState[int]("peer_sessions") // Computadores da rede atual com a conexão WebRTC estabelecida
+	TopicTraffic         = bus.NewState[Traffic]("traffic")
+
+	TopicEvents  = bus.NewEvent[Event]("events")
+	TopicRefresh = bus.NewEvent[Refresh]("refresh")
+)
+
+// Refresh é um pedido do núcleo para a interface redesenhar uma parte, publicado em
+// TopicRefresh; sem interface gráfica, ninguém o lê
+type Refresh int
+
+const (
+	// RefreshUI redesenha o estado da conexão e a janela principal
+	RefreshUI Refresh = iota
+	// RefreshNetworkList redesenha a lista de redes e a janela de membros aberta
+	RefreshNetworkList
+)
+
+// Traffic são os totais da sessão na rede atual e a vazão da última amostra, em bytes e
+// bytes por segundo
+type Traffic struct {
+	Sent        uint64
+	Received    uint64
+	SendRate    float64
+	ReceiveRate float64
+}
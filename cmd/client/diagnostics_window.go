@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+)
+
+// DiagnosticsWindow runs the connection doctor: it tests the signaling server, the STUN and
+// TURN servers, the NAT type and a local data channel, and copies the report for an issue
+type DiagnosticsWindow struct {
+	ui.BaseWindow
+	UI *UIManager
+
+	checksBox  *fyne.Container
+	summary    *widget.Label
+	progress   *widget.ProgressBarInfinite
+	runButton  *widget.Button
+	copyButton *widget.Button
+	report     core.DiagnosticReport
+	running    bool
+}
+
+var globalDiagnosticsWindow *DiagnosticsWindow
+
+// NewDiagnosticsWindow creates the connection doctor window
+func NewDiagnosticsWindow(uiManager *UIManager) *DiagnosticsWindow {
+	dw := &DiagnosticsWindow{UI: uiManager}
+	dw.BaseWindow = *ui.NewBaseWindow(uiManager.App, "Connection Doctor", 480, 420)
+	dw.BaseWindow.Window.SetOnClosed(func() {
+		globalDiagnosticsWindow = nil
+	})
+	dw.setupUI()
+	return dw
+}
+
+// setupUI initializes the UI components of the diagnostics window
+func (dw *DiagnosticsWindow) setupUI() {
+	intro := widget.NewLabelWithStyle(
+		"Tests the connection to the server, the STUN and TURN servers from Settings, the NAT in front of this computer and WebRTC itself.",
+		fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+	intro.Wrapping = fyne.TextWrapWord
+	dw.checksBox = container.NewVBox(intro)
+	dw.summary = widget.NewLabel("")
+	dw.progress = widget.NewProgressBarInfinite()
+	dw.progress.Hide()
+
+	dw.runButton = widget.NewButtonWithIcon("Run", theme.MediaPlayIcon(), dw.run)
+	dw.runButton.Importance = widget.HighImportance
+	dw.copyButton = widget.NewButtonWithIcon("Copy report", theme.ContentCopyIcon(), func() {
+		copyToClipboard(dw.report.Text(), "Diagnostics report")
+	})
+	dw.copyButton.Disable()
+
+	content := container.NewBorder(
+		nil,
+		container.NewVBox(dw.progress, dw.summary, container.NewHBox(dw.runButton, dw.copyButton)),
+		nil,
+		nil,
+		container.NewVScroll(dw.checksBox),
+	)
+	dw.BaseWindow.Window.SetContent(container.NewPadded(content))
+}
+
+// run starts the checks off the Fyne thread and adds each result as it finishes
+func (dw *DiagnosticsWindow) run() {
+	if dw.running {
+		return
+	}
+	dw.running = true
+	dw.runButton.Disable()
+	dw.copyButton.Disable()
+	dw.checksBox.RemoveAll()
+	dw.summary.SetText("Running…")
+	dw.progress.Show()
+
+	crash.Go("diagnostics", func() {
+		report := dw.UI.VPN.RunDiagnostics(AppVersion, dw.UI.defaultWebsocketURL, func(check core.DiagnosticCheck) {
+			fyne.Do(func() {
+				dw.checksBox.Add(checkRow(check))
+			})
+		})
+		fyne.Do(func() {
+			dw.report = report
+			dw.running = false
+			dw.progress.Hide()
+			dw.runButton.Enable()
+			dw.copyButton.Enable()
+			if failed := report.Failed(); failed > 0 {
+				dw.summary.SetText(fmt.Sprintf("%d of %d checks failed. Copy the report to attach it to an issue.", failed, len(report.Checks)))
+			} else {
+				dw.summary.SetText("All checks passed.")
+			}
+		})
+	})
+}
+
+// checkRow builds the row of one check: the result icon, the name and what was found
+func checkRow(check core.DiagnosticCheck) fyne.CanvasObject {
+	resource := theme.ConfirmIcon()
+	switch check.Status {
+	case core.DiagnosticFail:
+		resource = theme.ErrorIcon()
+	case core.DiagnosticWarn:
+		resource = theme.WarningIcon()
+	case core.DiagnosticSkip:
+		resource = theme.InfoIcon()
+	}
+
+	nameLabel := widget.NewLabelWithStyle(check.Name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	nameLabel.Truncation = fyne.TextTruncateEllipsis
+	detailLabel := widget.NewLabel(check.Detail)
+	detailLabel.Wrapping = fyne.TextWrapWord
+	lines := container.NewVBox(nameLabel, detailLabel)
+	if check.Hint != "" && check.Status != core.DiagnosticPass {
+		hintLabel := widget.NewLabelWithStyle(check.Hint, fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+		hintLabel.Wrapping = fyne.TextWrapWord
+		lines.Add(hintLabel)
+	}
+	return container.NewBorder(nil, nil, container.NewVBox(widget.NewIcon(resource)), nil, lines)
+}
+
+// OpenDiagnosticsWindow creates and shows the connection doctor, which starts right away
+func (ui *UIManager) OpenDiagnosticsWindow() {
+	if globalDiagnosticsWindow != nil && globalDiagnosticsWindow.BaseWindow.Window != nil {
+		globalDiagnosticsWindow.BaseWindow.Window.RequestFocus()
+		return
+	}
+
+	globalDiagnosticsWindow = NewDiagnosticsWindow(ui)
+	globalDiagnosticsWindow.BaseWindow.Show()
+	globalDiagnosticsWindow.run()
+}
@@ -0,0 +1,57 @@
+package dialogs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ShowBandwidthLimitDialog pede o limite de envio em Mbit/s, que chega a setLimit em kbit/s.
+// Vazio ou zero remove o limite.
+func ShowBandwidthLimitDialog(title, hint string, kbps int, setLimit func(kbps int) error, window fyne.Window) {
+	limitEntry := widget.NewEntry()
+	limitEntry.PlaceHolder = "No limit"
+	if kbps > 0 {
+		limitEntry.SetText(strconv.FormatFloat(float64(kbps)/1000, 'f', -1, 64))
+	}
+	limitEntry.Validator = func(text string) error {
+		_, err := parseMbps(text)
+		return err
+	}
+
+	items := []*widget.FormItem{
+		{Text: "Upload (Mbit/s)", Widget: limitEntry, HintText: hint},
+	}
+
+	dialog.ShowForm(title, "Save", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		kbps, _ := parseMbps(limitEntry.Text)
+		if err := setLimit(kbps); err != nil {
+			dialog.ShowError(err, window)
+		}
+	}, window)
+}
+
+// parseMbps lê um limite em Mbit/s, como "2.5", e retorna em kbit/s
+func parseMbps(text string) (int, error) {
+	text = strings.TrimSpace(strings.ReplaceAll(text, ",", "."))
+	if text == "" {
+		return 0, nil
+	}
+	mbps, err := strconv.ParseFloat(text, 64)
+	if err != nil || mbps < 0 || mbps > 1e6 || math.IsNaN(mbps) {
+		return 0, fmt.Errorf("%q is not a number of Mbit/s", text)
+	}
+	if mbps > 0 && mbps < 0.064 {
+		return 0, errors.New("the lowest limit is 0.064 Mbit/s")
+	}
+	return int(math.Round(mbps * 1000)), nil
+}
@@ -0,0 +1,50 @@
+package dialogs
+
+import (
+	"errors"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+)
+
+// ShowChangePINDialog pede um novo PIN para a sala e o envia com changePIN.
+// Membros atuais continuam na sala, só quem entrar depois precisa do PIN novo.
+func ShowChangePINDialog(networkName string, changePIN func(pin string) error, window fyne.Window) {
+	pinEntry := widget.NewPasswordEntry()
+	pinEntry.PlaceHolder = "New 4-digit PIN"
+	ui.ConfigurePINEntry(pinEntry)
+
+	confirmPINEntry := widget.NewPasswordEntry()
+	confirmPINEntry.PlaceHolder = "Repeat new PIN"
+	ui.ConfigurePINEntry(confirmPINEntry)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("New PIN", pinEntry),
+		widget.NewFormItem("Repeat PIN", confirmPINEntry),
+		widget.NewFormItem("", widget.NewLabel("Members stay connected.\nNew computers must use the new PIN.")),
+	}
+
+	dialog.ShowForm("Change PIN of "+networkName, "Change", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		if pinEntry.Text != confirmPINEntry.Text {
+			dialog.ShowError(errors.New("PINs do not match"), window)
+			return
+		}
+
+		crash.Go("change PIN", func() {
+			err := changePIN(pinEntry.Text)
+			fyne.Do(func() {
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				dialog.ShowInformation("PIN Changed", "The PIN of "+networkName+" was changed.", window)
+			})
+		})
+	}, window)
+}
@@ -0,0 +1,80 @@
+package dialogs
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+	"github.com/itxtoledo/govpn/libs/utils"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// ShowInviteDialog mostra o link de convite da sala, com QR code, para o dono compartilhar.
+// O PIN só entra no link quando o dono marca a opção e o digita, já que o cliente não o guarda.
+func ShowInviteDialog(networkID, networkName, server string, window fyne.Window) {
+	linkEntry := widget.NewEntry()
+	linkEntry.MultiLine = true
+	linkEntry.Wrapping = fyne.TextWrapBreak
+	linkEntry.Disable()
+
+	qrImage := canvas.NewImageFromImage(nil)
+	qrImage.FillMode = canvas.ImageFillContain
+	qrImage.ScaleMode = canvas.ImageScalePixels
+	qrImage.SetMinSize(fyne.NewSize(200, 200))
+
+	pinEntry := widget.NewPasswordEntry()
+	pinEntry.PlaceHolder = "4-digit PIN"
+	ui.ConfigurePINEntry(pinEntry)
+	pinEntry.Hide()
+
+	includePIN := widget.NewCheck("Include the PIN", nil)
+
+	update := func() {
+		invite := core.Invite{NetworkID: networkID, Server: server}
+		if includePIN.Checked && utils.ValidatePIN(pinEntry.Text) {
+			invite.PIN = pinEntry.Text
+		}
+		link := invite.URL()
+		linkEntry.SetText(link)
+		if code, err := qrcode.New(link, qrcode.Medium); err == nil {
+			qrImage.Image = code.Image(256)
+			qrImage.Refresh()
+		}
+	}
+	includePIN.OnChanged = func(checked bool) {
+		if checked {
+			pinEntry.Show()
+		} else {
+			pinEntry.Hide()
+		}
+		update()
+	}
+	pinEntry.OnChanged = func(string) {
+		update()
+	}
+	update()
+
+	copyButton := widget.NewButtonWithIcon("Copy link", theme.ContentCopyIcon(), func() {
+		fyne.CurrentApp().Clipboard().SetContent(linkEntry.Text)
+	})
+
+	hint := widget.NewLabel("Anyone with this link can ask to join. With the PIN in it, the link alone is enough.")
+	hint.Wrapping = fyne.TextWrapWord
+
+	content := container.NewVBox(
+		hint,
+		container.NewCenter(qrImage),
+		linkEntry,
+		includePIN,
+		pinEntry,
+		container.NewCenter(copyButton),
+	)
+
+	d := dialog.NewCustom("Invite to "+networkName, "Close", content, window)
+	d.Resize(fyne.NewSize(320, 0))
+	d.Show()
+}
@@ -0,0 +1,84 @@
+package dialogs
+
+import (
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+)
+
+// ShowQuickSwitchDialog lista as redes para trocar de rede só pelo teclado: o texto digitado
+// filtra pelo nome ou ID, Enter conecta à primeira da lista e Tab leva à lista, onde as setas
+// escolhem e Espaço conecta. A rede conectada aparece marcada e escolhê-la a desconecta.
+func ShowQuickSwitchDialog(networks []data.Network, currentNetworkID string, connect func(networkID string), window fyne.Window) {
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].NetworkName < networks[j].NetworkName
+	})
+	filtered := networks
+
+	var d dialog.Dialog
+	choose := func(network data.Network) {
+		d.Hide()
+		connect(network.NetworkID)
+	}
+
+	list := widget.NewList(
+		func() int {
+			return len(filtered)
+		},
+		func() fyne.CanvasObject {
+			return container.NewHBox(widget.NewIcon(theme.RadioButtonIcon()), widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			row := item.(*fyne.Container)
+			network := filtered[id]
+			icon := theme.RadioButtonIcon()
+			if network.NetworkID == currentNetworkID {
+				icon = theme.RadioButtonCheckedIcon()
+			}
+			row.Objects[0].(*widget.Icon).SetResource(icon)
+			row.Objects[1].(*widget.Label).SetText(network.NetworkName)
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		if id < len(filtered) {
+			choose(filtered[id])
+		}
+	}
+
+	filterEntry := widget.NewEntry()
+	filterEntry.PlaceHolder = "Type a network name or ID"
+	filterEntry.OnChanged = func(text string) {
+		text = strings.ToLower(strings.TrimSpace(text))
+		filtered = nil
+		for _, network := range networks {
+			if strings.Contains(strings.ToLower(network.NetworkName), text) || strings.HasPrefix(network.NetworkID, text) {
+				filtered = append(filtered, network)
+			}
+		}
+		list.UnselectAll()
+		list.Refresh()
+	}
+	filterEntry.OnSubmitted = func(string) {
+		if len(filtered) > 0 {
+			choose(filtered[0])
+		}
+	}
+
+	hint := widget.NewLabel("Enter connects to the first network, Tab moves to the list.")
+	hint.Wrapping = fyne.TextWrapWord
+	if len(networks) == 0 {
+		hint.SetText("This computer has not joined any network yet.")
+	}
+
+	content := container.NewBorder(container.NewVBox(filterEntry, hint), nil, nil, nil, list)
+	d = dialog.NewCustom("Switch Network", "Cancel", content, window)
+	d.Resize(fyne.NewSize(300, 360))
+	d.Show()
+	window.Canvas().Focus(filterEntry)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/dialogs"
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// transferRefreshInterval is how often the transfers window redraws the progress
+const transferRefreshInterval = time.Second
+
+// FileTransfersWindow lists the files sent and received in the current network with their progress
+type FileTransfersWindow struct {
+	ui.BaseWindow
+	UI *UIManager
+
+	transfersBox *fyne.Container
+	done         chan struct{}
+}
+
+var globalFileTransfersWindow *FileTransfersWindow
+
+// NewFileTransfersWindow creates the transfers window
+func NewFileTransfersWindow(uiManager *UIManager) *FileTransfersWindow {
+	tw := &FileTransfersWindow{
+		UI:   uiManager,
+		done: make(chan struct{}),
+	}
+	tw.BaseWindow = *ui.NewBaseWindow(uiManager.App, "File Transfers", 420, 360)
+	tw.BaseWindow.Window.SetOnClosed(func() {
+		close(tw.done)
+		globalFileTransfersWindow = nil
+	})
+	tw.setupUI()
+	return tw
+}
+
+// setupUI initializes the UI components of the transfers window
+func (tw *FileTransfersWindow) setupUI() {
+	tw.transfersBox = container.NewVBox()
+
+	clearButton := widget.NewButton("Clear finished", func() {
+		if nm := tw.UI.VPN.NetworkManager; nm != nil {
+			nm.ClearFinishedTransfers()
+		}
+		tw.Refresh()
+	})
+	folderButton := widget.NewButtonWithIcon("Open downloads", theme.FolderOpenIcon(), func() {
+		if nm := tw.UI.VPN.NetworkManager; nm != nil {
+			openFolder(nm.DownloadFolder())
+		}
+	})
+
+	content := container.NewBorder(
+		nil,
+		container.NewHBox(clearButton, folderButton),
+		nil,
+		nil,
+		container.NewVScroll(tw.transfersBox),
+	)
+	tw.BaseWindow.Window.SetContent(container.NewPadded(content))
+}
+
+// follow redraws the window every transferRefreshInterval until it closes
+func (tw *FileTransfersWindow) follow() {
+	ticker := time.NewTicker(transferRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tw.done:
+			return
+		case <-ticker.C:
+			fyne.Do(tw.Refresh)
+		}
+	}
+}
+
+// Refresh rebuilds the list from the transfers of the network manager. Must run on the Fyne thread.
+func (tw *FileTransfersWindow) Refresh() {
+	if tw.BaseWindow.Window == nil {
+		return
+	}
+
+	var transfers []network.Transfer
+	if nm := tw.UI.VPN.NetworkManager; nm != nil {
+		transfers = nm.FileTransfers()
+	}
+
+	tw.transfersBox.RemoveAll()
+	// Newest first
+	for i := len(transfers) - 1; i >= 0; i-- {
+		tw.transfersBox.Add(tw.transferRow(transfers[i]))
+	}
+	if len(transfers) == 0 {
+		tw.transfersBox.Add(widget.NewLabelWithStyle("No file transfers yet. Right-click a computer of the connected network to send it a file.", fyne.TextAlignCenter, fyne.TextStyle{Italic: true}))
+	}
+	tw.transfersBox.Refresh()
+}
+
+// transferRow builds the row of one transfer: direction, name, progress and the cancel button
+func (tw *FileTransfersWindow) transferRow(transfer network.Transfer) fyne.CanvasObject {
+	peerName := tw.UI.peerName(transfer.Peer)
+	direction := theme.UploadIcon()
+	title := fmt.Sprintf("%s to %s", transfer.Name, peerName)
+	if !transfer.Sending {
+		direction = theme.DownloadIcon()
+		title = fmt.Sprintf("%s from %s", transfer.Name, peerName)
+	}
+
+	nameLabel := widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	nameLabel.Truncation = fyne.TextTruncateEllipsis
+
+	status := fmt.Sprintf("%s · %s of %s", transfer.State, formatBytes(transfer.Bytes), formatBytes(transfer.Size))
+	switch {
+	case transfer.State == network.TransferFailed:
+		status = "Failed: " + transfer.Error
+	case transfer.State == network.TransferDone && !transfer.Sending:
+		status = "Saved as " + transfer.Path
+	case transfer.State == network.TransferOffered && transfer.Offset > 0:
+		status += " (resuming)"
+	}
+	statusLabel := widget.NewLabel(status)
+	statusLabel.Truncation = fyne.TextTruncateEllipsis
+
+	progress := widget.NewProgressBar()
+	if transfer.Size > 0 {
+		progress.SetValue(float64(transfer.Bytes) / float64(transfer.Size))
+	} else if transfer.State == network.TransferDone {
+		progress.SetValue(1)
+	}
+
+	var action fyne.CanvasObject = widget.NewLabel("")
+	if !transfer.State.Finished() {
+		action = widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+			if nm := tw.UI.VPN.NetworkManager; nm != nil {
+				crash.Go("cancel transfer", func() { nm.CancelTransfer(transfer) })
+			}
+		})
+	} else if transfer.State == network.TransferDone && !transfer.Sending {
+		action = widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+			openFolder(filepath.Dir(transfer.Path))
+		})
+	}
+
+	return container.NewBorder(nil, nil, widget.NewIcon(direction), action,
+		container.NewVBox(nameLabel, progress, statusLabel))
+}
+
+// Show shows the transfers window and keeps the progress up to date while it is open
+func (tw *FileTransfersWindow) Show() {
+	tw.Refresh()
+	tw.BaseWindow.Show()
+	crash.Go("transfers window", tw.follow)
+}
+
+// OpenFileTransfersWindow creates and shows the transfers window
+func (ui *UIManager) OpenFileTransfersWindow() {
+	if globalFileTransfersWindow != nil && globalFileTransfersWindow.BaseWindow.Window != nil {
+		globalFileTransfersWindow.BaseWindow.Window.RequestFocus()
+		return
+	}
+
+	globalFileTransfersWindow = NewFileTransfersWindow(ui)
+	globalFileTransfersWindow.Show()
+}
+
+// sendFileItem returns the context menu entry that sends a file to a computer of the
+// connected network, or nil when it cannot receive one
+func (ui *UIManager) sendFileItem(computer smodels.ComputerInfo) *fyne.MenuItem {
+	nm := ui.VPN.NetworkManager
+	if nm == nil || nm.NetworkID == "" || !computer.IsOnline || computer.PublicKey == ui.VPN.PublicKeyStr {
+		return nil
+	}
+
+	return fyne.NewMenuItem("Send file…", func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialogs.ShowError(err, ui.MainWindow)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			path := reader.URI().Path()
+			reader.Close()
+
+			if _, err := nm.SendFile(computer.PublicKey, path); err != nil {
+				logger.Warn("Failed to send file", "peer", computer.PublicKey, "error", err)
+				dialogs.ShowError(err, ui.MainWindow)
+				return
+			}
+			ui.OpenFileTransfersWindow()
+		}, ui.MainWindow)
+	})
+}
+
+// showFileOffer asks whether to accept a file a computer of the connected network offers
+func (ui *UIManager) showFileOffer(transfer network.Transfer) {
+	message := fmt.Sprintf("%s wants to send you %s (%s).", ui.peerName(transfer.Peer), transfer.Name, formatBytes(transfer.Size))
+	if transfer.Offset > 0 {
+		message += fmt.Sprintf("\n\n%s of it arrived before, the rest continues from there.", formatBytes(transfer.Offset))
+	}
+	message += "\n\nIt will be saved to " + ui.VPN.NetworkManager.DownloadFolder() + "."
+
+	fyne.Do(func() {
+		dialog.ShowCustomConfirm("Incoming File", "Accept", "Decline", widget.NewLabel(message), func(accept bool) {
+			nm := ui.VPN.NetworkManager
+			if !accept {
+				crash.Go("decline file", func() { nm.DeclineFile(transfer.Peer, transfer.ID) })
+				return
+			}
+			ui.OpenFileTransfersWindow()
+			crash.Go("accept file", func() {
+				if err := nm.AcceptFile(transfer.Peer, transfer.ID); err != nil && !errors.Is(err, network.ErrTransfersClosed) {
+					logger.Warn("Failed to accept file", "peer", transfer.Peer, "error", err)
+					fyne.Do(func() {
+						dialogs.ShowError(err, ui.MainWindow)
+					})
+				}
+			})
+		}, ui.MainWindow)
+	})
+}
+
+// peerName returns the name of a computer of the connected network
+func (ui *UIManager) peerName(publicKey string) string {
+	if nm := ui.VPN.NetworkManager; nm != nil {
+		for _, joined := range ui.RealtimeData.GetNetworks() {
+			if joined.NetworkID == nm.NetworkID {
+				return memberName(joined, publicKey)
+			}
+		}
+	}
+	return publicKey[:min(8, len(publicKey))] + "…"
+}
+
+// openFolder shows a folder in the file manager of the system
+func openFolder(dir string) {
+	folderURL, err := url.Parse(storage.NewFileURI(dir).String())
+	if err == nil {
+		err = fyne.CurrentApp().OpenURL(folderURL)
+	}
+	if err != nil {
+		logger.Warn("Failed to open folder", "path", dir, "error", err)
+	}
+}
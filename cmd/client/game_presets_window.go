@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/cmd/client/dialogs"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+)
+
+// thisComputerHost is the host option of a preset whose games run on this computer
+const thisComputerHost = "This computer"
+
+// GamePresetsWindow enables game presets on a network: each one shares the ports of the game,
+// relays LAN broadcasts when the game finds matches that way and, with another computer as
+// host, forwards the ports to it when there is no virtual network interface
+type GamePresetsWindow struct {
+	ui.BaseWindow
+	UI        *UIManager
+	networkID string
+}
+
+var globalGamePresetsWindow *GamePresetsWindow
+
+// NewGamePresetsWindow creates the game presets window of a network
+func NewGamePresetsWindow(uiManager *UIManager, network *data.Network) *GamePresetsWindow {
+	gw := &GamePresetsWindow{
+		UI:        uiManager,
+		networkID: network.NetworkID,
+	}
+	gw.BaseWindow = *ui.NewBaseWindow(uiManager.App, "Games in "+network.NetworkName, 480, 480)
+	gw.BaseWindow.Window.SetOnClosed(func() {
+		globalGamePresetsWindow = nil
+	})
+	gw.setupUI(*network)
+	return gw
+}
+
+// setupUI builds one row per preset with its ports and the computer hosting the game
+func (gw *GamePresetsWindow) setupUI(network data.Network) {
+	hosts, hostIPs := gw.hostOptions(network)
+	enabled := gw.UI.ConfigManager.GetConfig().NetworkPresets(gw.networkID)
+
+	rows := container.NewVBox()
+	for _, preset := range core.GamePresets {
+		i := slices.IndexFunc(enabled, func(p core.NetworkPreset) bool { return p.Preset == preset.ID })
+
+		hostSelect := widget.NewSelect(hosts, nil)
+		hostSelect.SetSelected(thisComputerHost)
+		check := widget.NewCheck(preset.Name, nil)
+		if i >= 0 {
+			check.SetChecked(true)
+			if host := enabled[i].Host; host != "" {
+				if !slices.Contains(hostIPs, host) {
+					// The host left the network, its address stays until another is picked
+					hostSelect.Options = append(hostSelect.Options, host)
+					hostIPs = append(hostIPs, host)
+				}
+				hostSelect.SetSelected(hostSelect.Options[slices.Index(hostIPs, host)])
+			}
+		} else {
+			hostSelect.Disable()
+		}
+
+		save := func() {
+			host := ""
+			if index := hostSelect.SelectedIndex(); index > 0 {
+				host = hostIPs[index]
+			}
+			err := gw.UI.ConfigManager.SetNetworkPreset(gw.networkID, core.NetworkPreset{Preset: preset.ID, Host: host}, check.Checked)
+			if err != nil {
+				dialogs.ShowError(fmt.Errorf("failed to save the game presets: %v", err), gw.BaseWindow.Window)
+			}
+		}
+		check.OnChanged = func(checked bool) {
+			if checked {
+				hostSelect.Enable()
+			} else {
+				hostSelect.Disable()
+			}
+			save()
+		}
+		hostSelect.OnChanged = func(string) { save() }
+
+		ports := widget.NewLabelWithStyle(presetDetails(preset), fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+		ports.Truncation = fyne.TextTruncateEllipsis
+		rows.Add(container.NewBorder(nil, nil, nil, hostSelect, container.NewVBox(check, ports)))
+	}
+
+	note := "Enabled games share their ports with the network. Pick the computer hosting the match to reach it through port forwarding when the virtual network interface is unavailable. Changes apply on the next connection to this network."
+	if nm := gw.UI.VPN.NetworkManager; nm != nil && nm.NetworkID == gw.networkID {
+		note += " Disconnect and connect again to apply them now."
+	}
+	noteLabel := widget.NewLabelWithStyle(note, fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+	noteLabel.Wrapping = fyne.TextWrapWord
+
+	content := container.NewBorder(noteLabel, nil, nil, nil, container.NewVScroll(rows))
+	gw.BaseWindow.Window.SetContent(container.NewPadded(content))
+}
+
+// hostOptions lists this computer and the other members of the network as hosts, with the
+// virtual IP of each option at the same index, empty for this computer
+func (gw *GamePresetsWindow) hostOptions(network data.Network) ([]string, []string) {
+	hosts, hostIPs := []string{thisComputerHost}, []string{""}
+	for _, computer := range network.Computers {
+		if computer.PublicKey == gw.UI.VPN.PublicKeyStr || computer.ComputerIP == "" {
+			continue
+		}
+		hosts = append(hosts, fmt.Sprintf("%s (%s)", computer.Name, computer.ComputerIP))
+		hostIPs = append(hostIPs, computer.ComputerIP)
+	}
+	return hosts, hostIPs
+}
+
+// presetDetails describes the ports of a preset and whether it relays broadcasts
+func presetDetails(preset core.GamePreset) string {
+	ports := make([]string, len(preset.Ports))
+	for i, port := range preset.Ports {
+		ports[i] = port.String()
+	}
+	details := strings.Join(ports, ", ")
+	if preset.Broadcast {
+		details += " · LAN discovery"
+	}
+	return details
+}
+
+// OpenGamePresetsWindow creates and shows the game presets window of a network
+func (ui *UIManager) OpenGamePresetsWindow(network *data.Network) {
+	if globalGamePresetsWindow != nil && globalGamePresetsWindow.BaseWindow.Window != nil {
+		if globalGamePresetsWindow.networkID == network.NetworkID {
+			globalGamePresetsWindow.BaseWindow.Window.RequestFocus()
+			return
+		}
+		globalGamePresetsWindow.Close()
+	}
+
+	globalGamePresetsWindow = NewGamePresetsWindow(ui, network)
+	globalGamePresetsWindow.BaseWindow.Show()
+}
@@ -6,14 +6,24 @@ toolchain go1.23.11
 
 require (
 	fyne.io/fyne/v2 v2.6.0
+	github.com/Microsoft/go-winio v0.6.2
+	github.com/godbus/dbus/v5 v5.1.0
+	github.com/itxtoledo/govpn/libs/logger v0.0.0
 	github.com/itxtoledo/govpn/libs/signaling/client v0.0.0
 	github.com/itxtoledo/govpn/libs/signaling/models v0.0.0
 	github.com/pion/webrtc/v4 v4.1.3
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	github.com/songgao/water v0.0.0-20200317203138-2b4b6d7c09d8
+	golang.org/x/crypto v0.40.0
+	golang.org/x/net v0.42.0
+	golang.org/x/sys v0.34.0
+	golang.zx2c4.com/wireguard v0.0.0-20231211153847-12269c276173
 )
 
 replace (
 	github.com/itxtoledo/govpn/cmd/client/webrtc v0.0.0 => ./webrtc
 	github.com/itxtoledo/govpn/libs/crypto_utils v0.0.0 => ../../libs/crypto_utils
+	github.com/itxtoledo/govpn/libs/logger v0.0.0 => ../../libs/logger
 	github.com/itxtoledo/govpn/libs/network v0.0.0 => ../../libs/network
 	github.com/itxtoledo/govpn/libs/signaling/client v0.0.0 => ../../libs/signaling/client
 	github.com/itxtoledo/govpn/libs/signaling/models v0.0.0 => ../../libs/signaling/models
@@ -39,7 +49,6 @@ require (
 	github.com/go-gl/gl v0.0.0-20231021071112-07e5d0ea2e71 // indirect
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20240506104042-037f3cc74f2a // indirect
 	github.com/go-text/typesetting v0.2.1 // indirect
-	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/hack-pad/go-indexeddb v0.3.2 // indirect
@@ -69,10 +78,10 @@ require (
 	github.com/stretchr/testify v1.10.0 // indirect
 	github.com/wlynxg/anet v0.0.5 // indirect
 	github.com/yuin/goldmark v1.7.8 // indirect
-	golang.org/x/crypto v0.40.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	go.uber.org/zap v1.27.0 // indirect
 	golang.org/x/image v0.24.0 // indirect
-	golang.org/x/net v0.42.0 // indirect
-	golang.org/x/sys v0.34.0 // indirect
 	golang.org/x/text v0.27.0 // indirect
+	golang.zx2c4.com/wintun v0.0.0-20230126152724-0fa3db229ce2 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -3,7 +3,6 @@ package main
 
 import (
 	"fmt"
-	"log"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -11,8 +10,13 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
 	"github.com/itxtoledo/govpn/cmd/client/data"
 	"github.com/itxtoledo/govpn/cmd/client/icon"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
 )
 
 // HeaderComponent representa o componente de cabeçalho da aplicação
@@ -38,7 +42,7 @@ func NewHeaderComponent(ui *UIManager, defaultWebsocketURL string) *HeaderCompon
 	})
 	hc.PowerButton.Importance = widget.HighImportance // Make power button more prominent
 
-	hc.NetworkLabel = widget.NewLabelWithData(hc.UI.RealtimeData.NetworkName)
+	hc.NetworkLabel = widget.NewLabelWithData(hc.UI.Bindings.NetworkName)
 
 	// New Settings Button
 	hc.SettingsButton = widget.NewButtonWithIcon("", theme.SettingsIcon(), func() {
@@ -55,7 +59,7 @@ func NewHeaderComponent(ui *UIManager, defaultWebsocketURL string) *HeaderCompon
 // configureListeners configura os listeners para os bindings
 func (hc *HeaderComponent) configureListeners() {
 	// Listener para o estado da conexão
-	hc.UI.RealtimeData.ConnectionState.AddListener(binding.NewDataListener(func() {
+	hc.UI.Bindings.ConnectionState.AddListener(binding.NewDataListener(func() {
 
 		hc.updatePowerButtonState()
 	}))
@@ -66,40 +70,52 @@ func (hc *HeaderComponent) CreateHeaderContainer() *fyne.Container {
 
 	// Container para informações do usuário (IP e nome) - layout compacto
 	combinedInfoBinding := binding.NewString()
-	hc.UI.RealtimeData.ComputerIP.AddListener(binding.NewDataListener(func() {
-		ip, _ := hc.UI.RealtimeData.ComputerIP.Get()
-		name, _ := hc.UI.RealtimeData.ComputerName.Get()
+	hc.UI.Bindings.ComputerIP.AddListener(binding.NewDataListener(func() {
+		ip := hc.UI.RealtimeData.GetComputerIP()
+		name := hc.UI.RealtimeData.GetComputerName()
 		combinedInfoBinding.Set(fmt.Sprintf("%s\n%s", ip, name))
 	}))
-	hc.UI.RealtimeData.ComputerName.AddListener(binding.NewDataListener(func() {
-		ip, _ := hc.UI.RealtimeData.ComputerIP.Get()
-		name, _ := hc.UI.RealtimeData.ComputerName.Get()
+	hc.UI.Bindings.ComputerName.AddListener(binding.NewDataListener(func() {
+		ip := hc.UI.RealtimeData.GetComputerIP()
+		name := hc.UI.RealtimeData.GetComputerName()
 		combinedInfoBinding.Set(fmt.Sprintf("%s\n%s", ip, name))
 	}))
 
 	// Initialize the combined binding with current values
-	ip, _ := hc.UI.RealtimeData.ComputerIP.Get()
-	name, _ := hc.UI.RealtimeData.ComputerName.Get()
+	ip := hc.UI.RealtimeData.GetComputerIP()
+	name := hc.UI.RealtimeData.GetComputerName()
 	combinedInfoBinding.Set(fmt.Sprintf("%s\n%s", ip, name))
 
 	combinedInfoLabel := widget.NewLabelWithData(combinedInfoBinding)
 	combinedInfoLabel.TextStyle = fyne.TextStyle{Monospace: true, Bold: true}
 
+	// Endereço público visto pelo servidor e tipo de NAT, vazios enquanto desconectado
+	publicIPBinding := binding.NewString()
+	updatePublicIP := func() {
+		publicIP := hc.UI.RealtimeData.GetPublicIP()
+		natType := hc.UI.RealtimeData.GetNatType()
+		switch {
+		case publicIP == "":
+			publicIPBinding.Set("")
+		case natType == "":
+			publicIPBinding.Set("Public: " + publicIP)
+		default:
+			publicIPBinding.Set(fmt.Sprintf("Public: %s (NAT: %s)", publicIP, core.NATTypeLabel(smodels.NatType(natType))))
+		}
+	}
+	hc.UI.Bindings.PublicIP.AddListener(binding.NewDataListener(updatePublicIP))
+	hc.UI.Bindings.NatType.AddListener(binding.NewDataListener(updatePublicIP))
+	publicIPLabel := widget.NewLabelWithData(publicIPBinding)
+	publicIPLabel.TextStyle = fyne.TextStyle{Monospace: true}
+
 	computerInfoContainer := container.NewVBox(
 		combinedInfoLabel,
+		publicIPLabel,
 	)
 
-	// Container para o power button com tamanho fixo para garantir proporção 1:1
-	powerButtonContainer := container.NewWithoutLayout(hc.PowerButton)
-	powerButtonContainer.Resize(fyne.NewSize(44, 44))
-	hc.PowerButton.Move(fyne.NewPos(0, 0))
-	hc.PowerButton.Resize(fyne.NewSize(44, 44))
-
-	// Container para o settings button com tamanho fixo para garantir proporção 1:1
-	settingsButtonContainer := container.NewWithoutLayout(hc.SettingsButton)
-	settingsButtonContainer.Resize(fyne.NewSize(44, 44))
-	hc.SettingsButton.Move(fyne.NewPos(0, 0))
-	hc.SettingsButton.Resize(fyne.NewSize(44, 44))
+	// Botões só com ícone num quadrado de 44 pontos, que cresce com a escala da interface
+	powerButtonContainer := ui.NewSquare(44, hc.PowerButton)
+	settingsButtonContainer := ui.NewSquare(44, hc.SettingsButton)
 
 	// Container superior usando HBox com três colunas
 	topContainer := container.NewHBox(
@@ -120,33 +136,31 @@ func (hc *HeaderComponent) CreateHeaderContainer() *fyne.Container {
 
 // toggleConnection alterna o estado da conexão
 func (hc *HeaderComponent) toggleConnection() {
-	state, _ := hc.UI.RealtimeData.ConnectionState.Get()
-	connectionState := data.ConnectionState(state)
+	connectionState := hc.UI.RealtimeData.GetConnectionState()
 
 	if connectionState == data.StateDisconnected {
 		// Conectar
-		go func() {
-			log.Println("Connecting to VPN network...")
-			hc.UI.VPN.Run(hc.defaultWebsocketURL, hc.UI.RealtimeData, hc.UI.refreshNetworkList, hc.UI.refreshUI)
-		}()
+		crash.Go("connect button", func() {
+			logger.Debug("Connect button clicked")
+			hc.UI.VPN.Run(hc.defaultWebsocketURL, hc.UI.RealtimeData)
+		})
 	} else {
 		// Desconectar
-		go func() {
-			log.Println("Disconnecting from VPN network...")
+		crash.Go("disconnect button", func() {
+			logger.Debug("Disconnect button clicked")
 			if hc.UI.VPN.NetworkManager != nil {
 				err := hc.UI.VPN.NetworkManager.Disconnect()
 				if err != nil {
-					log.Printf("Error disconnecting: %v", err)
+					logger.Warn("Error disconnecting", "error", err)
 				}
 			}
-		}()
+		})
 	}
 }
 
 // updatePowerButtonState atualiza o estado do botão de energia
 func (hc *HeaderComponent) updatePowerButtonState() {
-	state, _ := hc.UI.RealtimeData.ConnectionState.Get()
-	connectionState := data.ConnectionState(state)
+	connectionState := hc.UI.RealtimeData.GetConnectionState()
 
 	// Atualizar ícone do botão
 	hc.PowerButton.SetIcon(icon.Power)
@@ -0,0 +1,53 @@
+// Command govpn-helper cria e mantém a interface TUN do GoVPN com privilégios de
+// administrador, para que a interface gráfica rode como um usuário comum.
+// Instale como serviço do sistema com os arquivos de dist/.
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+func main() {
+	logger.Init()
+
+	if runningAsService() {
+		if err := runService(); err != nil {
+			logger.Fatal("Helper service failed", "error", err)
+		}
+		return
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		<-stop
+		close(done)
+	}()
+
+	if err := serve(done); err != nil {
+		logger.Fatal("Helper failed", "error", err)
+	}
+}
+
+// serve atende a interface gráfica até done fechar
+func serve(done <-chan struct{}) error {
+	ln, err := network.ListenHelper()
+	if err != nil {
+		return err
+	}
+	logger.Info("GoVPN helper listening", "address", ln.Addr())
+
+	go func() {
+		<-done
+		ln.Close()
+	}()
+
+	return network.ServeHelper(ln)
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+// No Linux e no macOS o systemd e o launchd rodam o helper como um processo comum
+func runningAsService() bool {
+	return false
+}
+
+func runService() error {
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/itxtoledo/govpn/libs/logger"
+	"golang.org/x/sys/windows/svc"
+)
+
+// serviceName é o nome usado ao registrar o helper com sc.exe
+const serviceName = "GoVPNHelper"
+
+func runningAsService() bool {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		logger.Warn("Could not tell whether running as a service", "error", err)
+		return false
+	}
+	return isService
+}
+
+func runService() error {
+	return svc.Run(serviceName, helperService{})
+}
+
+// helperService atende o gerenciador de serviços do Windows
+type helperService struct{}
+
+func (helperService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	done := make(chan struct{})
+	failed := make(chan error, 1)
+	go func() {
+		failed <- serve(done)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case err := <-failed:
+			logger.Error("Helper stopped", "error", err)
+			return false, 1
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				close(done)
+				<-failed
+				return false, 0
+			}
+		}
+	}
+}
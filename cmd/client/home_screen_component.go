@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"log"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -10,13 +9,15 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
 	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/libs/logger"
 	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
 )
 
 // NetworkManagerAdapter adapts NetworkManager to implement dialogs.NetworkManagerInterface
 type NetworkManagerAdapter struct {
-	*NetworkManager
+	*core.NetworkManager
 }
 
 // CreateNetwork adapts the NetworkManager CreateNetwork method to match the interface
@@ -64,14 +65,14 @@ type HomeScreenComponent struct {
 	NetworksContainer *fyne.Container
 
 	// Dependencies
-	ConfigManager   *ConfigManager
+	ConfigManager   *core.ConfigManager
 	RealtimeData    *data.RealtimeDataLayer
 	NetworkListComp *NetworkListComponent // Add NetworkListComp here
 	UI              *UIManager
 }
 
 // NewHomeScreenComponent cria uma nova instância do componente da tela principal
-func NewHomeScreenComponent(configManager *ConfigManager, realtimeData *data.RealtimeDataLayer, networkListComp *NetworkListComponent, ui *UIManager) *HomeScreenComponent {
+func NewHomeScreenComponent(configManager *core.ConfigManager, realtimeData *data.RealtimeDataLayer, networkListComp *NetworkListComponent, ui *UIManager) *HomeScreenComponent {
 	htc := &HomeScreenComponent{
 		ConfigManager:   configManager,
 		RealtimeData:    realtimeData,
@@ -88,87 +89,66 @@ func (htc *HomeScreenComponent) CreateHomeScreenContainer() *fyne.Container {
 	networksContainer := htc.NetworkListComp.GetContainer()
 	htc.NetworksContainer = networksContainer
 
-	// Criar um botão para criar uma nova sala
-	createNetworkButton := widget.NewButtonWithIcon("Create Network", theme.ContentAddIcon(), func() {
-		log.Println("Create network button clicked")
-
-		// Check network connection status
-		isConnected, _ := htc.RealtimeData.IsConnected.Get()
-		if !isConnected {
-			dialog.ShowError(fmt.Errorf("not connected to server"), htc.UI.MainWindow)
-			return
-		}
-
-		// Get computername, handling the multiple return values
-		computername, err := htc.UI.RealtimeData.ComputerName.Get()
-		if err != nil {
-			log.Printf("Error getting computername: %v", err)
-			computername = "Computer" // Default fallback
-		}
-
-		// Create and show the network creation window (singleton pattern)
-		if globalNetworkWindow != nil && globalNetworkWindow.BaseWindow.Window != nil {
-			// Focus on existing window if already open
-			globalNetworkWindow.BaseWindow.Window.RequestFocus()
-			return
-		}
-
-		adapter := &NetworkManagerAdapter{htc.UI.VPN.NetworkManager}
-		globalNetworkWindow = NewNetworkWindow(
-			htc.UI.App,
-			adapter.CreateNetwork,
-			adapter.GetNetworkID,
-			computername,
-			func(networkID, networkName, pin string) {
-				htc.UI.HandleNetworkCreated(networkID, networkName, pin)
-			},
-		)
-		globalNetworkWindow.Show()
-	})
-
-	// Criar um botão para entrar em uma sala
-	joinNetworkButton := widget.NewButtonWithIcon("Join Network", theme.LoginIcon(), func() {
-		log.Println("Join network button clicked")
-
-		// Check network connection status
-		isConnected, _ := htc.RealtimeData.IsConnected.Get()
-		if !isConnected {
-			dialog.ShowError(fmt.Errorf("not connected to server"), htc.UI.MainWindow)
-			return
-		}
-
-		// Get computername, handling the multiple return values
-		computername, err := htc.UI.RealtimeData.ComputerName.Get()
-		if err != nil {
-			log.Printf("Error getting computername: %v", err)
-			computername = "Computer" // Default fallback
-		}
-
-		// Create and show the network joining window (singleton pattern)
-		if globalJoinWindow != nil && globalJoinWindow.BaseWindow.Window != nil {
-			// Focus on existing window if already open
-			globalJoinWindow.BaseWindow.Window.RequestFocus()
-			return
-		}
-
-		adapter := &NetworkManagerAdapter{htc.UI.VPN.NetworkManager}
-		globalJoinWindow = NewJoinWindow(
-			htc.UI.App,
-			adapter.JoinNetwork,
-			computername,
-			func(networkID, pin string) {
-				htc.UI.HandleNetworkJoined(networkID, pin)
-			},
-		)
-		globalJoinWindow.Show()
-	})
+	// Botões para criar e entrar em uma sala, que os atalhos de teclado também acionam
+	createNetworkButton := widget.NewButtonWithIcon("Create Network", theme.ContentAddIcon(), htc.ShowCreateNetwork)
+	joinNetworkButton := widget.NewButtonWithIcon("Join Network", theme.LoginIcon(), htc.ShowJoinNetwork)
 
 	// Criar o container da aba de salas
 	return container.NewBorder(
 		nil,
-		container.NewHBox(layout.NewSpacer(), joinNetworkButton, createNetworkButton),
+		container.NewHBox(htc.UI.ThroughputWidget.Container(), layout.NewSpacer(), joinNetworkButton, createNetworkButton),
 		nil,
 		nil,
 		networksContainer,
 	)
 }
+
+// ShowCreateNetwork abre a janela de criação de sala, ou foca a que já está aberta
+func (htc *HomeScreenComponent) ShowCreateNetwork() {
+	logger.Debug("Create network requested")
+
+	// Check network connection status
+	isConnected := htc.RealtimeData.IsConnected()
+	if !isConnected {
+		dialog.ShowError(fmt.Errorf("not connected to server"), htc.UI.MainWindow)
+		return
+	}
+
+	computername := htc.UI.RealtimeData.GetComputerName()
+	if computername == "" {
+		computername = "Computer" // Default fallback
+	}
+
+	// Create and show the network creation window (singleton pattern)
+	if globalNetworkWindow != nil && globalNetworkWindow.BaseWindow.Window != nil {
+		// Focus on existing window if already open
+		globalNetworkWindow.BaseWindow.Window.RequestFocus()
+		return
+	}
+
+	adapter := &NetworkManagerAdapter{htc.UI.VPN.NetworkManager}
+	globalNetworkWindow = NewNetworkWindow(
+		htc.UI.App,
+		adapter.CreateNetwork,
+		adapter.GetNetworkID,
+		computername,
+		func(networkID, networkName, pin string) {
+			htc.UI.HandleNetworkCreated(networkID, networkName, pin)
+		},
+	)
+	globalNetworkWindow.Show()
+}
+
+// ShowJoinNetwork abre a janela para entrar em uma sala
+func (htc *HomeScreenComponent) ShowJoinNetwork() {
+	logger.Debug("Join network requested")
+
+	// Check network connection status
+	isConnected := htc.RealtimeData.IsConnected()
+	if !isConnected {
+		dialog.ShowError(fmt.Errorf("not connected to server"), htc.UI.MainWindow)
+		return
+	}
+
+	htc.UI.ShowJoinWindow("", "")
+}
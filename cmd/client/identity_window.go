@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"io"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// identityFileName é o nome sugerido ao salvar a identidade exportada
+const identityFileName = "govpn-identity.txt"
+
+// Só uma janela de identidade aberta por vez
+var globalIdentityWindow *IdentityWindow
+
+// IdentityWindow exporta o par de chaves deste computador, protegido por senha, e importa
+// o de outro, para o usuário continuar nas mesmas redes ao trocar de computador
+type IdentityWindow struct {
+	*ui.BaseWindow
+	PublicKeyEntry     *widget.Entry
+	ExportPassEntry    *widget.Entry
+	ExportConfirmEntry *widget.Entry
+	ImportTextEntry    *widget.Entry
+	ImportPassEntry    *widget.Entry
+
+	configManager *core.ConfigManager
+}
+
+// NewIdentityWindow cria a janela de identidade, ou devolve a que já está aberta
+func NewIdentityWindow(app fyne.App, configManager *core.ConfigManager) *IdentityWindow {
+	if globalIdentityWindow != nil {
+		return globalIdentityWindow
+	}
+
+	iw := &IdentityWindow{
+		BaseWindow:    ui.NewBaseWindow(app, "Identity", 420, 440),
+		configManager: configManager,
+	}
+	globalIdentityWindow = iw
+	iw.Window.SetOnClosed(func() {
+		iw.Window = nil
+		globalIdentityWindow = nil
+	})
+
+	publicKey, _ := configManager.GetKeyPair()
+	iw.PublicKeyEntry = widget.NewEntry()
+	iw.PublicKeyEntry.SetText(publicKey)
+	iw.PublicKeyEntry.Disable()
+
+	// Exportação: a senha é pedida duas vezes, já que um erro de digitação perde a chave
+	iw.ExportPassEntry = widget.NewPasswordEntry()
+	iw.ExportPassEntry.SetPlaceHolder("Passphrase")
+	iw.ExportConfirmEntry = widget.NewPasswordEntry()
+	iw.ExportConfirmEntry.SetPlaceHolder("Repeat the passphrase")
+
+	exportHint := widget.NewLabel("The exported identity lets another computer join your networks as this one. Keep it private and use a strong passphrase.")
+	exportHint.Wrapping = fyne.TextWrapWord
+
+	exportTab := container.NewVBox(
+		exportHint,
+		iw.ExportPassEntry,
+		iw.ExportConfirmEntry,
+		container.NewHBox(
+			layout.NewSpacer(),
+			widget.NewButtonWithIcon("Show QR code", theme.VisibilityIcon(), iw.showQRCode),
+			widget.NewButtonWithIcon("Save to file…", theme.DocumentSaveIcon(), iw.saveToFile),
+		),
+	)
+
+	// Importação: o texto vem colado, de um arquivo ou lido do QR code por outro aparelho
+	iw.ImportTextEntry = widget.NewMultiLineEntry()
+	iw.ImportTextEntry.SetPlaceHolder("govpn-identity:…")
+	iw.ImportTextEntry.Wrapping = fyne.TextWrapBreak
+	iw.ImportTextEntry.SetMinRowsVisible(3)
+	iw.ImportPassEntry = widget.NewPasswordEntry()
+	iw.ImportPassEntry.SetPlaceHolder("Passphrase")
+
+	importHint := widget.NewLabel("Paste an identity exported on another computer or open its file. It replaces the identity of this computer.")
+	importHint.Wrapping = fyne.TextWrapWord
+
+	importTab := container.NewVBox(
+		importHint,
+		iw.ImportTextEntry,
+		iw.ImportPassEntry,
+		container.NewHBox(
+			widget.NewButtonWithIcon("Open file…", theme.FolderOpenIcon(), iw.openFile),
+			layout.NewSpacer(),
+			widget.NewButtonWithIcon("Import", theme.DownloadIcon(), iw.importIdentity),
+		),
+	)
+
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabelWithStyle("Your Public Key:", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			iw.PublicKeyEntry,
+		),
+		nil, nil, nil,
+		container.NewAppTabs(
+			container.NewTabItemWithIcon("Export", theme.UploadIcon(), container.NewPadded(exportTab)),
+			container.NewTabItemWithIcon("Import", theme.DownloadIcon(), container.NewPadded(importTab)),
+		),
+	)
+	iw.SetContent(container.NewPadded(content))
+
+	return iw
+}
+
+// exportIdentity confere a senha e cifra a identidade
+func (iw *IdentityWindow) exportIdentity() (string, bool) {
+	if iw.ExportPassEntry.Text != iw.ExportConfirmEntry.Text {
+		dialog.ShowError(errors.New("the passphrases do not match"), iw.Window)
+		return "", false
+	}
+	exported, err := iw.configManager.ExportIdentity(iw.ExportPassEntry.Text)
+	if err != nil {
+		dialog.ShowError(err, iw.Window)
+		return "", false
+	}
+	return exported, true
+}
+
+// showQRCode mostra a identidade exportada como QR code, com a opção de copiar o texto
+func (iw *IdentityWindow) showQRCode() {
+	exported, ok := iw.exportIdentity()
+	if !ok {
+		return
+	}
+	code, err := qrcode.New(exported, qrcode.Medium)
+	if err != nil {
+		dialog.ShowError(err, iw.Window)
+		return
+	}
+
+	image := canvas.NewImageFromImage(code.Image(256))
+	image.FillMode = canvas.ImageFillContain
+	image.ScaleMode = canvas.ImageScalePixels
+	image.SetMinSize(fyne.NewSize(256, 256))
+
+	copyButton := widget.NewButtonWithIcon("Copy as text", theme.ContentCopyIcon(), func() {
+		fyne.CurrentApp().Clipboard().SetContent(exported)
+	})
+	dialog.ShowCustom("Identity QR code", "Close", container.NewVBox(
+		image,
+		container.NewCenter(copyButton),
+	), iw.Window)
+}
+
+// saveToFile grava a identidade exportada num arquivo escolhido pelo usuário
+func (iw *IdentityWindow) saveToFile() {
+	exported, ok := iw.exportIdentity()
+	if !ok {
+		return
+	}
+
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, iw.Window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+		if _, err := io.WriteString(writer, exported+"\n"); err != nil {
+			dialog.ShowError(err, iw.Window)
+			return
+		}
+		iw.ExportPassEntry.SetText("")
+		iw.ExportConfirmEntry.SetText("")
+		dialog.ShowInformation("Identity exported", "Saved to "+writer.URI().Name()+".", iw.Window)
+	}, iw.Window)
+	save.SetFileName(identityFileName)
+	save.Show()
+}
+
+// openFile lê uma identidade exportada para o campo de importação
+func (iw *IdentityWindow) openFile() {
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, iw.Window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+		// A identidade tem pouco mais de cem bytes; o limite evita ler um arquivo errado inteiro
+		content, err := io.ReadAll(io.LimitReader(reader, 4096))
+		if err != nil {
+			dialog.ShowError(err, iw.Window)
+			return
+		}
+		iw.ImportTextEntry.SetText(string(content))
+	}, iw.Window)
+}
+
+// importIdentity confirma a troca e importa a identidade
+func (iw *IdentityWindow) importIdentity() {
+	dialog.ShowConfirm("Replace identity",
+		"This computer will use the imported identity and leave the networks of the current one. Export the current identity first if you want to keep it. Continue?",
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			publicKey, err := iw.configManager.ImportIdentity(iw.ImportTextEntry.Text, iw.ImportPassEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, iw.Window)
+				return
+			}
+			iw.ImportTextEntry.SetText("")
+			iw.ImportPassEntry.SetText("")
+			iw.PublicKeyEntry.SetText(publicKey)
+			dialog.ShowInformation("Identity imported", "Restart "+AppName+" to use the imported identity.", iw.Window)
+		}, iw.Window)
+}
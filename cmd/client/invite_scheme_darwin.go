@@ -0,0 +1,8 @@
+package main
+
+// registerInviteScheme não faz nada no macOS: o esquema vem do CFBundleURLTypes do
+// Info.plist do pacote e o link chega por um Apple Event, que o Fyne não repassa. Lá o
+// link é colado na janela de entrada.
+func registerInviteScheme() error {
+	return nil
+}
@@ -0,0 +1,60 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/itxtoledo/govpn/cmd/client/core"
+)
+
+// inviteEntryName é a desktop entry que abre os links govpn://. Ela não aparece no menu
+// de aplicativos; serve só para o xdg-open achar o cliente.
+const inviteEntryName = "govpn-invite.desktop"
+
+// registerInviteScheme grava a desktop entry do esquema e a torna a padrão dele. Só mexe
+// no sistema quando a entrada mudou, como depois de mover o executável.
+func registerInviteScheme() error {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dataDir = filepath.Join(homeDir, ".local", "share")
+	}
+	path := filepath.Join(dataDir, "applications", inviteEntryName)
+
+	executable, err := appExecutable()
+	if err != nil {
+		return err
+	}
+	entry := fmt.Sprintf(`[Desktop Entry]
+Type=Application
+Name=%s
+Comment=Opens GoVPN invite links
+Exec=%s %%u
+Terminal=false
+NoDisplay=true
+MimeType=x-scheme-handler/%s;
+`, AppName, desktopExec([]string{executable}), core.InviteScheme)
+
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, []byte(entry)) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to write the invite link handler: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(entry), 0644); err != nil {
+		return fmt.Errorf("failed to write the invite link handler: %v", err)
+	}
+
+	if output, err := exec.Command("xdg-mime", "default", inviteEntryName, "x-scheme-handler/"+core.InviteScheme).CombinedOutput(); err != nil {
+		return fmt.Errorf("xdg-mime failed: %v: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
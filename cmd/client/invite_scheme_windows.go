@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"golang.org/x/sys/windows/registry"
+)
+
+// inviteSchemeKey é a classe do esquema govpn:// do usuário, que não pede administrador
+const inviteSchemeKey = `Software\Classes\` + core.InviteScheme
+
+// registerInviteScheme registra o executável atual como o programa dos links govpn://
+func registerInviteScheme() error {
+	executable, err := appExecutable()
+	if err != nil {
+		return err
+	}
+
+	key, _, err := registry.CreateKey(registry.CURRENT_USER, inviteSchemeKey, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to register the invite link handler: %v", err)
+	}
+	defer key.Close()
+	if err := key.SetStringValue("", "URL:"+AppName+" invite"); err != nil {
+		return fmt.Errorf("failed to register the invite link handler: %v", err)
+	}
+	if err := key.SetStringValue("URL Protocol", ""); err != nil {
+		return fmt.Errorf("failed to register the invite link handler: %v", err)
+	}
+
+	command, _, err := registry.CreateKey(registry.CURRENT_USER, inviteSchemeKey+`\shell\open\command`, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to register the invite link handler: %v", err)
+	}
+	defer command.Close()
+	if err := command.SetStringValue("", `"`+executable+`" "%1"`); err != nil {
+		return fmt.Errorf("failed to register the invite link handler: %v", err)
+	}
+	return nil
+}
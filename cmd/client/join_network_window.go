@@ -2,16 +2,18 @@ package main
 
 import (
 	"errors"
-	"fmt"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
 	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
 	"github.com/itxtoledo/govpn/libs/utils"
-	"github.com/itxtoledo/govpn/cmd/client/ui"
 )
 
 // Global variable to ensure only one join window can be open
@@ -21,23 +23,37 @@ var globalJoinWindow *JoinWindow
 type JoinWindow struct {
 	*ui.BaseWindow
 	JoinNetwork  func(string, string, string) (*smodels.JoinNetworkResponse, error)
+	CheckNetwork func(networkID string) (*smodels.NetworkExistsResponse, error)
 	ComputerName string
 
-	OnNetworkJoined   func(networkID, pin string)
+	// Campos preenchidos por um convite, guardados para quando a janela for montada
+	NetworkIDEntry *widget.Entry
+	PINEntry       *widget.Entry
+	StatusLabel    *widget.Label
+	JoinButton     *widget.Button
+	networkID      string
+	pin            string
+
+	// checkSeq descarta a resposta de uma consulta feita para um ID que já mudou
+	checkSeq int
+
+	OnNetworkJoined func(networkID, pin string)
 }
 
 // NewJoinWindow creates a new network joining window
 func NewJoinWindow(
 	app fyne.App,
 	joinNetwork func(string, string, string) (*smodels.JoinNetworkResponse, error),
+	checkNetwork func(networkID string) (*smodels.NetworkExistsResponse, error),
 	computername string,
 	onNetworkJoined func(networkID, pin string),
 ) *JoinWindow {
 	jw := &JoinWindow{
-		BaseWindow:        ui.NewBaseWindow(app, "Join Network", 320, 260),
-		JoinNetwork:       joinNetwork,
-		ComputerName:      computername,
-		OnNetworkJoined:   onNetworkJoined,
+		BaseWindow:      ui.NewBaseWindow(app, "Join Network", 320, 340),
+		JoinNetwork:     joinNetwork,
+		CheckNetwork:    checkNetwork,
+		ComputerName:    computername,
+		OnNetworkJoined: onNetworkJoined,
 	}
 
 	// Set close callback to reset the global instance when window closes
@@ -58,23 +74,56 @@ func (jw *JoinWindow) Show() {
 	titleLabel.TextStyle = fyne.TextStyle{Bold: true}
 	titleContainer := container.NewHBox(titleIcon, titleLabel)
 
+	// Um ID ou convite copiado antes de abrir a janela já entra no campo
+	pastedID := ""
+	if jw.networkID == "" {
+		clipboard := fyne.CurrentApp().Clipboard().Content()
+		if invite, err := core.ParseInvite(clipboard); err == nil {
+			jw.networkID, jw.pin = invite.NetworkID, invite.PIN
+		} else if utils.ValidateNetworkID(normalizeNetworkID(clipboard)) {
+			jw.networkID = normalizeNetworkID(clipboard)
+		}
+		pastedID = jw.networkID
+	}
+	pastedLabel := widget.NewLabelWithStyle("Filled in from the clipboard", fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+	pastedLabel.Hide()
+
 	// Create form inputs with better styling
 	networkIDEntry := widget.NewEntry()
-	networkIDEntry.PlaceHolder = "Network ID (e.g. ABC123)"
+	networkIDEntry.PlaceHolder = "Network ID or invite link"
 
 	pinEntry := widget.NewPasswordEntry()
 	pinEntry.PlaceHolder = "4-digit PIN"
 	ui.ConfigurePINEntry(pinEntry)
+	pinEntry.SetText(jw.pin)
+
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
 
-	// Add keyboard shortcuts
+	// Um link de convite colado no campo vira o ID e, se houver, o PIN; um ID é conferido
+	// antes de o PIN ser pedido
+	networkIDEntry.OnChanged = func(text string) {
+		if pastedID == "" || normalizeNetworkID(text) != pastedID {
+			pastedLabel.Hide()
+		}
+		if invite, err := core.ParseInvite(text); err == nil {
+			jw.SetInvite(invite.NetworkID, invite.PIN)
+			return
+		}
+		jw.checkNetworkID(text)
+	}
+	jw.NetworkIDEntry, jw.PINEntry, jw.StatusLabel = networkIDEntry, pinEntry, statusLabel
+
+	// Enter no ID passa para o PIN, liberado depois da consulta, e Enter no PIN entra na rede
 	networkIDEntry.OnSubmitted = func(text string) {
-		pinEntry.FocusGained()
+		if !pinEntry.Disabled() {
+			jw.BaseWindow.Window.Canvas().Focus(pinEntry)
+		}
 	}
 
 	pinEntry.OnSubmitted = func(text string) {
-		// Trigger join button when Enter is pressed on pin field
-		if networkIDEntry.Text != "" && utils.ValidatePIN(text) {
-			// Will be triggered by the join button logic
+		if !jw.JoinButton.Disabled() {
+			jw.JoinButton.OnTapped()
 		}
 	}
 
@@ -82,6 +131,8 @@ func (jw *JoinWindow) Show() {
 	formContainer := container.NewVBox(
 		widget.NewLabel("Network ID:"),
 		container.NewPadded(networkIDEntry),
+		pastedLabel,
+		statusLabel,
 		widget.NewLabel("PIN:"),
 		container.NewPadded(pinEntry),
 	)
@@ -89,11 +140,11 @@ func (jw *JoinWindow) Show() {
 	// Create buttons
 	var joinButton *widget.Button
 	joinButton = widget.NewButtonWithIcon("Join Network", theme.ConfirmIcon(), func() {
-		networkID := networkIDEntry.Text
+		networkID := normalizeNetworkID(networkIDEntry.Text)
 		pin := pinEntry.Text
 
-		if networkID == "" {
-			dialog.ShowError(errors.New("network ID cannot be empty"), jw.BaseWindow.Window)
+		if !utils.ValidateNetworkID(networkID) {
+			dialog.ShowError(errors.New("enter a valid network ID"), jw.BaseWindow.Window)
 			return
 		}
 
@@ -106,16 +157,16 @@ func (jw *JoinWindow) Show() {
 		joinButton.SetText("Joining...")
 		joinButton.Disable()
 
-		go func() {
+		crash.Go("join network", func() {
 			_, err := jw.JoinNetwork(networkID, pin, jw.ComputerName)
 
 			// Use goroutine to update UI
-			go func() {
+			crash.Go("join network result", func() {
 				joinButton.SetText("Join Network")
 				joinButton.Enable()
 
 				if err != nil {
-					dialog.ShowError(fmt.Errorf("failed to join network: %v", err), jw.BaseWindow.Window)
+					dialog.ShowError(requestError("join network", err), jw.BaseWindow.Window)
 					return
 				}
 
@@ -124,8 +175,8 @@ func (jw *JoinWindow) Show() {
 
 				// Close the join window after invoking the callback
 				jw.BaseWindow.Close()
-			}()
-		}()
+			})
+		})
 	})
 
 	cancelButton := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
@@ -134,6 +185,7 @@ func (jw *JoinWindow) Show() {
 
 	// Style buttons
 	joinButton.Importance = widget.HighImportance
+	jw.JoinButton = joinButton
 
 	// Create button container with better spacing
 	buttonContainer := container.NewGridWithColumns(2, cancelButton, joinButton)
@@ -147,9 +199,86 @@ func (jw *JoinWindow) Show() {
 		container.NewPadded(buttonContainer),
 	)
 
-		jw.BaseWindow.SetContent(content)
+	jw.BaseWindow.SetContent(content)
 	jw.BaseWindow.Show()
 
-	// Set focus on the network ID field when window opens
-	jw.BaseWindow.Window.Canvas().Focus(networkIDEntry)
+	// Preencher o ID dispara a consulta, que libera o PIN e o foca quando a rede existe
+	networkIDEntry.SetText(jw.networkID)
+	if pastedID != "" {
+		pastedLabel.Show()
+	}
+	if jw.networkID == "" {
+		jw.checkNetworkID("")
+		jw.BaseWindow.Window.Canvas().Focus(networkIDEntry)
+	}
+}
+
+// normalizeNetworkID tira espaços e maiúsculas de um ID digitado; os IDs são hexadecimais
+// minúsculos
+func normalizeNetworkID(text string) string {
+	return strings.ToLower(strings.TrimSpace(text))
+}
+
+// checkNetworkID confere o formato do ID e pergunta ao servidor se a rede existe. O PIN só
+// é pedido para uma rede que existe e tem vaga; sem resposta do servidor, como num servidor
+// antigo, a entrada segue e o próprio servidor responde ao pedido.
+func (jw *JoinWindow) checkNetworkID(text string) {
+	jw.checkSeq++
+	seq := jw.checkSeq
+	networkID := normalizeNetworkID(text)
+
+	askPIN := func(ok bool, status string, importance widget.Importance) {
+		jw.StatusLabel.SetText(status)
+		jw.StatusLabel.Importance = importance
+		jw.StatusLabel.Refresh()
+		if ok {
+			jw.PINEntry.Enable()
+			jw.JoinButton.Enable()
+			jw.BaseWindow.Window.Canvas().Focus(jw.PINEntry)
+		} else {
+			jw.PINEntry.Disable()
+			jw.JoinButton.Disable()
+		}
+	}
+
+	switch {
+	case networkID == "":
+		askPIN(false, "Paste a network ID or an invite link.", widget.MediumImportance)
+		return
+	case !utils.ValidateNetworkID(networkID):
+		askPIN(false, "A network ID has 16 characters, digits and the letters a to f.", widget.DangerImportance)
+		return
+	}
+
+	askPIN(false, "Looking up the network...", widget.MediumImportance)
+	crash.Go("check network", func() {
+		res, err := jw.CheckNetwork(networkID)
+		fyne.Do(func() {
+			if seq != jw.checkSeq || jw.BaseWindow.Window == nil {
+				return
+			}
+			switch {
+			case err != nil:
+				askPIN(true, "Could not look up the network, the server will check it on join.", widget.WarningImportance)
+			case !res.Exists:
+				askPIN(false, "There is no network with this ID.", widget.DangerImportance)
+			case res.IsFull:
+				askPIN(false, res.NetworkName+" is full.", widget.DangerImportance)
+			default:
+				askPIN(true, "Network: "+res.NetworkName, widget.SuccessImportance)
+			}
+		})
+	})
+}
+
+// SetInvite preenche o ID da rede e, se o convite trouxer, o PIN
+func (jw *JoinWindow) SetInvite(networkID, pin string) {
+	jw.networkID = networkID
+	if pin != "" {
+		jw.pin = pin
+	}
+	if jw.NetworkIDEntry != nil {
+		jw.NetworkIDEntry.SetText(jw.networkID)
+		jw.PINEntry.SetText(jw.pin)
+	}
 }
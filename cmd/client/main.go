@@ -1,46 +1,113 @@
 package main
 
 import (
+	"errors"
 	"flag"
-	"io"
-	"log"
 	"os"
-	"path/filepath"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/data/binding"
 	"fyne.io/fyne/v2/driver/desktop"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
 	"github.com/itxtoledo/govpn/cmd/client/data"
 	"github.com/itxtoledo/govpn/cmd/client/icon"
+	"github.com/itxtoledo/govpn/libs/logger"
 )
 
 func main() {
+	// Um panic na interface fecha o cliente; o relatório fica para a próxima abertura
+	defer crash.Fatal()
+
 	var configPath string
+	var minimized bool
 	flag.StringVar(&configPath, "config", "", "Path to custom configuration directory")
+	flag.BoolVar(&minimized, "minimized", false, "Start in the system tray without showing the window")
 	flag.Parse()
 
-	configManager := NewConfigManager(configPath)
+	// Até a pasta de dados ser conhecida o log vai só para o console
+	logger.Setup(logger.Options{Level: core.DefaultLogLevel, Console: os.Stdout, StdLog: true})
+	configManager := core.NewConfigManager(configPath)
+	if err := configManager.SetupLog("govpn.log", os.Stdout); err != nil {
+		logger.Error("Error opening the log file", "error", err)
+	}
+	crash.Setup(configManager.GetDataPath(), AppVersion)
+
+	// Um link govpn:// chega como argumento quando o sistema abre o cliente por ele. Com o
+	// cliente já aberto, o link vai para ele e este processo termina.
+	var invite *core.Invite
+	if link := flag.Arg(0); link != "" {
+		parsed, err := core.ParseInvite(link)
+		if err != nil {
+			logger.Warn("Ignoring argument", "argument", link, "error", err)
+		} else if _, err := core.CallControl(configManager.GetDataPath(), "open_invite", map[string]string{"url": link}); err == nil {
+			logger.Info("Invite passed to the running client")
+			return
+		} else {
+			if !errors.Is(err, core.ErrControlUnavailable) {
+				logger.Warn("Could not pass the invite to the running client", "error", err)
+			}
+			invite = &parsed
+		}
+	}
 
-	// Determine log file path (always in the data directory)
-	logFilePath := filepath.Join(configManager.GetDataPath(), "govpn.log")
+	crash.Go("register invite scheme", func() {
+		if err := registerInviteScheme(); err != nil {
+			logger.Warn("Could not register the invite link handler", "error", err)
+		}
+	})
 
-	// Create a multi-writer that always includes os.Stdout
-	var writers []io.Writer
-	writers = append(writers, os.Stdout)
+	fyneApp := app.NewWithID("com.itxtoledo.govpn")
 
-	logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		log.Printf("Error opening log file %s: %v", logFilePath, err)
-		// If log file cannot be opened, logging will only go to stdout (which is already in writers)
+	// Toda a interface depende da configuração, que cifrada com senha só abre depois dela
+	var stop func()
+	start := func() {
+		stop = startUI(fyneApp, configManager, minimized, invite)
+	}
+	if configManager.Locked() != nil {
+		NewUnlockWindow(fyneApp, configManager, start).Show()
 	} else {
-		writers = append(writers, logFile)
+		start()
 	}
 
-	mw := io.MultiWriter(writers...)
-	log.SetOutput(mw)
-	log.SetFlags(log.Lshortfile | log.LstdFlags)
+	fyneApp.Run()
+	if stop != nil {
+		stop()
+	}
+	tidyUp()
+}
+
+// startUI monta a interface com a configuração já aberta e a exibe, com o convite que abriu
+// o aplicativo, se houver. O retorno encerra a API de controle depois que o aplicativo sai.
+func startUI(fyneApp fyne.App, configManager *core.ConfigManager, minimized bool, invite *core.Invite) func() {
+	// A configuração cifrada só revela o nível do log e a aparência depois de aberta
+	logger.SetLevel(configManager.GetConfig().MinLogLevel())
+	applyTheme(fyneApp, configManager.GetConfig())
+
 	computername := configManager.GetConfig().ComputerName
-		ui := NewUIManager(DefaultServerAddress, computername, configPath)
+	ui := NewUIManager(fyneApp, DefaultServerAddress, computername, configManager)
+
+	// Panics recuperados avisam na hora; o que fechou o cliente da última vez, ao abrir
+	crash.SetHandler(ui.showCrashReport)
+	if report, ok := crash.Pending(); ok {
+		ui.showCrashReport(report)
+	}
+
+	// API de controle local para scripts e launchers de jogos, por onde chegam também os
+	// convites abertos com o aplicativo já em execução
+	ui.VPN.OnInvite = func(invite core.Invite) {
+		fyne.Do(func() {
+			ui.OpenInvite(invite)
+		})
+	}
+	stop := func() {}
+	if ln, err := ui.VPN.ListenControl(); err != nil {
+		logger.Warn("Control API unavailable", "error", err)
+	} else {
+		crash.Go("control API", func() { ui.VPN.ServeControl(ln) })
+		stop = func() { ln.Close() }
+	}
 
 	// Set up system tray
 	if desk, ok := ui.App.(desktop.App); ok {
@@ -65,44 +132,42 @@ func main() {
 		connectItem := fyne.NewMenuItem("Connect", func() {
 			if ui.VPN != nil {
 				// The Run method handles the connection logic
-				go ui.VPN.Run(DefaultServerAddress, ui.RealtimeData, ui.refreshNetworkList, ui.refreshUI)
+				crash.Go("tray connect", func() { ui.VPN.Run(DefaultServerAddress, ui.RealtimeData) })
 			}
 		})
 
 		disconnectItem := fyne.NewMenuItem("Disconnect", func() {
 			if ui.VPN != nil && ui.VPN.NetworkManager != nil {
-				go ui.VPN.NetworkManager.Disconnect()
+				crash.Go("tray disconnect", func() { ui.VPN.NetworkManager.Disconnect() })
 			}
 		})
 
-		// Create the menu with separators for better organization
-		menu := fyne.NewMenu(AppName,
-			showItem,
-			fyne.NewMenuItemSeparator(),
-			connectItem,
-			disconnectItem,
-			fyne.NewMenuItemSeparator(),
-			aboutItem,
-			quitItem,
-		)
-		desk.SetSystemTrayMenu(menu)
-
-		// Add a listener to update the menu based on connection state
-		ui.RealtimeData.ConnectionState.AddListener(binding.NewDataListener(func() {
-			state, _ := ui.RealtimeData.ConnectionState.Get()
-			connectionState := data.ConnectionState(state)
-
+		// O menu é montado de novo quando a conexão, as redes ou o IP virtual mudam, com as
+		// redes num submenu para conectar e desconectar sem abrir a janela
+		updateMenu := func() {
+			connectionState := ui.RealtimeData.GetConnectionState()
 			connectItem.Disabled = (connectionState != data.StateDisconnected)
 			disconnectItem.Disabled = (connectionState == data.StateDisconnected)
 
-			// Update the menu to reflect the new state
-			desk.SetSystemTrayMenu(menu)
-		}))
-
-		// Set initial state (app starts disconnected)
-		connectItem.Disabled = false
-		disconnectItem.Disabled = true
-		desk.SetSystemTrayMenu(menu)
+			// Create the menu with separators for better organization
+			desk.SetSystemTrayMenu(fyne.NewMenu(AppName,
+				showItem,
+				fyne.NewMenuItemSeparator(),
+				ui.trayStatusItem(),
+				ui.trayNetworksItem(),
+				fyne.NewMenuItemSeparator(),
+				connectItem,
+				disconnectItem,
+				fyne.NewMenuItemSeparator(),
+				aboutItem,
+				quitItem,
+			))
+		}
+		listener := binding.NewDataListener(updateMenu)
+		ui.Bindings.ConnectionState.AddListener(listener)
+		ui.Bindings.Networks.AddListener(listener)
+		ui.Bindings.NetworkName.AddListener(listener)
+		ui.Bindings.ComputerIP.AddListener(listener)
 	}
 
 	// Hide window on close
@@ -110,10 +175,16 @@ func main() {
 		ui.MainWindow.Hide()
 	})
 
-	ui.Run(DefaultServerAddress)
-	tidyUp()
+	// Sem bandeja não haveria como abrir a janela depois
+	_, hasTray := ui.App.(desktop.App)
+	ui.Start(DefaultServerAddress, minimized && hasTray)
+	if invite != nil {
+		ui.OpenInvite(*invite)
+	}
+	return stop
 }
 
 func tidyUp() {
-	log.Println("Exited")
+	logger.Info("Exited")
+	logger.Sync()
 }
@@ -0,0 +1,12 @@
+//go:build !windows || !(amd64 || arm64)
+
+package network
+
+import "github.com/itxtoledo/govpn/libs/logger"
+
+// restrictApps só existe no Windows; aqui a interface continua aberta a todos os programas
+// e o tráfego é dividido só pelas rotas
+func restrictApps(dev Device, apps []string) error {
+	logger.Warn("Per-program rules are only supported on Windows, splitting traffic by routes only", "device", dev.Name(), "programs", len(apps))
+	return nil
+}
@@ -0,0 +1,222 @@
+//go:build windows && (amd64 || arm64)
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+// Filtros do WFP (Windows Filtering Platform) que deixam só os programas escolhidos usarem
+// a interface: um filtro bloqueia as conexões de saída e de entrada na interface e, com peso
+// maior, um filtro por programa as permite. A sessão é dinâmica, então o sistema apaga os
+// filtros quando ela fecha junto com a interface, ou quando o processo morre.
+// As estruturas seguem o alinhamento de 64 bits de fwpmtypes.h.
+
+var (
+	fwpuclnt = windows.NewLazySystemDLL("fwpuclnt.dll")
+
+	procFwpmEngineOpen0           = fwpuclnt.NewProc("FwpmEngineOpen0")
+	procFwpmEngineClose0          = fwpuclnt.NewProc("FwpmEngineClose0")
+	procFwpmTransactionBegin0     = fwpuclnt.NewProc("FwpmTransactionBegin0")
+	procFwpmTransactionCommit0    = fwpuclnt.NewProc("FwpmTransactionCommit0")
+	procFwpmTransactionAbort0     = fwpuclnt.NewProc("FwpmTransactionAbort0")
+	procFwpmFilterAdd0            = fwpuclnt.NewProc("FwpmFilterAdd0")
+	procFwpmGetAppIdFromFileName0 = fwpuclnt.NewProc("FwpmGetAppIdFromFileName0")
+	procFwpmFreeMemory0           = fwpuclnt.NewProc("FwpmFreeMemory0")
+)
+
+var (
+	layerALEAuthConnectV4    = windows.GUID{Data1: 0xc38d57d1, Data2: 0x05a7, Data3: 0x4c33, Data4: [8]byte{0x90, 0x4f, 0x7f, 0xbc, 0xee, 0xe6, 0x0e, 0x82}}
+	layerALEAuthRecvAcceptV4 = windows.GUID{Data1: 0xe1cd9fe7, Data2: 0xf4b5, Data3: 0x4273, Data4: [8]byte{0x96, 0xc0, 0x59, 0x2e, 0x48, 0x7b, 0x86, 0x50}}
+	conditionLocalInterface  = windows.GUID{Data1: 0x4cd62a49, Data2: 0x59c3, Data3: 0x4969, Data4: [8]byte{0xb7, 0xf3, 0xbd, 0xa5, 0xd3, 0x28, 0x90, 0xa4}}
+	conditionALEAppID        = windows.GUID{Data1: 0xd78e1e87, Data2: 0x8644, Data3: 0x4ea5, Data4: [8]byte{0x94, 0x37, 0xd8, 0x09, 0xec, 0xef, 0xc9, 0x71}}
+)
+
+const (
+	rpcCAuthnWinNT     = 10
+	fwpmSessionDynamic = 0x1
+	fwpUint8           = 1
+	fwpUint64          = 4
+	fwpByteBlobType    = 12
+	fwpMatchEqual      = 0
+	fwpActionBlock     = 0x1001
+	fwpActionPermit    = 0x1002
+	filterWeightBlock  = 0
+	filterWeightPermit = 15
+)
+
+type fwpmDisplayData0 struct {
+	name        *uint16
+	description *uint16
+}
+
+type fwpmSession0 struct {
+	sessionKey           windows.GUID
+	displayData          fwpmDisplayData0
+	flags                uint32
+	txnWaitTimeoutInMSec uint32
+	processID            uint32
+	sid                  *windows.SID
+	username             *uint16
+	kernelMode           int32
+}
+
+type fwpByteBlob struct {
+	size uint32
+	data *uint8
+}
+
+// fwpValue0 também serve de FWP_CONDITION_VALUE0, que tem o mesmo formato
+type fwpValue0 struct {
+	typ   uint32
+	value uintptr
+}
+
+type fwpmFilterCondition0 struct {
+	fieldKey       windows.GUID
+	matchType      uint32
+	conditionValue fwpValue0
+}
+
+type fwpmAction0 struct {
+	typ        uint32
+	filterType windows.GUID
+}
+
+type fwpmFilter0 struct {
+	filterKey           windows.GUID
+	displayData         fwpmDisplayData0
+	flags               uint32
+	providerKey         *windows.GUID
+	providerData        fwpByteBlob
+	layerKey            windows.GUID
+	subLayerKey         windows.GUID
+	weight              fwpValue0
+	numFilterConditions uint32
+	filterCondition     *fwpmFilterCondition0
+	action              fwpmAction0
+	_                   uint32 // A união seguinte tem um UINT64
+	providerContextKey  windows.GUID
+	reserved            *windows.GUID
+	filterID            uint64
+	effectiveWeight     fwpValue0
+}
+
+// wfpSession é a sessão dinâmica que mantém os filtros de uma interface
+type wfpSession windows.Handle
+
+func (s wfpSession) Close() error {
+	return wfpCall(procFwpmEngineClose0, uintptr(s))
+}
+
+// wfpCall chama uma função do WFP, que retorna o código de erro em vez de usar GetLastError
+func wfpCall(proc *windows.LazyProc, args ...uintptr) error {
+	if err := proc.Find(); err != nil {
+		return err
+	}
+	if r, _, _ := proc.Call(args...); r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+// restrictApps deixa só os programas em apps usarem a interface dev
+func restrictApps(dev Device, apps []string) error {
+	wintun, ok := dev.(*wintunDevice)
+	if !ok {
+		return errors.New("not a wintun device")
+	}
+	native, ok := wintun.dev.(*tun.NativeTun)
+	if !ok {
+		return errors.New("wintun device has no interface LUID")
+	}
+
+	name, _ := windows.UTF16PtrFromString("GoVPN split tunnel")
+	session := fwpmSession0{displayData: fwpmDisplayData0{name: name}, flags: fwpmSessionDynamic}
+	var engine windows.Handle
+	if err := wfpCall(procFwpmEngineOpen0, 0, rpcCAuthnWinNT, 0, uintptr(unsafe.Pointer(&session)), uintptr(unsafe.Pointer(&engine))); err != nil {
+		return fmt.Errorf("opening the filtering engine: %w", err)
+	}
+
+	if err := addAppFilters(engine, native.LUID(), apps); err != nil {
+		wfpSession(engine).Close()
+		return err
+	}
+	wintun.filters = wfpSession(engine)
+	return nil
+}
+
+// addAppFilters adiciona numa transação os filtros de bloqueio da interface e os de
+// permissão de cada programa, nas conexões de saída e de entrada
+func addAppFilters(engine windows.Handle, luid uint64, apps []string) error {
+	if err := wfpCall(procFwpmTransactionBegin0, uintptr(engine), 0); err != nil {
+		return err
+	}
+
+	var appIDs []*fwpByteBlob
+	defer func() {
+		for _, appID := range appIDs {
+			procFwpmFreeMemory0.Call(uintptr(unsafe.Pointer(&appID)))
+		}
+	}()
+	for _, app := range apps {
+		path, err := windows.UTF16PtrFromString(app)
+		if err != nil {
+			wfpCall(procFwpmTransactionAbort0, uintptr(engine))
+			return err
+		}
+		var appID *fwpByteBlob
+		if err := wfpCall(procFwpmGetAppIdFromFileName0, uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&appID))); err != nil {
+			wfpCall(procFwpmTransactionAbort0, uintptr(engine))
+			return fmt.Errorf("program %s: %w", app, err)
+		}
+		appIDs = append(appIDs, appID)
+	}
+
+	iface := fwpmFilterCondition0{
+		fieldKey:       conditionLocalInterface,
+		matchType:      fwpMatchEqual,
+		conditionValue: fwpValue0{typ: fwpUint64, value: uintptr(unsafe.Pointer(&luid))},
+	}
+	for _, layer := range []windows.GUID{layerALEAuthConnectV4, layerALEAuthRecvAcceptV4} {
+		if err := addFilter(engine, layer, fwpActionBlock, filterWeightBlock, []fwpmFilterCondition0{iface}); err != nil {
+			wfpCall(procFwpmTransactionAbort0, uintptr(engine))
+			return err
+		}
+		for _, appID := range appIDs {
+			app := fwpmFilterCondition0{
+				fieldKey:       conditionALEAppID,
+				matchType:      fwpMatchEqual,
+				conditionValue: fwpValue0{typ: fwpByteBlobType, value: uintptr(unsafe.Pointer(appID))},
+			}
+			if err := addFilter(engine, layer, fwpActionPermit, filterWeightPermit, []fwpmFilterCondition0{iface, app}); err != nil {
+				wfpCall(procFwpmTransactionAbort0, uintptr(engine))
+				return err
+			}
+		}
+	}
+
+	return wfpCall(procFwpmTransactionCommit0, uintptr(engine))
+}
+
+// addFilter adiciona um filtro com as condições, todas exigidas, na subcamada padrão
+func addFilter(engine windows.Handle, layer windows.GUID, action uint32, weight uint8, conditions []fwpmFilterCondition0) error {
+	name, _ := windows.UTF16PtrFromString("GoVPN split tunnel")
+	filter := fwpmFilter0{
+		displayData:         fwpmDisplayData0{name: name},
+		layerKey:            layer,
+		weight:              fwpValue0{typ: fwpUint8, value: uintptr(weight)},
+		numFilterConditions: uint32(len(conditions)),
+		filterCondition:     &conditions[0],
+		action:              fwpmAction0{typ: action},
+	}
+	if err := wfpCall(procFwpmFilterAdd0, uintptr(engine), uintptr(unsafe.Pointer(&filter)), 0, 0); err != nil {
+		return fmt.Errorf("adding filter: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package network
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// Descoberta na rede: os pacotes com que os sistemas e alguns jogos anunciam e procuram nomes
+// e serviços na LAN são copiados para todos os peers, mesmo com o broadcast desligado, para
+// pastas compartilhadas e partidas aparecerem sem digitar IPs. Os grupos multicast recebem
+// rotas próprias na interface; os broadcasts do NetBIOS já vão para ela pela rota da sub-rede.
+
+// discoveryService é um protocolo de descoberta: o grupo multicast e a porta UDP de destino,
+// ou só a porta quando o protocolo usa o broadcast da sub-rede
+type discoveryService struct {
+	group net.IP
+	port  uint16
+}
+
+// discoveryServices são os protocolos copiados aos peers
+var discoveryServices = []discoveryService{
+	{group: net.IPv4(224, 0, 0, 251), port: 5353}, // mDNS e DNS-SD (Bonjour, Avahi)
+	{group: net.IPv4(224, 0, 0, 252), port: 5355}, // LLMNR, nomes de computador do Windows
+	{group: net.IPv4(224, 0, 2, 60), port: 4445},  // Partidas abertas na LAN do Minecraft Java
+	{port: 137}, // Serviço de nomes do NetBIOS
+	{port: 138}, // Datagramas do NetBIOS, a lista de computadores do Windows
+}
+
+// discoveryRoutes são as rotas dos grupos de discoveryServices
+var discoveryRoutes = func() []string {
+	var routes []string
+	for _, service := range discoveryServices {
+		if service.group != nil {
+			routes = append(routes, service.group.String()+"/32")
+		}
+	}
+	return routes
+}()
+
+// EnableDiscovery copia para todos os peers os pacotes dos protocolos de descoberta, com o
+// broadcast ligado ou não. Deve ser chamado antes de Run.
+func (r *Router) EnableDiscovery() {
+	r.discovery = true
+}
+
+// isDiscovery diz se o pacote IPv4 é UDP de um protocolo de descoberta
+func (r *Router) isDiscovery(packet []byte) bool {
+	headerLen := int(packet[0]&0x0f) * 4
+	if packet[9] != 17 || len(packet) < headerLen+8 {
+		return false
+	}
+	dst := net.IP(packet[16:20])
+	port := binary.BigEndian.Uint16(packet[headerLen+2:])
+
+	for _, service := range discoveryServices {
+		if service.port != port {
+			continue
+		}
+		if service.group != nil && service.group.Equal(dst) {
+			return true
+		}
+		if service.group == nil && (dst.Equal(net.IPv4bcast) || dst.Equal(r.subnetBroadcast())) {
+			return true
+		}
+	}
+	return false
+}
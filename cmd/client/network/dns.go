@@ -0,0 +1,188 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Servidor DNS da rede virtual: responde <nome>.govpn com o IP virtual do computador e as
+// consultas reversas da sub-rede da rede. Ele escuta no IP da interface TUN, na porta DNSPort, e
+// OpenDevice com Config.DNS faz o sistema mandar para lá só as consultas desses domínios;
+// o resto continua no DNS de sempre. Não é recursivo: outros nomes são recusados.
+
+// DNSDomain é o domínio dos nomes dos computadores
+const DNSDomain = "govpn"
+
+// reverseDomain é o domínio das consultas reversas da sub-rede /24 de ip, como
+// "0.10.10.in-addr.arpa" para 10.10.0.0/24
+func reverseDomain(ip net.IP) string {
+	ip = ip.To4()
+	return fmt.Sprintf("%d.%d.%d.in-addr.arpa", ip[2], ip[1], ip[0])
+}
+
+// dnsTTL é por quantos segundos as respostas podem ficar em cache; curto, já que os IPs
+// mudam quando outra rede é conectada
+const dnsTTL = 60
+
+// DNSLabel converte o nome de um computador num rótulo DNS: minúsculas, dígitos e hífens,
+// como "Maria's PC" para "marias-pc". Retorna vazio quando não sobra nenhum caractere.
+func DNSLabel(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+		case r == '\'' || r == '’':
+		default:
+			hyphen = true
+		}
+	}
+	label := b.String()
+	if len(label) > 63 {
+		label = strings.TrimRight(label[:63], "-")
+	}
+	return label
+}
+
+// DNSServer responde os nomes dos computadores da rede
+type DNSServer struct {
+	conn    net.PacketConn
+	reverse string // Domínio das consultas reversas da sub-rede deste computador
+
+	mu    sync.RWMutex
+	hosts map[string]net.IP // Nome completo, como "maria.govpn." -> IP virtual
+	ptrs  map[string]string // Nome reverso, como "3.0.10.10.in-addr.arpa." -> nome completo
+}
+
+// ListenDNS abre o servidor DNS no IP virtual deste computador
+func ListenDNS(ip string) (*DNSServer, error) {
+	conn, err := net.ListenPacket("udp", net.JoinHostPort(ip, strconv.Itoa(DNSPort)))
+	if err != nil {
+		return nil, err
+	}
+	return &DNSServer{conn: conn, reverse: reverseDomain(net.ParseIP(ip)), hosts: make(map[string]net.IP), ptrs: make(map[string]string)}, nil
+}
+
+// SetRecords substitui os registros, de nome do computador sem o domínio para IP virtual
+func (s *DNSServer) SetRecords(records map[string]string) {
+	hosts := make(map[string]net.IP, len(records))
+	ptrs := make(map[string]string, len(records))
+	for label, address := range records {
+		ip := net.ParseIP(address).To4()
+		if ip == nil || label == "" {
+			continue
+		}
+		host := label + "." + DNSDomain + "."
+		hosts[host] = ip
+		ptrs[fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", ip[3], ip[2], ip[1], ip[0])] = host
+	}
+
+	s.mu.Lock()
+	s.hosts, s.ptrs = hosts, ptrs
+	s.mu.Unlock()
+}
+
+// Run responde as consultas até o servidor ser fechado
+func (s *DNSServer) Run() {
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Warn("DNS server stopped", "error", err)
+			}
+			return
+		}
+
+		reply, err := s.answer(buf[:n])
+		if err != nil {
+			logger.Debug("Ignoring DNS query", "from", from, "error", err)
+			continue
+		}
+		s.conn.WriteTo(reply, from)
+	}
+}
+
+// answer monta a resposta de uma consulta
+func (s *DNSServer) answer(query []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	if header.Response {
+		return nil, errors.New("not a query")
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	reply := dnsmessage.Header{ID: header.ID, Response: true, OpCode: header.OpCode, RecursionDesired: header.RecursionDesired}
+	name := strings.ToLower(question.Name.String())
+
+	s.mu.RLock()
+	ip, isHost := s.hosts[name]
+	ptr, isPTR := s.ptrs[name]
+	s.mu.RUnlock()
+
+	switch {
+	case header.OpCode != 0 || question.Class != dnsmessage.ClassINET:
+		reply.RCode = dnsmessage.RCodeNotImplemented
+	case strings.HasSuffix(name, "."+DNSDomain+".") || strings.HasSuffix(name, "."+s.reverse+"."):
+		reply.Authoritative = true
+		if !isHost && !isPTR {
+			reply.RCode = dnsmessage.RCodeNameError
+		}
+	default:
+		reply.RCode = dnsmessage.RCodeRefused
+	}
+
+	b := dnsmessage.NewBuilder(nil, reply)
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(question); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	// Um nome conhecido sem registro do tipo pedido, como AAAA, responde sem erro e vazio
+	resource := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: dnsTTL}
+	switch {
+	case reply.RCode != dnsmessage.RCodeSuccess:
+	case isHost && (question.Type == dnsmessage.TypeA || question.Type == dnsmessage.TypeALL):
+		var a dnsmessage.AResource
+		copy(a.A[:], ip)
+		err = b.AResource(resource, a)
+	case isPTR && (question.Type == dnsmessage.TypePTR || question.Type == dnsmessage.TypeALL):
+		var target dnsmessage.Name
+		if target, err = dnsmessage.NewName(ptr); err == nil {
+			err = b.PTRResource(resource, dnsmessage.PTRResource{PTR: target})
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// Close fecha o servidor
+func (s *DNSServer) Close() error {
+	return s.conn.Close()
+}
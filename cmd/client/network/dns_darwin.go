@@ -0,0 +1,38 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// DNSPort é a porta do servidor DNS; fora da faixa privilegiada, o cliente abre o servidor
+// mesmo quando é o helper quem tem os privilégios
+const DNSPort = 15353
+
+// resolverDir guarda um arquivo por domínio com o servidor que o responde
+const resolverDir = "/etc/resolver"
+
+// configureDNS cria os arquivos do resolver do macOS para os domínios da VPN; desfazer os
+// apaga, já que eles sobrevivem à interface
+func configureDNS(name string, ip net.IP) (func(), error) {
+	if err := os.MkdirAll(resolverDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	content := fmt.Sprintf("# Created by GoVPN for %s\nnameserver %s\nport %d\n", name, ip, DNSPort)
+	files := []string{filepath.Join(resolverDir, DNSDomain), filepath.Join(resolverDir, reverseDomain(ip))}
+	undo := func() {
+		for _, file := range files {
+			os.Remove(file)
+		}
+	}
+	for _, file := range files {
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			undo()
+			return nil, err
+		}
+	}
+	return undo, nil
+}
@@ -0,0 +1,24 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// DNSPort é a porta do servidor DNS; fora da faixa privilegiada, o cliente abre o servidor
+// mesmo quando é o helper quem tem os privilégios
+const DNSPort = 15353
+
+// configureDNS manda ao servidor da interface as consultas dos domínios da VPN pelo
+// systemd-resolved; a configuração some junto com a interface
+func configureDNS(name string, ip net.IP) (func(), error) {
+	server := net.JoinHostPort(ip.String(), strconv.Itoa(DNSPort))
+	if err := run("resolvectl", "dns", name, server); err != nil {
+		return nil, fmt.Errorf("systemd-resolved is needed to resolve .%s names: %w", DNSDomain, err)
+	}
+	if err := run("resolvectl", "domain", name, "~"+DNSDomain, "~"+reverseDomain(ip)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !windows
+
+package network
+
+import "net"
+
+// DNSPort é a porta do servidor DNS
+const DNSPort = 53
+
+func configureDNS(name string, ip net.IP) (func(), error) {
+	return nil, ErrUnsupported
+}
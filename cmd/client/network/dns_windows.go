@@ -0,0 +1,30 @@
+package network
+
+import (
+	"fmt"
+	"net"
+)
+
+// DNSPort é a porta do servidor DNS; as regras do NRPT só aceitam a porta padrão
+const DNSPort = 53
+
+// nrptComment marca as regras criadas pelo GoVPN, para apagá-las inclusive depois de uma queda
+const nrptComment = "GoVPN"
+
+// configureDNS cria uma regra do NRPT (Name Resolution Policy Table) que manda as consultas
+// dos domínios da VPN ao servidor da interface; desfazer a apaga, já que ela sobrevive à
+// interface
+func configureDNS(name string, ip net.IP) (func(), error) {
+	removeNRPTRules()
+	add := fmt.Sprintf("Add-DnsClientNrptRule -Namespace '.%s','.%s' -NameServers '%s' -Comment '%s'", DNSDomain, reverseDomain(ip), ip, nrptComment)
+	if err := run("powershell", "-NoProfile", "-NonInteractive", "-Command", add); err != nil {
+		return nil, err
+	}
+	return removeNRPTRules, nil
+}
+
+// removeNRPTRules apaga as regras do NRPT criadas pelo GoVPN
+func removeNRPTRules() {
+	remove := fmt.Sprintf("Get-DnsClientNrptRule | Where-Object Comment -eq '%s' | Remove-DnsClientNrptRule -Force", nrptComment)
+	run("powershell", "-NoProfile", "-NonInteractive", "-Command", remove)
+}
@@ -0,0 +1,128 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// O ping de um peer sob demanda, com a interface TUN, é um eco ICMP de verdade: o roteador
+// monta o pedido com o IP virtual deste computador como origem e o entrega ao peer como um
+// pacote qualquer da interface. O sistema do peer responde como a um ping comum, e a
+// resposta é separada aqui antes de chegar à interface, já que o sistema deste computador
+// não enviou o pedido. Sem resposta, o firewall do peer pode estar bloqueando o ICMP; o
+// Pinger.Probe confere então o canal de dados.
+
+const (
+	protocolICMP         = 1
+	icmpEchoReply        = 0
+	icmpEchoRequest      = 8
+	icmpHeaderSize       = 8
+	echoPayload          = "govpn"
+	echoTTL         byte = 64
+)
+
+// ErrEchoTimeout é retornado quando o peer não responde ao eco a tempo
+var ErrEchoTimeout = errors.New("no echo reply")
+
+// Echo envia um eco ICMP ao IP virtual de um peer pela interface e retorna o tempo de ida e
+// volta
+func (r *Router) Echo(peerIP string, timeout time.Duration) (time.Duration, error) {
+	dst, ok := r.routeKey(net.ParseIP(peerIP))
+	if !ok {
+		return 0, fmt.Errorf("invalid peer address %q", peerIP)
+	}
+	r.mu.RLock()
+	publicKey, ok := r.peers[dst]
+	r.mu.RUnlock()
+	if !ok {
+		return 0, fmt.Errorf("no peer at %s", peerIP)
+	}
+
+	reply := make(chan time.Time, 1)
+	r.echoMu.Lock()
+	r.echoSeq++
+	seq := r.echoSeq
+	r.echoWaiters[seq] = reply
+	r.echoMu.Unlock()
+	defer func() {
+		r.echoMu.Lock()
+		delete(r.echoWaiters, seq)
+		r.echoMu.Unlock()
+	}()
+
+	sent := time.Now()
+	packet := echoRequest(r.localIP, net.IP(dst[:]), r.echoID, seq)
+	if err := r.send(publicKey, EncodeFrame(FrameTypePacket, packet)); err != nil {
+		return 0, err
+	}
+
+	select {
+	case received := <-reply:
+		return received.Sub(sent), nil
+	case <-time.After(timeout):
+		return 0, ErrEchoTimeout
+	case <-r.done:
+		return 0, errors.New("tunnel closed")
+	}
+}
+
+// handleEchoReply entrega a resposta a um Echo, e diz se o pacote era uma
+func (r *Router) handleEchoReply(packet []byte) bool {
+	headerLen := int(packet[0]&0x0f) * 4
+	if packet[9] != protocolICMP || headerLen < ipv4HeaderSize || len(packet) < headerLen+icmpHeaderSize || !net.IP(packet[16:20]).Equal(r.localIP) {
+		return false
+	}
+	icmp := packet[headerLen:]
+	if icmp[0] != icmpEchoReply || binary.BigEndian.Uint16(icmp[4:6]) != r.echoID {
+		return false
+	}
+
+	r.echoMu.Lock()
+	reply, ok := r.echoWaiters[binary.BigEndian.Uint16(icmp[6:8])]
+	r.echoMu.Unlock()
+	if ok {
+		select {
+		case reply <- time.Now():
+		default:
+		}
+	}
+	return true
+}
+
+// echoRequest monta o pacote IPv4 de um pedido de eco ICMP
+func echoRequest(src, dst net.IP, id, seq uint16) []byte {
+	packet := make([]byte, ipv4HeaderSize+icmpHeaderSize+len(echoPayload))
+	packet[0] = 4<<4 | ipv4HeaderSize/4
+	binary.BigEndian.PutUint16(packet[2:4], uint16(len(packet)))
+	packet[8] = echoTTL
+	packet[9] = protocolICMP
+	copy(packet[12:16], src.To4())
+	copy(packet[16:20], dst.To4())
+	binary.BigEndian.PutUint16(packet[10:12], internetChecksum(packet[:ipv4HeaderSize]))
+
+	icmp := packet[ipv4HeaderSize:]
+	icmp[0] = icmpEchoRequest
+	binary.BigEndian.PutUint16(icmp[4:6], id)
+	binary.BigEndian.PutUint16(icmp[6:8], seq)
+	copy(icmp[icmpHeaderSize:], echoPayload)
+	binary.BigEndian.PutUint16(icmp[2:4], internetChecksum(icmp))
+	return packet
+}
+
+// internetChecksum é a soma de verificação do RFC 1071, usada pelo IPv4 e pelo ICMP
+func internetChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
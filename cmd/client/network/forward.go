@@ -0,0 +1,98 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PortForward leva uma porta local até a porta de um peer, no modo sem interface TUN
+type PortForward struct {
+	Protocol   string `json:"protocol"` // "tcp" ou "udp"
+	LocalPort  int    `json:"local_port"`
+	PeerIP     string `json:"peer_ip"` // IP virtual do peer, como 10.10.0.3
+	RemotePort int    `json:"remote_port"`
+}
+
+// SharedPort é uma porta deste computador que os peers podem alcançar pelo proxy
+type SharedPort struct {
+	Protocol string `json:"protocol"`
+	Port     int    `json:"port"`
+}
+
+// String formata o redirecionamento como "tcp 25565 10.10.0.3:25565"
+func (f PortForward) String() string {
+	return fmt.Sprintf("%s %d %s", f.Protocol, f.LocalPort, net.JoinHostPort(f.PeerIP, strconv.Itoa(f.RemotePort)))
+}
+
+// ParsePortForward lê um redirecionamento no formato de String
+func ParsePortForward(s string) (PortForward, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return PortForward{}, fmt.Errorf("port forward %q is not \"protocol local-port peer-ip:port\"", s)
+	}
+
+	protocol, err := parseProtocol(fields[0])
+	if err != nil {
+		return PortForward{}, err
+	}
+	localPort, err := parsePort(fields[1])
+	if err != nil {
+		return PortForward{}, err
+	}
+
+	host, port, err := net.SplitHostPort(fields[2])
+	if err != nil {
+		return PortForward{}, fmt.Errorf("invalid peer address %q: %w", fields[2], err)
+	}
+	if net.ParseIP(host).To4() == nil {
+		return PortForward{}, fmt.Errorf("invalid peer IP %q", host)
+	}
+	remotePort, err := parsePort(port)
+	if err != nil {
+		return PortForward{}, err
+	}
+
+	return PortForward{Protocol: protocol, LocalPort: localPort, PeerIP: host, RemotePort: remotePort}, nil
+}
+
+// String formata a porta como "tcp 25565"
+func (p SharedPort) String() string {
+	return fmt.Sprintf("%s %d", p.Protocol, p.Port)
+}
+
+// ParseSharedPort lê uma porta compartilhada no formato de String
+func ParseSharedPort(s string) (SharedPort, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return SharedPort{}, fmt.Errorf("shared port %q is not \"protocol port\"", s)
+	}
+
+	protocol, err := parseProtocol(fields[0])
+	if err != nil {
+		return SharedPort{}, err
+	}
+	port, err := parsePort(fields[1])
+	if err != nil {
+		return SharedPort{}, err
+	}
+
+	return SharedPort{Protocol: protocol, Port: port}, nil
+}
+
+func parseProtocol(s string) (string, error) {
+	protocol := strings.ToLower(s)
+	if protocol != "tcp" && protocol != "udp" {
+		return "", fmt.Errorf("unknown protocol %q, use tcp or udp", s)
+	}
+	return protocol, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
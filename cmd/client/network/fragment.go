@@ -0,0 +1,131 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Frames maiores que o MTU do caminho seguem em até maxFragments pedaços, cada um com o
+// identificador do frame, sua posição e o total. Pedaços de frames incompletos são
+// descartados depois de fragmentTimeout.
+const (
+	fragmentHeaderSize = 4 + 1 + 1
+	maxFragments       = 64
+	maxPendingFrames   = 256
+	fragmentTimeout    = 5 * time.Second
+)
+
+// ErrFrameTooLarge é retornado ao fragmentar um frame que não cabe em maxFragments pedaços
+var ErrFrameTooLarge = errors.New("frame too large to fragment")
+
+// fragmentID numera os frames fragmentados enviados por este computador
+var fragmentID atomic.Uint32
+
+// Fragment divide um frame em frames FrameTypeFragment de no máximo size bytes cada
+func Fragment(frame []byte, size int) ([][]byte, error) {
+	chunk := size - frameHeaderSize - fragmentHeaderSize
+	if chunk <= 0 {
+		return nil, fmt.Errorf("MTU %d too small to fragment", size)
+	}
+	count := (len(frame) + chunk - 1) / chunk
+	if count > maxFragments {
+		return nil, ErrFrameTooLarge
+	}
+
+	id := fragmentID.Add(1)
+	fragments := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		part := frame[i*chunk : min((i+1)*chunk, len(frame))]
+		payload := make([]byte, fragmentHeaderSize+len(part))
+		binary.BigEndian.PutUint32(payload, id)
+		payload[4] = byte(i)
+		payload[5] = byte(count)
+		copy(payload[fragmentHeaderSize:], part)
+		fragments = append(fragments, EncodeFrame(FrameTypeFragment, payload))
+	}
+	return fragments, nil
+}
+
+// pendingFrame junta os pedaços recebidos de um frame
+type pendingFrame struct {
+	parts    [][]byte
+	received int
+	started  time.Time
+}
+
+// fragmentKey identifica um frame fragmentado pelo peer que o enviou
+type fragmentKey struct {
+	peer string
+	id   uint32
+}
+
+// Defragmenter remonta os frames fragmentados recebidos dos peers
+type Defragmenter struct {
+	mu      sync.Mutex
+	pending map[fragmentKey]*pendingFrame
+}
+
+// NewDefragmenter cria um remontador vazio
+func NewDefragmenter() *Defragmenter {
+	return &Defragmenter{pending: make(map[fragmentKey]*pendingFrame)}
+}
+
+// Add guarda o payload de um FrameTypeFragment e retorna o frame inteiro quando o último
+// pedaço chega, ou nil enquanto faltam pedaços
+func (d *Defragmenter) Add(peerPublicKey string, payload []byte) ([]byte, error) {
+	if len(payload) <= fragmentHeaderSize {
+		return nil, errors.New("malformed fragment")
+	}
+	id := binary.BigEndian.Uint32(payload)
+	index, count := int(payload[4]), int(payload[5])
+	if count == 0 || count > maxFragments || index >= count {
+		return nil, fmt.Errorf("invalid fragment %d of %d", index, count)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.expire(now)
+
+	key := fragmentKey{peer: peerPublicKey, id: id}
+	frame, ok := d.pending[key]
+	if !ok {
+		if len(d.pending) >= maxPendingFrames {
+			return nil, errors.New("too many incomplete fragmented frames")
+		}
+		frame = &pendingFrame{parts: make([][]byte, count), started: now}
+		d.pending[key] = frame
+	}
+	if len(frame.parts) != count {
+		return nil, fmt.Errorf("fragment count %d differs from %d", count, len(frame.parts))
+	}
+	if frame.parts[index] != nil {
+		return nil, nil
+	}
+	frame.parts[index] = append([]byte(nil), payload[fragmentHeaderSize:]...)
+	frame.received++
+	if frame.received < count {
+		return nil, nil
+	}
+
+	delete(d.pending, key)
+	var whole []byte
+	for _, part := range frame.parts {
+		whole = append(whole, part...)
+	}
+	return whole, nil
+}
+
+// expire descarta os frames que não se completaram a tempo. Callers must hold d.mu.
+func (d *Defragmenter) expire(now time.Time) {
+	for key, frame := range d.pending {
+		if now.Sub(frame.started) >= fragmentTimeout {
+			delete(d.pending, key)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package network
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fragmentPayloads fragmenta frame e retorna os payloads dos pedaços
+func fragmentPayloads(t *testing.T, frame []byte, size int) [][]byte {
+	t.Helper()
+
+	fragments, err := Fragment(frame, size)
+	if err != nil {
+		t.Fatalf("fragment: %v", err)
+	}
+	payloads := make([][]byte, 0, len(fragments))
+	for _, fragment := range fragments {
+		if len(fragment) > size {
+			t.Fatalf("fragment of %d bytes, larger than %d", len(fragment), size)
+		}
+		frameType, payload, err := DecodeFrame(fragment)
+		if err != nil || frameType != FrameTypeFragment {
+			t.Fatalf("fragment decoded as %d (err %v)", frameType, err)
+		}
+		payloads = append(payloads, payload)
+	}
+	return payloads
+}
+
+func TestDefragmenterOutOfOrder(t *testing.T) {
+	frame := bytes.Repeat([]byte("0123456789"), 300)
+	payloads := fragmentPayloads(t, frame, 1000)
+	if len(payloads) != 4 {
+		t.Fatalf("fragments = %d, want 4", len(payloads))
+	}
+
+	d := NewDefragmenter()
+	for i := len(payloads) - 1; i > 0; i-- {
+		whole, err := d.Add("peer", payloads[i])
+		if err != nil || whole != nil {
+			t.Fatalf("fragment %d: got %d bytes (err %v) before the frame was complete", i, len(whole), err)
+		}
+		// Pedaços repetidos são ignorados
+		if whole, err := d.Add("peer", payloads[i]); err != nil || whole != nil {
+			t.Fatalf("repeated fragment %d: got %d bytes (err %v)", i, len(whole), err)
+		}
+	}
+
+	whole, err := d.Add("peer", payloads[0])
+	if err != nil {
+		t.Fatalf("last fragment: %v", err)
+	}
+	if !bytes.Equal(whole, frame) {
+		t.Errorf("reassembled %d bytes, want the original %d", len(whole), len(frame))
+	}
+}
+
+func TestDefragmenterSeparatesPeers(t *testing.T) {
+	frame := bytes.Repeat([]byte("x"), 1500)
+	payloads := fragmentPayloads(t, frame, 1000)
+
+	// O mesmo identificador vindo de outro peer é outro frame
+	d := NewDefragmenter()
+	if whole, _ := d.Add("peer-a", payloads[0]); whole != nil {
+		t.Fatal("frame complete after its first fragment")
+	}
+	if whole, _ := d.Add("peer-b", payloads[1]); whole != nil {
+		t.Fatal("fragments of different peers were joined")
+	}
+	if whole, _ := d.Add("peer-a", payloads[1]); !bytes.Equal(whole, frame) {
+		t.Errorf("reassembled %d bytes, want %d", len(whole), len(frame))
+	}
+}
+
+func TestDefragmenterRejectsInvalid(t *testing.T) {
+	d := NewDefragmenter()
+	for name, payload := range map[string][]byte{
+		"short":          {0, 0, 0, 1, 0},
+		"zero count":     {0, 0, 0, 1, 0, 0, 'x'},
+		"index too high": {0, 0, 0, 1, 2, 2, 'x'},
+		"too many":       {0, 0, 0, 1, 0, maxFragments + 1, 'x'},
+	} {
+		if _, err := d.Add("peer", payload); err == nil {
+			t.Errorf("%s fragment accepted", name)
+		}
+	}
+}
+
+func TestFragmentTooLarge(t *testing.T) {
+	if _, err := Fragment(make([]byte, 100*maxFragments), 100); err != ErrFrameTooLarge {
+		t.Errorf("error = %v, want %v", err, ErrFrameTooLarge)
+	}
+	if _, err := Fragment([]byte("x"), frameHeaderSize+fragmentHeaderSize); err == nil {
+		t.Error("fragmented with no room for data")
+	}
+}
@@ -0,0 +1,131 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Os frames viajam como mensagens binárias no canal de dados; as de texto continuam sendo do chat
+const (
+	frameVersion    byte = 1
+	frameHeaderSize      = 2
+)
+
+// FrameType identifica o conteúdo de um frame
+type FrameType byte
+
+const (
+	// FrameTypePacket carrega um pacote IP lido da interface TUN
+	FrameTypePacket FrameType = 1
+	// FrameTypeStreamOpen pede ao peer que conecte um fluxo a uma porta compartilhada
+	FrameTypeStreamOpen FrameType = 2
+	// FrameTypeStreamData carrega dados de um fluxo do proxy
+	FrameTypeStreamData FrameType = 3
+	// FrameTypeStreamClose encerra um fluxo do proxy
+	FrameTypeStreamClose FrameType = 4
+	// FrameTypeHandshake negocia as chaves de sessão com o peer, é o único enviado sem cifra
+	FrameTypeHandshake FrameType = 5
+	// FrameTypeSealed carrega um dos frames acima cifrado com a chave de sessão
+	FrameTypeSealed FrameType = 6
+	// FrameTypePing mede o enlace com o peer, que devolve o payload num FrameTypePong
+	FrameTypePing FrameType = 7
+	// FrameTypePong responde a um FrameTypePing
+	FrameTypePong FrameType = 8
+	// FrameTypeFragment carrega um pedaço de um frame cifrado maior que o MTU do caminho
+	FrameTypeFragment FrameType = 9
+	// FrameTypeMTUProbe testa se um frame do seu tamanho chega ao peer
+	FrameTypeMTUProbe FrameType = 10
+	// FrameTypeMTUAck confirma um FrameTypeMTUProbe recebido
+	FrameTypeMTUAck FrameType = 11
+	// FrameTypeFileOffer oferece um arquivo ao peer
+	FrameTypeFileOffer FrameType = 12
+	// FrameTypeFileAccept aceita um arquivo oferecido, a partir do que já foi recebido
+	FrameTypeFileAccept FrameType = 13
+	// FrameTypeFileData carrega um pedaço de um arquivo
+	FrameTypeFileData FrameType = 14
+	// FrameTypeFileClose encerra uma transferência, concluída ou não
+	FrameTypeFileClose FrameType = 15
+)
+
+// IsStream diz se o frame pertence ao proxy de portas e não à interface TUN
+func (t FrameType) IsStream() bool {
+	return t == FrameTypeStreamOpen || t == FrameTypeStreamData || t == FrameTypeStreamClose
+}
+
+// IsPing diz se o frame pertence à medição do enlace
+func (t FrameType) IsPing() bool {
+	return t == FrameTypePing || t == FrameTypePong
+}
+
+// protocolUDP é o número do UDP no campo de protocolo do cabeçalho IPv4
+const protocolUDP = 17
+
+// Unreliable diz se o frame pode seguir pelo canal sem retransmissão nem ordem: pacotes
+// UDP da interface TUN, que jogos e voz preferem perder a receber atrasados, e as sondas
+// de MTU, que medem esse canal. O resto, inclusive os fluxos do proxy, precisa chegar
+// inteiro e em ordem.
+func Unreliable(frame []byte) bool {
+	frameType, packet, err := DecodeFrame(frame)
+	if err != nil {
+		return false
+	}
+	if frameType == FrameTypeMTUProbe {
+		return true
+	}
+	if frameType != FrameTypePacket {
+		return false
+	}
+	return len(packet) >= ipv4HeaderSize && packet[0]>>4 == 4 && packet[9] == protocolUDP
+}
+
+// Background diz se o frame é um pacote da interface TUN para um grupo multicast ou um
+// broadcast, que os sistemas enviam sozinhos mesmo sem ninguém usar a rede
+func Background(frame []byte) bool {
+	frameType, packet, err := DecodeFrame(frame)
+	if err != nil || frameType != FrameTypePacket || len(packet) < ipv4HeaderSize {
+		return false
+	}
+	dst := packet[16:20]
+	return dst[0] >= 224 || dst[3] == 255
+}
+
+// IsMTU diz se o frame pertence à descoberta do MTU do caminho
+func (t FrameType) IsMTU() bool {
+	return t == FrameTypeMTUProbe || t == FrameTypeMTUAck
+}
+
+// IsFile diz se o frame pertence à transferência de arquivos
+func (t FrameType) IsFile() bool {
+	return t >= FrameTypeFileOffer && t <= FrameTypeFileClose
+}
+
+// IsFileTransfer diz se o frame deve seguir pelo canal dos arquivos, separado do canal
+// do resto para que um arquivo grande não atrase o tráfego da rede
+func IsFileTransfer(frame []byte) bool {
+	frameType, _, err := DecodeFrame(frame)
+	return err == nil && frameType.IsFile()
+}
+
+// ErrShortFrame é retornado para frames menores que o cabeçalho
+var ErrShortFrame = errors.New("frame shorter than its header")
+
+// EncodeFrame monta um frame com o cabeçalho de versão e tipo seguido do payload
+func EncodeFrame(frameType FrameType, payload []byte) []byte {
+	frame := make([]byte, frameHeaderSize+len(payload))
+	frame[0] = frameVersion
+	frame[1] = byte(frameType)
+	copy(frame[frameHeaderSize:], payload)
+	return frame
+}
+
+// DecodeFrame separa o tipo e o payload de um frame recebido.
+// O payload aponta para o mesmo buffer do frame.
+func DecodeFrame(frame []byte) (FrameType, []byte, error) {
+	if len(frame) < frameHeaderSize {
+		return 0, nil, ErrShortFrame
+	}
+	if frame[0] != frameVersion {
+		return 0, nil, fmt.Errorf("unsupported frame version %d", frame[0])
+	}
+	return FrameType(frame[1]), frame[frameHeaderSize:], nil
+}
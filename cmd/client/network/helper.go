@@ -0,0 +1,323 @@
+package network
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// O helper privilegiado é quem cria a interface TUN; a interface gráfica roda sem privilégios
+// e fala com ele por um socket local. Cada conexão abre uma interface, que fecha junto com ela.
+// Depois da resposta de abertura a conexão só transporta pacotes, cada um precedido do tamanho.
+// Só os membros do grupo HelperGroup alcançam o socket (no Windows, os usuários interativos), e
+// cada conexão é identificada pelo usuário do outro lado para que um não derrube a do outro.
+
+// ErrHelperUnavailable é retornado quando o helper não está instalado ou não está rodando
+var ErrHelperUnavailable = errors.New("privileged helper is not running")
+
+const (
+	// helperDialTimeout limita a espera pelo helper
+	helperDialTimeout = 2 * time.Second
+	// maxHelperApps limita os programas de um pedido
+	maxHelperApps = 64
+)
+
+// helperOpenRequest pede ao helper uma interface TUN
+type helperOpenRequest struct {
+	Address   string `json:"address"`
+	MTU       int    `json:"mtu,omitempty"`
+	Broadcast bool   `json:"broadcast,omitempty"`
+
+	Routes    []string `json:"routes,omitempty"`
+	Apps      []string `json:"apps,omitempty"`
+	DNS       bool     `json:"dns,omitempty"`
+	Discovery bool     `json:"discovery,omitempty"`
+}
+
+// helperOpenResponse responde a abertura com o nome da interface ou o erro
+type helperOpenResponse struct {
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// helperSession é a interface aberta para um usuário
+type helperSession struct {
+	conn   net.Conn
+	caller string
+	done   chan struct{} // Fechado quando a interface da sessão já foi fechada
+}
+
+// ServeHelper atende a interface gráfica em ln até ele ser fechado.
+// Só uma interface fica aberta por vez: uma nova conexão do mesmo usuário fecha a anterior, e
+// a de outro usuário é recusada enquanto ela estiver aberta.
+func ServeHelper(ln net.Listener) error {
+	var (
+		mu      sync.Mutex
+		current *helperSession
+	)
+
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			caller, err := helperCaller(conn)
+			if err != nil {
+				logger.Warn("Cannot identify helper client", "error", err)
+				writeMessage(conn, helperOpenResponse{Error: "cannot identify the caller"})
+				conn.Close()
+				return
+			}
+
+			mu.Lock()
+			previous := current
+			if previous != nil && previous.caller != caller {
+				mu.Unlock()
+				logger.Warn("Refused helper client while another user has the TUN device open", "client", caller, "owner", previous.caller)
+				writeMessage(conn, helperOpenResponse{Error: "the virtual network interface is in use by another user"})
+				conn.Close()
+				return
+			}
+			session := &helperSession{conn: conn, caller: caller, done: make(chan struct{})}
+			current = session
+			mu.Unlock()
+
+			// A interface anterior precisa sumir antes de a nova ser conferida com as redes locais
+			if previous != nil {
+				previous.conn.Close()
+				<-previous.done
+			}
+
+			serveHelperConn(conn, caller)
+			close(session.done)
+
+			mu.Lock()
+			if current == session {
+				current = nil
+			}
+			mu.Unlock()
+		}()
+	}
+}
+
+// serveHelperConn abre a interface pedida e troca pacotes com a conexão até ela fechar
+func serveHelperConn(conn net.Conn, caller string) {
+	defer conn.Close()
+
+	var req helperOpenRequest
+	if err := readMessage(conn, &req); err != nil {
+		logger.Warn("Invalid helper request", "client", caller, "error", err)
+		return
+	}
+	if err := validateHelperRequest(req); err != nil {
+		logger.Warn("Refused helper request", "client", caller, "address", req.Address, "error", err)
+		writeMessage(conn, helperOpenResponse{Error: err.Error()})
+		return
+	}
+
+	dev, err := OpenDevice(Config{Address: req.Address, MTU: req.MTU, Broadcast: req.Broadcast, Routes: req.Routes, Apps: req.Apps, DNS: req.DNS, Discovery: req.Discovery})
+	if err != nil {
+		writeMessage(conn, helperOpenResponse{Error: err.Error()})
+		return
+	}
+	defer dev.Close()
+
+	if err := writeMessage(conn, helperOpenResponse{Name: dev.Name()}); err != nil {
+		return
+	}
+	logger.Info("Opened TUN device", "device", dev.Name(), "address", req.Address, "client", caller)
+
+	// Pacotes da interface seguem para a conexão
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, err := dev.Read(buf)
+			if err != nil {
+				conn.Close()
+				return
+			}
+			if err := writePacket(conn, buf[:n]); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Pacotes da conexão são escritos na interface
+	buf := make([]byte, 65535)
+	for {
+		n, err := readPacket(conn, buf)
+		if err != nil {
+			logger.Info("Closing TUN device", "device", dev.Name(), "reason", err)
+			return
+		}
+		if _, err := dev.Write(buf[:n]); err != nil {
+			logger.Debug("Failed to write packet", "device", dev.Name(), "error", err)
+		}
+	}
+}
+
+// validateHelperRequest confere o pedido de abertura. O helper não confia no cliente: todo
+// usuário do grupo do helper fala com ele, então o que ele pede é conferido de novo aqui.
+func validateHelperRequest(req helperOpenRequest) error {
+	// Só a faixa de onde o servidor tira as sub-redes das redes é aceita
+	if ip := net.ParseIP(req.Address).To4(); ip == nil || !vpnRange.Contains(ip) {
+		return fmt.Errorf("address %q is outside %s", req.Address, vpnRange)
+	}
+	if req.MTU != 0 && (req.MTU < 576 || req.MTU > DefaultMTU) {
+		return fmt.Errorf("MTU %d out of range", req.MTU)
+	}
+
+	// A sub-rede não pode tomar o lugar de uma rede local, o que desviaria para a interface o
+	// tráfego da LAN, de outra VPN ou da interface de outro usuário
+	locals, err := LocalNetworks()
+	if err != nil {
+		logger.Debug("Checking subnet conflicts without the route table", "error", err)
+	}
+	if conflicts := SubnetConflicts(req.Address, locals); len(conflicts) > 0 {
+		return fmt.Errorf("the subnet of %s overlaps the local network %s", req.Address, conflicts[0])
+	}
+
+	// As rotas só podem ser de multicast e broadcast
+	for _, route := range req.Routes {
+		if _, err := ParseTunnelRoute(route); err != nil {
+			return err
+		}
+	}
+
+	// Os programas precisam ser executáveis que existem, pelo caminho completo
+	if len(req.Apps) > maxHelperApps {
+		return fmt.Errorf("too many programs (%d, at most %d)", len(req.Apps), maxHelperApps)
+	}
+	for _, app := range req.Apps {
+		path, err := ParseTunnelApp(app)
+		if err != nil {
+			return err
+		}
+		if path != app {
+			return fmt.Errorf("program %q is not a clean full path", app)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("program %q: %w", app, err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("program %q is not a file", app)
+		}
+	}
+
+	// DNS não traz dados do cliente: o resolvedor do sistema só manda à própria interface,
+	// no endereço conferido acima, os nomes .govpn e a zona reversa da sub-rede
+	return nil
+}
+
+// helperDevice é uma interface TUN aberta pelo helper
+type helperDevice struct {
+	conn    net.Conn
+	name    string
+	writeMu sync.Mutex
+}
+
+// OpenHelperDevice pede a interface TUN ao helper privilegiado.
+// Retorna ErrHelperUnavailable quando não há helper, para a interface ser criada diretamente.
+func OpenHelperDevice(cfg Config) (Device, error) {
+	conn, err := dialHelper()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrHelperUnavailable, err)
+	}
+
+	if err := writeMessage(conn, helperOpenRequest{Address: cfg.Address, MTU: cfg.MTU, Broadcast: cfg.Broadcast, Routes: cfg.Routes, Apps: cfg.Apps, DNS: cfg.DNS, Discovery: cfg.Discovery}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send request to helper: %w", err)
+	}
+
+	var resp helperOpenResponse
+	if err := readMessage(conn, &resp); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read helper response: %w", err)
+	}
+	if resp.Error != "" {
+		conn.Close()
+		return nil, fmt.Errorf("helper failed to create TUN device: %s", resp.Error)
+	}
+
+	return &helperDevice{conn: conn, name: resp.Name}, nil
+}
+
+func (d *helperDevice) Name() string {
+	return d.name
+}
+
+func (d *helperDevice) Read(packet []byte) (int, error) {
+	return readPacket(d.conn, packet)
+}
+
+func (d *helperDevice) Write(packet []byte) (int, error) {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	if err := writePacket(d.conn, packet); err != nil {
+		return 0, err
+	}
+	return len(packet), nil
+}
+
+func (d *helperDevice) Close() error {
+	return d.conn.Close()
+}
+
+// writePacket escreve um pacote precedido do tamanho em dois bytes
+func writePacket(w io.Writer, packet []byte) error {
+	if len(packet) > 0xffff {
+		return fmt.Errorf("packet of %d bytes is too large", len(packet))
+	}
+	buf := make([]byte, 2+len(packet))
+	binary.BigEndian.PutUint16(buf, uint16(len(packet)))
+	copy(buf[2:], packet)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readPacket lê um pacote escrito por writePacket
+func readPacket(r io.Reader, buf []byte) (int, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return 0, err
+	}
+	n := int(binary.BigEndian.Uint16(size[:]))
+	if n > len(buf) {
+		return 0, fmt.Errorf("packet of %d bytes does not fit the buffer", n)
+	}
+	return io.ReadFull(r, buf[:n])
+}
+
+// writeMessage escreve uma mensagem JSON como um pacote
+func writeMessage(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writePacket(w, data)
+}
+
+// readMessage lê uma mensagem escrita por writeMessage
+func readMessage(r io.Reader, v any) error {
+	buf := make([]byte, 4096)
+	n, err := readPacket(r, buf)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf[:n], v)
+}
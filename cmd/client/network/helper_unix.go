@@ -0,0 +1,84 @@
+//go:build !windows
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+const (
+	// HelperSocketPath é onde o helper privilegiado escuta
+	HelperSocketPath = "/var/run/govpn-helper.sock"
+	// HelperGroup é o grupo cujos membros podem usar o helper
+	HelperGroup = "govpn"
+)
+
+// ListenHelper abre o socket do helper, acessível ao root e aos membros de HelperGroup.
+// Sem o grupo, só o root alcança o socket.
+func ListenHelper() (net.Listener, error) {
+	if err := os.Remove(HelperSocketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", HelperSocketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := os.FileMode(0600)
+	if group, err := user.LookupGroup(HelperGroup); err != nil {
+		logger.Warn("Helper group not found, only root can use the helper", "group", HelperGroup, "error", err)
+	} else {
+		gid, err := strconv.Atoi(group.Gid)
+		if err == nil {
+			err = os.Chown(HelperSocketPath, -1, gid)
+		}
+		if err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("giving the socket to group %s: %w", HelperGroup, err)
+		}
+		mode = 0660
+	}
+	if err := os.Chmod(HelperSocketPath, mode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+func dialHelper() (net.Conn, error) {
+	return net.DialTimeout("unix", HelperSocketPath, helperDialTimeout)
+}
+
+// helperCaller identifica o usuário do outro lado do socket pelo UID
+func helperCaller(conn net.Conn) (string, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return "", fmt.Errorf("not a UNIX socket: %T", conn)
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		uid    uint32
+		uidErr error
+	)
+	if err := raw.Control(func(fd uintptr) {
+		uid, uidErr = peerUID(int(fd))
+	}); err != nil {
+		return "", err
+	}
+	if uidErr != nil {
+		return "", fmt.Errorf("reading peer credentials: %w", uidErr)
+	}
+	return "uid " + strconv.FormatUint(uint64(uid), 10), nil
+}
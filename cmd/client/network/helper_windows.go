@@ -0,0 +1,56 @@
+package network
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Microsoft/go-winio"
+	"golang.org/x/sys/windows"
+)
+
+// HelperPipePath é o named pipe onde o helper privilegiado escuta
+const HelperPipePath = `\\.\pipe\govpn-helper`
+
+// helperPipeSecurity dá acesso total ao sistema e leitura e escrita aos usuários interativos;
+// cada conexão é identificada pelo token do processo cliente
+const helperPipeSecurity = "D:P(A;;GA;;;SY)(A;;GRGW;;;IU)"
+
+// ListenHelper abre o named pipe do helper
+func ListenHelper() (net.Listener, error) {
+	return winio.ListenPipe(HelperPipePath, &winio.PipeConfig{SecurityDescriptor: helperPipeSecurity})
+}
+
+func dialHelper() (net.Conn, error) {
+	timeout := helperDialTimeout
+	return winio.DialPipe(HelperPipePath, &timeout)
+}
+
+// helperCaller identifica o usuário do outro lado do pipe pelo SID do token do processo
+func helperCaller(conn net.Conn) (string, error) {
+	pipe, ok := conn.(interface{ Fd() uintptr })
+	if !ok {
+		return "", fmt.Errorf("not a named pipe: %T", conn)
+	}
+
+	var pid uint32
+	if err := windows.GetNamedPipeClientProcessId(windows.Handle(pipe.Fd()), &pid); err != nil {
+		return "", fmt.Errorf("reading the client process: %w", err)
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", fmt.Errorf("opening client process %d: %w", pid, err)
+	}
+	defer windows.CloseHandle(process)
+
+	var token windows.Token
+	if err := windows.OpenProcessToken(process, windows.TOKEN_QUERY, &token); err != nil {
+		return "", fmt.Errorf("opening the token of process %d: %w", pid, err)
+	}
+	defer token.Close()
+
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return "", fmt.Errorf("reading the token of process %d: %w", pid, err)
+	}
+	return user.User.Sid.String(), nil
+}
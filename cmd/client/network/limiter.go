@@ -0,0 +1,62 @@
+package network
+
+import (
+	"sync"
+	"time"
+)
+
+// minBucketBurst é a menor rajada de um balde, para um frame do tamanho máximo sempre caber
+// mesmo nos limites mais baixos
+const minBucketBurst = 64 * 1024
+
+// TokenBucket limita uma taxa de envio: as fichas, em bytes, enchem o balde na taxa
+// configurada até a rajada de um décimo de segundo, e cada envio tira o seu tamanho. O
+// balde pode ficar devendo, e quem envia espera a dívida ser paga. Um balde nil não limita.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // Bytes por segundo
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket cria um balde cheio que limita o envio a bytesPerSecond
+func NewTokenBucket(bytesPerSecond int) *TokenBucket {
+	rate := float64(bytesPerSecond)
+	burst := max(rate/10, minBucketBurst)
+	return &TokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// refill soma as fichas acumuladas desde a última chamada; o chamador segura mu
+func (b *TokenBucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// Delay diz quanto tempo falta para n bytes caberem no balde, sem tirá-los
+func (b *TokenBucket) Delay(n int) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	missing := float64(n) - b.tokens
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / b.rate * float64(time.Second))
+}
+
+// Take tira n bytes do balde, mesmo que ele fique devendo
+func (b *TokenBucket) Take(n int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	b.tokens -= float64(n)
+}
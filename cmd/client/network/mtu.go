@@ -0,0 +1,214 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+)
+
+// Descoberta do MTU do caminho: cada peer recebe sondas cifradas dos tamanhos de
+// mtuProbeSizes, da maior para a menor, e o maior tamanho confirmado passa a ser o limite
+// dos frames enviados a ele. Sem confirmação depois de mtuProbeRounds rodadas vale o menor.
+const (
+	mtuProbeRounds = 2
+	mtuProbeHeader = 4
+)
+
+// mtuProbeSizes são os tamanhos sondados, já cifrados: um pacote inteiro da interface TUN
+// e os limites comuns de túneis e enlaces móveis até o mínimo do IPv4
+var mtuProbeSizes = []int{DefaultMTU + frameHeaderSize + SealOverhead, 1200, 1024, 576}
+
+// PacketMTU é o maior pacote IP que cabe num frame cifrado de pathMTU bytes sem fragmentar
+func PacketMTU(pathMTU int) int {
+	return pathMTU - SealOverhead - frameHeaderSize
+}
+
+// mtuState acompanha a sondagem de um peer
+type mtuState struct {
+	seq      uint32
+	inFlight map[uint32]int // Tamanho de cada sonda sem confirmação
+	best     int
+	rounds   int
+	resolved bool
+}
+
+// MTUProber descobre o MTU do caminho até cada peer com sondas sobre o canal sem
+// retransmissão, o mesmo dos pacotes UDP, que é onde um frame grande some sem aviso
+type MTUProber struct {
+	send     SendFunc
+	onResult func(peerPublicKey string, pathMTU int)
+
+	mu    sync.Mutex
+	peers map[string]*mtuState
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewMTUProber cria o sondador. send deve recusar com ErrPeerUnreachable os peers ainda
+// sem canal; onResult recebe o MTU de cada peer assim que ele é descoberto.
+func NewMTUProber(send SendFunc, onResult func(peerPublicKey string, pathMTU int)) *MTUProber {
+	return &MTUProber{
+		send:     send,
+		onResult: onResult,
+		peers:    make(map[string]*mtuState),
+		done:     make(chan struct{}),
+	}
+}
+
+// SetPeers define os peers sondados, mantendo o MTU dos que continuam
+func (p *MTUProber) SetPeers(peerPublicKeys []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	peers := make(map[string]*mtuState, len(peerPublicKeys))
+	for _, key := range peerPublicKeys {
+		if state, ok := p.peers[key]; ok {
+			peers[key] = state
+		} else {
+			peers[key] = &mtuState{inFlight: make(map[uint32]int)}
+		}
+	}
+	p.peers = peers
+}
+
+// Reset volta a sondar um peer, quando o caminho até ele muda
+func (p *MTUProber) Reset(peerPublicKey string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.peers[peerPublicKey]; ok {
+		p.peers[peerPublicKey] = &mtuState{inFlight: make(map[uint32]int)}
+	}
+}
+
+// Run sonda os peers de MTU ainda desconhecido a cada PingInterval até Close
+func (p *MTUProber) Run() {
+	ticker := time.NewTicker(PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.round()
+		}
+	}
+}
+
+// round encerra a sondagem dos peers que esgotaram as rodadas e envia uma sonda de cada
+// tamanho aos demais
+func (p *MTUProber) round() {
+	p.mu.Lock()
+	probes := make(map[string]map[uint32]int)
+	for key, state := range p.peers {
+		if state.resolved {
+			continue
+		}
+		if state.rounds >= mtuProbeRounds {
+			p.resolve(key, state, max(state.best, mtuProbeSizes[len(mtuProbeSizes)-1]))
+			continue
+		}
+		// Registradas antes de enviar, a confirmação pode chegar antes de send retornar
+		state.inFlight = make(map[uint32]int, len(mtuProbeSizes))
+		for _, size := range mtuProbeSizes {
+			state.seq++
+			state.inFlight[state.seq] = size
+		}
+		probes[key] = state.inFlight
+	}
+	p.mu.Unlock()
+
+	for key, inFlight := range probes {
+		sent := true
+		for seq, size := range inFlight {
+			payload := make([]byte, size-SealOverhead-frameHeaderSize)
+			binary.BigEndian.PutUint32(payload, seq)
+			if err := p.send(key, EncodeFrame(FrameTypeMTUProbe, payload)); errors.Is(err, ErrPeerUnreachable) {
+				// Sem caminho até o peer a rodada não conta
+				sent = false
+				break
+			}
+		}
+		if sent {
+			p.mu.Lock()
+			if state, ok := p.peers[key]; ok && !state.resolved {
+				state.rounds++
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// HandleFrame confirma as sondas de um peer e registra as confirmações que ele devolve
+func (p *MTUProber) HandleFrame(peerPublicKey string, frame []byte) error {
+	frameType, payload, err := DecodeFrame(frame)
+	if err != nil {
+		return err
+	}
+	if len(payload) < mtuProbeHeader {
+		return errors.New("malformed MTU probe")
+	}
+
+	switch frameType {
+	case FrameTypeMTUProbe:
+		return p.send(peerPublicKey, EncodeFrame(FrameTypeMTUAck, payload[:mtuProbeHeader]))
+	case FrameTypeMTUAck:
+		p.handleAck(peerPublicKey, binary.BigEndian.Uint32(payload))
+		return nil
+	default:
+		return errors.New("not an MTU frame")
+	}
+}
+
+// handleAck guarda o tamanho confirmado e encerra a sondagem quando o maior deles chega
+func (p *MTUProber) handleAck(peerPublicKey string, seq uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.peers[peerPublicKey]
+	if !ok || state.resolved {
+		return
+	}
+	size, ok := state.inFlight[seq]
+	if !ok {
+		return
+	}
+	delete(state.inFlight, seq)
+	state.best = max(state.best, size)
+	if size == mtuProbeSizes[0] {
+		p.resolve(peerPublicKey, state, size)
+	}
+}
+
+// resolve fixa o MTU de um peer. Callers must hold p.mu.
+func (p *MTUProber) resolve(peerPublicKey string, state *mtuState, pathMTU int) {
+	state.best = pathMTU
+	state.resolved = true
+	state.inFlight = nil
+	if p.onResult != nil {
+		crash.Go("path MTU result", func() { p.onResult(peerPublicKey, pathMTU) })
+	}
+}
+
+// MTU retorna o maior frame cifrado que chega ao peer, ou 0 enquanto ele é desconhecido
+func (p *MTUProber) MTU(peerPublicKey string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if state, ok := p.peers[peerPublicKey]; ok && state.resolved {
+		return state.best
+	}
+	return 0
+}
+
+// Close para a sondagem
+func (p *MTUProber) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+}
@@ -0,0 +1,111 @@
+package network
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// WatchInterval é de quanto em quanto tempo os endereços locais são conferidos. Um
+// intervalo bem maior que ele entre duas conferências indica que o computador dormiu.
+const (
+	WatchInterval   = 3 * time.Second
+	sleepJumpFactor = 3
+)
+
+// NetworkWatcher avisa quando a rede local muda, como numa troca de Wi-Fi, ou quando o
+// computador volta da suspensão, para que as conexões com os peers procurem novos caminhos
+type NetworkWatcher struct {
+	onChange  func(reason string)
+	vpnSubnet *net.IPNet // Sub-rede da interface TUN, cujos endereços não contam como mudança de rede
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewNetworkWatcher cria o observador para a interface TUN com o endereço address; onChange
+// é chamado na goroutine de Run
+func NewNetworkWatcher(address string, onChange func(reason string)) *NetworkWatcher {
+	w := &NetworkWatcher{
+		onChange: onChange,
+		done:     make(chan struct{}),
+	}
+	if ip := net.ParseIP(address).To4(); ip != nil {
+		w.vpnSubnet = &net.IPNet{IP: ip.Mask(net.CIDRMask(DefaultPrefixLen, 32)), Mask: net.CIDRMask(DefaultPrefixLen, 32)}
+	}
+	return w
+}
+
+// Run confere os endereços a cada WatchInterval até Close
+func (w *NetworkWatcher) Run() {
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+
+	addresses, err := localAddresses(w.vpnSubnet)
+	if err != nil {
+		logger.Warn("Cannot list local addresses", "error", err)
+	}
+	previous := time.Now()
+	for {
+		select {
+		case <-w.done:
+			return
+		case now := <-ticker.C:
+			// Round(0) tira a leitura monotônica, que para durante a suspensão em alguns sistemas
+			slept := now.Round(0).Sub(previous.Round(0)) > sleepJumpFactor*WatchInterval
+			previous = now
+
+			current, err := localAddresses(w.vpnSubnet)
+			if err != nil {
+				logger.Warn("Cannot list local addresses", "error", err)
+				continue
+			}
+			switch {
+			case slept:
+				w.onChange("resumed from sleep")
+			case current != addresses:
+				w.onChange("local addresses changed")
+			}
+			addresses = current
+		}
+	}
+}
+
+// Close para de observar
+func (w *NetworkWatcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+}
+
+// localAddresses lista os endereços das interfaces ativas, fora loopback e a própria VPN
+func localAddresses(vpnSubnet *net.IPNet) (string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+
+	var addresses []string
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLinkLocalUnicast() || vpnSubnet != nil && vpnSubnet.Contains(ipNet.IP) {
+				continue
+			}
+			addresses = append(addresses, iface.Name+" "+ipNet.IP.String())
+		}
+	}
+	slices.Sort(addresses)
+	return strings.Join(addresses, ","), nil
+}
@@ -0,0 +1,12 @@
+package network
+
+import "golang.org/x/sys/unix"
+
+// peerUID retorna o UID do processo do outro lado do socket
+func peerUID(fd int) (uint32, error) {
+	cred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return 0, err
+	}
+	return cred.Uid, nil
+}
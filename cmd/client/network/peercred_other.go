@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package network
+
+import "errors"
+
+// peerUID não tem implementação aqui, então o helper recusa todas as conexões
+func peerUID(fd int) (uint32, error) {
+	return 0, errors.New("peer credentials are not supported on this platform")
+}
@@ -0,0 +1,260 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Medição dos enlaces: um ping por peer a cada PingInterval, contado como perdido se o
+// pong não voltar em pingTimeout. A perda é calculada sobre os últimos pingWindow pings.
+const (
+	PingInterval = 5 * time.Second
+	pingTimeout  = 3 * time.Second
+	pingWindow   = 20
+	pingSize     = 4
+)
+
+// LinkStats é a qualidade medida do enlace com um peer
+type LinkStats struct {
+	RTT     time.Duration // Último tempo de ida e volta
+	Jitter  time.Duration // Variação média do RTT, como no RFC 3550
+	Loss    float64       // Fração dos últimos pings sem resposta, de 0 a 1
+	Samples int           // Quantos pings entraram na conta da perda
+}
+
+// pingState acompanha os pings de um peer
+type pingState struct {
+	seq      uint32
+	inFlight map[uint32]time.Time
+	probes   map[uint32]chan time.Time // Pings de Probe, fora da conta da perda
+	outcomes []bool                    // true para pings respondidos, os mais antigos primeiro
+	stats    LinkStats
+	measured bool
+}
+
+// record guarda o resultado de um ping e atualiza a perda
+func (p *pingState) record(answered bool) {
+	p.outcomes = append(p.outcomes, answered)
+	if len(p.outcomes) > pingWindow {
+		p.outcomes = p.outcomes[len(p.outcomes)-pingWindow:]
+	}
+
+	lost := 0
+	for _, ok := range p.outcomes {
+		if !ok {
+			lost++
+		}
+	}
+	p.stats.Loss = float64(lost) / float64(len(p.outcomes))
+	p.stats.Samples = len(p.outcomes)
+}
+
+// Pinger mede latência, jitter e perda com pings da aplicação sobre o canal de dados de
+// cada peer. Os peers sem canal estabelecido são pulados e não contam como perda.
+type Pinger struct {
+	send     SendFunc
+	onUpdate func(map[string]LinkStats)
+
+	mu    sync.Mutex
+	peers map[string]*pingState
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewPinger cria o medidor. send deve recusar com ErrPeerUnreachable os peers ainda sem
+// canal; onUpdate recebe as medições depois de cada rodada de pings.
+func NewPinger(send SendFunc, onUpdate func(map[string]LinkStats)) *Pinger {
+	return &Pinger{
+		send:     send,
+		onUpdate: onUpdate,
+		peers:    make(map[string]*pingState),
+		done:     make(chan struct{}),
+	}
+}
+
+// SetPeers define os peers medidos, mantendo as medições dos que continuam
+func (p *Pinger) SetPeers(peerPublicKeys []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	peers := make(map[string]*pingState, len(peerPublicKeys))
+	for _, key := range peerPublicKeys {
+		if state, ok := p.peers[key]; ok {
+			peers[key] = state
+		} else {
+			peers[key] = &pingState{inFlight: make(map[uint32]time.Time), probes: make(map[uint32]chan time.Time)}
+		}
+	}
+	p.peers = peers
+}
+
+// Run envia uma rodada de pings a cada PingInterval até Close
+func (p *Pinger) Run() {
+	ticker := time.NewTicker(PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.round()
+		}
+	}
+}
+
+// round conta os pings expirados como perdidos, pinga cada peer e publica as medições
+func (p *Pinger) round() {
+	now := time.Now()
+
+	p.mu.Lock()
+	pings := make(map[string][]byte, len(p.peers))
+	for key, state := range p.peers {
+		for seq, sent := range state.inFlight {
+			if now.Sub(sent) >= pingTimeout {
+				delete(state.inFlight, seq)
+				state.record(false)
+			}
+		}
+		// Registrado antes de enviar, o pong pode chegar antes de send retornar
+		state.seq++
+		state.inFlight[state.seq] = now
+		payload := make([]byte, pingSize)
+		binary.BigEndian.PutUint32(payload, state.seq)
+		pings[key] = payload
+	}
+	p.mu.Unlock()
+
+	for key, payload := range pings {
+		if err := p.send(key, EncodeFrame(FrameTypePing, payload)); err == nil {
+			continue
+		}
+		p.mu.Lock()
+		if state, ok := p.peers[key]; ok {
+			delete(state.inFlight, binary.BigEndian.Uint32(payload))
+		}
+		p.mu.Unlock()
+	}
+
+	if p.onUpdate != nil {
+		p.onUpdate(p.Stats())
+	}
+}
+
+// HandleFrame responde aos pings de um peer e mede os pongs que ele devolve
+func (p *Pinger) HandleFrame(peerPublicKey string, frame []byte) error {
+	frameType, payload, err := DecodeFrame(frame)
+	if err != nil {
+		return err
+	}
+	if len(payload) != pingSize {
+		return errors.New("malformed ping")
+	}
+
+	switch frameType {
+	case FrameTypePing:
+		return p.send(peerPublicKey, EncodeFrame(FrameTypePong, payload))
+	case FrameTypePong:
+		p.handlePong(peerPublicKey, binary.BigEndian.Uint32(payload), time.Now())
+		return nil
+	default:
+		return errors.New("not a ping frame")
+	}
+}
+
+// handlePong atualiza RTT, jitter e perda com a resposta a um ping
+func (p *Pinger) handlePong(peerPublicKey string, seq uint32, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.peers[peerPublicKey]
+	if !ok {
+		return
+	}
+	if probe, ok := state.probes[seq]; ok {
+		select {
+		case probe <- now:
+		default:
+		}
+		return
+	}
+	sent, ok := state.inFlight[seq]
+	if !ok {
+		// Pong atrasado de um ping já contado como perdido
+		return
+	}
+	delete(state.inFlight, seq)
+	state.record(true)
+
+	rtt := now.Sub(sent)
+	if state.measured {
+		diff := rtt - state.stats.RTT
+		if diff < 0 {
+			diff = -diff
+		}
+		state.stats.Jitter += (diff - state.stats.Jitter) / 16
+	}
+	state.stats.RTT = rtt
+	state.measured = true
+}
+
+// Probe envia um ping avulso a um peer e retorna o tempo de ida e volta, sem mexer nas
+// medições das rodadas
+func (p *Pinger) Probe(peerPublicKey string, timeout time.Duration) (time.Duration, error) {
+	pong := make(chan time.Time, 1)
+	p.mu.Lock()
+	state, ok := p.peers[peerPublicKey]
+	if !ok {
+		p.mu.Unlock()
+		return 0, ErrPeerUnreachable
+	}
+	state.seq++
+	seq := state.seq
+	state.probes[seq] = pong
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(state.probes, seq)
+		p.mu.Unlock()
+	}()
+
+	payload := make([]byte, pingSize)
+	binary.BigEndian.PutUint32(payload, seq)
+	sent := time.Now()
+	if err := p.send(peerPublicKey, EncodeFrame(FrameTypePing, payload)); err != nil {
+		return 0, err
+	}
+
+	select {
+	case received := <-pong:
+		return received.Sub(sent), nil
+	case <-time.After(timeout):
+		return 0, ErrEchoTimeout
+	case <-p.done:
+		return 0, errors.New("pinger closed")
+	}
+}
+
+// Stats retorna as medições dos peers que já responderam a algum ping
+func (p *Pinger) Stats() map[string]LinkStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make(map[string]LinkStats, len(p.peers))
+	for key, state := range p.peers {
+		if state.measured {
+			stats[key] = state.stats
+		}
+	}
+	return stats
+}
+
+// Close para as rodadas de pings
+func (p *Pinger) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+}
@@ -0,0 +1,372 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+const (
+	// streamChunkSize limita os dados de cada frame de fluxo
+	streamChunkSize = 16 * 1024
+	// udpIdleTimeout encerra as sessões UDP sem tráfego
+	udpIdleTimeout = 2 * time.Minute
+	// localDialTimeout limita a conexão com a porta compartilhada
+	localDialTimeout = 2 * time.Second
+	// streamReplyBit marca os frames enviados por quem aceitou o fluxo, cada lado numera os seus
+	streamReplyBit uint32 = 1 << 31
+)
+
+// streamKey identifica um fluxo: mine diz se foi este computador que o abriu
+type streamKey struct {
+	peer string
+	id   uint32
+	mine bool
+}
+
+// stream é uma conexão TCP ou sessão UDP local levada até um peer
+type stream struct {
+	key  streamKey
+	conn io.WriteCloser
+}
+
+// Proxy é o modo sem interface TUN: escuta portas locais e leva as conexões até portas
+// compartilhadas dos peers pelos canais de dados. Não exige privilégios de administrador.
+type Proxy struct {
+	send   SendFunc
+	shared map[SharedPort]bool
+
+	mu        sync.Mutex
+	peers     map[string]string // IP virtual -> chave pública
+	streams   map[streamKey]*stream
+	nextID    uint32
+	listeners []io.Closer
+	closed    bool
+}
+
+// NewProxy cria o proxy; os peers só alcançam as portas em shared
+func NewProxy(send SendFunc, shared []SharedPort) *Proxy {
+	p := &Proxy{
+		send:    send,
+		shared:  make(map[SharedPort]bool, len(shared)),
+		peers:   make(map[string]string),
+		streams: make(map[streamKey]*stream),
+	}
+	for _, port := range shared {
+		p.shared[port] = true
+	}
+	return p
+}
+
+// SetPeers substitui a tabela de IPs virtuais dos peers, de IP para chave pública
+func (p *Proxy) SetPeers(peers map[string]string) {
+	table := make(map[string]string, len(peers))
+	for ip, publicKey := range peers {
+		table[ip] = publicKey
+	}
+
+	p.mu.Lock()
+	p.peers = table
+	p.mu.Unlock()
+}
+
+// Listen abre as portas locais dos redirecionamentos em 127.0.0.1.
+// Um redirecionamento que falha não impede os outros; os erros são retornados juntos.
+func (p *Proxy) Listen(forwards []PortForward) error {
+	var errs []error
+	for _, forward := range forwards {
+		addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(forward.LocalPort))
+		switch forward.Protocol {
+		case "tcp":
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("forward %s: %w", forward, err))
+				continue
+			}
+			p.addListener(ln)
+			crash.Go("TCP forward", func() { p.serveTCP(ln, forward) })
+		case "udp":
+			conn, err := net.ListenPacket("udp", addr)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("forward %s: %w", forward, err))
+				continue
+			}
+			p.addListener(conn)
+			crash.Go("UDP forward", func() { p.serveUDP(conn.(*net.UDPConn), forward) })
+		default:
+			errs = append(errs, fmt.Errorf("forward %s: unknown protocol", forward))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Proxy) addListener(l io.Closer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.listeners = append(p.listeners, l)
+}
+
+// serveTCP abre um fluxo para cada conexão aceita na porta local
+func (p *Proxy) serveTCP(ln net.Listener, forward PortForward) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s, err := p.openStream(forward, conn)
+		if err != nil {
+			logger.Warn("Refusing forwarded connection", "forward", forward, "error", err)
+			conn.Close()
+			continue
+		}
+		crash.Go("TCP forward stream", func() { p.pump(s, conn, 0) })
+	}
+}
+
+// udpReply devolve os datagramas de um fluxo ao cliente local que abriu a sessão
+type udpReply struct {
+	conn *net.UDPConn
+	addr *net.UDPAddr
+}
+
+func (r udpReply) Write(b []byte) (int, error) {
+	return r.conn.WriteToUDP(b, r.addr)
+}
+
+// Close não fecha nada, o socket é compartilhado pelas sessões da porta
+func (r udpReply) Close() error {
+	return nil
+}
+
+// serveUDP abre um fluxo para cada endereço de origem que manda datagramas à porta local
+func (p *Proxy) serveUDP(conn *net.UDPConn, forward PortForward) {
+	sessions := make(map[string]*stream)
+	buf := make([]byte, 65535)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		s := sessions[from.String()]
+		if s == nil || !p.active(s) {
+			// Sessões encerradas pelo peer são esquecidas aqui
+			for addr, old := range sessions {
+				if !p.active(old) {
+					delete(sessions, addr)
+				}
+			}
+
+			s, err = p.openStream(forward, udpReply{conn: conn, addr: from})
+			if err != nil {
+				logger.Debug("Dropping forwarded datagram", "forward", forward, "error", err)
+				continue
+			}
+			sessions[from.String()] = s
+		}
+
+		if err := p.sendStream(s, FrameTypeStreamData, buf[:n]); err != nil {
+			p.closeStream(s, false)
+		}
+	}
+}
+
+// openStream registra um fluxo de saída e pede ao peer que conecte na porta remota
+func (p *Proxy) openStream(forward PortForward, conn io.WriteCloser) (*stream, error) {
+	p.mu.Lock()
+	publicKey, ok := p.peers[forward.PeerIP]
+	if !ok {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("no computer with address %s in the network", forward.PeerIP)
+	}
+	p.nextID = (p.nextID + 1) &^ streamReplyBit
+	s := &stream{key: streamKey{peer: publicKey, id: p.nextID, mine: true}, conn: conn}
+	p.streams[s.key] = s
+	p.mu.Unlock()
+
+	open := make([]byte, 3)
+	open[0] = protocolByte(forward.Protocol)
+	binary.BigEndian.PutUint16(open[1:], uint16(forward.RemotePort))
+	if err := p.sendStream(s, FrameTypeStreamOpen, open); err != nil {
+		p.closeStream(s, false)
+		return nil, err
+	}
+	return s, nil
+}
+
+// pump envia ao peer o que chega da conexão local até ela fechar ou ficar ociosa
+func (p *Proxy) pump(s *stream, conn net.Conn, idle time.Duration) {
+	buf := make([]byte, streamChunkSize)
+	for {
+		if idle > 0 {
+			conn.SetReadDeadline(time.Now().Add(idle))
+		}
+		n, err := conn.Read(buf)
+		if n > 0 {
+			if sendErr := p.sendStream(s, FrameTypeStreamData, buf[:n]); sendErr != nil {
+				p.closeStream(s, false)
+				return
+			}
+		}
+		if err != nil {
+			p.closeStream(s, true)
+			return
+		}
+	}
+}
+
+// HandleFrame trata um frame de fluxo recebido de peerPublicKey
+func (p *Proxy) HandleFrame(peerPublicKey string, frame []byte) error {
+	frameType, payload, err := DecodeFrame(frame)
+	if err != nil {
+		return err
+	}
+	if len(payload) < 4 {
+		return ErrShortFrame
+	}
+
+	wireID := binary.BigEndian.Uint32(payload)
+	key := streamKey{peer: peerPublicKey, id: wireID &^ streamReplyBit, mine: wireID&streamReplyBit != 0}
+	payload = payload[4:]
+
+	if frameType == FrameTypeStreamOpen {
+		if key.mine || len(payload) < 3 {
+			return fmt.Errorf("malformed stream open from %s", peerPublicKey)
+		}
+		p.acceptStream(key, protocolName(payload[0]), int(binary.BigEndian.Uint16(payload[1:])))
+		return nil
+	}
+
+	p.mu.Lock()
+	s := p.streams[key]
+	p.mu.Unlock()
+	if s == nil {
+		return nil
+	}
+
+	switch frameType {
+	case FrameTypeStreamData:
+		if _, err := s.conn.Write(payload); err != nil {
+			p.closeStream(s, true)
+		}
+	case FrameTypeStreamClose:
+		p.closeStream(s, false)
+	default:
+		return fmt.Errorf("unknown frame type %d", frameType)
+	}
+	return nil
+}
+
+// acceptStream conecta um fluxo aberto pelo peer à porta local, se ela for compartilhada
+func (p *Proxy) acceptStream(key streamKey, protocol string, port int) {
+	s := &stream{key: key}
+	if !p.shared[SharedPort{Protocol: protocol, Port: port}] {
+		logger.Warn("Peer asked for a port that is not shared", "peer", key.peer, "protocol", protocol, "port", port)
+		p.sendStream(s, FrameTypeStreamClose, nil)
+		return
+	}
+
+	conn, err := net.DialTimeout(protocol, net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), localDialTimeout)
+	if err != nil {
+		logger.Warn("Failed to reach shared port", "protocol", protocol, "port", port, "error", err)
+		p.sendStream(s, FrameTypeStreamClose, nil)
+		return
+	}
+	s.conn = conn
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		conn.Close()
+		return
+	}
+	p.streams[key] = s
+	p.mu.Unlock()
+
+	idle := time.Duration(0)
+	if protocol == "udp" {
+		idle = udpIdleTimeout
+	}
+	crash.Go("shared port stream", func() { p.pump(s, conn, idle) })
+}
+
+// sendStream envia um frame do fluxo s ao peer
+func (p *Proxy) sendStream(s *stream, frameType FrameType, data []byte) error {
+	wireID := s.key.id
+	if !s.key.mine {
+		wireID |= streamReplyBit
+	}
+
+	payload := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(payload, wireID)
+	copy(payload[4:], data)
+	return p.send(s.key.peer, EncodeFrame(frameType, payload))
+}
+
+// active diz se o fluxo ainda está aberto
+func (p *Proxy) active(s *stream) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.streams[s.key] == s
+}
+
+// closeStream fecha a conexão local do fluxo, avisando o peer quando notify é verdadeiro
+func (p *Proxy) closeStream(s *stream, notify bool) {
+	p.mu.Lock()
+	if p.streams[s.key] != s {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.streams, s.key)
+	p.mu.Unlock()
+
+	s.conn.Close()
+	if notify {
+		p.sendStream(s, FrameTypeStreamClose, nil)
+	}
+}
+
+// Close fecha as portas locais e todos os fluxos
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	listeners := p.listeners
+	p.listeners = nil
+	streams := p.streams
+	p.streams = make(map[streamKey]*stream)
+	p.mu.Unlock()
+
+	for _, l := range listeners {
+		l.Close()
+	}
+	for _, s := range streams {
+		s.conn.Close()
+		p.sendStream(s, FrameTypeStreamClose, nil)
+	}
+	return nil
+}
+
+func protocolByte(protocol string) byte {
+	if protocol == "udp" {
+		return 17
+	}
+	return 6
+}
+
+func protocolName(b byte) string {
+	if b == 17 {
+		return "udp"
+	}
+	return "tcp"
+}
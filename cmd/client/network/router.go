@@ -0,0 +1,237 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// ipv4HeaderSize é o tamanho mínimo do cabeçalho IPv4
+const ipv4HeaderSize = 20
+
+// ErrPeerUnreachable é retornado por SendFunc enquanto o canal de dados do peer não abriu
+var ErrPeerUnreachable = errors.New("peer is not reachable yet")
+
+// SendFunc entrega um frame ao peer dono da chave pública
+type SendFunc func(peerPublicKey string, frame []byte) error
+
+// Router liga a interface TUN aos peers: pacotes lidos da interface seguem para o peer
+// dono do IP de destino e os frames recebidos dos peers são escritos de volta na interface
+type Router struct {
+	dev     Device
+	localIP net.IP
+	subnet  *net.IPNet
+	send    SendFunc
+
+	// Com broadcast ligado, broadcasts e multicast vão para todos os peers; com groups,
+	// só os destinos dentro deles, além do broadcast da sub-rede
+	broadcast bool
+	groups    []*net.IPNet
+
+	// Com discovery ligado, os protocolos de descoberta vão para todos os peers (discovery.go)
+	discovery bool
+
+	mu    sync.RWMutex
+	peers map[[4]byte]string // IP virtual -> chave pública
+
+	// Ecos ICMP pedidos por Echo, esperando resposta pelo número de sequência (echo.go)
+	echoID      uint16
+	echoSeq     uint16
+	echoWaiters map[uint16]chan time.Time
+	echoMu      sync.Mutex
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewRouter cria o roteador da interface dev, cujo endereço é localIP na sub-rede padrão
+func NewRouter(dev Device, localIP string, send SendFunc) (*Router, error) {
+	ip := net.ParseIP(localIP).To4()
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IPv4 address %q", localIP)
+	}
+
+	mask := net.CIDRMask(DefaultPrefixLen, 32)
+	return &Router{
+		dev:     dev,
+		localIP: ip,
+		subnet:  &net.IPNet{IP: ip.Mask(mask), Mask: mask},
+		send:    send,
+		peers:   make(map[[4]byte]string),
+		done:    make(chan struct{}),
+
+		echoID:      uint16(rand.Uint32()),
+		echoWaiters: make(map[uint16]chan time.Time),
+	}, nil
+}
+
+// SetPeers substitui a tabela de rotas pelos IPs virtuais dos peers, de IP para chave pública
+func (r *Router) SetPeers(peers map[string]string) {
+	table := make(map[[4]byte]string, len(peers))
+	for ip, publicKey := range peers {
+		key, ok := r.routeKey(net.ParseIP(ip))
+		if !ok {
+			logger.Warn("Ignoring peer with an address outside the subnet", "peer", publicKey, "address", ip, "subnet", r.subnet)
+			continue
+		}
+		table[key] = publicKey
+	}
+
+	r.mu.Lock()
+	r.peers = table
+	r.mu.Unlock()
+}
+
+// EnableBroadcast replica para todos os peers os broadcasts da sub-rede, o broadcast
+// limitado e o multicast, como numa LAN. Com groups, o broadcast limitado e o multicast só
+// são replicados para os destinos dentro deles. Deve ser chamado antes de Run.
+func (r *Router) EnableBroadcast(groups []*net.IPNet) {
+	r.broadcast = true
+	r.groups = groups
+}
+
+// Run encaminha os pacotes lidos da interface até ela ser fechada
+func (r *Router) Run() {
+	buf := make([]byte, 65535)
+	for {
+		n, err := r.dev.Read(buf)
+		if err != nil {
+			select {
+			case <-r.done:
+			default:
+				if !errors.Is(err, os.ErrClosed) {
+					logger.Error("TUN device read failed", "device", r.dev.Name(), "error", err)
+				}
+			}
+			return
+		}
+		r.route(buf[:n])
+	}
+}
+
+// route envia um pacote lido da interface ao peer dono do destino
+func (r *Router) route(packet []byte) {
+	// Só IPv4: o sistema também manda IPv6 e multicast pela interface, que não têm para onde ir
+	if len(packet) < ipv4HeaderSize || packet[0]>>4 != 4 {
+		return
+	}
+
+	if (r.broadcast && r.isBroadcast(net.IP(packet[16:20]))) || (r.discovery && r.isDiscovery(packet)) {
+		r.replicate(packet)
+		return
+	}
+
+	dst, ok := r.routeKey(net.IP(packet[16:20]))
+	if !ok {
+		return
+	}
+
+	r.mu.RLock()
+	publicKey, ok := r.peers[dst]
+	r.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	// Sem canal aberto o pacote se perde, como numa rede real; a aplicação retransmite
+	if err := r.send(publicKey, EncodeFrame(FrameTypePacket, packet)); err != nil && !errors.Is(err, ErrPeerUnreachable) {
+		logger.Debug("Dropping packet", "destination", net.IP(packet[16:20]), "error", err)
+	}
+}
+
+// isBroadcast diz se dst é o broadcast da sub-rede, o broadcast limitado ou um grupo multicast
+// permitido
+func (r *Router) isBroadcast(dst net.IP) bool {
+	if dst.IsMulticast() || dst.Equal(net.IPv4bcast) {
+		return len(r.groups) == 0 || slices.ContainsFunc(r.groups, func(group *net.IPNet) bool {
+			return group.Contains(dst)
+		})
+	}
+	return dst.Equal(r.subnetBroadcast())
+}
+
+// subnetBroadcast é o último endereço da sub-rede, como 10.10.0.255
+func (r *Router) subnetBroadcast() net.IP {
+	last := make(net.IP, net.IPv4len)
+	for i := range last {
+		last[i] = r.subnet.IP[i] | ^r.subnet.Mask[i]
+	}
+	return last
+}
+
+// replicate envia uma cópia do pacote a cada peer
+func (r *Router) replicate(packet []byte) {
+	r.mu.RLock()
+	peers := make([]string, 0, len(r.peers))
+	for _, publicKey := range r.peers {
+		peers = append(peers, publicKey)
+	}
+	r.mu.RUnlock()
+
+	frame := EncodeFrame(FrameTypePacket, packet)
+	for _, publicKey := range peers {
+		if err := r.send(publicKey, frame); err != nil && !errors.Is(err, ErrPeerUnreachable) {
+			logger.Debug("Dropping broadcast", "peer", publicKey, "error", err)
+		}
+	}
+}
+
+// HandleFrame escreve na interface o pacote de um frame recebido de peerPublicKey.
+// Pacotes cuja origem não é o IP do peer são descartados, um peer não fala pelos outros.
+func (r *Router) HandleFrame(peerPublicKey string, frame []byte) error {
+	frameType, payload, err := DecodeFrame(frame)
+	if err != nil {
+		return err
+	}
+	if frameType != FrameTypePacket {
+		return fmt.Errorf("unknown frame type %d", frameType)
+	}
+
+	if len(payload) < ipv4HeaderSize || payload[0]>>4 != 4 {
+		return fmt.Errorf("frame from %s does not carry an IPv4 packet", peerPublicKey)
+	}
+
+	src, ok := r.routeKey(net.IP(payload[12:16]))
+	r.mu.RLock()
+	owner := r.peers[src]
+	r.mu.RUnlock()
+	if !ok || owner != peerPublicKey {
+		return fmt.Errorf("packet from %s has source %s outside its address", peerPublicKey, net.IP(payload[12:16]))
+	}
+
+	if r.handleEchoReply(payload) {
+		return nil
+	}
+	if _, err := r.dev.Write(payload); err != nil {
+		return fmt.Errorf("failed to write packet to %s: %w", r.dev.Name(), err)
+	}
+	return nil
+}
+
+// Close fecha a interface e encerra Run
+func (r *Router) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+		err = r.dev.Close()
+	})
+	return err
+}
+
+// routeKey converte um IP da sub-rede virtual em chave da tabela; o próprio IP não é rota
+func (r *Router) routeKey(ip net.IP) ([4]byte, bool) {
+	var key [4]byte
+	ip4 := ip.To4()
+	if ip4 == nil || !r.subnet.Contains(ip4) || ip4.Equal(r.localIP) {
+		return key, false
+	}
+	copy(key[:], ip4)
+	return key, true
+}
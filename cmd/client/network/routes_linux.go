@@ -0,0 +1,53 @@
+package network
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"strings"
+)
+
+// routeTable é a tabela de rotas IPv4 do kernel
+const routeTable = "/proc/net/route"
+
+// localRoutes lê as rotas IPv4 do kernel, fora a rota padrão e as da própria VPN
+func localRoutes() ([]LocalNetwork, error) {
+	file, err := os.Open(routeTable)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var routes []LocalNetwork
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Cabeçalho
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] == DefaultDeviceName {
+			continue
+		}
+		destination, ok := parseRouteHex(fields[1])
+		mask, okMask := parseRouteHex(fields[7])
+		if !ok || !okMask || mask.Equal(net.IPv4zero.To4()) {
+			continue
+		}
+		subnet := &net.IPNet{IP: destination.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)}
+		routes = append(routes, LocalNetwork{Subnet: subnet, Interface: fields[0], Route: true})
+	}
+	return routes, scanner.Err()
+}
+
+// parseRouteHex lê um endereço de /proc/net/route, o número em hexadecimal dos bytes do
+// endereço lidos na ordem do host
+func parseRouteHex(s string) (net.IP, bool) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return nil, false
+	}
+	ip := make(net.IP, 4)
+	binary.NativeEndian.PutUint32(ip, binary.BigEndian.Uint32(b))
+	return ip, true
+}
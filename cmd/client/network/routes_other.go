@@ -0,0 +1,9 @@
+//go:build !linux
+
+package network
+
+// localRoutes não lê a tabela de rotas fora do Linux; os conflitos vêm só dos endereços
+// das interfaces
+func localRoutes() ([]LocalNetwork, error) {
+	return nil, nil
+}
@@ -0,0 +1,468 @@
+package network
+
+import (
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Tempo de vida das chaves de sessão: o lado que inicia negocia novas chaves depois de
+// KeyRotationInterval ou de keyRotationFrames frames enviados, o que vier primeiro
+const (
+	KeyRotationInterval = 10 * time.Minute
+	keyRotationFrames   = 1 << 24
+	handshakeRetry      = 5 * time.Second
+)
+
+// Mensagens do handshake
+const (
+	handshakeInit  byte = 1
+	handshakeReply byte = 2
+)
+
+// SealOverhead é quanto um frame cresce ao ser cifrado: cabeçalho, época, contador e tag
+const SealOverhead = frameHeaderSize + sealedHeaderSize + chacha20poly1305.Overhead
+
+const (
+	handshakeContext  = "govpn handshake v2"
+	keysContext       = "govpn data keys v2"
+	networkKeyContext = "govpn network key v1"
+	sealedHeaderSize  = 4 + 8 // época e contador
+	replayWindowSize  = 64
+)
+
+// NetworkKeySize é o tamanho da chave derivada do PIN de uma rede
+const NetworkKeySize = 32
+
+var (
+	// ErrNoSessionKey é retornado ao cifrar antes do handshake com o peer terminar
+	ErrNoSessionKey = errors.New("no session key with peer yet")
+	// ErrReplayedFrame é retornado para frames cifrados repetidos ou velhos demais
+	ErrReplayedFrame = errors.New("replayed or stale frame")
+	// ErrBadHandshake é retornado para handshakes malformados ou com assinatura inválida
+	ErrBadHandshake = errors.New("invalid handshake")
+)
+
+// sessionKeys são as chaves de uma época, uma para cada sentido
+type sessionKeys struct {
+	epoch        uint32
+	initiatorKey []byte
+	send         cipher.AEAD
+	recv         cipher.AEAD
+	sent         uint64
+	created      time.Time
+	replay       replayWindow
+}
+
+// SecureSession cifra os frames trocados com um peer usando ChaCha20-Poly1305, com chaves
+// derivadas de um handshake X25519 assinado pelas chaves de identidade Ed25519 dos dois
+// lados e amarradas à rede. Assim o tráfego continua protegido mesmo que o DTLS do canal
+// de dados seja terminado por um relay. Quem inicia o handshake (o lado que oferece na
+// malha) também faz a rotação; a chave anterior continua valendo para receber.
+//
+// A chave derivada do PIN da rede (NetworkKey) entra na transcrição assinada e no HKDF, então
+// o servidor de sinalização, que vê as chaves públicas e as efêmeras, não consegue se passar
+// por um membro sem saber o PIN. Depois de uma troca de PIN um computador pode ter várias
+// chaves: quem responde tenta cada uma, e quem inicia passa para a próxima a cada handshake
+// sem resposta, até achar uma que o peer também tem.
+type SecureSession struct {
+	identity    ed25519.PrivateKey
+	peer        ed25519.PublicKey
+	networkID   string
+	networkKeys [][]byte // Chaves derivadas do PIN, a mais nova primeiro
+	initiator   bool
+	send        func(frame []byte) error
+
+	mu       sync.Mutex
+	current  *sessionKeys
+	previous *sessionKeys
+
+	// Handshake iniciado por este lado, esperando resposta
+	pending        *ecdh.PrivateKey
+	pendingEpoch   uint32
+	pendingStarted time.Time
+	keyIndex       int // Chave da rede usada nos handshakes que este lado inicia
+}
+
+// NetworkKey deriva a chave de uma rede a partir do PIN com Argon2id, com o ID da rede como
+// sal. O PIN tem poucos dígitos, então a chave não resiste a quem grava um handshake e testa
+// todos os PINs; ela impede que quem não sabe o PIN, como o servidor, complete um handshake.
+func NetworkKey(networkID, pin string) []byte {
+	salt := append([]byte(networkKeyContext+"\x00"), networkID...)
+	return argon2.IDKey([]byte(pin), salt, 1, 64*1024, 4, NetworkKeySize)
+}
+
+// NewSecureSession prepara a cifra com um peer. networkKeys são as chaves derivadas do PIN
+// da rede, a mais nova primeiro; send entrega frames de handshake ao peer sem cifrar;
+// initiator diz se este lado inicia os handshakes.
+func NewSecureSession(identity ed25519.PrivateKey, peer ed25519.PublicKey, networkID string, networkKeys [][]byte, initiator bool, send func(frame []byte) error) *SecureSession {
+	return &SecureSession{
+		identity:    identity,
+		peer:        peer,
+		networkID:   networkID,
+		networkKeys: networkKeys,
+		initiator:   initiator,
+		send:        send,
+	}
+}
+
+// Start envia o primeiro handshake quando este lado é quem inicia
+func (s *SecureSession) Start() error {
+	if !s.initiator {
+		return nil
+	}
+
+	s.mu.Lock()
+	frame, err := s.beginHandshake()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.send(frame)
+}
+
+// Established diz se já há chave para cifrar frames
+func (s *SecureSession) Established() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.current != nil
+}
+
+// Seal cifra um frame para o peer. Sem chave retorna ErrNoSessionKey; quando a chave está
+// velha, o lado que inicia aproveita o envio para negociar a próxima.
+func (s *SecureSession) Seal(frame []byte) ([]byte, error) {
+	s.mu.Lock()
+	keys := s.current
+	if keys == nil {
+		handshake := s.retryHandshake()
+		s.mu.Unlock()
+		if handshake != nil {
+			s.send(handshake)
+		}
+		return nil, ErrNoSessionKey
+	}
+
+	counter := keys.sent
+	keys.sent++
+
+	var handshake []byte
+	if s.initiator && s.pending == nil && (keys.sent >= keyRotationFrames || time.Since(keys.created) >= KeyRotationInterval) {
+		handshake, _ = s.beginHandshake()
+	} else {
+		handshake = s.retryHandshake()
+	}
+	s.mu.Unlock()
+
+	if handshake != nil {
+		if err := s.send(handshake); err != nil {
+			return nil, fmt.Errorf("sending key rotation: %w", err)
+		}
+	}
+
+	sealed := make([]byte, frameHeaderSize+sealedHeaderSize, frameHeaderSize+sealedHeaderSize+len(frame)+keys.send.Overhead())
+	sealed[0] = frameVersion
+	sealed[1] = byte(FrameTypeSealed)
+	binary.BigEndian.PutUint32(sealed[frameHeaderSize:], keys.epoch)
+	binary.BigEndian.PutUint64(sealed[frameHeaderSize+4:], counter)
+
+	return keys.send.Seal(sealed, sealNonce(counter), frame, sealed), nil
+}
+
+// Open decifra um frame do tipo FrameTypeSealed e retorna o frame original
+func (s *SecureSession) Open(sealed []byte) ([]byte, error) {
+	if len(sealed) < frameHeaderSize+sealedHeaderSize {
+		return nil, ErrShortFrame
+	}
+	header := sealed[:frameHeaderSize+sealedHeaderSize]
+	epoch := binary.BigEndian.Uint32(header[frameHeaderSize:])
+	counter := binary.BigEndian.Uint64(header[frameHeaderSize+4:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys *sessionKeys
+	switch {
+	case s.current != nil && s.current.epoch == epoch:
+		keys = s.current
+	case s.previous != nil && s.previous.epoch == epoch:
+		keys = s.previous
+	default:
+		return nil, ErrNoSessionKey
+	}
+
+	if !keys.replay.check(counter) {
+		return nil, ErrReplayedFrame
+	}
+	frame, err := keys.recv.Open(nil, sealNonce(counter), sealed[len(header):], header)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting frame: %w", err)
+	}
+	keys.replay.accept(counter)
+	return frame, nil
+}
+
+// HandleHandshake processa um frame do tipo FrameTypeHandshake recebido do peer
+func (s *SecureSession) HandleHandshake(payload []byte) error {
+	if len(payload) < 1 {
+		return ErrBadHandshake
+	}
+
+	switch payload[0] {
+	case handshakeInit:
+		if s.initiator {
+			return fmt.Errorf("%w: peer started a handshake it should answer", ErrBadHandshake)
+		}
+		reply, err := s.answerHandshake(payload)
+		if err != nil {
+			return err
+		}
+		return s.send(reply)
+	case handshakeReply:
+		if !s.initiator {
+			return fmt.Errorf("%w: reply to a handshake we did not start", ErrBadHandshake)
+		}
+		return s.finishHandshake(payload)
+	default:
+		return fmt.Errorf("%w: unknown message %d", ErrBadHandshake, payload[0])
+	}
+}
+
+// beginHandshake gera a chave efêmera da próxima época e monta o frame de início.
+// Callers must hold the lock.
+func (s *SecureSession) beginHandshake() ([]byte, error) {
+	if len(s.networkKeys) == 0 {
+		return nil, errors.New("no network key to authenticate the handshake")
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating ephemeral key: %w", err)
+	}
+
+	epoch := uint32(1)
+	if s.current != nil {
+		epoch = s.current.epoch + 1
+	}
+	if s.pending != nil {
+		// O peer não respondeu; talvez não tenha esta chave da rede
+		s.keyIndex = (s.keyIndex + 1) % len(s.networkKeys)
+	}
+	s.pending, s.pendingEpoch, s.pendingStarted = ephemeral, epoch, time.Now()
+	networkKey := s.networkKeys[s.keyIndex]
+
+	// init: tipo | época | efêmera do iniciador | assinatura
+	msg := make([]byte, 0, 1+4+32+ed25519.SignatureSize)
+	msg = append(msg, handshakeInit)
+	msg = binary.BigEndian.AppendUint32(msg, epoch)
+	msg = append(msg, ephemeral.PublicKey().Bytes()...)
+	msg = append(msg, ed25519.Sign(s.identity, s.transcript(msg, s.peer, networkKey))...)
+	return EncodeFrame(FrameTypeHandshake, msg), nil
+}
+
+// retryHandshake começa o primeiro handshake ou repete um que ficou sem resposta.
+// Callers must hold the lock.
+func (s *SecureSession) retryHandshake() []byte {
+	if !s.initiator || (s.pending == nil && s.current != nil) {
+		return nil
+	}
+	if s.pending != nil && time.Since(s.pendingStarted) < handshakeRetry {
+		return nil
+	}
+	frame, err := s.beginHandshake()
+	if err != nil {
+		return nil
+	}
+	return frame
+}
+
+// answerHandshake verifica o início de handshake do peer, instala as chaves da nova época
+// e monta a resposta
+func (s *SecureSession) answerHandshake(msg []byte) ([]byte, error) {
+	const size = 1 + 4 + 32 + ed25519.SignatureSize
+	if len(msg) != size {
+		return nil, ErrBadHandshake
+	}
+	signed, signature := msg[:size-ed25519.SignatureSize], msg[size-ed25519.SignatureSize:]
+	var networkKey []byte
+	for _, key := range s.networkKeys {
+		if ed25519.Verify(s.peer, s.transcript(signed, s.identity.Public().(ed25519.PublicKey), key), signature) {
+			networkKey = key
+			break
+		}
+	}
+	if networkKey == nil {
+		return nil, fmt.Errorf("%w: bad signature or different network PIN", ErrBadHandshake)
+	}
+
+	epoch := binary.BigEndian.Uint32(msg[1:5])
+	initiatorKey, err := ecdh.X25519().NewPublicKey(msg[5:37])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadHandshake, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Uma época velha, ou a atual com a mesma chave efêmera, só pode ser um handshake repetido.
+	// A época atual com outra chave é o iniciador repetindo um handshake sem resposta.
+	if s.current != nil && (epoch < s.current.epoch || epoch == s.current.epoch && string(msg[5:37]) == string(s.current.initiatorKey)) {
+		return nil, fmt.Errorf("%w: epoch %d is not newer than %d", ErrBadHandshake, epoch, s.current.epoch)
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating ephemeral key: %w", err)
+	}
+	keys, err := s.deriveKeys(epoch, ephemeral, initiatorKey, msg[5:37], ephemeral.PublicKey().Bytes(), networkKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// reply: tipo | época | efêmera do iniciador | efêmera do respondedor | assinatura
+	reply := make([]byte, 0, 1+4+32+32+ed25519.SignatureSize)
+	reply = append(reply, handshakeReply)
+	reply = binary.BigEndian.AppendUint32(reply, epoch)
+	reply = append(reply, msg[5:37]...)
+	reply = append(reply, ephemeral.PublicKey().Bytes()...)
+	reply = append(reply, ed25519.Sign(s.identity, s.transcript(reply, s.peer, networkKey))...)
+
+	s.previous, s.current = s.current, keys
+	return EncodeFrame(FrameTypeHandshake, reply), nil
+}
+
+// finishHandshake verifica a resposta do peer ao nosso handshake e instala as chaves
+func (s *SecureSession) finishHandshake(msg []byte) error {
+	const size = 1 + 4 + 32 + 32 + ed25519.SignatureSize
+	if len(msg) != size {
+		return ErrBadHandshake
+	}
+	signed, signature := msg[:size-ed25519.SignatureSize], msg[size-ed25519.SignatureSize:]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	epoch := binary.BigEndian.Uint32(msg[1:5])
+	if s.pending == nil || epoch != s.pendingEpoch || string(msg[5:37]) != string(s.pending.PublicKey().Bytes()) {
+		return fmt.Errorf("%w: reply does not match the pending handshake", ErrBadHandshake)
+	}
+	networkKey := s.networkKeys[s.keyIndex]
+	if !ed25519.Verify(s.peer, s.transcript(signed, s.identity.Public().(ed25519.PublicKey), networkKey), signature) {
+		return fmt.Errorf("%w: bad signature", ErrBadHandshake)
+	}
+	responderKey, err := ecdh.X25519().NewPublicKey(msg[37:69])
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadHandshake, err)
+	}
+
+	keys, err := s.deriveKeys(epoch, s.pending, responderKey, msg[5:37], msg[37:69], networkKey)
+	if err != nil {
+		return err
+	}
+	s.previous, s.current = s.current, keys
+	s.pending = nil
+	return nil
+}
+
+// transcript é o que cada lado assina: o contexto, a rede, a chave do PIN, a mensagem e o
+// destinatário, para que um handshake não possa ser reaproveitado com outro peer ou em outra
+// rede, nem feito sem saber o PIN
+func (s *SecureSession) transcript(msg []byte, recipient ed25519.PublicKey, networkKey []byte) []byte {
+	t := make([]byte, 0, len(handshakeContext)+1+len(s.networkID)+1+len(networkKey)+len(msg)+len(recipient))
+	t = append(t, handshakeContext...)
+	t = append(t, 0)
+	t = append(t, s.networkID...)
+	t = append(t, 0)
+	t = append(t, networkKey...)
+	t = append(t, msg...)
+	return append(t, recipient...)
+}
+
+// deriveKeys combina as chaves efêmeras e a chave do PIN e deriva uma chave para cada
+// sentido com HKDF-SHA256. Callers must hold the lock.
+func (s *SecureSession) deriveKeys(epoch uint32, private *ecdh.PrivateKey, remote *ecdh.PublicKey, initiatorKey, responderKey, networkKey []byte) (*sessionKeys, error) {
+	shared, err := private.ECDH(remote)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadHandshake, err)
+	}
+	shared = append(shared, networkKey...)
+
+	info := make([]byte, 0, len(keysContext)+len(initiatorKey)+len(responderKey))
+	info = append(append(append(info, keysContext...), initiatorKey...), responderKey...)
+
+	material := make([]byte, 2*chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, []byte(s.networkID), info), material); err != nil {
+		return nil, fmt.Errorf("deriving session keys: %w", err)
+	}
+
+	toResponder, err := chacha20poly1305.New(material[:chacha20poly1305.KeySize])
+	if err != nil {
+		return nil, err
+	}
+	toInitiator, err := chacha20poly1305.New(material[chacha20poly1305.KeySize:])
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &sessionKeys{epoch: epoch, initiatorKey: initiatorKey, created: time.Now()}
+	if s.initiator {
+		keys.send, keys.recv = toResponder, toInitiator
+	} else {
+		keys.send, keys.recv = toInitiator, toResponder
+	}
+	return keys, nil
+}
+
+// sealNonce monta o nonce a partir do contador; cada época tem chaves próprias
+func sealNonce(counter uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[4:], counter)
+	return nonce
+}
+
+// replayWindow recusa contadores já vistos ou mais velhos que a janela
+type replayWindow struct {
+	highest uint64
+	seen    uint64 // bit i marca highest-i
+	started bool
+}
+
+// check diz se o contador ainda não foi aceito
+func (w *replayWindow) check(counter uint64) bool {
+	if !w.started || counter > w.highest {
+		return true
+	}
+	diff := w.highest - counter
+	return diff < replayWindowSize && w.seen&(1<<diff) == 0
+}
+
+// accept marca o contador como visto, depois que o frame foi autenticado
+func (w *replayWindow) accept(counter uint64) {
+	if !w.started {
+		w.highest, w.seen, w.started = counter, 1, true
+		return
+	}
+	if counter > w.highest {
+		shift := counter - w.highest
+		if shift >= replayWindowSize {
+			w.seen = 0
+		} else {
+			w.seen <<= shift
+		}
+		w.seen |= 1
+		w.highest = counter
+		return
+	}
+	w.seen |= 1 << (w.highest - counter)
+}
@@ -0,0 +1,102 @@
+package network
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+)
+
+func TestNetworkKey(t *testing.T) {
+	key := NetworkKey("net-1", "1234")
+	if len(key) != NetworkKeySize {
+		t.Fatalf("key size = %d, want %d", len(key), NetworkKeySize)
+	}
+	if !bytes.Equal(key, NetworkKey("net-1", "1234")) {
+		t.Error("the same network and PIN gave different keys")
+	}
+	if bytes.Equal(key, NetworkKey("net-1", "4321")) {
+		t.Error("different PINs gave the same key")
+	}
+	if bytes.Equal(key, NetworkKey("net-2", "1234")) {
+		t.Error("the same PIN in different networks gave the same key")
+	}
+}
+
+// sessionPair liga duas sessões diretamente, entregando os handshakes de uma à outra
+func sessionPair(t *testing.T, initiatorKeys, responderKeys [][]byte) (initiator, responder *SecureSession) {
+	t.Helper()
+
+	initiatorPublic, initiatorIdentity, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responderPublic, responderIdentity, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliver := func(to **SecureSession) func([]byte) error {
+		return func(frame []byte) error {
+			_, payload, err := DecodeFrame(frame)
+			if err != nil {
+				return err
+			}
+			return (*to).HandleHandshake(payload)
+		}
+	}
+	initiator = NewSecureSession(initiatorIdentity, responderPublic, "net-1", initiatorKeys, true, deliver(&responder))
+	responder = NewSecureSession(responderIdentity, initiatorPublic, "net-1", responderKeys, false, deliver(&initiator))
+	return initiator, responder
+}
+
+func TestSecureSessionSamePIN(t *testing.T) {
+	key := NetworkKey("net-1", "1234")
+	initiator, responder := sessionPair(t, [][]byte{key}, [][]byte{key})
+
+	if err := initiator.Start(); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	if !initiator.Established() || !responder.Established() {
+		t.Fatal("handshake did not establish keys on both sides")
+	}
+
+	frame := EncodeFrame(FrameTypePacket, []byte("hello"))
+	sealed, err := initiator.Seal(frame)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	opened, err := responder.Open(sealed)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if !bytes.Equal(opened, frame) {
+		t.Errorf("opened %q, want %q", opened, frame)
+	}
+	if _, err := responder.Open(sealed); err != ErrReplayedFrame {
+		t.Errorf("replayed frame error = %v, want %v", err, ErrReplayedFrame)
+	}
+}
+
+func TestSecureSessionWrongPIN(t *testing.T) {
+	initiator, responder := sessionPair(t, [][]byte{NetworkKey("net-1", "1234")}, [][]byte{NetworkKey("net-1", "4321")})
+
+	if err := initiator.Start(); err == nil {
+		t.Error("handshake accepted with a different PIN")
+	}
+	if initiator.Established() || responder.Established() {
+		t.Error("keys established with a different PIN")
+	}
+}
+
+func TestSecureSessionAfterPINChange(t *testing.T) {
+	oldKey, newKey := NetworkKey("net-1", "1234"), NetworkKey("net-1", "4321")
+	initiator, responder := sessionPair(t, [][]byte{oldKey}, [][]byte{newKey, oldKey})
+
+	if err := initiator.Start(); err != nil {
+		t.Fatalf("handshake with the previous PIN: %v", err)
+	}
+	if !initiator.Established() || !responder.Established() {
+		t.Error("a peer that still has the previous PIN could not connect")
+	}
+}
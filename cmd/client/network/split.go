@@ -0,0 +1,48 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+)
+
+// Divisão do túnel: além da sub-rede da VPN, a interface só recebe as rotas de Config.Routes
+// quando o broadcast está ligado, e o roteador só replica aos peers os destinos dentro delas.
+// No Windows, Config.Apps deixa só os programas escolhidos usarem a interface, por filtros
+// do WFP; nas outras plataformas a divisão é só pelas rotas.
+
+// multicastNet é a faixa de multicast do IPv4
+var multicastNet = &net.IPNet{IP: net.IPv4(224, 0, 0, 0).To4(), Mask: net.CIDRMask(4, 32)}
+
+// ParseTunnelRoute lê uma sub-rede de multicast, como 239.255.255.250/32, ou o broadcast
+// limitado 255.255.255.255/32. Um IP sem prefixo vale como /32. Outras sub-redes não têm
+// peer para onde ir e são recusadas.
+func ParseTunnelRoute(s string) (*net.IPNet, error) {
+	cidr := s
+	if !strings.Contains(cidr, "/") {
+		cidr += "/32"
+	}
+	ip, subnet, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return nil, fmt.Errorf("invalid IPv4 subnet %q", s)
+	}
+
+	ones, _ := subnet.Mask.Size()
+	if subnet.IP.Equal(net.IPv4bcast) && ones == 32 {
+		return subnet, nil
+	}
+	if !multicastNet.Contains(subnet.IP) || ones < 4 {
+		return nil, fmt.Errorf("subnet %s is not multicast or the broadcast address, only those reach peers", subnet)
+	}
+	return subnet, nil
+}
+
+// ParseTunnelApp lê o caminho completo de um programa que pode usar a interface
+func ParseTunnelApp(s string) (string, error) {
+	path := strings.Trim(strings.TrimSpace(s), `"`)
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("program %q is not a full path", s)
+	}
+	return filepath.Clean(path), nil
+}
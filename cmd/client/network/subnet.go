@@ -0,0 +1,88 @@
+package network
+
+import (
+	"fmt"
+	"net"
+)
+
+// Antes de subir a interface TUN, a sub-rede da rede é comparada com as redes locais: os
+// endereços das interfaces e, onde o sistema permite lê-la, a tabela de rotas. Uma sub-rede
+// que se sobrepõe a uma delas deixaria parte da LAN, ou da outra VPN, inalcançável.
+
+// vpnRange é a faixa de onde o servidor tira a sub-rede de cada rede
+var vpnRange = &net.IPNet{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)}
+
+// LocalNetwork é uma rede alcançável por uma interface deste computador
+type LocalNetwork struct {
+	Subnet    *net.IPNet
+	Interface string
+	Route     bool // Veio da tabela de rotas, e não do endereço da interface
+}
+
+// String formata a rede como "192.168.1.0/24 (wlan0)"
+func (n LocalNetwork) String() string {
+	return fmt.Sprintf("%s (%s)", n.Subnet, n.Interface)
+}
+
+// LocalNetworks lista as redes IPv4 das interfaces ativas, fora loopback e link-local, e as
+// rotas que não são a rota padrão
+func LocalNetworks() ([]LocalNetwork, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var networks []LocalNetwork
+	seen := make(map[string]bool)
+	add := func(network LocalNetwork) {
+		key := network.Subnet.String() + " " + network.Interface
+		if !seen[key] {
+			seen[key] = true
+			networks = append(networks, network)
+		}
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			add(LocalNetwork{Subnet: &net.IPNet{IP: ipNet.IP.To4().Mask(ipNet.Mask), Mask: ipNet.Mask}, Interface: iface.Name})
+		}
+	}
+
+	routes, err := localRoutes()
+	if err != nil {
+		// Sem as rotas ainda sobram os endereços das interfaces
+		return networks, fmt.Errorf("cannot read the route table: %w", err)
+	}
+	for _, route := range routes {
+		add(route)
+	}
+	return networks, nil
+}
+
+// SubnetConflicts retorna as redes locais que se sobrepõem à sub-rede /24 do endereço
+// virtual address
+func SubnetConflicts(address string, networks []LocalNetwork) []LocalNetwork {
+	ip := net.ParseIP(address).To4()
+	if ip == nil {
+		return nil
+	}
+	subnet := &net.IPNet{IP: ip.Mask(net.CIDRMask(DefaultPrefixLen, 32)), Mask: net.CIDRMask(DefaultPrefixLen, 32)}
+
+	var conflicts []LocalNetwork
+	for _, network := range networks {
+		if network.Subnet.Contains(subnet.IP) || subnet.Contains(network.Subnet.IP) {
+			conflicts = append(conflicts, network)
+		}
+	}
+	return conflicts
+}
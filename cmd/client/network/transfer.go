@@ -0,0 +1,751 @@
+package network
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// Transferência de arquivos entre peers. Quem envia oferece o arquivo com nome, tamanho e
+// SHA-256; quem recebe recusa ou aceita a partir do que já tem de uma tentativa anterior,
+// guardado num arquivo .part identificado pelo hash. Os dados seguem em pedaços de
+// transferChunkSize e no fim quem recebe confere o hash antes de dar o arquivo por recebido.
+const (
+	transferChunkSize = 16 * 1024
+	// transferReplyBit marca os frames enviados por quem recebe, cada lado numera os seus envios
+	transferReplyBit uint32 = 1 << 31
+	// transferIdleTimeout encerra as transferências em andamento sem nenhum frame
+	transferIdleTimeout = 30 * time.Second
+	// transferRetryDelay é a espera entre tentativas enquanto o peer está inalcançável
+	transferRetryDelay = 250 * time.Millisecond
+	// maxTransferNameLength limita o nome oferecido, em bytes
+	maxTransferNameLength = 255
+)
+
+// Como uma transferência terminou, no frame FrameTypeFileClose
+const (
+	transferCloseDone byte = iota
+	transferCloseDeclined
+	transferCloseCancelled
+	transferCloseFailed
+)
+
+// ErrTransfersClosed é retornado depois que as transferências foram encerradas com Close
+var ErrTransfersClosed = errors.New("file transfers closed")
+
+// TransferState é a fase de uma transferência
+type TransferState int
+
+const (
+	TransferPreparing TransferState = iota // Calculando o hash antes de oferecer
+	TransferOffered                        // Esperando quem recebe aceitar
+	TransferActive                         // Enviando ou recebendo os dados
+	TransferDone                           // Recebido e conferido
+	TransferDeclined                       // Recusado por quem recebe
+	TransferCancelled                      // Cancelado por um dos lados
+	TransferFailed                         // Interrompido por um erro
+)
+
+// String descreve a fase para a interface
+func (s TransferState) String() string {
+	switch s {
+	case TransferPreparing:
+		return "Preparing"
+	case TransferOffered:
+		return "Waiting"
+	case TransferActive:
+		return "Transferring"
+	case TransferDone:
+		return "Done"
+	case TransferDeclined:
+		return "Declined"
+	case TransferCancelled:
+		return "Cancelled"
+	default:
+		return "Failed"
+	}
+}
+
+// Finished diz se a transferência terminou, bem ou mal
+func (s TransferState) Finished() bool {
+	return s >= TransferDone
+}
+
+// Transfer é o estado de uma transferência como visto pela interface
+type Transfer struct {
+	ID      uint32
+	Peer    string // Chave pública do outro computador
+	Sending bool
+	Name    string
+	Size    int64
+	Offset  int64 // De onde a transferência começou, maior que zero ao retomar
+	Bytes   int64 // Quanto do arquivo já foi transferido, contando Offset
+	State   TransferState
+	Error   string
+	Path    string // O arquivo enviado, ou o recebido depois de concluída
+	Started time.Time
+}
+
+// transferKey identifica uma transferência: sending diz se é este computador que envia
+type transferKey struct {
+	peer    string
+	id      uint32
+	sending bool
+}
+
+// transfer é uma transferência com o arquivo aberto
+type transfer struct {
+	Transfer
+	key          transferKey
+	hash         []byte
+	partPath     string    // Onde quem recebe guarda os dados até conferir o hash
+	file         *os.File  // Lido no envio, escrito no recebimento
+	received     hash.Hash // Hash do que já foi recebido, calculado conforme os dados chegam
+	lastActivity time.Time
+}
+
+// FileTransfers envia e recebe arquivos pelos canais de dados dos peers
+type FileTransfers struct {
+	send     SendFunc
+	dir      string
+	onOffer  func(Transfer)
+	onFinish func(Transfer)
+
+	mu        sync.Mutex
+	transfers map[transferKey]*transfer
+	nextID    uint32
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewFileTransfers cria as transferências; os arquivos recebidos vão para dir. send deve
+// recusar com ErrPeerUnreachable os peers ainda sem canal. onOffer recebe cada arquivo
+// oferecido, que espera Accept ou Decline; onFinish recebe cada transferência terminada.
+func NewFileTransfers(send SendFunc, dir string, onOffer, onFinish func(Transfer)) *FileTransfers {
+	return &FileTransfers{
+		send:      send,
+		dir:       dir,
+		onOffer:   onOffer,
+		onFinish:  onFinish,
+		transfers: make(map[transferKey]*transfer),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run encerra as transferências em andamento sem atividade até Close
+func (ft *FileTransfers) Run() {
+	ticker := time.NewTicker(transferIdleTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ft.done:
+			return
+		case now := <-ticker.C:
+			ft.mu.Lock()
+			var stale []*transfer
+			for _, t := range ft.transfers {
+				if t.State == TransferActive && now.Sub(t.lastActivity) > transferIdleTimeout {
+					stale = append(stale, t)
+				}
+			}
+			ft.mu.Unlock()
+
+			for _, t := range stale {
+				ft.finish(t, TransferFailed, "the other computer stopped responding", true)
+			}
+		}
+	}
+}
+
+// List retorna as transferências da sessão, as mais antigas primeiro
+func (ft *FileTransfers) List() []Transfer {
+	ft.mu.Lock()
+	list := make([]Transfer, 0, len(ft.transfers))
+	for _, t := range ft.transfers {
+		list = append(list, t.Transfer)
+	}
+	ft.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Started.Before(list[j].Started)
+	})
+	return list
+}
+
+// ClearFinished esquece as transferências terminadas
+func (ft *FileTransfers) ClearFinished() {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	for key, t := range ft.transfers {
+		if t.State.Finished() {
+			delete(ft.transfers, key)
+		}
+	}
+}
+
+// SendFile oferece o arquivo em filePath ao peer. O hash é calculado em segundo plano,
+// antes da oferta, e a transferência começa quando o peer aceita.
+func (ft *FileTransfers) SendFile(peerPublicKey, filePath string) (Transfer, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return Transfer{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return Transfer{}, fmt.Errorf("%s is not a regular file", filepath.Base(filePath))
+	}
+
+	ft.mu.Lock()
+	ft.nextID = (ft.nextID + 1) &^ transferReplyBit
+	t := &transfer{
+		Transfer: Transfer{
+			ID:      ft.nextID,
+			Peer:    peerPublicKey,
+			Sending: true,
+			Name:    filepath.Base(filePath),
+			Size:    info.Size(),
+			State:   TransferPreparing,
+			Path:    filePath,
+			Started: time.Now(),
+		},
+	}
+	t.key = transferKey{peer: peerPublicKey, id: t.ID, sending: true}
+	ft.transfers[t.key] = t
+	ft.mu.Unlock()
+
+	crash.Go("file offer", func() { ft.offer(t) })
+	return t.Transfer, nil
+}
+
+// offer calcula o hash do arquivo e o oferece ao peer
+func (ft *FileTransfers) offer(t *transfer) {
+	sum, err := hashFile(t.Path, t.Size)
+	if err != nil {
+		ft.finish(t, TransferFailed, err.Error(), false)
+		return
+	}
+
+	ft.mu.Lock()
+	if t.State != TransferPreparing {
+		ft.mu.Unlock()
+		return
+	}
+	t.hash = sum
+	t.State = TransferOffered
+	ft.mu.Unlock()
+
+	name := t.Name
+	if len(name) > maxTransferNameLength {
+		name = name[:maxTransferNameLength]
+	}
+	payload := make([]byte, 8+sha256.Size+len(name))
+	binary.BigEndian.PutUint64(payload, uint64(t.Size))
+	copy(payload[8:], sum)
+	copy(payload[8+sha256.Size:], name)
+	if err := ft.sendPersistent(t.key, FrameTypeFileOffer, payload); err != nil {
+		ft.finish(t, TransferFailed, err.Error(), false)
+	}
+}
+
+// Accept aceita um arquivo oferecido pelo peer, retomando o que já foi recebido dele
+func (ft *FileTransfers) Accept(peerPublicKey string, id uint32) error {
+	ft.mu.Lock()
+	t := ft.transfers[transferKey{peer: peerPublicKey, id: id}]
+	if t == nil || t.State != TransferOffered {
+		ft.mu.Unlock()
+		return errors.New("the file is no longer offered")
+	}
+
+	file, received, err := openPart(t.partPath, t.Offset)
+	if err != nil {
+		ft.mu.Unlock()
+		ft.finish(t, TransferFailed, err.Error(), true)
+		return err
+	}
+	t.file, t.received = file, received
+	t.State = TransferActive
+	t.lastActivity = time.Now()
+	offset := t.Offset
+	ft.mu.Unlock()
+
+	payload := make([]byte, 8)
+	binary.BigEndian.PutUint64(payload, uint64(offset))
+	if err := ft.sendPersistent(t.key, FrameTypeFileAccept, payload); err != nil {
+		ft.finish(t, TransferFailed, err.Error(), false)
+		return err
+	}
+	return nil
+}
+
+// Decline recusa um arquivo oferecido pelo peer
+func (ft *FileTransfers) Decline(peerPublicKey string, id uint32) {
+	ft.mu.Lock()
+	t := ft.transfers[transferKey{peer: peerPublicKey, id: id}]
+	ft.mu.Unlock()
+
+	if t != nil {
+		ft.finish(t, TransferDeclined, "", true)
+	}
+}
+
+// Cancel interrompe uma transferência. Ao receber, o que já chegou fica guardado para
+// retomar quando o arquivo for oferecido de novo.
+func (ft *FileTransfers) Cancel(peerPublicKey string, id uint32, sending bool) {
+	ft.mu.Lock()
+	t := ft.transfers[transferKey{peer: peerPublicKey, id: id, sending: sending}]
+	ft.mu.Unlock()
+
+	if t != nil {
+		ft.finish(t, TransferCancelled, "", true)
+	}
+}
+
+// HandleFrame trata um frame de transferência recebido de peerPublicKey
+func (ft *FileTransfers) HandleFrame(peerPublicKey string, frame []byte) error {
+	frameType, payload, err := DecodeFrame(frame)
+	if err != nil {
+		return err
+	}
+	if len(payload) < 4 {
+		return ErrShortFrame
+	}
+
+	wireID := binary.BigEndian.Uint32(payload)
+	key := transferKey{peer: peerPublicKey, id: wireID &^ transferReplyBit, sending: wireID&transferReplyBit != 0}
+	payload = payload[4:]
+
+	if frameType == FrameTypeFileOffer {
+		if key.sending || len(payload) < 8+sha256.Size {
+			return fmt.Errorf("malformed file offer from %s", peerPublicKey)
+		}
+		return ft.handleOffer(key, payload)
+	}
+
+	ft.mu.Lock()
+	t := ft.transfers[key]
+	ft.mu.Unlock()
+	if t == nil {
+		return nil
+	}
+
+	switch frameType {
+	case FrameTypeFileAccept:
+		if !key.sending || len(payload) < 8 {
+			return fmt.Errorf("malformed file accept from %s", peerPublicKey)
+		}
+		ft.handleAccept(t, int64(binary.BigEndian.Uint64(payload)))
+	case FrameTypeFileData:
+		if key.sending || len(payload) < 8 {
+			return fmt.Errorf("malformed file data from %s", peerPublicKey)
+		}
+		ft.handleData(t, int64(binary.BigEndian.Uint64(payload)), payload[8:])
+	case FrameTypeFileClose:
+		if len(payload) < 1 {
+			return ErrShortFrame
+		}
+		ft.handleClose(t, payload[0], string(payload[1:]))
+	default:
+		return fmt.Errorf("unknown frame type %d", frameType)
+	}
+	return nil
+}
+
+// handleOffer registra um arquivo oferecido e o entrega a onOffer, que decide se aceita
+func (ft *FileTransfers) handleOffer(key transferKey, payload []byte) error {
+	size := int64(binary.BigEndian.Uint64(payload))
+	sum := append([]byte(nil), payload[8:8+sha256.Size]...)
+	name := sanitizeFileName(string(payload[8+sha256.Size:]))
+
+	t := &transfer{
+		Transfer: Transfer{
+			ID:      key.id,
+			Peer:    key.peer,
+			Name:    name,
+			Size:    size,
+			State:   TransferOffered,
+			Started: time.Now(),
+		},
+		key:      key,
+		hash:     sum,
+		partPath: filepath.Join(ft.dir, fmt.Sprintf("%s.%x.part", name, sum[:6])),
+	}
+	if size < 0 || name == "" {
+		ft.sendClose(t, transferCloseFailed, "invalid file offer")
+		return fmt.Errorf("invalid file offer from %s", key.peer)
+	}
+
+	// O que sobrou de uma tentativa anterior do mesmo arquivo é retomado
+	if info, err := os.Stat(t.partPath); err == nil {
+		if info.Size() <= size {
+			t.Offset = info.Size()
+			t.Bytes = t.Offset
+		} else {
+			os.Remove(t.partPath)
+		}
+	}
+
+	ft.mu.Lock()
+	if old := ft.transfers[key]; old != nil && !old.State.Finished() {
+		ft.mu.Unlock()
+		return nil
+	}
+	ft.transfers[key] = t
+	ft.mu.Unlock()
+
+	logger.Info("File offered by peer", "peer", key.peer, "name", name, "size", size, "resumeFrom", t.Offset)
+	if ft.onOffer != nil {
+		ft.onOffer(t.Transfer)
+	}
+	return nil
+}
+
+// handleAccept começa a enviar o arquivo a partir do que o peer já tem
+func (ft *FileTransfers) handleAccept(t *transfer, offset int64) {
+	ft.mu.Lock()
+	if t.State != TransferOffered {
+		ft.mu.Unlock()
+		return
+	}
+	if offset < 0 || offset > t.Size {
+		offset = 0
+	}
+
+	file, err := os.Open(t.Path)
+	if err == nil {
+		_, err = file.Seek(offset, io.SeekStart)
+		if err != nil {
+			file.Close()
+		}
+	}
+	if err != nil {
+		ft.mu.Unlock()
+		ft.finish(t, TransferFailed, err.Error(), true)
+		return
+	}
+	t.file = file
+	t.Offset, t.Bytes = offset, offset
+	t.State = TransferActive
+	t.lastActivity = time.Now()
+	ft.mu.Unlock()
+
+	logger.Info("Sending file to peer", "peer", t.Peer, "name", t.Name, "resumeFrom", offset)
+	crash.Go("file send", func() { ft.pump(t, file) })
+}
+
+// pump envia o arquivo em pedaços e avisa o peer no fim; a transferência termina quando
+// ele confirma o hash
+func (ft *FileTransfers) pump(t *transfer, file *os.File) {
+	buf := make([]byte, 8+transferChunkSize)
+	for {
+		ft.mu.Lock()
+		active := t.State == TransferActive
+		offset := t.Bytes
+		ft.mu.Unlock()
+		if !active {
+			return
+		}
+
+		n, err := file.Read(buf[8:])
+		if n > 0 {
+			binary.BigEndian.PutUint64(buf, uint64(offset))
+			if sendErr := ft.sendPersistent(t.key, FrameTypeFileData, buf[:8+n]); sendErr != nil {
+				ft.finish(t, TransferFailed, sendErr.Error(), true)
+				return
+			}
+			ft.mu.Lock()
+			t.Bytes += int64(n)
+			t.lastActivity = time.Now()
+			ft.mu.Unlock()
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			ft.finish(t, TransferFailed, err.Error(), true)
+			return
+		}
+	}
+
+	ft.mu.Lock()
+	sent := t.Bytes
+	ft.mu.Unlock()
+	if sent != t.Size {
+		ft.finish(t, TransferFailed, "the file changed while it was being sent", true)
+		return
+	}
+	if err := ft.sendPersistent(t.key, FrameTypeFileClose, []byte{transferCloseDone}); err != nil {
+		ft.finish(t, TransferFailed, err.Error(), false)
+	}
+}
+
+// handleData grava um pedaço recebido, que precisa continuar de onde o anterior parou
+func (ft *FileTransfers) handleData(t *transfer, offset int64, data []byte) {
+	ft.mu.Lock()
+	if t.State != TransferActive {
+		ft.mu.Unlock()
+		return
+	}
+	var err error
+	switch {
+	case offset != t.Bytes:
+		err = fmt.Errorf("data out of order at %d, expected %d", offset, t.Bytes)
+	case t.Bytes+int64(len(data)) > t.Size:
+		err = errors.New("more data than the offered size")
+	default:
+		_, err = t.file.Write(data)
+		t.received.Write(data)
+	}
+	if err == nil {
+		t.Bytes += int64(len(data))
+		t.lastActivity = time.Now()
+	}
+	ft.mu.Unlock()
+
+	if err != nil {
+		ft.finish(t, TransferFailed, err.Error(), true)
+	}
+}
+
+// handleClose termina a transferência como o peer pediu. Para quem recebe, o fim do
+// envio só vale depois de conferir o hash.
+func (ft *FileTransfers) handleClose(t *transfer, status byte, message string) {
+	switch status {
+	case transferCloseDone:
+		if t.Sending {
+			ft.finish(t, TransferDone, "", false)
+			return
+		}
+		ft.complete(t)
+	case transferCloseDeclined:
+		ft.finish(t, TransferDeclined, "", false)
+	case transferCloseCancelled:
+		ft.finish(t, TransferCancelled, "", false)
+	default:
+		if message == "" {
+			message = "the other computer reported an error"
+		}
+		ft.finish(t, TransferFailed, message, false)
+	}
+}
+
+// complete confere o arquivo recebido e o move para o nome final
+func (ft *FileTransfers) complete(t *transfer) {
+	ft.mu.Lock()
+	if t.State != TransferActive {
+		ft.mu.Unlock()
+		return
+	}
+	file := t.file
+	t.file = nil
+	received := t.Bytes
+	sum := t.received.Sum(nil)
+	ft.mu.Unlock()
+
+	err := file.Close()
+	if err == nil && received != t.Size {
+		err = fmt.Errorf("received %d of %d bytes", received, t.Size)
+	}
+	if err == nil && string(sum) != string(t.hash) {
+		os.Remove(t.partPath)
+		err = errors.New("the received file does not match the checksum")
+	}
+	var finalPath string
+	if err == nil {
+		finalPath = uniquePath(filepath.Join(ft.dir, t.Name))
+		err = os.Rename(t.partPath, finalPath)
+	}
+	if err != nil {
+		ft.finish(t, TransferFailed, err.Error(), true)
+		return
+	}
+
+	ft.mu.Lock()
+	t.Path = finalPath
+	ft.mu.Unlock()
+	logger.Info("File received from peer", "peer", t.Peer, "path", finalPath)
+	ft.finish(t, TransferDone, "", true)
+}
+
+// finish encerra a transferência no estado final, avisando o peer quando notify é
+// verdadeiro, e a entrega a onFinish. Transferências já encerradas não mudam.
+func (ft *FileTransfers) finish(t *transfer, state TransferState, message string, notify bool) {
+	ft.mu.Lock()
+	if t.State.Finished() {
+		ft.mu.Unlock()
+		return
+	}
+	t.State = state
+	t.Error = message
+	file := t.file
+	t.file = nil
+	snapshot := t.Transfer
+	ft.mu.Unlock()
+
+	if file != nil {
+		file.Close()
+	}
+	if state == TransferFailed {
+		logger.Warn("File transfer failed", "peer", t.Peer, "name", t.Name, "sending", t.Sending, "error", message)
+	}
+
+	if notify {
+		status := transferCloseFailed
+		switch state {
+		case TransferDone:
+			status = transferCloseDone
+		case TransferDeclined:
+			status = transferCloseDeclined
+		case TransferCancelled:
+			status = transferCloseCancelled
+		}
+		ft.sendClose(t, status, message)
+	}
+	if ft.onFinish != nil {
+		ft.onFinish(snapshot)
+	}
+}
+
+// sendClose avisa o peer do fim da transferência, sem esperar pelo canal
+func (ft *FileTransfers) sendClose(t *transfer, status byte, message string) {
+	payload := append([]byte{status}, message...)
+	if err := ft.send(t.Peer, ft.encode(t.key, FrameTypeFileClose, payload)); err != nil {
+		logger.Debug("Failed to tell peer a file transfer ended", "peer", t.Peer, "error", err)
+	}
+}
+
+// sendPersistent envia um frame da transferência, esperando até transferIdleTimeout
+// enquanto o peer está inalcançável, como durante o handshake ou uma reconexão
+func (ft *FileTransfers) sendPersistent(key transferKey, frameType FrameType, data []byte) error {
+	frame := ft.encode(key, frameType, data)
+	deadline := time.Now().Add(transferIdleTimeout)
+	for {
+		err := ft.send(key.peer, frame)
+		if !errors.Is(err, ErrPeerUnreachable) || time.Now().After(deadline) {
+			return err
+		}
+		select {
+		case <-ft.done:
+			return ErrTransfersClosed
+		case <-time.After(transferRetryDelay):
+		}
+	}
+}
+
+// encode monta um frame da transferência com o identificador como o peer o conhece
+func (ft *FileTransfers) encode(key transferKey, frameType FrameType, data []byte) []byte {
+	wireID := key.id
+	if !key.sending {
+		wireID |= transferReplyBit
+	}
+
+	payload := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(payload, wireID)
+	copy(payload[4:], data)
+	return EncodeFrame(frameType, payload)
+}
+
+// Close cancela as transferências em andamento; o que já foi recebido fica para retomar
+func (ft *FileTransfers) Close() error {
+	ft.closeOnce.Do(func() {
+		close(ft.done)
+	})
+
+	ft.mu.Lock()
+	var open []*transfer
+	for _, t := range ft.transfers {
+		if !t.State.Finished() {
+			open = append(open, t)
+		}
+	}
+	ft.mu.Unlock()
+
+	for _, t := range open {
+		ft.finish(t, TransferCancelled, "", true)
+	}
+	return nil
+}
+
+// hashFile calcula o SHA-256 dos primeiros size bytes do arquivo
+func hashFile(filePath string, size int64) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, file, size); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// openPart abre o arquivo parcial para continuar escrevendo a partir de offset, com o
+// hash do que ele já tem. Assim o arquivo completo é conferido assim que o último pedaço
+// chega, sem ler tudo de novo.
+func openPart(partPath string, offset int64) (*os.File, hash.Hash, error) {
+	if err := os.MkdirAll(filepath.Dir(partPath), 0755); err != nil {
+		return nil, nil, err
+	}
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	h := sha256.New()
+	err = file.Truncate(offset)
+	if err == nil {
+		_, err = io.CopyN(h, file, offset)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, h, nil
+}
+
+// sanitizeFileName reduz o nome oferecido pelo peer a um nome de arquivo sem pastas nem
+// caracteres que algum sistema recuse
+func sanitizeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(strings.Trim(name, "."))
+	if len(name) > maxTransferNameLength {
+		name = name[:maxTransferNameLength]
+	}
+	return name
+}
+
+// uniquePath acrescenta " (n)" ao nome quando o arquivo já existe
+func uniquePath(filePath string) string {
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		return filePath
+	}
+	ext := filepath.Ext(filePath)
+	base := strings.TrimSuffix(filePath, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Stat(candidate); errors.Is(err, os.ErrNotExist) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package network leva o tráfego IP entre a interface TUN local e os peers da rede virtual
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+const (
+	// DefaultMTU deixa espaço para os cabeçalhos de DTLS/SCTP dentro de um datagrama UDP
+	DefaultMTU = 1400
+	// DefaultPrefixLen é o tamanho da sub-rede de cada rede, 10.10.0.0/24 se o dono não a
+	// trocou
+	DefaultPrefixLen = 24
+	// DefaultDeviceName é o nome pedido ao sistema, onde ele permite escolher
+	DefaultDeviceName = "govpn0"
+)
+
+// broadcastRoutes são as rotas adicionadas no modo de broadcast: multicast e o broadcast limitado
+var broadcastRoutes = []string{"224.0.0.0/4", "255.255.255.255/32"}
+
+// ErrUnsupported é retornado nas plataformas sem driver TUN
+var ErrUnsupported = errors.New("TUN devices are not supported on this platform")
+
+// Config descreve a interface virtual a criar
+type Config struct {
+	Name      string // Nome desejado; macOS sempre usa utunN
+	Address   string // Endereço atribuído pelo servidor, como 10.10.0.2
+	PrefixLen int
+	MTU       int
+	Broadcast bool // Levar broadcasts e multicast para a interface, para jogos que se descobrem na LAN
+
+	// Divisão do túnel: as sub-redes de broadcast e multicast levadas à interface, no lugar
+	// de broadcastRoutes, e os programas que podem usá-la; vazios não restringem nada
+	Routes []string
+	Apps   []string
+
+	DNS       bool // Mandar ao servidor DNS da interface as consultas de nomes .govpn
+	Discovery bool // Levar à interface os grupos multicast dos protocolos de descoberta
+}
+
+// Device é uma interface TUN aberta: cada Read e Write transporta um pacote IP inteiro
+type Device interface {
+	Name() string
+	Read(packet []byte) (int, error)
+	Write(packet []byte) (int, error)
+	Close() error
+}
+
+// OpenDevice cria a interface TUN e configura o endereço e a rota da sub-rede.
+// Criar a interface exige privilégios de administrador em todas as plataformas.
+func OpenDevice(cfg Config) (Device, error) {
+	if cfg.Name == "" {
+		cfg.Name = DefaultDeviceName
+	}
+	if cfg.PrefixLen == 0 {
+		cfg.PrefixLen = DefaultPrefixLen
+	}
+	if cfg.MTU == 0 {
+		cfg.MTU = DefaultMTU
+	}
+
+	ip := net.ParseIP(cfg.Address).To4()
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IPv4 address %q", cfg.Address)
+	}
+
+	dev, err := openPlatformDevice(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TUN device: %w", err)
+	}
+
+	subnet := &net.IPNet{IP: ip.Mask(net.CIDRMask(cfg.PrefixLen, 32)), Mask: net.CIDRMask(cfg.PrefixLen, 32)}
+	if err := configureDevice(dev.Name(), ip, subnet, cfg.MTU); err != nil {
+		dev.Close()
+		return nil, fmt.Errorf("failed to configure TUN device %s: %w", dev.Name(), err)
+	}
+
+	if cfg.Broadcast {
+		routes := cfg.Routes
+		if len(routes) == 0 {
+			routes = broadcastRoutes
+		}
+		if err := addBroadcastRoutes(dev.Name(), routes); err != nil {
+			dev.Close()
+			return nil, fmt.Errorf("failed to route broadcasts to %s: %w", dev.Name(), err)
+		}
+	}
+
+	if cfg.Discovery {
+		if err := addBroadcastRoutes(dev.Name(), discoveryRoutes); err != nil {
+			dev.Close()
+			return nil, fmt.Errorf("failed to route discovery groups to %s: %w", dev.Name(), err)
+		}
+	}
+
+	if len(cfg.Apps) > 0 {
+		if err := restrictApps(dev, cfg.Apps); err != nil {
+			dev.Close()
+			return nil, fmt.Errorf("failed to restrict %s to the chosen programs: %w", dev.Name(), err)
+		}
+	}
+
+	if cfg.DNS {
+		undo, err := configureDNS(dev.Name(), ip)
+		if err != nil {
+			// Sem DNS os computadores continuam alcançáveis pelo IP
+			logger.Warn("Computer names will not resolve", "device", dev.Name(), "error", err)
+		} else if undo != nil {
+			dev = undoDevice{Device: dev, undo: undo}
+		}
+	}
+
+	return dev, nil
+}
+
+// undoDevice desfaz ao fechar a interface uma configuração do sistema que sobreviveria a ela
+type undoDevice struct {
+	Device
+	undo func()
+}
+
+func (d undoDevice) Close() error {
+	d.undo()
+	return d.Device.Close()
+}
+
+// run executa um comando de configuração de rede, incluindo a saída no erro
+func run(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %v: %w: %s", name, args, err, out)
+	}
+	return nil
+}
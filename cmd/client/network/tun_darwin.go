@@ -0,0 +1,40 @@
+package network
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/songgao/water"
+)
+
+// waterDevice adapta a interface do water, que já remove o cabeçalho de família do utun
+type waterDevice struct {
+	*water.Interface
+}
+
+func openPlatformDevice(cfg Config) (Device, error) {
+	// O macOS escolhe o nome utunN, cfg.Name é ignorado
+	iface, err := water.New(water.Config{DeviceType: water.TUN})
+	if err != nil {
+		return nil, err
+	}
+	return waterDevice{iface}, nil
+}
+
+// configureDevice configura o utun, que é ponto a ponto e precisa da rota da sub-rede à parte
+func configureDevice(name string, ip net.IP, subnet *net.IPNet, mtu int) error {
+	if err := run("ifconfig", name, "inet", ip.String(), ip.String(), "netmask", net.IP(subnet.Mask).String(), "mtu", strconv.Itoa(mtu), "up"); err != nil {
+		return err
+	}
+	return run("route", "-n", "add", "-net", subnet.String(), "-interface", name)
+}
+
+// addBroadcastRoutes manda as rotas de multicast e broadcast para o utun
+func addBroadcastRoutes(name string, routes []string) error {
+	for _, route := range routes {
+		if err := run("route", "-n", "add", "-net", route, "-interface", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/songgao/water"
+)
+
+// waterDevice adapta a interface do water, que já entrega um pacote por Read
+type waterDevice struct {
+	*water.Interface
+}
+
+func openPlatformDevice(cfg Config) (Device, error) {
+	iface, err := water.New(water.Config{
+		DeviceType: water.TUN,
+		PlatformSpecificParams: water.PlatformSpecificParams{
+			Name: cfg.Name,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return waterDevice{iface}, nil
+}
+
+// configureDevice usa o iproute2; o kernel cria a rota da sub-rede junto com o endereço
+func configureDevice(name string, ip net.IP, subnet *net.IPNet, mtu int) error {
+	prefixLen, _ := subnet.Mask.Size()
+	if err := run("ip", "addr", "add", fmt.Sprintf("%s/%d", ip, prefixLen), "dev", name); err != nil {
+		return err
+	}
+	return run("ip", "link", "set", "dev", name, "mtu", strconv.Itoa(mtu), "up")
+}
+
+// addBroadcastRoutes manda as rotas de multicast e broadcast para a interface; as rotas
+// somem junto com ela
+func addBroadcastRoutes(name string, routes []string) error {
+	for _, route := range routes {
+		if err := run("ip", "route", "add", route, "dev", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !windows
+
+package network
+
+import "net"
+
+func openPlatformDevice(cfg Config) (Device, error) {
+	return nil, ErrUnsupported
+}
+
+func configureDevice(name string, ip net.IP, subnet *net.IPNet, mtu int) error {
+	return ErrUnsupported
+}
+
+func addBroadcastRoutes(name string, routes []string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,78 @@
+package network
+
+import (
+	"io"
+	"net"
+	"strconv"
+
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+// wintunDevice adapta o dispositivo do wireguard-go, que lê e escreve pacotes em lotes.
+// O wintun.dll precisa estar ao lado do executável.
+type wintunDevice struct {
+	dev   tun.Device
+	name  string
+	bufs  [][]byte
+	sizes []int
+
+	filters io.Closer // Filtros das regras de programa, que vivem enquanto a interface existe
+}
+
+func openPlatformDevice(cfg Config) (Device, error) {
+	dev, err := tun.CreateTUN(cfg.Name, cfg.MTU)
+	if err != nil {
+		return nil, err
+	}
+	name, err := dev.Name()
+	if err != nil {
+		dev.Close()
+		return nil, err
+	}
+	return &wintunDevice{dev: dev, name: name, bufs: make([][]byte, 1), sizes: make([]int, 1)}, nil
+}
+
+func (d *wintunDevice) Name() string {
+	return d.name
+}
+
+// Read lê um pacote; o lote tem tamanho um, então nenhum pacote fica retido entre chamadas
+func (d *wintunDevice) Read(packet []byte) (int, error) {
+	d.bufs[0] = packet
+	if _, err := d.dev.Read(d.bufs, d.sizes, 0); err != nil {
+		return 0, err
+	}
+	return d.sizes[0], nil
+}
+
+func (d *wintunDevice) Write(packet []byte) (int, error) {
+	if _, err := d.dev.Write([][]byte{packet}, 0); err != nil {
+		return 0, err
+	}
+	return len(packet), nil
+}
+
+func (d *wintunDevice) Close() error {
+	if d.filters != nil {
+		d.filters.Close()
+	}
+	return d.dev.Close()
+}
+
+// configureDevice usa o netsh; a rota da sub-rede é criada junto com o endereço
+func configureDevice(name string, ip net.IP, subnet *net.IPNet, mtu int) error {
+	if err := run("netsh", "interface", "ipv4", "set", "address", "name="+name, "source=static", "addr="+ip.String(), "mask="+net.IP(subnet.Mask).String()); err != nil {
+		return err
+	}
+	return run("netsh", "interface", "ipv4", "set", "subinterface", name, "mtu="+strconv.Itoa(mtu), "store=active")
+}
+
+// addBroadcastRoutes manda as rotas de multicast e broadcast para a interface até o reinício
+func addBroadcastRoutes(name string, routes []string) error {
+	for _, route := range routes {
+		if err := run("netsh", "interface", "ipv4", "add", "route", route, name, "store=active"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Wake-on-LAN: a placa de rede de um computador desligado, com a opção ligada na BIOS, acorda
+// ao receber o "pacote mágico" com o MAC dela. O pacote só anda dentro da LAN, então quem o
+// envia é outro membro da rede que está na mesma LAN, a pedido do servidor.
+
+// wakePort é a porta de descarte, a usual do pacote mágico
+const wakePort = 9
+
+// ErrNoWakeInterface é retornado quando nenhuma interface serve para ser acordada pela LAN
+var ErrNoWakeInterface = errors.New("no wired or wireless LAN interface with a private IPv4 address")
+
+// MagicPacket monta o pacote mágico: seis bytes 0xFF seguidos de dezesseis cópias do MAC
+func MagicPacket(mac net.HardwareAddr) []byte {
+	packet := bytes.Repeat([]byte{0xFF}, 6)
+	for i := 0; i < 16; i++ {
+		packet = append(packet, mac...)
+	}
+	return packet
+}
+
+// SendMagicPacket envia o pacote mágico do MAC ao broadcast da sub-rede e ao broadcast geral
+func SendMagicPacket(mac, subnet string) error {
+	hardware, err := net.ParseMAC(mac)
+	if err != nil || len(hardware) != 6 {
+		return fmt.Errorf("invalid MAC address %q", mac)
+	}
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil || ipNet.IP.To4() == nil {
+		return fmt.Errorf("invalid subnet %q", subnet)
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	packet := MagicPacket(hardware)
+	var sent bool
+	for _, broadcast := range []net.IP{broadcastAddress(ipNet), net.IPv4bcast} {
+		if _, err = conn.WriteToUDP(packet, &net.UDPAddr{IP: broadcast, Port: wakePort}); err == nil {
+			sent = true
+		}
+	}
+	if !sent {
+		return err
+	}
+	return nil
+}
+
+// broadcastAddress retorna o último endereço da sub-rede
+func broadcastAddress(subnet *net.IPNet) net.IP {
+	ip := subnet.IP.To4()
+	broadcast := make(net.IP, len(ip))
+	for i := range ip {
+		broadcast[i] = ip[i] | ^subnet.Mask[i]
+	}
+	return broadcast
+}
+
+// WakeInterface retorna o MAC e a sub-rede da primeira interface ativa com placa física e
+// IPv4 privado, a que um membro na mesma LAN consegue acordar
+func WakeInterface() (mac, subnet string, err error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) != 6 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil || !ipNet.IP.IsPrivate() {
+				continue
+			}
+			network := &net.IPNet{IP: ipNet.IP.Mask(ipNet.Mask), Mask: ipNet.Mask}
+			return iface.HardwareAddr.String(), network.String(), nil
+		}
+	}
+	return "", "", ErrNoWakeInterface
+}
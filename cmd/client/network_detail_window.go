@@ -0,0 +1,388 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/cmd/client/icon"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// NetworkDetailWindow lists the members of a network with their IP, status and link quality.
+// The menu of each member, also opened with a right click, copies its IP and name, opens the
+// direct chat, sends files, wakes an offline member that allows it, and for the owner has the
+// actions kick, ban, reserve IP and make owner. Online peers of the connected network also
+// get a Ping button that measures the round trip on demand.
+type NetworkDetailWindow struct {
+	ui.BaseWindow
+	UI        *UIManager
+	networkID string
+
+	titleLabel  *widget.Label
+	ownerLabel  *widget.Label
+	membersBox  *fyne.Container
+	statusLabel *widget.Label
+}
+
+var globalNetworkDetailWindow *NetworkDetailWindow
+
+// NewNetworkDetailWindow creates the detail window of a network
+func NewNetworkDetailWindow(uiManager *UIManager, network *data.Network) *NetworkDetailWindow {
+	dw := &NetworkDetailWindow{
+		UI:        uiManager,
+		networkID: network.NetworkID,
+	}
+	dw.BaseWindow = *ui.NewBaseWindow(uiManager.App, network.NetworkName, 440, 420)
+	dw.BaseWindow.Window.SetOnClosed(func() {
+		globalNetworkDetailWindow = nil
+	})
+	dw.setupUI()
+	return dw
+}
+
+// setupUI initializes the UI components of the detail window
+func (dw *NetworkDetailWindow) setupUI() {
+	dw.titleLabel = widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	dw.ownerLabel = widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+	dw.membersBox = container.NewVBox()
+	dw.statusLabel = widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+	dw.statusLabel.Wrapping = fyne.TextWrapWord
+
+	content := container.NewBorder(
+		container.NewVBox(dw.titleLabel, dw.ownerLabel, widget.NewSeparator()),
+		dw.statusLabel,
+		nil,
+		nil,
+		container.NewVScroll(dw.membersBox),
+	)
+
+	dw.BaseWindow.Window.SetContent(container.NewPadded(content))
+}
+
+// network returns the current state of the network, which roster updates replace
+func (dw *NetworkDetailWindow) network() (data.Network, bool) {
+	for _, network := range dw.UI.RealtimeData.GetNetworks() {
+		if network.NetworkID == dw.networkID {
+			return network, true
+		}
+	}
+	return data.Network{}, false
+}
+
+// Refresh rebuilds the member list from the realtime data. The window closes itself once
+// the network is gone, as after leaving it or being kicked. Must run on the Fyne thread.
+func (dw *NetworkDetailWindow) Refresh() {
+	if dw.BaseWindow.Window == nil {
+		return
+	}
+	network, ok := dw.network()
+	if !ok {
+		dw.Close()
+		return
+	}
+
+	myPublicKey := dw.UI.VPN.PublicKeyStr
+	isOwner := myPublicKey != "" && network.AdminPublicKey == myPublicKey
+	isConnected := dw.UI.VPN.NetworkManager != nil && dw.UI.VPN.NetworkManager.NetworkID == network.NetworkID
+
+	dw.BaseWindow.Window.SetTitle(network.NetworkName)
+	dw.titleLabel.SetText(fmt.Sprintf("%s (%s)", network.NetworkName, network.NetworkID))
+	if isOwner {
+		dw.ownerLabel.SetText("You own this network")
+	} else {
+		dw.ownerLabel.SetText("Owned by " + memberName(network, network.AdminPublicKey))
+	}
+
+	// Online first, then by name
+	computers := append([]smodels.ComputerInfo(nil), network.Computers...)
+	sort.SliceStable(computers, func(i, j int) bool {
+		if computers[i].IsOnline != computers[j].IsOnline {
+			return computers[i].IsOnline
+		}
+		return strings.ToLower(computers[i].Name) < strings.ToLower(computers[j].Name)
+	})
+
+	dw.membersBox.RemoveAll()
+	for _, computer := range computers {
+		dw.membersBox.Add(dw.memberRow(network, computer, myPublicKey, isOwner, isConnected))
+	}
+	if len(computers) == 0 {
+		dw.membersBox.Add(widget.NewLabelWithStyle("No member information yet. Connect to the network to load it.", fyne.TextAlignCenter, fyne.TextStyle{Italic: true}))
+	}
+	dw.membersBox.Refresh()
+}
+
+// memberRow builds the row of one member: status icon, name and details, and the actions menu
+func (dw *NetworkDetailWindow) memberRow(network data.Network, computer smodels.ComputerInfo, myPublicKey string, isOwner, isConnected bool) fyne.CanvasObject {
+	isSelf := computer.PublicKey == myPublicKey
+	// Offline, the saved list only tells when each member was last seen
+	online := (computer.IsOnline && !dw.UI.RealtimeData.NetworksOffline()) || (isSelf && isConnected)
+
+	activity := icon.ConnectionOff
+	if online {
+		activity = icon.ConnectionOn
+	}
+
+	name := computer.Name
+	if isSelf {
+		name += " (you)"
+	}
+	if computer.PublicKey == network.AdminPublicKey {
+		name += " · owner"
+	}
+
+	details := []string{computer.ComputerIP}
+	if computer.IPReserved {
+		details[0] += " (reserved)"
+	}
+	if online {
+		details = append(details, "Online")
+	} else if seen, ok := dw.UI.RealtimeData.LastSeen(network.NetworkID, computer.PublicKey); ok {
+		details = append(details, "Last seen "+formatLastSeen(seen, time.Now()))
+	} else {
+		details = append(details, "Offline")
+	}
+	if !isSelf && isConnected && computer.IsOnline {
+		details = append(details, dw.linkDetails(computer)...)
+	}
+
+	nameLabel := widget.NewLabelWithStyle(name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	nameLabel.Truncation = fyne.TextTruncateEllipsis
+	detailsLabel := widget.NewLabelWithStyle(strings.Join(details, " · "), fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	detailsLabel.Truncation = fyne.TextTruncateEllipsis
+
+	showMenu := func(position fyne.Position) {
+		items := computerCopyItems(computer, dw.UI.hostNames(network)[computer.PublicKey])
+		if messageItem := dw.UI.chatItem(computer); messageItem != nil {
+			items = append(items, messageItem)
+		}
+		if isConnected {
+			if sendItem := dw.UI.sendFileItem(computer); sendItem != nil {
+				items = append(items, sendItem)
+			}
+			if !isSelf && !online && computer.Wakeable {
+				items = append(items, fyne.NewMenuItem("Wake", func() { dw.wake(network, computer) }))
+			}
+		}
+		if isOwner && dw.UI.VPN.NetworkManager != nil {
+			items = append(items, fyne.NewMenuItemSeparator())
+			items = append(items, dw.memberActions(network, computer, isSelf)...)
+		}
+		widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), dw.BaseWindow.Window.Canvas(), position)
+	}
+
+	var actionButton *widget.Button
+	actionButton = widget.NewButtonWithIcon("", theme.MoreHorizontalIcon(), func() {
+		position := fyne.CurrentApp().Driver().AbsolutePositionForObject(actionButton)
+		showMenu(position.AddXY(0, actionButton.Size().Height))
+	})
+
+	buttons := container.NewHBox(actionButton)
+	if !isSelf && isConnected && online {
+		var pingButton *widget.Button
+		pingButton = widget.NewButton("Ping", func() { dw.ping(computer, pingButton) })
+		buttons.Objects = []fyne.CanvasObject{pingButton, actionButton}
+	}
+
+	row := container.NewBorder(nil, nil, widget.NewIcon(activity), buttons,
+		container.NewVBox(nameLabel, detailsLabel))
+	return ui.NewTappableContainer(row, nil, func(pe *fyne.PointEvent) {
+		showMenu(pe.AbsolutePosition)
+	})
+}
+
+// formatLastSeen describes how long ago a member was last seen online
+func formatLastSeen(seen, now time.Time) string {
+	ago := now.Sub(seen)
+	switch {
+	case ago < time.Minute:
+		return "just now"
+	case ago < time.Hour:
+		return fmt.Sprintf("%d min ago", int(ago/time.Minute))
+	case ago < 24*time.Hour:
+		return fmt.Sprintf("%d h ago", int(ago/time.Hour))
+	case ago < 48*time.Hour:
+		return "yesterday"
+	case ago < 7*24*time.Hour:
+		return fmt.Sprintf("%d days ago", int(ago/(24*time.Hour)))
+	}
+	return seen.Local().Format("Jan 2, 2006")
+}
+
+// linkDetails describes the link with an online peer of the connected network: measured
+// ping, the path found by ICE (or the NAT prediction), a reduced MTU and server relaying
+func (dw *NetworkDetailWindow) linkDetails(computer smodels.ComputerInfo) []string {
+	var details []string
+	if link, ok := dw.UI.RealtimeData.GetPeerLink(computer.PublicKey); ok {
+		details = append(details, core.PingLabel(link))
+	}
+	if path, ok := dw.UI.RealtimeData.GetPeerPath(computer.PublicKey); ok {
+		details = append(details, core.PathLabel(path))
+	} else {
+		myNatType := dw.UI.RealtimeData.GetNatType()
+		if hint := core.DirectConnectionHint(smodels.NatType(myNatType), computer.NatType); hint != "" {
+			details = append(details, hint)
+		}
+	}
+	if mtu, ok := dw.UI.RealtimeData.GetPeerMTU(computer.PublicKey); ok && core.MTULabel(mtu) != "" {
+		details = append(details, core.MTULabel(mtu))
+	}
+	if dw.UI.VPN.NetworkManager.IsRelayed(computer.PublicKey) {
+		details = append(details, "Relayed")
+	}
+	return details
+}
+
+// memberActions returns the owner actions for a member. The owner can only reserve its own IP.
+func (dw *NetworkDetailWindow) memberActions(network data.Network, computer smodels.ComputerInfo, isSelf bool) []*fyne.MenuItem {
+	nm := dw.UI.VPN.NetworkManager
+	window := dw.BaseWindow.Window
+
+	reserveLabel := "Reserve IP " + computer.ComputerIP
+	if computer.IPReserved {
+		reserveLabel = "Release IP " + computer.ComputerIP
+	}
+	items := []*fyne.MenuItem{
+		fyne.NewMenuItem(reserveLabel, func() {
+			dw.run("Changing the IP reservation…", func() error {
+				return nm.SetIPReserved(network.NetworkID, computer.PublicKey, !computer.IPReserved)
+			})
+		}),
+	}
+	if isSelf {
+		return items
+	}
+
+	kickItem := fyne.NewMenuItem("Kick", func() {
+		dialog.ShowConfirm("Kick Computer",
+			fmt.Sprintf("Remove %s from %s? It can join again with the PIN.", computer.Name, network.NetworkName),
+			func(confirmed bool) {
+				if confirmed {
+					dw.run("Kicking "+computer.Name+"…", func() error {
+						return nm.KickComputer(network.NetworkID, computer.PublicKey)
+					})
+				}
+			}, window)
+	})
+
+	banItem := fyne.NewMenuItem("Ban…", func() {
+		reasonEntry := widget.NewEntry()
+		reasonEntry.SetPlaceHolder("Optional, shown to the computer")
+		dialog.ShowForm("Ban "+computer.Name, "Ban", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Reason", reasonEntry),
+		}, func(confirmed bool) {
+			if confirmed {
+				dw.run("Banning "+computer.Name+"…", func() error {
+					return nm.BanComputer(network.NetworkID, computer.PublicKey, strings.TrimSpace(reasonEntry.Text))
+				})
+			}
+		}, window)
+	})
+
+	transferItem := fyne.NewMenuItem("Make owner", func() {
+		dialog.ShowConfirm("Transfer Ownership",
+			fmt.Sprintf("Make %s the owner of %s? You stay in the network but can no longer manage it.", computer.Name, network.NetworkName),
+			func(confirmed bool) {
+				if confirmed {
+					dw.run("Transferring ownership…", func() error {
+						return nm.TransferOwnership(network.NetworkID, computer.PublicKey)
+					})
+				}
+			}, window)
+	})
+
+	return append(items, kickItem, banItem, fyne.NewMenuItemSeparator(), transferItem)
+}
+
+// run executes a member action off the Fyne thread and reports how it went in the status line.
+// The roster sent by the server after the change updates the list.
+func (dw *NetworkDetailWindow) run(progress string, action func() error) {
+	dw.statusLabel.SetText(progress)
+	crash.Go("network detail action", func() {
+		err := action()
+		fyne.Do(func() {
+			if err == nil {
+				dw.statusLabel.SetText("")
+				return
+			}
+			logger.Warn("Member action failed", "networkID", dw.networkID, "error", err)
+			if errors.Is(err, core.ErrQueuedOffline) {
+				dw.statusLabel.SetText("The server is unreachable; the change will be sent when it reconnects.")
+				return
+			}
+			dw.statusLabel.SetText("")
+			if dw.BaseWindow.Window != nil {
+				dialog.ShowError(err, dw.BaseWindow.Window)
+			}
+		})
+	})
+}
+
+// ping measures the round trip to an online peer right away and shows it in the status line
+func (dw *NetworkDetailWindow) ping(computer smodels.ComputerInfo, button *widget.Button) {
+	button.Disable()
+	dw.statusLabel.SetText("Pinging " + computer.Name + "…")
+	crash.Go("ping peer", func() {
+		result, err := dw.UI.VPN.NetworkManager.PingPeer(computer.PublicKey)
+		fyne.Do(func() {
+			button.Enable()
+			if err != nil {
+				dw.statusLabel.SetText(fmt.Sprintf("%s did not answer: %v", computer.Name, err))
+				return
+			}
+			dw.statusLabel.SetText(computer.Name + ": " + result.Label())
+		})
+	})
+}
+
+// wake asks the server to wake an offline member through another member on its LAN. The
+// computer shows up online once it has booted and the client started.
+func (dw *NetworkDetailWindow) wake(network data.Network, computer smodels.ComputerInfo) {
+	dw.statusLabel.SetText("Waking " + computer.Name + "…")
+	crash.Go("wake computer", func() {
+		err := dw.UI.VPN.NetworkManager.WakeComputer(network.NetworkID, computer.PublicKey)
+		fyne.Do(func() {
+			if err == nil {
+				dw.statusLabel.SetText("A member on its LAN is waking " + computer.Name + ". It may take a minute to come online.")
+				return
+			}
+			logger.Warn("Failed to wake computer", "networkID", network.NetworkID, "publicKey", computer.PublicKey, "error", err)
+			dw.statusLabel.SetText("")
+			if dw.BaseWindow.Window != nil {
+				dialog.ShowError(err, dw.BaseWindow.Window)
+			}
+		})
+	})
+}
+
+// Show shows the detail window with the current member list
+func (dw *NetworkDetailWindow) Show() {
+	dw.Refresh()
+	dw.BaseWindow.Show()
+}
+
+// memberName returns the name of a member of the network, or a shortened key when unknown
+func memberName(network data.Network, publicKey string) string {
+	for _, computer := range network.Computers {
+		if computer.PublicKey == publicKey {
+			return computer.Name
+		}
+	}
+	if len(publicKey) > 8 {
+		return publicKey[:8] + "…"
+	}
+	return publicKey
+}
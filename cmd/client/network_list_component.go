@@ -2,8 +2,8 @@ package main
 
 import (
 	"fmt"
-	"log"
-	"sort"
+	"slices"
+	"strings"
 	"sync"
 
 	"fyne.io/fyne/v2"
@@ -11,18 +11,32 @@ import (
 	"fyne.io/fyne/v2/dialog"
 
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
 	"github.com/itxtoledo/govpn/cmd/client/data"
 	"github.com/itxtoledo/govpn/cmd/client/dialogs"
 	"github.com/itxtoledo/govpn/cmd/client/icon"
 	"github.com/itxtoledo/govpn/cmd/client/ui"
+	"github.com/itxtoledo/govpn/libs/logger"
 )
 
+// Rótulos das ordens da lista de redes
+var networkSortLabels = map[string]string{
+	core.NetworkSortName:          "Name",
+	core.NetworkSortLastConnected: "Last connected",
+	core.NetworkSortMembers:       "Members",
+	core.NetworkSortOnline:        "Online",
+}
+
 // NetworkListComponent representa o componente da árvore de rede
 type NetworkListComponent struct {
 	UI               *UIManager
 	Container        *fyne.Container
 	NetworkAccordion *ui.CustomAccordion
+	SearchEntry      *widget.Entry   // Filtra as redes pelo nome, pelo ID e pelos membros
+	SortSelect       *widget.Select  // Ordem das redes, guardada na configuração
 	contentContainer *fyne.Container // New field to hold dynamic content
 	updateMutex      sync.Mutex
 }
@@ -43,9 +57,36 @@ func (ntc *NetworkListComponent) init() {
 	// Initialize the dynamic content container
 	ntc.contentContainer = container.NewStack()
 
+	// Busca e ordem da lista; a busca vale só enquanto o aplicativo está aberto
+	ntc.SearchEntry = widget.NewEntry()
+	ntc.SearchEntry.SetPlaceHolder("Search networks")
+	ntc.SearchEntry.OnChanged = func(string) {
+		ntc.UpdateNetworkList(ntc.UI.openAccordionStates)
+	}
+	ntc.SortSelect = widget.NewSelect([]string{
+		networkSortLabels[core.NetworkSortName],
+		networkSortLabels[core.NetworkSortLastConnected],
+		networkSortLabels[core.NetworkSortMembers],
+		networkSortLabels[core.NetworkSortOnline],
+	}, nil)
+	ntc.SortSelect.SetSelected(networkSortLabels[ntc.UI.ConfigManager.GetConfig().NetworkSort])
+	if ntc.SortSelect.Selected == "" {
+		ntc.SortSelect.SetSelected(networkSortLabels[core.NetworkSortName])
+	}
+	ntc.SortSelect.OnChanged = func(label string) {
+		for order, orderLabel := range networkSortLabels {
+			if orderLabel == label {
+				if err := ntc.UI.ConfigManager.SetNetworkSort(order); err != nil {
+					logger.Error("Failed to save the network list order", "error", err)
+				}
+			}
+		}
+		ntc.UpdateNetworkList(ntc.UI.openAccordionStates)
+	}
+
 	// Criar o container principal
 	ntc.Container = container.NewBorder(
-		nil, // widget.NewLabelWithStyle("Available Networks", fyne.TextAlignLeading, fyne.TextStyle{Bold: true, Monospace: false}),
+		container.NewBorder(nil, nil, nil, ntc.SortSelect, ntc.SearchEntry),
 		nil,
 		nil,
 		nil,
@@ -63,29 +104,20 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 		// Clear the content container before adding new content
 		ntc.contentContainer.RemoveAll()
 
-		// Get networks directly from the binding
-		networksList, err := ntc.UI.RealtimeData.Networks.Get()
-		if err != nil {
-			log.Printf("Error getting networks from binding: %v", err)
-			return
-		}
-		log.Printf("UpdateNetworkList: Retrieved %d networks from binding.", len(networksList))
+		networks := ntc.UI.RealtimeData.GetNetworks()
 
-		// Convert to a slice of Network for sorting
-		networks := make([]data.Network, len(networksList))
-		for i, item := range networksList {
-			if networkPtr, ok := item.(*data.Network); ok {
-				networks[i] = *networkPtr
-			} else {
-				log.Printf("Type assertion failed for network at index %d", i)
-			}
+		core.SortNetworks(networks, ntc.UI.ConfigManager.GetConfig().NetworkSort)
+		total := len(networks)
+		if query := strings.ToLower(strings.TrimSpace(ntc.SearchEntry.Text)); query != "" {
+			networks = slices.DeleteFunc(networks, func(network data.Network) bool {
+				return !networkMatches(network, query)
+			})
 		}
 
-		sort.Slice(networks, func(i, j int) bool {
-			return networks[i].NetworkName < networks[j].NetworkName
-		})
+		// Sem o servidor, a lista é a última conhecida e o estado dos membros pode ter mudado
+		offline := ntc.UI.RealtimeData.NetworksOffline()
 
-		log.Printf("UpdateNetworkList: Processed %d networks for display.", len(networks))
+		logger.Debug("Updating network list", "networks", len(networks), "total", total, "offline", offline)
 
 		if len(networks) > 0 {
 			// Store the open state of current accordion items in the passed map
@@ -105,14 +137,14 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 			}
 
 			// Get computername from config for display
-			computername, _ := ntc.UI.RealtimeData.ComputerName.Get()
+			computername := ntc.UI.RealtimeData.GetComputerName()
 			if computername == "" {
 				computername = "You"
 			}
 
 			// Add each network as an accordion item
 			for _, network := range networks {
-				log.Printf("Processing network: %s (ID=%s)", network.NetworkName, network.NetworkID)
+				logger.Debug("Adding network to the list", "name", network.NetworkName, "networkID", network.NetworkID)
 				// Check if this network is the one we're currently connected to
 				// Use a copy of the network for the closure to avoid unexpected behavior
 				// due to loop variable reuse.
@@ -121,6 +153,7 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 
 				// Create connected computers list
 				computersContainer := container.NewVBox()
+				hostNames := ntc.UI.hostNames(localNetwork)
 
 				// Use apenas os computadores que vêm do servidor
 
@@ -139,26 +172,44 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 
 						// Se este computador for o nosso e estivermos conectados a esta rede,
 						// mostrar como conectado independentemente do status online
-						// log.Printf("NetworkListComponent: Computer %s (Network %s) IsOnline: %t", computer.Name, localNetwork.NetworkName, computer.IsOnline)
+						// logger.Debug("Computer", "name", computer.Name, "network", localNetwork.NetworkName, "online", computer.IsOnline)
 						if isConnected && myPublicKey != "" && computer.PublicKey == myPublicKey {
 							activity = icon.ConnectionOn
-						} else if computer.IsOnline {
+						} else if computer.IsOnline && !offline {
 							activity = icon.ConnectionOn
 						}
 
-						var displayComputerName string
-						if len(computer.Name) > 5 {
-							displayComputerName = computer.Name[:5] + "..."
-						} else {
-							displayComputerName = computer.Name
-						}
-						computerItem := container.NewHBox(
+						// Os detalhes de cada computador (ping, caminho, MTU) ficam na janela de detalhes
+						nameLabel := widget.NewLabelWithStyle(computer.Name, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+						nameLabel.Truncation = fyne.TextTruncateEllipsis
+						computerItem := container.NewBorder(nil, nil,
 							widget.NewIcon(activity),
-							widget.NewLabelWithStyle(displayComputerName, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true}),
-							layout.NewSpacer(),
 							widget.NewLabelWithStyle(computer.ComputerIP, fyne.TextAlignTrailing, fyne.TextStyle{Monospace: true}),
+							nameLabel,
 						)
-						computersContainer.Add(computerItem)
+						// O botão direito copia o IP, que costuma ser digitado em jogos e outros
+						// programas, abre a conversa direta e envia arquivos aos computadores
+						// online da rede conectada e limita a banda enviada a eles
+						menuItems := computerCopyItems(computer, hostNames[computer.PublicKey])
+						var peerItems []*fyne.MenuItem
+						if messageItem := ntc.UI.chatItem(computer); messageItem != nil {
+							peerItems = append(peerItems, messageItem)
+						}
+						if isConnected {
+							if sendItem := ntc.UI.sendFileItem(computer); sendItem != nil {
+								peerItems = append(peerItems, sendItem)
+							}
+						}
+						if limitItem := ntc.UI.peerLimitItem(localNetwork, computer); limitItem != nil {
+							peerItems = append(peerItems, limitItem)
+						}
+						if len(peerItems) > 0 {
+							menuItems = append(append(menuItems, fyne.NewMenuItemSeparator()), peerItems...)
+						}
+						computersContainer.Add(ui.NewTappableContainer(computerItem, nil, func(pe *fyne.PointEvent) {
+							menu := fyne.NewMenu(computer.Name, menuItems...)
+							widget.NewPopUpMenu(menu, ntc.UI.MainWindow.Canvas()).ShowAtPosition(pe.AbsolutePosition)
+						}))
 					}
 				}
 
@@ -166,13 +217,15 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 				computersBox := computersContainer
 
 				// Create actions section
-				actionsBox := container.NewVBox()
-
-				content := container.NewHBox(
-					container.NewVBox(
-						computersBox,
-						actionsBox,
-					),
+				detailsButton := widget.NewButtonWithIcon("Details", theme.InfoIcon(), func() {
+					ntc.UI.OpenNetworkDetailWindow(&localNetwork)
+				})
+				detailsButton.Importance = widget.LowImportance
+				actionsBox := container.NewHBox(layout.NewSpacer(), detailsButton)
+
+				content := container.NewVBox(
+					computersBox,
+					actionsBox,
 				)
 
 				// Create custom title without activity indicator
@@ -191,6 +244,10 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 				}
 				titleLabel := widget.NewLabelWithStyle(fmt.Sprintf("%s (%s)", localNetwork.NetworkName, localNetwork.NetworkID), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
 				computerCountLabel := widget.NewLabelWithStyle(fmt.Sprintf("(%d/10)", connectedComputers), fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+				if offline {
+					computerCountLabel.SetText("offline")
+					computerCountLabel.Importance = widget.WarningImportance
+				}
 
 				customTitle := container.NewHBox(
 					titleLabel,
@@ -198,27 +255,20 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 
 				// Create custom accordion item with context menu support and computer count
 				accordionItem := ui.NewCustomAccordionItemWithEndContentAndCallbacks(customTitle, content, computerCountLabel, nil, func(pe *fyne.PointEvent) {
-					copyIDItem := fyne.NewMenuItem("Copy network ID", func() {
-						fyne.CurrentApp().Clipboard().SetContent(localNetwork.NetworkID)
-						fyne.CurrentApp().SendNotification(&fyne.Notification{
-							Title:   "Copied!",
-							Content: "Network ID copied to clipboard.",
-						})
-					})
 
 					leaveItem := fyne.NewMenuItem("Leave Network", func() {
 						// Delegate deletion to NetworkManager
 						if ntc.UI.VPN.NetworkManager != nil {
-							go func() {
+							crash.Go("leave network", func() {
 								err := ntc.UI.VPN.NetworkManager.LeaveNetworkById(localNetwork.NetworkID)
 								if err != nil {
-									log.Printf("Error deleting network: %v", err)
+									logger.Warn("Error leaving network", "networkID", localNetwork.NetworkID, "error", err)
 									fyne.CurrentApp().SendNotification(&fyne.Notification{
 										Title:   "Error",
 										Content: "Failed to leave network: " + err.Error(),
 									})
 								} else {
-									log.Println("Successfully left network:", localNetwork.NetworkName)
+									logger.Info("Left network", "name", localNetwork.NetworkName)
 									fyne.CurrentApp().SendNotification(&fyne.Notification{
 										Title:   "Success",
 										Content: "Successfully left network: " + localNetwork.NetworkName,
@@ -227,7 +277,7 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 									// Show success dialog on the main thread
 									dialog.ShowInformation("Success", "Successfully left network: "+localNetwork.NetworkName, ntc.UI.MainWindow)
 								}
-							}()
+							})
 						}
 					})
 
@@ -245,17 +295,17 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 
 						if isConnected {
 							// If already connected, disconnect
-							log.Println("Disconnecting from network:", localNetwork.NetworkName)
-							go func() {
+							logger.Debug("Disconnecting from network", "name", localNetwork.NetworkName)
+							crash.Go("disconnect network", func() {
 								err := ntc.UI.VPN.NetworkManager.DisconnectNetwork(localNetwork.NetworkID)
 								if err != nil {
-									log.Printf("Error disconnecting from network: %v", err)
+									logger.Warn("Error disconnecting from network", "networkID", localNetwork.NetworkID, "error", err)
 									dialog.ShowError(fmt.Errorf("failed to disconnect from network: %v", err), ntc.UI.MainWindow)
 								} else {
-									log.Println("Successfully disconnected from network.")
+									logger.Debug("Disconnected from network", "name", localNetwork.NetworkName)
 									dialog.ShowInformation("Success", "Successfully disconnected from network.", ntc.UI.MainWindow)
 								}
-							}()
+							})
 						} else {
 							// Show connection dialog
 							if ntc.UI.ConnectDialog == nil {
@@ -265,7 +315,47 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 						}
 					})
 
-					menu := fyne.NewMenu(localNetwork.NetworkName, connectItem, chatItem, copyIDItem, fyne.NewMenuItemSeparator(), leaveItem)
+					autoConnect := slices.Contains(ntc.UI.ConfigManager.GetConfig().AutoConnectNetworks, localNetwork.NetworkID)
+					autoConnectItem := fyne.NewMenuItem("Connect on start", func() {
+						if err := ntc.UI.ConfigManager.SetAutoConnect(localNetwork.NetworkID, !autoConnect); err != nil {
+							dialog.ShowError(fmt.Errorf("failed to save auto-connect: %v", err), ntc.UI.MainWindow)
+						}
+					})
+					autoConnectItem.Checked = autoConnect
+
+					detailsItem := fyne.NewMenuItem("Details…", func() {
+						ntc.UI.OpenNetworkDetailWindow(&localNetwork)
+					})
+
+					presetsItem := fyne.NewMenuItem("Game presets…", func() {
+						ntc.UI.OpenGamePresetsWindow(&localNetwork)
+					})
+
+					historyItem := fyne.NewMenuItem("Traffic history", func() {
+						ntc.UI.OpenTrafficHistoryWindow(localNetwork.NetworkID)
+					})
+
+					items := []*fyne.MenuItem{connectItem, autoConnectItem, detailsItem, presetsItem, ntc.UI.networkLimitItem(localNetwork), historyItem, chatItem, ntc.UI.messagesItem()}
+					if isConnected {
+						items = append(items, fyne.NewMenuItem("File transfers", ntc.UI.OpenFileTransfersWindow))
+						items = append(items, fyne.NewMenuItem("Find game hosts", ntc.UI.OpenServiceScanWindow))
+					}
+					items = append(items, fyne.NewMenuItemSeparator())
+					items = append(items, ntc.UI.networkCopyItems(localNetwork)...)
+					if myPublicKey != "" && localNetwork.AdminPublicKey == myPublicKey {
+						items = append(items, fyne.NewMenuItemSeparator(), fyne.NewMenuItem("Statistics", func() {
+							ntc.UI.OpenNetworkStatsWindow(&localNetwork)
+						}), fyne.NewMenuItem("Invite link…", func() {
+							dialogs.ShowInviteDialog(localNetwork.NetworkID, localNetwork.NetworkName, ntc.UI.currentServer(), ntc.UI.MainWindow)
+						}), fyne.NewMenuItem("Change PIN", func() {
+							dialogs.ShowChangePINDialog(localNetwork.NetworkName, func(pin string) error {
+								return ntc.UI.VPN.NetworkManager.ChangeNetworkPIN(localNetwork.NetworkID, pin)
+							}, ntc.UI.MainWindow)
+						}))
+					}
+					items = append(items, fyne.NewMenuItemSeparator(), leaveItem)
+
+					menu := fyne.NewMenu(localNetwork.NetworkName, items...)
 					popUp := widget.NewPopUpMenu(menu, ntc.UI.MainWindow.Canvas())
 					popUp.ShowAtPosition(pe.AbsolutePosition)
 				}, localNetwork.NetworkID)
@@ -284,9 +374,13 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 
 			if len(ntc.NetworkAccordion.Items) > 0 {
 				// Add the accordion container to the content container
-				ntc.contentContainer.Add(ntc.NetworkAccordion.GetContainer())
+				if offline {
+					ntc.contentContainer.Add(container.NewBorder(offlineBadge(), nil, nil, nil, ntc.NetworkAccordion.GetContainer()))
+				} else {
+					ntc.contentContainer.Add(ntc.NetworkAccordion.GetContainer())
+				}
 			} else {
-				log.Printf("No networks available to display after filtering/processing") // Add informative message when no networks are available
+				// Add informative message when no networks are available
 				noNetworksLabel := widget.NewLabelWithStyle(
 					"No networks available.\nCreate or join a network to get started.",
 					fyne.TextAlignCenter,
@@ -295,10 +389,13 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 				ntc.contentContainer.Add(container.NewCenter(noNetworksLabel)) // Add centered label
 			}
 		} else {
-			log.Printf("No networks available to display")
 			// Add informative message when no networks are available
+			message := "No networks available.\nCreate or join a network to get started."
+			if total > 0 {
+				message = fmt.Sprintf("No networks match \"%s\".", strings.TrimSpace(ntc.SearchEntry.Text))
+			}
 			noNetworksLabel := widget.NewLabelWithStyle(
-				"No networks available.\nCreate or join a network to get started.",
+				message,
 				fyne.TextAlignCenter,
 				fyne.TextStyle{Italic: true},
 			)
@@ -311,6 +408,25 @@ func (ntc *NetworkListComponent) UpdateNetworkList(openStates map[string]bool) {
 	})
 }
 
+// offlineBadge avisa que a lista é a última recebida do servidor
+func offlineBadge() fyne.CanvasObject {
+	label := widget.NewLabel("Offline: showing the last known networks")
+	label.Importance = widget.WarningImportance
+	label.Wrapping = fyne.TextWrapWord
+	return container.NewBorder(nil, nil, widget.NewIcon(theme.WarningIcon()), nil, label)
+}
+
+// networkMatches diz se a rede tem a busca, já em minúsculas, no nome, no ID ou no nome ou
+// IP de algum membro
+func networkMatches(network data.Network, query string) bool {
+	if strings.Contains(strings.ToLower(network.NetworkName), query) || strings.Contains(strings.ToLower(network.NetworkID), query) {
+		return true
+	}
+	return slices.ContainsFunc(network.Computers, func(computer data.ComputerInfo) bool {
+		return strings.Contains(strings.ToLower(computer.Name), query) || strings.HasPrefix(computer.ComputerIP, query)
+	})
+}
+
 // GetContainer retorna o container principal
 func (ntc *NetworkListComponent) GetContainer() *fyne.Container {
 	return ntc.Container
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// NetworkStatsWindow shows the activity counters the server keeps for an owned network
+type NetworkStatsWindow struct {
+	ui.BaseWindow
+	network *data.Network
+	fetch   func(networkID string) (*smodels.NetworkStatsResponse, error)
+
+	membersLabel  *widget.Label
+	joinsLabel    *widget.Label
+	peakLabel     *widget.Label
+	activityLabel *widget.Label
+	relayedLabel  *widget.Label
+	sinceLabel    *widget.Label
+	statusLabel   *widget.Label
+}
+
+var globalNetworkStatsWindow *NetworkStatsWindow
+
+// NewNetworkStatsWindow creates the statistics window of a network; fetch asks the server for the counters
+func NewNetworkStatsWindow(app fyne.App, network *data.Network, fetch func(networkID string) (*smodels.NetworkStatsResponse, error)) *NetworkStatsWindow {
+	sw := &NetworkStatsWindow{
+		network: network,
+		fetch:   fetch,
+	}
+	sw.BaseWindow = *ui.NewBaseWindow(app, network.NetworkName+" Statistics", 360, 300)
+	sw.BaseWindow.Window.SetOnClosed(func() {
+		globalNetworkStatsWindow = nil
+	})
+	sw.setupUI()
+	return sw
+}
+
+// setupUI initializes the UI components of the statistics window
+func (sw *NetworkStatsWindow) setupUI() {
+	sw.membersLabel = widget.NewLabel("-")
+	sw.joinsLabel = widget.NewLabel("-")
+	sw.peakLabel = widget.NewLabel("-")
+	sw.activityLabel = widget.NewLabel("-")
+	sw.relayedLabel = widget.NewLabel("-")
+	sw.sinceLabel = widget.NewLabel("-")
+	sw.statusLabel = widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+
+	form := widget.NewForm(
+		widget.NewFormItem("Members", sw.membersLabel),
+		widget.NewFormItem("Total joins", sw.joinsLabel),
+		widget.NewFormItem("Peak online", sw.peakLabel),
+		widget.NewFormItem("Last activity", sw.activityLabel),
+		widget.NewFormItem("Relayed", sw.relayedLabel),
+		widget.NewFormItem("Counting since", sw.sinceLabel),
+	)
+
+	refreshButton := widget.NewButton("Refresh", func() {
+		crash.Go("network stats", sw.refresh)
+	})
+
+	content := container.NewBorder(
+		widget.NewLabelWithStyle(fmt.Sprintf("%s (%s)", sw.network.NetworkName, sw.network.NetworkID), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		container.NewVBox(sw.statusLabel, refreshButton),
+		nil,
+		nil,
+		form,
+	)
+
+	sw.BaseWindow.Window.SetContent(container.NewPadded(content))
+}
+
+// refresh fetches the counters from the server and updates the labels
+func (sw *NetworkStatsWindow) refresh() {
+	stats, err := sw.fetch(sw.network.NetworkID)
+	if err != nil {
+		logger.Warn("Error fetching network statistics", "networkID", sw.network.NetworkID, "error", err)
+		fyne.Do(func() {
+			sw.statusLabel.SetText("Failed to load statistics: " + err.Error())
+		})
+		return
+	}
+
+	fyne.Do(func() {
+		sw.membersLabel.SetText(fmt.Sprintf("%d (%d online)", stats.Members, stats.OnlineMembers))
+		sw.joinsLabel.SetText(strconv.FormatInt(stats.TotalJoins, 10))
+		sw.peakLabel.SetText(strconv.Itoa(stats.PeakMembers))
+		sw.activityLabel.SetText(stats.LastActivity.Local().Format(time.DateTime))
+		sw.relayedLabel.SetText(fmt.Sprintf("%d messages, %s", stats.RelayedMessages, formatBytes(stats.RelayedBytes)))
+		sw.sinceLabel.SetText(stats.Since.Local().Format(time.DateTime))
+		sw.statusLabel.SetText("Counters reset when the server restarts")
+	})
+}
+
+// Show shows the statistics window and loads the counters
+func (sw *NetworkStatsWindow) Show() {
+	sw.BaseWindow.Show()
+	crash.Go("network stats", sw.refresh)
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// NoticeBanner shows the latest server notice under the header until it is dismissed
+type NoticeBanner struct {
+	icon      *widget.Icon
+	label     *widget.Label
+	container *fyne.Container
+
+	mu        sync.Mutex
+	current   string          // ID of the notice on display
+	dismissed map[string]bool // Notices closed by the user, not shown again this session
+}
+
+// NewNoticeBanner creates a hidden notice banner
+func NewNoticeBanner() *NoticeBanner {
+	nb := &NoticeBanner{
+		icon:      widget.NewIcon(theme.InfoIcon()),
+		label:     widget.NewLabel(""),
+		dismissed: make(map[string]bool),
+	}
+	nb.label.Wrapping = fyne.TextWrapWord
+
+	closeButton := widget.NewButtonWithIcon("", theme.CancelIcon(), nb.dismiss)
+	closeButton.Importance = widget.LowImportance
+
+	nb.container = container.NewBorder(nil, nil, nb.icon, closeButton, nb.label)
+	nb.container.Hide()
+
+	return nb
+}
+
+// Container returns the banner's container, to be placed in the main window
+func (nb *NoticeBanner) Container() *fyne.Container {
+	return nb.container
+}
+
+// ShowNotice displays a notice, unless the user already dismissed it
+func (nb *NoticeBanner) ShowNotice(notice smodels.ServerNoticeNotification) {
+	nb.mu.Lock()
+	if nb.dismissed[notice.ID] {
+		nb.mu.Unlock()
+		return
+	}
+	nb.current = notice.ID
+	nb.mu.Unlock()
+
+	fyne.Do(func() {
+		if notice.Level == smodels.NoticeLevelWarning {
+			nb.icon.SetResource(theme.WarningIcon())
+		} else {
+			nb.icon.SetResource(theme.InfoIcon())
+		}
+		nb.label.SetText(notice.Message)
+		nb.container.Show()
+	})
+}
+
+// dismiss hides the banner and remembers the notice so reconnecting does not bring it back
+func (nb *NoticeBanner) dismiss() {
+	nb.mu.Lock()
+	nb.dismissed[nb.current] = true
+	nb.mu.Unlock()
+
+	nb.container.Hide()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+)
+
+// notificationKind is an event that can raise a desktop notification, with its title and
+// the label of its toggle in the settings
+type notificationKind struct {
+	Event data.EventType
+	Title string
+	Label string
+}
+
+// notificationKinds lists the events with desktop notifications, in the order of the settings
+var notificationKinds = []notificationKind{
+	{data.EventComputerJoined, "Computer joined", "A computer joins a network"},
+	{data.EventComputerLeft, "Computer left", "A computer leaves a network"},
+	{data.EventKicked, "Removed from network", "This computer is removed from a network"},
+	{data.EventNetworkDeleted, "Network deleted", "A network is deleted"},
+	{data.EventServerShutdown, "Server shutting down", "The server is shutting down"},
+	{data.EventPeerConnected, "Peer connected", "A direct connection to a peer is established"},
+	{data.EventPeerLost, "Peer connection lost", "A direct connection to a peer is lost"},
+	{data.EventFileOffered, "Incoming file", "A computer offers a file"},
+	{data.EventFileTransferFinished, "File transfer finished", "A file transfer finishes or fails"},
+	{data.EventChatMessage, "New message", "A computer sends you a message"},
+}
+
+// notify shows a desktop notification for the event, unless it was turned off in the settings
+func (ui *UIManager) notify(event data.Event) {
+	if !ui.ConfigManager.GetConfig().NotificationEnabled(event.Type) {
+		return
+	}
+
+	for _, kind := range notificationKinds {
+		if kind.Event != event.Type {
+			continue
+		}
+		content := event.Message
+		// The deletion event carries the network ID as its message and the name as its data
+		if event.Type == data.EventNetworkDeleted {
+			name, _ := event.Data.(string)
+			content = fmt.Sprintf("Network %s was deleted by its owner.", name)
+		}
+		fyne.CurrentApp().SendNotification(&fyne.Notification{
+			Title:   kind.Title,
+			Content: content,
+		})
+		return
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/dialogs"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// PeerChatWindow holds the direct conversations with other computers, one tab per peer.
+// Messages sent while the peer is offline wait and go out once its data channel opens.
+type PeerChatWindow struct {
+	ui.BaseWindow
+	UI *UIManager
+
+	tabs  *container.DocTabs
+	chats map[string]*peerChatTab
+}
+
+// peerChatTab is the conversation with one peer
+type peerChatTab struct {
+	peer        string
+	name        string
+	unread      int
+	item        *container.TabItem
+	messagesBox *fyne.Container
+	scroll      *container.Scroll
+}
+
+var globalPeerChatWindow *PeerChatWindow
+
+// unreadChats counts the messages that arrived from each peer while the chat window was
+// closed; its tabs open with the window. Only touched on the Fyne thread.
+var unreadChats = map[string]int{}
+
+// NewPeerChatWindow creates the direct chat window
+func NewPeerChatWindow(uiManager *UIManager) *PeerChatWindow {
+	cw := &PeerChatWindow{
+		UI:    uiManager,
+		chats: make(map[string]*peerChatTab),
+	}
+	cw.BaseWindow = *ui.NewBaseWindow(uiManager.App, "Messages", 440, 480)
+	cw.BaseWindow.Window.SetOnClosed(func() {
+		globalPeerChatWindow = nil
+	})
+
+	cw.tabs = container.NewDocTabs()
+	cw.tabs.OnClosed = func(item *container.TabItem) {
+		for peer, chat := range cw.chats {
+			if chat.item == item {
+				delete(cw.chats, peer)
+			}
+		}
+	}
+	cw.tabs.OnSelected = func(item *container.TabItem) {
+		for _, chat := range cw.chats {
+			if chat.item == item {
+				cw.markRead(chat)
+			}
+		}
+	}
+	cw.BaseWindow.Window.SetContent(cw.tabs)
+	return cw
+}
+
+// openChat selects the tab of a peer, creating it with the stored history if needed
+func (cw *PeerChatWindow) openChat(peer, name string) *peerChatTab {
+	if chat, ok := cw.chats[peer]; ok {
+		cw.tabs.Select(chat.item)
+		return chat
+	}
+
+	chat := &peerChatTab{peer: peer, name: name, messagesBox: container.NewVBox()}
+	chat.scroll = container.NewVScroll(chat.messagesBox)
+
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("Message " + name)
+	send := func() {
+		text := entry.Text
+		if text == "" {
+			return
+		}
+		entry.SetText("")
+		cw.send(chat, text)
+	}
+	entry.OnSubmitted = func(string) { send() }
+	sendButton := widget.NewButtonWithIcon("", theme.MailSendIcon(), send)
+
+	clearButton := widget.NewButtonWithIcon("Clear history", theme.DeleteIcon(), func() {
+		dialog.ShowConfirm("Clear History",
+			fmt.Sprintf("Delete the conversation with %s from this computer?", name),
+			func(confirmed bool) {
+				if !confirmed {
+					return
+				}
+				if err := cw.UI.VPN.NetworkManager.ClearChatHistory(peer); err != nil {
+					dialogs.ShowError(err, cw.BaseWindow.Window)
+				}
+				cw.refreshChat(chat)
+			}, cw.BaseWindow.Window)
+	})
+	clearButton.Importance = widget.LowImportance
+
+	content := container.NewBorder(
+		container.NewHBox(clearButton),
+		container.NewBorder(nil, nil, nil, sendButton, entry),
+		nil,
+		nil,
+		chat.scroll,
+	)
+	chat.item = container.NewTabItem(name, container.NewPadded(content))
+	cw.chats[peer] = chat
+	cw.tabs.Append(chat.item)
+	cw.tabs.Select(chat.item)
+	cw.refreshChat(chat)
+	cw.BaseWindow.Window.Canvas().Focus(entry)
+	return chat
+}
+
+// send hands a message to the network manager off the Fyne thread and shows it once stored
+func (cw *PeerChatWindow) send(chat *peerChatTab, text string) {
+	nm := cw.UI.VPN.NetworkManager
+	crash.Go("send chat message", func() {
+		_, err := nm.SendChatMessage(chat.peer, text)
+		fyne.Do(func() {
+			if err != nil {
+				logger.Warn("Failed to send chat message", "peer", chat.peer, "error", err)
+				if cw.BaseWindow.Window != nil {
+					dialogs.ShowError(err, cw.BaseWindow.Window)
+				}
+				return
+			}
+			cw.refreshChat(chat)
+		})
+	})
+}
+
+// refreshChat rebuilds the messages of a tab from the stored history and scrolls to the newest
+func (cw *PeerChatWindow) refreshChat(chat *peerChatTab) {
+	messages := cw.UI.VPN.NetworkManager.ChatHistory(chat.peer)
+
+	chat.messagesBox.RemoveAll()
+	for _, message := range messages {
+		chat.messagesBox.Add(cw.messageRow(chat, message))
+	}
+	if len(messages) == 0 {
+		chat.messagesBox.Add(widget.NewLabelWithStyle("No messages yet. They are kept on this computer only.", fyne.TextAlignCenter, fyne.TextStyle{Italic: true}))
+	}
+	chat.messagesBox.Refresh()
+	chat.scroll.ScrollToBottom()
+}
+
+// messageRow builds one message: sender, time and delivery state, then the text. A right
+// click copies the text, handy for passwords and addresses.
+func (cw *PeerChatWindow) messageRow(chat *peerChatTab, message core.ChatMessage) fyne.CanvasObject {
+	header := fmt.Sprintf("%s · %s", chat.name, chatTime(message.Time))
+	align := fyne.TextAlignLeading
+	if message.Outgoing {
+		header = fmt.Sprintf("You · %s · %s", chatTime(message.Time), deliveryLabel(message.State))
+		align = fyne.TextAlignTrailing
+	}
+
+	headerLabel := widget.NewLabelWithStyle(header, align, fyne.TextStyle{Italic: true})
+	textLabel := widget.NewLabelWithStyle(message.Text, align, fyne.TextStyle{})
+	textLabel.Wrapping = fyne.TextWrapWord
+
+	return ui.NewTappableContainer(container.NewVBox(headerLabel, textLabel), nil, func(pe *fyne.PointEvent) {
+		menu := fyne.NewMenu("", fyne.NewMenuItem("Copy text", func() {
+			copyToClipboard(message.Text, "Message")
+		}))
+		widget.ShowPopUpMenuAtPosition(menu, cw.BaseWindow.Window.Canvas(), pe.AbsolutePosition)
+	})
+}
+
+// update shows a received message or a new delivery state in the tab of the peer. Received
+// messages open the tab and count as unread until it is selected.
+func (cw *PeerChatWindow) update(event core.ChatEvent) {
+	chat, ok := cw.chats[event.Peer]
+	if !ok {
+		if event.Message.Outgoing {
+			return
+		}
+		selected := cw.tabs.Selected()
+		chat = cw.openChat(event.Peer, cw.UI.peerName(event.Peer))
+		if selected != nil {
+			cw.tabs.Select(selected)
+		}
+	}
+	cw.refreshChat(chat)
+
+	if !event.Message.Outgoing && cw.tabs.Selected() != chat.item {
+		chat.unread++
+		chat.item.Text = fmt.Sprintf("%s (%d)", chat.name, chat.unread)
+		cw.tabs.Refresh()
+	}
+}
+
+// markRead clears the unread count of a tab
+func (cw *PeerChatWindow) markRead(chat *peerChatTab) {
+	if chat.unread == 0 {
+		return
+	}
+	chat.unread = 0
+	chat.item.Text = chat.name
+	cw.tabs.Refresh()
+}
+
+// OpenPeerChat shows the chat window with the conversation with a computer
+func (ui *UIManager) OpenPeerChat(computer smodels.ComputerInfo) {
+	if globalPeerChatWindow == nil || globalPeerChatWindow.BaseWindow.Window == nil {
+		ui.showPeerChatWindow()
+	}
+	delete(unreadChats, computer.PublicKey)
+	globalPeerChatWindow.openChat(computer.PublicKey, computer.Name)
+	globalPeerChatWindow.BaseWindow.Window.RequestFocus()
+}
+
+// showPeerChatWindow creates the chat window with a tab for each peer with unread messages
+func (ui *UIManager) showPeerChatWindow() {
+	globalPeerChatWindow = NewPeerChatWindow(ui)
+	for peer, unread := range unreadChats {
+		chat := globalPeerChatWindow.openChat(peer, ui.peerName(peer))
+		chat.unread = unread
+		chat.item.Text = fmt.Sprintf("%s (%d)", chat.name, unread)
+	}
+	clear(unreadChats)
+	globalPeerChatWindow.tabs.Refresh()
+	globalPeerChatWindow.BaseWindow.Show()
+}
+
+// handleChatEvent passes a chat event to the window, or counts the message as unread while
+// the window is closed
+func (ui *UIManager) handleChatEvent(event core.ChatEvent) {
+	fyne.Do(func() {
+		if globalPeerChatWindow != nil && globalPeerChatWindow.BaseWindow.Window != nil {
+			globalPeerChatWindow.update(event)
+			return
+		}
+		if !event.Message.Outgoing {
+			unreadChats[event.Peer]++
+		}
+	})
+}
+
+// chatItem returns the context menu entry that opens the conversation with a computer, or
+// nil for this computer
+func (ui *UIManager) chatItem(computer smodels.ComputerInfo) *fyne.MenuItem {
+	if ui.VPN.NetworkManager == nil || computer.PublicKey == ui.VPN.PublicKeyStr {
+		return nil
+	}
+	return fyne.NewMenuItem("Message…", func() {
+		ui.OpenPeerChat(computer)
+	})
+}
+
+// messagesItem returns the menu entry that opens the chat window, counting the unread messages
+func (ui *UIManager) messagesItem() *fyne.MenuItem {
+	label := "Messages"
+	unread := 0
+	for _, count := range unreadChats {
+		unread += count
+	}
+	if unread > 0 {
+		label = fmt.Sprintf("Messages (%d unread)", unread)
+	}
+	return fyne.NewMenuItem(label, func() {
+		if globalPeerChatWindow != nil && globalPeerChatWindow.BaseWindow.Window != nil {
+			globalPeerChatWindow.BaseWindow.Window.RequestFocus()
+			return
+		}
+		ui.showPeerChatWindow()
+	})
+}
+
+// chatTime formats the time of a message, with the date when it is not from today
+func chatTime(t time.Time) string {
+	now := time.Now()
+	if t.Year() == now.Year() && t.YearDay() == now.YearDay() {
+		return t.Format("15:04")
+	}
+	return t.Format("Jan 2 15:04")
+}
+
+// deliveryLabel describes the delivery state of a sent message
+func deliveryLabel(state core.ChatDeliveryState) string {
+	switch state {
+	case core.ChatDelivered:
+		return "Delivered"
+	case core.ChatSent:
+		return "Sent"
+	default:
+		return "Waiting for the peer"
+	}
+}
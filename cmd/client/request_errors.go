@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	sclient "github.com/itxtoledo/govpn/libs/signaling/client"
+)
+
+// requestError turns the error of a request to the server into what the user should read,
+// with advice for the failures the signaling client reports by kind. action completes
+// "failed to ..." for every other error.
+func requestError(action string, err error) error {
+	switch {
+	case errors.Is(err, sclient.ErrWrongPIN):
+		return errors.New("wrong PIN for this network; check it with whoever shared the network")
+	case errors.Is(err, sclient.ErrNetworkNotFound):
+		return errors.New("there is no network with this ID; it may have been deleted, ask for a new invite")
+	case errors.Is(err, sclient.ErrTimeout):
+		return errors.New("the server did not answer in time; check your connection and try again")
+	case errors.Is(err, sclient.ErrNotConnected), errors.Is(err, sclient.ErrConnectionLost):
+		return errors.New("not connected to the server; connect and try again")
+	}
+	return fmt.Errorf("failed to %s: %v", action, err)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+)
+
+// ServiceScanWindow finds the games and services hosted by the computers of the connected
+// network, among the ones that allow it in their settings
+type ServiceScanWindow struct {
+	ui.BaseWindow
+	UI *UIManager
+
+	resultsBox *fyne.Container
+	summary    *widget.Label
+	progress   *widget.ProgressBarInfinite
+	scanButton *widget.Button
+	scanning   bool
+}
+
+var globalServiceScanWindow *ServiceScanWindow
+
+// NewServiceScanWindow creates the window that finds game hosts
+func NewServiceScanWindow(uiManager *UIManager) *ServiceScanWindow {
+	sw := &ServiceScanWindow{UI: uiManager}
+	sw.BaseWindow = *ui.NewBaseWindow(uiManager.App, "Find Game Hosts", 440, 420)
+	sw.BaseWindow.Window.SetOnClosed(func() {
+		globalServiceScanWindow = nil
+	})
+	sw.setupUI()
+	return sw
+}
+
+// setupUI initializes the UI components of the scan window
+func (sw *ServiceScanWindow) setupUI() {
+	sw.resultsBox = container.NewVBox()
+	sw.summary = widget.NewLabel("")
+	sw.summary.Wrapping = fyne.TextWrapWord
+	sw.progress = widget.NewProgressBarInfinite()
+	sw.progress.Hide()
+	sw.scanButton = widget.NewButtonWithIcon("Scan again", theme.ViewRefreshIcon(), sw.scan)
+
+	content := container.NewBorder(
+		nil,
+		container.NewVBox(sw.progress, sw.summary, container.NewHBox(sw.scanButton)),
+		nil,
+		nil,
+		container.NewVScroll(sw.resultsBox),
+	)
+	sw.BaseWindow.Window.SetContent(container.NewPadded(content))
+}
+
+// scan asks every online computer of the connected network and probes the ones that
+// allow it, adding each as it finishes
+func (sw *ServiceScanWindow) scan() {
+	if sw.scanning {
+		return
+	}
+	nm := sw.UI.VPN.NetworkManager
+	if nm == nil {
+		return
+	}
+	sw.scanning = true
+	sw.scanButton.Disable()
+	sw.resultsBox.RemoveAll()
+	sw.summary.SetText("Asking the computers of the network…")
+	sw.progress.Show()
+
+	crash.Go("service scan", func() {
+		results, err := nm.ScanServices(func(result core.ServiceScanResult) {
+			fyne.Do(func() {
+				sw.resultsBox.Add(sw.resultRow(result))
+			})
+		})
+		fyne.Do(func() {
+			sw.scanning = false
+			sw.progress.Hide()
+			sw.scanButton.Enable()
+			if err != nil {
+				sw.summary.SetText(err.Error())
+				return
+			}
+
+			hosts, unsearched := 0, 0
+			for _, result := range results {
+				if len(result.Services) > 0 {
+					hosts++
+				}
+				if result.Status != core.ScanAllowed {
+					unsearched++
+				}
+			}
+			switch {
+			case len(results) == 0:
+				sw.summary.SetText("No other computer of the network is online.")
+			case unsearched > 0:
+				sw.summary.SetText(fmt.Sprintf("%d of %d computers host something. %d did not answer or did not allow the search, which is a setting on their side.", hosts, len(results), unsearched))
+			default:
+				sw.summary.SetText(fmt.Sprintf("%d of %d computers host something.", hosts, len(results)))
+			}
+		})
+	})
+}
+
+// resultRow builds the entry of one computer: its services, or why it was not searched.
+// A right click on a service copies its address.
+func (sw *ServiceScanWindow) resultRow(result core.ServiceScanResult) fyne.CanvasObject {
+	title := widget.NewLabelWithStyle(fmt.Sprintf("%s (%s)", result.Name, result.IP), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	title.Truncation = fyne.TextTruncateEllipsis
+	rows := container.NewVBox(title)
+
+	switch {
+	case result.Status == core.ScanDeclined:
+		rows.Add(widget.NewLabelWithStyle("Does not allow searching for games", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}))
+	case result.Status == core.ScanNoAnswer:
+		rows.Add(widget.NewLabelWithStyle("No answer, it may run an older version", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}))
+	case len(result.Services) == 0:
+		rows.Add(widget.NewLabelWithStyle("Nothing found", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}))
+	}
+
+	for _, service := range result.Services {
+		address := net.JoinHostPort(result.IP, strconv.Itoa(service.Port))
+		label := widget.NewLabelWithStyle(fmt.Sprintf("%s · %s", service.Name, address), fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+		rows.Add(ui.NewTappableContainer(container.NewBorder(nil, nil, widget.NewIcon(theme.ComputerIcon()), nil, label), nil, func(pe *fyne.PointEvent) {
+			menu := fyne.NewMenu("", fyne.NewMenuItem("Copy "+address, func() {
+				copyToClipboard(address, "Address "+address)
+			}))
+			widget.ShowPopUpMenuAtPosition(menu, sw.BaseWindow.Window.Canvas(), pe.AbsolutePosition)
+		}))
+	}
+	return rows
+}
+
+// OpenServiceScanWindow creates the window that finds game hosts and starts a search
+func (ui *UIManager) OpenServiceScanWindow() {
+	if globalServiceScanWindow != nil && globalServiceScanWindow.BaseWindow.Window != nil {
+		globalServiceScanWindow.BaseWindow.Window.RequestFocus()
+		return
+	}
+
+	globalServiceScanWindow = NewServiceScanWindow(ui)
+	globalServiceScanWindow.BaseWindow.Show()
+	globalServiceScanWindow.scan()
+}
@@ -1,40 +1,144 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/network"
 	"github.com/itxtoledo/govpn/cmd/client/ui"
+	clientwebrtc_impl "github.com/itxtoledo/govpn/cmd/client/webrtc"
 )
 
+// Rótulos dos modos de transporte mostrados nas configurações
+var tunnelModeLabels = map[string]string{
+	core.TunnelModeAuto:      "Automatic",
+	core.TunnelModeTUN:       "Virtual interface (administrator)",
+	core.TunnelModeUserspace: "Port forwarding only",
+}
+
+// Rótulos dos modos de cifra da configuração
+var encryptionLabels = map[string]string{
+	core.EncryptionNone:       "Off",
+	core.EncryptionSystem:     "Key in the system key store",
+	core.EncryptionPassphrase: "Passphrase",
+}
+
+// Escalas da interface oferecidas nas configurações, até core.MaxUIScale
+var interfaceScales = []float32{1, 1.25, 1.5, 1.75, 2}
+
+// scaleLabel escreve a escala em porcentagem
+func scaleLabel(scale float32) string {
+	return fmt.Sprintf("%d%%", int(scale*100+0.5))
+}
+
+// Rótulos dos temas da interface
+var themeLabels = map[string]string{
+	core.ThemeSystem: "Same as the system",
+	core.ThemeDark:   "Dark",
+	core.ThemeLight:  "Light",
+}
+
+// applyTheme aplica a aparência escolhida na configuração a todas as janelas
+func applyTheme(app fyne.App, config core.Config) {
+	t := ui.Theme{Scale: config.InterfaceScale()}
+	switch config.Theme {
+	case core.ThemeDark:
+		t.Variant, t.FixedVariant = theme.VariantDark, true
+	case core.ThemeLight:
+		t.Variant, t.FixedVariant = theme.VariantLight, true
+	}
+	if accent, err := ui.ParseColor(config.AccentColor); err == nil {
+		t.Accent = accent
+	}
+	ui.SetTheme(app, t)
+}
+
+// settingsFileName é o nome sugerido ao exportar as configurações
+const settingsFileName = "govpn-settings.json"
+
+// maxSettingsFileSize limita a leitura de um arquivo importado, que tem poucos kilobytes
+const maxSettingsFileSize = 1 << 20
+
 // Global variable to ensure only one settings window can be open
 var globalSettingsWindow *SettingsWindow
 
 // SettingsWindow represents the settings window
 type SettingsWindow struct {
 	*ui.BaseWindow
+	SearchEntry        *widget.Entry
 	ComputerNameEntry  *widget.Entry
+	AutostartCheck     *widget.Check
 	ServerAddressEntry *widget.Entry
-	
+	ServerListURLEntry *widget.Entry
+	AutoSelectCheck    *widget.Check
+	PickServerButton   *widget.Button
+	ServerPinsEntry    *widget.Entry
+	PinServerButton    *widget.Button
+	ScheduleEntry      *widget.Entry
+	IdleEntry          *widget.Entry
+	TunnelModeSelect   *widget.Select
+	PortForwardsEntry  *widget.Entry
+	SharedPortsEntry   *widget.Entry
+	LANBroadcastCheck  *widget.Check
+	TunnelRoutesEntry  *widget.Entry
+	PeerDNSCheck       *widget.Check
+	DiscoveryCheck     *widget.Check
+	TunnelAppsEntry    *widget.Entry
+	ServiceScanCheck   *widget.Check
+	WakeCheck          *widget.Check
+	ICEServersEntry    *widget.Entry
+	RelayOnlyCheck     *widget.Check
+	EncryptionSelect   *widget.Select
+	PassphraseEntry    *widget.Entry
+	IdentityButton     *widget.Button
+	DiagnoseButton     *widget.Button
+	NotificationsGroup *widget.CheckGroup
+	LogLevelSelect     *widget.Select
+	ThemeSelect        *widget.Select
+	AccentSwatch       *canvas.Rectangle
+	AccentButton       *widget.Button
+	AccentResetButton  *widget.Button
+	ScaleSelect        *widget.Select
+	ExportButton       *widget.Button
+	ImportButton       *widget.Button
 	SaveButton         *widget.Button
 
-	configManager *ConfigManager // Add ConfigManager field
+	configManager *core.ConfigManager // Add ConfigManager field
+	accentColor   string              // Cor de destaque escolhida, #rrggbb ou vazia para a padrão
+
+	formItems  []*widget.FormItem // Todas as opções, na ordem do formulário
+	formScroll *container.Scroll  // Mostra o formulário com as opções que batem com a busca
 
 	// Callback
-	OnSettingsSaved func(config Config)
+	OnSettingsSaved func(config core.Config)
+	OnDiagnose      func() // Abre o diagnóstico da conexão, que precisa do cliente
+	OnReopen        func() // Abre a janela de novo, para mostrar as configurações importadas
 }
 
 // NewSettingsWindow creates a new settings window
-func NewSettingsWindow(app fyne.App, configManager *ConfigManager, currentConfig Config, onSettingsSaved func(config Config)) *SettingsWindow {
+func NewSettingsWindow(app fyne.App, configManager *core.ConfigManager, currentConfig core.Config, onSettingsSaved func(config core.Config)) *SettingsWindow {
 	if globalSettingsWindow != nil {
 		return globalSettingsWindow
 	}
 
 	sw := &SettingsWindow{
-		BaseWindow:      ui.NewBaseWindow(app, "Settings", 320, 260),
+		BaseWindow:      ui.NewBaseWindow(app, "Settings", 320, 600),
 		OnSettingsSaved: onSettingsSaved,
 		configManager:   configManager,
 	}
@@ -46,6 +150,13 @@ func NewSettingsWindow(app fyne.App, configManager *ConfigManager, currentConfig
 
 	globalSettingsWindow = sw
 
+	// Busca nas opções, pelo nome, pela dica e pelo texto das caixas de marcar
+	sw.SearchEntry = widget.NewEntry()
+	sw.SearchEntry.SetPlaceHolder("Search settings")
+	sw.SearchEntry.OnChanged = func(query string) {
+		sw.filterSettings(query)
+	}
+
 	// Computer Name Entry
 	sw.ComputerNameEntry = widget.NewEntry()
 	sw.ComputerNameEntry.SetText(currentConfig.ComputerName)
@@ -56,12 +167,201 @@ func NewSettingsWindow(app fyne.App, configManager *ConfigManager, currentConfig
 		}
 	}
 
+	// Início automático, lido da entrada do sistema e não da configuração
+	sw.AutostartCheck = widget.NewCheck("Start with the computer", nil)
+	sw.AutostartCheck.SetChecked(autostartEnabled())
+
 	// Server Address Entry
 	sw.ServerAddressEntry = widget.NewEntry()
 	sw.ServerAddressEntry.SetText(currentConfig.ServerAddress)
 	sw.ServerAddressEntry.SetPlaceHolder("Enter server address (ws://host:port)")
 
-	
+	// Server list, to pick a server by region and latency
+	sw.ServerListURLEntry = widget.NewEntry()
+	sw.ServerListURLEntry.SetText(currentConfig.ServerListURL)
+	sw.ServerListURLEntry.SetPlaceHolder("https://example.com/servers.json")
+
+	sw.AutoSelectCheck = widget.NewCheck("Use the fastest server on start", nil)
+	sw.AutoSelectCheck.SetChecked(currentConfig.AutoSelectServer)
+
+	sw.PickServerButton = widget.NewButtonWithIcon("", theme.SearchIcon(), func() {
+		sw.pickServer()
+	})
+
+	// Certificados fixados do servidor do endereço acima, um por linha
+	sw.ServerPinsEntry = widget.NewMultiLineEntry()
+	sw.ServerPinsEntry.SetText(strings.Join(currentConfig.PinsFor(currentConfig.ServerAddress), "\n"))
+	sw.ServerPinsEntry.SetPlaceHolder("sha256/base64 of the public key")
+	sw.ServerPinsEntry.SetMinRowsVisible(2)
+	sw.PinServerButton = widget.NewButtonWithIcon("", theme.DownloadIcon(), func() {
+		sw.pinServer()
+	})
+
+	// Janelas da conexão automática, uma por linha, e minutos sem tráfego até desconectar
+	schedule := make([]string, len(currentConfig.ConnectSchedule))
+	for i, window := range currentConfig.ConnectSchedule {
+		schedule[i] = window.String()
+	}
+	sw.ScheduleEntry = widget.NewMultiLineEntry()
+	sw.ScheduleEntry.SetText(strings.Join(schedule, "\n"))
+	sw.ScheduleEntry.SetPlaceHolder("mon-fri 18:00-23:30")
+	sw.ScheduleEntry.SetMinRowsVisible(2)
+
+	sw.IdleEntry = widget.NewEntry()
+	if currentConfig.IdleDisconnectMinutes > 0 {
+		sw.IdleEntry.SetText(strconv.Itoa(currentConfig.IdleDisconnectMinutes))
+	}
+	sw.IdleEntry.SetPlaceHolder("Never")
+
+	// Transporte do tráfego e redirecionamentos de portas, um por linha
+	sw.TunnelModeSelect = widget.NewSelect([]string{
+		tunnelModeLabels[core.TunnelModeAuto],
+		tunnelModeLabels[core.TunnelModeTUN],
+		tunnelModeLabels[core.TunnelModeUserspace],
+	}, nil)
+	sw.TunnelModeSelect.SetSelected(tunnelModeLabels[currentConfig.TunnelMode])
+
+	forwards := make([]string, len(currentConfig.PortForwards))
+	for i, forward := range currentConfig.PortForwards {
+		forwards[i] = forward.String()
+	}
+	sw.PortForwardsEntry = widget.NewMultiLineEntry()
+	sw.PortForwardsEntry.SetText(strings.Join(forwards, "\n"))
+	sw.PortForwardsEntry.SetPlaceHolder("tcp 25565 10.10.0.3:25565")
+	sw.PortForwardsEntry.SetMinRowsVisible(3)
+
+	shared := make([]string, len(currentConfig.SharedPorts))
+	for i, port := range currentConfig.SharedPorts {
+		shared[i] = port.String()
+	}
+	sw.SharedPortsEntry = widget.NewMultiLineEntry()
+	sw.SharedPortsEntry.SetText(strings.Join(shared, "\n"))
+	sw.SharedPortsEntry.SetPlaceHolder("udp 7777")
+	sw.SharedPortsEntry.SetMinRowsVisible(2)
+
+	sw.LANBroadcastCheck = widget.NewCheck("Relay LAN broadcasts to peers", nil)
+	sw.LANBroadcastCheck.SetChecked(currentConfig.LANBroadcast)
+	sw.DiscoveryCheck = widget.NewCheck("Share network discovery (mDNS, NetBIOS)", nil)
+	sw.DiscoveryCheck.SetChecked(currentConfig.LANDiscovery)
+	sw.PeerDNSCheck = widget.NewCheck("Reach computers by name (name.govpn)", nil)
+	sw.PeerDNSCheck.SetChecked(currentConfig.PeerDNS)
+	sw.TunnelRoutesEntry = widget.NewMultiLineEntry()
+	sw.TunnelRoutesEntry.SetText(strings.Join(currentConfig.TunnelRoutes, "\n"))
+	sw.TunnelRoutesEntry.SetPlaceHolder("239.255.255.250/32")
+	sw.TunnelRoutesEntry.SetMinRowsVisible(2)
+	sw.TunnelAppsEntry = widget.NewMultiLineEntry()
+	sw.TunnelAppsEntry.SetText(strings.Join(currentConfig.TunnelApps, "\n"))
+	sw.TunnelAppsEntry.SetPlaceHolder(`C:\Games\Game\game.exe`)
+	sw.TunnelAppsEntry.SetMinRowsVisible(2)
+	if runtime.GOOS != "windows" {
+		sw.TunnelAppsEntry.Disable()
+	}
+
+	sw.ServiceScanCheck = widget.NewCheck("Let members find games hosted here", nil)
+	sw.ServiceScanCheck.SetChecked(currentConfig.AllowServiceScan)
+
+	sw.WakeCheck = widget.NewCheck("Let members wake this computer", nil)
+	sw.WakeCheck.SetChecked(currentConfig.AllowWake)
+
+	iceServers := make([]string, len(currentConfig.ICEServers))
+	for i, server := range currentConfig.ICEServers {
+		iceServers[i] = server.String()
+	}
+	sw.ICEServersEntry = widget.NewMultiLineEntry()
+	sw.ICEServersEntry.SetText(strings.Join(iceServers, "\n"))
+	sw.ICEServersEntry.SetPlaceHolder(clientwebrtc_impl.DefaultICEServers[0].String())
+	sw.ICEServersEntry.SetMinRowsVisible(2)
+
+	sw.RelayOnlyCheck = widget.NewCheck("Connect through TURN relays only", nil)
+	sw.RelayOnlyCheck.SetChecked(currentConfig.ICERelayOnly)
+
+	// Cifra da configuração; a senha só é pedida ao escolher ou trocar
+	sw.PassphraseEntry = widget.NewPasswordEntry()
+	sw.PassphraseEntry.SetPlaceHolder("New passphrase")
+	sw.EncryptionSelect = widget.NewSelect([]string{
+		encryptionLabels[core.EncryptionNone],
+		encryptionLabels[core.EncryptionSystem],
+		encryptionLabels[core.EncryptionPassphrase],
+	}, func(label string) {
+		if label == encryptionLabels[core.EncryptionPassphrase] {
+			sw.PassphraseEntry.Show()
+		} else {
+			sw.PassphraseEntry.Hide()
+		}
+	})
+	sw.EncryptionSelect.SetSelected(encryptionLabels[configManager.Encryption()])
+
+	// O par de chaves é exportado e importado numa janela própria
+	sw.IdentityButton = widget.NewButtonWithIcon("Export or import…", theme.AccountIcon(), func() {
+		NewIdentityWindow(app, configManager).Show()
+	})
+
+	sw.DiagnoseButton = widget.NewButtonWithIcon("Connection doctor…", theme.SearchIcon(), func() {
+		if sw.OnDiagnose != nil {
+			sw.OnDiagnose()
+		}
+	})
+
+	// Notificações da área de trabalho, uma opção por evento, todas ligadas por padrão
+	notificationLabels := make([]string, len(notificationKinds))
+	enabledNotifications := []string{}
+	for i, kind := range notificationKinds {
+		notificationLabels[i] = kind.Label
+		if currentConfig.NotificationEnabled(kind.Event) {
+			enabledNotifications = append(enabledNotifications, kind.Label)
+		}
+	}
+	sw.NotificationsGroup = widget.NewCheckGroup(notificationLabels, nil)
+	sw.NotificationsGroup.SetSelected(enabledNotifications)
+
+	// Tema claro ou escuro e cor de destaque, aplicados ao salvar
+	sw.ThemeSelect = widget.NewSelect([]string{
+		themeLabels[core.ThemeSystem],
+		themeLabels[core.ThemeDark],
+		themeLabels[core.ThemeLight],
+	}, nil)
+	sw.ThemeSelect.SetSelected(themeLabels[currentConfig.Theme])
+	if sw.ThemeSelect.Selected == "" {
+		sw.ThemeSelect.SetSelected(themeLabels[core.ThemeSystem])
+	}
+
+	sw.AccentSwatch = canvas.NewRectangle(color.Transparent)
+	sw.AccentSwatch.SetMinSize(fyne.NewSquareSize(24))
+	sw.AccentSwatch.CornerRadius = 4
+	sw.AccentButton = widget.NewButtonWithIcon("Choose…", theme.ColorPaletteIcon(), func() {
+		picker := dialog.NewColorPicker("Accent Color", "Color of buttons, links and highlights", func(c color.Color) {
+			sw.setAccentColor(ui.FormatColor(c))
+		}, sw.BaseWindow.Window)
+		picker.Advanced = true
+		if accent, err := ui.ParseColor(sw.accentColor); err == nil {
+			picker.SetColor(accent)
+		}
+		picker.Show()
+	})
+	sw.AccentResetButton = widget.NewButton("Default", func() {
+		sw.setAccentColor("")
+	})
+	sw.setAccentColor(currentConfig.AccentColor)
+
+	// Escala da interface, que aumenta o texto e a área clicável dos botões
+	scaleLabels := make([]string, len(interfaceScales))
+	for i, scale := range interfaceScales {
+		scaleLabels[i] = scaleLabel(scale)
+	}
+	sw.ScaleSelect = widget.NewSelect(scaleLabels, nil)
+	sw.ScaleSelect.SetSelected(scaleLabel(currentConfig.InterfaceScale()))
+
+	// Nível do log em govpn.log; debug ajuda a investigar problemas de conexão
+	sw.LogLevelSelect = widget.NewSelect(core.LogLevels, nil)
+	sw.LogLevelSelect.SetSelected(currentConfig.MinLogLevel())
+
+	// Todas as configurações, sem a identidade, num arquivo para outro computador
+	sw.ExportButton = widget.NewButtonWithIcon("Export…", theme.UploadIcon(), func() {
+		sw.exportSettings()
+	})
+	sw.ImportButton = widget.NewButtonWithIcon("Import…", theme.DownloadIcon(), func() {
+		sw.importSettings()
+	})
 
 	// Save Button
 	sw.SaveButton = widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
@@ -76,20 +376,176 @@ func (sw *SettingsWindow) saveSettings() {
 	// Get current config to preserve existing keys
 	currentConfig := sw.configManager.GetConfig()
 
+	forwards, err := parseLines(sw.PortForwardsEntry.Text, network.ParsePortForward)
+	if err != nil {
+		dialog.ShowError(err, sw.BaseWindow.Window)
+		return
+	}
+	shared, err := parseLines(sw.SharedPortsEntry.Text, network.ParseSharedPort)
+	if err != nil {
+		dialog.ShowError(err, sw.BaseWindow.Window)
+		return
+	}
+	tunnelRoutes, err := parseLines(sw.TunnelRoutesEntry.Text, func(s string) (string, error) {
+		route, err := network.ParseTunnelRoute(s)
+		if err != nil {
+			return "", err
+		}
+		return route.String(), nil
+	})
+	if err != nil {
+		dialog.ShowError(err, sw.BaseWindow.Window)
+		return
+	}
+	tunnelApps, err := parseLines(sw.TunnelAppsEntry.Text, network.ParseTunnelApp)
+	if err != nil {
+		dialog.ShowError(err, sw.BaseWindow.Window)
+		return
+	}
+	iceServers, err := parseLines(sw.ICEServersEntry.Text, clientwebrtc_impl.ParseICEServer)
+	if err != nil {
+		dialog.ShowError(err, sw.BaseWindow.Window)
+		return
+	}
+	serverPins, err := parseLines(sw.ServerPinsEntry.Text, core.ParseServerPin)
+	if err != nil {
+		dialog.ShowError(err, sw.BaseWindow.Window)
+		return
+	}
+	if len(serverPins) > 0 && !strings.HasPrefix(sw.ServerAddressEntry.Text, "wss://") {
+		dialog.ShowError(errors.New("certificate pins need a wss:// server address"), sw.BaseWindow.Window)
+		return
+	}
+	schedule, err := parseLines(sw.ScheduleEntry.Text, core.ParseConnectWindow)
+	if err != nil {
+		dialog.ShowError(err, sw.BaseWindow.Window)
+		return
+	}
+	var idleMinutes int
+	if text := strings.TrimSpace(sw.IdleEntry.Text); text != "" {
+		if idleMinutes, err = strconv.Atoi(text); err != nil || idleMinutes < 0 {
+			dialog.ShowError(fmt.Errorf("idle minutes %q is not a whole number of minutes", text), sw.BaseWindow.Window)
+			return
+		}
+	}
+	if sw.RelayOnlyCheck.Checked && !slices.ContainsFunc(iceServers, clientwebrtc_impl.ICEServer.IsTURN) {
+		dialog.ShowError(errors.New("connecting through relays only needs at least one TURN server"), sw.BaseWindow.Window)
+		return
+	}
+
+	tunnelMode := core.TunnelModeAuto
+	for mode, label := range tunnelModeLabels {
+		if label == sw.TunnelModeSelect.Selected {
+			tunnelMode = mode
+		}
+	}
+
+	uiTheme := core.ThemeSystem
+	for mode, label := range themeLabels {
+		if label == sw.ThemeSelect.Selected {
+			uiTheme = mode
+		}
+	}
+
+	var uiScale float32
+	for _, scale := range interfaceScales {
+		if scaleLabel(scale) == sw.ScaleSelect.Selected && scale != 1 {
+			uiScale = scale
+		}
+	}
+
 	// Create a new config object with updated values
-	newConfig := Config{
-		ComputerName:  sw.ComputerNameEntry.Text,
-		ServerAddress: sw.ServerAddressEntry.Text,
-		PublicKey:     currentConfig.PublicKey,
-		PrivateKey:    currentConfig.PrivateKey,
+	newConfig := core.Config{
+		ComputerName:     sw.ComputerNameEntry.Text,
+		ServerAddress:    sw.ServerAddressEntry.Text,
+		PublicKey:        currentConfig.PublicKey,
+		PrivateKey:       currentConfig.PrivateKey,
+		KeyStore:         currentConfig.KeyStore,
+		ServerListURL:    sw.ServerListURLEntry.Text,
+		AutoSelectServer: sw.AutoSelectCheck.Checked,
+		TunnelMode:       tunnelMode,
+		PortForwards:     forwards,
+		SharedPorts:      shared,
+		LANBroadcast:     sw.LANBroadcastCheck.Checked,
+		TunnelRoutes:     tunnelRoutes,
+		PeerDNS:          sw.PeerDNSCheck.Checked,
+		LANDiscovery:     sw.DiscoveryCheck.Checked,
+		TunnelApps:       tunnelApps,
+		AllowServiceScan: sw.ServiceScanCheck.Checked,
+		AllowWake:        sw.WakeCheck.Checked,
+		ICEServers:       iceServers,
+		ICERelayOnly:     sw.RelayOnlyCheck.Checked,
+		Theme:            uiTheme,
+		AccentColor:      sw.accentColor,
+		UIScale:          uiScale,
+		LogLevel:         sw.LogLevelSelect.Selected,
+
+		ServerPins:            currentConfig.WithPins(sw.ServerAddressEntry.Text, serverPins),
+		ConnectSchedule:       schedule,
+		IdleDisconnectMinutes: idleMinutes,
+
+		GamePresets:         currentConfig.GamePresets,
+		BandwidthLimits:     currentConfig.BandwidthLimits,
+		AutoConnectNetworks: currentConfig.AutoConnectNetworks,
+		LastNetworkID:       currentConfig.LastNetworkID,
+		NetworkSort:         currentConfig.NetworkSort,
+	}
+
+	for _, kind := range notificationKinds {
+		if !slices.Contains(sw.NotificationsGroup.Selected, kind.Label) {
+			newConfig.MutedNotifications = append(newConfig.MutedNotifications, kind.Event)
+		}
 	}
 
 	
 
+	encryption := core.EncryptionNone
+	for mode, label := range encryptionLabels {
+		if label == sw.EncryptionSelect.Selected {
+			encryption = mode
+		}
+	}
+	// Com a senha em branco, a senha atual continua valendo
+	passphrase := sw.PassphraseEntry.Text
+	if encryption != sw.configManager.Encryption() || (encryption == core.EncryptionPassphrase && passphrase != "") {
+		if encryption == core.EncryptionPassphrase && passphrase == "" {
+			dialog.ShowError(errors.New("enter a passphrase to encrypt the settings"), sw.BaseWindow.Window)
+			return
+		}
+		if err := sw.configManager.SetEncryption(encryption, passphrase); err != nil {
+			dialog.ShowError(err, sw.BaseWindow.Window)
+			return
+		}
+		sw.PassphraseEntry.SetText("")
+	}
+
+	if sw.AutostartCheck.Checked != autostartEnabled() {
+		if err := setAutostart(sw.AutostartCheck.Checked); err != nil {
+			dialog.ShowError(err, sw.BaseWindow.Window)
+			return
+		}
+	}
+
 	// Invoke the callback with the new config
 	sw.OnSettingsSaved(newConfig)
 }
 
+// setAccentColor guarda a cor de destaque escolhida e a mostra na amostra, com a cor
+// primária do tema quando é a padrão
+func (sw *SettingsWindow) setAccentColor(hex string) {
+	accent, err := ui.ParseColor(hex)
+	if err != nil {
+		sw.accentColor = ""
+		sw.AccentSwatch.FillColor = theme.DefaultTheme().Color(theme.ColorNamePrimary, fyne.CurrentApp().Settings().ThemeVariant())
+		sw.AccentResetButton.Disable()
+	} else {
+		sw.accentColor = hex
+		sw.AccentSwatch.FillColor = accent
+		sw.AccentResetButton.Enable()
+	}
+	sw.AccentSwatch.Refresh()
+}
+
 // Show displays the settings window
 func (sw *SettingsWindow) Show() {
 	// Create title with icon
@@ -99,28 +555,247 @@ func (sw *SettingsWindow) Show() {
 	titleContainer := container.NewHBox(titleIcon, titleLabel)
 
 	// Settings Form
-	form := &widget.Form{
-		Items: []*widget.FormItem{
-			{Text: "ComputerName", Widget: sw.ComputerNameEntry, HintText: "Your display name in the VPN"},
-			{Text: "Server", Widget: sw.ServerAddressEntry, HintText: "Address of the signaling server"},
-		},
+	sw.formItems = []*widget.FormItem{
+		{Text: "ComputerName", Widget: sw.ComputerNameEntry, HintText: "Your display name in the VPN"},
+		{Text: "", Widget: sw.AutostartCheck, HintText: "Opens minimized in the system tray"},
+		{Text: "Server", Widget: container.NewBorder(nil, nil, nil, sw.PickServerButton, sw.ServerAddressEntry), HintText: "Address of the signaling server"},
+		{Text: "Pins", Widget: container.NewBorder(nil, nil, nil, sw.PinServerButton, sw.ServerPinsEntry), HintText: "Certificates a wss:// server must present; empty trusts the system"},
+		{Text: "Server list", Widget: sw.ServerListURLEntry, HintText: "Servers to pick from by latency"},
+		{Text: "", Widget: sw.AutoSelectCheck},
+		{Text: "Schedule", Widget: sw.ScheduleEntry, HintText: "When \"Connect on start\" networks connect by themselves; empty is always"},
+		{Text: "Idle", Widget: sw.IdleEntry, HintText: "Minutes without VPN traffic before disconnecting"},
+		{Text: "Traffic", Widget: sw.TunnelModeSelect, HintText: "Applies on the next network connection"},
+		{Text: "", Widget: sw.LANBroadcastCheck, HintText: "For games that find each other on the LAN"},
+		{Text: "", Widget: sw.DiscoveryCheck, HintText: "Shared folders and LAN games show up without typing IPs"},
+		{Text: "", Widget: sw.PeerDNSCheck, HintText: "Only .govpn names use the VPN's DNS; applies on the next connection"},
+		{Text: "Broadcast to", Widget: sw.TunnelRoutesEntry, HintText: "Multicast groups relayed to peers; empty relays all"},
+		{Text: "Programs", Widget: sw.TunnelAppsEntry, HintText: "Only these use the virtual network (Windows); empty allows all"},
+		{Text: "", Widget: sw.ServiceScanCheck, HintText: "Answers \"Find game hosts\" with the game ports open here"},
+		{Text: "", Widget: sw.WakeCheck, HintText: "Wake-on-LAN through a member on this LAN; enable it in the BIOS too"},
+		{Text: "Forwards", Widget: sw.PortForwardsEntry, HintText: "protocol local-port peer-ip:port"},
+		{Text: "Shared", Widget: sw.SharedPortsEntry, HintText: "Local ports peers may reach: protocol port"},
+		{Text: "ICE servers", Widget: sw.ICEServersEntry, HintText: "stun:host:port, or turn:host:port username password"},
+		{Text: "", Widget: sw.RelayOnlyCheck, HintText: "Hides your addresses from peers; applies to new connections"},
+		{Text: "Encryption", Widget: sw.EncryptionSelect, HintText: "Encrypts the settings and keys saved on this computer"},
+		{Text: "", Widget: sw.PassphraseEntry, HintText: "Asked every time the app starts"},
+		{Text: "Identity", Widget: sw.IdentityButton, HintText: "Move your key pair to another computer"},
+		{Text: "Diagnostics", Widget: sw.DiagnoseButton, HintText: "Tests the server, STUN, TURN and NAT, with a report for issues"},
+		{Text: "Notify when", Widget: sw.NotificationsGroup},
+		{Text: "Theme", Widget: sw.ThemeSelect},
+		{Text: "Accent", Widget: container.NewHBox(container.NewCenter(sw.AccentSwatch), sw.AccentButton, sw.AccentResetButton)},
+		{Text: "Scale", Widget: sw.ScaleSelect, HintText: "Larger text and buttons in every window"},
+		{Text: "Log level", Widget: sw.LogLevelSelect, HintText: "Debug logs every connection step to govpn.log"},
 	}
+	sw.formScroll = container.NewVScroll(nil)
+	sw.filterSettings(sw.SearchEntry.Text)
 
 	// Button Container
 	buttonContainer := container.NewHBox(
+		sw.ExportButton,
+		sw.ImportButton,
 		layout.NewSpacer(),
 		sw.SaveButton,
 	)
 
-	// Main Container
-	content := container.NewVBox(
-		container.NewPadded(titleContainer),
-		widget.NewSeparator(),
-		container.NewPadded(form),
-		widget.NewSeparator(),
-		container.NewPadded(buttonContainer),
+	// Main Container, the form scrolls between the title and the buttons
+	content := container.NewBorder(
+		container.NewVBox(container.NewPadded(titleContainer), container.NewPadded(sw.SearchEntry), widget.NewSeparator()),
+		container.NewVBox(widget.NewSeparator(), container.NewPadded(buttonContainer)),
+		nil, nil,
+		sw.formScroll,
 	)
 
 	sw.BaseWindow.SetContent(content)
 	sw.BaseWindow.Show()
 }
+
+// filterSettings mostra só as opções que contêm todas as palavras da busca
+func (sw *SettingsWindow) filterSettings(query string) {
+	words := strings.Fields(strings.ToLower(query))
+	var items []*widget.FormItem
+	for _, item := range sw.formItems {
+		text := settingSearchText(item)
+		if !slices.ContainsFunc(words, func(word string) bool { return !strings.Contains(text, word) }) {
+			items = append(items, item)
+		}
+	}
+
+	if len(items) == 0 {
+		sw.formScroll.Content = container.NewCenter(widget.NewLabel("No settings match \"" + strings.TrimSpace(query) + "\""))
+	} else {
+		sw.formScroll.Content = container.NewPadded(widget.NewForm(items...))
+	}
+	sw.formScroll.ScrollToTop()
+	sw.formScroll.Refresh()
+}
+
+// settingSearchText junta em minúsculas os textos de uma opção em que a busca procura
+func settingSearchText(item *widget.FormItem) string {
+	texts := []string{item.Text, item.HintText}
+	switch w := item.Widget.(type) {
+	case *widget.Check:
+		texts = append(texts, w.Text)
+	case *widget.Button:
+		texts = append(texts, w.Text)
+	case *widget.CheckGroup:
+		texts = append(texts, w.Options...)
+	}
+	return strings.ToLower(strings.Join(texts, " "))
+}
+
+// exportSettings grava as configurações salvas num arquivo escolhido pelo usuário
+func (sw *SettingsWindow) exportSettings() {
+	content, err := sw.configManager.ExportSettings()
+	if err != nil {
+		dialog.ShowError(err, sw.BaseWindow.Window)
+		return
+	}
+
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, sw.BaseWindow.Window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+		if _, err := writer.Write(content); err != nil {
+			dialog.ShowError(err, sw.BaseWindow.Window)
+			return
+		}
+		dialog.ShowInformation("Settings exported", "Saved to "+writer.URI().Name()+". Your identity is not in the file; move it with Identity.", sw.BaseWindow.Window)
+	}, sw.BaseWindow.Window)
+	save.SetFileName(settingsFileName)
+	save.Show()
+}
+
+// importSettings lê um arquivo exportado e, confirmado pelo usuário, troca as configurações
+// deste computador pelas dele
+func (sw *SettingsWindow) importSettings() {
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, sw.BaseWindow.Window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+		content, err := io.ReadAll(io.LimitReader(reader, maxSettingsFileSize))
+		if err != nil {
+			dialog.ShowError(err, sw.BaseWindow.Window)
+			return
+		}
+		imported, err := sw.configManager.ImportSettings(content)
+		if err != nil {
+			dialog.ShowError(err, sw.BaseWindow.Window)
+			return
+		}
+
+		dialog.ShowConfirm("Import settings",
+			"Replace the settings of this computer with the ones in "+reader.URI().Name()+"? Your identity and the settings encryption stay as they are.",
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				sw.OnSettingsSaved(imported)
+				// O formulário foi montado com as configurações anteriores
+				sw.BaseWindow.Close()
+				globalSettingsWindow = nil
+				if sw.OnReopen != nil {
+					sw.OnReopen()
+				}
+			}, sw.BaseWindow.Window)
+	}, sw.BaseWindow.Window)
+}
+
+// pickServer pings the servers of the list and lets the user choose one, fastest first
+func (sw *SettingsWindow) pickServer() {
+	listURL := sw.ServerListURLEntry.Text
+	if listURL == "" {
+		dialog.ShowInformation("Server list", "Enter the address of a server list first.", sw.BaseWindow.Window)
+		return
+	}
+
+	sw.PickServerButton.Disable()
+	crash.Go("server picker", func() {
+		ranked, err := core.RankServerList(listURL)
+		fyne.Do(func() {
+			sw.PickServerButton.Enable()
+			if err != nil {
+				dialog.ShowError(err, sw.BaseWindow.Window)
+				return
+			}
+
+			options := make([]string, len(ranked))
+			for i, r := range ranked {
+				options[i] = core.FormatServerChoice(r)
+			}
+			choice := widget.NewRadioGroup(options, nil)
+			choice.SetSelected(options[0])
+
+			dialog.ShowCustomConfirm("Choose a server", "Use", "Cancel", choice, func(ok bool) {
+				if !ok {
+					return
+				}
+				for i, option := range options {
+					if option == choice.Selected {
+						sw.ServerAddressEntry.SetText(ranked[i].Server.Address)
+					}
+				}
+			}, sw.BaseWindow.Window)
+		})
+	})
+}
+
+// pinServer busca o certificado que o servidor do endereço apresenta agora e, confirmado
+// pelo usuário, acrescenta o pin dele aos certificados fixados
+func (sw *SettingsWindow) pinServer() {
+	serverAddress := sw.ServerAddressEntry.Text
+	sw.PinServerButton.Disable()
+	crash.Go("certificate pin", func() {
+		pin, err := core.FetchServerPin(serverAddress)
+		fyne.Do(func() {
+			sw.PinServerButton.Enable()
+			if err != nil {
+				dialog.ShowError(err, sw.BaseWindow.Window)
+				return
+			}
+			if strings.Contains(sw.ServerPinsEntry.Text, pin) {
+				dialog.ShowInformation("Certificate pin", "The certificate of this server is already pinned.", sw.BaseWindow.Window)
+				return
+			}
+
+			message := widget.NewLabel("The server presents a certificate with this public key:\n\n" + pin + "\n\nPin it only if you trust the network you are on, or after comparing it with the server owner.")
+			message.Wrapping = fyne.TextWrapWord
+			dialog.ShowCustomConfirm("Pin certificate", "Pin", "Cancel", message, func(ok bool) {
+				if !ok {
+					return
+				}
+				text := strings.TrimSpace(sw.ServerPinsEntry.Text)
+				if text != "" {
+					text += "\n"
+				}
+				sw.ServerPinsEntry.SetText(text + pin)
+			}, sw.BaseWindow.Window)
+		})
+	})
+}
+
+// parseLines lê uma entrada por linha, ignorando linhas vazias, e aponta a linha inválida
+func parseLines[T any](text string, parse func(string) (T, error)) ([]T, error) {
+	var items []T
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		item, err := parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/dialogs"
+)
+
+// shortcut is a keyboard shortcut of the main window, pressed together with Ctrl (Cmd on macOS)
+type shortcut struct {
+	Key         fyne.KeyName
+	Label       string
+	Description string
+	Action      func()
+}
+
+// shortcuts lists the keyboard shortcuts of the main window, in the order of the help
+func (ui *UIManager) shortcuts() []shortcut {
+	return []shortcut{
+		{fyne.KeyD, "D", "Connect to or disconnect from the server", ui.HeaderComponent.toggleConnection},
+		{fyne.KeyK, "K", "Switch network", ui.showQuickSwitch},
+		{fyne.KeyN, "N", "Create a network", ui.HomeScreenComponent.ShowCreateNetwork},
+		{fyne.KeyJ, "J", "Join a network", ui.HomeScreenComponent.ShowJoinNetwork},
+		{fyne.KeyComma, ",", "Settings", ui.ShowSettingsWindow},
+		{fyne.KeySlash, "/", "Show these shortcuts (also F1)", ui.showShortcuts},
+	}
+}
+
+// shortcutModifier is how the modifier of the shortcuts is written on this system
+func shortcutModifier() string {
+	if runtime.GOOS == "darwin" {
+		return "⌘"
+	}
+	return "Ctrl+"
+}
+
+// setupShortcuts registers the keyboard shortcuts on the main window
+func (ui *UIManager) setupShortcuts() {
+	canvas := ui.MainWindow.Canvas()
+	for _, s := range ui.shortcuts() {
+		action := s.Action
+		canvas.AddShortcut(&desktop.CustomShortcut{KeyName: s.Key, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut) {
+			action()
+		})
+	}
+	canvas.SetOnTypedKey(func(event *fyne.KeyEvent) {
+		if event.Name == fyne.KeyF1 {
+			ui.showShortcuts()
+		}
+	})
+}
+
+// showShortcuts lists the keyboard shortcuts
+func (ui *UIManager) showShortcuts() {
+	grid := container.NewGridWithColumns(2)
+	for _, s := range ui.shortcuts() {
+		key := widget.NewLabel(shortcutModifier() + s.Label)
+		key.TextStyle = fyne.TextStyle{Monospace: true, Bold: true}
+		grid.Add(key)
+		grid.Add(widget.NewLabel(s.Description))
+	}
+	hint := widget.NewLabel("Tab and Shift+Tab move between fields and buttons, Space presses the focused button and Escape closes a window while no text field has the focus.")
+	hint.Wrapping = fyne.TextWrapWord
+
+	d := dialog.NewCustom("Keyboard Shortcuts", "Close", container.NewVBox(grid, hint), ui.MainWindow)
+	d.Resize(fyne.NewSize(300, 0))
+	d.Show()
+}
+
+// showQuickSwitch lets the user pick the network to connect to from the keyboard
+func (ui *UIManager) showQuickSwitch() {
+	isConnected := ui.RealtimeData.IsConnected()
+	if !isConnected || ui.VPN.NetworkManager == nil {
+		dialog.ShowError(errors.New("not connected to server"), ui.MainWindow)
+		return
+	}
+
+	dialogs.ShowQuickSwitchDialog(ui.RealtimeData.GetNetworks(), ui.VPN.NetworkManager.NetworkID, func(networkID string) {
+		crash.Go("quick switch", func() {
+			if err := ui.ConnectToNetwork(networkID, ui.VPN.ComputerName); err != nil {
+				fyne.Do(func() {
+					dialogs.ShowError(err, ui.MainWindow)
+				})
+			}
+		})
+	}, ui.MainWindow)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+)
+
+// StatusBar sums up the connection at the bottom of the main window: the signaling server,
+// the peers with an established session against the members online, the virtual IP of this
+// computer and the throughput of the tunnel. Each part follows the UI bindings of the data
+// layer and reads the current values from it.
+type StatusBar struct {
+	realtimeData *data.RealtimeDataLayer
+
+	serverLabel *widget.Label
+	peersLabel  *widget.Label
+	ipLabel     *widget.Label
+	rateLabel   *widget.Label
+	container   *fyne.Container
+}
+
+// NewStatusBar creates the status bar and binds it to the realtime data
+func NewStatusBar(realtimeData *data.RealtimeDataLayer, bindings *Bindings) *StatusBar {
+	sb := &StatusBar{realtimeData: realtimeData}
+	newLabel := func() *widget.Label {
+		label := widget.NewLabel("")
+		label.SizeName = theme.SizeNameCaptionText
+		label.Truncation = fyne.TextTruncateEllipsis
+		return label
+	}
+	sb.serverLabel = newLabel()
+	sb.peersLabel = newLabel()
+	sb.ipLabel = newLabel()
+	sb.ipLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	sb.rateLabel = newLabel()
+	sb.rateLabel.TextStyle = fyne.TextStyle{Monospace: true}
+
+	sb.container = container.NewVBox(
+		widget.NewSeparator(),
+		container.NewGridWithColumns(2, sb.serverLabel, sb.peersLabel, sb.ipLabel, sb.rateLabel),
+	)
+
+	bindings.ConnectionState.AddListener(binding.NewDataListener(sb.updateServer))
+
+	updatePeers := binding.NewDataListener(sb.updatePeers)
+	bindings.PeerSessions.AddListener(updatePeers)
+	bindings.Networks.AddListener(updatePeers)
+	bindings.NetworkName.AddListener(updatePeers)
+
+	bindings.ComputerIP.AddListener(binding.NewDataListener(sb.updateIP))
+
+	bindings.Traffic.AddListener(binding.NewDataListener(sb.updateRate))
+	return sb
+}
+
+// Container returns the status bar's container
+func (sb *StatusBar) Container() *fyne.Container {
+	return sb.container
+}
+
+// updateServer shows the state of the connection to the signaling server
+func (sb *StatusBar) updateServer() {
+	switch sb.realtimeData.GetConnectionState() {
+	case data.StateConnected:
+		sb.serverLabel.SetText("● Server online")
+		sb.serverLabel.Importance = widget.SuccessImportance
+	case data.StateConnecting:
+		sb.serverLabel.SetText("● Connecting…")
+		sb.serverLabel.Importance = widget.WarningImportance
+	default:
+		sb.serverLabel.SetText("● Offline")
+		sb.serverLabel.Importance = widget.LowImportance
+	}
+	sb.serverLabel.Refresh()
+}
+
+// updatePeers shows how many computers of the connected network have a session with this
+// one, out of the ones online
+func (sb *StatusBar) updatePeers() {
+	networkID := sb.realtimeData.GetNetworkName()
+	publicKey := sb.realtimeData.GetPublicKey()
+	sessions := sb.realtimeData.GetPeerSessions()
+
+	for _, network := range sb.realtimeData.GetNetworks() {
+		if network.NetworkID != networkID {
+			continue
+		}
+		online := 0
+		for _, computer := range network.Computers {
+			if computer.IsOnline && computer.PublicKey != publicKey {
+				online++
+			}
+		}
+		sb.peersLabel.SetText(fmt.Sprintf("Peers %d/%d", sessions, online))
+		return
+	}
+	sb.peersLabel.SetText("No network")
+}
+
+// updateIP shows the virtual IP of this computer in the connected network
+func (sb *StatusBar) updateIP() {
+	ip := sb.realtimeData.GetComputerIP()
+	if ip == "" || ip == "0.0.0.0" {
+		ip = "No virtual IP"
+	}
+	sb.ipLabel.SetText(ip)
+}
+
+// updateRate shows the throughput of the last traffic sample
+func (sb *StatusBar) updateRate() {
+	traffic := sb.realtimeData.GetTraffic()
+	sb.rateLabel.SetText(fmt.Sprintf("↑%s/s ↓%s/s", formatBytes(int64(traffic.SendRate)), formatBytes(int64(traffic.ReceiveRate))))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+	"github.com/itxtoledo/govpn/cmd/client/data"
+)
+
+// throughputGraphSize is the size of the throughput graph next to the network buttons
+var throughputGraphSize = fyne.NewSize(120, 32)
+
+// ThroughputWidget draws the throughput of the last minute and the totals of the session,
+// hidden while no network is connected
+type ThroughputWidget struct {
+	realtimeData *data.RealtimeDataLayer
+	graph        *canvas.Raster
+	rateLabel    *widget.Label
+	totalLabel   *widget.Label
+	container    *fyne.Container
+
+	mu       sync.Mutex
+	sent     []float64 // Throughput of each second relative to the busiest one, 0 to 1
+	received []float64
+}
+
+// NewThroughputWidget creates the widget and starts following the traffic samples
+func NewThroughputWidget(realtimeData *data.RealtimeDataLayer) *ThroughputWidget {
+	tw := &ThroughputWidget{
+		realtimeData: realtimeData,
+		rateLabel:    widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true}),
+		totalLabel:   widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+	}
+
+	tw.graph = canvas.NewRasterWithPixels(tw.pixel)
+	tw.graph.SetMinSize(throughputGraphSize)
+
+	tw.container = container.NewHBox(tw.graph, container.NewVBox(tw.rateLabel, tw.totalLabel))
+	tw.container.Hide()
+
+	crash.Go("throughput graph", tw.follow)
+	return tw
+}
+
+// Container returns the widget's container
+func (tw *ThroughputWidget) Container() *fyne.Container {
+	return tw.container
+}
+
+// follow redraws the widget after every traffic sample
+func (tw *ThroughputWidget) follow() {
+	ticker := time.NewTicker(core.TrafficSampleInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		tw.update()
+	}
+}
+
+// update scales the recent samples for the graph and refreshes the labels
+func (tw *ThroughputWidget) update() {
+	history := tw.realtimeData.TrafficHistory()
+	traffic := tw.realtimeData.GetTraffic()
+
+	peak := 1.0
+	for _, sample := range history {
+		peak = max(peak, sample.Sent, sample.Received)
+	}
+	sent := make([]float64, len(history))
+	received := make([]float64, len(history))
+	for i, sample := range history {
+		sent[i] = sample.Sent / peak
+		received[i] = sample.Received / peak
+	}
+
+	tw.mu.Lock()
+	tw.sent, tw.received = sent, received
+	tw.mu.Unlock()
+
+	fyne.Do(func() {
+		if len(history) == 0 {
+			tw.container.Hide()
+			return
+		}
+		last := history[len(history)-1]
+		tw.rateLabel.SetText(fmt.Sprintf("↑ %s/s ↓ %s/s", formatBytes(int64(last.Sent)), formatBytes(int64(last.Received))))
+		tw.totalLabel.SetText(fmt.Sprintf("Session: ↑ %s ↓ %s", formatBytes(int64(traffic.Sent)), formatBytes(int64(traffic.Received))))
+		tw.container.Show()
+		tw.graph.Refresh()
+	})
+}
+
+// pixel colors the graph: received bytes as the filled area, sent bytes as a line over it.
+// The newest sample is on the right edge.
+func (tw *ThroughputWidget) pixel(x, y, w, h int) color.Color {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	index := len(tw.received) - data.TrafficHistorySize + x*data.TrafficHistorySize/max(w, 1)
+	if index < 0 || index >= len(tw.received) {
+		return color.Transparent
+	}
+
+	level := h - 1 - y
+	if level == int(tw.sent[index]*float64(h-1)) {
+		return theme.Color(theme.ColorNameSuccess)
+	}
+	if level <= int(tw.received[index]*float64(h-1)) {
+		return theme.Color(theme.ColorNamePrimary)
+	}
+	return color.Transparent
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/dialogs"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+)
+
+// trafficChartDays is how many days the traffic history chart shows, ending today
+const trafficChartDays = 30
+
+// allNetworksOption sums the history of every network in the network select
+const allNetworksOption = "All networks"
+
+// TrafficHistoryWindow shows how much data each network moved per day: a bar chart of the
+// last trafficChartDays days and the totals of today, the last week and the last month
+type TrafficHistoryWindow struct {
+	ui.BaseWindow
+	UI *UIManager
+
+	history       []core.NetworkTraffic
+	networkSelect *widget.Select
+	chart         *canvas.Raster
+	rangeLabel    *widget.Label
+	todayLabel    *widget.Label
+	weekLabel     *widget.Label
+	monthLabel    *widget.Label
+	keptLabel     *widget.Label
+
+	mu       sync.Mutex
+	sent     []float64 // Bytes sent each day relative to the busiest one, 0 to 1, oldest first
+	received []float64
+}
+
+var globalTrafficHistoryWindow *TrafficHistoryWindow
+
+// NewTrafficHistoryWindow creates the traffic history window, showing networkID first when set
+func NewTrafficHistoryWindow(uiManager *UIManager, networkID string) *TrafficHistoryWindow {
+	tw := &TrafficHistoryWindow{UI: uiManager}
+	tw.BaseWindow = *ui.NewBaseWindow(uiManager.App, "Traffic History", 480, 400)
+	tw.BaseWindow.Window.SetOnClosed(func() {
+		globalTrafficHistoryWindow = nil
+	})
+	tw.setupUI()
+	tw.reload(networkID)
+	return tw
+}
+
+// setupUI initializes the UI components of the traffic history window
+func (tw *TrafficHistoryWindow) setupUI() {
+	tw.networkSelect = widget.NewSelect(nil, func(string) {
+		tw.update()
+	})
+
+	tw.chart = canvas.NewRasterWithPixels(tw.pixel)
+	tw.chart.SetMinSize(fyne.NewSize(440, 140))
+	tw.rangeLabel = widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+
+	tw.todayLabel = widget.NewLabel("-")
+	tw.weekLabel = widget.NewLabel("-")
+	tw.monthLabel = widget.NewLabel("-")
+	tw.keptLabel = widget.NewLabel("-")
+	form := widget.NewForm(
+		widget.NewFormItem("Today", tw.todayLabel),
+		widget.NewFormItem("Last 7 days", tw.weekLabel),
+		widget.NewFormItem(fmt.Sprintf("Last %d days", trafficChartDays), tw.monthLabel),
+		widget.NewFormItem("All kept days", tw.keptLabel),
+	)
+
+	legend := widget.NewLabelWithStyle("Bars: received, line: sent", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+
+	refreshButton := widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), func() {
+		tw.reload(tw.selectedNetworkID())
+	})
+	clearButton := widget.NewButtonWithIcon("Clear history", theme.DeleteIcon(), func() {
+		dialog.ShowConfirm("Clear history", "Delete the traffic history of every network?", func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if nm := tw.UI.VPN.NetworkManager; nm != nil {
+				if err := nm.ClearTrafficHistory(); err != nil {
+					dialogs.ShowError(err, tw.BaseWindow.Window)
+				}
+			}
+			tw.reload("")
+		}, tw.BaseWindow.Window)
+	})
+
+	content := container.NewBorder(
+		tw.networkSelect,
+		container.NewHBox(refreshButton, clearButton),
+		nil,
+		nil,
+		container.NewVBox(tw.chart, tw.rangeLabel, legend, form),
+	)
+	tw.BaseWindow.Window.SetContent(container.NewPadded(content))
+}
+
+// reload reads the history again and selects networkID, or all networks when it has no history
+func (tw *TrafficHistoryWindow) reload(networkID string) {
+	tw.history = nil
+	if nm := tw.UI.VPN.NetworkManager; nm != nil {
+		tw.history = nm.TrafficHistory()
+	}
+
+	options := []string{allNetworksOption}
+	selected := allNetworksOption
+	for _, network := range tw.history {
+		options = append(options, tw.networkLabel(network))
+		if network.NetworkID == networkID {
+			selected = tw.networkLabel(network)
+		}
+	}
+	tw.networkSelect.SetOptions(options)
+	if tw.networkSelect.Selected == selected {
+		tw.update()
+	} else {
+		tw.networkSelect.SetSelected(selected)
+	}
+}
+
+// networkLabel names a network in the select; networks left long ago may have no name
+func (tw *TrafficHistoryWindow) networkLabel(network core.NetworkTraffic) string {
+	if network.Name == "" || network.Name == network.NetworkID {
+		return network.NetworkID
+	}
+	return fmt.Sprintf("%s (%s)", network.Name, network.NetworkID)
+}
+
+// selectedNetworkID returns the network in the select, or empty for all networks
+func (tw *TrafficHistoryWindow) selectedNetworkID() string {
+	for _, network := range tw.history {
+		if tw.networkLabel(network) == tw.networkSelect.Selected {
+			return network.NetworkID
+		}
+	}
+	return ""
+}
+
+// update sums the days of the selected networks into the chart and the totals
+func (tw *TrafficHistoryWindow) update() {
+	networkID := tw.selectedNetworkID()
+	days := make(map[string][2]uint64)
+	for _, network := range tw.history {
+		if networkID != "" && network.NetworkID != networkID {
+			continue
+		}
+		for _, day := range network.Days {
+			total := days[day.Date]
+			days[day.Date] = [2]uint64{total[0] + day.Sent, total[1] + day.Received}
+		}
+	}
+
+	// Totals since a number of days ago, today included
+	today := time.Now()
+	since := func(n int) (sent, received uint64) {
+		for i := range n {
+			total := days[today.AddDate(0, 0, -i).Format(time.DateOnly)]
+			sent += total[0]
+			received += total[1]
+		}
+		return sent, received
+	}
+
+	var peak uint64 = 1
+	chartSent := make([]uint64, trafficChartDays)
+	chartReceived := make([]uint64, trafficChartDays)
+	for i := range trafficChartDays {
+		total := days[today.AddDate(0, 0, i-trafficChartDays+1).Format(time.DateOnly)]
+		chartSent[i], chartReceived[i] = total[0], total[1]
+		peak = max(peak, total[0], total[1])
+	}
+	sent := make([]float64, trafficChartDays)
+	received := make([]float64, trafficChartDays)
+	for i := range trafficChartDays {
+		sent[i] = float64(chartSent[i]) / float64(peak)
+		received[i] = float64(chartReceived[i]) / float64(peak)
+	}
+
+	tw.mu.Lock()
+	tw.sent, tw.received = sent, received
+	tw.mu.Unlock()
+
+	var keptSent, keptReceived uint64
+	for _, total := range days {
+		keptSent += total[0]
+		keptReceived += total[1]
+	}
+
+	first := today.AddDate(0, 0, 1-trafficChartDays)
+	tw.rangeLabel.SetText(fmt.Sprintf("%s – %s, busiest day %s", first.Format("Jan 2"), today.Format("Jan 2"), formatBytes(int64(peak))))
+	tw.todayLabel.SetText(trafficTotal(since(1)))
+	tw.weekLabel.SetText(trafficTotal(since(7)))
+	tw.monthLabel.SetText(trafficTotal(since(trafficChartDays)))
+	tw.keptLabel.SetText(trafficTotal(keptSent, keptReceived))
+	tw.chart.Refresh()
+}
+
+// trafficTotal formats the bytes sent and received in a period
+func trafficTotal(sent, received uint64) string {
+	return fmt.Sprintf("↑ %s ↓ %s", formatBytes(int64(sent)), formatBytes(int64(received)))
+}
+
+// pixel colors the chart: one bar per day with the received bytes, and the sent bytes as a
+// mark across it. Today is on the right edge.
+func (tw *TrafficHistoryWindow) pixel(x, y, w, h int) color.Color {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if len(tw.received) == 0 {
+		return color.Transparent
+	}
+	slot := max(w/len(tw.received), 1)
+	index := x / slot
+	if index >= len(tw.received) || x%slot >= slot*3/4 {
+		return color.Transparent
+	}
+
+	level := h - 1 - y
+	if level == int(tw.sent[index]*float64(h-1)) {
+		return theme.Color(theme.ColorNameSuccess)
+	}
+	if level < int(tw.received[index]*float64(h-1)) {
+		return theme.Color(theme.ColorNamePrimary)
+	}
+	return color.Transparent
+}
+
+// OpenTrafficHistoryWindow creates and shows the traffic history window, showing networkID
+// first when set
+func (ui *UIManager) OpenTrafficHistoryWindow(networkID string) {
+	if globalTrafficHistoryWindow != nil && globalTrafficHistoryWindow.BaseWindow.Window != nil {
+		globalTrafficHistoryWindow.reload(networkID)
+		globalTrafficHistoryWindow.BaseWindow.Window.RequestFocus()
+		return
+	}
+
+	globalTrafficHistoryWindow = NewTrafficHistoryWindow(ui, networkID)
+	globalTrafficHistoryWindow.BaseWindow.Show()
+}
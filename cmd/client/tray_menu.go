@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
+)
+
+// trayStatusItem builds the tray entry that shows the connected network and the virtual IP
+// of this computer in it. Clicking it copies the IP.
+func (ui *UIManager) trayStatusItem() *fyne.MenuItem {
+	networkID, ip := ui.trayCurrentNetwork()
+	if networkID == "" {
+		item := fyne.NewMenuItem("Not connected to a network", nil)
+		item.Disabled = true
+		return item
+	}
+	return fyne.NewMenuItem(fmt.Sprintf("%s · %s", ui.trayNetworkName(networkID), ip), func() {
+		copyToClipboard(ip, "Virtual IP")
+	})
+}
+
+// trayNetworksItem builds the tray submenu with the joined networks, each with Connect or
+// Disconnect and, for the connected one, its virtual IP
+func (ui *UIManager) trayNetworksItem() *fyne.MenuItem {
+	online := ui.RealtimeData.IsConnected()
+	currentNetworkID, ip := ui.trayCurrentNetwork()
+
+	var items []*fyne.MenuItem
+	for _, network := range ui.RealtimeData.GetNetworks() {
+		networkID := network.NetworkID
+		connected := networkID == currentNetworkID
+
+		var actions []*fyne.MenuItem
+		if connected {
+			disconnectItem := fyne.NewMenuItem("Disconnect", func() {
+				ui.trayToggleNetwork(networkID, "disconnect from")
+			})
+			disconnectItem.Disabled = !online
+			actions = append(actions, fyne.NewMenuItem("Copy IP "+ip, func() {
+				copyToClipboard(ip, "Virtual IP")
+			}), disconnectItem)
+		} else {
+			connectItem := fyne.NewMenuItem("Connect", func() {
+				ui.trayToggleNetwork(networkID, "connect to")
+			})
+			connectItem.Disabled = !online
+			actions = append(actions, connectItem)
+		}
+
+		item := fyne.NewMenuItem(ui.trayNetworkName(networkID), nil)
+		item.Checked = connected
+		item.ChildMenu = fyne.NewMenu("", actions...)
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		empty := fyne.NewMenuItem("No networks yet", nil)
+		empty.Disabled = true
+		items = append(items, empty)
+	}
+
+	networksItem := fyne.NewMenuItem("Networks", nil)
+	networksItem.ChildMenu = fyne.NewMenu("", items...)
+	return networksItem
+}
+
+// trayCurrentNetwork returns the network this computer is connected to and its virtual IP
+// in it, or an empty ID when it is in none
+func (ui *UIManager) trayCurrentNetwork() (networkID, ip string) {
+	if ui.VPN == nil || ui.VPN.NetworkManager == nil || ui.VPN.NetworkManager.NetworkID == "" {
+		return "", ""
+	}
+	ip = ui.RealtimeData.GetComputerIP()
+	return ui.VPN.NetworkManager.NetworkID, ip
+}
+
+// trayNetworkName returns the name of a joined network, or its ID when the list lacks it
+func (ui *UIManager) trayNetworkName(networkID string) string {
+	for _, network := range ui.RealtimeData.GetNetworks() {
+		if network.NetworkID == networkID && network.NetworkName != "" {
+			return network.NetworkName
+		}
+	}
+	return networkID
+}
+
+// trayToggleNetwork connects to the network, or disconnects when it is the connected one,
+// off the Fyne thread. The main window may be hidden, so a failure shows as a notification.
+func (ui *UIManager) trayToggleNetwork(networkID, action string) {
+	crash.Go("tray network", func() {
+		if err := ui.ConnectToNetwork(networkID, ui.VPN.ComputerName); err != nil {
+			ui.App.SendNotification(&fyne.Notification{
+				Title:   AppName,
+				Content: fmt.Sprintf("Could not %s %s: %v", action, ui.trayNetworkName(networkID), err),
+			})
+		}
+	})
+}
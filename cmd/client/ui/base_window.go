@@ -28,6 +28,7 @@ func NewBaseWindow(app fyne.App, title string, width, height float32) *BaseWindo
 	bw.Window.Resize(fyne.NewSize(width, height))
 	bw.Window.SetFixedSize(true)
 	bw.Window.CenterOnScreen()
+	bw.closeOnEscape()
 
 	// Configurar callback de fechamento
 	bw.Window.SetOnClosed(func() {
@@ -46,6 +47,7 @@ func (bw *BaseWindow) Show() {
 		bw.Window.Resize(fyne.NewSize(bw.width, bw.height))
 		bw.Window.SetFixedSize(true)
 		bw.Window.CenterOnScreen()
+		bw.closeOnEscape()
 
 		// Reconfigurar o callback de fechamento
 		bw.Window.SetOnClosed(func() {
@@ -61,6 +63,15 @@ func (bw *BaseWindow) Show() {
 	bw.Window.Show()
 }
 
+// closeOnEscape fecha a janela com Escape, quando nenhum campo está com o foco
+func (bw *BaseWindow) closeOnEscape() {
+	bw.Window.Canvas().SetOnTypedKey(func(event *fyne.KeyEvent) {
+		if event.Name == fyne.KeyEscape {
+			bw.Close()
+		}
+	})
+}
+
 // Hide esconde a janela
 func (bw *BaseWindow) Hide() {
 	if bw.Window != nil {
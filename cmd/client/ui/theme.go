@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+)
+
+// Theme é o tema padrão do Fyne com as escolhas das configurações: a variante clara ou
+// escura, fixa ou a do sistema, a cor de destaque e a escala da interface, que multiplica
+// todos os tamanhos. Com o texto, os ícones e os espaçamentos cresce também a área clicável
+// de botões e listas.
+type Theme struct {
+	Variant      fyne.ThemeVariant
+	FixedVariant bool        // Usar Variant em vez da variante do sistema
+	Accent       color.NRGBA // Cor de destaque; transparente usa a do tema padrão
+	Scale        float32
+}
+
+// Color retorna a cor do tema padrão na variante escolhida, com o destaque no lugar da cor
+// primária e nas cores derivadas dela
+func (t Theme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	if t.FixedVariant {
+		variant = t.Variant
+	}
+	if t.Accent.A != 0 {
+		switch name {
+		case theme.ColorNamePrimary, theme.ColorNameHyperlink:
+			return t.Accent
+		case theme.ColorNameFocus:
+			return color.NRGBA{R: t.Accent.R, G: t.Accent.G, B: t.Accent.B, A: 0x7f}
+		case theme.ColorNameSelection:
+			return color.NRGBA{R: t.Accent.R, G: t.Accent.G, B: t.Accent.B, A: 0x3f}
+		}
+	}
+	return theme.DefaultTheme().Color(name, variant)
+}
+
+// Font retorna a fonte do tema padrão
+func (t Theme) Font(style fyne.TextStyle) fyne.Resource {
+	return theme.DefaultTheme().Font(style)
+}
+
+// Icon retorna o ícone do tema padrão
+func (t Theme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return theme.DefaultTheme().Icon(name)
+}
+
+// Size retorna o tamanho do tema padrão multiplicado pela escala
+func (t Theme) Size(name fyne.ThemeSizeName) float32 {
+	return theme.DefaultTheme().Size(name) * t.Scale
+}
+
+// SetTheme aplica o tema a todas as janelas do aplicativo, que são redesenhadas; nada muda
+// quando o tema já está em uso
+func SetTheme(app fyne.App, t Theme) {
+	if current, ok := app.Settings().Theme().(Theme); ok && current == t {
+		return
+	}
+	app.Settings().SetTheme(t)
+}
+
+// Scale retorna a escala da interface em uso
+func Scale() float32 {
+	if t, ok := fyne.CurrentApp().Settings().Theme().(Theme); ok {
+		return t.Scale
+	}
+	return 1
+}
+
+// ParseColor lê uma cor no formato #rrggbb
+func ParseColor(hex string) (color.NRGBA, error) {
+	if len(hex) != 7 || hex[0] != '#' {
+		return color.NRGBA{}, errors.New("color must be in the #rrggbb format")
+	}
+	rgb, err := strconv.ParseUint(hex[1:], 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %s", hex)
+	}
+	return color.NRGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, nil
+}
+
+// FormatColor escreve a cor no formato #rrggbb, ignorando a transparência
+func FormatColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+}
+
+// squareLayout dá a cada objeto um quadrado de lado fixo, multiplicado pela escala
+type squareLayout struct {
+	side float32
+}
+
+func (l squareLayout) MinSize([]fyne.CanvasObject) fyne.Size {
+	return fyne.NewSquareSize(l.side * Scale())
+}
+
+func (l squareLayout) Layout(objects []fyne.CanvasObject, _ fyne.Size) {
+	for _, o := range objects {
+		o.Move(fyne.NewPos(0, 0))
+		o.Resize(l.MinSize(objects))
+	}
+}
+
+// NewSquare põe o objeto, em geral um botão só com ícone, num quadrado de lado side que
+// acompanha a escala da interface
+func NewSquare(side float32, object fyne.CanvasObject) *fyne.Container {
+	return container.New(squareLayout{side: side}, object)
+}
@@ -2,16 +2,21 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 
+	"github.com/itxtoledo/govpn/cmd/client/bus"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/crash"
 	"github.com/itxtoledo/govpn/cmd/client/data"
 	dialogs "github.com/itxtoledo/govpn/cmd/client/dialogs"
+	"github.com/itxtoledo/govpn/cmd/client/network"
+	"github.com/itxtoledo/govpn/libs/logger"
+	sclient "github.com/itxtoledo/govpn/libs/signaling/client"
 	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
 )
 
@@ -20,36 +25,37 @@ type UIManager struct {
 	openAccordionStates map[string]bool
 	App                 fyne.App
 	MainWindow          fyne.Window
-	VPN                 *VPNClient
-	ConfigManager       *ConfigManager
+	VPN                 *core.VPNClient
+	ConfigManager       *core.ConfigManager
 	NetworkListComp     *NetworkListComponent
 	HomeScreenComponent *HomeScreenComponent
 	HeaderComponent     *HeaderComponent
+	NoticeBanner        *NoticeBanner
+	ThroughputWidget    *ThroughputWidget
+	StatusBar           *StatusBar
 	AboutWindow         *AboutWindow
 	ConnectDialog       *dialogs.ConnectDialog
 	ComputerList        []smodels.Computer
 	SelectedNetwork     *data.Network
 	defaultWebsocketURL string
 
-	// Nova camada de dados em tempo real
+	// Nova camada de dados em tempo real, e os bindings da interface que a seguem
 	RealtimeData *data.RealtimeDataLayer
+	Bindings     *Bindings
 }
 
 // NewUIManager creates a new instance of UIManager
-func NewUIManager(websocketURL string, computername string, configPath string) *UIManager {
+func NewUIManager(fyneApp fyne.App, websocketURL string, computername string, configManager *core.ConfigManager) *UIManager {
 	ui := &UIManager{
+		App:                 fyneApp,
+		ConfigManager:       configManager,
 		defaultWebsocketURL: websocketURL,
 		openAccordionStates: make(map[string]bool),
 	}
 
 	// Criar a camada de dados em tempo real - ensure this is properly initialized
 	ui.RealtimeData = data.NewRealtimeDataLayer()
-
-	// Create new app
-	ui.App = app.NewWithID("com.itxtoledo.govpn")
-
-	// Initialize configuration manager
-	ui.ConfigManager = NewConfigManager(configPath)
+	ui.Bindings = NewBindings(ui.RealtimeData.Bus)
 
 	// Create main window
 	ui.MainWindow = ui.App.NewWindow("GoVPN")
@@ -57,16 +63,17 @@ func NewUIManager(websocketURL string, computername string, configPath string) *
 	ui.MainWindow.SetMaster()
 
 	// Create VPN client - note the order change to avoid circular reference
-	ui.VPN = NewVPNClient(ui.ConfigManager, websocketURL, computername)
+	ui.VPN = core.NewVPNClient(ui.ConfigManager, websocketURL, computername)
 
 	// Initialize default values AFTER all components are created
 	ui.RealtimeData.InitDefaults()
 
 	// Setup components
 	ui.setupComponents()
+	ui.setupShortcuts()
 
 	// Setup NetworkManager for VPN client now that dependencies are available
-	ui.VPN.SetupNetworkManager(ui.RealtimeData, ui.refreshNetworkList, ui.refreshUI)
+	ui.VPN.SetupNetworkManager(ui.RealtimeData)
 
 	// Configure quit handler
 	ui.MainWindow.SetOnClosed(func() {
@@ -74,7 +81,8 @@ func NewUIManager(websocketURL string, computername string, configPath string) *
 	})
 
 	// Configurar listener de eventos da camada de dados em tempo real
-	go ui.listenForDataEvents()
+	crash.Go("data events", ui.listenForDataEvents)
+	crash.Go("refresh requests", ui.listenForRefresh)
 
 	// Refresh UI
 	ui.refreshUI()
@@ -82,9 +90,21 @@ func NewUIManager(websocketURL string, computername string, configPath string) *
 	return ui
 }
 
+// listenForRefresh redraws what the client core asks for
+func (ui *UIManager) listenForRefresh() {
+	for refresh := range bus.Subscribe(ui.RealtimeData.Bus, data.TopicRefresh).C() {
+		switch refresh {
+		case data.RefreshNetworkList:
+			ui.refreshNetworkList()
+		case data.RefreshUI:
+			ui.refreshUI()
+		}
+	}
+}
+
 // listenForDataEvents escuta eventos da camada de dados em tempo real
 func (ui *UIManager) listenForDataEvents() {
-	for event := range ui.RealtimeData.Subscribe() {
+	for event := range ui.RealtimeData.Subscribe().C() {
 		switch event.Type {
 		case data.EventConnectionStateChanged:
 			// Atualizar a UI quando o estado da conexão mudar
@@ -98,39 +118,130 @@ func (ui *UIManager) listenForDataEvents() {
 		case data.EventNetworkDeleted:
 			// Atualizar a UI quando uma sala for excluída
 			ui.refreshNetworkList()
+			ui.notify(event)
 		case data.EventNetworksChanged:
 			// Atualizar a UI quando a lista de redes for alterada
 			ui.refreshNetworkList()
 		case data.EventSettingsChanged:
 			// Atualizar configurações quando forem alteradas
 			ui.refreshUI()
-		case data.EventComputerJoined:
-			// Exibir notificação de computador conectado
-			// dialog.ShowInformation("Computer Connected", event.Message, ui.MainWindow)
+		case data.EventComputerJoined, data.EventComputerLeft, data.EventKicked, data.EventPeerConnected, data.EventPeerLost:
+			// Notificações da área de trabalho, conforme as escolhas nas configurações
+			ui.notify(event)
 		case data.EventComputerConnected:
 			// Exibir notificação de computador conectado
 			// dialog.ShowInformation("Computer Connected", event.Message, ui.MainWindow)
+		case data.EventNetworkExpiring:
+			// Oferecer manter a rede ativa
+			if warning, ok := event.Data.(smodels.NetworkExpiryWarningNotification); ok {
+				ui.showNetworkExpiryWarning(warning.NetworkID, event.Message)
+			}
+		case data.EventServerShutdown:
+			// Avisar no banner e na área de trabalho
+			ui.NoticeBanner.ShowNotice(smodels.ServerNoticeNotification{
+				ID:      "server-shutdown",
+				Message: event.Message,
+				Level:   smodels.NoticeLevelWarning,
+				SentAt:  time.Now(),
+			})
+			ui.notify(event)
+		case data.EventFileOffered:
+			// Perguntar se o arquivo é aceito
+			if transfer, ok := event.Data.(network.Transfer); ok {
+				ui.showFileOffer(transfer)
+			}
+			ui.notify(event)
+		case data.EventFileTransferFinished:
+			ui.notify(event)
+		case data.EventChatMessage:
+			// Mostrar na janela de mensagens; só as recebidas trazem texto para notificar
+			if chat, ok := event.Data.(core.ChatEvent); ok {
+				ui.handleChatEvent(chat)
+			}
+			if event.Message != "" {
+				ui.notify(event)
+			}
+		case data.EventServerNotice:
+			// Exibir o aviso do servidor no banner
+			if notice, ok := event.Data.(smodels.ServerNoticeNotification); ok {
+				ui.NoticeBanner.ShowNotice(notice)
+			}
+		case data.EventSubnetConflict:
+			// Avisar da sobreposição e, ao dono, oferecer outra sub-rede
+			if conflict, ok := event.Data.(core.SubnetConflict); ok {
+				ui.showSubnetConflict(conflict)
+			}
 		case data.EventError:
 			// Exibir erro
-			log.Printf("Error event: %s", event.Message)
+			logger.Warn("Error event", "message", event.Message)
 		}
 	}
 }
 
+// showNetworkExpiryWarning asks the owner whether an expiring network should be kept alive
+func (ui *UIManager) showNetworkExpiryWarning(networkID, message string) {
+	fyne.Do(func() {
+		dialog.ShowConfirm("Network Expiring", message+"\n\nKeep it alive?", func(keep bool) {
+			if !keep {
+				return
+			}
+			crash.Go("keep network alive", func() {
+				if err := ui.VPN.NetworkManager.KeepNetworkAlive(networkID); err != nil {
+					logger.Warn("Error keeping network alive", "networkID", networkID, "error", err)
+					fyne.Do(func() {
+						dialogs.ShowError(err, ui.MainWindow)
+					})
+				}
+			})
+		}, ui.MainWindow)
+	})
+}
+
+// showSubnetConflict warns that the network subnet overlaps local networks and lets the
+// owner move the network to another range
+func (ui *UIManager) showSubnetConflict(conflict core.SubnetConflict) {
+	message := fmt.Sprintf("This network uses %s, which overlaps these local networks:\n\n%s\n\n"+
+		"Computers in the overlapping range may be unreachable while connected.",
+		conflict.Subnet, strings.Join(conflict.Conflicts, "\n"))
+
+	fyne.Do(func() {
+		if !conflict.Owner {
+			dialog.ShowInformation("Subnet Conflict", message+" Ask the network owner to change the subnet.", ui.MainWindow)
+			return
+		}
+		dialog.ShowConfirm("Subnet Conflict", message+"\n\nMove the network to another range? Every member gets a new IP.", func(change bool) {
+			if !change {
+				return
+			}
+			crash.Go("change subnet", func() {
+				if _, err := ui.VPN.NetworkManager.RequestAlternateSubnet(conflict.NetworkID); err != nil {
+					logger.Warn("Error changing the network subnet", "networkID", conflict.NetworkID, "error", err)
+					fyne.Do(func() {
+						dialogs.ShowError(err, ui.MainWindow)
+					})
+				}
+			})
+		}, ui.MainWindow)
+	})
+}
+
 // setupComponents initializes all UI components
 func (ui *UIManager) setupComponents() {
 	// Create components
 	ui.HeaderComponent = NewHeaderComponent(ui, ui.defaultWebsocketURL)
 	ui.NetworkListComp = NewNetworkListComponent(ui)
+	ui.ThroughputWidget = NewThroughputWidget(ui.RealtimeData)
 	ui.HomeScreenComponent = NewHomeScreenComponent(ui.ConfigManager, ui.RealtimeData, ui.NetworkListComp, ui)
+	ui.NoticeBanner = NewNoticeBanner()
+	ui.StatusBar = NewStatusBar(ui.RealtimeData, ui.Bindings)
 
 	// Create main container
 	headerContainer := ui.HeaderComponent.CreateHeaderContainer()
 
 	// Create vertical container
 	mainContainer := container.NewBorder(
-		headerContainer,
-		nil,
+		container.NewVBox(headerContainer, ui.NoticeBanner.Container()),
+		ui.StatusBar.Container(),
 		nil,
 		nil,
 		ui.HomeScreenComponent.CreateHomeScreenContainer(),
@@ -160,6 +271,37 @@ func (ui *UIManager) OpenChatWindow(network *data.Network) {
 	globalChatWindow.Show()
 }
 
+// OpenNetworkDetailWindow creates and shows the member list of a network
+func (ui *UIManager) OpenNetworkDetailWindow(network *data.Network) {
+	if globalNetworkDetailWindow != nil && globalNetworkDetailWindow.BaseWindow.Window != nil {
+		if globalNetworkDetailWindow.networkID == network.NetworkID {
+			globalNetworkDetailWindow.BaseWindow.Window.RequestFocus()
+			return
+		}
+		// One detail window at a time, showing the network asked for last
+		globalNetworkDetailWindow.Close()
+	}
+
+	globalNetworkDetailWindow = NewNetworkDetailWindow(ui, network)
+	globalNetworkDetailWindow.Show()
+}
+
+// OpenNetworkStatsWindow creates and shows the statistics window of an owned network
+func (ui *UIManager) OpenNetworkStatsWindow(network *data.Network) {
+	if globalNetworkStatsWindow != nil && globalNetworkStatsWindow.BaseWindow.Window != nil {
+		// Focus on existing window if already open
+		globalNetworkStatsWindow.BaseWindow.Window.RequestFocus()
+		return
+	}
+
+	globalNetworkStatsWindow = NewNetworkStatsWindow(
+		ui.App,
+		network,
+		ui.VPN.NetworkManager.GetNetworkStats,
+	)
+	globalNetworkStatsWindow.Show()
+}
+
 func (ui *UIManager) ShowAboutWindow() {
 	// Create and show the about window (singleton pattern)
 	if ui.AboutWindow != nil && ui.AboutWindow.BaseWindow.Window != nil {
@@ -193,19 +335,21 @@ func (ui *UIManager) ShowSettingsWindow() {
 		config,
 		ui.HandleSettingsSaved,
 	)
+	globalSettingsWindow.OnDiagnose = ui.OpenDiagnosticsWindow
+	globalSettingsWindow.OnReopen = ui.ShowSettingsWindow
 	globalSettingsWindow.Show()
 }
 
 // handleAppQuit handles application quit
 func (ui *UIManager) handleAppQuit() {
-	log.Println("Quitting app...")
+	logger.Info("Quitting app")
 
 }
 
 // refreshUI refreshes the UI components
 func (ui *UIManager) refreshUI() {
 	// Use dados da camada de dados em tempo real para atualizar a UI
-	isConnected, _ := ui.RealtimeData.IsConnected.Get()
+	isConnected := ui.RealtimeData.IsConnected()
 
 	// Atualizar componentes baseados nos dados em tempo real
 	ui.VPN.IsConnected = isConnected
@@ -242,13 +386,13 @@ func (ui *UIManager) ConnectToNetwork(networkID, computername string) error {
 
 	// If already connected to the selected network, disconnect
 	if currentNetworkID == networkID {
-		log.Printf("Attempting to disconnect from network %s", networkID)
+		logger.Debug("Disconnecting from the selected network", "networkID", networkID)
 		return ui.VPN.NetworkManager.DisconnectNetwork(networkID)
 	}
 
 	// If connected to a different network, disconnect first
 	if currentNetworkID != "" {
-		log.Printf("Already connected to network %s, disconnecting before connecting to %s", currentNetworkID, networkID)
+		logger.Debug("Disconnecting from the current network before connecting to another", "from", currentNetworkID, "to", networkID)
 		err := ui.VPN.NetworkManager.DisconnectNetwork(currentNetworkID)
 		if err != nil {
 			return fmt.Errorf("failed to disconnect from current network: %v", err)
@@ -256,7 +400,7 @@ func (ui *UIManager) ConnectToNetwork(networkID, computername string) error {
 	}
 
 	// Connect to the selected network
-	log.Printf("Attempting to connect to network %s", networkID)
+	logger.Debug("Connecting to the selected network", "networkID", networkID)
 	return ui.VPN.NetworkManager.ConnectNetwork(networkID)
 }
 
@@ -269,6 +413,9 @@ func (ui *UIManager) refreshNetworkList() {
 		if ui.NetworkListComp != nil {
 			ui.NetworkListComp.UpdateNetworkList(ui.openAccordionStates)
 		}
+		if globalNetworkDetailWindow != nil {
+			globalNetworkDetailWindow.Refresh()
+		}
 	})
 
 	// Update UI
@@ -288,6 +435,81 @@ func (ui *UIManager) HandleNetworkCreated(networkID, networkName, pin string) {
 	dialog.ShowInformation("Success", "Network created and saved!", ui.MainWindow)
 }
 
+// ShowJoinWindow opens the network joining window, or focuses the open one, filled with an
+// invite's network ID and PIN when given
+func (ui *UIManager) ShowJoinWindow(networkID, pin string) {
+	if globalJoinWindow != nil && globalJoinWindow.BaseWindow.Window != nil {
+		if networkID != "" {
+			globalJoinWindow.SetInvite(networkID, pin)
+		}
+		globalJoinWindow.BaseWindow.Window.RequestFocus()
+		return
+	}
+
+	computername := ui.RealtimeData.GetComputerName()
+	if computername == "" {
+		computername = "Computer" // Default fallback
+	}
+
+	adapter := &NetworkManagerAdapter{ui.VPN.NetworkManager}
+	globalJoinWindow = NewJoinWindow(
+		ui.App,
+		adapter.JoinNetwork,
+		func(networkID string) (*smodels.NetworkExistsResponse, error) {
+			return sclient.CheckNetworkExists(ui.currentServer(), networkID)
+		},
+		computername,
+		func(networkID, pin string) {
+			ui.HandleNetworkJoined(networkID, pin)
+		},
+	)
+	globalJoinWindow.SetInvite(networkID, pin)
+	globalJoinWindow.Show()
+}
+
+// OpenInvite mostra a janela de entrada preenchida com um convite. Um convite de outro
+// servidor pede antes para trocar de servidor, já que a rede só existe lá.
+func (ui *UIManager) OpenInvite(invite core.Invite) {
+	logger.Info("Opening invite", "networkID", invite.NetworkID, "server", invite.Server)
+	ui.MainWindow.Show()
+
+	if invite.Server == "" || invite.Server == ui.currentServer() {
+		ui.ShowJoinWindow(invite.NetworkID, invite.PIN)
+		return
+	}
+
+	message := fmt.Sprintf("This invite is for a network on %s. Switch to that server to join it?", invite.Server)
+	dialog.ShowConfirm("Switch server", message, func(ok bool) {
+		if !ok {
+			return
+		}
+
+		// O servidor do convite vale também nos próximos inícios, sem a escolha automática
+		config := ui.ConfigManager.GetConfig()
+		config.ServerAddress = invite.Server
+		config.AutoSelectServer = false
+		if err := ui.ConfigManager.UpdateConfig(config); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save the server: %v", err), ui.MainWindow)
+			return
+		}
+		ui.RealtimeData.SetServerAddress(invite.Server)
+
+		crash.Go("open invite", func() {
+			ui.VPN.NetworkManager.Disconnect()
+			ui.VPN.Run(ui.defaultWebsocketURL, ui.RealtimeData)
+		})
+		ui.ShowJoinWindow(invite.NetworkID, invite.PIN)
+	}, ui.MainWindow)
+}
+
+// currentServer retorna o endereço do servidor em uso, que os convites levam
+func (ui *UIManager) currentServer() string {
+	if server := ui.RealtimeData.GetServerAddress(); server != "" {
+		return server
+	}
+	return ui.defaultWebsocketURL
+}
+
 // HandleNetworkJoined is the callback for when a network is joined
 func (ui *UIManager) HandleNetworkJoined(networkID, pin string) {
 	network := data.Network{
@@ -301,11 +523,11 @@ func (ui *UIManager) HandleNetworkJoined(networkID, pin string) {
 }
 
 // Run runs the application
-func (ui *UIManager) HandleSettingsSaved(config Config) {
+func (ui *UIManager) HandleSettingsSaved(config core.Config) {
 	// Save new settings
 	err := ui.ConfigManager.UpdateConfig(config)
 	if err != nil {
-		log.Printf("Error saving settings: %v", err)
+		logger.Error("Error saving settings", "error", err)
 	}
 
 	// Apply settings
@@ -313,7 +535,7 @@ func (ui *UIManager) HandleSettingsSaved(config Config) {
 }
 
 // applySettings applies the settings
-func (ui *UIManager) applySettings(config Config) {
+func (ui *UIManager) applySettings(config core.Config) {
 
 	// Update computer name in realtime data layer
 	ui.RealtimeData.SetComputerName(config.ComputerName)
@@ -321,12 +543,22 @@ func (ui *UIManager) applySettings(config Config) {
 	// Update server address
 	ui.RealtimeData.SetServerAddress(config.ServerAddress)
 
+	// A aparência vale na hora para todas as janelas
+	applyTheme(ui.App, config)
+
+	// O nível do log muda na hora, sem reiniciar
+	if err := logger.SetLevel(config.MinLogLevel()); err != nil {
+		logger.Warn("Invalid log level", "level", config.LogLevel, "error", err)
+	}
+
 	// Send updated client info to the server
 	if ui.VPN != nil && ui.VPN.NetworkManager != nil {
 		// Update client info on the server only if the computer name has changed
 		if config.ComputerName != ui.ConfigManager.GetConfig().ComputerName {
 			ui.VPN.NetworkManager.UpdateClientInfo()
 		}
+		// Só vai ao servidor quando o endereço informado muda
+		crash.Go("wake info", ui.VPN.NetworkManager.ReportWakeInfo)
 	}
 
 	// Emit settings changed event
@@ -335,16 +567,20 @@ func (ui *UIManager) applySettings(config Config) {
 	// Refresh UI
 }
 
-// Run starts the UI and the VPN client
-func (ui *UIManager) Run(defaultWebsocketURL string) {
-	log.Println("Iniciando GoVPN Client")
+// Start shows the UI and starts the VPN client; minimized, only the tray icon shows until Show
+// is picked. The event loop is run by the caller.
+func (ui *UIManager) Start(defaultWebsocketURL string, minimized bool) {
+	logger.Info("Starting GoVPN client", "version", AppVersion)
 
 	// Networks are now managed by RealtimeDataLayer
 
 	// Garantir que as configurações sejam aplicadas antes de exibir a janela
 	if ui.VPN != nil {
 		// Carrega as configurações do ConfigManager para a camada de dados
-		ui.VPN.loadSettings(ui.RealtimeData)
+		ui.VPN.LoadSettings(ui.RealtimeData)
+
+		// Mostra as últimas redes conhecidas até o servidor enviar a lista
+		ui.VPN.NetworkManager.LoadNetworks()
 	}
 
 	// Verificar o tamanho da janela principal - fixar em 300x600 conforme requisitos
@@ -356,15 +592,18 @@ func (ui *UIManager) Run(defaultWebsocketURL string) {
 
 	// Iniciar conexão com o servidor de sinalização em segundo plano
 	if ui.VPN != nil {
-		go func() {
+		crash.Go("start connection", func() {
 			fyne.Do(func() {
-
-				log.Println("Iniciando conexão automática com o servidor de sinalização")
-				ui.VPN.Run(defaultWebsocketURL, ui.RealtimeData, ui.refreshNetworkList, ui.refreshUI)
+				logger.Debug("Connecting to the signaling server in the background")
+				ui.VPN.Run(defaultWebsocketURL, ui.RealtimeData)
 			})
-		}()
+		})
 	}
 
-	// Exibir a janela e executar o loop de eventos principal
-	ui.MainWindow.ShowAndRun()
+	// Exibir a janela; minimizado, ela só aparece pelo item Show da bandeja
+	if minimized {
+		logger.Info("Starting minimized to the system tray")
+		return
+	}
+	ui.MainWindow.Show()
 }
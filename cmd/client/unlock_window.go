@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/itxtoledo/govpn/cmd/client/core"
+	"github.com/itxtoledo/govpn/cmd/client/ui"
+)
+
+// UnlockWindow pede a senha da configuração cifrada antes de o aplicativo abrir. Com a
+// chave no cofre do sistema fora do alcance, mostra o erro e deixa tentar de novo.
+type UnlockWindow struct {
+	*ui.BaseWindow
+	PassphraseEntry *widget.Entry
+	ErrorLabel      *widget.Label
+	UnlockButton    *widget.Button
+
+	configManager *core.ConfigManager
+	onUnlocked    func()
+	unlocked      bool
+}
+
+// NewUnlockWindow cria a janela; onUnlocked roda depois que a configuração abrir
+func NewUnlockWindow(app fyne.App, configManager *core.ConfigManager, onUnlocked func()) *UnlockWindow {
+	uw := &UnlockWindow{
+		BaseWindow:    ui.NewBaseWindow(app, "Unlock "+AppName, 320, 200),
+		configManager: configManager,
+		onUnlocked:    onUnlocked,
+	}
+
+	// Fechar sem abrir a configuração encerra o aplicativo
+	uw.Window.SetOnClosed(func() {
+		uw.Window = nil
+		if !uw.unlocked {
+			app.Quit()
+		}
+	})
+
+	uw.PassphraseEntry = widget.NewPasswordEntry()
+	uw.PassphraseEntry.SetPlaceHolder("Passphrase")
+	uw.PassphraseEntry.OnSubmitted = func(string) {
+		uw.unlock()
+	}
+
+	uw.ErrorLabel = widget.NewLabel("")
+	uw.ErrorLabel.Wrapping = fyne.TextWrapWord
+	uw.ErrorLabel.Importance = widget.DangerImportance
+
+	uw.UnlockButton = widget.NewButtonWithIcon("Unlock", theme.ConfirmIcon(), func() {
+		uw.unlock()
+	})
+
+	message := "Your settings are encrypted. Enter the passphrase to open them."
+	if err := configManager.Locked(); !errors.Is(err, core.ErrConfigLocked) {
+		// A chave está no cofre do sistema, não há senha a pedir
+		message = "The key that encrypts your settings could not be read from the system key store."
+		uw.ErrorLabel.SetText(err.Error())
+		uw.PassphraseEntry.Hide()
+		uw.UnlockButton.SetText("Try again")
+	}
+	messageLabel := widget.NewLabel(message)
+	messageLabel.Wrapping = fyne.TextWrapWord
+
+	content := container.NewVBox(
+		messageLabel,
+		uw.PassphraseEntry,
+		uw.ErrorLabel,
+		container.NewHBox(layout.NewSpacer(), uw.UnlockButton),
+	)
+	uw.SetContent(container.NewPadded(content))
+	uw.Window.Canvas().Focus(uw.PassphraseEntry)
+
+	return uw
+}
+
+// unlock tenta abrir a configuração e, aberta, segue com o aplicativo
+func (uw *UnlockWindow) unlock() {
+	if err := uw.configManager.Unlock(uw.PassphraseEntry.Text); err != nil {
+		uw.PassphraseEntry.SetText("")
+		uw.ErrorLabel.SetText(err.Error())
+		return
+	}
+
+	// A interface abre antes de a janela fechar, para o aplicativo não ficar sem janelas
+	uw.unlocked = true
+	uw.onUnlocked()
+	uw.Close()
+}
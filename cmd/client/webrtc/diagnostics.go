@@ -0,0 +1,156 @@
+package clientwebrtc_impl
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// ErrProbeTimeout is returned when a diagnostic probe does not finish in time
+var ErrProbeTimeout = errors.New("timed out")
+
+// ProbeICEServer gathers candidates with a single ICE server and returns the address it
+// gave: the public address for a STUN server, the relayed one for a TURN server, which
+// also proves its credentials work
+func ProbeICEServer(server ICEServer, timeout time.Duration) (string, error) {
+	want := webrtc.ICECandidateTypeSrflx
+	if server.IsTURN() {
+		want = webrtc.ICECandidateTypeRelay
+	}
+
+	pc, err := webrtc.NewPeerConnection(Options{ICEServers: []ICEServer{server}, RelayOnly: server.IsTURN()}.configuration())
+	if err != nil {
+		return "", err
+	}
+	defer pc.Close()
+
+	found := make(chan string, 1)
+	pc.OnICECandidate(func(c *webrtc.ICECandidate) {
+		if c != nil && c.Typ == want {
+			select {
+			case found <- net.JoinHostPort(c.Address, strconv.Itoa(int(c.Port))):
+			default:
+			}
+		}
+	})
+	gathered := webrtc.GatheringCompletePromise(pc)
+
+	// Without a channel or a track the offer has nothing to gather candidates for
+	if _, err := pc.CreateDataChannel("probe", nil); err != nil {
+		return "", err
+	}
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		return "", err
+	}
+	if err := pc.SetLocalDescription(offer); err != nil {
+		return "", err
+	}
+
+	select {
+	case address := <-found:
+		return address, nil
+	case <-gathered:
+		// The last candidate may have been sent just before gathering completed
+		select {
+		case address := <-found:
+			return address, nil
+		default:
+		}
+		if server.IsTURN() {
+			return "", errors.New("no relayed address, check the URL and the credentials")
+		}
+		return "", errors.New("no public address, the server did not answer")
+	case <-time.After(timeout):
+		return "", ErrProbeTimeout
+	}
+}
+
+// Loopback connects two peer connections of this computer, echoes a message over a data
+// channel between them and returns the round trip. They use local addresses only, which
+// tests the DTLS and SCTP stack, unless opts has RelayOnly: then the message goes through
+// the TURN servers, like the traffic to peers.
+func Loopback(opts Options, timeout time.Duration) (time.Duration, error) {
+	config := webrtc.Configuration{}
+	if opts.RelayOnly {
+		config = opts.configuration()
+	}
+
+	offerer, err := webrtc.NewPeerConnection(config)
+	if err != nil {
+		return 0, err
+	}
+	defer offerer.Close()
+	answerer, err := webrtc.NewPeerConnection(config)
+	if err != nil {
+		return 0, err
+	}
+	defer answerer.Close()
+
+	answerer.OnDataChannel(func(dc *webrtc.DataChannel) {
+		dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+			dc.Send(msg.Data)
+		})
+	})
+
+	dc, err := offerer.CreateDataChannel("loopback", nil)
+	if err != nil {
+		return 0, err
+	}
+	// The message carries its send time, which comes back with the echo
+	echoed := make(chan time.Duration, 1)
+	dc.OnOpen(func() {
+		dc.SendText(strconv.FormatInt(time.Now().UnixNano(), 10))
+	})
+	dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		sentAt, err := strconv.ParseInt(string(msg.Data), 10, 64)
+		if err != nil {
+			return
+		}
+		select {
+		case echoed <- time.Since(time.Unix(0, sentAt)):
+		default:
+		}
+	})
+
+	deadline := time.After(timeout)
+	// The descriptions carry every candidate, so nothing has to be trickled
+	exchange := func(from, to *webrtc.PeerConnection, description webrtc.SessionDescription) error {
+		gathered := webrtc.GatheringCompletePromise(from)
+		if err := from.SetLocalDescription(description); err != nil {
+			return err
+		}
+		select {
+		case <-gathered:
+		case <-deadline:
+			return fmt.Errorf("gathering candidates: %w", ErrProbeTimeout)
+		}
+		return to.SetRemoteDescription(*from.LocalDescription())
+	}
+
+	offer, err := offerer.CreateOffer(nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := exchange(offerer, answerer, offer); err != nil {
+		return 0, err
+	}
+	answer, err := answerer.CreateAnswer(nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := exchange(answerer, offerer, answer); err != nil {
+		return 0, err
+	}
+
+	select {
+	case rtt := <-echoed:
+		return rtt, nil
+	case <-deadline:
+		return 0, fmt.Errorf("echo over the data channel: %w", ErrProbeTimeout)
+	}
+}
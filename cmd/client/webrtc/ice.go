@@ -0,0 +1,90 @@
+package clientwebrtc_impl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// DefaultICEServers are used when the settings list no ICE servers
+var DefaultICEServers = []ICEServer{{URL: "stun:stun.l.google.com:19302"}}
+
+// ICEServer is a STUN or TURN server used to find a path to a peer
+type ICEServer struct {
+	URL        string `json:"url"`                  // stun:, stuns:, turn: or turns: URL
+	Username   string `json:"username,omitempty"`   // TURN only
+	Credential string `json:"credential,omitempty"` // TURN only
+}
+
+// Options configures the peer connections of a WebRTCManager
+type Options struct {
+	ICEServers []ICEServer // DefaultICEServers when empty
+	RelayOnly  bool        // Only connect through TURN relays, hiding local and public addresses from peers
+}
+
+// IsTURN reports whether the server relays traffic
+func (s ICEServer) IsTURN() bool {
+	return strings.HasPrefix(s.URL, "turn:") || strings.HasPrefix(s.URL, "turns:")
+}
+
+// String formats the server as "stun:host:port" or "turn:host:port username credential"
+func (s ICEServer) String() string {
+	if s.Username == "" && s.Credential == "" {
+		return s.URL
+	}
+	return fmt.Sprintf("%s %s %s", s.URL, s.Username, s.Credential)
+}
+
+// ParseICEServer reads a server in the String format
+func ParseICEServer(s string) (ICEServer, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ICEServer{}, errors.New("empty ICE server")
+	}
+
+	server := ICEServer{URL: fields[0]}
+	scheme, host, ok := strings.Cut(server.URL, ":")
+	if !ok || host == "" {
+		return ICEServer{}, fmt.Errorf("ICE server %q is not a stun: or turn: URL", fields[0])
+	}
+
+	switch scheme {
+	case "stun", "stuns":
+		if len(fields) != 1 {
+			return ICEServer{}, fmt.Errorf("STUN server %q takes no credentials", fields[0])
+		}
+	case "turn", "turns":
+		if len(fields) != 3 {
+			return ICEServer{}, fmt.Errorf("TURN server %q is not \"url username credential\"", s)
+		}
+		server.Username, server.Credential = fields[1], fields[2]
+	default:
+		return ICEServer{}, fmt.Errorf("unknown ICE server scheme %q, use stun, stuns, turn or turns", scheme)
+	}
+	return server, nil
+}
+
+// configuration builds the pion configuration of a peer connection
+func (o Options) configuration() webrtc.Configuration {
+	servers := o.ICEServers
+	if len(servers) == 0 {
+		servers = DefaultICEServers
+	}
+
+	config := webrtc.Configuration{}
+	for _, server := range servers {
+		iceServer := webrtc.ICEServer{URLs: []string{server.URL}}
+		if server.IsTURN() {
+			iceServer.Username = server.Username
+			iceServer.Credential = server.Credential
+			iceServer.CredentialType = webrtc.ICECredentialTypePassword
+		}
+		config.ICEServers = append(config.ICEServers, iceServer)
+	}
+	if o.RelayOnly {
+		config.ICETransportPolicy = webrtc.ICETransportPolicyRelay
+	}
+	return config
+}
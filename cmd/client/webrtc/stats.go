@@ -0,0 +1,62 @@
+package clientwebrtc_impl
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// PathStats describes the candidate pair ICE selected to reach the peer
+type PathStats struct {
+	LocalCandidateType  webrtc.ICECandidateType // host, srflx, prflx or relay
+	RemoteCandidateType webrtc.ICECandidateType
+	BytesSent           uint64        // Bytes sent over the pair, SCTP and DTLS overhead included
+	BytesReceived       uint64        // Bytes received over the pair
+	RTT                 time.Duration // Round trip time of the latest ICE check
+	AvailableBitrate    float64       // Estimated outgoing capacity in bits per second, 0 when unknown
+	Retransmissions     uint64        // ICE checks retransmitted on the pair, in both directions
+}
+
+// Relayed reports whether the path goes through a TURN server on either side
+func (p PathStats) Relayed() bool {
+	return p.LocalCandidateType == webrtc.ICECandidateTypeRelay || p.RemoteCandidateType == webrtc.ICECandidateTypeRelay
+}
+
+// PathStats returns the stats of the selected candidate pair, false until ICE connects
+func (w *WebRTCManager) PathStats() (PathStats, bool) {
+	report := w.peerConnection.GetStats()
+
+	var pair webrtc.ICECandidatePairStats
+	found := false
+	for _, stats := range report {
+		candidate, ok := stats.(webrtc.ICECandidatePairStats)
+		if !ok || candidate.State != webrtc.StatsICECandidatePairStateSucceeded {
+			continue
+		}
+		// The nominated pair carries the traffic; any other succeeded pair is a fallback
+		if !found || candidate.Nominated {
+			pair, found = candidate, true
+		}
+		if candidate.Nominated {
+			break
+		}
+	}
+	if !found {
+		return PathStats{}, false
+	}
+
+	stats := PathStats{
+		BytesSent:        pair.BytesSent,
+		BytesReceived:    pair.BytesReceived,
+		RTT:              time.Duration(pair.CurrentRoundTripTime * float64(time.Second)),
+		AvailableBitrate: pair.AvailableOutgoingBitrate,
+		Retransmissions:  pair.RetransmissionsSent + pair.RetransmissionsReceived,
+	}
+	if local, ok := report[pair.LocalCandidateID].(webrtc.ICECandidateStats); ok {
+		stats.LocalCandidateType = local.CandidateType
+	}
+	if remote, ok := report[pair.RemoteCandidateID].(webrtc.ICECandidateStats); ok {
+		stats.RemoteCandidateType = remote.CandidateType
+	}
+	return stats, true
+}
@@ -1,34 +1,54 @@
 package clientwebrtc_impl
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"sync/atomic"
 
+	"github.com/itxtoledo/govpn/libs/logger"
 	"github.com/pion/webrtc/v4"
 )
 
+// ErrDataChannelNotOpen is returned when sending before the data channel opened or after it closed
+var ErrDataChannelNotOpen = errors.New("data channel is not open")
+
+// Labels of the data channels each side creates: a reliable, ordered one for control
+// traffic and streams, one without retransmissions or ordering for UDP packets and a
+// reliable one for file transfers, so a large file does not hold back the rest
+const (
+	reliableChannelLabel   = "data"
+	unreliableChannelLabel = "unreliable"
+	fileChannelLabel       = "files"
+)
+
 // WebRTCManager handles the WebRTC connection and data channel
 type WebRTCManager struct {
 	peerConnection *webrtc.PeerConnection
 	dataChannel    *webrtc.DataChannel
+	peerChannel    atomic.Pointer[webrtc.DataChannel] // The channel the peer created
+
+	unreliableChannel     *webrtc.DataChannel
+	peerUnreliableChannel atomic.Pointer[webrtc.DataChannel]
+
+	fileChannel     *webrtc.DataChannel
+	peerFileChannel atomic.Pointer[webrtc.DataChannel]
+
+	// Bytes carried by the data channels, messages and packets alike
+	bytesSent     atomic.Uint64
+	bytesReceived atomic.Uint64
 
 	// Callbacks
-	onConnectionStateChange func(webrtc.PeerConnectionState)
+	onConnectionStateChange    func(webrtc.PeerConnectionState)
 	onICEConnectionStateChange func(webrtc.ICEConnectionState)
-	onDataChannelMessage func([]byte)
-	onDataChannelOpen func()
+	onDataChannelMessage       func([]byte)
+	onDataChannelOpen          func()
+	onPacket                   func([]byte)
 }
 
-// NewWebRTCManager creates a new WebRTCManager
-func NewWebRTCManager() (*WebRTCManager, error) {
+// NewWebRTCManager creates a new WebRTCManager using the ICE servers of opts
+func NewWebRTCManager(opts Options) (*WebRTCManager, error) {
 	// Create a new RTCPeerConnection
-	peerConnection, err := webrtc.NewPeerConnection(webrtc.Configuration{
-		ICEServers: []webrtc.ICEServer{
-			{
-				URLs: []string{"stun:stun.l.google.com:19302"},
-			},
-		},
-	})
+	peerConnection, err := webrtc.NewPeerConnection(opts.configuration())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create peer connection: %w", err)
 	}
@@ -50,6 +70,21 @@ func NewWebRTCManager() (*WebRTCManager, error) {
 		}
 	})
 
+	// Each side sends on the channel it created; the one opened by the peer is read, and
+	// only written to by SendPacket while our own channel is not open yet
+	w.peerConnection.OnDataChannel(func(dc *webrtc.DataChannel) {
+		logger.Debug("Peer opened data channel", "label", dc.Label())
+		dc.OnMessage(w.handleMessage)
+		switch dc.Label() {
+		case unreliableChannelLabel:
+			w.peerUnreliableChannel.Store(dc)
+		case fileChannelLabel:
+			w.peerFileChannel.Store(dc)
+		default:
+			w.peerChannel.Store(dc)
+		}
+	})
+
 	return w, nil
 }
 
@@ -90,6 +125,11 @@ func (w *WebRTCManager) CreateOffer(iceRestart bool) (*webrtc.SessionDescription
 	return &offer, nil
 }
 
+// ICEConnectionState returns the current ICE connection state
+func (w *WebRTCManager) ICEConnectionState() webrtc.ICEConnectionState {
+	return w.peerConnection.ICEConnectionState()
+}
+
 // HandleOfferAndCreateAnswer handles an incoming SDP offer and creates an SDP answer
 func (w *WebRTCManager) HandleOfferAndCreateAnswer(offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
 	if err := w.peerConnection.SetRemoteDescription(offer); err != nil {
@@ -131,6 +171,16 @@ func (w *WebRTCManager) AddICECandidate(candidate webrtc.ICECandidateInit) error
 
 // Close closes the WebRTC connection
 func (w *WebRTCManager) Close() error {
+	if w.fileChannel != nil {
+		if err := w.fileChannel.Close(); err != nil {
+			return err
+		}
+	}
+	if w.unreliableChannel != nil {
+		if err := w.unreliableChannel.Close(); err != nil {
+			return err
+		}
+	}
 	if w.dataChannel != nil {
 		if err := w.dataChannel.Close(); err != nil {
 			return err
@@ -144,41 +194,164 @@ func (w *WebRTCManager) Close() error {
 	return nil
 }
 
-// CreateDataChannel creates a new data channel and sets up the event handlers
+// CreateDataChannel creates the reliable, unreliable and file data channels and sets up the event handlers
 func (w *WebRTCManager) CreateDataChannel() error {
 	// Create a new data channel
-	dataChannel, err := w.peerConnection.CreateDataChannel("data", nil)
+	dataChannel, err := w.peerConnection.CreateDataChannel(reliableChannelLabel, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create data channel: %w", err)
 	}
 
+	ordered := false
+	maxRetransmits := uint16(0)
+	unreliableChannel, err := w.peerConnection.CreateDataChannel(unreliableChannelLabel, &webrtc.DataChannelInit{
+		Ordered:        &ordered,
+		MaxRetransmits: &maxRetransmits,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create unreliable data channel: %w", err)
+	}
+	unreliableChannel.OnMessage(w.handleMessage)
+	w.unreliableChannel = unreliableChannel
+
+	fileChannel, err := w.peerConnection.CreateDataChannel(fileChannelLabel, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create file data channel: %w", err)
+	}
+	fileChannel.OnMessage(w.handleMessage)
+	w.fileChannel = fileChannel
+
 	w.dataChannel = dataChannel
 
 	// Set up the event handlers
 	w.dataChannel.OnOpen(func() {
-		log.Println("Data channel opened")
+		logger.Debug("Data channel opened")
 		if w.onDataChannelOpen != nil {
 			w.onDataChannelOpen()
 		}
 	})
 
-	w.dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
-		log.Printf("Message from data channel: %s\n", string(msg.Data))
-		if w.onDataChannelMessage != nil {
-			w.onDataChannelMessage(msg.Data)
-		}
-	})
+	w.dataChannel.OnMessage(w.handleMessage)
 
 	return nil
 }
 
-// SendMessage sends a message over the data channel
+// handleMessage passes binary messages to the packet callback and text ones to the message callback
+func (w *WebRTCManager) handleMessage(msg webrtc.DataChannelMessage) {
+	w.bytesReceived.Add(uint64(len(msg.Data)))
+
+	if !msg.IsString && w.onPacket != nil {
+		w.onPacket(msg.Data)
+		return
+	}
+
+	logger.Debug("Message from data channel", "message", string(msg.Data))
+	if w.onDataChannelMessage != nil {
+		w.onDataChannelMessage(msg.Data)
+	}
+}
+
+// SendMessage sends a text message over the data channel, or over the peer's channel
+// while ours has not opened yet
 func (w *WebRTCManager) SendMessage(message string) error {
-	if w.dataChannel == nil || w.dataChannel.ReadyState() != webrtc.DataChannelStateOpen {
-		return fmt.Errorf("data channel is not open")
+	dc := w.dataChannel
+	if dc == nil || dc.ReadyState() != webrtc.DataChannelStateOpen {
+		dc = w.peerChannel.Load()
+	}
+	if dc == nil || dc.ReadyState() != webrtc.DataChannelStateOpen {
+		return ErrDataChannelNotOpen
 	}
 
-	return w.dataChannel.SendText(message)
+	if err := dc.SendText(message); err != nil {
+		return err
+	}
+	w.bytesSent.Add(uint64(len(message)))
+	return nil
+}
+
+// SetOnPacket sets the callback for binary messages, which carry tunneled packets
+func (w *WebRTCManager) SetOnPacket(callback func([]byte)) {
+	w.onPacket = callback
+}
+
+// SendPacket sends a binary message over the data channel, or over the peer's channel
+// when ours has not opened yet, so a handshake can be answered as soon as it arrives
+func (w *WebRTCManager) SendPacket(frame []byte) error {
+	dc := w.dataChannel
+	if dc == nil || dc.ReadyState() != webrtc.DataChannelStateOpen {
+		dc = w.peerChannel.Load()
+	}
+	if dc == nil || dc.ReadyState() != webrtc.DataChannelStateOpen {
+		return ErrDataChannelNotOpen
+	}
+
+	if err := dc.Send(frame); err != nil {
+		return err
+	}
+	w.bytesSent.Add(uint64(len(frame)))
+	return nil
+}
+
+// SendUnreliable sends a binary message that may be lost or reordered, on the unreliable
+// channel of either side. Until one of them opens it goes over SendPacket.
+func (w *WebRTCManager) SendUnreliable(frame []byte) error {
+	dc := w.unreliableChannel
+	if dc == nil || dc.ReadyState() != webrtc.DataChannelStateOpen {
+		dc = w.peerUnreliableChannel.Load()
+	}
+	if dc == nil || dc.ReadyState() != webrtc.DataChannelStateOpen {
+		return w.SendPacket(frame)
+	}
+
+	if err := dc.Send(frame); err != nil {
+		return err
+	}
+	w.bytesSent.Add(uint64(len(frame)))
+	return nil
+}
+
+// SendFile sends a binary message of a file transfer on the file channel of either side.
+// Until one of them opens it goes over SendPacket.
+func (w *WebRTCManager) SendFile(frame []byte) error {
+	dc := w.openFileChannel()
+	if dc == nil {
+		return w.SendPacket(frame)
+	}
+
+	if err := dc.Send(frame); err != nil {
+		return err
+	}
+	w.bytesSent.Add(uint64(len(frame)))
+	return nil
+}
+
+// FileBufferedAmount returns the bytes queued on the channel SendFile writes to, which
+// file transfers keep low so they do not fill the memory faster than the link drains it
+func (w *WebRTCManager) FileBufferedAmount() uint64 {
+	dc := w.openFileChannel()
+	if dc == nil {
+		dc = w.dataChannel
+	}
+	if dc == nil {
+		return 0
+	}
+	return dc.BufferedAmount()
+}
+
+// openFileChannel returns the open file channel of either side, or nil
+func (w *WebRTCManager) openFileChannel() *webrtc.DataChannel {
+	if dc := w.fileChannel; dc != nil && dc.ReadyState() == webrtc.DataChannelStateOpen {
+		return dc
+	}
+	if dc := w.peerFileChannel.Load(); dc != nil && dc.ReadyState() == webrtc.DataChannelStateOpen {
+		return dc
+	}
+	return nil
+}
+
+// Traffic returns the bytes sent and received over the data channels so far
+func (w *WebRTCManager) Traffic() (sent, received uint64) {
+	return w.bytesSent.Load(), w.bytesReceived.Load()
 }
 
 // OnMessageReceived is a callback for when a message is received
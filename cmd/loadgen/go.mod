@@ -0,0 +1,19 @@
+module github.com/itxtoledo/govpn/cmd/loadgen
+
+go 1.22.0
+
+require (
+	github.com/itxtoledo/govpn/libs/signaling/client v0.0.0
+	github.com/itxtoledo/govpn/libs/signaling/models v0.0.0
+)
+
+require (
+	github.com/gorilla/websocket v1.5.3 // indirect
+	github.com/itxtoledo/govpn/libs/utils v0.0.0 // indirect
+)
+
+replace (
+	github.com/itxtoledo/govpn/libs/signaling/client v0.0.0 => ../../libs/signaling/client
+	github.com/itxtoledo/govpn/libs/signaling/models v0.0.0 => ../../libs/signaling/models
+	github.com/itxtoledo/govpn/libs/utils v0.0.0 => ../../libs/utils
+)
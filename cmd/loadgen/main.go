@@ -0,0 +1,209 @@
+// Command loadgen runs synthetic signaling clients against a GoVPN server and
+// reports request latency percentiles, for sizing MaxClientsPerNetwork and buffers.
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/signaling/client"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// loadgenPIN is the PIN of every network created by the generator
+const loadgenPIN = "0000"
+
+// options are the command-line settings of a run
+type options struct {
+	server       string
+	clients      int
+	networkSize  int
+	duration     time.Duration
+	pingInterval time.Duration
+	rampUp       time.Duration
+	verbose      bool
+}
+
+func parseOptions() options {
+	var o options
+	flag.StringVar(&o.server, "server", "ws://localhost:8080/ws", "WebSocket address of the signaling server")
+	flag.IntVar(&o.clients, "clients", 50, "number of simulated clients")
+	flag.IntVar(&o.networkSize, "network-size", 10, "clients per network; the first one creates it, the others join")
+	flag.DurationVar(&o.duration, "duration", 30*time.Second, "how long each client keeps pinging after joining")
+	flag.DurationVar(&o.pingInterval, "ping-interval", time.Second, "delay between pings of a client")
+	flag.DurationVar(&o.rampUp, "ramp-up", 5*time.Second, "time over which client connections are spread")
+	flag.BoolVar(&o.verbose, "v", false, "show the signaling client log")
+	flag.Parse()
+	return o
+}
+
+func main() {
+	o := parseOptions()
+	if o.clients <= 0 || o.networkSize <= 0 || o.pingInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "clients, network-size and ping-interval must be positive")
+		os.Exit(2)
+	}
+
+	// The client library logs every message, which would drown the report
+	if !o.verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	fmt.Printf("Running %d clients in networks of %d against %s for %s\n",
+		o.clients, o.networkSize, o.server, o.duration)
+	fmt.Println("Raise MAX_CONNS_PER_IP on the server (or set it to 0) when running more clients than it allows from one address.")
+
+	recorder := newRecorder()
+	start := time.Now()
+	run(o, recorder)
+	elapsed := time.Since(start)
+
+	recorder.Report(os.Stdout, elapsed)
+}
+
+// run starts every client and waits for all of them to finish.
+// Clients are grouped into networks: the first of each group creates the network
+// and hands its ID to the rest of the group, who join it.
+func run(o options, recorder *recorder) {
+	groups := make([]*groupNetwork, (o.clients+o.networkSize-1)/o.networkSize)
+	for i := range groups {
+		groups[i] = &groupNetwork{ready: make(chan struct{})}
+		size := min(o.networkSize, o.clients-i*o.networkSize)
+		groups[i].members.Add(size - 1)
+	}
+
+	var stagger time.Duration
+	if o.clients > 1 {
+		stagger = o.rampUp / time.Duration(o.clients)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < o.clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			time.Sleep(stagger * time.Duration(i))
+
+			sim := &simulatedClient{
+				index:    i,
+				options:  o,
+				recorder: recorder,
+				creator:  i%o.networkSize == 0,
+			}
+			sim.run(groups[i/o.networkSize])
+		}(i)
+	}
+	wg.Wait()
+}
+
+// groupNetwork is the network shared by a group of clients.
+// id is set, or left empty on failure, before ready is closed.
+// The creator leaves last, since its leaving deletes the network.
+type groupNetwork struct {
+	id      string
+	ready   chan struct{}
+	members sync.WaitGroup // Members that have not left yet
+}
+
+// simulatedClient is one generated client going through connect, create or join, ping and leave
+type simulatedClient struct {
+	index    int
+	options  options
+	recorder *recorder
+	creator  bool
+}
+
+func (c *simulatedClient) run(group *groupNetwork) {
+	if !c.creator {
+		defer group.members.Done()
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		c.recorder.Fail("connect", err)
+		c.abandon(group)
+		return
+	}
+
+	sc := client.NewSignalingClient(base64.StdEncoding.EncodeToString(publicKey), nil)
+	sc.SetPrivateKey(privateKey)
+
+	if err := c.recorder.Time("connect", func() error { return sc.Connect(c.options.server) }); err != nil {
+		c.abandon(group)
+		return
+	}
+	defer sc.Disconnect()
+
+	id, err := c.enterNetwork(sc, group)
+	if err != nil {
+		return
+	}
+
+	deadline := time.Now().Add(c.options.duration)
+	ticker := time.NewTicker(c.options.pingInterval)
+	defer ticker.Stop()
+	for time.Now().Before(deadline) {
+		c.recorder.Time("ping", func() error {
+			_, err := sc.SendMessage(smodels.TypePing, map[string]interface{}{
+				"timestamp": time.Now().UnixNano(),
+			})
+			return err
+		})
+		<-ticker.C
+	}
+
+	if c.creator {
+		group.members.Wait()
+	}
+	c.recorder.Time("leave", func() error {
+		_, err := sc.LeaveNetwork(id)
+		return err
+	})
+}
+
+// enterNetwork creates the group's network or joins the one its creator made
+func (c *simulatedClient) enterNetwork(sc *client.SignalingClient, group *groupNetwork) (string, error) {
+	computerName := fmt.Sprintf("loadgen-%d", c.index)
+
+	if c.creator {
+		defer close(group.ready)
+
+		var resp *smodels.CreateNetworkResponse
+		err := c.recorder.Time("create", func() (err error) {
+			resp, err = sc.CreateNetwork(fmt.Sprintf("loadgen %d", c.index), loadgenPIN, computerName)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		group.id = resp.NetworkID
+		return group.id, nil
+	}
+
+	<-group.ready
+	if group.id == "" {
+		err := errors.New("network of the group was not created")
+		c.recorder.Fail("join", err)
+		return "", err
+	}
+	return group.id, c.recorder.Time("join", func() error {
+		_, err := sc.JoinNetwork(group.id, loadgenPIN, computerName)
+		return err
+	})
+}
+
+// abandon unblocks the group when its creator could not get as far as creating the network
+func (c *simulatedClient) abandon(group *groupNetwork) {
+	if c.creator {
+		close(group.ready)
+	}
+}
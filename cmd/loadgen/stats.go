@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// maxErrorSamples is how many distinct error messages are kept per operation
+const maxErrorSamples = 5
+
+// operationStats holds every latency measured for one kind of request
+type operationStats struct {
+	latencies []time.Duration
+	errors    int
+	samples   map[string]int // Error message -> occurrences
+}
+
+// recorder collects latencies and errors from every simulated client
+type recorder struct {
+	mu         sync.Mutex
+	operations map[string]*operationStats
+	order      []string // Operations in the order they were first seen
+}
+
+func newRecorder() *recorder {
+	return &recorder{operations: make(map[string]*operationStats)}
+}
+
+// Time runs fn, recording its latency under op on success and an error otherwise
+func (r *recorder) Time(op string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	elapsed := time.Since(start)
+
+	if err != nil {
+		r.Fail(op, err)
+		return err
+	}
+
+	r.mu.Lock()
+	stats := r.operation(op)
+	stats.latencies = append(stats.latencies, elapsed)
+	r.mu.Unlock()
+	return nil
+}
+
+// Fail records a failed request of op
+func (r *recorder) Fail(op string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.operation(op)
+	stats.errors++
+	if _, seen := stats.samples[err.Error()]; seen || len(stats.samples) < maxErrorSamples {
+		stats.samples[err.Error()]++
+	}
+}
+
+// operation returns the stats of op, creating them if needed. Callers must hold the lock.
+func (r *recorder) operation(op string) *operationStats {
+	stats, ok := r.operations[op]
+	if !ok {
+		stats = &operationStats{samples: make(map[string]int)}
+		r.operations[op] = stats
+		r.order = append(r.order, op)
+	}
+	return stats
+}
+
+// Report writes a latency table per operation followed by the errors seen
+func (r *recorder) Report(w io.Writer, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fmt.Fprintf(w, "\nCompleted in %s\n\n", elapsed.Round(time.Millisecond))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\tok\terrors\treq/s\tp50\tp90\tp99\tmax\t")
+	for _, op := range r.order {
+		stats := r.operations[op]
+		sorted := append([]time.Duration(nil), stats.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		var max time.Duration
+		if len(sorted) > 0 {
+			max = sorted[len(sorted)-1]
+		}
+		rate := float64(len(sorted)) / elapsed.Seconds()
+
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n", op, len(sorted), stats.errors, rate,
+			formatLatency(percentile(sorted, 0.50)),
+			formatLatency(percentile(sorted, 0.90)),
+			formatLatency(percentile(sorted, 0.99)),
+			formatLatency(max))
+	}
+	tw.Flush()
+
+	for _, op := range r.order {
+		for msg, count := range r.operations[op].samples {
+			fmt.Fprintf(w, "%s error (%dx): %s\n", op, count, msg)
+		}
+	}
+}
+
+// percentile returns the value at percentile p (0..1) of an already sorted list
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+// formatLatency prints a latency in milliseconds with two decimals
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+	"github.com/itxtoledo/govpn/libs/signaling/proto/adminpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The gRPC admin service listens on its own address, ADMIN_GRPC_LISTEN, so it can be kept
+// on localhost or a private interface while the WebSocket endpoint is public. It is off
+// unless that address is set, and every call must carry ADMIN_TOKEN as a bearer token in
+// the authorization metadata, the same token as the HTTP admin endpoints.
+
+// parseAdminGRPCListen parses ADMIN_GRPC_LISTEN, a single address in LISTEN syntax
+func parseAdminGRPCListen(address string) (listenerSpec, error) {
+	if strings.Contains(address, ",") {
+		return listenerSpec{}, errors.New("takes a single address")
+	}
+	return parseListenerSpec(strings.TrimSpace(address))
+}
+
+// startAdminGRPC opens the admin listener and serves AdminService on it
+func (s *WebSocketServer) startAdminGRPC(address string) error {
+	spec, err := parseAdminGRPCListen(address)
+	if err != nil {
+		return fmt.Errorf("invalid admin gRPC listen address: %w", err)
+	}
+
+	// gRPC negotiates TLS itself, so the listener stays plain
+	var opts []grpc.ServerOption
+	if spec.certFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(spec.certFile, spec.keyFile)
+		if err != nil {
+			return fmt.Errorf("loading TLS certificate of %s: %w", spec, err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	plain := spec
+	plain.certFile, plain.keyFile = "", ""
+	ln, err := plain.listen()
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", spec, err)
+	}
+
+	s.adminGRPC = s.serveAdminGRPC(ln, opts...)
+	logger.Info("Admin gRPC service listening", "address", spec.String())
+	return nil
+}
+
+// serveAdminGRPC serves AdminService on ln in the background
+func (s *WebSocketServer) serveAdminGRPC(ln net.Listener, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(s.requireAdminGRPCToken))
+	server := grpc.NewServer(opts...)
+	adminpb.RegisterAdminServiceServer(server, &adminGRPCServer{admin: NewAdminService(s)})
+
+	go func() {
+		if err := server.Serve(ln); err != nil {
+			logger.Error("Admin gRPC server error", "address", ln.Addr().String(), "error", err)
+		}
+	}()
+	return server
+}
+
+// stopAdminGRPC lets calls in progress finish until ctx expires, then closes the rest
+func stopAdminGRPC(ctx context.Context, server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+// requireAdminGRPCToken only lets calls carrying the admin bearer token through
+func (s *WebSocketServer) requireAdminGRPCToken(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	s.mu.RLock()
+	token := s.config.AdminToken
+	s.mu.RUnlock()
+
+	// ADMIN_TOKEN is required to start the service, but a reload can clear it
+	if token == "" {
+		return nil, status.Error(codes.PermissionDenied, "admin token not configured")
+	}
+
+	var given string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			if bearer, ok := strings.CutPrefix(value, "Bearer "); ok {
+				given = bearer
+				break
+			}
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		logger.Warn("Rejected admin gRPC call", "method", info.FullMethod)
+		return nil, status.Error(codes.Unauthenticated, "invalid admin token")
+	}
+
+	return handler(ctx, req)
+}
+
+// adminGRPCServer converts between the generated messages and AdminService
+type adminGRPCServer struct {
+	adminpb.UnimplementedAdminServiceServer
+	admin *AdminService
+}
+
+func (g *adminGRPCServer) ListNetworks(ctx context.Context, req *adminpb.ListNetworksRequest) (*adminpb.ListNetworksResponse, error) {
+	networks, err := g.admin.ListNetworks(ctx)
+	if err != nil {
+		return nil, adminGRPCError(err)
+	}
+
+	resp := &adminpb.ListNetworksResponse{Networks: make([]*adminpb.Network, 0, len(networks))}
+	for _, network := range networks {
+		resp.Networks = append(resp.Networks, toNetworkMessage(network))
+	}
+	return resp, nil
+}
+
+func (g *adminGRPCServer) GetNetwork(ctx context.Context, req *adminpb.GetNetworkRequest) (*adminpb.GetNetworkResponse, error) {
+	if req.GetNetworkId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "network_id is required")
+	}
+
+	network, members, err := g.admin.GetNetwork(ctx, req.GetNetworkId())
+	if err != nil {
+		return nil, adminGRPCError(err)
+	}
+	return &adminpb.GetNetworkResponse{Network: toNetworkMessage(network), Members: toMemberMessages(members)}, nil
+}
+
+func (g *adminGRPCServer) ListMembers(ctx context.Context, req *adminpb.ListMembersRequest) (*adminpb.ListMembersResponse, error) {
+	if req.GetNetworkId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "network_id is required")
+	}
+
+	members, err := g.admin.ListMembers(ctx, req.GetNetworkId())
+	if err != nil {
+		return nil, adminGRPCError(err)
+	}
+	return &adminpb.ListMembersResponse{Members: toMemberMessages(members)}, nil
+}
+
+func (g *adminGRPCServer) RenameNetwork(ctx context.Context, req *adminpb.RenameNetworkRequest) (*adminpb.RenameNetworkResponse, error) {
+	if req.GetNetworkId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "network_id is required")
+	}
+
+	network, err := g.admin.RenameNetwork(ctx, req.GetNetworkId(), req.GetNetworkName())
+	if err != nil {
+		return nil, adminGRPCError(err)
+	}
+	return &adminpb.RenameNetworkResponse{Network: toNetworkMessage(network)}, nil
+}
+
+func (g *adminGRPCServer) DeleteNetwork(ctx context.Context, req *adminpb.DeleteNetworkRequest) (*adminpb.DeleteNetworkResponse, error) {
+	if req.GetNetworkId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "network_id is required")
+	}
+
+	if err := g.admin.DeleteNetwork(ctx, req.GetNetworkId()); err != nil {
+		return nil, adminGRPCError(err)
+	}
+	return &adminpb.DeleteNetworkResponse{NetworkId: req.GetNetworkId()}, nil
+}
+
+func (g *adminGRPCServer) KickComputer(ctx context.Context, req *adminpb.KickComputerRequest) (*adminpb.KickComputerResponse, error) {
+	if req.GetNetworkId() == "" || req.GetPublicKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "network_id and public_key are required")
+	}
+
+	wasOnline, err := g.admin.KickComputer(ctx, req.GetNetworkId(), req.GetPublicKey())
+	if err != nil {
+		return nil, adminGRPCError(err)
+	}
+	return &adminpb.KickComputerResponse{NetworkId: req.GetNetworkId(), PublicKey: req.GetPublicKey(), WasOnline: wasOnline}, nil
+}
+
+func (g *adminGRPCServer) SetMaintenanceMode(ctx context.Context, req *adminpb.SetMaintenanceModeRequest) (*adminpb.SetMaintenanceModeResponse, error) {
+	enabled, err := g.admin.SetMaintenanceMode(ctx, req.GetEnabled())
+	if err != nil {
+		return nil, adminGRPCError(err)
+	}
+	return &adminpb.SetMaintenanceModeResponse{Enabled: enabled}, nil
+}
+
+func (g *adminGRPCServer) BroadcastNotice(ctx context.Context, req *adminpb.BroadcastNoticeRequest) (*adminpb.BroadcastNoticeResponse, error) {
+	noticeID, delivered, err := g.admin.BroadcastNotice(ctx, req.GetMessage(), smodels.NoticeLevel(req.GetLevel()), req.GetNetworkId())
+	if err != nil {
+		return nil, adminGRPCError(err)
+	}
+	return &adminpb.BroadcastNoticeResponse{NoticeId: noticeID, Delivered: int32(delivered)}, nil
+}
+
+// adminGRPCError maps an AdminService error to a gRPC status. Unexpected errors are logged
+// and reported without their details.
+func adminGRPCError(err error) error {
+	switch {
+	case errors.Is(err, errAdminNetworkNotFound), errors.Is(err, errNoticeNetworkNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, errAdminNameRequired), errors.Is(err, errInvalidNotice):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, errAdminKickOwner):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		logger.Error("Admin gRPC call failed", "error", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// toNetworkMessage converts the admin view of a network into its message
+func toNetworkMessage(network AdminNetwork) *adminpb.Network {
+	return &adminpb.Network{
+		Id:             network.ID,
+		Name:           network.Name,
+		OwnerPublicKey: network.OwnerPublicKey,
+		CreatedAt:      toTimestamp(network.CreatedAt),
+		LastActive:     toTimestamp(network.LastActive),
+		OnlineCount:    int32(network.OnlineCount),
+	}
+}
+
+// toMemberMessages converts the admin view of members into their messages
+func toMemberMessages(members []AdminMember) []*adminpb.Member {
+	messages := make([]*adminpb.Member, 0, len(members))
+	for _, member := range members {
+		messages = append(messages, &adminpb.Member{
+			PublicKey:     member.PublicKey,
+			ComputerName:  member.ComputerName,
+			PeerIp:        member.PeerIP,
+			IsOnline:      member.IsOnline,
+			JoinedAt:      toTimestamp(member.JoinedAt),
+			LastConnected: toTimestamp(member.LastConnected),
+		})
+	}
+	return messages
+}
+
+// toTimestamp converts a time, leaving the field unset for the zero time
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
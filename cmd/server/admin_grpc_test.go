@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/itxtoledo/govpn/libs/signaling/proto/adminpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testAdminToken = "test-admin-token"
+
+// startAdminGRPCClient serves the admin service of server on a local port and returns a
+// client for it, closed when the test ends
+func startAdminGRPCClient(t *testing.T, server *inProcessServer) adminpb.AdminServiceClient {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	grpcServer := server.Server.serveAdminGRPC(ln)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial admin service: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return adminpb.NewAdminServiceClient(conn)
+}
+
+// withAdminToken adds token to the metadata of the calls made with the returned context
+func withAdminToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAdminGRPCRequiresToken(t *testing.T) {
+	server := startTestServer(t, func(cfg *Config) { cfg.AdminToken = testAdminToken })
+	admin := startAdminGRPCClient(t, server)
+
+	for name, ctx := range map[string]context.Context{
+		"no token":    context.Background(),
+		"wrong token": withAdminToken("wrong"),
+	} {
+		_, err := admin.ListNetworks(ctx, &adminpb.ListNetworksRequest{})
+		if code := status.Code(err); code != codes.Unauthenticated {
+			t.Errorf("%s: code = %s, want %s", name, code, codes.Unauthenticated)
+		}
+	}
+}
+
+func TestAdminGRPCManagesNetworks(t *testing.T) {
+	server := startTestServer(t, func(cfg *Config) { cfg.AdminToken = testAdminToken })
+	admin := startAdminGRPCClient(t, server)
+	ctx := withAdminToken(testAdminToken)
+
+	owner := dial(t, server)
+	member := dial(t, server)
+	networkID := createNetwork(t, owner, "office")
+	if _, err := member.JoinNetwork(networkID, testPIN, "laptop"); err != nil {
+		t.Fatalf("join network: %v", err)
+	}
+
+	list, err := admin.ListNetworks(ctx, &adminpb.ListNetworksRequest{})
+	if err != nil {
+		t.Fatalf("list networks: %v", err)
+	}
+	if len(list.Networks) != 1 || list.Networks[0].Id != networkID || list.Networks[0].OnlineCount != 2 {
+		t.Errorf("networks = %v, want %s with 2 online", list.Networks, networkID)
+	}
+
+	got, err := admin.GetNetwork(ctx, &adminpb.GetNetworkRequest{NetworkId: networkID})
+	if err != nil {
+		t.Fatalf("get network: %v", err)
+	}
+	if len(got.Members) != 2 {
+		t.Errorf("members = %d, want 2", len(got.Members))
+	}
+
+	_, err = admin.KickComputer(ctx, &adminpb.KickComputerRequest{NetworkId: networkID, PublicKey: owner.PublicKey})
+	if code := status.Code(err); code != codes.FailedPrecondition {
+		t.Errorf("kicking the owner: code = %s, want %s", code, codes.FailedPrecondition)
+	}
+	kicked, err := admin.KickComputer(ctx, &adminpb.KickComputerRequest{NetworkId: networkID, PublicKey: member.PublicKey})
+	if err != nil {
+		t.Fatalf("kick: %v", err)
+	}
+	if !kicked.WasOnline {
+		t.Error("kicked member reported offline")
+	}
+
+	if _, err := admin.DeleteNetwork(ctx, &adminpb.DeleteNetworkRequest{NetworkId: networkID}); err != nil {
+		t.Fatalf("delete network: %v", err)
+	}
+	_, err = admin.GetNetwork(ctx, &adminpb.GetNetworkRequest{NetworkId: networkID})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("deleted network: code = %s, want %s", code, codes.NotFound)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// adminBroadcastRequest is the body of POST /admin/broadcast
+type adminBroadcastRequest struct {
+	Message   string              `json:"message"`
+	Level     smodels.NoticeLevel `json:"level"`
+	NetworkID string              `json:"network_id"`
+}
+
+// adminBroadcastResponse reports which notice was sent and to how many clients
+type adminBroadcastResponse struct {
+	NoticeID  string `json:"notice_id"`
+	Delivered int    `json:"delivered"`
+}
+
+// registerAdminRoutes adds the operator endpoints, which require ADMIN_TOKEN
+func (s *WebSocketServer) registerAdminRoutes(mux *http.ServeMux) {
+	admin := NewAdminService(s)
+	mux.HandleFunc("POST /admin/broadcast", s.requireAdminToken(func(w http.ResponseWriter, r *http.Request) {
+		s.handleAdminBroadcast(admin, w, r)
+	}))
+}
+
+// requireAdminToken only lets requests carrying the admin bearer token through.
+// Without ADMIN_TOKEN the endpoints do not exist.
+func (s *WebSocketServer) requireAdminToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		token := s.config.AdminToken
+		s.mu.RUnlock()
+
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			logger.Warn("Rejected admin request", "path", r.URL.Path, "remoteAddr", r.RemoteAddr)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid admin token"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// handleAdminBroadcast sends an operator notice to every connected client or to one network
+func (s *WebSocketServer) handleAdminBroadcast(admin *AdminService, w http.ResponseWriter, r *http.Request) {
+	var req adminBroadcastRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16*1024)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+
+	noticeID, delivered, err := admin.BroadcastNotice(r.Context(), req.Message, req.Level, req.NetworkID)
+	switch {
+	case errors.Is(err, errInvalidNotice):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, errNoticeNetworkNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("Error broadcasting notice", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to broadcast notice"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, adminBroadcastResponse{NoticeID: noticeID, Delivered: delivered})
+}
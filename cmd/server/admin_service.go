@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+	"github.com/itxtoledo/govpn/libs/utils"
+)
+
+var (
+	errAdminNetworkNotFound = errors.New("network not found")
+	errAdminNameRequired    = errors.New("network name is required")
+	errAdminKickOwner       = errors.New("cannot kick the network owner")
+)
+
+// AdminNetwork is the admin view of a network (mirrors govpn.admin.v1.Network)
+type AdminNetwork struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	OwnerPublicKey string    `json:"owner_public_key"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastActive     time.Time `json:"last_active"`
+	OnlineCount    int       `json:"online_count"`
+}
+
+// AdminMember is the admin view of a network member (mirrors govpn.admin.v1.Member)
+type AdminMember struct {
+	PublicKey     string    `json:"public_key"`
+	ComputerName  string    `json:"computer_name"`
+	PeerIP        string    `json:"peer_ip"`
+	IsOnline      bool      `json:"is_online"`
+	JoinedAt      time.Time `json:"joined_at"`
+	LastConnected time.Time `json:"last_connected"`
+}
+
+// AdminService implements the operations of the AdminService defined in
+// libs/signaling/proto/admin.proto. It is transport agnostic: the gRPC server in
+// admin_grpc.go and the HTTP admin endpoints convert messages and delegate to it.
+type AdminService struct {
+	server *WebSocketServer
+}
+
+// NewAdminService creates a new admin service bound to a WebSocket server
+func NewAdminService(server *WebSocketServer) *AdminService {
+	return &AdminService{server: server}
+}
+
+// ListNetworks returns every network stored by the server
+func (a *AdminService) ListNetworks(ctx context.Context) ([]AdminNetwork, error) {
+	networks, err := a.server.store.ListNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	a.server.mu.RLock()
+	defer a.server.mu.RUnlock()
+
+	result := make([]AdminNetwork, 0, len(networks))
+	for _, network := range networks {
+		result = append(result, a.toAdminNetwork(network))
+	}
+	return result, nil
+}
+
+// GetNetwork returns a single network and its members
+func (a *AdminService) GetNetwork(ctx context.Context, networkID string) (AdminNetwork, []AdminMember, error) {
+	network, err := a.server.store.GetNetwork(networkID)
+	if err != nil {
+		return AdminNetwork{}, nil, a.networkError(networkID, err)
+	}
+
+	members, err := a.ListMembers(ctx, networkID)
+	if err != nil {
+		return AdminNetwork{}, nil, err
+	}
+
+	a.server.mu.RLock()
+	defer a.server.mu.RUnlock()
+	return a.toAdminNetwork(network), members, nil
+}
+
+// ListMembers returns the computers that joined a network
+func (a *AdminService) ListMembers(ctx context.Context, networkID string) ([]AdminMember, error) {
+	exists, err := a.server.store.NetworkExists(networkID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errAdminNetworkNotFound
+	}
+
+	computers, err := a.server.store.GetComputersInNetwork(networkID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.server.mu.RLock()
+	defer a.server.mu.RUnlock()
+
+	members := make([]AdminMember, 0, len(computers))
+	for _, computer := range computers {
+		members = append(members, AdminMember{
+			PublicKey:     computer.PublicKey,
+			ComputerName:  computer.ComputerName,
+			PeerIP:        computer.PeerIP,
+			IsOnline:      a.server.isComputerOnline(networkID, computer.PublicKey),
+			JoinedAt:      computer.JoinedAt,
+			LastConnected: computer.LastConnected,
+		})
+	}
+	return members, nil
+}
+
+// RenameNetwork changes the name of a network and notifies connected members
+func (a *AdminService) RenameNetwork(ctx context.Context, networkID, networkName string) (AdminNetwork, error) {
+	if networkName == "" {
+		return AdminNetwork{}, errAdminNameRequired
+	}
+
+	s := a.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	network, err := s.store.GetNetwork(networkID)
+	if err != nil {
+		return AdminNetwork{}, a.networkError(networkID, err)
+	}
+
+	if err := s.store.UpdateNetworkName(networkID, networkName); err != nil {
+		return AdminNetwork{}, err
+	}
+	network.Name = networkName
+
+	renamePayload := map[string]interface{}{
+		"network_id":   networkID,
+		"network_name": networkName,
+	}
+	s.broadcastNetworkEvent(networkID, smodels.TypeNetworkRenamed, renamePayload, nil)
+
+	logger.Info("Network renamed by admin", "networkID", networkID, "newName", networkName)
+	return a.toAdminNetwork(network), nil
+}
+
+// DeleteNetwork removes a network and notifies connected members
+func (a *AdminService) DeleteNetwork(ctx context.Context, networkID string) error {
+	s := a.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.store.GetNetwork(networkID); err != nil {
+		return a.networkError(networkID, err)
+	}
+
+	if err := s.deleteNetworkAndNotify(networkID, nil); err != nil {
+		return err
+	}
+
+	logger.Info("Network deleted by admin", "networkID", networkID)
+	return nil
+}
+
+// KickComputer removes a computer from a network and closes its connection.
+// It reports whether the computer was connected when it was kicked.
+func (a *AdminService) KickComputer(ctx context.Context, networkID, publicKey string) (bool, error) {
+	s := a.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	network, err := s.store.GetNetwork(networkID)
+	if err != nil {
+		return false, a.networkError(networkID, err)
+	}
+
+	if publicKey == network.OwnerPublicKey {
+		return false, errAdminKickOwner
+	}
+
+	wasOnline, err := s.kickMember(networkID, publicKey, "")
+	if err != nil {
+		return false, err
+	}
+
+	logger.Info("Computer kicked by admin", "networkID", networkID, "publicKey", publicKey, "wasOnline", wasOnline)
+	return wasOnline, nil
+}
+
+// SetMaintenanceMode makes the server refuse new networks and joins, or accept them again.
+// It reports the resulting state; a configuration reload applies MAINTENANCE_MODE again.
+func (a *AdminService) SetMaintenanceMode(ctx context.Context, enabled bool) (bool, error) {
+	a.server.SetMaintenanceMode(enabled)
+	return enabled, nil
+}
+
+// BroadcastNotice sends a server notice to every connected client, or only to the connected
+// members of networkID when it is set. It returns the notice ID and how many clients got it.
+func (a *AdminService) BroadcastNotice(ctx context.Context, message string, level smodels.NoticeLevel, networkID string) (string, int, error) {
+	if message == "" {
+		return "", 0, fmt.Errorf("%w: message is required", errInvalidNotice)
+	}
+	if len(message) > maxNoticeLength {
+		return "", 0, fmt.Errorf("%w: message must be at most %d bytes", errInvalidNotice, maxNoticeLength)
+	}
+	if level == "" {
+		level = smodels.NoticeLevelInfo
+	}
+	if level != smodels.NoticeLevelInfo && level != smodels.NoticeLevelWarning {
+		return "", 0, fmt.Errorf("%w: level must be info or warning", errInvalidNotice)
+	}
+
+	if networkID != "" {
+		exists, err := a.server.store.NetworkExists(networkID)
+		if err != nil {
+			return "", 0, err
+		}
+		if !exists {
+			return "", 0, errNoticeNetworkNotFound
+		}
+	}
+
+	id, err := utils.GenerateMessageID()
+	if err != nil {
+		return "", 0, err
+	}
+	notice := smodels.ServerNoticeNotification{
+		ID:        "notice-" + id,
+		Message:   message,
+		Level:     level,
+		NetworkID: networkID,
+		SentAt:    time.Now(),
+	}
+
+	delivered := a.server.broadcastNotice(notice)
+	logger.Info("Notice broadcast by admin", "noticeID", notice.ID, "networkID", networkID, "delivered", delivered)
+	return notice.ID, delivered, nil
+}
+
+// networkError tells a missing network, reported as errAdminNetworkNotFound, from a store
+// failure while looking it up, which is returned as is
+func (a *AdminService) networkError(networkID string, err error) error {
+	if exists, existsErr := a.server.store.NetworkExists(networkID); existsErr == nil && !exists {
+		return errAdminNetworkNotFound
+	}
+	return err
+}
+
+// toAdminNetwork converts a stored network into its admin view.
+// Callers must hold at least a read lock on the server.
+func (a *AdminService) toAdminNetwork(network SupabaseNetwork) AdminNetwork {
+	return AdminNetwork{
+		ID:             network.ID,
+		Name:           network.Name,
+		OwnerPublicKey: network.OwnerPublicKey,
+		CreatedAt:      network.CreatedAt,
+		LastActive:     network.LastActive,
+		OnlineCount:    len(a.server.networks[network.ID]),
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// authNonceSize is the number of random bytes in a connection challenge
+const authNonceSize = 32
+
+// clientSession tracks the authentication state of one connection
+type clientSession struct {
+	claimedKey    string // Public key sent in the X-Client-ID header, if any
+	publicKey     string // Public key proven by the signed challenge
+	nonce         []byte
+	authenticated bool
+	natType       smodels.NatType // Reported with NatReport, empty until then
+	address       string          // Public IP the connection came from, "" when unknown
+
+	ctx    context.Context // Cancelled when the connection closes
+	cancel context.CancelFunc
+
+	writeMu sync.Mutex // Serializes writes, gorilla/websocket allows one writer at a time
+}
+
+// openSession creates the session of a new connection from address and sends it a
+// challenge. The context of the session is derived from ctx and lasts until closeSession.
+func (s *WebSocketServer) openSession(ctx context.Context, conn *websocket.Conn, claimedKey, address string) error {
+	nonce := make([]byte, authNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	sessionCtx, cancel := context.WithCancel(ctx)
+	s.sessionsMu.Lock()
+	s.sessions[conn] = &clientSession{claimedKey: claimedKey, address: address, nonce: nonce, ctx: sessionCtx, cancel: cancel}
+	s.sessionsMu.Unlock()
+
+	s.mu.RLock()
+	required := s.config.RequireAuth
+	timeout := s.config.AuthTimeout
+	s.mu.RUnlock()
+
+	if required {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+	}
+
+	return s.sendSignal(conn, smodels.TypeAuthChallenge, smodels.AuthChallenge{
+		Nonce:     base64.StdEncoding.EncodeToString(nonce),
+		ExpiresIn: int(timeout / time.Second),
+		Required:  required,
+	}, "")
+}
+
+// closeSession cancels the context of a closed connection and forgets its session
+func (s *WebSocketServer) closeSession(conn *websocket.Conn) {
+	s.sessionsMu.Lock()
+	if session, ok := s.sessions[conn]; ok {
+		session.cancel()
+		delete(s.sessions, conn)
+	}
+	s.sessionsMu.Unlock()
+}
+
+// authenticatedKey returns the public key a connection proved, if any
+func (s *WebSocketServer) authenticatedKey(conn *websocket.Conn) (string, bool) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	session, ok := s.sessions[conn]
+	if !ok || !session.authenticated {
+		return "", false
+	}
+	return session.publicKey, true
+}
+
+// handleAuthResponse verifies the signature of the connection challenge
+func (s *WebSocketServer) handleAuthResponse(ctx context.Context, conn *websocket.Conn, req smodels.AuthResponseRequest, originalID string) {
+	s.sessionsMu.Lock()
+	session, ok := s.sessions[conn]
+	if !ok || session.authenticated {
+		s.sessionsMu.Unlock()
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Connection is already authenticated", originalID)
+		return
+	}
+
+	err := verifyChallenge(session, req)
+	s.sessionsMu.Unlock()
+
+	if err != nil {
+		logger.Warn("Authentication failed", "remoteAddr", conn.RemoteAddr().String(), "publicKey", req.PublicKey, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrAuthFailed, "Authentication failed: "+err.Error(), originalID)
+		s.closeConn(conn)
+		return
+	}
+
+	// A key replaced by a key migration may no longer connect
+	revoked, err := s.store.IsKeyRevoked(req.PublicKey)
+	if err != nil {
+		logger.Error("Error checking revoked key", "publicKey", req.PublicKey, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error checking public key", originalID)
+		s.closeConn(conn)
+		return
+	}
+	if revoked {
+		logger.Warn("Revoked key tried to authenticate", "remoteAddr", conn.RemoteAddr().String(), "publicKey", req.PublicKey)
+		s.sendErrorSignal(conn, smodels.ErrKeyRevoked, "This key was replaced by a key migration", originalID)
+		s.closeConn(conn)
+		return
+	}
+
+	s.mu.Lock()
+	claimed := s.claimPublicKey(conn, req.PublicKey, originalID)
+	if claimed {
+		s.sessionsMu.Lock()
+		session.publicKey = req.PublicKey
+		session.authenticated = true
+		session.nonce = nil
+		s.sessionsMu.Unlock()
+	}
+	s.mu.Unlock()
+	if !claimed {
+		s.closeConn(conn)
+		return
+	}
+
+	conn.SetReadDeadline(time.Time{})
+	logger.Info("Client authenticated", "remoteAddr", conn.RemoteAddr().String(), "publicKey", req.PublicKey)
+
+	s.sendSignal(conn, smodels.TypeAuthResult, smodels.AuthResult{
+		PublicKey:     req.PublicKey,
+		Authenticated: true,
+	}, originalID)
+
+	s.mu.RLock()
+	required := s.config.RequireAuth
+	s.mu.RUnlock()
+
+	// Without required auth the initial state was already sent on connect
+	if required {
+		go s.sendInitialState(ctx, conn, req.PublicKey)
+	}
+}
+
+// verifyChallenge checks that req carries a valid signature of the session nonce
+func verifyChallenge(session *clientSession, req smodels.AuthResponseRequest) error {
+	if req.PublicKey == "" {
+		return errors.New("public key is required")
+	}
+	if session.claimedKey != "" && session.claimedKey != req.PublicKey {
+		return errors.New("public key does not match X-Client-ID")
+	}
+
+	keyBytes, err := base64.StdEncoding.DecodeString(req.PublicKey)
+	if err != nil || len(keyBytes) != ed25519.PublicKeySize {
+		return errors.New("invalid public key")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(req.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature encoding")
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(keyBytes), smodels.AuthChallengeMessage(session.nonce), signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// authorizeMessage decides whether a message may reach its handler.
+// Until the challenge is answered only pings are accepted; afterwards requests
+// may not claim a public key other than the authenticated one.
+func (s *WebSocketServer) authorizeMessage(conn *websocket.Conn, sigMsg smodels.SignalingMessage) bool {
+	if sigMsg.Type == smodels.TypeAuthResponse {
+		return true
+	}
+
+	publicKey, authenticated := s.authenticatedKey(conn)
+	if !authenticated {
+		s.mu.RLock()
+		required := s.config.RequireAuth
+		s.mu.RUnlock()
+
+		if required && sigMsg.Type != smodels.TypePing {
+			s.sendErrorSignal(conn, smodels.ErrUnauthenticated, "Answer the authentication challenge first", sigMsg.ID)
+			return false
+		}
+		return true
+	}
+
+	var claimed struct {
+		PublicKey       string `json:"public_key"`
+		SenderPublicKey string `json:"sender_public_key"`
+	}
+	if err := json.Unmarshal(sigMsg.Payload, &claimed); err != nil {
+		return true // Malformed payloads are rejected by the handler
+	}
+
+	if (claimed.PublicKey != "" && claimed.PublicKey != publicKey) ||
+		(claimed.SenderPublicKey != "" && claimed.SenderPublicKey != publicKey) {
+		s.sendErrorSignal(conn, smodels.ErrPublicKeyMismatch, "Public key does not match the authenticated connection", sigMsg.ID)
+		return false
+	}
+	return true
+}
+
+// sendInitialState sends a freshly identified client its networks and any expiry warnings
+func (s *WebSocketServer) sendInitialState(ctx context.Context, conn *websocket.Conn, publicKey string) {
+	req := smodels.GetComputerNetworksRequest{
+		BaseRequest: smodels.BaseRequest{
+			PublicKey: publicKey,
+		},
+	}
+
+	s.handleGetComputerNetworksWithIP(ctx, conn, req, "")
+	if requestAbandoned(ctx, conn, "initial state") {
+		return
+	}
+	s.sendExpiryWarnings(conn, publicKey)
+}
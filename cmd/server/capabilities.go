@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// supportedMessageTypes lists the client to server message types handled by dispatchMessage
+var supportedMessageTypes = []smodels.MessageType{
+	smodels.TypeAuthResponse,
+	smodels.TypeCreateNetwork,
+	smodels.TypeJoinNetwork,
+	smodels.TypeConnectNetwork,
+	smodels.TypeDisconnectNetwork,
+	smodels.TypeLeaveNetwork,
+	smodels.TypeKick,
+	smodels.TypeRename,
+	smodels.TypePing,
+	smodels.TypeGetComputerNetworks,
+	smodels.TypeSyncNetwork,
+	smodels.TypeNatReport,
+	smodels.TypeGetNetworkStats,
+	smodels.TypeUpdateClientInfo,
+	smodels.TypeListPublicNetworks,
+	smodels.TypeKeepNetworkAlive,
+	smodels.TypeSetOwnerPolicy,
+	smodels.TypeChangePIN,
+	smodels.TypeBan,
+	smodels.TypeReserveIP,
+	smodels.TypeTransferOwnership,
+	smodels.TypeChangeSubnet,
+	smodels.TypeWakeInfo,
+	smodels.TypeWake,
+	smodels.TypeCreateRecoveryCode,
+	smodels.TypeMigrateKey,
+	smodels.TypeSdpOffer,
+	smodels.TypeSdpAnswer,
+	smodels.TypeIceCandidate,
+	smodels.TypeRelayFrame,
+}
+
+// sendServerCapabilities tells a new connection what this server supports and its limits
+func (s *WebSocketServer) sendServerCapabilities(conn *websocket.Conn) {
+	s.mu.RLock()
+	limits := smodels.ServerLimits{
+		MaxClientsPerNetwork:     s.config.MaxClientsPerNetwork,
+		MaxNetworksPerOwner:      s.config.MaxNetworksPerOwner,
+		MaxMessageSize:           s.config.MaxMessageSize,
+		MaxPayloadSize:           s.config.MaxPayloadSize,
+		MaxConnsPerIP:            s.config.MaxConnsPerIP,
+		ComputerNetworksPageSize: defaultComputerNetworksPageSize,
+		RelayQuotaBytes:          s.config.RelayQuotaBytes,
+	}
+	s.mu.RUnlock()
+
+	var natProbePorts []int
+	if s.natProbe != nil {
+		natProbePorts = s.natProbe.Ports()
+	}
+
+	s.sendSignal(conn, smodels.TypeServerCapabilities, smodels.ServerCapabilitiesNotification{
+		ProtocolVersion: smodels.ProtocolVersion,
+		ServerVersion:   serverVersion,
+		ServerCommit:    buildCommit(),
+		MessageTypes:    supportedMessageTypes,
+		Features:        serverCapabilities,
+		Limits:          limits,
+		NatProbePorts:   natProbePorts,
+	}, "")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// clientAddr is where a connection comes from, as far as the server can tell
+type clientAddr struct {
+	ip        netip.Addr // Invalid for a UNIX socket peer without X-Forwarded-For
+	port      int
+	forwarded bool
+}
+
+// key returns the address used for per-IP connection limits, "" when it is unknown
+func (a clientAddr) key() string {
+	if !a.ip.IsValid() {
+		return ""
+	}
+	return a.ip.String()
+}
+
+// parseTrustedProxies parses the comma-separated IPs and CIDR ranges of TRUSTED_PROXIES
+func parseTrustedProxies(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		ip, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", entry, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// isTrustedProxy reports whether ip belongs to one of the trusted ranges
+func isTrustedProxy(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddress works out the public address of the client behind a request.
+// X-Forwarded-For is only honored when the direct peer is a trusted proxy or a
+// local process on a UNIX socket; its entries are read right to left, skipping
+// trusted proxies, so a client cannot spoof its address by sending the header itself.
+func (s *WebSocketServer) clientAddress(r *http.Request) clientAddr {
+	s.mu.RLock()
+	trustedList := s.config.TrustedProxies
+	s.mu.RUnlock()
+
+	// Validated at load time, an error here can only mean no proxies are trusted
+	trusted, _ := parseTrustedProxies(trustedList)
+
+	var peer clientAddr
+	if host, port, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if ip, err := netip.ParseAddr(host); err == nil {
+			peer.ip = ip.Unmap()
+			peer.port, _ = strconv.Atoi(port)
+		}
+	}
+
+	if peer.ip.IsValid() && !isTrustedProxy(peer.ip, trusted) {
+		return peer
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		ip = ip.Unmap()
+		if isTrustedProxy(ip, trusted) && i > 0 {
+			continue
+		}
+		return clientAddr{ip: ip, forwarded: true}
+	}
+	return peer
+}
+
+// sendClientIPInfo tells a new connection which address the server sees it coming from
+func (s *WebSocketServer) sendClientIPInfo(conn *websocket.Conn, addr clientAddr) {
+	if !addr.ip.IsValid() {
+		return
+	}
+
+	version := "ipv6"
+	if addr.ip.Is4() {
+		version = "ipv4"
+	}
+
+	s.sendSignal(conn, smodels.TypeClientIPInfo, smodels.ClientIPInfoNotification{
+		IP:        addr.ip.String(),
+		IPVersion: version,
+		Port:      addr.port,
+		Forwarded: addr.forwarded,
+	}, "")
+}
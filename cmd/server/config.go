@@ -0,0 +1,551 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// Store backends accepted by STORE_BACKEND
+const (
+	storeBackendSupabase = "supabase"
+	storeBackendMemory   = "memory"
+)
+
+// Config holds the configuration for the WebSocket server
+type Config struct {
+	Port                   string        // Port to listen on
+	Listen                 string        // Comma-separated listen addresses, replacing Port when set
+	NatProbePort           int           // First of the two UDP ports answering NAT probes (0 disables)
+	StoreBackend           string        // Where networks are stored (supabase, memory)
+	SupabaseURL            string        // URL of the Supabase instance
+	SupabaseKey            string        // API key for Supabase
+	SupabaseNetworksTable  string        // Name of the networks table in Supabase
+	ReadBufferSize         int           // Size of the read buffer for WebSocket connections
+	WriteBufferSize        int           // Size of the write buffer for WebSocket connections
+	MaxClientsPerNetwork   int           // Maximum number of clients allowed in a network
+	MaxNetworksPerOwner    int           // Maximum number of networks a single public key can own
+	MaxConnsPerIP          int           // Maximum number of open WebSocket connections from one IP (0 disables)
+	MaxTotalConns          int           // Maximum number of open WebSocket connections overall (0 disables)
+	DefaultOwnerPolicy     string        // What happens to a network when its owner disconnects (preserve, delete, transfer)
+	DuplicateSessionPolicy string        // What happens when a public key connects twice (replace, reject)
+	NetworkExpiryDays      int           // Number of days after which inactive networks are deleted
+	ExpiryWarningDays      int           // Owners are warned this many days before a network expires
+	AllowAllOrigins        bool          // Whether to allow all origins for WebSocket connections
+	TrustedProxies         string        // Comma-separated IPs/CIDRs whose X-Forwarded-For header is honored
+	RequireAuth            bool          // Whether clients must answer the signed challenge before sending requests
+	MaintenanceMode        bool          // Whether new networks and joins are refused while existing sessions keep working
+	Motd                   string        // Message of the day sent to clients after they connect
+	AdminToken             string        // Bearer token of the HTTP admin endpoints and the gRPC admin service (empty disables them)
+	AdminGRPCListen        string        // Address of the gRPC admin service in LISTEN syntax (empty disables it)
+	AuthTimeout            time.Duration // How long a client has to answer the challenge
+	CleanupInterval        time.Duration // Interval at which to clean up stale networks
+	LogLevel               string        // Log level (debug, info, warn, error)
+	ShutdownTimeout        time.Duration // Timeout for graceful shutdown
+	CacheTTL               time.Duration // How long Supabase lookups are cached (0 disables)
+	MaxMessageSize         int64         // Maximum size in bytes of a single WebSocket message
+	MaxPayloadSize         int           // Maximum size in bytes of a decoded message payload
+	IdempotencyTTL         time.Duration // How long responses are kept for idempotency keys
+	RelayQuotaBytes        int64         // Bytes per minute each network may relay through the server when P2P fails (0 disables relaying)
+}
+
+// defaultConfig returns the configuration used when nothing else is set
+func defaultConfig() Config {
+	return Config{
+		Port:                   "8080",
+		StoreBackend:           storeBackendSupabase,
+		SupabaseNetworksTable:  "networks",
+		ReadBufferSize:         1024,
+		WriteBufferSize:        1024,
+		MaxClientsPerNetwork:   50,
+		MaxNetworksPerOwner:    5,
+		MaxConnsPerIP:          20,
+		MaxTotalConns:          10000,
+		DefaultOwnerPolicy:     "preserve",
+		DuplicateSessionPolicy: duplicateSessionReplace,
+		NetworkExpiryDays:      7,
+		ExpiryWarningDays:      2,
+		AllowAllOrigins:        true,
+		RequireAuth:            true,
+		AuthTimeout:            10 * time.Second,
+		CleanupInterval:        24 * time.Hour, // Run cleanup once a day
+		ShutdownTimeout:        2 * time.Second,
+		CacheTTL:               5 * time.Second,
+		MaxMessageSize:         64 * 1024,
+		MaxPayloadSize:         32 * 1024,
+		IdempotencyTTL:         5 * time.Minute,
+		RelayQuotaBytes:        8 * 1024 * 1024,
+	}
+}
+
+// configOption describes one setting and where it can be read from.
+// The key is used in config files; flags use the same key with dashes.
+type configOption struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	set    func(cfg *Config, value string) error
+	get    func(cfg *Config) string
+}
+
+// flagName returns the command-line flag name of the option
+func (o configOption) flagName() string {
+	return strings.ReplaceAll(o.key, "_", "-")
+}
+
+// asSecret marks the option so its value is masked when printed
+func (o configOption) asSecret() configOption {
+	o.secret = true
+	return o
+}
+
+func stringOption(key, env, usage string, field func(*Config) *string) configOption {
+	return configOption{
+		key:   key,
+		env:   env,
+		usage: usage,
+		set: func(cfg *Config, value string) error {
+			*field(cfg) = value
+			return nil
+		},
+		get: func(cfg *Config) string { return *field(cfg) },
+	}
+}
+
+func intOption(key, env, usage string, field func(*Config) *int) configOption {
+	return configOption{
+		key:   key,
+		env:   env,
+		usage: usage,
+		set: func(cfg *Config, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("must be an integer")
+			}
+			*field(cfg) = n
+			return nil
+		},
+		get: func(cfg *Config) string { return strconv.Itoa(*field(cfg)) },
+	}
+}
+
+func int64Option(key, env, usage string, field func(*Config) *int64) configOption {
+	return configOption{
+		key:   key,
+		env:   env,
+		usage: usage,
+		set: func(cfg *Config, value string) error {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("must be an integer")
+			}
+			*field(cfg) = n
+			return nil
+		},
+		get: func(cfg *Config) string { return strconv.FormatInt(*field(cfg), 10) },
+	}
+}
+
+func boolOption(key, env, usage string, field func(*Config) *bool) configOption {
+	return configOption{
+		key:   key,
+		env:   env,
+		usage: usage,
+		set: func(cfg *Config, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("must be true or false")
+			}
+			*field(cfg) = b
+			return nil
+		},
+		get: func(cfg *Config) string { return strconv.FormatBool(*field(cfg)) },
+	}
+}
+
+// durationOption reads a whole number of units (e.g. hours or seconds) into a duration
+func durationOption(key, env, usage string, unit time.Duration, field func(*Config) *time.Duration) configOption {
+	return configOption{
+		key:   key,
+		env:   env,
+		usage: usage,
+		set: func(cfg *Config, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("must be an integer")
+			}
+			*field(cfg) = time.Duration(n) * unit
+			return nil
+		},
+		get: func(cfg *Config) string { return strconv.FormatInt(int64(*field(cfg)/unit), 10) },
+	}
+}
+
+// configOptions lists every setting in the order it is printed
+var configOptions = []configOption{
+	stringOption("port", "PORT", "port to listen on",
+		func(c *Config) *string { return &c.Port }),
+	stringOption("listen", "LISTEN", "comma-separated listen addresses (host:port, unix:///path.sock, ?cert=&key= for TLS); overrides port",
+		func(c *Config) *string { return &c.Listen }),
+	intOption("nat_probe_port", "NAT_PROBE_PORT", "first of two consecutive UDP ports answering NAT probes (0 disables)",
+		func(c *Config) *int { return &c.NatProbePort }),
+	stringOption("store_backend", "STORE_BACKEND", "where networks are stored (supabase, memory)",
+		func(c *Config) *string { return &c.StoreBackend }),
+	stringOption("supabase_url", "SUPABASE_URL", "URL of the Supabase instance",
+		func(c *Config) *string { return &c.SupabaseURL }),
+	stringOption("supabase_key", "SUPABASE_KEY", "API key for Supabase",
+		func(c *Config) *string { return &c.SupabaseKey }).asSecret(),
+	stringOption("supabase_networks_table", "SUPABASE_NETWORKS_TABLE", "name of the networks table in Supabase",
+		func(c *Config) *string { return &c.SupabaseNetworksTable }),
+	intOption("read_buffer_size", "READ_BUFFER_SIZE", "WebSocket read buffer size in bytes",
+		func(c *Config) *int { return &c.ReadBufferSize }),
+	intOption("write_buffer_size", "WRITE_BUFFER_SIZE", "WebSocket write buffer size in bytes",
+		func(c *Config) *int { return &c.WriteBufferSize }),
+	intOption("max_clients_per_network", "MAX_CLIENTS_PER_NETWORK", "maximum number of clients in a network",
+		func(c *Config) *int { return &c.MaxClientsPerNetwork }),
+	intOption("max_networks_per_owner", "MAX_NETWORKS_PER_OWNER", "maximum number of networks a public key can own",
+		func(c *Config) *int { return &c.MaxNetworksPerOwner }),
+	intOption("max_conns_per_ip", "MAX_CONNS_PER_IP", "maximum open connections from one IP (0 disables)",
+		func(c *Config) *int { return &c.MaxConnsPerIP }),
+	intOption("max_total_conns", "MAX_TOTAL_CONNS", "maximum open connections overall (0 disables)",
+		func(c *Config) *int { return &c.MaxTotalConns }),
+	stringOption("default_owner_policy", "DEFAULT_OWNER_POLICY", "owner disconnect policy for new networks (preserve, delete, transfer)",
+		func(c *Config) *string { return &c.DefaultOwnerPolicy }),
+	stringOption("duplicate_session_policy", "DUPLICATE_SESSION_POLICY", "what happens when a public key connects twice (replace, reject)",
+		func(c *Config) *string { return &c.DuplicateSessionPolicy }),
+	intOption("network_expiry_days", "NETWORK_EXPIRY_DAYS", "days of inactivity before a network is deleted",
+		func(c *Config) *int { return &c.NetworkExpiryDays }),
+	intOption("expiry_warning_days", "EXPIRY_WARNING_DAYS", "days before expiry when owners are warned (0 disables)",
+		func(c *Config) *int { return &c.ExpiryWarningDays }),
+	boolOption("allow_all_origins", "ALLOW_ALL_ORIGINS", "accept WebSocket connections from any origin",
+		func(c *Config) *bool { return &c.AllowAllOrigins }),
+	stringOption("trusted_proxies", "TRUSTED_PROXIES", "comma-separated proxy IPs or CIDR ranges whose X-Forwarded-For header is trusted",
+		func(c *Config) *string { return &c.TrustedProxies }),
+	boolOption("require_auth", "REQUIRE_AUTH", "require clients to sign the connection challenge before sending requests",
+		func(c *Config) *bool { return &c.RequireAuth }),
+	boolOption("maintenance_mode", "MAINTENANCE_MODE", "refuse new networks and joins while keeping existing connections",
+		func(c *Config) *bool { return &c.MaintenanceMode }),
+	stringOption("motd", "MOTD", "message of the day sent to clients after they connect",
+		func(c *Config) *string { return &c.Motd }),
+	stringOption("admin_token", "ADMIN_TOKEN", "bearer token for the HTTP admin endpoints and the gRPC admin service (empty disables them)",
+		func(c *Config) *string { return &c.AdminToken }).asSecret(),
+	stringOption("admin_grpc_listen", "ADMIN_GRPC_LISTEN", "address of the gRPC admin service (host:port, unix:///path.sock, ?cert=&key= for TLS); empty disables it",
+		func(c *Config) *string { return &c.AdminGRPCListen }),
+	durationOption("auth_timeout_seconds", "AUTH_TIMEOUT_SECONDS", "seconds a client has to answer the connection challenge", time.Second,
+		func(c *Config) *time.Duration { return &c.AuthTimeout }),
+	durationOption("cleanup_interval_hours", "CLEANUP_INTERVAL_HOURS", "hours between stale network cleanups", time.Hour,
+		func(c *Config) *time.Duration { return &c.CleanupInterval }),
+	stringOption("log_level", "LOG_LEVEL", "log level (debug, info, warn, error)",
+		func(c *Config) *string { return &c.LogLevel }),
+	durationOption("shutdown_timeout_seconds", "SHUTDOWN_TIMEOUT_SECONDS", "graceful shutdown timeout in seconds", time.Second,
+		func(c *Config) *time.Duration { return &c.ShutdownTimeout }),
+	durationOption("cache_ttl_seconds", "CACHE_TTL_SECONDS", "Supabase lookup cache TTL in seconds (0 disables)", time.Second,
+		func(c *Config) *time.Duration { return &c.CacheTTL }),
+	int64Option("max_message_size", "MAX_MESSAGE_SIZE", "maximum WebSocket message size in bytes",
+		func(c *Config) *int64 { return &c.MaxMessageSize }),
+	intOption("max_payload_size", "MAX_PAYLOAD_SIZE", "maximum decoded payload size in bytes",
+		func(c *Config) *int { return &c.MaxPayloadSize }),
+	durationOption("idempotency_ttl_seconds", "IDEMPOTENCY_TTL_SECONDS", "how long idempotent responses are kept, in seconds", time.Second,
+		func(c *Config) *time.Duration { return &c.IdempotencyTTL }),
+	int64Option("relay_quota_bytes", "RELAY_QUOTA_BYTES", "bytes per minute each network may relay between members that cannot connect directly (0 disables relaying)",
+		func(c *Config) *int64 { return &c.RelayQuotaBytes }),
+}
+
+// findConfigOption returns the option with the given file key
+func findConfigOption(key string) (configOption, bool) {
+	for _, o := range configOptions {
+		if o.key == key {
+			return o, true
+		}
+	}
+	return configOption{}, false
+}
+
+// Validate checks that the configuration is usable and reports every problem found
+func (c Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("port: must be a number between 1 and 65535"))
+	}
+	if _, err := parseListenerSpecs(c.Listen); err != nil {
+		errs = append(errs, fmt.Errorf("listen: %w", err))
+	}
+	if c.AdminGRPCListen != "" {
+		if _, err := parseAdminGRPCListen(c.AdminGRPCListen); err != nil {
+			errs = append(errs, fmt.Errorf("admin_grpc_listen: %w", err))
+		}
+		if c.AdminToken == "" {
+			errs = append(errs, fmt.Errorf("admin_grpc_listen: needs admin_token"))
+		}
+	}
+	switch c.StoreBackend {
+	case storeBackendSupabase:
+		if c.SupabaseURL == "" {
+			errs = append(errs, fmt.Errorf("supabase_url: is required"))
+		}
+		if c.SupabaseKey == "" {
+			errs = append(errs, fmt.Errorf("supabase_key: is required"))
+		}
+		if c.SupabaseNetworksTable == "" {
+			errs = append(errs, fmt.Errorf("supabase_networks_table: must not be empty"))
+		}
+	case storeBackendMemory:
+	default:
+		errs = append(errs, fmt.Errorf("store_backend: must be supabase or memory"))
+	}
+	if c.NatProbePort < 0 || c.NatProbePort > 65534 {
+		errs = append(errs, fmt.Errorf("nat_probe_port: must be between 0 and 65534"))
+	}
+	if c.ReadBufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("read_buffer_size: must be positive"))
+	}
+	if c.WriteBufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("write_buffer_size: must be positive"))
+	}
+	if c.MaxClientsPerNetwork <= 0 {
+		errs = append(errs, fmt.Errorf("max_clients_per_network: must be positive"))
+	}
+	if c.MaxNetworksPerOwner <= 0 {
+		errs = append(errs, fmt.Errorf("max_networks_per_owner: must be positive"))
+	}
+	if _, err := parseTrustedProxies(c.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("trusted_proxies: %w", err))
+	}
+	if c.MaxConnsPerIP < 0 {
+		errs = append(errs, fmt.Errorf("max_conns_per_ip: must not be negative"))
+	}
+	if c.MaxTotalConns < 0 {
+		errs = append(errs, fmt.Errorf("max_total_conns: must not be negative"))
+	}
+	if !smodels.OwnerPolicy(c.DefaultOwnerPolicy).Valid() {
+		errs = append(errs, fmt.Errorf("default_owner_policy: must be one of preserve, delete, transfer"))
+	}
+	if c.DuplicateSessionPolicy != duplicateSessionReplace && c.DuplicateSessionPolicy != duplicateSessionReject {
+		errs = append(errs, fmt.Errorf("duplicate_session_policy: must be replace or reject"))
+	}
+	if c.NetworkExpiryDays <= 0 {
+		errs = append(errs, fmt.Errorf("network_expiry_days: must be positive"))
+	}
+	if c.ExpiryWarningDays < 0 {
+		errs = append(errs, fmt.Errorf("expiry_warning_days: must not be negative"))
+	}
+	if c.AuthTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("auth_timeout_seconds: must be positive"))
+	}
+	if c.CleanupInterval <= 0 {
+		errs = append(errs, fmt.Errorf("cleanup_interval_hours: must be positive"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("shutdown_timeout_seconds: must be positive"))
+	}
+	if c.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("cache_ttl_seconds: must not be negative"))
+	}
+	if len(c.Motd) > maxNoticeLength {
+		errs = append(errs, fmt.Errorf("motd: must be at most %d bytes", maxNoticeLength))
+	}
+	if c.MaxMessageSize <= 0 {
+		errs = append(errs, fmt.Errorf("max_message_size: must be positive"))
+	}
+	if c.MaxPayloadSize <= 0 {
+		errs = append(errs, fmt.Errorf("max_payload_size: must be positive"))
+	}
+	if c.IdempotencyTTL < 0 {
+		errs = append(errs, fmt.Errorf("idempotency_ttl_seconds: must not be negative"))
+	}
+	if c.RelayQuotaBytes < 0 {
+		errs = append(errs, fmt.Errorf("relay_quota_bytes: must not be negative"))
+	}
+
+	switch strings.ToLower(c.LogLevel) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log_level: must be one of debug, info, warn, error"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// WriteTo prints the configuration in config file format, masking secrets
+func (c Config) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, o := range configOptions {
+		value := o.get(&c)
+		if o.secret && value != "" {
+			value = "********"
+		}
+		fmt.Fprintf(&b, "%s: %q\n", o.key, value)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// serverFlags holds the parsed command-line flags of the server
+type serverFlags struct {
+	configFile   string
+	printConfig  bool
+	printVersion bool
+	overrides    map[string]string // option key -> value, only for flags given explicitly
+}
+
+// parseFlags parses the command-line arguments of the server
+func parseFlags(args []string) (serverFlags, error) {
+	fs := flag.NewFlagSet("govpn-server", flag.ContinueOnError)
+
+	var flags serverFlags
+	fs.StringVar(&flags.configFile, "config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	fs.BoolVar(&flags.printConfig, "print-config", false, "print the effective configuration and exit")
+	fs.BoolVar(&flags.printVersion, "version", false, "print the version and exit")
+
+	values := make(map[string]*string, len(configOptions))
+	for _, o := range configOptions {
+		usage := o.usage
+		if o.env != "" {
+			usage += " (env " + o.env + ")"
+		}
+		values[o.flagName()] = fs.String(o.flagName(), "", usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return serverFlags{}, err
+	}
+
+	// Only flags that were actually passed override the file and environment
+	flags.overrides = make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if value, ok := values[f.Name]; ok {
+			flags.overrides[strings.ReplaceAll(f.Name, "-", "_")] = *value
+		}
+	})
+
+	return flags, nil
+}
+
+// loadConfig builds the server configuration. Later sources take precedence:
+// defaults, then the config file, then environment variables, then flags.
+func loadConfig(flags serverFlags) (Config, error) {
+	cfg := defaultConfig()
+	var errs []error
+
+	if flags.configFile != "" {
+		values, err := readConfigFile(flags.configFile)
+		if err != nil {
+			return cfg, err
+		}
+		for key, value := range values {
+			o, ok := findConfigOption(key)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown key %q", flags.configFile, key))
+				continue
+			}
+			if err := o.set(&cfg, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", flags.configFile, key, err))
+			}
+		}
+	}
+
+	for _, o := range configOptions {
+		value, ok := os.LookupEnv(o.env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := o.set(&cfg, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", o.env, err))
+		}
+	}
+
+	for _, o := range configOptions {
+		value, ok := flags.overrides[o.key]
+		if !ok {
+			continue
+		}
+		if err := o.set(&cfg, value); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", o.flagName(), err))
+		}
+	}
+
+	return cfg, errors.Join(errs...)
+}
+
+// readConfigFile reads a flat YAML ("key: value") or TOML ("key = value") file.
+// The format is chosen by extension; nested sections are not supported.
+func readConfigFile(path string) (map[string]string, error) {
+	var sep, example string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		sep, example = ":", "key: value"
+	case ".toml":
+		sep, example = "=", "key = value"
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format (use .yaml, .yml or .toml)", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" || line == "---" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, sep)
+		if !ok || strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("%s:%d: expected %q", path, lineNo, example)
+		}
+
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+
+		if _, dup := values[key]; dup {
+			return nil, fmt.Errorf("%s:%d: duplicate key %q", path, lineNo, key)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	return values, nil
+}
+
+// stripComment removes a trailing # comment that is not inside quotes
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
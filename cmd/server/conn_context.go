@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// errConnClosed is returned by writeJSON for connections that no longer have a session
+var errConnClosed = errors.New("connection closed")
+
+// closedContext is handed out for connections that no longer have a session
+var closedContext = func() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}()
+
+// connContext returns the context of a connection, which is cancelled once the
+// connection closes or a write to it fails
+func (s *WebSocketServer) connContext(conn *websocket.Conn) context.Context {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	if session, ok := s.sessions[conn]; ok {
+		return session.ctx
+	}
+	return closedContext
+}
+
+// writeJSON writes v to conn. A connection is written from its own handler and from
+// the handlers of other members, for broadcasts and relayed frames, while
+// gorilla/websocket supports a single concurrent writer, so every write goes through
+// the lock of the connection's session.
+func (s *WebSocketServer) writeJSON(conn *websocket.Conn, v interface{}) error {
+	s.sessionsMu.Lock()
+	session, ok := s.sessions[conn]
+	s.sessionsMu.Unlock()
+	if !ok {
+		return errConnClosed
+	}
+
+	session.writeMu.Lock()
+	defer session.writeMu.Unlock()
+	return conn.WriteJSON(v)
+}
+
+// cancelConn cancels the context of a connection so its pending work is dropped
+func (s *WebSocketServer) cancelConn(conn *websocket.Conn) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	if session, ok := s.sessions[conn]; ok {
+		session.cancel()
+	}
+}
+
+// closeConn cancels the context of a connection and closes it, for connections
+// the server drops itself
+func (s *WebSocketServer) closeConn(conn *websocket.Conn) {
+	s.cancelConn(conn)
+	conn.Close()
+}
+
+// requestAbandoned reports whether the connection a handler works for has gone away,
+// in which case the handler stops before its next store call or reply. Store calls
+// cannot be interrupted, so this is checked between them.
+func requestAbandoned(ctx context.Context, conn *websocket.Conn, step string) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	logger.Debug("Connection closed, abandoning request", "remoteAddr", conn.RemoteAddr().String(), "step", step)
+	return true
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// acquireConnSlot reserves a connection slot for ip, enforcing MAX_TOTAL_CONNS and
+// MAX_CONNS_PER_IP (0 disables either limit). It reports whether the slot was granted.
+// An empty ip, a UNIX socket peer with no forwarded address, is only counted in the total.
+func (s *WebSocketServer) acquireConnSlot(ip string) bool {
+	s.mu.RLock()
+	maxTotal := s.config.MaxTotalConns
+	maxPerIP := s.config.MaxConnsPerIP
+	s.mu.RUnlock()
+
+	s.connLimitsMu.Lock()
+	defer s.connLimitsMu.Unlock()
+
+	if maxTotal > 0 && s.totalConns >= maxTotal {
+		return false
+	}
+	if maxPerIP > 0 && ip != "" && s.connsPerIP[ip] >= maxPerIP {
+		return false
+	}
+
+	s.totalConns++
+	s.connsPerIP[ip]++
+	return true
+}
+
+// releaseConnSlot frees the slot taken by acquireConnSlot
+func (s *WebSocketServer) releaseConnSlot(ip string) {
+	s.connLimitsMu.Lock()
+	defer s.connLimitsMu.Unlock()
+
+	s.totalConns--
+	if s.connsPerIP[ip] <= 1 {
+		delete(s.connsPerIP, ip)
+	} else {
+		s.connsPerIP[ip]--
+	}
+}
+
+// rejectConnection answers a WebSocket upgrade request with 429 Too Many Requests
+func rejectConnection(w http.ResponseWriter, ip string) {
+	logger.Warn("Connection rejected, limit reached", "ip", ip)
+	w.Header().Set("Retry-After", "10")
+	http.Error(w, "too many connections", http.StatusTooManyRequests)
+}
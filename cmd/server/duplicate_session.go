@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// What happens when a public key connects while another connection already uses it
+const (
+	duplicateSessionReplace = "replace" // The old connection is told it was replaced and closed
+	duplicateSessionReject  = "reject"  // The new connection is refused until the old one goes away
+)
+
+// connsOfKey returns the open connections other than except that use publicKey, either
+// because they signed the challenge with it or joined a network with it.
+// Callers must hold the server lock.
+func (s *WebSocketServer) connsOfKey(publicKey string, except *websocket.Conn) []*websocket.Conn {
+	seen := make(map[*websocket.Conn]bool)
+	var conns []*websocket.Conn
+
+	for conn, key := range s.clientToPublicKey {
+		if key == publicKey && conn != except && !seen[conn] {
+			seen[conn] = true
+			conns = append(conns, conn)
+		}
+	}
+
+	s.sessionsMu.Lock()
+	for conn, session := range s.sessions {
+		// Replaced connections keep their session until their read loop ends
+		if session.authenticated && session.publicKey == publicKey && session.ctx.Err() == nil && conn != except && !seen[conn] {
+			seen[conn] = true
+			conns = append(conns, conn)
+		}
+	}
+	s.sessionsMu.Unlock()
+
+	return conns
+}
+
+// claimPublicKey applies DUPLICATE_SESSION_POLICY before conn starts using publicKey.
+// It reports false after answering originalID with an error when conn is refused.
+// Callers must hold the server lock.
+func (s *WebSocketServer) claimPublicKey(conn *websocket.Conn, publicKey, originalID string) bool {
+	others := s.connsOfKey(publicKey, conn)
+	if len(others) == 0 {
+		return true
+	}
+
+	if s.config.DuplicateSessionPolicy == duplicateSessionReject {
+		logger.Warn("Refused second connection of public key", "remoteAddr", conn.RemoteAddr().String(), "publicKey", publicKey)
+		s.sendErrorSignal(conn, smodels.ErrSessionActive, "This public key is already connected from another session", originalID)
+		return false
+	}
+
+	for _, old := range others {
+		s.replaceSession(old, conn, publicKey)
+	}
+	return true
+}
+
+// replaceSession closes old, whose public key just connected on replacement, and takes it
+// out of its network. The owner policy is not applied since the owner is still around.
+// Callers must hold the server lock.
+func (s *WebSocketServer) replaceSession(old, replacement *websocket.Conn, publicKey string) {
+	logger.Info("Replacing connection of public key", "oldAddr", old.RemoteAddr().String(), "newAddr", replacement.RemoteAddr().String(), "publicKey", publicKey)
+
+	replacedFrom, _, err := net.SplitHostPort(replacement.RemoteAddr().String())
+	if err != nil {
+		replacedFrom = ""
+	}
+	s.sendSignal(old, smodels.TypeSessionReplaced, smodels.SessionReplacedNotification{
+		PublicKey:    publicKey,
+		ReplacedFrom: replacedFrom,
+	}, "")
+	s.closeConn(old)
+
+	networkID := s.clients[old]
+	if networkID == "" {
+		delete(s.clientToPublicKey, old)
+		return
+	}
+
+	s.detachClient(old, networkID)
+	if _, exists := s.networks[networkID]; exists {
+		s.broadcastNetworkEvent(networkID, smodels.TypeComputerDisconnected, smodels.ComputerDisconnectedNotification{
+			NetworkID: networkID,
+			PublicKey: publicKey,
+		}, old)
+		s.broadcastRoster(networkID)
+	}
+	s.statsManager.UpdateStats(len(s.clients), len(s.networks))
+}
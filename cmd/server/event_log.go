@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// eventReplaySize is how many notifications are kept per network for reconnecting clients
+const eventReplaySize = 128
+
+// networkEventLog is a ring buffer of the latest notifications of a network.
+// Every notification broadcast to a network goes through it, so its sequence
+// increases by exactly one per notification and clients can detect gaps.
+type networkEventLog struct {
+	seq     uint64 // Sequence of the latest event
+	events  []smodels.SignalingMessage
+	start   int       // Index of the oldest event
+	updated time.Time // When the latest event was appended
+}
+
+// newNetworkEventLog creates an empty log. Sequences start at the creation time in
+// microseconds, so numbers from before a server restart never match new events.
+func newNetworkEventLog() *networkEventLog {
+	return &networkEventLog{
+		seq:     uint64(time.Now().UnixMicro()),
+		events:  make([]smodels.SignalingMessage, 0, eventReplaySize),
+		updated: time.Now(),
+	}
+}
+
+// append stores a new event and returns it with its sequence set
+func (l *networkEventLog) append(msgType smodels.MessageType, payload []byte) smodels.SignalingMessage {
+	l.seq++
+	l.updated = time.Now()
+	event := smodels.SignalingMessage{Type: msgType, Payload: payload, Sequence: l.seq}
+
+	if len(l.events) < eventReplaySize {
+		l.events = append(l.events, event)
+	} else {
+		l.events[l.start] = event
+		l.start = (l.start + 1) % eventReplaySize
+	}
+	return event
+}
+
+// since returns the events after seq, oldest first. ok is false when some of
+// them were already dropped from the buffer and the caller needs a full resync.
+func (l *networkEventLog) since(seq uint64) ([]smodels.SignalingMessage, bool) {
+	if seq == l.seq {
+		return nil, true
+	}
+	if seq > l.seq || len(l.events) == 0 || seq+1 < l.events[l.start].Sequence {
+		return nil, false
+	}
+
+	var missed []smodels.SignalingMessage
+	for i := 0; i < len(l.events); i++ {
+		event := l.events[(l.start+i)%len(l.events)]
+		if event.Sequence > seq {
+			missed = append(missed, event)
+		}
+	}
+	return missed, true
+}
+
+// eventLog returns the event log of a network, creating it if needed.
+// Callers must hold the server lock.
+func (s *WebSocketServer) eventLog(networkID string) *networkEventLog {
+	log, ok := s.eventLogs[networkID]
+	if !ok {
+		log = newNetworkEventLog()
+		s.eventLogs[networkID] = log
+	}
+	return log
+}
+
+// broadcastNetworkEvent records a notification in the network's log and sends it,
+// with its sequence, to every connected member except exclude.
+// Callers must hold the server lock.
+func (s *WebSocketServer) broadcastNetworkEvent(networkID string, msgType smodels.MessageType, payload interface{}, exclude *websocket.Conn) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("Failed to marshal network event", "error", err, "type", msgType, "networkID", networkID)
+		return
+	}
+
+	event := s.eventLog(networkID).append(msgType, payloadBytes)
+
+	for _, computer := range s.networks[networkID] {
+		if computer == exclude {
+			continue
+		}
+		if err := s.writeJSON(computer, event); err != nil {
+			logger.Error("Failed to send network event", "error", err, "type", msgType, "networkID", networkID)
+		}
+	}
+}
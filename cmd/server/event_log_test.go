@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// appendEvents adds n events to log and returns the sequence of the last one
+func appendEvents(log *networkEventLog, n int) uint64 {
+	for range n {
+		log.append(smodels.TypeComputerConnected, []byte("{}"))
+	}
+	return log.seq
+}
+
+func TestEventLogSince(t *testing.T) {
+	log := newNetworkEventLog()
+	start := log.seq
+	latest := appendEvents(log, 5)
+
+	tests := []struct {
+		name string
+		seq  uint64
+		want int // Number of events returned
+		ok   bool
+	}{
+		{"up to date", latest, 0, true},
+		{"missed some", latest - 2, 2, true},
+		{"missed all", start, 5, true},
+		{"before the log", start - 1, 0, false},
+		{"from the future", latest + 1, 0, false},
+	}
+	for _, tt := range tests {
+		events, ok := log.since(tt.seq)
+		if ok != tt.ok || len(events) != tt.want {
+			t.Errorf("%s: since(%d) = %d events, %v; want %d events, %v", tt.name, tt.seq, len(events), ok, tt.want, tt.ok)
+			continue
+		}
+		for i, event := range events {
+			if want := tt.seq + uint64(i) + 1; event.Sequence != want {
+				t.Errorf("%s: event %d has sequence %d, want %d", tt.name, i, event.Sequence, want)
+			}
+		}
+	}
+}
+
+func TestEventLogSinceAfterWrap(t *testing.T) {
+	log := newNetworkEventLog()
+	start := log.seq
+	latest := appendEvents(log, eventReplaySize+10)
+
+	// The first 10 events were overwritten
+	if _, ok := log.since(start + 9); ok {
+		t.Error("since reported complete events although some were dropped")
+	}
+
+	events, ok := log.since(start + 10)
+	if !ok || len(events) != eventReplaySize {
+		t.Fatalf("since(oldest - 1) = %d events, %v; want %d events, true", len(events), ok, eventReplaySize)
+	}
+	if events[0].Sequence != start+11 || events[len(events)-1].Sequence != latest {
+		t.Errorf("events run from %d to %d, want %d to %d", events[0].Sequence, events[len(events)-1].Sequence, start+11, latest)
+	}
+}
@@ -9,6 +9,7 @@ require (
 
 replace (
 	github.com/itxtoledo/govpn/libs/crypto_utils v0.0.0 => ../../libs/crypto_utils
+	github.com/itxtoledo/govpn/libs/logger v0.0.0 => ../../libs/logger
 	github.com/itxtoledo/govpn/libs/network v0.0.0 => ../../libs/network
 	github.com/itxtoledo/govpn/libs/signaling v0.0.0 => ../../libs/signaling
 	github.com/itxtoledo/govpn/libs/signaling/models => ../../libs/signaling/models
@@ -17,9 +18,13 @@ replace (
 
 require (
 	github.com/gorilla/websocket v1.5.3
+	github.com/itxtoledo/govpn/libs/logger v0.0.0
+	github.com/itxtoledo/govpn/libs/signaling v0.0.0
 	github.com/itxtoledo/govpn/libs/signaling/models v0.0.0
+	github.com/supabase-community/postgrest-go v0.0.11
 	github.com/supabase-community/supabase-go v0.0.4
-	go.uber.org/zap v1.27.0
+	google.golang.org/grpc v1.71.1
+	google.golang.org/protobuf v1.36.4
 )
 
 require (
@@ -27,8 +32,12 @@ require (
 	github.com/stretchr/testify v1.9.0 // indirect
 	github.com/supabase-community/functions-go v0.0.0-20220927045802-22373e6cb51d // indirect
 	github.com/supabase-community/gotrue-go v1.2.0 // indirect
-	github.com/supabase-community/postgrest-go v0.0.11 // indirect
 	github.com/supabase-community/storage-go v0.7.0 // indirect
 	github.com/tomnomnom/linkheader v0.0.0-20180905144013-02ca5825eb80 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
+	go.uber.org/zap v1.27.0 // indirect
+	golang.org/x/net v0.34.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250115164207-1a7da9e5054f // indirect
 )
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+	"github.com/itxtoledo/govpn/libs/utils"
+)
+
+// harnessTimeout bounds how long harness clients wait for a message
+const harnessTimeout = 5 * time.Second
+
+// errHarnessTimeout is returned when the awaited message does not arrive within harnessTimeout
+var errHarnessTimeout = errors.New("timed out")
+
+// inProcessServer is a signaling server on a local httptest listener backed by a MemoryStore.
+// Go tests use it to drive protocol flows (create, join, kick,
+// owner leaving) without Supabase. Background jobs are not started.
+type inProcessServer struct {
+	Server *WebSocketServer
+	Store  *MemoryStore
+	HTTP   *httptest.Server
+	URL    string // ws:// address of the /ws endpoint
+}
+
+// startInProcessServer starts a server with cfg on a random local port
+func startInProcessServer(cfg Config) (*inProcessServer, error) {
+	memory := NewMemoryStore()
+	return startInProcessServerWithStore(cfg, memory, memory)
+}
+
+// startInProcessServerWithStore starts a server that uses store, which keeps its data in
+// memory. store can wrap memory to make some calls fail.
+func startInProcessServerWithStore(cfg Config, memory *MemoryStore, store NetworkStore) (*inProcessServer, error) {
+	logger.Init()
+
+	server, err := NewWebSocketServerWithStore(cfg, store)
+	if err != nil {
+		return nil, err
+	}
+
+	httpServer := httptest.NewServer(server.Handler())
+	return &inProcessServer{
+		Server: server,
+		Store:  memory,
+		HTTP:   httpServer,
+		URL:    "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/ws",
+	}, nil
+}
+
+// Close stops the listener and closes every open connection
+func (p *inProcessServer) Close() {
+	p.HTTP.CloseClientConnections()
+	p.HTTP.Close()
+}
+
+// Dial connects a new client with a fresh key pair and answers the connection challenge
+func (p *inProcessServer) Dial() (*harnessClient, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return p.DialKey(privateKey)
+}
+
+// DialKey connects a client with an existing key, for example to reconnect a client
+func (p *inProcessServer) DialKey(privateKey ed25519.PrivateKey) (*harnessClient, error) {
+	client := &harnessClient{
+		PublicKey:  base64.StdEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+		privateKey: privateKey,
+	}
+
+	header := http.Header{}
+	var err error
+	header.Set("X-Client-ID", client.PublicKey)
+	client.conn, _, err = websocket.DefaultDialer.Dial(p.URL, header)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", p.URL, err)
+	}
+	client.incoming = make(chan smodels.SignalingMessage, 64)
+	go client.readLoop()
+
+	if err := client.authenticate(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// harnessClient is a bare signaling client for driving the server message by message.
+// Messages that arrive while waiting for something else are queued for Expect.
+type harnessClient struct {
+	PublicKey  string
+	privateKey ed25519.PrivateKey
+	conn       *websocket.Conn
+	incoming   chan smodels.SignalingMessage
+	readErr    error // Set before incoming is closed
+	pending    []smodels.SignalingMessage
+}
+
+// Close closes the connection, as if the client went away
+func (c *harnessClient) Close() {
+	c.conn.Close()
+}
+
+// Addr returns the address the server sees for this client
+func (c *harnessClient) Addr() string {
+	return c.conn.LocalAddr().String()
+}
+
+// authenticate signs the challenge sent on connect
+func (c *harnessClient) authenticate() error {
+	msg, err := c.Expect(smodels.TypeAuthChallenge)
+	if err != nil {
+		return err
+	}
+
+	var challenge smodels.AuthChallenge
+	if err := json.Unmarshal(msg.Payload, &challenge); err != nil {
+		return fmt.Errorf("decode challenge: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(challenge.Nonce)
+	if err != nil {
+		return fmt.Errorf("decode nonce: %w", err)
+	}
+
+	signature := ed25519.Sign(c.privateKey, smodels.AuthChallengeMessage(nonce))
+	_, err = c.Request(smodels.TypeAuthResponse, smodels.AuthResponseRequest{
+		BaseRequest: smodels.BaseRequest{PublicKey: c.PublicKey},
+		Signature:   base64.StdEncoding.EncodeToString(signature),
+	}, smodels.TypeAuthResult)
+	return err
+}
+
+// Send writes a request without waiting for its response and returns its message ID
+func (c *harnessClient) Send(msgType smodels.MessageType, payload interface{}) (string, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	id, err := utils.GenerateMessageID()
+	if err != nil {
+		return "", err
+	}
+
+	return id, c.conn.WriteJSON(smodels.SignalingMessage{ID: id, Type: msgType, Payload: payloadBytes})
+}
+
+// Request sends a request and waits for its response, which must be of type want.
+// An Error response is returned as an error carrying its code.
+func (c *harnessClient) Request(msgType smodels.MessageType, payload interface{}, want smodels.MessageType) (smodels.SignalingMessage, error) {
+	id, err := c.Send(msgType, payload)
+	if err != nil {
+		return smodels.SignalingMessage{}, err
+	}
+
+	msg, err := c.next(func(m smodels.SignalingMessage) bool { return m.ID == id })
+	if err != nil {
+		return msg, fmt.Errorf("%s: %w", msgType, err)
+	}
+
+	if msg.Type == smodels.TypeError {
+		var errPayload smodels.ErrorResponse
+		json.Unmarshal(msg.Payload, &errPayload)
+		return msg, fmt.Errorf("%s: %w", msgType, &serverError{Code: errPayload.Code, Message: errPayload.Error})
+	}
+	if msg.Type != want {
+		return msg, fmt.Errorf("%s: got %s, want %s", msgType, msg.Type, want)
+	}
+	return msg, nil
+}
+
+// serverError is an Error response to a harness request
+type serverError struct {
+	Code    smodels.ErrorCode
+	Message string
+}
+
+func (e *serverError) Error() string {
+	return fmt.Sprintf("server error %s: %s", e.Code, e.Message)
+}
+
+// errorCode returns the code of the Error response in err, "" if err is not one
+func errorCode(err error) smodels.ErrorCode {
+	var serverErr *serverError
+	if errors.As(err, &serverErr) {
+		return serverErr.Code
+	}
+	return ""
+}
+
+// Expect returns the first message of type msgType, queued or arriving within the timeout
+func (c *harnessClient) Expect(msgType smodels.MessageType) (smodels.SignalingMessage, error) {
+	msg, err := c.next(func(m smodels.SignalingMessage) bool { return m.Type == msgType })
+	if err != nil {
+		return msg, fmt.Errorf("waiting for %s: %w", msgType, err)
+	}
+	return msg, nil
+}
+
+// ExpectClosed waits until the server closes the connection, skipping any messages
+func (c *harnessClient) ExpectClosed() error {
+	_, err := c.next(func(smodels.SignalingMessage) bool { return false })
+	if errors.Is(err, errHarnessTimeout) {
+		return errors.New("connection still open")
+	}
+	return nil
+}
+
+// next returns the first message that matches, queueing the others it reads on the way
+func (c *harnessClient) next(match func(smodels.SignalingMessage) bool) (smodels.SignalingMessage, error) {
+	for i, msg := range c.pending {
+		if match(msg) {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return msg, nil
+		}
+	}
+
+	timeout := time.NewTimer(harnessTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case msg, ok := <-c.incoming:
+			if !ok {
+				return smodels.SignalingMessage{}, c.readErr
+			}
+			if match(msg) {
+				return msg, nil
+			}
+			c.pending = append(c.pending, msg)
+		case <-timeout.C:
+			return smodels.SignalingMessage{}, errHarnessTimeout
+		}
+	}
+}
+
+// readLoop hands every received message to next until the connection fails.
+// Reading in the background keeps a timed out wait from breaking the connection.
+func (c *harnessClient) readLoop() {
+	defer close(c.incoming)
+	for {
+		var msg smodels.SignalingMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			c.readErr = err
+			return
+		}
+		c.incoming <- msg
+	}
+}
+
+// CreateNetwork creates a network owned by the client
+func (c *harnessClient) CreateNetwork(name, pin string) (smodels.CreateNetworkResponse, error) {
+	var resp smodels.CreateNetworkResponse
+	msg, err := c.Request(smodels.TypeCreateNetwork, smodels.CreateNetworkRequest{
+		BaseRequest:  smodels.BaseRequest{PublicKey: c.PublicKey},
+		NetworkName:  name,
+		PIN:          pin,
+		ComputerName: "owner",
+	}, smodels.TypeNetworkCreated)
+	if err != nil {
+		return resp, err
+	}
+	return resp, json.Unmarshal(msg.Payload, &resp)
+}
+
+// JoinNetwork joins an existing network
+func (c *harnessClient) JoinNetwork(networkID, pin, computerName string) (smodels.JoinNetworkResponse, error) {
+	var resp smodels.JoinNetworkResponse
+	msg, err := c.Request(smodels.TypeJoinNetwork, smodels.JoinNetworkRequest{
+		BaseRequest:  smodels.BaseRequest{PublicKey: c.PublicKey},
+		NetworkID:    networkID,
+		PIN:          pin,
+		ComputerName: computerName,
+	}, smodels.TypeNetworkJoined)
+	if err != nil {
+		return resp, err
+	}
+	return resp, json.Unmarshal(msg.Payload, &resp)
+}
+
+// ConnectNetwork reconnects the client to a network it joined before. lastSequence is the
+// last network event the client saw, 0 for none.
+func (c *harnessClient) ConnectNetwork(networkID string, lastSequence uint64) error {
+	_, err := c.Request(smodels.TypeConnectNetwork, smodels.ConnectNetworkRequest{
+		BaseRequest:  smodels.BaseRequest{PublicKey: c.PublicKey},
+		NetworkID:    networkID,
+		LastSequence: lastSequence,
+	}, smodels.TypeNetworkConnected)
+	return err
+}
+
+// Kick removes another computer from a network the client owns.
+// The server identifies the target by the address of its connection, see Addr.
+func (c *harnessClient) Kick(networkID, targetID string) error {
+	_, err := c.Request(smodels.TypeKick, smodels.KickRequest{
+		BaseRequest: smodels.BaseRequest{PublicKey: c.PublicKey},
+		NetworkID:   networkID,
+		TargetID:    targetID,
+	}, smodels.TypeKickResponse)
+	return err
+}
+
+// LeaveNetwork leaves a network. When the owner leaves, the owner policy of the network applies.
+func (c *harnessClient) LeaveNetwork(networkID string) error {
+	_, err := c.Request(smodels.TypeLeaveNetwork, smodels.LeaveNetworkRequest{
+		BaseRequest: smodels.BaseRequest{PublicKey: c.PublicKey},
+		NetworkID:   networkID,
+	}, smodels.TypeLeaveNetwork)
+	return err
+}
+
+// Ban removes another computer from a network the client owns and keeps it from joining again
+func (c *harnessClient) Ban(networkID, targetPublicKey, reason string) error {
+	_, err := c.Request(smodels.TypeBan, smodels.BanRequest{
+		BaseRequest:     smodels.BaseRequest{PublicKey: c.PublicKey},
+		NetworkID:       networkID,
+		TargetPublicKey: targetPublicKey,
+		Reason:          reason,
+	}, smodels.TypeBanResponse)
+	return err
+}
+
+// ReserveIP keeps the IP of a member of a network the client owns, or releases it
+func (c *harnessClient) ReserveIP(networkID, targetPublicKey string, release bool) error {
+	_, err := c.Request(smodels.TypeReserveIP, smodels.ReserveIPRequest{
+		BaseRequest:     smodels.BaseRequest{PublicKey: c.PublicKey},
+		NetworkID:       networkID,
+		TargetPublicKey: targetPublicKey,
+		Release:         release,
+	}, smodels.TypeReserveIPResponse)
+	return err
+}
+
+// TransferOwnership hands a network the client owns to another member
+func (c *harnessClient) TransferOwnership(networkID, targetPublicKey string) error {
+	_, err := c.Request(smodels.TypeTransferOwnership, smodels.TransferOwnershipRequest{
+		BaseRequest:     smodels.BaseRequest{PublicKey: c.PublicKey},
+		NetworkID:       networkID,
+		TargetPublicKey: targetPublicKey,
+	}, smodels.TypeOwnershipTransferred)
+	return err
+}
+
+// ReportWakeInfo lets members wake the client's computer through a member on its LAN
+func (c *harnessClient) ReportWakeInfo(mac, subnet string) error {
+	_, err := c.Request(smodels.TypeWakeInfo, smodels.WakeInfoRequest{
+		BaseRequest: smodels.BaseRequest{PublicKey: c.PublicKey},
+		MAC:         mac,
+		Subnet:      subnet,
+	}, smodels.TypeWakeInfoResponse)
+	return err
+}
+
+// Wake asks the server to wake an offline member of the network the client is connected to
+func (c *harnessClient) Wake(networkID, targetPublicKey string) error {
+	_, err := c.Request(smodels.TypeWake, smodels.WakeRequest{
+		BaseRequest:     smodels.BaseRequest{PublicKey: c.PublicKey},
+		NetworkID:       networkID,
+		TargetPublicKey: targetPublicKey,
+	}, smodels.TypeWakeResponse)
+	return err
+}
+
+// ChangeSubnet moves a network the client owns to a subnet that overlaps none of avoid
+func (c *harnessClient) ChangeSubnet(networkID string, avoid []string) error {
+	_, err := c.Request(smodels.TypeChangeSubnet, smodels.ChangeSubnetRequest{
+		BaseRequest: smodels.BaseRequest{PublicKey: c.PublicKey},
+		NetworkID:   networkID,
+		Avoid:       avoid,
+	}, smodels.TypeChangeSubnetResponse)
+	return err
+}
+
+// ChangePIN replaces the PIN of a network the client owns
+func (c *harnessClient) ChangePIN(networkID, pin string) error {
+	_, err := c.Request(smodels.TypeChangePIN, smodels.ChangePINRequest{
+		BaseRequest: smodels.BaseRequest{PublicKey: c.PublicKey},
+		NetworkID:   networkID,
+		PIN:         pin,
+	}, smodels.TypePINChanged)
+	return err
+}
+
+// CreateRecoveryCode asks for a recovery code for the client's key
+func (c *harnessClient) CreateRecoveryCode() (string, error) {
+	msg, err := c.Request(smodels.TypeCreateRecoveryCode, smodels.CreateRecoveryCodeRequest{
+		BaseRequest: smodels.BaseRequest{PublicKey: c.PublicKey},
+	}, smodels.TypeRecoveryCodeCreated)
+	if err != nil {
+		return "", err
+	}
+	var resp smodels.RecoveryCodeResponse
+	return resp.RecoveryCode, json.Unmarshal(msg.Payload, &resp)
+}
+
+// MigrateKey moves the networks and memberships of old to the client's key, authorized by
+// the old private key, or by recoveryCode when it is not empty
+func (c *harnessClient) MigrateKey(old *harnessClient, recoveryCode string) (smodels.MigrateKeyResponse, error) {
+	req := smodels.MigrateKeyRequest{
+		BaseRequest:  smodels.BaseRequest{PublicKey: c.PublicKey},
+		OldPublicKey: old.PublicKey,
+		RecoveryCode: recoveryCode,
+	}
+	if recoveryCode == "" {
+		req.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(old.privateKey, smodels.KeyMigrationMessage(c.PublicKey)))
+	}
+
+	var resp smodels.MigrateKeyResponse
+	msg, err := c.Request(smodels.TypeMigrateKey, req, smodels.TypeKeyMigrated)
+	if err != nil {
+		return resp, err
+	}
+	return resp, json.Unmarshal(msg.Payload, &resp)
+}
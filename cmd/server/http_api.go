@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// serverCapabilities lists the optional protocol features this server supports
+var serverCapabilities = []string{
+	"network_members",
+	"error_codes",
+	"idempotency_keys",
+	"multiple_networks_per_owner",
+	"public_networks",
+	"expiry_warnings",
+	"owner_policy",
+	"signed_challenge_auth",
+	"key_migration",
+	"event_replay",
+	"notification_sequences",
+	"paged_computer_networks",
+	"client_ip_info",
+	"nat_report",
+	"network_stats",
+	"maintenance_mode",
+	"server_notices",
+	"single_session",
+	"pin_rotation",
+	"network_roster",
+	"relay_fallback",
+	"member_management",
+}
+
+// registerAPIRoutes adds the plain HTTP API used by clients before opening the signaling socket
+func (s *WebSocketServer) registerAPIRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/networks/{id}/exists", s.handleNetworkExistsEndpoint)
+	mux.HandleFunc("GET /api/server-info", s.handleServerInfoEndpoint)
+	mux.HandleFunc("GET /ping", handlePingEndpoint)
+	mux.HandleFunc("GET /version", handleVersionEndpoint)
+}
+
+// handlePingEndpoint answers as cheaply as possible so clients can time the round trip
+// to each server of a server list
+func handlePingEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleNetworkExistsEndpoint lets a client validate an invite code without a WebSocket.
+// Only the name and whether the network is full are revealed, never the PIN.
+func (s *WebSocketServer) handleNetworkExistsEndpoint(w http.ResponseWriter, r *http.Request) {
+	networkID := r.PathValue("id")
+	response := smodels.NetworkExistsResponse{NetworkID: networkID}
+
+	if len(networkID) > fieldMaxLengths["network_id"] {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid network id"})
+		return
+	}
+
+	exists, err := s.store.NetworkExists(networkID)
+	if err != nil {
+		logger.Error("Error checking if network exists", "networkID", networkID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to check network"})
+		return
+	}
+
+	if exists {
+		network, err := s.store.GetNetwork(networkID)
+		if err == nil {
+			s.mu.RLock()
+			response.IsFull = len(s.networks[networkID]) >= s.config.MaxClientsPerNetwork
+			s.mu.RUnlock()
+			response.NetworkName = network.Name
+		}
+		response.Exists = true
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleServerInfoEndpoint reports the version, capabilities, limits and current load of the server
+func (s *WebSocketServer) handleServerInfoEndpoint(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	response := smodels.ServerInfoResponse{
+		Version:              serverVersion,
+		Capabilities:         serverCapabilities,
+		MaxClientsPerNetwork: s.config.MaxClientsPerNetwork,
+		MaxNetworksPerOwner:  s.config.MaxNetworksPerOwner,
+		ActiveConnections:    len(s.clients),
+		ActiveNetworks:       len(s.networks),
+		Maintenance:          s.config.MaintenanceMode,
+	}
+	s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("Failed to write JSON response", "error", err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// idempotentResponse is a response remembered for a client-supplied idempotency key
+type idempotentResponse struct {
+	msgType smodels.MessageType
+	payload json.RawMessage
+}
+
+// idempotencyCacheKey scopes idempotency keys to the public key that sent them
+func idempotencyCacheKey(publicKey, key string) string {
+	return publicKey + "|" + key
+}
+
+// replayIdempotent resends the stored response for a repeated idempotency key.
+// It returns false when there is nothing to replay and the request must be processed.
+func (s *WebSocketServer) replayIdempotent(conn *websocket.Conn, publicKey, key, originalID string) bool {
+	if key == "" || publicKey == "" {
+		return false
+	}
+
+	cached, ok := s.idempotencyCache.Get(idempotencyCacheKey(publicKey, key))
+	if !ok {
+		return false
+	}
+
+	logger.Info("Replaying response for repeated idempotency key", "publicKey", publicKey, "idempotencyKey", key, "type", cached.msgType)
+	s.sendSignal(conn, cached.msgType, cached.payload, originalID)
+	return true
+}
+
+// rememberIdempotent stores a successful response under the client's idempotency key
+func (s *WebSocketServer) rememberIdempotent(publicKey, key string, msgType smodels.MessageType, payload interface{}) {
+	if key == "" || publicKey == "" {
+		return
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("Failed to marshal response for idempotency cache", "error", err, "type", msgType)
+		return
+	}
+
+	s.idempotencyCache.Set(idempotencyCacheKey(publicKey, key), idempotentResponse{
+		msgType: msgType,
+		payload: payloadBytes,
+	})
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+)
+
+// jobJitter is the fraction of its interval by which each run of a job is randomly
+// shifted, so jobs with equal intervals and several server instances don't run in lockstep
+const jobJitter = 0.1
+
+const (
+	jobStaleNetworks = "stale_networks"
+	jobStaleMembers  = "stale_members"
+	jobStatsFlush    = "stats_flush"
+	jobWakeTargets   = "wake_targets"
+
+	staleMembersInterval = 5 * time.Minute
+	statsFlushInterval   = time.Minute
+	wakeTargetsInterval  = time.Hour
+
+	// eventLogIdleTTL is how long the event log of a network without connections is kept
+	eventLogIdleTTL = time.Hour
+)
+
+// JobStats summarizes the runs of a periodic job
+type JobStats struct {
+	Interval       string    `json:"interval"`
+	Runs           int64     `json:"runs"`
+	Failures       int64     `json:"failures"`
+	LastRun        time.Time `json:"last_run"`
+	LastDurationMs float64   `json:"last_duration_ms"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+// periodicJob is a task run by the scheduler every interval
+type periodicJob struct {
+	name     string
+	run      func() error
+	interval time.Duration
+	reset    chan time.Duration
+	stats    JobStats
+}
+
+// jobScheduler runs the background maintenance tasks of the server,
+// each in its own goroutine, until it is stopped
+type jobScheduler struct {
+	mu       sync.Mutex
+	jobs     []*periodicJob
+	started  bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func newJobScheduler() *jobScheduler {
+	return &jobScheduler{stop: make(chan struct{})}
+}
+
+// Register adds a job. Jobs registered after Start are not run.
+func (js *jobScheduler) Register(name string, interval time.Duration, run func() error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	js.jobs = append(js.jobs, &periodicJob{
+		name:     name,
+		run:      run,
+		interval: interval,
+		reset:    make(chan time.Duration, 1),
+		stats:    JobStats{Interval: interval.String()},
+	})
+}
+
+// Start launches every registered job
+func (js *jobScheduler) Start() {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	if js.started {
+		return
+	}
+	js.started = true
+
+	for _, job := range js.jobs {
+		js.wg.Add(1)
+		go js.loop(job)
+	}
+	logger.Info("Background jobs started", "jobs", len(js.jobs))
+}
+
+// Stop signals every job to exit and waits for runs in progress to finish
+func (js *jobScheduler) Stop() {
+	js.stopOnce.Do(func() {
+		close(js.stop)
+	})
+	js.wg.Wait()
+}
+
+// SetInterval changes how often a job runs, starting from now
+func (js *jobScheduler) SetInterval(name string, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	for _, job := range js.jobs {
+		if job.name != name {
+			continue
+		}
+		job.stats.Interval = interval.String()
+
+		// Drop a pending reset that was not consumed yet, only the latest interval matters
+		select {
+		case <-job.reset:
+		default:
+		}
+		job.reset <- interval
+		return
+	}
+}
+
+// Stats returns the metrics of every job by name
+func (js *jobScheduler) Stats() map[string]JobStats {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	result := make(map[string]JobStats, len(js.jobs))
+	for _, job := range js.jobs {
+		result[job.name] = job.stats
+	}
+	return result
+}
+
+// loop runs a job every interval, give or take the jitter, until the scheduler stops
+func (js *jobScheduler) loop(job *periodicJob) {
+	defer js.wg.Done()
+
+	interval := job.interval
+	timer := time.NewTimer(jittered(interval))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			js.runOnce(job)
+			timer.Reset(jittered(interval))
+		case interval = <-job.reset:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(jittered(interval))
+		case <-js.stop:
+			return
+		}
+	}
+}
+
+// runOnce runs a job and records its outcome
+func (js *jobScheduler) runOnce(job *periodicJob) {
+	start := time.Now()
+	err := job.run()
+	duration := time.Since(start)
+
+	js.mu.Lock()
+	job.stats.Runs++
+	job.stats.LastRun = start
+	job.stats.LastDurationMs = durationMs(duration)
+	job.stats.LastError = ""
+	if err != nil {
+		job.stats.Failures++
+		job.stats.LastError = err.Error()
+	}
+	js.mu.Unlock()
+
+	if err != nil {
+		logger.Error("Background job failed", "job", job.name, "duration", duration, "error", err)
+		return
+	}
+	logger.Debug("Background job finished", "job", job.name, "duration", duration)
+}
+
+// jittered returns d shifted randomly by up to jobJitter of its length in either direction
+func jittered(d time.Duration) time.Duration {
+	spread := int64(float64(d) * jobJitter)
+	if spread <= 0 {
+		return d
+	}
+	return d - time.Duration(spread) + time.Duration(rand.Int63n(2*spread+1))
+}
+
+// registerJobs adds the maintenance tasks of the server to its scheduler
+func (s *WebSocketServer) registerJobs() {
+	s.mu.RLock()
+	cleanupInterval := s.config.CleanupInterval
+	s.mu.RUnlock()
+
+	s.jobs.Register(jobStaleNetworks, cleanupInterval, s.DeleteStaleNetworks)
+	s.jobs.Register(jobStaleMembers, staleMembersInterval, s.pruneStaleMembers)
+	s.jobs.Register(jobStatsFlush, statsFlushInterval, s.flushStats)
+	s.jobs.Register(jobWakeTargets, wakeTargetsInterval, s.pruneWakeTargets)
+}
+
+// pruneStaleMembers drops offline computers from the in-memory presence maps and
+// forgets the event logs of networks nobody has been connected to for a while.
+// A client reconnecting after its network's log was dropped gets a full member list.
+func (s *WebSocketServer) pruneStaleMembers() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	membersRemoved := 0
+	for networkID, computers := range s.connectedComputers {
+		for publicKey, online := range computers {
+			if !online {
+				delete(computers, publicKey)
+				membersRemoved++
+			}
+		}
+		if len(computers) == 0 {
+			delete(s.connectedComputers, networkID)
+		}
+	}
+
+	logsRemoved := 0
+	cutoff := time.Now().Add(-eventLogIdleTTL)
+	for networkID, log := range s.eventLogs {
+		if _, active := s.networks[networkID]; !active && log.updated.Before(cutoff) {
+			delete(s.eventLogs, networkID)
+			logsRemoved++
+		}
+	}
+
+	if membersRemoved > 0 || logsRemoved > 0 {
+		logger.Info("Pruned stale members", "offlineMembers", membersRemoved, "eventLogs", logsRemoved)
+	}
+	return nil
+}
+
+// flushStats refreshes the gauges and uptime reported by /stats without waiting for a request
+func (s *WebSocketServer) flushStats() error {
+	s.mu.RLock()
+	activeConnections := len(s.clients)
+	activeNetworks := len(s.networks)
+	s.mu.RUnlock()
+
+	s.statsManager.UpdateStats(activeConnections, activeNetworks)
+	return nil
+}
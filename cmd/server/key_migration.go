@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// recoveryCodeSize is the number of random bytes in a recovery code
+const recoveryCodeSize = 20
+
+// newRecoveryCode returns a random code formatted in groups of four characters
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:min(i+4, len(encoded))])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring case, spaces and dashes
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// handleCreateRecoveryCode creates a recovery code for the authenticated key of the connection
+func (s *WebSocketServer) handleCreateRecoveryCode(ctx context.Context, conn *websocket.Conn, req smodels.CreateRecoveryCodeRequest, originalID string) {
+	publicKey, authenticated := s.authenticatedKey(conn)
+	if !authenticated {
+		s.sendErrorSignal(conn, smodels.ErrUnauthenticated, "Answer the authentication challenge first", originalID)
+		return
+	}
+
+	code, err := newRecoveryCode()
+	if err != nil {
+		logger.Error("Error generating recovery code", "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error generating recovery code", originalID)
+		return
+	}
+
+	if err := s.store.SetRecoveryCodeHash(publicKey, hashRecoveryCode(code)); err != nil {
+		logger.Error("Error storing recovery code", "publicKey", publicKey, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error storing recovery code", originalID)
+		return
+	}
+
+	logger.Info("Recovery code created", "publicKey", publicKey)
+	s.sendSignal(conn, smodels.TypeRecoveryCodeCreated, smodels.RecoveryCodeResponse{
+		PublicKey:    publicKey,
+		RecoveryCode: code,
+	}, originalID)
+}
+
+// handleMigrateKey moves the networks and memberships of an old key to the authenticated
+// key of the connection, then revokes the old key and closes its connections
+func (s *WebSocketServer) handleMigrateKey(ctx context.Context, conn *websocket.Conn, req smodels.MigrateKeyRequest, originalID string) {
+	newKey, authenticated := s.authenticatedKey(conn)
+	if !authenticated {
+		s.sendErrorSignal(conn, smodels.ErrUnauthenticated, "Answer the authentication challenge first", originalID)
+		return
+	}
+
+	if req.OldPublicKey == "" || req.OldPublicKey == newKey {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Old public key must be set and differ from the new one", originalID)
+		return
+	}
+
+	oldKeyBytes, err := base64.StdEncoding.DecodeString(req.OldPublicKey)
+	if err != nil || len(oldKeyBytes) != ed25519.PublicKeySize {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid old public key", originalID)
+		return
+	}
+
+	revoked, err := s.store.IsKeyRevoked(req.OldPublicKey)
+	if err != nil {
+		logger.Error("Error checking revoked key", "publicKey", req.OldPublicKey, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error checking old key", originalID)
+		return
+	}
+	if revoked {
+		s.sendErrorSignal(conn, smodels.ErrKeyRevoked, "Old key was already migrated", originalID)
+		return
+	}
+
+	switch {
+	case req.Signature != "":
+		signature, err := base64.StdEncoding.DecodeString(req.Signature)
+		if err != nil || !ed25519.Verify(ed25519.PublicKey(oldKeyBytes), smodels.KeyMigrationMessage(newKey), signature) {
+			s.sendErrorSignal(conn, smodels.ErrAuthFailed, "Invalid signature of the old key", originalID)
+			return
+		}
+	case req.RecoveryCode != "":
+		storedHash, err := s.store.GetRecoveryCodeHash(req.OldPublicKey)
+		if err != nil {
+			logger.Error("Error fetching recovery code", "publicKey", req.OldPublicKey, "error", err)
+			s.sendErrorSignal(conn, smodels.ErrInternal, "Error checking recovery code", originalID)
+			return
+		}
+		if storedHash == "" || subtle.ConstantTimeCompare([]byte(storedHash), []byte(hashRecoveryCode(req.RecoveryCode))) != 1 {
+			s.sendErrorSignal(conn, smodels.ErrAuthFailed, "Invalid recovery code", originalID)
+			return
+		}
+	default:
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "A signature or recovery code is required", originalID)
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "migrate key") {
+		return
+	}
+
+	networks, memberships, err := s.store.MigratePublicKey(req.OldPublicKey, newKey)
+	if err != nil {
+		logger.Error("Error migrating public key", "oldKey", req.OldPublicKey, "newKey", newKey, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error migrating key", originalID)
+		return
+	}
+
+	s.closeConnectionsOf(req.OldPublicKey)
+
+	logger.Info("Public key migrated", "oldKey", req.OldPublicKey, "newKey", newKey, "networks", networks, "memberships", memberships)
+	s.sendSignal(conn, smodels.TypeKeyMigrated, smodels.MigrateKeyResponse{
+		OldPublicKey: req.OldPublicKey,
+		NewPublicKey: newKey,
+		Networks:     networks,
+		Memberships:  memberships,
+	}, originalID)
+}
+
+// closeConnectionsOf closes every connection identified by a revoked key.
+// Their read loops then run the usual disconnect cleanup.
+func (s *WebSocketServer) closeConnectionsOf(publicKey string) {
+	var conns []*websocket.Conn
+
+	s.sessionsMu.Lock()
+	for c, session := range s.sessions {
+		if session.publicKey == publicKey || session.claimedKey == publicKey {
+			conns = append(conns, c)
+		}
+	}
+	s.sessionsMu.Unlock()
+
+	s.mu.RLock()
+	for c, pk := range s.clientToPublicKey {
+		if pk == publicKey {
+			conns = append(conns, c)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, c := range conns {
+		logger.Info("Closing connection of revoked key", "remoteAddr", c.RemoteAddr().String(), "publicKey", publicKey)
+		s.closeConn(c)
+	}
+}
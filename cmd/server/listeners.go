@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// listenerSpec is one address the server accepts connections on, parsed from LISTEN.
+// Entries are host:port, tcp4://host:port, tcp6://host:port or unix:///path/to.sock;
+// adding ?cert=file&key=file serves TLS on that listener only.
+type listenerSpec struct {
+	network  string // tcp, tcp4, tcp6 or unix
+	address  string
+	certFile string
+	keyFile  string
+}
+
+// String formats the spec for logs, without the key file
+func (l listenerSpec) String() string {
+	s := l.network + "://" + l.address
+	if l.certFile != "" {
+		s += " (TLS)"
+	}
+	return s
+}
+
+// parseListenerSpecs parses a comma-separated LISTEN value. Empty entries are skipped.
+func parseListenerSpecs(list string) ([]listenerSpec, error) {
+	var specs []listenerSpec
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		spec, err := parseListenerSpec(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", entry, err)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// parseListenerSpec parses a single LISTEN entry
+func parseListenerSpec(entry string) (listenerSpec, error) {
+	if !strings.Contains(entry, "://") {
+		if path, ok := strings.CutPrefix(entry, "unix:"); ok {
+			entry = "unix://" + path
+		} else {
+			entry = "tcp://" + entry
+		}
+	}
+
+	u, err := url.Parse(entry)
+	if err != nil {
+		return listenerSpec{}, err
+	}
+
+	spec := listenerSpec{
+		network:  u.Scheme,
+		certFile: u.Query().Get("cert"),
+		keyFile:  u.Query().Get("key"),
+	}
+
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		if _, _, err := net.SplitHostPort(u.Host); err != nil {
+			return listenerSpec{}, err
+		}
+		spec.address = u.Host
+	case "unix":
+		spec.address = u.Host + u.Path
+		if spec.address == "" {
+			return listenerSpec{}, errors.New("missing socket path")
+		}
+	default:
+		return listenerSpec{}, fmt.Errorf("unknown network %q", u.Scheme)
+	}
+
+	if (spec.certFile == "") != (spec.keyFile == "") {
+		return listenerSpec{}, errors.New("TLS needs both cert and key")
+	}
+	return spec, nil
+}
+
+// listen opens the listener, wrapped in TLS when the spec has a certificate
+func (l listenerSpec) listen() (net.Listener, error) {
+	if l.network == "unix" {
+		if err := removeStaleSocket(l.address); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen(l.network, l.address)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.certFile == "" {
+		return ln, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("loading TLS certificate of %s: %w", l, err)
+	}
+	return tls.NewListener(ln, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+// removeStaleSocket deletes a socket file left behind by a previous run.
+// A socket something still answers on is left alone and the listen fails.
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+	return os.Remove(path)
+}
+
+// openListeners opens every listener, closing the ones already open if any fails
+func openListeners(specs []listenerSpec) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(specs))
+	for _, spec := range specs {
+		ln, err := spec.listen()
+		if err != nil {
+			for _, open := range listeners {
+				open.Close()
+			}
+			return nil, fmt.Errorf("listening on %s: %w", spec, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// rejectDuringMaintenance answers a request that would add a network or a member with a
+// maintenance error and reports whether it did. Connected clients keep working, so the
+// server can be drained before a rolling upgrade. Callers must hold the server lock.
+func (s *WebSocketServer) rejectDuringMaintenance(conn *websocket.Conn, originalID string) bool {
+	if !s.config.MaintenanceMode {
+		return false
+	}
+	s.sendErrorSignal(conn, smodels.ErrMaintenance, "Server is in maintenance mode, try again later or use another server", originalID)
+	return true
+}
+
+// SetMaintenanceMode turns maintenance mode on or off until the next configuration reload
+func (s *WebSocketServer) SetMaintenanceMode(enabled bool) {
+	s.mu.Lock()
+	changed := s.config.MaintenanceMode != enabled
+	s.config.MaintenanceMode = enabled
+	s.mu.Unlock()
+
+	if changed {
+		logger.Info("Maintenance mode changed", "enabled", enabled)
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// maxBanReasonLength (in characters) matches the reason column of network_bans
+const maxBanReasonLength = 255
+
+// requireOwner fetches a network and checks that conn belongs to its owner, answering
+// originalID with an error otherwise. action completes "Only network owner can ...".
+// Callers must hold the server lock.
+func (s *WebSocketServer) requireOwner(conn *websocket.Conn, networkID, requestPublicKey, action, originalID string) (SupabaseNetwork, bool) {
+	network, err := s.store.GetNetwork(networkID)
+	if err != nil {
+		s.sendErrorSignal(conn, smodels.ErrNetworkNotFound, "Network does not exist", originalID)
+		return SupabaseNetwork{}, false
+	}
+
+	publicKey, hasPublicKey := s.clientToPublicKey[conn]
+	if !hasPublicKey {
+		publicKey = requestPublicKey
+	}
+	if publicKey == "" || publicKey != network.OwnerPublicKey {
+		s.sendErrorSignal(conn, smodels.ErrNotOwner, "Only network owner can "+action, originalID)
+		return SupabaseNetwork{}, false
+	}
+	return network, true
+}
+
+// requireTargetMember checks that targetPublicKey is a member of the network other than the
+// owner, answering originalID with an error otherwise. Callers must hold the server lock.
+func (s *WebSocketServer) requireTargetMember(conn *websocket.Conn, network SupabaseNetwork, targetPublicKey, originalID string) (ComputerNetwork, bool) {
+	if targetPublicKey == "" {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Target public key is required", originalID)
+		return ComputerNetwork{}, false
+	}
+	if targetPublicKey == network.OwnerPublicKey {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "The owner cannot be the target", originalID)
+		return ComputerNetwork{}, false
+	}
+
+	computer, err := s.store.GetComputerInNetwork(network.ID, targetPublicKey)
+	if err != nil {
+		s.sendErrorSignal(conn, smodels.ErrTargetNotFound, "Target is not a member of the network", originalID)
+		return ComputerNetwork{}, false
+	}
+	return computer, true
+}
+
+// kickMember removes the membership of publicKey and, when it is connected, tells it why
+// and closes its connection. It reports whether the computer was connected.
+// Callers must hold the server lock.
+func (s *WebSocketServer) kickMember(networkID, publicKey, reason string) (bool, error) {
+	if err := s.store.RemoveComputerFromNetwork(networkID, publicKey); err != nil {
+		return false, err
+	}
+
+	var target *websocket.Conn
+	for _, computer := range s.networks[networkID] {
+		if s.clientToPublicKey[computer] == publicKey {
+			target = computer
+			break
+		}
+	}
+
+	if computers, ok := s.connectedComputers[networkID]; ok {
+		delete(computers, publicKey)
+	}
+
+	wasOnline := target != nil
+	if wasOnline {
+		s.sendSignal(target, smodels.TypeKicked, smodels.KickedNotification{
+			NetworkID: networkID,
+			Reason:    reason,
+		}, "")
+
+		s.closeConn(target)
+		s.removeClient(target, networkID)
+
+		s.broadcastNetworkEvent(networkID, smodels.TypeComputerDisconnected, smodels.ComputerDisconnectedNotification{
+			NetworkID: networkID,
+			PublicKey: publicKey,
+		}, nil)
+		s.statsManager.UpdateStats(len(s.clients), len(s.networks))
+	}
+	s.broadcastRoster(networkID)
+	return wasOnline, nil
+}
+
+// handleKickByPublicKey removes a member from the network; it needs the PIN to join again.
+// Callers must hold the server lock.
+func (s *WebSocketServer) handleKickByPublicKey(ctx context.Context, conn *websocket.Conn, req smodels.KickRequest, originalID string) {
+	network, ok := s.requireOwner(conn, req.NetworkID, req.PublicKey, "kick computers", originalID)
+	if !ok {
+		return
+	}
+	if _, ok := s.requireTargetMember(conn, network, req.TargetPublicKey, originalID); !ok {
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "kick") {
+		return
+	}
+
+	wasOnline, err := s.kickMember(req.NetworkID, req.TargetPublicKey, "")
+	if err != nil {
+		logger.Error("Error kicking computer", "networkID", req.NetworkID, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error removing computer from network", originalID)
+		return
+	}
+
+	logger.Info("Computer kicked from network", "networkID", req.NetworkID, "publicKey", req.TargetPublicKey, "wasOnline", wasOnline)
+	s.sendSignal(conn, smodels.TypeKickResponse, smodels.KickResponse{
+		NetworkID:       req.NetworkID,
+		TargetPublicKey: req.TargetPublicKey,
+	}, originalID)
+}
+
+// handleBan removes a member from the network and keeps its public key from joining again
+func (s *WebSocketServer) handleBan(ctx context.Context, conn *websocket.Conn, req smodels.BanRequest, originalID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	network, ok := s.requireOwner(conn, req.NetworkID, req.PublicKey, "ban computers", originalID)
+	if !ok {
+		return
+	}
+	if _, ok := s.requireTargetMember(conn, network, req.TargetPublicKey, originalID); !ok {
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "ban") {
+		return
+	}
+
+	// Banned first, so a failure leaves the computer in the network rather than free to rejoin
+	if err := s.store.BanComputer(req.NetworkID, req.TargetPublicKey, req.Reason); err != nil {
+		logger.Error("Error banning computer", "networkID", req.NetworkID, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error banning computer", originalID)
+		return
+	}
+	if err := s.store.ReleaseIP(req.NetworkID, req.TargetPublicKey); err != nil {
+		logger.Warn("Error releasing the IP of a banned computer", "networkID", req.NetworkID, "error", err)
+	}
+
+	wasOnline, err := s.kickMember(req.NetworkID, req.TargetPublicKey, req.Reason)
+	if err != nil {
+		logger.Error("Error removing banned computer", "networkID", req.NetworkID, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error removing computer from network", originalID)
+		return
+	}
+
+	logger.Info("Computer banned from network", "networkID", req.NetworkID, "publicKey", req.TargetPublicKey, "wasOnline", wasOnline)
+	s.sendSignal(conn, smodels.TypeBanResponse, smodels.BanResponse{
+		NetworkID:       req.NetworkID,
+		TargetPublicKey: req.TargetPublicKey,
+	}, originalID)
+}
+
+// handleReserveIP keeps the current IP of a member for it, or releases it
+func (s *WebSocketServer) handleReserveIP(ctx context.Context, conn *websocket.Conn, req smodels.ReserveIPRequest, originalID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	network, ok := s.requireOwner(conn, req.NetworkID, req.PublicKey, "reserve IPs", originalID)
+	if !ok {
+		return
+	}
+
+	// The owner may reserve its own IP too, so the target is not checked with requireTargetMember
+	if req.TargetPublicKey == "" {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Target public key is required", originalID)
+		return
+	}
+	computer, err := s.store.GetComputerInNetwork(network.ID, req.TargetPublicKey)
+	if err != nil {
+		s.sendErrorSignal(conn, smodels.ErrTargetNotFound, "Target is not a member of the network", originalID)
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "reserve IP") {
+		return
+	}
+
+	if req.Release {
+		err = s.store.ReleaseIP(req.NetworkID, req.TargetPublicKey)
+	} else {
+		err = s.store.ReserveIP(req.NetworkID, req.TargetPublicKey, computer.PeerIP)
+	}
+	if err != nil {
+		logger.Error("Error changing IP reservation", "networkID", req.NetworkID, "release", req.Release, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error changing IP reservation", originalID)
+		return
+	}
+
+	logger.Info("IP reservation changed", "networkID", req.NetworkID, "publicKey", req.TargetPublicKey, "ip", computer.PeerIP, "reserved", !req.Release)
+	s.sendSignal(conn, smodels.TypeReserveIPResponse, smodels.ReserveIPResponse{
+		NetworkID:       req.NetworkID,
+		TargetPublicKey: req.TargetPublicKey,
+		IP:              computer.PeerIP,
+		Reserved:        !req.Release,
+	}, originalID)
+	s.broadcastRoster(req.NetworkID)
+}
+
+// handleTransferOwnership hands the network to another member chosen by the owner
+func (s *WebSocketServer) handleTransferOwnership(ctx context.Context, conn *websocket.Conn, req smodels.TransferOwnershipRequest, originalID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	network, ok := s.requireOwner(conn, req.NetworkID, req.PublicKey, "transfer ownership", originalID)
+	if !ok {
+		return
+	}
+	if _, ok := s.requireTargetMember(conn, network, req.TargetPublicKey, originalID); !ok {
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "transfer ownership") {
+		return
+	}
+
+	if err := s.handOverNetwork(network, req.TargetPublicKey); err != nil {
+		logger.Error("Error transferring network ownership", "networkID", req.NetworkID, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error transferring ownership", originalID)
+		return
+	}
+
+	s.sendSignal(conn, smodels.TypeOwnershipTransferred, smodels.TransferOwnershipResponse{
+		NetworkID:      req.NetworkID,
+		OwnerPublicKey: req.TargetPublicKey,
+	}, originalID)
+	s.broadcastRoster(req.NetworkID)
+}
+
+// handleChangeSubnet moves the network to another /24 that overlaps none of the local
+// networks the owner's computer reported, renumbering every member
+func (s *WebSocketServer) handleChangeSubnet(ctx context.Context, conn *websocket.Conn, req smodels.ChangeSubnetRequest, originalID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	network, ok := s.requireOwner(conn, req.NetworkID, req.PublicKey, "change the subnet", originalID)
+	if !ok {
+		return
+	}
+	if len(req.Avoid) > maxAvoidedSubnets {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Too many subnets to avoid", originalID)
+		return
+	}
+
+	subnet, err := pickSubnet(networkSubnet(network).String(), req.Avoid)
+	if errors.Is(err, errNoFreeSubnet) {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Every subnet overlaps one to avoid", originalID)
+		return
+	}
+	if err != nil {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, err.Error(), originalID)
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "change subnet") {
+		return
+	}
+
+	if err := s.store.ChangeNetworkSubnet(req.NetworkID, subnet); err != nil {
+		logger.Error("Error changing network subnet", "networkID", req.NetworkID, "subnet", subnet, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error changing subnet", originalID)
+		return
+	}
+
+	logger.Info("Network subnet changed", "networkID", req.NetworkID, "subnet", subnet)
+	s.sendSignal(conn, smodels.TypeChangeSubnetResponse, smodels.ChangeSubnetResponse{
+		NetworkID: req.NetworkID,
+		Subnet:    subnet,
+	}, originalID)
+	s.broadcastNetworkEvent(req.NetworkID, smodels.TypeNetworkSubnetChanged, smodels.NetworkSubnetChangedNotification{
+		NetworkID: req.NetworkID,
+		Subnet:    subnet,
+	}, nil)
+	s.broadcastRoster(req.NetworkID)
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a NetworkStore that keeps all data in process.
+// Nothing survives a restart, so it is meant for local development and tests.
+type MemoryStore struct {
+	mu            sync.RWMutex
+	networks      map[string]SupabaseNetwork
+	computers     []ComputerNetwork
+	nextID        int
+	recoveryCodes map[string]string
+	revokedKeys   map[string]string            // Revoked key -> key that replaced it
+	bans          map[string]map[string]string // Network -> banned key -> reason
+	reservedIPs   map[string]map[string]string // Network -> public key -> reserved IP
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		networks:      make(map[string]SupabaseNetwork),
+		recoveryCodes: make(map[string]string),
+		revokedKeys:   make(map[string]string),
+		bans:          make(map[string]map[string]string),
+		reservedIPs:   make(map[string]map[string]string),
+	}
+}
+
+// CreateNetwork stores a new network
+func (ms *MemoryStore) CreateNetwork(network SupabaseNetwork) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return ms.createNetworkLocked(network)
+}
+
+// CreateNetworkWithOwner stores a network and its owner's membership, or neither
+func (ms *MemoryStore) CreateNetworkWithOwner(network SupabaseNetwork, computerName, peerIP string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if err := ms.createNetworkLocked(network); err != nil {
+		return err
+	}
+	if err := ms.addComputerLocked(network.ID, network.OwnerPublicKey, computerName, peerIP); err != nil {
+		delete(ms.networks, network.ID)
+		return err
+	}
+	return nil
+}
+
+// createNetworkLocked stores a network. Callers must hold the lock.
+func (ms *MemoryStore) createNetworkLocked(network SupabaseNetwork) error {
+	if _, exists := ms.networks[network.ID]; exists {
+		return fmt.Errorf("failed to create network: duplicate id %s", network.ID)
+	}
+	if network.Tags == nil {
+		network.Tags = []string{}
+	}
+	ms.networks[network.ID] = network
+	return nil
+}
+
+// GetNetwork returns a network by its ID
+func (ms *MemoryStore) GetNetwork(networkID string) (SupabaseNetwork, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	network, ok := ms.networks[networkID]
+	if !ok {
+		return SupabaseNetwork{}, fmt.Errorf("network not found: %s", networkID)
+	}
+	return network, nil
+}
+
+// NetworkExists checks if a network exists with the given ID
+func (ms *MemoryStore) NetworkExists(networkID string) (bool, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	_, ok := ms.networks[networkID]
+	return ok, nil
+}
+
+// updateNetwork applies change to a stored network
+func (ms *MemoryStore) updateNetwork(networkID string, change func(*SupabaseNetwork)) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	network, ok := ms.networks[networkID]
+	if !ok {
+		return nil // Like an UPDATE matching no rows
+	}
+	change(&network)
+	ms.networks[networkID] = network
+	return nil
+}
+
+// UpdateNetworkActivity updates the last activity of a network
+func (ms *MemoryStore) UpdateNetworkActivity(networkID string) error {
+	return ms.updateNetwork(networkID, func(n *SupabaseNetwork) {
+		n.LastActive = time.Now()
+	})
+}
+
+// UpdateNetworkName renames a network
+func (ms *MemoryStore) UpdateNetworkName(networkID, newName string) error {
+	return ms.updateNetwork(networkID, func(n *SupabaseNetwork) {
+		n.Name = newName
+		n.LastActive = time.Now()
+	})
+}
+
+// UpdateNetworkOwner transfers a network to another public key
+func (ms *MemoryStore) UpdateNetworkOwner(networkID, ownerPublicKey string) error {
+	return ms.updateNetwork(networkID, func(n *SupabaseNetwork) {
+		n.OwnerPublicKey = ownerPublicKey
+		n.LastActive = time.Now()
+	})
+}
+
+// UpdateNetworkOwnerPolicy changes what happens to a network when its owner disconnects
+func (ms *MemoryStore) UpdateNetworkOwnerPolicy(networkID, policy string) error {
+	return ms.updateNetwork(networkID, func(n *SupabaseNetwork) {
+		n.OwnerPolicy = policy
+	})
+}
+
+// UpdateNetworkPIN replaces the PIN new members must give to join a network
+func (ms *MemoryStore) UpdateNetworkPIN(networkID, pin string) error {
+	return ms.updateNetwork(networkID, func(n *SupabaseNetwork) {
+		n.PIN = pin
+	})
+}
+
+// ChangeNetworkSubnet moves a network to another /24, renumbering the IPs of its members
+// and its reservations to the same last octet in the new subnet
+func (ms *MemoryStore) ChangeNetworkSubnet(networkID, subnet string) error {
+	_, parsed, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return fmt.Errorf("failed to change subnet: %w", err)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	network, ok := ms.networks[networkID]
+	if !ok {
+		return fmt.Errorf("failed to change subnet: network %s does not exist", networkID)
+	}
+	network.Subnet = parsed.String()
+	ms.networks[networkID] = network
+
+	for i := range ms.computers {
+		if ms.computers[i].NetworkID == networkID && ms.computers[i].PeerIP != "" {
+			ms.computers[i].PeerIP = renumberIP(ms.computers[i].PeerIP, parsed)
+		}
+	}
+	for key, ip := range ms.reservedIPs[networkID] {
+		ms.reservedIPs[networkID][key] = renumberIP(ip, parsed)
+	}
+	return nil
+}
+
+// DeleteNetwork removes a network and, like the database cascade, its memberships, bans
+// and reserved IPs
+func (ms *MemoryStore) DeleteNetwork(networkID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.networks, networkID)
+	delete(ms.bans, networkID)
+	delete(ms.reservedIPs, networkID)
+	ms.computers = slices.DeleteFunc(ms.computers, func(c ComputerNetwork) bool {
+		return c.NetworkID == networkID
+	})
+	return nil
+}
+
+// filterNetworks returns the stored networks that match
+func (ms *MemoryStore) filterNetworks(match func(SupabaseNetwork) bool) []SupabaseNetwork {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var networks []SupabaseNetwork
+	for _, network := range ms.networks {
+		if match(network) {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// GetStaleNetworks returns networks that have not been active for expiryDays
+func (ms *MemoryStore) GetStaleNetworks(expiryDays int) ([]SupabaseNetwork, error) {
+	cutoff := time.Now().Add(-time.Hour * 24 * time.Duration(expiryDays))
+	return ms.filterNetworks(func(n SupabaseNetwork) bool {
+		return n.LastActive.Before(cutoff)
+	}), nil
+}
+
+// ListNetworks returns every stored network
+func (ms *MemoryStore) ListNetworks() ([]SupabaseNetwork, error) {
+	return ms.filterNetworks(func(SupabaseNetwork) bool { return true }), nil
+}
+
+// ListPublicNetworks returns up to limit public networks, most recently active first
+func (ms *MemoryStore) ListPublicNetworks(tag string, limit int) ([]SupabaseNetwork, error) {
+	networks := ms.filterNetworks(func(n SupabaseNetwork) bool {
+		return n.IsPublic && (tag == "" || slices.Contains(n.Tags, tag))
+	})
+
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].LastActive.After(networks[j].LastActive)
+	})
+	if len(networks) > limit {
+		networks = networks[:limit]
+	}
+	return networks, nil
+}
+
+// GetNetworksByOwner returns the networks owned by a public key, oldest first
+func (ms *MemoryStore) GetNetworksByOwner(publicKey string) ([]SupabaseNetwork, error) {
+	networks := ms.filterNetworks(func(n SupabaseNetwork) bool {
+		return n.OwnerPublicKey == publicKey
+	})
+
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].CreatedAt.Before(networks[j].CreatedAt)
+	})
+	return networks, nil
+}
+
+// GetMemberCounts returns how many computers joined each of the given networks
+func (ms *MemoryStore) GetMemberCounts(networkIDs []string) (map[string]int, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	counts := make(map[string]int, len(networkIDs))
+	for _, c := range ms.computers {
+		if slices.Contains(networkIDs, c.NetworkID) {
+			counts[c.NetworkID]++
+		}
+	}
+	return counts, nil
+}
+
+// AddComputerToNetwork stores a membership, enforcing the same unique keys as the database
+func (ms *MemoryStore) AddComputerToNetwork(networkID, publicKey, computerName, peerIp string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return ms.addComputerLocked(networkID, publicKey, computerName, peerIp)
+}
+
+// addComputerLocked stores a membership. Callers must hold the lock.
+func (ms *MemoryStore) addComputerLocked(networkID, publicKey, computerName, peerIp string) error {
+	if _, ok := ms.networks[networkID]; !ok {
+		return fmt.Errorf("failed to add computer to network: network %s does not exist", networkID)
+	}
+	for _, c := range ms.computers {
+		if c.NetworkID != networkID {
+			continue
+		}
+		if c.PublicKey == publicKey {
+			return fmt.Errorf("failed to add computer to network: computer already joined")
+		}
+		if peerIp != "" && c.PeerIP == peerIp {
+			return fmt.Errorf("failed to add computer to network: IP %s already in use", peerIp)
+		}
+	}
+
+	ms.nextID++
+	now := time.Now()
+	ms.computers = append(ms.computers, ComputerNetwork{
+		ID:            ms.nextID,
+		NetworkID:     networkID,
+		PublicKey:     publicKey,
+		ComputerName:  computerName,
+		JoinedAt:      now,
+		LastConnected: now,
+		PeerIP:        peerIp,
+	})
+	return nil
+}
+
+// RemoveComputerFromNetwork deletes a membership
+func (ms *MemoryStore) RemoveComputerFromNetwork(networkID, publicKey string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.computers = slices.DeleteFunc(ms.computers, func(c ComputerNetwork) bool {
+		return c.NetworkID == networkID && c.PublicKey == publicKey
+	})
+	return nil
+}
+
+// filterComputers returns the stored memberships that match
+func (ms *MemoryStore) filterComputers(match func(ComputerNetwork) bool) []ComputerNetwork {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var computers []ComputerNetwork
+	for _, c := range ms.computers {
+		if match(c) {
+			computers = append(computers, c)
+		}
+	}
+	return computers
+}
+
+// GetComputersInNetwork returns every membership of a network
+func (ms *MemoryStore) GetComputersInNetwork(networkID string) ([]ComputerNetwork, error) {
+	return ms.filterComputers(func(c ComputerNetwork) bool {
+		return c.NetworkID == networkID
+	}), nil
+}
+
+// GetComputerInNetwork returns the membership of a computer in a network
+func (ms *MemoryStore) GetComputerInNetwork(networkID, publicKey string) (ComputerNetwork, error) {
+	computers := ms.filterComputers(func(c ComputerNetwork) bool {
+		return c.NetworkID == networkID && c.PublicKey == publicKey
+	})
+	if len(computers) == 0 {
+		return ComputerNetwork{}, fmt.Errorf("computer not found in network")
+	}
+	return computers[0], nil
+}
+
+// IsComputerInNetwork checks if a computer is already in a network
+func (ms *MemoryStore) IsComputerInNetwork(networkID, publicKey string) (bool, error) {
+	_, err := ms.GetComputerInNetwork(networkID, publicKey)
+	return err == nil, nil
+}
+
+// GetComputerNetworks returns every membership of a public key
+func (ms *MemoryStore) GetComputerNetworks(publicKey string) ([]ComputerNetwork, error) {
+	return ms.filterComputers(func(c ComputerNetwork) bool {
+		return c.PublicKey == publicKey
+	}), nil
+}
+
+// GetUsedIPsForNetwork returns the IPs assigned in a network
+func (ms *MemoryStore) GetUsedIPsForNetwork(networkID string) ([]string, error) {
+	var ips []string
+	for _, c := range ms.filterComputers(func(c ComputerNetwork) bool { return c.NetworkID == networkID }) {
+		if c.PeerIP != "" {
+			ips = append(ips, c.PeerIP)
+		}
+	}
+	return ips, nil
+}
+
+// UpdateClientNameInNetworks renames a computer in every network it joined
+func (ms *MemoryStore) UpdateClientNameInNetworks(publicKey, newName string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for i := range ms.computers {
+		if ms.computers[i].PublicKey == publicKey {
+			ms.computers[i].ComputerName = newName
+		}
+	}
+	return nil
+}
+
+// BanComputer keeps a public key from joining a network again
+func (ms *MemoryStore) BanComputer(networkID, publicKey, reason string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.networks[networkID]; !ok {
+		return fmt.Errorf("failed to ban computer: network %s does not exist", networkID)
+	}
+	if ms.bans[networkID] == nil {
+		ms.bans[networkID] = make(map[string]string)
+	}
+	ms.bans[networkID][publicKey] = reason
+	return nil
+}
+
+// IsComputerBanned checks if a public key was banned from a network
+func (ms *MemoryStore) IsComputerBanned(networkID, publicKey string) (bool, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	_, banned := ms.bans[networkID][publicKey]
+	return banned, nil
+}
+
+// ReserveIP keeps peerIP for a public key in a network, replacing its previous reservation
+func (ms *MemoryStore) ReserveIP(networkID, publicKey, peerIP string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.networks[networkID]; !ok {
+		return fmt.Errorf("failed to reserve IP: network %s does not exist", networkID)
+	}
+	for key, ip := range ms.reservedIPs[networkID] {
+		if ip == peerIP && key != publicKey {
+			return fmt.Errorf("failed to reserve IP: %s is reserved for another computer", peerIP)
+		}
+	}
+	if ms.reservedIPs[networkID] == nil {
+		ms.reservedIPs[networkID] = make(map[string]string)
+	}
+	ms.reservedIPs[networkID][publicKey] = peerIP
+	return nil
+}
+
+// ReleaseIP drops the IP reserved for a public key in a network
+func (ms *MemoryStore) ReleaseIP(networkID, publicKey string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.reservedIPs[networkID], publicKey)
+	return nil
+}
+
+// GetReservedIPs returns the IPs reserved in a network by public key
+func (ms *MemoryStore) GetReservedIPs(networkID string) (map[string]string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	reserved := make(map[string]string, len(ms.reservedIPs[networkID]))
+	for key, ip := range ms.reservedIPs[networkID] {
+		reserved[key] = ip
+	}
+	return reserved, nil
+}
+
+// SetRecoveryCodeHash stores the recovery code hash of a public key, replacing any previous one
+func (ms *MemoryStore) SetRecoveryCodeHash(publicKey, codeHash string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.recoveryCodes[publicKey] = codeHash
+	return nil
+}
+
+// GetRecoveryCodeHash returns the recovery code hash of a public key, or "" if none was created
+func (ms *MemoryStore) GetRecoveryCodeHash(publicKey string) (string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return ms.recoveryCodes[publicKey], nil
+}
+
+// IsKeyRevoked checks if a public key was replaced by a key migration
+func (ms *MemoryStore) IsKeyRevoked(publicKey string) (bool, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	_, revoked := ms.revokedKeys[publicKey]
+	return revoked, nil
+}
+
+// MigratePublicKey moves every network and membership of oldKey to newKey and revokes oldKey,
+// following migrate_public_key in migrations/007_member_management.sql
+func (ms *MemoryStore) MigratePublicKey(oldKey, newKey string) (int, int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, revoked := ms.revokedKeys[newKey]; revoked {
+		return 0, 0, fmt.Errorf("failed to migrate public key: new key was revoked")
+	}
+
+	movedNetworks := 0
+	for id, network := range ms.networks {
+		if network.OwnerPublicKey == oldKey {
+			network.OwnerPublicKey = newKey
+			network.LastActive = time.Now()
+			ms.networks[id] = network
+			movedNetworks++
+		}
+	}
+
+	joined := make(map[string]bool)
+	for _, c := range ms.computers {
+		if c.PublicKey == newKey {
+			joined[c.NetworkID] = true
+		}
+	}
+	ms.computers = slices.DeleteFunc(ms.computers, func(c ComputerNetwork) bool {
+		return c.PublicKey == oldKey && joined[c.NetworkID]
+	})
+
+	movedMemberships := 0
+	for i := range ms.computers {
+		if ms.computers[i].PublicKey == oldKey {
+			ms.computers[i].PublicKey = newKey
+			movedMemberships++
+		}
+	}
+
+	for _, bans := range ms.bans {
+		if reason, ok := bans[oldKey]; ok {
+			if _, exists := bans[newKey]; !exists {
+				bans[newKey] = reason
+			}
+			delete(bans, oldKey)
+		}
+	}
+	for _, reserved := range ms.reservedIPs {
+		if ip, ok := reserved[oldKey]; ok {
+			if _, exists := reserved[newKey]; !exists {
+				reserved[newKey] = ip
+			}
+			delete(reserved, oldKey)
+		}
+	}
+
+	delete(ms.recoveryCodes, oldKey)
+	ms.revokedKeys[oldKey] = newKey
+
+	return movedNetworks, movedMemberships, nil
+}
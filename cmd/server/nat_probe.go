@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// natProbeMaxSize bounds the probe datagrams read; anything larger is not a probe
+const natProbeMaxSize = 512
+
+// natProbe answers UDP probes with the address they arrived from, on NAT_PROBE_PORT
+// and the port after it. A probe may ask for the answer to come from the other port,
+// which tells the client whether its NAT filters by port.
+type natProbe struct {
+	conns [2]*net.UDPConn
+	wg    sync.WaitGroup
+}
+
+// startNATProbe listens on port and port+1 and starts answering probes
+func startNATProbe(port int) (*natProbe, error) {
+	p := &natProbe{}
+	for i := range p.conns {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port + i})
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("listening for NAT probes on UDP port %d: %w", port+i, err)
+		}
+		p.conns[i] = conn
+	}
+
+	for i := range p.conns {
+		p.wg.Add(1)
+		go p.serve(i)
+	}
+	return p, nil
+}
+
+// Ports returns the UDP ports probes are answered on
+func (p *natProbe) Ports() []int {
+	ports := make([]int, 0, len(p.conns))
+	for _, conn := range p.conns {
+		ports = append(ports, conn.LocalAddr().(*net.UDPAddr).Port)
+	}
+	return ports
+}
+
+// Close stops answering probes
+func (p *natProbe) Close() {
+	for _, conn := range p.conns {
+		if conn != nil {
+			conn.Close()
+		}
+	}
+	p.wg.Wait()
+}
+
+// serve answers the probes arriving on conns[i] until the socket is closed
+func (p *natProbe) serve(i int) {
+	defer p.wg.Done()
+
+	buf := make([]byte, natProbeMaxSize)
+	for {
+		n, from, err := p.conns[i].ReadFromUDP(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			logger.Warn("NAT probe read failed", "error", err)
+			continue
+		}
+
+		// Small datagrams are dropped so the probe cannot amplify spoofed traffic
+		if n < smodels.NatProbeMinSize {
+			continue
+		}
+
+		var req smodels.NatProbeRequest
+		if err := json.Unmarshal(buf[:n], &req); err != nil || req.TransactionID == "" {
+			continue
+		}
+
+		resp, err := json.Marshal(smodels.NatProbeResponse{
+			TransactionID: req.TransactionID,
+			IP:            from.IP.String(),
+			Port:          from.Port,
+		})
+		if err != nil || len(resp) > n {
+			continue
+		}
+
+		out := p.conns[i]
+		if req.ChangePort {
+			out = p.conns[1-i]
+		}
+		if _, err := out.WriteToUDP(resp, from); err != nil {
+			logger.Debug("NAT probe answer failed", "to", from.String(), "error", err)
+		}
+	}
+}
+
+// handleNatReport records the NAT type a client detected and shares it with the
+// members of the network it is connected to
+func (s *WebSocketServer) handleNatReport(ctx context.Context, conn *websocket.Conn, req smodels.NatReportRequest, originalID string) {
+	if !req.NatType.Valid() {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Unknown NAT type", originalID)
+		return
+	}
+
+	s.sessionsMu.Lock()
+	if session, ok := s.sessions[conn]; ok {
+		session.natType = req.NatType
+	}
+	s.sessionsMu.Unlock()
+
+	logger.Info("Client reported NAT type", "remoteAddr", conn.RemoteAddr().String(), "publicKey", req.PublicKey, "natType", req.NatType)
+	s.sendSignal(conn, smodels.TypeNatReportResponse, smodels.NatReportResponse{NatType: req.NatType}, originalID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	networkID := s.clients[conn]
+	if networkID == "" {
+		return
+	}
+	s.broadcastNetworkEvent(networkID, smodels.TypeComputerNatType, smodels.ComputerNatTypeNotification{
+		NetworkID: networkID,
+		PublicKey: s.clientToPublicKey[conn],
+		NatType:   req.NatType,
+	}, conn)
+}
+
+// connNatType returns the NAT type reported on a connection, empty when it reported none
+func (s *WebSocketServer) connNatType(conn *websocket.Conn) smodels.NatType {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	if session, ok := s.sessions[conn]; ok {
+		return session.natType
+	}
+	return ""
+}
+
+// natTypeOf returns the NAT type reported by the connection of publicKey in a network.
+// Callers must hold the server lock.
+func (s *WebSocketServer) natTypeOf(networkID, publicKey string) smodels.NatType {
+	for _, conn := range s.networks[networkID] {
+		if s.clientToPublicKey[conn] == publicKey {
+			return s.connNatType(conn)
+		}
+	}
+	return ""
+}
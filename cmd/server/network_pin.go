@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// handleChangePIN lets the owner replace the PIN of a network, for instance after an
+// invite leaked. Members keep their membership; only new joins need the new PIN.
+func (s *WebSocketServer) handleChangePIN(ctx context.Context, conn *websocket.Conn, req smodels.ChangePINRequest, originalID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.pinRegex.MatchString(req.PIN) {
+		s.sendErrorSignal(conn, smodels.ErrInvalidPIN, "PIN does not match required pattern", originalID)
+		return
+	}
+
+	network, err := s.store.GetNetwork(req.NetworkID)
+	if err != nil {
+		s.sendErrorSignal(conn, smodels.ErrNetworkNotFound, "Network does not exist", originalID)
+		return
+	}
+
+	publicKey, hasPublicKey := s.clientToPublicKey[conn]
+	if !hasPublicKey {
+		publicKey = req.PublicKey
+	}
+	if publicKey == "" || publicKey != network.OwnerPublicKey {
+		s.sendErrorSignal(conn, smodels.ErrNotOwner, "Only network owner can change the PIN", originalID)
+		return
+	}
+
+	if req.PIN == network.PIN {
+		s.sendErrorSignal(conn, smodels.ErrInvalidPIN, "New PIN must differ from the current one", originalID)
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "change PIN") {
+		return
+	}
+
+	if err := s.store.UpdateNetworkPIN(req.NetworkID, req.PIN); err != nil {
+		logger.Error("Error updating network PIN", "networkID", req.NetworkID, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error updating network PIN", originalID)
+		return
+	}
+
+	logger.Info("Network PIN changed", "networkID", req.NetworkID)
+	s.sendSignal(conn, smodels.TypePINChanged, smodels.ChangePINResponse{
+		NetworkID: req.NetworkID,
+		ChangedAt: time.Now(),
+	}, originalID)
+}
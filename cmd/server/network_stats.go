@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// networkCounters are the activity counters of one network since the server started
+type networkCounters struct {
+	totalJoins      int64
+	peakMembers     int
+	lastActivity    time.Time
+	relayedMessages int64
+	relayedBytes    int64
+	since           time.Time
+}
+
+// networkStatsTracker keeps activity counters per network in memory.
+// It has its own lock since relaying only holds the server lock for reading.
+type networkStatsTracker struct {
+	mu       sync.Mutex
+	networks map[string]*networkCounters
+}
+
+func newNetworkStatsTracker() *networkStatsTracker {
+	return &networkStatsTracker{networks: make(map[string]*networkCounters)}
+}
+
+// counters returns the counters of a network, creating them if needed. Callers must hold the lock.
+func (t *networkStatsTracker) counters(networkID string) *networkCounters {
+	c, ok := t.networks[networkID]
+	if !ok {
+		now := time.Now()
+		c = &networkCounters{since: now, lastActivity: now}
+		t.networks[networkID] = c
+	}
+	return c
+}
+
+// RecordJoin counts a computer joining a network
+func (t *networkStatsTracker) RecordJoin(networkID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.counters(networkID)
+	c.totalJoins++
+	c.lastActivity = time.Now()
+}
+
+// RecordOnline notes that online computers are now connected to a network
+func (t *networkStatsTracker) RecordOnline(networkID string, online int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.counters(networkID)
+	c.peakMembers = max(c.peakMembers, online)
+	c.lastActivity = time.Now()
+}
+
+// RecordRelay counts a signaling message of size bytes forwarded between members of a network
+func (t *networkStatsTracker) RecordRelay(networkID string, size int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.counters(networkID)
+	c.relayedMessages++
+	c.relayedBytes += int64(size)
+	c.lastActivity = time.Now()
+}
+
+// Snapshot returns a copy of the counters of a network
+func (t *networkStatsTracker) Snapshot(networkID string) networkCounters {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return *t.counters(networkID)
+}
+
+// Delete forgets the counters of a deleted network
+func (t *networkStatsTracker) Delete(networkID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.networks, networkID)
+}
+
+// handleGetNetworkStats sends the activity counters of a network to its owner
+func (s *WebSocketServer) handleGetNetworkStats(ctx context.Context, conn *websocket.Conn, req smodels.GetNetworkStatsRequest, originalID string) {
+	network, err := s.store.GetNetwork(req.NetworkID)
+	if err != nil {
+		s.sendErrorSignal(conn, smodels.ErrNetworkNotFound, "Network does not exist", originalID)
+		return
+	}
+
+	if req.PublicKey == "" || req.PublicKey != network.OwnerPublicKey {
+		s.sendErrorSignal(conn, smodels.ErrNotOwner, "Only the network owner can see its statistics", originalID)
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "network stats") {
+		return
+	}
+
+	members, err := s.store.GetComputersInNetwork(req.NetworkID)
+	if err != nil {
+		logger.Error("Error fetching network members for stats", "error", err, "networkID", req.NetworkID)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error fetching network members", originalID)
+		return
+	}
+
+	s.mu.RLock()
+	online := len(s.networks[req.NetworkID])
+	s.mu.RUnlock()
+
+	counters := s.networkStats.Snapshot(req.NetworkID)
+	s.sendSignal(conn, smodels.TypeNetworkStats, smodels.NetworkStatsResponse{
+		NetworkID:       req.NetworkID,
+		Members:         len(members),
+		OnlineMembers:   online,
+		TotalJoins:      counters.totalJoins,
+		PeakMembers:     counters.peakMembers,
+		LastActivity:    counters.lastActivity,
+		RelayedMessages: counters.relayedMessages,
+		RelayedBytes:    counters.relayedBytes,
+		Since:           counters.since,
+	}, originalID)
+}
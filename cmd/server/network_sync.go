@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// Page sizes for GetComputerNetworks
+const (
+	defaultComputerNetworksPageSize = 50
+	maxComputerNetworksPageSize     = 200
+)
+
+// computerNetworksPageSize returns the page size to use for a requested size
+func computerNetworksPageSize(requested int) int {
+	if requested <= 0 {
+		return defaultComputerNetworksPageSize
+	}
+	return min(requested, maxComputerNetworksPageSize)
+}
+
+// pageComputerNetworks sorts memberships by network ID and returns the page that starts
+// after cursor, along with the cursor of the next page ("" on the last page)
+func pageComputerNetworks(memberships []ComputerNetwork, cursor string, pageSize int) ([]ComputerNetwork, string) {
+	sort.Slice(memberships, func(i, j int) bool {
+		return memberships[i].NetworkID < memberships[j].NetworkID
+	})
+
+	start := sort.Search(len(memberships), func(i int) bool {
+		return memberships[i].NetworkID > cursor
+	})
+	end := min(start+pageSize, len(memberships))
+
+	page := memberships[start:end]
+	if end < len(memberships) && len(page) > 0 {
+		return page, page[len(page)-1].NetworkID
+	}
+	return page, ""
+}
+
+// networkInfoVersion hashes everything a client shows about a network, so it can skip
+// networks whose version did not change since its last sync
+func networkInfoVersion(info smodels.ComputerNetworkInfo) string {
+	info.Version = ""
+	info.Unchanged = false
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPageComputerNetworks(t *testing.T) {
+	var memberships []ComputerNetwork
+	for _, id := range []string{"e", "b", "d", "a", "c"} {
+		memberships = append(memberships, ComputerNetwork{NetworkID: id})
+	}
+
+	var pages []string
+	cursor := ""
+	for range len(memberships) + 1 {
+		page, next := pageComputerNetworks(memberships, cursor, 2)
+		ids := ""
+		for _, m := range page {
+			ids += m.NetworkID
+		}
+		pages = append(pages, ids)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if got, want := fmt.Sprint(pages), "[ab cd e]"; got != want {
+		t.Errorf("pages = %s, want %s", got, want)
+	}
+}
+
+func TestPageComputerNetworksExactPages(t *testing.T) {
+	memberships := []ComputerNetwork{{NetworkID: "b"}, {NetworkID: "a"}}
+
+	page, next := pageComputerNetworks(memberships, "", 2)
+	if len(page) != 2 || next != "" {
+		t.Errorf("full single page = %d memberships, next %q; want 2, no next page", len(page), next)
+	}
+
+	page, next = pageComputerNetworks(memberships, "b", 2)
+	if len(page) != 0 || next != "" {
+		t.Errorf("page after the last cursor = %d memberships, next %q; want none", len(page), next)
+	}
+}
+
+func TestComputerNetworksPageSize(t *testing.T) {
+	for requested, want := range map[int]int{
+		0:                               defaultComputerNetworksPageSize,
+		-1:                              defaultComputerNetworksPageSize,
+		10:                              10,
+		maxComputerNetworksPageSize + 1: maxComputerNetworksPageSize,
+	} {
+		if got := computerNetworksPageSize(requested); got != want {
+			t.Errorf("computerNetworksPageSize(%d) = %d, want %d", requested, got, want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/gorilla/websocket"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// maxNoticeLength bounds the text of a server notice so it fits in a client banner
+const maxNoticeLength = 1024
+
+var (
+	errInvalidNotice         = errors.New("invalid notice")
+	errNoticeNetworkNotFound = errors.New("network not found")
+)
+
+// noticeID derives a notice ID from its text, so the message of the day keeps its ID across
+// reconnects and a client that dismissed it does not show it again
+func noticeID(prefix, message string) string {
+	sum := sha256.Sum256([]byte(message))
+	return prefix + "-" + hex.EncodeToString(sum[:6])
+}
+
+// sendMotd sends the message of the day to a new connection, if one is configured
+func (s *WebSocketServer) sendMotd(conn *websocket.Conn) {
+	s.mu.RLock()
+	motd := s.config.Motd
+	s.mu.RUnlock()
+
+	if motd == "" {
+		return
+	}
+
+	s.sendSignal(conn, smodels.TypeServerNotice, smodels.ServerNoticeNotification{
+		ID:      noticeID("motd", motd),
+		Message: motd,
+		Level:   smodels.NoticeLevelInfo,
+		SentAt:  time.Now(),
+	}, "")
+}
+
+// broadcastNotice sends a notice to the connected members of notice.NetworkID, or to every
+// open connection when it is empty, and returns how many connections it was written to
+func (s *WebSocketServer) broadcastNotice(notice smodels.ServerNoticeNotification) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var targets []*websocket.Conn
+	if notice.NetworkID != "" {
+		targets = append(targets, s.networks[notice.NetworkID]...)
+	} else {
+		s.sessionsMu.Lock()
+		for conn := range s.sessions {
+			targets = append(targets, conn)
+		}
+		s.sessionsMu.Unlock()
+	}
+
+	delivered := 0
+	for _, conn := range targets {
+		if err := s.sendSignal(conn, smodels.TypeServerNotice, notice, ""); err == nil {
+			delivered++
+		}
+	}
+	return delivered
+}
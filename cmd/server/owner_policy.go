@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// ownerPolicy returns the owner policy of a network, falling back to the server default.
+// Callers must hold the server lock.
+func (s *WebSocketServer) ownerPolicy(network SupabaseNetwork) smodels.OwnerPolicy {
+	policy := smodels.OwnerPolicy(network.OwnerPolicy)
+	if !policy.Valid() {
+		policy = smodels.OwnerPolicy(s.config.DefaultOwnerPolicy)
+	}
+	return policy
+}
+
+// handleOwnerDeparture applies the owner policy after the owner's connection was removed
+// from the network. ownerLeft is true when the owner also gave up membership (LeaveNetwork),
+// in which case the network is deleted unless ownership can be transferred.
+// Callers must hold the server lock.
+func (s *WebSocketServer) handleOwnerDeparture(networkID string, network SupabaseNetwork, ownerLeft bool) {
+	policy := s.ownerPolicy(network)
+	logger.Info("Applying owner policy", "networkID", networkID, "policy", policy, "ownerLeft", ownerLeft)
+
+	switch policy {
+	case smodels.OwnerPolicyTransfer:
+		if s.transferOwnership(networkID, network) || !ownerLeft {
+			return
+		}
+	case smodels.OwnerPolicyPreserve:
+		if !ownerLeft {
+			return
+		}
+	}
+
+	if err := s.deleteNetworkAndNotify(networkID, nil); err != nil {
+		logger.Error("Error deleting network after owner departure", "networkID", networkID, "error", err)
+		return
+	}
+	logger.Info("Network deleted after owner departure", "networkID", networkID, "policy", policy)
+}
+
+// transferOwnership hands a network to its oldest remaining member and notifies connected members.
+// It reports whether ownership changed. Callers must hold the server lock.
+func (s *WebSocketServer) transferOwnership(networkID string, network SupabaseNetwork) bool {
+	members, err := s.store.GetComputersInNetwork(networkID)
+	if err != nil {
+		logger.Error("Error fetching members for ownership transfer", "networkID", networkID, "error", err)
+		return false
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].JoinedAt.Before(members[j].JoinedAt)
+	})
+
+	newOwner := ""
+	for _, member := range members {
+		if member.PublicKey != network.OwnerPublicKey {
+			newOwner = member.PublicKey
+			break
+		}
+	}
+	if newOwner == "" {
+		logger.Info("No member to transfer ownership to", "networkID", networkID)
+		return false
+	}
+
+	if err := s.handOverNetwork(network, newOwner); err != nil {
+		logger.Error("Error transferring network ownership", "networkID", networkID, "error", err)
+		return false
+	}
+	return true
+}
+
+// handOverNetwork makes newOwner the owner of a network and notifies connected members.
+// Callers must hold the server lock.
+func (s *WebSocketServer) handOverNetwork(network SupabaseNetwork, newOwner string) error {
+	if err := s.store.UpdateNetworkOwner(network.ID, newOwner); err != nil {
+		return err
+	}
+
+	notification := smodels.NetworkOwnerChangedNotification{
+		NetworkID:              network.ID,
+		PreviousOwnerPublicKey: network.OwnerPublicKey,
+		OwnerPublicKey:         newOwner,
+	}
+	s.broadcastNetworkEvent(network.ID, smodels.TypeNetworkOwnerChanged, notification, nil)
+
+	logger.Info("Network ownership transferred", "networkID", network.ID, "from", network.OwnerPublicKey, "to", newOwner)
+	return nil
+}
+
+// deleteNetworkAndNotify deletes a network, tells its connected members (except exclude)
+// and drops it from memory. Callers must hold the server lock.
+func (s *WebSocketServer) deleteNetworkAndNotify(networkID string, exclude *websocket.Conn) error {
+	if err := s.store.DeleteNetwork(networkID); err != nil {
+		return err
+	}
+
+	s.broadcastNetworkEvent(networkID, smodels.TypeNetworkDeleted, smodels.NetworkDeletedNotification{
+		NetworkID: networkID,
+	}, exclude)
+
+	delete(s.networks, networkID)
+	delete(s.connectedComputers, networkID)
+	delete(s.eventLogs, networkID)
+	s.relayQuota.Delete(networkID)
+	s.networkStats.Delete(networkID)
+	for c, cNetworkID := range s.clients {
+		if cNetworkID == networkID {
+			delete(s.clients, c)
+		}
+	}
+
+	s.statsManager.UpdateStats(len(s.clients), len(s.networks))
+	return nil
+}
+
+// handleSetOwnerPolicy changes what happens to a network when its owner disconnects
+func (s *WebSocketServer) handleSetOwnerPolicy(ctx context.Context, conn *websocket.Conn, req smodels.SetOwnerPolicyRequest, originalID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !req.Policy.Valid() {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Owner policy must be preserve, delete or transfer", originalID)
+		return
+	}
+
+	network, err := s.store.GetNetwork(req.NetworkID)
+	if err != nil {
+		s.sendErrorSignal(conn, smodels.ErrNetworkNotFound, "Network does not exist", originalID)
+		return
+	}
+
+	publicKey, hasPublicKey := s.clientToPublicKey[conn]
+	if !hasPublicKey {
+		publicKey = req.PublicKey
+	}
+	if publicKey == "" || publicKey != network.OwnerPublicKey {
+		s.sendErrorSignal(conn, smodels.ErrNotOwner, "Only network owner can change the owner policy", originalID)
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "set owner policy") {
+		return
+	}
+
+	if err := s.store.UpdateNetworkOwnerPolicy(req.NetworkID, string(req.Policy)); err != nil {
+		logger.Error("Error updating owner policy", "networkID", req.NetworkID, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error updating owner policy", originalID)
+		return
+	}
+
+	logger.Info("Owner policy changed", "networkID", req.NetworkID, "policy", req.Policy)
+	s.sendSignal(conn, smodels.TypeSetOwnerPolicyResponse, smodels.SetOwnerPolicyResponse{
+		NetworkID: req.NetworkID,
+		Policy:    req.Policy,
+	}, originalID)
+}
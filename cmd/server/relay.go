@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// relayQuotaWindow is the period over which RELAY_QUOTA_BYTES is counted
+const relayQuotaWindow = time.Minute
+
+// relayUsage is how much a network relayed in the current window
+type relayUsage struct {
+	windowStart time.Time
+	bytes       int64
+}
+
+// relayQuotaTracker counts the frames each network relays through the server so a
+// network whose members cannot connect directly does not take all the bandwidth.
+// Like networkStatsTracker it has its own lock, relaying only holds the server lock for reading.
+type relayQuotaTracker struct {
+	mu       sync.Mutex
+	networks map[string]*relayUsage
+}
+
+func newRelayQuotaTracker() *relayQuotaTracker {
+	return &relayQuotaTracker{networks: make(map[string]*relayUsage)}
+}
+
+// Allow counts size bytes against the quota of a network and reports whether they fit.
+// Frames that do not fit are not counted.
+func (t *relayQuotaTracker) Allow(networkID string, size int, quota int64, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	usage, ok := t.networks[networkID]
+	if !ok || now.Sub(usage.windowStart) >= relayQuotaWindow {
+		usage = &relayUsage{windowStart: now}
+		t.networks[networkID] = usage
+	}
+	if usage.bytes+int64(size) > quota {
+		return false
+	}
+	usage.bytes += int64(size)
+	return true
+}
+
+// Delete forgets the usage of a deleted network
+func (t *relayQuotaTracker) Delete(networkID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.networks, networkID)
+}
+
+// handleRelayFrame forwards an encrypted peer frame to another member of the sender's network.
+// Clients fall back to this when their WebRTC connection fails, so the frames are opaque
+// to the server and only limited by the network's relay quota.
+func (s *WebSocketServer) handleRelayFrame(ctx context.Context, senderConn *websocket.Conn, req smodels.RelayFrame, originalID string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	senderPublicKey, ok := s.clientToPublicKey[senderConn]
+	if !ok {
+		s.sendErrorSignal(senderConn, smodels.ErrNotConnected, "Sender public key not found", originalID)
+		return
+	}
+
+	senderNetworkID, ok := s.clients[senderConn]
+	if !ok {
+		s.sendErrorSignal(senderConn, smodels.ErrNotConnected, "Sender not in any network", originalID)
+		return
+	}
+
+	targetConn := s.findNetworkPeer(senderNetworkID, req.TargetPublicKey)
+	if targetConn == nil {
+		s.sendErrorSignal(senderConn, smodels.ErrTargetNotFound, "Target client not found or not in the same network", originalID)
+		return
+	}
+
+	quota := s.config.RelayQuotaBytes
+	if quota <= 0 || !s.relayQuota.Allow(senderNetworkID, len(req.Data), quota, time.Now()) {
+		s.sendErrorSignal(senderConn, smodels.ErrRelayQuotaExceeded, "The relay quota of this network is used up, try again in a minute", originalID)
+		return
+	}
+
+	frame := smodels.RelayFrame{
+		SenderPublicKey: senderPublicKey,
+		TargetPublicKey: req.TargetPublicKey,
+		Data:            req.Data,
+	}
+	if err := s.sendSignal(targetConn, smodels.TypeRelayFrame, frame, ""); err != nil {
+		logger.Error("Failed to relay frame", "error", err, "sender", senderPublicKey, "target", req.TargetPublicKey)
+		return
+	}
+	s.networkStats.RecordRelay(senderNetworkID, len(req.Data))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// broadcastRoster sends the complete member list of a network to every connection
+// attached to it. It is called after joins, connects and every other membership
+// change, and costs nothing when no member is connected. Callers must hold the server lock.
+func (s *WebSocketServer) broadcastRoster(networkID string) {
+	conns := s.networks[networkID]
+	if len(conns) == 0 {
+		return
+	}
+
+	network, err := s.store.GetNetwork(networkID)
+	if err != nil {
+		logger.Debug("Skipping roster of a network that no longer exists", "networkID", networkID)
+		return
+	}
+
+	computers, err := s.store.GetComputersInNetwork(networkID)
+	if err != nil {
+		// Members still get the individual events, the next change sends a roster again
+		logger.Error("Error fetching network members for roster", "error", err, "networkID", networkID)
+		return
+	}
+
+	reserved, err := s.store.GetReservedIPs(networkID)
+	if err != nil {
+		logger.Debug("Sending roster without IP reservations", "error", err, "networkID", networkID)
+	}
+
+	roster := smodels.NetworkRosterNotification{
+		NetworkID:      networkID,
+		OwnerPublicKey: network.OwnerPublicKey,
+		Members:        make([]smodels.RosterMember, 0, len(computers)),
+		LastSequence:   s.eventLog(networkID).seq,
+	}
+	for _, computer := range computers {
+		roster.Members = append(roster.Members, smodels.RosterMember{
+			ComputerInfo: smodels.ComputerInfo{
+				Name:       computer.ComputerName,
+				ComputerIP: computer.PeerIP,
+				PublicKey:  computer.PublicKey,
+				IsOnline:   s.isComputerOnline(networkID, computer.PublicKey),
+				NatType:    s.natTypeOf(networkID, computer.PublicKey),
+				IPReserved: computer.PeerIP != "" && reserved[computer.PublicKey] == computer.PeerIP,
+				Wakeable:   s.isWakeable(computer.PublicKey),
+			},
+			IsOwner: computer.PublicKey == network.OwnerPublicKey,
+		})
+	}
+
+	for _, conn := range conns {
+		s.sendSignal(conn, smodels.TypeNetworkRoster, roster, "")
+	}
+}
@@ -5,17 +5,46 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
-	"github.com/itxtoledo/govpn/cmd/server/logger"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+const (
+	latencyWindowSize   = 512 // Número de amostras mantidas por tipo de mensagem
+	maxTrackedMsgTypes  = 64  // Limite de tipos distintos para evitar crescimento ilimitado
+	otherMessageTypeKey = "other"
 )
 
 // StatsManager gerencia as estatísticas do servidor WebSocket
 type StatsManager struct {
-	stats  ServerStats
-	mu     sync.RWMutex
-	config Config
+	stats    ServerStats
+	mu       sync.RWMutex
+	config   Config
+	messages map[smodels.MessageType]*messageMetrics
+}
+
+// messageMetrics acumula contadores e uma janela móvel de latências para um tipo de mensagem
+type messageMetrics struct {
+	count     int64
+	errors    int64
+	samples   []time.Duration
+	next      int
+	maxSample time.Duration
+}
+
+// MessageTypeStats resume as métricas de processamento de um tipo de mensagem
+type MessageTypeStats struct {
+	Count     int64   `json:"count"`      // Total de mensagens processadas
+	Errors    int64   `json:"errors"`     // Total de respostas de erro enviadas
+	ErrorRate float64 `json:"error_rate"` // Fração de mensagens que resultaram em erro
+	P50Ms     float64 `json:"p50_ms"`     // Percentil 50 da janela móvel
+	P90Ms     float64 `json:"p90_ms"`     // Percentil 90 da janela móvel
+	P99Ms     float64 `json:"p99_ms"`     // Percentil 99 da janela móvel
+	MaxMs     float64 `json:"max_ms"`     // Maior latência observada desde o início
 }
 
 // ServerStats armazena várias métricas do servidor WebSocket
@@ -39,16 +68,20 @@ func NewStatsManager(cfg Config) *StatsManager {
 	return &StatsManager{
 		stats: ServerStats{
 			StartTime:            time.Now(),
-			Version:              "1.0.0", // Versão hardcoded do servidor
+			Version:              serverVersion,
 			LastCleanupTime:      time.Time{},
 			StaleNetworksRemoved: 0,
 		},
-		config: cfg,
+		config:   cfg,
+		messages: make(map[smodels.MessageType]*messageMetrics),
 	}
 }
 
 // UpdateStats atualiza as estatísticas com base nos dados atuais do servidor
 func (sm *StatsManager) UpdateStats(activeConnections, activeNetworks int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
 	// Track if we hit a new peak for logging
 	connectionPeak := false
 	networksPeak := false
@@ -124,6 +157,101 @@ func (sm *StatsManager) UpdateCleanupStats(numRemoved int) {
 		"timestamp", sm.stats.LastCleanupTime.Format(time.RFC3339))
 }
 
+// metricsFor retorna as métricas de um tipo de mensagem, criando-as se necessário.
+// Deve ser chamado com o mutex travado.
+func (sm *StatsManager) metricsFor(msgType smodels.MessageType) *messageMetrics {
+	m, ok := sm.messages[msgType]
+	if ok {
+		return m
+	}
+
+	if len(sm.messages) >= maxTrackedMsgTypes {
+		msgType = otherMessageTypeKey
+		if m, ok := sm.messages[msgType]; ok {
+			return m
+		}
+	}
+
+	m = &messageMetrics{samples: make([]time.Duration, 0, latencyWindowSize)}
+	sm.messages[msgType] = m
+	return m
+}
+
+// RecordMessageDuration registra o tempo de processamento de uma mensagem
+func (sm *StatsManager) RecordMessageDuration(msgType smodels.MessageType, duration time.Duration) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	m := sm.metricsFor(msgType)
+	m.count++
+	if duration > m.maxSample {
+		m.maxSample = duration
+	}
+
+	if len(m.samples) < latencyWindowSize {
+		m.samples = append(m.samples, duration)
+	} else {
+		m.samples[m.next] = duration
+	}
+	m.next = (m.next + 1) % latencyWindowSize
+}
+
+// RecordMessageError registra uma resposta de erro para um tipo de mensagem
+func (sm *StatsManager) RecordMessageError(msgType smodels.MessageType) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.metricsFor(msgType).errors++
+}
+
+// GetMessageStats retorna as métricas por tipo de mensagem com percentis da janela móvel
+func (sm *StatsManager) GetMessageStats() map[string]MessageTypeStats {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	result := make(map[string]MessageTypeStats, len(sm.messages))
+	for msgType, m := range sm.messages {
+		sorted := make([]time.Duration, len(m.samples))
+		copy(sorted, m.samples)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		stats := MessageTypeStats{
+			Count:  m.count,
+			Errors: m.errors,
+			P50Ms:  durationMs(percentile(sorted, 0.50)),
+			P90Ms:  durationMs(percentile(sorted, 0.90)),
+			P99Ms:  durationMs(percentile(sorted, 0.99)),
+			MaxMs:  durationMs(m.maxSample),
+		}
+		if m.count > 0 {
+			stats.ErrorRate = float64(m.errors) / float64(m.count)
+		}
+		result[string(msgType)] = stats
+	}
+	return result
+}
+
+// percentile retorna o valor no percentil p (0..1) de uma lista já ordenada
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
+
+// durationMs converte uma duração para milissegundos
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// SetConfig substitui a configuração exibida no endpoint /stats (usado no reload)
+func (sm *StatsManager) SetConfig(cfg Config) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.config = cfg
+}
+
 // GetStats retorna uma cópia da estrutura de estatísticas atual
 func (sm *StatsManager) GetStats() ServerStats {
 	sm.mu.RLock()
@@ -145,7 +273,8 @@ func (sm *StatsManager) HandleStatsEndpoint(w http.ResponseWriter, r *http.Reque
 
 	// Cria resposta com estatísticas atuais
 	statsResponse := map[string]interface{}{
-		"server_stats": stats,
+		"server_stats":  stats,
+		"message_stats": sm.GetMessageStats(),
 		"config": map[string]interface{}{
 			"max_clients_per_network": sm.config.MaxClientsPerNetwork,
 			"network_expiry_days":     sm.config.NetworkExpiryDays,
@@ -0,0 +1,49 @@
+package main
+
+// NetworkStore persists networks, memberships and key state for the server.
+// SupabaseManager is the production implementation; MemoryStore keeps everything
+// in process for local development and protocol tests.
+type NetworkStore interface {
+	CreateNetwork(network SupabaseNetwork) error
+	CreateNetworkWithOwner(network SupabaseNetwork, computerName, peerIP string) error
+	GetNetwork(networkID string) (SupabaseNetwork, error)
+	NetworkExists(networkID string) (bool, error)
+	UpdateNetworkActivity(networkID string) error
+	UpdateNetworkName(networkID, newName string) error
+	UpdateNetworkOwner(networkID, ownerPublicKey string) error
+	UpdateNetworkOwnerPolicy(networkID, policy string) error
+	UpdateNetworkPIN(networkID, pin string) error
+	ChangeNetworkSubnet(networkID, subnet string) error
+	DeleteNetwork(networkID string) error
+	GetStaleNetworks(expiryDays int) ([]SupabaseNetwork, error)
+	ListNetworks() ([]SupabaseNetwork, error)
+	ListPublicNetworks(tag string, limit int) ([]SupabaseNetwork, error)
+	GetNetworksByOwner(publicKey string) ([]SupabaseNetwork, error)
+	GetMemberCounts(networkIDs []string) (map[string]int, error)
+
+	AddComputerToNetwork(networkID, publicKey, computerName, peerIp string) error
+	RemoveComputerFromNetwork(networkID, publicKey string) error
+	GetComputersInNetwork(networkID string) ([]ComputerNetwork, error)
+	GetComputerInNetwork(networkID, publicKey string) (ComputerNetwork, error)
+	IsComputerInNetwork(networkID, publicKey string) (bool, error)
+	GetComputerNetworks(publicKey string) ([]ComputerNetwork, error)
+	GetUsedIPsForNetwork(networkID string) ([]string, error)
+	UpdateClientNameInNetworks(publicKey, newName string) error
+
+	BanComputer(networkID, publicKey, reason string) error
+	IsComputerBanned(networkID, publicKey string) (bool, error)
+	ReserveIP(networkID, publicKey, peerIP string) error
+	ReleaseIP(networkID, publicKey string) error
+	GetReservedIPs(networkID string) (map[string]string, error)
+
+	SetRecoveryCodeHash(publicKey, codeHash string) error
+	GetRecoveryCodeHash(publicKey string) (string, error)
+	IsKeyRevoked(publicKey string) (bool, error)
+	MigratePublicKey(oldKey, newKey string) (int, int, error)
+}
+
+// Both stores must keep satisfying the interface
+var (
+	_ NetworkStore = (*SupabaseManager)(nil)
+	_ NetworkStore = (*MemoryStore)(nil)
+)
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// maxAvoidedSubnets bounds the local CIDRs a client may ask the server to avoid
+const maxAvoidedSubnets = 64
+
+// errNoFreeSubnet is returned when every candidate subnet overlaps one to avoid
+var errNoFreeSubnet = errors.New("no free subnet")
+
+// networkSubnet returns the virtual /24 of a network
+func networkSubnet(network SupabaseNetwork) *net.IPNet {
+	if network.Subnet != "" {
+		if _, subnet, err := net.ParseCIDR(network.Subnet); err == nil {
+			return subnet
+		}
+	}
+	_, subnet, _ := net.ParseCIDR(smodels.DefaultSubnet)
+	return subnet
+}
+
+// subnetIP returns the address of host (1-254) inside a /24
+func subnetIP(subnet *net.IPNet, host int) string {
+	ip := subnet.IP.To4()
+	return net.IPv4(ip[0], ip[1], ip[2], byte(host)).String()
+}
+
+// renumberIP moves ip to subnet, keeping its last octet, so members keep telling each
+// other apart by the same number after a subnet change
+func renumberIP(ip string, subnet *net.IPNet) string {
+	parsed := net.ParseIP(ip).To4()
+	if parsed == nil {
+		return ip
+	}
+	return subnetIP(subnet, int(parsed[3]))
+}
+
+// pickSubnet chooses a /24 inside 10.0.0.0/8 other than current that overlaps none of
+// avoid. Candidates go up from 10.11.0.0/24, so the choice is the same for the same
+// input and stays close to the default.
+func pickSubnet(current string, avoid []string) (string, error) {
+	var avoided []*net.IPNet
+	for _, cidr := range avoid {
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return "", fmt.Errorf("invalid subnet %q", cidr)
+		}
+		avoided = append(avoided, subnet)
+	}
+	if current == "" {
+		current = smodels.DefaultSubnet
+	}
+
+	for i := 0; i < 256; i++ {
+		second := byte((11 + i) % 256)
+		if second == 0 {
+			continue
+		}
+		candidate := &net.IPNet{IP: net.IPv4(10, second, 0, 0).To4(), Mask: net.CIDRMask(24, 32)}
+		if candidate.String() == current || overlapsAny(candidate, avoided) {
+			continue
+		}
+		return candidate.String(), nil
+	}
+	return "", errNoFreeSubnet
+}
+
+// overlapsAny reports whether subnet overlaps any of the others
+func overlapsAny(subnet *net.IPNet, others []*net.IPNet) bool {
+	for _, other := range others {
+		if subnet.Contains(other.IP) || other.Contains(subnet.IP) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/itxtoledo/govpn/cmd/server/logger"
+	"github.com/itxtoledo/govpn/libs/logger"
+	"github.com/supabase-community/postgrest-go"
 	"github.com/supabase-community/supabase-go"
 )
 
@@ -17,6 +21,10 @@ type SupabaseNetwork struct {
 	OwnerPublicKey string    `json:"owner_public_key"`
 	CreatedAt      time.Time `json:"created_at"`
 	LastActive     time.Time `json:"last_active"`
+	IsPublic       bool      `json:"is_public"`
+	Tags           []string  `json:"tags"`
+	OwnerPolicy    string    `json:"owner_policy"`
+	Subnet         string    `json:"subnet,omitempty"` // Virtual /24 of the network, smodels.DefaultSubnet if empty
 }
 
 // SupabaseManager handles all Supabase database operations for the server
@@ -24,20 +32,42 @@ type SupabaseManager struct {
 	client        *supabase.Client
 	networksTable string
 	logLevel      string
+
+	// Read-through caches for hot lookups, invalidated on writes
+	networkCache  *ttlCache[SupabaseNetwork]
+	computerCache *ttlCache[ComputerNetwork]
 }
 
-// NewSupabaseManager creates a new instance of the Supabase manager
-func NewSupabaseManager(supabaseURL, supabaseKey, networksTable, logLevel string) (*SupabaseManager, error) {
+// NewSupabaseManager creates a new instance of the Supabase manager.
+// cacheTTL controls how long GetNetwork/GetComputerInNetwork results are reused (0 disables caching).
+func NewSupabaseManager(supabaseURL, supabaseKey, networksTable, logLevel string, cacheTTL time.Duration) (*SupabaseManager, error) {
 	client, err := supabase.NewClient(supabaseURL, supabaseKey, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Supabase client: %w", err)
 	}
 
-	return &SupabaseManager{
+	sm := &SupabaseManager{
 		client:        client,
 		networksTable: networksTable,
 		logLevel:      logLevel,
-	}, nil
+		networkCache:  newTTLCache[SupabaseNetwork](cacheTTL),
+		computerCache: newTTLCache[ComputerNetwork](cacheTTL),
+	}
+	if err := sm.checkMigrations(); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+// computerCacheKey builds the cache key for a computer membership
+func computerCacheKey(networkID, publicKey string) string {
+	return networkID + "|" + publicKey
+}
+
+// invalidateNetwork drops a cached network and all cached memberships of it
+func (sm *SupabaseManager) invalidateNetwork(networkID string) {
+	sm.networkCache.Delete(networkID)
+	sm.computerCache.DeletePrefix(networkID + "|")
 }
 
 // CreateNetwork inserts a new network into the Supabase database
@@ -49,6 +79,12 @@ func (sm *SupabaseManager) CreateNetwork(network SupabaseNetwork) error {
 		"owner_public_key": network.OwnerPublicKey,
 		"created_at":       network.CreatedAt.Format(time.RFC3339),
 		"last_active":      network.LastActive.Format(time.RFC3339),
+		"is_public":        network.IsPublic,
+		"tags":             network.Tags,
+		"owner_policy":     network.OwnerPolicy,
+	}
+	if network.Tags == nil {
+		networkData["tags"] = []string{}
 	}
 
 	if sm.logLevel == "debug" {
@@ -60,11 +96,102 @@ func (sm *SupabaseManager) CreateNetwork(network SupabaseNetwork) error {
 		return fmt.Errorf("failed to create network in Supabase: %w", err)
 	}
 
+	sm.invalidateNetwork(network.ID)
+
+	return nil
+}
+
+// postgrestFunctionNotFound is the PostgREST error code for calling an RPC that does not exist
+const postgrestFunctionNotFound = "PGRST202"
+
+// postgresNotNullViolation is the Postgres error code for a NULL written to a NOT NULL column
+const postgresNotNullViolation = "23502"
+
+// errCreateNetworkMigrationMissing reports a database without create_network_with_owner
+var errCreateNetworkMigrationMissing = errors.New("create_network_with_owner is missing, apply migrations/006_create_network_with_owner.sql")
+
+// checkMigrations makes sure the functions the server calls exist, so a database that
+// misses a migration is reported at startup instead of on the first request
+func (sm *SupabaseManager) checkMigrations() error {
+	// Every argument is NULL, so an existing function fails on the first insert and writes nothing
+	body := sm.client.Rpc("create_network_with_owner", "", map[string]interface{}{
+		"network_id":    nil,
+		"network_name":  nil,
+		"network_pin":   nil,
+		"owner_key":     nil,
+		"is_public":     nil,
+		"tags":          nil,
+		"owner_policy":  nil,
+		"computer_name": nil,
+		"peer_ip":       nil,
+	})
+
+	var result struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return fmt.Errorf("failed to check migrations: unexpected response %q", body)
+	}
+	switch result.Code {
+	case postgrestFunctionNotFound:
+		return errCreateNetworkMigrationMissing
+	case postgresNotNullViolation:
+		return nil
+	default:
+		return fmt.Errorf("failed to check migrations: unexpected response %q", body)
+	}
+}
+
+// CreateNetworkWithOwner inserts a network and its owner's membership in a single database
+// transaction (see migrations/006_create_network_with_owner.sql)
+func (sm *SupabaseManager) CreateNetworkWithOwner(network SupabaseNetwork, computerName, peerIP string) error {
+	tags := network.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	if sm.logLevel == "debug" {
+		logger.Debug("Creating network with owner in Supabase", "networkID", network.ID, "networkName", network.Name)
+	}
+
+	body := sm.client.Rpc("create_network_with_owner", "", map[string]interface{}{
+		"network_id":    network.ID,
+		"network_name":  network.Name,
+		"network_pin":   network.PIN,
+		"owner_key":     network.OwnerPublicKey,
+		"is_public":     network.IsPublic,
+		"tags":          tags,
+		"owner_policy":  network.OwnerPolicy,
+		"computer_name": computerName,
+		"peer_ip":       peerIP,
+	})
+
+	var result struct {
+		NetworkID *string `json:"network_id"`
+		Code      string  `json:"code"`
+		Message   string  `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return fmt.Errorf("failed to create network: unexpected response %q", body)
+	}
+	if result.NetworkID == nil {
+		if result.Code == postgrestFunctionNotFound {
+			return fmt.Errorf("failed to create network: %w", errCreateNetworkMigrationMissing)
+		}
+		return fmt.Errorf("failed to create network: %s", result.Message)
+	}
+
+	sm.invalidateNetwork(network.ID)
+
 	return nil
 }
 
 // GetNetwork fetches a network from the Supabase database by its ID
 func (sm *SupabaseManager) GetNetwork(networkID string) (SupabaseNetwork, error) {
+	if network, ok := sm.networkCache.Get(networkID); ok {
+		return network, nil
+	}
+
 	var networks []SupabaseNetwork
 	data, _, err := sm.client.From(sm.networksTable).Select("*", "", false).Eq("id", networkID).Execute()
 	if err != nil {
@@ -79,6 +206,7 @@ func (sm *SupabaseManager) GetNetwork(networkID string) (SupabaseNetwork, error)
 		return SupabaseNetwork{}, fmt.Errorf("network not found: %s", networkID)
 	}
 
+	sm.networkCache.Set(networkID, networks[0])
 	return networks[0], nil
 }
 
@@ -98,6 +226,8 @@ func (sm *SupabaseManager) UpdateNetworkActivity(networkID string) error {
 		return fmt.Errorf("failed to update network activity: %w", err)
 	}
 
+	sm.networkCache.Delete(networkID)
+
 	return nil
 }
 
@@ -117,6 +247,89 @@ func (sm *SupabaseManager) UpdateNetworkName(networkID, newName string) error {
 		return fmt.Errorf("failed to update network name: %w", err)
 	}
 
+	sm.networkCache.Delete(networkID)
+
+	return nil
+}
+
+// UpdateNetworkOwner transfers ownership of a network to another public key
+func (sm *SupabaseManager) UpdateNetworkOwner(networkID, ownerPublicKey string) error {
+	updateData := map[string]interface{}{
+		"owner_public_key": ownerPublicKey,
+		"last_active":      time.Now().Format(time.RFC3339),
+	}
+
+	if sm.logLevel == "debug" {
+		logger.Debug("Updating owner for network", "networkID", networkID, "ownerPublicKey", ownerPublicKey)
+	}
+
+	_, _, err := sm.client.From(sm.networksTable).Update(updateData, "", "").Eq("id", networkID).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to update network owner: %w", err)
+	}
+
+	sm.networkCache.Delete(networkID)
+
+	return nil
+}
+
+// UpdateNetworkOwnerPolicy changes what happens to a network when its owner disconnects
+func (sm *SupabaseManager) UpdateNetworkOwnerPolicy(networkID, policy string) error {
+	updateData := map[string]interface{}{
+		"owner_policy": policy,
+	}
+
+	_, _, err := sm.client.From(sm.networksTable).Update(updateData, "", "").Eq("id", networkID).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to update network owner policy: %w", err)
+	}
+
+	sm.networkCache.Delete(networkID)
+
+	return nil
+}
+
+// UpdateNetworkPIN replaces the PIN new members must give to join a network
+func (sm *SupabaseManager) UpdateNetworkPIN(networkID, pin string) error {
+	updateData := map[string]interface{}{
+		"pin": pin,
+	}
+
+	_, _, err := sm.client.From(sm.networksTable).Update(updateData, "", "").Eq("id", networkID).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to update network PIN: %w", err)
+	}
+
+	sm.networkCache.Delete(networkID)
+
+	return nil
+}
+
+// ChangeNetworkSubnet moves a network to another /24 and renumbers its members and
+// reservations in a single transaction (see migrations/008_network_subnet.sql)
+func (sm *SupabaseManager) ChangeNetworkSubnet(networkID, subnet string) error {
+	if sm.logLevel == "debug" {
+		logger.Debug("Changing network subnet", "networkID", networkID, "subnet", subnet)
+	}
+
+	body := sm.client.Rpc("change_network_subnet", "", map[string]string{
+		"target_network": networkID,
+		"new_subnet":     subnet,
+	})
+
+	var result struct {
+		Subnet  *string `json:"subnet"`
+		Message string  `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return fmt.Errorf("failed to change subnet: unexpected response %q", body)
+	}
+	if result.Subnet == nil {
+		return fmt.Errorf("failed to change subnet: %s", result.Message)
+	}
+
+	sm.invalidateNetwork(networkID)
+
 	return nil
 }
 
@@ -131,6 +344,8 @@ func (sm *SupabaseManager) DeleteNetwork(networkID string) error {
 		return fmt.Errorf("failed to delete network: %w", err)
 	}
 
+	sm.invalidateNetwork(networkID)
+
 	return nil
 }
 
@@ -176,6 +391,67 @@ func (sm *SupabaseManager) GetStaleNetworks(expiryDays int) ([]SupabaseNetwork,
 	return staleNetworks, nil
 }
 
+// ListNetworks fetches every network stored in the Supabase database
+func (sm *SupabaseManager) ListNetworks() ([]SupabaseNetwork, error) {
+	var networks []SupabaseNetwork
+	data, _, err := sm.client.From(sm.networksTable).Select("*", "", false).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &networks); err != nil {
+		return nil, fmt.Errorf("failed to parse networks data: %w", err)
+	}
+
+	return networks, nil
+}
+
+// ListPublicNetworks returns up to limit public networks, most recently active first.
+// If tag is not empty only networks carrying that tag are returned.
+func (sm *SupabaseManager) ListPublicNetworks(tag string, limit int) ([]SupabaseNetwork, error) {
+	query := sm.client.From(sm.networksTable).Select("*", "", false).Eq("is_public", "true")
+	if tag != "" {
+		query = query.Contains("tags", []string{tag})
+	}
+
+	var networks []SupabaseNetwork
+	data, _, err := query.Order("last_active", &postgrest.OrderOpts{Ascending: false}).Limit(limit, "").Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list public networks: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &networks); err != nil {
+		return nil, fmt.Errorf("failed to parse networks data: %w", err)
+	}
+
+	return networks, nil
+}
+
+// GetMemberCounts returns how many computers joined each of the given networks
+func (sm *SupabaseManager) GetMemberCounts(networkIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(networkIDs))
+	if len(networkIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		NetworkID string `json:"network_id"`
+	}
+	data, _, err := sm.client.From("computer_networks").Select("network_id", "", false).In("network_id", networkIDs).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count network members: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse computer network data: %w", err)
+	}
+
+	for _, row := range rows {
+		counts[row.NetworkID]++
+	}
+	return counts, nil
+}
+
 // NetworkExists checks if a network exists with the given ID
 func (sm *SupabaseManager) NetworkExists(networkID string) (bool, error) {
 	var networks []map[string]interface{}
@@ -191,22 +467,22 @@ func (sm *SupabaseManager) NetworkExists(networkID string) (bool, error) {
 	return len(networks) > 0, nil
 }
 
-// PublicKeyHasNetwork checks if a public key already has an associated network
-func (sm *SupabaseManager) PublicKeyHasNetwork(publicKey string) (bool, string, error) {
-	var networks []map[string]interface{}
-	data, _, err := sm.client.From(sm.networksTable).Select("id", "", false).Eq("owner_public_key", publicKey).Execute()
+// GetNetworksByOwner returns the networks owned by a public key, oldest first
+func (sm *SupabaseManager) GetNetworksByOwner(publicKey string) ([]SupabaseNetwork, error) {
+	var networks []SupabaseNetwork
+	data, _, err := sm.client.From(sm.networksTable).Select("*", "", false).Eq("owner_public_key", publicKey).Execute()
 	if err != nil {
-		return false, "", fmt.Errorf("failed to check if public key has network: %w", err)
+		return nil, fmt.Errorf("failed to get networks by owner: %w", err)
 	}
 
 	if err := json.Unmarshal(data, &networks); err != nil {
-		return false, "", fmt.Errorf("failed to parse network data: %w", err)
+		return nil, fmt.Errorf("failed to parse network data: %w", err)
 	}
 
-	if len(networks) > 0 {
-		return true, networks[0]["id"].(string), nil
-	}
-	return false, "", nil
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].CreatedAt.Before(networks[j].CreatedAt)
+	})
+	return networks, nil
 }
 
 // ComputerNetwork represents a record in the computer_networks table linking computers to networks
@@ -240,6 +516,8 @@ func (sm *SupabaseManager) AddComputerToNetwork(networkID, publicKey, computerNa
 		return fmt.Errorf("failed to add computer to network in Supabase: %w", err)
 	}
 
+	sm.computerCache.Delete(computerCacheKey(networkID, publicKey))
+
 	return nil
 }
 
@@ -258,6 +536,8 @@ func (sm *SupabaseManager) UpdateComputerNetworkConnection(networkID, publicKey
 		return fmt.Errorf("failed to update computer network connection: %w", err)
 	}
 
+	sm.computerCache.Delete(computerCacheKey(networkID, publicKey))
+
 	return nil
 }
 
@@ -272,6 +552,8 @@ func (sm *SupabaseManager) RemoveComputerFromNetwork(networkID, publicKey string
 		return fmt.Errorf("failed to remove computer from network: %w", err)
 	}
 
+	sm.computerCache.Delete(computerCacheKey(networkID, publicKey))
+
 	return nil
 }
 
@@ -341,7 +623,13 @@ func (sm *SupabaseManager) GetComputerNetworks(publicKey string) ([]ComputerNetw
 	return computerNetworks, nil
 }
 
+// GetComputerInNetwork fetches the membership record of a computer in a network
 func (sm *SupabaseManager) GetComputerInNetwork(networkID, publicKey string) (ComputerNetwork, error) {
+	cacheKey := computerCacheKey(networkID, publicKey)
+	if computer, ok := sm.computerCache.Get(cacheKey); ok {
+		return computer, nil
+	}
+
 	var computerNetworks []ComputerNetwork
 	data, _, err := sm.client.From("computer_networks").Select("*", "", false).Eq("network_id", networkID).Eq("public_key", publicKey).Execute()
 	if err != nil {
@@ -356,6 +644,7 @@ func (sm *SupabaseManager) GetComputerInNetwork(networkID, publicKey string) (Co
 		return ComputerNetwork{}, fmt.Errorf("computer not found in network")
 	}
 
+	sm.computerCache.Set(cacheKey, computerNetworks[0])
 	return computerNetworks[0], nil
 }
 
@@ -389,5 +678,186 @@ func (sm *SupabaseManager) UpdateClientNameInNetworks(publicKey, newName string)
 		return fmt.Errorf("failed to update client name in Supabase: %w", err)
 	}
 
+	sm.computerCache.DeleteFunc(func(key string) bool {
+		return strings.HasSuffix(key, "|"+publicKey)
+	})
+
+	return nil
+}
+
+// BanComputer keeps a public key from joining a network again
+func (sm *SupabaseManager) BanComputer(networkID, publicKey, reason string) error {
+	banData := map[string]interface{}{
+		"network_id": networkID,
+		"public_key": publicKey,
+		"reason":     reason,
+		"banned_at":  time.Now().Format(time.RFC3339),
+	}
+
+	_, _, err := sm.client.From("network_bans").Insert(banData, true, "network_id,public_key", "", "").Execute()
+	if err != nil {
+		return fmt.Errorf("failed to ban computer: %w", err)
+	}
+
+	return nil
+}
+
+// IsComputerBanned checks if a public key was banned from a network
+func (sm *SupabaseManager) IsComputerBanned(networkID, publicKey string) (bool, error) {
+	var rows []map[string]interface{}
+	data, _, err := sm.client.From("network_bans").Select("public_key", "", false).Eq("network_id", networkID).Eq("public_key", publicKey).Execute()
+	if err != nil {
+		return false, fmt.Errorf("failed to check ban: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("failed to parse ban data: %w", err)
+	}
+
+	return len(rows) > 0, nil
+}
+
+// ReserveIP keeps peerIP for a public key in a network, replacing its previous reservation
+func (sm *SupabaseManager) ReserveIP(networkID, publicKey, peerIP string) error {
+	reservationData := map[string]interface{}{
+		"network_id":  networkID,
+		"public_key":  publicKey,
+		"peer_ip":     peerIP,
+		"reserved_at": time.Now().Format(time.RFC3339),
+	}
+
+	_, _, err := sm.client.From("network_ip_reservations").Insert(reservationData, true, "network_id,public_key", "", "").Execute()
+	if err != nil {
+		return fmt.Errorf("failed to reserve IP: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseIP drops the IP reserved for a public key in a network
+func (sm *SupabaseManager) ReleaseIP(networkID, publicKey string) error {
+	_, _, err := sm.client.From("network_ip_reservations").Delete("", "").Eq("network_id", networkID).Eq("public_key", publicKey).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to release IP: %w", err)
+	}
+
+	return nil
+}
+
+// GetReservedIPs returns the IPs reserved in a network by public key
+func (sm *SupabaseManager) GetReservedIPs(networkID string) (map[string]string, error) {
+	var rows []struct {
+		PublicKey string `json:"public_key"`
+		PeerIP    string `json:"peer_ip"`
+	}
+	data, _, err := sm.client.From("network_ip_reservations").Select("public_key,peer_ip", "", false).Eq("network_id", networkID).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reserved IPs: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse reserved IP data: %w", err)
+	}
+
+	reserved := make(map[string]string, len(rows))
+	for _, row := range rows {
+		reserved[row.PublicKey] = row.PeerIP
+	}
+	return reserved, nil
+}
+
+// SetRecoveryCodeHash stores the recovery code hash of a public key, replacing any previous one
+func (sm *SupabaseManager) SetRecoveryCodeHash(publicKey, codeHash string) error {
+	recoveryData := map[string]interface{}{
+		"public_key": publicKey,
+		"code_hash":  codeHash,
+		"created_at": time.Now().Format(time.RFC3339),
+	}
+
+	_, _, err := sm.client.From("key_recovery_codes").Insert(recoveryData, true, "public_key", "", "").Execute()
+	if err != nil {
+		return fmt.Errorf("failed to store recovery code: %w", err)
+	}
+
 	return nil
 }
+
+// GetRecoveryCodeHash returns the recovery code hash of a public key, or "" if none was created
+func (sm *SupabaseManager) GetRecoveryCodeHash(publicKey string) (string, error) {
+	var rows []struct {
+		CodeHash string `json:"code_hash"`
+	}
+	data, _, err := sm.client.From("key_recovery_codes").Select("code_hash", "", false).Eq("public_key", publicKey).Execute()
+	if err != nil {
+		return "", fmt.Errorf("failed to get recovery code: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return "", fmt.Errorf("failed to parse recovery code data: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return "", nil
+	}
+	return rows[0].CodeHash, nil
+}
+
+// IsKeyRevoked checks if a public key was replaced by a key migration
+func (sm *SupabaseManager) IsKeyRevoked(publicKey string) (bool, error) {
+	var rows []map[string]interface{}
+	data, _, err := sm.client.From("revoked_keys").Select("public_key", "", false).Eq("public_key", publicKey).Execute()
+	if err != nil {
+		return false, fmt.Errorf("failed to check revoked key: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("failed to parse revoked key data: %w", err)
+	}
+
+	return len(rows) > 0, nil
+}
+
+// MigratePublicKey moves every network and membership of oldKey to newKey and revokes oldKey.
+// The move runs in a single database transaction (see migrations/007_member_management.sql).
+// It returns the number of networks and memberships that moved.
+func (sm *SupabaseManager) MigratePublicKey(oldKey, newKey string) (int, int, error) {
+	// Collect what is about to move so the caches can be dropped afterwards
+	owned, err := sm.GetNetworksByOwner(oldKey)
+	if err != nil {
+		return 0, 0, err
+	}
+	memberships, err := sm.GetComputerNetworks(oldKey)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if sm.logLevel == "debug" {
+		logger.Debug("Migrating public key", "oldKey", oldKey, "newKey", newKey)
+	}
+
+	body := sm.client.Rpc("migrate_public_key", "", map[string]string{
+		"old_key": oldKey,
+		"new_key": newKey,
+	})
+
+	var result struct {
+		Networks    *int   `json:"networks"`
+		Memberships *int   `json:"memberships"`
+		Message     string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return 0, 0, fmt.Errorf("failed to migrate public key: unexpected response %q", body)
+	}
+	if result.Networks == nil || result.Memberships == nil {
+		return 0, 0, fmt.Errorf("failed to migrate public key: %s", result.Message)
+	}
+
+	for _, network := range owned {
+		sm.networkCache.Delete(network.ID)
+	}
+	for _, membership := range memberships {
+		sm.invalidateNetwork(membership.NetworkID)
+	}
+
+	return *result.Networks, *result.Memberships, nil
+}
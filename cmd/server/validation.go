@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// fieldMaxLengths limits the length (in characters) of known request fields
+var fieldMaxLengths = map[string]int{
+	"network_name":      64,
+	"computer_name":     64,
+	"computername":      64,
+	"client_name":       64,
+	"network_id":        64,
+	"cursor":            64,
+	"pin":               32,
+	"public_key":        128,
+	"old_public_key":    128,
+	"recovery_code":     64,
+	"target_id":         128,
+	"target_public_key": 128,
+	"idempotency_key":   128,
+	"signature":         128,
+	"tag":               maxTagLength,
+	"reason":            maxBanReasonLength,
+}
+
+// Limits for the tags of a public network
+const (
+	maxTags      = 8
+	maxTagLength = 32
+)
+
+// validatePayload checks a message payload before it reaches a handler.
+// It verifies the payload size, UTF-8 validity and the length of known fields.
+func (s *WebSocketServer) validatePayload(payload []byte) []smodels.FieldError {
+	var errs []smodels.FieldError
+
+	if s.config.MaxPayloadSize > 0 && len(payload) > s.config.MaxPayloadSize {
+		errs = append(errs, smodels.FieldError{
+			Field:   "payload",
+			Message: fmt.Sprintf("must be at most %d bytes", s.config.MaxPayloadSize),
+		})
+		return errs
+	}
+
+	if !utf8.Valid(payload) {
+		errs = append(errs, smodels.FieldError{
+			Field:   "payload",
+			Message: "must be valid UTF-8",
+		})
+		return errs
+	}
+
+	// Payloads that are not JSON objects are left for the handler to reject
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil
+	}
+
+	for field, maxLen := range fieldMaxLengths {
+		raw, ok := fields[field]
+		if !ok {
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			errs = append(errs, smodels.FieldError{
+				Field:   field,
+				Message: "must be a string",
+			})
+			continue
+		}
+
+		if utf8.RuneCountInString(value) > maxLen {
+			errs = append(errs, smodels.FieldError{
+				Field:   field,
+				Message: fmt.Sprintf("must be at most %d characters", maxLen),
+			})
+		}
+	}
+
+	if raw, ok := fields["tags"]; ok {
+		if fieldErr, invalid := validateTags(raw); invalid {
+			errs = append(errs, fieldErr)
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// validateTags checks that tags is a short list of short strings
+func validateTags(raw json.RawMessage) (smodels.FieldError, bool) {
+	var tags []string
+	if err := json.Unmarshal(raw, &tags); err != nil {
+		return smodels.FieldError{Field: "tags", Message: "must be a list of strings"}, true
+	}
+
+	if len(tags) > maxTags {
+		return smodels.FieldError{Field: "tags", Message: fmt.Sprintf("must have at most %d tags", maxTags)}, true
+	}
+
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return smodels.FieldError{Field: "tags", Message: fmt.Sprintf("each tag must be at most %d characters", maxTagLength)}, true
+		}
+	}
+	return smodels.FieldError{}, false
+}
+
+// normalizeTags trims, lowercases and de-duplicates tags, dropping empty ones
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// sendValidationError sends an error response listing the invalid fields
+func (s *WebSocketServer) sendValidationError(conn *websocket.Conn, fieldErrors []smodels.FieldError, originalID string) {
+	s.sendErrorResponse(conn, smodels.ErrorResponse{
+		Code:   smodels.ErrInvalidRequest,
+		Error:  "Invalid request",
+		Fields: fieldErrors,
+	}, originalID)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// Build information, set at build time with
+//
+//	go build -ldflags "-X main.serverVersion=1.2.0 -X main.serverCommit=$(git rev-parse HEAD) -X main.serverBuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// serverVersion is reported by /version, /stats, /api/server-info and ServerCapabilities.
+var (
+	serverVersion   = "1.0.0"
+	serverCommit    = ""
+	serverBuildDate = ""
+)
+
+var (
+	vcsCommitOnce sync.Once
+	vcsCommit     string
+)
+
+// buildCommit returns the commit the server was built from. Without -ldflags it falls
+// back to the revision the go tool stamps into binaries built inside a git checkout.
+func buildCommit() string {
+	if serverCommit != "" {
+		return serverCommit
+	}
+
+	vcsCommitOnce.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		modified := false
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				vcsCommit = setting.Value
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if vcsCommit != "" && modified {
+			vcsCommit += "-dirty"
+		}
+	})
+	return vcsCommit
+}
+
+// versionInfo describes this build
+func versionInfo() smodels.VersionResponse {
+	return smodels.VersionResponse{
+		Version:         serverVersion,
+		Commit:          buildCommit(),
+		BuildDate:       serverBuildDate,
+		GoVersion:       runtime.Version(),
+		ProtocolVersion: smodels.ProtocolVersion,
+	}
+}
+
+// handleVersionEndpoint reports the version and build of the server
+func handleVersionEndpoint(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, versionInfo())
+}
+
+// orUnknown returns s, or "unknown" when it is empty
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
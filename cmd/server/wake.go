@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+const (
+	// wakeTargetTTL is how long Wake-on-LAN details are kept after they were last reported;
+	// clients report them again on every connection
+	wakeTargetTTL = 30 * 24 * time.Hour
+	// maxWakeTargets bounds how many public keys may have Wake-on-LAN details at once
+	maxWakeTargets = 10000
+)
+
+// wakeTarget is what another computer on the same LAN needs to wake a computer up
+type wakeTarget struct {
+	mac        string
+	subnet     string
+	address    string // Public IP it reported from, shared by the computers behind the same NAT
+	reportedAt time.Time
+}
+
+// wakeTargetOf returns the Wake-on-LAN details publicKey left, unless they expired
+func (s *WebSocketServer) wakeTargetOf(publicKey string) (wakeTarget, bool) {
+	s.wakeMu.Lock()
+	defer s.wakeMu.Unlock()
+
+	target, ok := s.wakeTargets[publicKey]
+	if !ok || time.Since(target.reportedAt) > wakeTargetTTL {
+		return wakeTarget{}, false
+	}
+	return target, true
+}
+
+// isWakeable reports whether publicKey left Wake-on-LAN details
+func (s *WebSocketServer) isWakeable(publicKey string) bool {
+	_, ok := s.wakeTargetOf(publicKey)
+	return ok
+}
+
+// setWakeTarget records or, with a zero target, forgets the details of publicKey. It
+// returns false when a new key would exceed maxWakeTargets.
+func (s *WebSocketServer) setWakeTarget(publicKey string, target wakeTarget) bool {
+	s.wakeMu.Lock()
+	defer s.wakeMu.Unlock()
+
+	if target.mac == "" {
+		delete(s.wakeTargets, publicKey)
+		return true
+	}
+	if _, ok := s.wakeTargets[publicKey]; !ok && len(s.wakeTargets) >= maxWakeTargets {
+		if s.pruneWakeTargetsLocked() == 0 {
+			return false
+		}
+	}
+	s.wakeTargets[publicKey] = target
+	return true
+}
+
+// pruneWakeTargets forgets the Wake-on-LAN details that expired
+func (s *WebSocketServer) pruneWakeTargets() error {
+	s.wakeMu.Lock()
+	removed := s.pruneWakeTargetsLocked()
+	s.wakeMu.Unlock()
+
+	if removed > 0 {
+		logger.Info("Pruned expired wake targets", "removed", removed)
+	}
+	return nil
+}
+
+// pruneWakeTargetsLocked is pruneWakeTargets for callers holding s.wakeMu
+func (s *WebSocketServer) pruneWakeTargetsLocked() int {
+	removed := 0
+	cutoff := time.Now().Add(-wakeTargetTTL)
+	for publicKey, target := range s.wakeTargets {
+		if target.reportedAt.Before(cutoff) {
+			delete(s.wakeTargets, publicKey)
+			removed++
+		}
+	}
+	return removed
+}
+
+// connAddress returns the public IP a connection came from, "" when unknown
+func (s *WebSocketServer) connAddress(conn *websocket.Conn) string {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	if session, ok := s.sessions[conn]; ok {
+		return session.address
+	}
+	return ""
+}
+
+// handleWakeInfo records, or with an empty MAC forgets, how to wake the reporting computer
+func (s *WebSocketServer) handleWakeInfo(ctx context.Context, conn *websocket.Conn, req smodels.WakeInfoRequest, originalID string) {
+	publicKey, authenticated := s.authenticatedKey(conn)
+	if !authenticated {
+		publicKey = req.PublicKey
+	}
+	if publicKey == "" {
+		s.sendErrorSignal(conn, smodels.ErrPublicKeyRequired, "Public key is required", originalID)
+		return
+	}
+
+	var target wakeTarget
+	if req.MAC != "" {
+		mac, err := net.ParseMAC(req.MAC)
+		if err != nil || len(mac) != 6 {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid MAC address", originalID)
+			return
+		}
+		ip, subnet, err := net.ParseCIDR(req.Subnet)
+		if err != nil || ip.To4() == nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid subnet", originalID)
+			return
+		}
+		target = wakeTarget{mac: mac.String(), subnet: subnet.String(), address: s.connAddress(conn), reportedAt: time.Now()}
+	}
+
+	if !s.setWakeTarget(publicKey, target) {
+		logger.Warn("Refused wake info, too many wake targets", "publicKey", publicKey, "limit", maxWakeTargets)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "The server cannot keep more Wake-on-LAN details right now", originalID)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	logger.Info("Client reported wake info", "publicKey", publicKey, "wakeable", req.MAC != "")
+	s.sendSignal(conn, smodels.TypeWakeInfoResponse, smodels.WakeInfoResponse{Wakeable: req.MAC != ""}, originalID)
+	if networkID := s.clients[conn]; networkID != "" {
+		s.broadcastRoster(networkID)
+	}
+}
+
+// handleWake asks an online member behind the same public address as an offline member
+// to broadcast the magic packet that wakes it
+func (s *WebSocketServer) handleWake(ctx context.Context, conn *websocket.Conn, req smodels.WakeRequest, originalID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.clients[conn] != req.NetworkID {
+		s.sendErrorSignal(conn, smodels.ErrNotConnected, "Connect to the network to wake its computers", originalID)
+		return
+	}
+	if _, err := s.store.GetComputerInNetwork(req.NetworkID, req.TargetPublicKey); err != nil {
+		s.sendErrorSignal(conn, smodels.ErrTargetNotFound, "Target is not a member of the network", originalID)
+		return
+	}
+	if s.isComputerOnline(req.NetworkID, req.TargetPublicKey) {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Target is already online", originalID)
+		return
+	}
+	target, ok := s.wakeTargetOf(req.TargetPublicKey)
+	if !ok {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Target has not enabled Wake-on-LAN", originalID)
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "wake") {
+		return
+	}
+
+	var relay *websocket.Conn
+	if target.address != "" {
+		for _, member := range s.networks[req.NetworkID] {
+			if s.connAddress(member) == target.address {
+				relay = member
+				break
+			}
+		}
+	}
+	if relay == nil {
+		s.sendErrorSignal(conn, smodels.ErrNoWakeRelay, "No online computer is on the same network as the target", originalID)
+		return
+	}
+
+	requester := s.clientToPublicKey[conn]
+	relayKey := s.clientToPublicKey[relay]
+	if err := s.sendSignal(relay, smodels.TypeWakeRelay, smodels.WakeRelayNotification{
+		NetworkID:          req.NetworkID,
+		TargetPublicKey:    req.TargetPublicKey,
+		MAC:                target.mac,
+		Subnet:             target.subnet,
+		RequesterPublicKey: requester,
+	}, ""); err != nil {
+		logger.Error("Failed to send wake relay", "networkID", req.NetworkID, "relay", relayKey, "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Failed to reach the computer that sends the magic packet", originalID)
+		return
+	}
+
+	logger.Info("Wake relayed", "networkID", req.NetworkID, "target", req.TargetPublicKey, "relay", relayKey, "requester", requester)
+	s.sendSignal(conn, smodels.TypeWakeResponse, smodels.WakeResponse{
+		NetworkID:       req.NetworkID,
+		TargetPublicKey: req.TargetPublicKey,
+		RelayPublicKey:  relayKey,
+	}, originalID)
+}
@@ -5,15 +5,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
-	"github.com/itxtoledo/govpn/cmd/server/logger"
+	"github.com/itxtoledo/govpn/libs/logger"
 	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
 	"github.com/itxtoledo/govpn/libs/utils"
+	"google.golang.org/grpc"
 )
 
 // WebSocketServer manages the WebSocket connections and network handling
@@ -24,12 +27,49 @@ type WebSocketServer struct {
 	connectedComputers map[string]map[string]bool   // Maps networkID to map of publicKey to connected status
 	mu                 sync.RWMutex
 	config             Config
-	supabaseManager    *SupabaseManager
+	store              NetworkStore
 	upgrader           websocket.Upgrader
 	pinRegex           *regexp.Regexp
 
 	// Server statistics
 	statsManager *StatsManager
+	inFlight     map[*websocket.Conn]smodels.MessageType // Message type being handled per connection
+	inFlightMu   sync.Mutex
+
+	// Open connections in total and per remote IP, for MAX_TOTAL_CONNS and MAX_CONNS_PER_IP
+	totalConns   int
+	connsPerIP   map[string]int
+	connLimitsMu sync.Mutex
+
+	// Recent membership events per network, replayed to reconnecting clients
+	eventLogs map[string]*networkEventLog
+
+	// Activity counters per network, shown to owners with GetNetworkStats
+	networkStats *networkStatsTracker
+
+	// Bytes each network relayed through the server in the current minute
+	relayQuota *relayQuotaTracker
+
+	// Authentication state per connection
+	sessions   map[*websocket.Conn]*clientSession
+	sessionsMu sync.Mutex
+
+	// Responses remembered per client idempotency key
+	idempotencyCache *ttlCache[idempotentResponse]
+
+	// Periodic maintenance tasks such as the stale network cleanup
+	jobs *jobScheduler
+
+	// UDP endpoint clients use to detect their NAT type, nil when disabled
+	natProbe *natProbe
+
+	// gRPC admin service on ADMIN_GRPC_LISTEN, nil when disabled
+	adminGRPC *grpc.Server
+
+	// Wake-on-LAN details reported by each public key, kept after it disconnects until they
+	// expire. The roster and network list read them without s.mu, so they have their own lock.
+	wakeTargets map[string]wakeTarget
+	wakeMu      sync.Mutex
 
 	// Graceful shutdown
 	shutdownChan chan struct{}
@@ -37,16 +77,26 @@ type WebSocketServer struct {
 	isShutdown   bool
 }
 
+// NewWebSocketServer creates a server backed by the store selected in the configuration
 func NewWebSocketServer(cfg Config) (*WebSocketServer, error) {
-	pinRegex, err := utils.PINRegex()
-	if err != nil {
-		return nil, fmt.Errorf("failed to compile pin pattern: %w", err)
+	if cfg.StoreBackend == storeBackendMemory {
+		logger.Warn("Using the in-memory store, networks are lost when the server stops")
+		return NewWebSocketServerWithStore(cfg, NewMemoryStore())
 	}
 
-	supaMgr, err := NewSupabaseManager(cfg.SupabaseURL, cfg.SupabaseKey, cfg.SupabaseNetworksTable, cfg.LogLevel)
+	supaMgr, err := NewSupabaseManager(cfg.SupabaseURL, cfg.SupabaseKey, cfg.SupabaseNetworksTable, cfg.LogLevel, cfg.CacheTTL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Supabase manager: %w", err)
 	}
+	return NewWebSocketServerWithStore(cfg, supaMgr)
+}
+
+// NewWebSocketServerWithStore creates a server that persists its data in store
+func NewWebSocketServerWithStore(cfg Config, store NetworkStore) (*WebSocketServer, error) {
+	pinRegex, err := utils.PINRegex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile pin pattern: %w", err)
+	}
 
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
@@ -65,18 +115,44 @@ func NewWebSocketServer(cfg Config) (*WebSocketServer, error) {
 
 		connectedComputers: make(map[string]map[string]bool),
 		config:             cfg,
-		supabaseManager:    supaMgr,
+		store:              store,
 		upgrader:           upgrader,
 		pinRegex:           pinRegex,
 		statsManager:       statsManager,
+		inFlight:           make(map[*websocket.Conn]smodels.MessageType),
+		sessions:           make(map[*websocket.Conn]*clientSession),
+		connsPerIP:         make(map[string]int),
+		eventLogs:          make(map[string]*networkEventLog),
+		networkStats:       newNetworkStatsTracker(),
+		relayQuota:         newRelayQuotaTracker(),
+		wakeTargets:        make(map[string]wakeTarget),
+		idempotencyCache:   newTTLCache[idempotentResponse](cfg.IdempotencyTTL),
+		jobs:               newJobScheduler(),
 		shutdownChan:       make(chan struct{}),
 		httpServer:         &http.Server{},
 		isShutdown:         false,
 	}, nil
 }
 
-func (s *WebSocketServer) generateUniqueIP(networkID string) (string, error) {
-	usedIPs, err := s.supabaseManager.GetUsedIPsForNetwork(networkID)
+// assignIP returns the IP reserved for publicKey in a network, or a free one
+func (s *WebSocketServer) assignIP(networkID, publicKey string) (string, error) {
+	reserved, err := s.store.GetReservedIPs(networkID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get reserved IPs for network %s: %w", networkID, err)
+	}
+	if ip, ok := reserved[publicKey]; ok {
+		return ip, nil
+	}
+	network, err := s.store.GetNetwork(networkID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get network %s: %w", networkID, err)
+	}
+	return s.generateUniqueIP(networkID, networkSubnet(network), reserved)
+}
+
+// generateUniqueIP returns an IP of the network's subnet that is neither in use nor reserved
+func (s *WebSocketServer) generateUniqueIP(networkID string, subnet *net.IPNet, reserved map[string]string) (string, error) {
+	usedIPs, err := s.store.GetUsedIPsForNetwork(networkID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get used IPs for network %s: %w", networkID, err)
 	}
@@ -85,10 +161,13 @@ func (s *WebSocketServer) generateUniqueIP(networkID string) (string, error) {
 	for _, ip := range usedIPs {
 		usedIPSet[ip] = true
 	}
+	for _, ip := range reserved {
+		usedIPSet[ip] = true
+	}
 
 	const maxAttempts = 254 // Limit attempts to find an IP
 	for i := 0; i < maxAttempts; i++ {
-		ip := fmt.Sprintf("10.10.0.%d", i+1)
+		ip := subnetIP(subnet, i+1)
 		if !usedIPSet[ip] {
 			return ip, nil
 		}
@@ -98,6 +177,14 @@ func (s *WebSocketServer) generateUniqueIP(networkID string) (string, error) {
 }
 
 func (s *WebSocketServer) HandleWebSocketEndpoint(w http.ResponseWriter, r *http.Request) {
+	addr := s.clientAddress(r)
+	ip := addr.key()
+	if !s.acquireConnSlot(ip) {
+		rejectConnection(w, ip)
+		return
+	}
+	defer s.releaseConnSlot(ip)
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		logger.Error("Failed to upgrade connection", "error", err)
@@ -106,28 +193,45 @@ func (s *WebSocketServer) HandleWebSocketEndpoint(w http.ResponseWriter, r *http
 	logger.Info("WebSocket handshake successful", "remoteAddr", conn.RemoteAddr().String())
 	defer conn.Close()
 
+	if s.config.MaxMessageSize > 0 {
+		conn.SetReadLimit(s.config.MaxMessageSize)
+	}
+
 	s.statsManager.IncrementConnectionsTotal()
 	s.statsManager.UpdateStats(len(s.clients), len(s.networks))
 
 	publicKeyHeader := r.Header.Get("X-Client-ID")
-	if publicKeyHeader != "" {
-		logger.Debug("Client connected with public key", "publicKey", publicKeyHeader)
+	if err := s.openSession(r.Context(), conn, publicKeyHeader, ip); err != nil {
+		logger.Error("Failed to send authentication challenge", "remoteAddr", conn.RemoteAddr().String(), "error", err)
+		return
+	}
+	defer s.closeSession(conn)
+	ctx := s.connContext(conn)
 
-		// msgID, _ := utils.GenerateMessageID()
+	s.sendServerCapabilities(conn)
+	s.sendClientIPInfo(conn, addr)
+	s.sendMotd(conn)
 
-		req := smodels.GetComputerNetworksRequest{
-			BaseRequest: smodels.BaseRequest{
-				PublicKey: publicKeyHeader,
-			},
-		}
+	s.mu.RLock()
+	requireAuth := s.config.RequireAuth
+	s.mu.RUnlock()
 
-		go s.handleGetComputerNetworksWithIP(conn, req, "")
+	// With required auth the initial state is sent once the challenge is answered
+	if publicKeyHeader != "" && !requireAuth {
+		logger.Debug("Client connected with public key", "publicKey", publicKeyHeader)
+		go s.sendInitialState(ctx, conn, publicKeyHeader)
 	}
 
 	for {
 		var sigMsg smodels.SignalingMessage
 		err := conn.ReadJSON(&sigMsg)
-		logger.Info("Received message", "remoteAddr", conn.RemoteAddr().String(), "type", sigMsg.Type, "payload", string(sigMsg.Payload))
+		// Payloads are never logged: they carry PINs, recovery codes and peer traffic
+		if sigMsg.Type == smodels.TypeRelayFrame {
+			// Relayed frames are too frequent to log above debug
+			logger.Debug("Received message", "remoteAddr", conn.RemoteAddr().String(), "type", sigMsg.Type, "id", sigMsg.ID)
+		} else {
+			logger.Info("Received message", "remoteAddr", conn.RemoteAddr().String(), "type", sigMsg.Type, "id", sigMsg.ID)
+		}
 		if err != nil {
 			s.handleDisconnect(conn)
 			return
@@ -135,190 +239,384 @@ func (s *WebSocketServer) HandleWebSocketEndpoint(w http.ResponseWriter, r *http
 
 		s.statsManager.IncrementMessagesProcessed()
 
-		originalID := sigMsg.ID
+		start := time.Now()
+		s.beginMessage(conn, sigMsg.Type)
+		s.dispatchMessage(ctx, conn, sigMsg)
+		s.endMessage(conn, sigMsg.Type, time.Since(start))
+	}
+}
+
+// dispatchMessage routes a signaling message to its handler
+func (s *WebSocketServer) dispatchMessage(ctx context.Context, conn *websocket.Conn, sigMsg smodels.SignalingMessage) {
+	originalID := sigMsg.ID
 
-		switch sigMsg.Type {
-		case smodels.TypeCreateNetwork:
-			var req smodels.CreateNetworkRequest
-			if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
-				s.sendErrorSignal(conn, "Invalid create network request format", originalID)
-				continue
-			}
+	if fieldErrors := s.validatePayload(sigMsg.Payload); len(fieldErrors) > 0 {
+		logger.Warn("Rejected invalid message", "remoteAddr", conn.RemoteAddr().String(), "type", sigMsg.Type, "fields", fieldErrors)
+		s.sendValidationError(conn, fieldErrors, originalID)
+		return
+	}
 
-			s.handleCreateNetwork(conn, req, originalID)
+	if !s.authorizeMessage(conn, sigMsg) {
+		return
+	}
 
-		case smodels.TypeJoinNetwork:
-			var req smodels.JoinNetworkRequest
-			if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
-				s.sendErrorSignal(conn, "Invalid join network request format", originalID)
-				continue
-			}
+	switch sigMsg.Type {
+	case smodels.TypeAuthResponse:
+		var req smodels.AuthResponseRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid auth response format", originalID)
+			return
+		}
 
-			s.handleJoinNetwork(conn, req, originalID)
+		s.handleAuthResponse(ctx, conn, req, originalID)
 
-		case smodels.TypeConnectNetwork:
-			var req smodels.ConnectNetworkRequest
-			if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
-				s.sendErrorSignal(conn, "Invalid connect network request format", originalID)
-				continue
-			}
+	case smodels.TypeSyncNetwork:
+		var req smodels.SyncNetworkRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid sync network request format", originalID)
+			return
+		}
 
-			s.handleConnectNetwork(conn, req, originalID)
+		s.handleSyncNetwork(ctx, conn, req, originalID)
 
-		case smodels.TypeDisconnectNetwork:
-			var req smodels.DisconnectNetworkRequest
-			if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
-				s.sendErrorSignal(conn, "Invalid disconnect network request format", originalID)
-				continue
-			}
+	case smodels.TypeCreateRecoveryCode:
+		var req smodels.CreateRecoveryCodeRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid create recovery code request format", originalID)
+			return
+		}
 
-			s.handleDisconnectNetwork(conn, req, originalID)
+		s.handleCreateRecoveryCode(ctx, conn, req, originalID)
 
-		case smodels.TypeLeaveNetwork:
-			var req smodels.LeaveNetworkRequest
-			if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
-				s.sendErrorSignal(conn, "Invalid leave network request format", originalID)
-				continue
-			}
+	case smodels.TypeMigrateKey:
+		var req smodels.MigrateKeyRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid migrate key request format", originalID)
+			return
+		}
 
-			s.handleLeaveNetwork(conn, req, originalID)
+		s.handleMigrateKey(ctx, conn, req, originalID)
 
-		case smodels.TypeKick:
-			var req smodels.KickRequest
-			if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
-				s.sendErrorSignal(conn, "Invalid kick request format", originalID)
-				continue
-			}
+	case smodels.TypeCreateNetwork:
+		var req smodels.CreateNetworkRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid create network request format", originalID)
+			return
+		}
 
-			s.handleKick(conn, req, originalID)
+		s.handleCreateNetwork(ctx, conn, req, originalID)
 
-		case smodels.TypeRename:
-			var req smodels.RenameRequest
-			if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
-				s.sendErrorSignal(conn, "Invalid rename request format", originalID)
-				continue
-			}
+	case smodels.TypeJoinNetwork:
+		var req smodels.JoinNetworkRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid join network request format", originalID)
+			return
+		}
 
-			s.handleRename(conn, req, originalID)
+		s.handleJoinNetwork(ctx, conn, req, originalID)
 
-		case smodels.TypePing:
-			s.handlePing(conn, sigMsg.Payload, originalID)
+	case smodels.TypeConnectNetwork:
+		var req smodels.ConnectNetworkRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid connect network request format", originalID)
+			return
+		}
 
-		case smodels.TypeGetComputerNetworks:
-			var req smodels.GetComputerNetworksRequest
-			if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
-				s.sendErrorSignal(conn, "Invalid get computer networks request format", originalID)
-				continue
-			}
+		s.handleConnectNetwork(ctx, conn, req, originalID)
 
-			s.handleGetComputerNetworks(conn, req, originalID)
+	case smodels.TypeDisconnectNetwork:
+		var req smodels.DisconnectNetworkRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid disconnect network request format", originalID)
+			return
+		}
 
-		case smodels.TypeUpdateClientInfo:
-			var req smodels.UpdateClientInfoRequest
-			if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
-				s.sendErrorSignal(conn, "Invalid update client info request format", originalID)
-				continue
-			}
+		s.handleDisconnectNetwork(ctx, conn, req, originalID)
 
-			s.handleUpdateClientInfo(conn, req, originalID)
+	case smodels.TypeLeaveNetwork:
+		var req smodels.LeaveNetworkRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid leave network request format", originalID)
+			return
+		}
 
-		case smodels.TypeSdpOffer:
-			var sdpOffer smodels.SdpOffer
-			if err := json.Unmarshal(sigMsg.Payload, &sdpOffer); err != nil {
-				s.sendErrorSignal(conn, "Invalid SDP offer format", originalID)
-				continue
-			}
-			s.handleWebRTCSignal(conn, sigMsg.Type, sdpOffer.TargetPublicKey, sigMsg.Payload, originalID)
+		s.handleLeaveNetwork(ctx, conn, req, originalID)
 
-		case smodels.TypeSdpAnswer:
-			var sdpAnswer smodels.SdpAnswer
-			if err := json.Unmarshal(sigMsg.Payload, &sdpAnswer); err != nil {
-				s.sendErrorSignal(conn, "Invalid SDP answer format", originalID)
-				continue
-			}
-			s.handleWebRTCSignal(conn, smodels.TypeSdpAnswer, sdpAnswer.TargetPublicKey, sigMsg.Payload, originalID)
+	case smodels.TypeKick:
+		var req smodels.KickRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid kick request format", originalID)
+			return
+		}
 
-		case smodels.TypeIceCandidate:
-			var iceCandidate smodels.IceCandidate
-			if err := json.Unmarshal(sigMsg.Payload, &iceCandidate); err != nil {
-				s.sendErrorSignal(conn, "Invalid ICE candidate format", originalID)
-				continue
-			}
-			s.handleWebRTCSignal(conn, smodels.TypeIceCandidate, iceCandidate.TargetPublicKey, sigMsg.Payload, originalID)
+		s.handleKick(ctx, conn, req, originalID)
 
-		default:
-			logger.Warn("Unknown message type", "type", sigMsg.Type)
-			if originalID != "" {
-				s.sendErrorSignal(conn, "Unknown message type", originalID)
-			}
+	case smodels.TypeRename:
+		var req smodels.RenameRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid rename request format", originalID)
+			return
+		}
+
+		s.handleRename(ctx, conn, req, originalID)
+
+	case smodels.TypePing:
+		s.handlePing(ctx, conn, sigMsg.Payload, originalID)
+
+	case smodels.TypeGetComputerNetworks:
+		var req smodels.GetComputerNetworksRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid get computer networks request format", originalID)
+			return
+		}
+
+		s.handleGetComputerNetworks(ctx, conn, req, originalID)
+
+	case smodels.TypeUpdateClientInfo:
+		var req smodels.UpdateClientInfoRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid update client info request format", originalID)
+			return
+		}
+
+		s.handleUpdateClientInfo(ctx, conn, req, originalID)
+
+	case smodels.TypeListPublicNetworks:
+		var req smodels.ListPublicNetworksRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid list public networks request format", originalID)
+			return
+		}
+
+		s.handleListPublicNetworks(ctx, conn, req, originalID)
+
+	case smodels.TypeKeepNetworkAlive:
+		var req smodels.KeepNetworkAliveRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid keep network alive request format", originalID)
+			return
+		}
+
+		s.handleKeepNetworkAlive(ctx, conn, req, originalID)
+
+	case smodels.TypeSetOwnerPolicy:
+		var req smodels.SetOwnerPolicyRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid set owner policy request format", originalID)
+			return
+		}
+
+		s.handleSetOwnerPolicy(ctx, conn, req, originalID)
+
+	case smodels.TypeChangePIN:
+		var req smodels.ChangePINRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid change PIN request format", originalID)
+			return
+		}
+
+		s.handleChangePIN(ctx, conn, req, originalID)
+
+	case smodels.TypeGetNetworkStats:
+		var req smodels.GetNetworkStatsRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid get network stats request format", originalID)
+			return
+		}
+
+		s.handleGetNetworkStats(ctx, conn, req, originalID)
+
+	case smodels.TypeNatReport:
+		var req smodels.NatReportRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid NAT report format", originalID)
+			return
+		}
+
+		s.handleNatReport(ctx, conn, req, originalID)
+
+	case smodels.TypeWakeInfo:
+		var req smodels.WakeInfoRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid wake info format", originalID)
+			return
+		}
+
+		s.handleWakeInfo(ctx, conn, req, originalID)
+
+	case smodels.TypeWake:
+		var req smodels.WakeRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid wake request format", originalID)
+			return
+		}
+
+		s.handleWake(ctx, conn, req, originalID)
+
+	case smodels.TypeBan:
+		var req smodels.BanRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid ban request format", originalID)
+			return
+		}
+
+		s.handleBan(ctx, conn, req, originalID)
+
+	case smodels.TypeReserveIP:
+		var req smodels.ReserveIPRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid reserve IP request format", originalID)
+			return
+		}
+
+		s.handleReserveIP(ctx, conn, req, originalID)
+
+	case smodels.TypeTransferOwnership:
+		var req smodels.TransferOwnershipRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid transfer ownership request format", originalID)
+			return
+		}
+
+		s.handleTransferOwnership(ctx, conn, req, originalID)
+
+	case smodels.TypeChangeSubnet:
+		var req smodels.ChangeSubnetRequest
+		if err := json.Unmarshal(sigMsg.Payload, &req); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid change subnet request format", originalID)
+			return
+		}
+
+		s.handleChangeSubnet(ctx, conn, req, originalID)
+
+	case smodels.TypeSdpOffer:
+		var sdpOffer smodels.SdpOffer
+		if err := json.Unmarshal(sigMsg.Payload, &sdpOffer); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid SDP offer format", originalID)
+			return
+		}
+		s.handleWebRTCSignal(ctx, conn, sigMsg.Type, sdpOffer.TargetPublicKey, sigMsg.Payload, originalID)
+
+	case smodels.TypeSdpAnswer:
+		var sdpAnswer smodels.SdpAnswer
+		if err := json.Unmarshal(sigMsg.Payload, &sdpAnswer); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid SDP answer format", originalID)
+			return
+		}
+		s.handleWebRTCSignal(ctx, conn, smodels.TypeSdpAnswer, sdpAnswer.TargetPublicKey, sigMsg.Payload, originalID)
+
+	case smodels.TypeIceCandidate:
+		var iceCandidate smodels.IceCandidate
+		if err := json.Unmarshal(sigMsg.Payload, &iceCandidate); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid ICE candidate format", originalID)
+			return
+		}
+		s.handleWebRTCSignal(ctx, conn, smodels.TypeIceCandidate, iceCandidate.TargetPublicKey, sigMsg.Payload, originalID)
+
+	case smodels.TypeRelayFrame:
+		var frame smodels.RelayFrame
+		if err := json.Unmarshal(sigMsg.Payload, &frame); err != nil {
+			s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid relay frame format", originalID)
+			return
+		}
+		s.handleRelayFrame(ctx, conn, frame, originalID)
+
+	default:
+		logger.Warn("Unknown message type", "type", sigMsg.Type)
+		if originalID != "" {
+			s.sendErrorSignal(conn, smodels.ErrUnknownMessageType, "Unknown message type", originalID)
 		}
 	}
 }
 
 // handleWebRTCSignal forwards WebRTC signaling messages to the target client
-func (s *WebSocketServer) handleWebRTCSignal(senderConn *websocket.Conn, msgType smodels.MessageType, targetPublicKey string, payload []byte, originalID string) {
+func (s *WebSocketServer) handleWebRTCSignal(ctx context.Context, senderConn *websocket.Conn, msgType smodels.MessageType, targetPublicKey string, payload []byte, originalID string) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	senderPublicKey, ok := s.clientToPublicKey[senderConn]
 	if !ok {
-		s.sendErrorSignal(senderConn, "Sender public key not found", originalID)
+		s.sendErrorSignal(senderConn, smodels.ErrNotConnected, "Sender public key not found", originalID)
 		return
 	}
 
 	senderNetworkID, ok := s.clients[senderConn]
 	if !ok {
-		s.sendErrorSignal(senderConn, "Sender not in any network", originalID)
+		s.sendErrorSignal(senderConn, smodels.ErrNotConnected, "Sender not in any network", originalID)
 		return
 	}
 
-	targetConn := (*websocket.Conn)(nil)
-	for conn, pk := range s.clientToPublicKey {
-		if pk == targetPublicKey {
-			// Check if target is in the same network as sender
-			if s.clients[conn] == senderNetworkID {
-				targetConn = conn
-				break
-			}
-		}
+	targetConn := s.findNetworkPeer(senderNetworkID, targetPublicKey)
+	if targetConn == nil {
+		s.sendErrorSignal(senderConn, smodels.ErrTargetNotFound, fmt.Sprintf("Target client %s not found or not in the same network", targetPublicKey), originalID)
+		return
 	}
 
-	if targetConn == nil {
-		s.sendErrorSignal(senderConn, fmt.Sprintf("Target client %s not found or not in the same network", targetPublicKey), originalID)
+	// The target picks the peer connection by sender_public_key, so it comes from the session
+	stamped, err := withSenderKey(payload, senderPublicKey)
+	if err != nil {
+		s.sendErrorSignal(senderConn, smodels.ErrInvalidRequest, "Invalid WebRTC signal format", originalID)
 		return
 	}
 
-	// Forward the original signaling message to the target
-	err := s.sendSignal(targetConn, msgType, json.RawMessage(payload), originalID)
+	// Forward the signaling message to the target
+	err = s.sendSignal(targetConn, msgType, json.RawMessage(stamped), originalID)
 	if err != nil {
 		logger.Error("Failed to forward WebRTC signal", "error", err, "sender", senderPublicKey, "target", targetPublicKey, "type", msgType)
-		s.sendErrorSignal(senderConn, "Failed to forward WebRTC signal", originalID)
+		s.sendErrorSignal(senderConn, smodels.ErrInternal, "Failed to forward WebRTC signal", originalID)
 	} else {
+		s.networkStats.RecordRelay(senderNetworkID, len(payload))
 		logger.Debug("WebRTC signal forwarded", "sender", senderPublicKey, "target", targetPublicKey, "type", msgType)
 	}
 }
 
-func (s *WebSocketServer) handleUpdateClientInfo(conn *websocket.Conn, req smodels.UpdateClientInfoRequest, originalID string) {
+// findNetworkPeer returns the connection of a computer connected to a network, or nil.
+// Callers must hold s.mu.
+func (s *WebSocketServer) findNetworkPeer(networkID, publicKey string) *websocket.Conn {
+	for conn, pk := range s.clientToPublicKey {
+		if pk == publicKey && s.clients[conn] == networkID {
+			return conn
+		}
+	}
+	return nil
+}
+
+// withSenderKey returns a WebRTC signal payload with sender_public_key set to publicKey
+func withSenderKey(payload []byte, publicKey string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+
+	key, err := json.Marshal(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	fields["sender_public_key"] = key
+	return json.Marshal(fields)
+}
+
+func (s *WebSocketServer) handleUpdateClientInfo(ctx context.Context, conn *websocket.Conn, req smodels.UpdateClientInfoRequest, originalID string) {
 	logger.Info("handleUpdateClientInfo: Received request", "originalID", originalID, "publicKey", req.PublicKey, "clientName", req.ClientName)
 
 	publicKey := req.PublicKey
 	if publicKey == "" {
 		logger.Warn("handleUpdateClientInfo: Public key is empty", "originalID", originalID)
-		s.sendErrorSignal(conn, "Public key is required for updating client info", originalID)
+		s.sendErrorSignal(conn, smodels.ErrPublicKeyRequired, "Public key is required for updating client info", originalID)
 		return
 	}
 
 	if req.ClientName == "" {
 		logger.Warn("handleUpdateClientInfo: Client name is empty", "originalID", originalID)
-		s.sendErrorSignal(conn, "Client name is required", originalID)
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Client name is required", originalID)
 		return
 	}
 	// Update client name in all networks
-	err := s.supabaseManager.UpdateClientNameInNetworks(publicKey, req.ClientName)
+	err := s.store.UpdateClientNameInNetworks(publicKey, req.ClientName)
 	if err != nil {
 		logger.Error("handleUpdateClientInfo: Error updating client name in networks", "error", err, "publicKey", publicKey)
 		clientErrorMessage := fmt.Sprintf("Failed to update client name: %s", err.Error())
-		s.sendErrorSignal(conn, clientErrorMessage, originalID)
+		s.sendErrorSignal(conn, smodels.ErrInternal, clientErrorMessage, originalID)
 		return
 	}
 	logger.Info("handleUpdateClientInfo: Client name updated successfully in networks", "publicKey", publicKey, "newName", req.ClientName)
@@ -333,40 +631,61 @@ func (s *WebSocketServer) handleUpdateClientInfo(conn *websocket.Conn, req smode
 
 	// Notify other clients in the networks about the name change
 	logger.Info("handleUpdateClientInfo: Notifying other clients about name change", "publicKey", publicKey)
-	computerNetworks, err := s.supabaseManager.GetComputerNetworks(publicKey)
+	computerNetworks, err := s.store.GetComputerNetworks(publicKey)
 	if err != nil {
 		logger.Error("handleUpdateClientInfo: Error getting computer networks for notification", "error", err, "publicKey", publicKey)
 		return
 	}
 	logger.Info("handleUpdateClientInfo: Successfully retrieved computer networks for notification", "publicKey", publicKey, "networkCount", len(computerNetworks))
 
+	s.mu.Lock()
 	for _, cn := range computerNetworks {
-		networkID := cn.NetworkID
-
-		connectionsInNetwork := s.networks[networkID]
-
-		for _, clientConn := range connectionsInNetwork {
-			clientPublicKey, ok := s.clientToPublicKey[clientConn]
-			if ok && clientPublicKey != publicKey {
-				// Send notification to other clients in the same network
-				notification := smodels.ComputerRenamedNotification{
-					NetworkID:       networkID,
-					PublicKey:       publicKey,
-					NewComputerName: req.ClientName,
-				}
-				logger.Info("handleUpdateClientInfo: Sending TypeComputerRenamed notification", "networkID", networkID, "targetClient", clientPublicKey)
-				s.sendSignal(clientConn, smodels.TypeComputerRenamed, notification, "")
-			}
+		notification := smodels.ComputerRenamedNotification{
+			NetworkID:       cn.NetworkID,
+			PublicKey:       publicKey,
+			NewComputerName: req.ClientName,
 		}
+		logger.Info("handleUpdateClientInfo: Sending TypeComputerRenamed notification", "networkID", cn.NetworkID)
+		s.broadcastNetworkEvent(cn.NetworkID, smodels.TypeComputerRenamed, notification, nil)
+		s.broadcastRoster(cn.NetworkID)
 	}
+	s.mu.Unlock()
 	logger.Info("handleUpdateClientInfo: Finished processing request", "originalID", originalID)
 }
 
-func (s *WebSocketServer) sendErrorSignal(conn *websocket.Conn, errorMsg string, originalID string) {
-	logger.Debug("sendErrorSignal: Sending error signal", "errorMsg", errorMsg, "originalID", originalID)
-	errPayload, _ := json.Marshal(map[string]string{"error": errorMsg})
+// beginMessage marks msgType as the message currently being handled for conn
+func (s *WebSocketServer) beginMessage(conn *websocket.Conn, msgType smodels.MessageType) {
+	s.inFlightMu.Lock()
+	s.inFlight[conn] = msgType
+	s.inFlightMu.Unlock()
+}
+
+// endMessage clears the in-flight message for conn and records its processing time
+func (s *WebSocketServer) endMessage(conn *websocket.Conn, msgType smodels.MessageType, duration time.Duration) {
+	s.inFlightMu.Lock()
+	delete(s.inFlight, conn)
+	s.inFlightMu.Unlock()
+
+	s.statsManager.RecordMessageDuration(msgType, duration)
+}
 
-	conn.WriteJSON(smodels.SignalingMessage{
+func (s *WebSocketServer) sendErrorSignal(conn *websocket.Conn, code smodels.ErrorCode, errorMsg string, originalID string) {
+	logger.Debug("sendErrorSignal: Sending error signal", "code", code, "errorMsg", errorMsg, "originalID", originalID)
+	s.sendErrorResponse(conn, smodels.ErrorResponse{Code: code, Error: errorMsg}, originalID)
+}
+
+// sendErrorResponse writes a TypeError message carrying the given error response
+func (s *WebSocketServer) sendErrorResponse(conn *websocket.Conn, errResp smodels.ErrorResponse, originalID string) {
+	s.inFlightMu.Lock()
+	msgType, ok := s.inFlight[conn]
+	s.inFlightMu.Unlock()
+	if ok {
+		s.statsManager.RecordMessageError(msgType)
+	}
+
+	errPayload, _ := json.Marshal(errResp)
+
+	s.writeJSON(conn, smodels.SignalingMessage{
 		ID:      originalID,
 		Type:    smodels.TypeError,
 		Payload: errPayload,
@@ -381,48 +700,86 @@ func (s *WebSocketServer) sendSignal(conn *websocket.Conn, msgType smodels.Messa
 		return err
 	}
 
-	err = conn.WriteJSON(smodels.SignalingMessage{
+	// Writing to a connection that is going away would only block or fail
+	if err := s.connContext(conn).Err(); err != nil {
+		logger.Debug("sendSignal: Connection closed, dropping signal", "type", msgType, "originalID", originalID)
+		return err
+	}
+
+	err = s.writeJSON(conn, smodels.SignalingMessage{
 		ID:      originalID,
 		Type:    msgType,
 		Payload: payloadBytes,
 	})
 	if err != nil {
 		logger.Error("sendSignal: Failed to write JSON to connection", "error", err, "type", msgType, "originalID", originalID)
+		s.cancelConn(conn)
 	} else {
-		logger.Debug("sendSignal: Successfully sent signal", "type", msgType, "originalID", originalID, "payload", string(payloadBytes))
+		logger.Debug("sendSignal: Successfully sent signal", "type", msgType, "originalID", originalID)
 	}
 	return err
 }
 
-func (s *WebSocketServer) handleCreateNetwork(conn *websocket.Conn, req smodels.CreateNetworkRequest, originalID string) {
+func (s *WebSocketServer) handleCreateNetwork(ctx context.Context, conn *websocket.Conn, req smodels.CreateNetworkRequest, originalID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if req.NetworkName == "" || req.PIN == "" || req.PublicKey == "" {
-		s.sendErrorSignal(conn, "Network name, pin, and public key are required", originalID)
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Network name, pin, and public key are required", originalID)
 		return
 	}
 
 	if !s.pinRegex.MatchString(req.PIN) {
-		s.sendErrorSignal(conn, "PIN does not match required pattern", originalID)
+		s.sendErrorSignal(conn, smodels.ErrInvalidPIN, "PIN does not match required pattern", originalID)
+		return
+	}
+
+	ownerPolicy := req.OwnerPolicy
+	if ownerPolicy == "" {
+		ownerPolicy = smodels.OwnerPolicy(s.config.DefaultOwnerPolicy)
+	}
+	if !ownerPolicy.Valid() {
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Owner policy must be preserve, delete or transfer", originalID)
 		return
 	}
 
-	hasNetwork, existingNetworkID, err := s.supabaseManager.PublicKeyHasNetwork(req.PublicKey)
+	if s.replayIdempotent(conn, req.PublicKey, req.IdempotencyKey, originalID) {
+		return
+	}
+
+	if s.rejectDuringMaintenance(conn, originalID) {
+		return
+	}
+
+	if !s.claimPublicKey(conn, req.PublicKey, originalID) {
+		return
+	}
+
+	ownedNetworks, err := s.store.GetNetworksByOwner(req.PublicKey)
 	if err != nil {
-		logger.Error("Error checking if public key has a network", "error", err)
-	} else if hasNetwork {
-		s.sendErrorSignal(conn, fmt.Sprintf("This public key has already created network: %s", existingNetworkID), originalID)
+		logger.Error("Error getting networks owned by public key", "error", err)
+	} else if len(ownedNetworks) >= s.config.MaxNetworksPerOwner {
+		ownedIDs := make([]string, 0, len(ownedNetworks))
+		for _, owned := range ownedNetworks {
+			ownedIDs = append(ownedIDs, owned.ID)
+		}
+		s.sendErrorSignal(conn, smodels.ErrNetworkLimitReached,
+			fmt.Sprintf("This public key already owns %d of %d networks: %s", len(ownedNetworks), s.config.MaxNetworksPerOwner, strings.Join(ownedIDs, ", ")),
+			originalID)
 		return
 	}
 
 	networkID := utils.GenerateNetworkID()
 
-	exists, err := s.supabaseManager.NetworkExists(networkID)
+	exists, err := s.store.NetworkExists(networkID)
 	if err != nil {
 		logger.Error("Error checking if network exists", "error", err)
 	} else if exists {
-		s.sendErrorSignal(conn, "Network ID conflict, please try again", originalID)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Network ID conflict, please try again", originalID)
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "create network") {
 		return
 	}
 
@@ -433,21 +790,21 @@ func (s *WebSocketServer) handleCreateNetwork(conn *websocket.Conn, req smodels.
 		OwnerPublicKey: req.PublicKey,
 		CreatedAt:      time.Now(),
 		LastActive:     time.Now(),
+		IsPublic:       req.Public,
+		Tags:           normalizeTags(req.Tags),
+		OwnerPolicy:    string(ownerPolicy),
 	}
 
-	err = s.supabaseManager.CreateNetwork(network)
+	// The network and the owner's membership are written together, so a failure
+	// cannot leave a network without members behind
+	creatorIP := subnetIP(networkSubnet(network), 1)
+	err = s.store.CreateNetworkWithOwner(network, req.ComputerName, creatorIP)
 	if err != nil {
-		logger.Error("Error creating network in Supabase", "error", err)
-		s.sendErrorSignal(conn, "Error creating network in database", originalID)
+		logger.Error("Error creating network in store", "error", err, "networkID", networkID)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error creating network in database", originalID)
 		return
 	}
 
-	creatorIP := "10.10.0.1"
-	err = s.supabaseManager.AddComputerToNetwork(networkID, req.PublicKey, req.ComputerName, creatorIP)
-	if err != nil {
-		logger.Error("Error adding network owner to computer_networks", "error", err)
-	}
-
 	s.clientToPublicKey[conn] = req.PublicKey
 
 	s.clients[conn] = networkID
@@ -456,6 +813,7 @@ func (s *WebSocketServer) handleCreateNetwork(conn *websocket.Conn, req smodels.
 		s.networks[networkID] = []*websocket.Conn{}
 	}
 	s.networks[networkID] = append(s.networks[networkID], conn)
+	s.networkStats.RecordOnline(networkID, len(s.networks[networkID]))
 
 	// Add creator to connectedComputers map
 	if _, ok := s.connectedComputers[networkID]; !ok {
@@ -466,15 +824,27 @@ func (s *WebSocketServer) handleCreateNetwork(conn *websocket.Conn, req smodels.
 	logger.Info("Network created",
 		"networkID", networkID,
 		"networkName", req.NetworkName,
+		"public", req.Public,
 		"clientAddr", conn.RemoteAddr().String(),
 		"computerName", req.ComputerName)
 
 	s.statsManager.UpdateStats(len(s.clients), len(s.networks))
 
+	owned := make([]smodels.OwnedNetworkInfo, 0, len(ownedNetworks)+1)
+	for _, n := range append(ownedNetworks, network) {
+		owned = append(owned, smodels.OwnedNetworkInfo{
+			NetworkID:   n.ID,
+			NetworkName: n.Name,
+			CreatedAt:   n.CreatedAt,
+		})
+	}
+
 	responsePayload := smodels.CreateNetworkResponse{
-		NetworkID:   networkID,
-		NetworkName: req.NetworkName,
-		PublicKey:   req.PublicKey,
+		NetworkID:        networkID,
+		NetworkName:      req.NetworkName,
+		PublicKey:        req.PublicKey,
+		OwnedNetworks:    owned,
+		MaxOwnedNetworks: s.config.MaxNetworksPerOwner,
 		Computers: []smodels.ComputerInfo{
 			{
 				Name:       req.ComputerName,
@@ -486,58 +856,89 @@ func (s *WebSocketServer) handleCreateNetwork(conn *websocket.Conn, req smodels.
 	}
 
 	logger.Debug("Sending TypeNetworkCreated response", "networkID", networkID, "originalID", originalID)
+	s.rememberIdempotent(req.PublicKey, req.IdempotencyKey, smodels.TypeNetworkCreated, responsePayload)
 	s.sendSignal(conn, smodels.TypeNetworkCreated, responsePayload, originalID)
+	s.broadcastRoster(networkID)
 }
 
-func (s *WebSocketServer) handleJoinNetwork(conn *websocket.Conn, req smodels.JoinNetworkRequest, originalID string) {
+func (s *WebSocketServer) handleJoinNetwork(ctx context.Context, conn *websocket.Conn, req smodels.JoinNetworkRequest, originalID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	network, err := s.supabaseManager.GetNetwork(req.NetworkID)
+	if s.replayIdempotent(conn, req.PublicKey, req.IdempotencyKey, originalID) {
+		return
+	}
+
+	if s.rejectDuringMaintenance(conn, originalID) {
+		return
+	}
+
+	network, err := s.store.GetNetwork(req.NetworkID)
 	if err != nil {
-		s.sendErrorSignal(conn, "Network does not exist", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNetworkNotFound, "Network does not exist", originalID)
 		return
 	}
 
 	if req.PIN != network.PIN {
-		s.sendErrorSignal(conn, "Incorrect PIN", originalID)
+		s.sendErrorSignal(conn, smodels.ErrWrongPIN, "Incorrect PIN", originalID)
 		return
 	}
 
 	if req.PublicKey == "" {
-		s.sendErrorSignal(conn, "Public key is required", originalID)
+		s.sendErrorSignal(conn, smodels.ErrPublicKeyRequired, "Public key is required", originalID)
+		return
+	}
+
+	banned, err := s.store.IsComputerBanned(req.NetworkID, req.PublicKey)
+	if err != nil {
+		logger.Error("Error checking network ban", "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error checking network membership", originalID)
+		return
+	}
+	if banned {
+		s.sendErrorSignal(conn, smodels.ErrBanned, "This computer was banned from the network", originalID)
+		return
+	}
+
+	if !s.claimPublicKey(conn, req.PublicKey, originalID) {
 		return
 	}
 
 	connections := s.networks[req.NetworkID]
 	if len(connections) >= s.config.MaxClientsPerNetwork {
-		s.sendErrorSignal(conn, "Network is full", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNetworkFull, "Network is full", originalID)
 		return
 	}
 
 	var assignedIP string
-	isInNetwork, err := s.supabaseManager.IsComputerInNetwork(req.NetworkID, req.PublicKey)
+	isInNetwork, err := s.store.IsComputerInNetwork(req.NetworkID, req.PublicKey)
 	if err != nil {
 		logger.Error("Error checking if computer is in network", "error", err)
-		s.sendErrorSignal(conn, "Error checking network membership", originalID)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error checking network membership", originalID)
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "join network") {
 		return
 	}
 
 	if !isInNetwork {
-		// Assign a new IP if not already in network
-		ip, err := s.generateUniqueIP(req.NetworkID)
+		// Assign the reserved IP or a new one if not already in network
+		ip, err := s.assignIP(req.NetworkID, req.PublicKey)
 		if err != nil {
-			s.sendErrorSignal(conn, "Failed to assign IP address", originalID)
+			s.sendErrorSignal(conn, smodels.ErrInternal, "Failed to assign IP address", originalID)
 			return
 		}
 		assignedIP = ip
 
-		err = s.supabaseManager.AddComputerToNetwork(req.NetworkID, req.PublicKey, req.ComputerName, assignedIP)
+		err = s.store.AddComputerToNetwork(req.NetworkID, req.PublicKey, req.ComputerName, assignedIP)
 		if err != nil {
 			logger.Error("Error adding computer to network", "error", err)
-			s.sendErrorSignal(conn, "Error adding computer to network", originalID)
+			s.sendErrorSignal(conn, smodels.ErrInternal, "Error adding computer to network", originalID)
 			return
 		}
+		s.networkStats.RecordJoin(req.NetworkID)
+
 		// Update connection status in memory
 		if _, ok := s.connectedComputers[req.NetworkID]; !ok {
 			s.connectedComputers[req.NetworkID] = make(map[string]bool)
@@ -545,10 +946,10 @@ func (s *WebSocketServer) handleJoinNetwork(conn *websocket.Conn, req smodels.Jo
 		s.connectedComputers[req.NetworkID][req.PublicKey] = true
 	} else {
 		// If already in network, retrieve existing IP
-		computer, err := s.supabaseManager.GetComputerInNetwork(req.NetworkID, req.PublicKey)
+		computer, err := s.store.GetComputerInNetwork(req.NetworkID, req.PublicKey)
 		if err != nil {
 			logger.Error("Error getting computer from network", "error", err)
-			s.sendErrorSignal(conn, "Error retrieving existing IP", originalID)
+			s.sendErrorSignal(conn, smodels.ErrInternal, "Error retrieving existing IP", originalID)
 			return
 		}
 		assignedIP = computer.PeerIP
@@ -567,10 +968,11 @@ func (s *WebSocketServer) handleJoinNetwork(conn *websocket.Conn, req smodels.Jo
 		s.networks[req.NetworkID] = []*websocket.Conn{}
 	}
 	s.networks[req.NetworkID] = append(s.networks[req.NetworkID], conn)
+	s.networkStats.RecordOnline(req.NetworkID, len(s.networks[req.NetworkID]))
 
 	clientCount := len(s.networks[req.NetworkID])
 
-	err = s.supabaseManager.UpdateNetworkActivity(req.NetworkID)
+	err = s.store.UpdateNetworkActivity(req.NetworkID)
 	if err != nil {
 		logger.Debug("Error updating network activity", "error", err)
 	}
@@ -588,57 +990,45 @@ func (s *WebSocketServer) handleJoinNetwork(conn *websocket.Conn, req smodels.Jo
 		"network_name": network.Name,
 		"computer_ip":  assignedIP,
 	}
+	s.rememberIdempotent(req.PublicKey, req.IdempotencyKey, smodels.TypeNetworkJoined, responsePayload)
 	s.sendSignal(conn, smodels.TypeNetworkJoined, responsePayload, originalID)
 
 	// Notify other clients in the network about the new computer
-	for _, computerConn := range s.networks[req.NetworkID] {
-		if computerConn != conn {
-			notification := smodels.ComputerConnectedNotification{
-				NetworkID:    req.NetworkID,
-				PublicKey:    req.PublicKey,
-				ComputerName: req.ComputerName,
-				ComputerIP:   assignedIP,
-			}
-			s.sendSignal(computerConn, smodels.TypeComputerConnected, notification, "")
-		}
-	}
+	s.broadcastNetworkEvent(req.NetworkID, smodels.TypeComputerConnected, smodels.ComputerConnectedNotification{
+		NetworkID:    req.NetworkID,
+		PublicKey:    req.PublicKey,
+		ComputerName: req.ComputerName,
+		ComputerIP:   assignedIP,
+		NatType:      s.connNatType(conn),
+	}, conn)
 
 	// Send existing computers' info to the newly joined client
-	for _, existingConn := range s.networks[req.NetworkID] {
-		if existingConn != conn {
-			existingPublicKey := s.clientToPublicKey[existingConn]
-			existingComputer, err := s.supabaseManager.GetComputerInNetwork(req.NetworkID, existingPublicKey)
-			if err == nil {
-				notification := smodels.ComputerConnectedNotification{
-					NetworkID:    req.NetworkID,
-					PublicKey:    existingPublicKey,
-					ComputerName: existingComputer.ComputerName,
-					ComputerIP:   existingComputer.PeerIP,
-				}
-				s.sendSignal(conn, smodels.TypeComputerConnected, notification, "")
-			}
-		}
-	}
+	s.sendNetworkMembers(conn, req.NetworkID, req.PublicKey, "")
+	s.broadcastRoster(req.NetworkID)
 }
 
-func (s *WebSocketServer) handleConnectNetwork(conn *websocket.Conn, req smodels.ConnectNetworkRequest, originalID string) {
+func (s *WebSocketServer) handleConnectNetwork(ctx context.Context, conn *websocket.Conn, req smodels.ConnectNetworkRequest, originalID string) {
 	// Perform Supabase reads outside the lock
 	logger.Debug("handleConnectNetwork: Received request", "originalID", originalID, "networkID", req.NetworkID, "publicKey", req.PublicKey)
-	network, err := s.supabaseManager.GetNetwork(req.NetworkID)
+	network, err := s.store.GetNetwork(req.NetworkID)
 	if err != nil {
-		s.sendErrorSignal(conn, "Network does not exist", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNetworkNotFound, "Network does not exist", originalID)
 		return
 	}
 
 	if req.PublicKey == "" {
-		s.sendErrorSignal(conn, "Public key is required", originalID)
+		s.sendErrorSignal(conn, smodels.ErrPublicKeyRequired, "Public key is required", originalID)
 		return
 	}
 
-	computer, err := s.supabaseManager.GetComputerInNetwork(req.NetworkID, req.PublicKey)
+	computer, err := s.store.GetComputerInNetwork(req.NetworkID, req.PublicKey)
 	if err != nil {
 		logger.Error("Error getting computer from network", "error", err)
-		s.sendErrorSignal(conn, "You must join this network first", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNotMember, "You must join this network first", originalID)
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "connect network") {
 		return
 	}
 
@@ -646,9 +1036,18 @@ func (s *WebSocketServer) handleConnectNetwork(conn *websocket.Conn, req smodels
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// A retried connect must not add the connection to the network a second time
+	if s.replayIdempotent(conn, req.PublicKey, req.IdempotencyKey, originalID) {
+		return
+	}
+
+	if !s.claimPublicKey(conn, req.PublicKey, originalID) {
+		return
+	}
+
 	connections := s.networks[req.NetworkID]
 	if len(connections) >= s.config.MaxClientsPerNetwork {
-		s.sendErrorSignal(conn, "Network is full", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNetworkFull, "Network is full", originalID)
 		return
 	}
 
@@ -665,11 +1064,12 @@ func (s *WebSocketServer) handleConnectNetwork(conn *websocket.Conn, req smodels
 		s.networks[req.NetworkID] = []*websocket.Conn{}
 	}
 	s.networks[req.NetworkID] = append(s.networks[req.NetworkID], conn)
+	s.networkStats.RecordOnline(req.NetworkID, len(s.networks[req.NetworkID]))
 
 	// Release lock before potentially long-running operations like DB updates or sending signals
 	// The defer will handle unlocking when the function returns.
 
-	err = s.supabaseManager.UpdateNetworkActivity(req.NetworkID)
+	err = s.store.UpdateNetworkActivity(req.NetworkID)
 	if err != nil {
 		logger.Debug("Error updating network activity", "error", err)
 	}
@@ -688,45 +1088,88 @@ func (s *WebSocketServer) handleConnectNetwork(conn *websocket.Conn, req smodels
 		"computer_ip":  computer.PeerIP,
 	}
 	s.sendSignal(conn, smodels.TypeNetworkConnected, responsePayload, originalID)
+	s.rememberIdempotent(req.PublicKey, req.IdempotencyKey, smodels.TypeNetworkConnected, responsePayload)
+
+	// Collect what the client missed before its own connect event is logged
+	var missed []smodels.SignalingMessage
+	canReplay := false
+	if req.LastSequence > 0 {
+		missed, canReplay = s.eventLog(req.NetworkID).since(req.LastSequence)
+	}
 
 	// Notify other clients in the network about the new computer
-	for _, computerConn := range s.networks[req.NetworkID] {
-		if computerConn != conn {
-			notification := smodels.ComputerConnectedNotification{
-				NetworkID:    req.NetworkID,
-				PublicKey:    req.PublicKey,
-				ComputerName: computer.ComputerName, // Use computer.ComputerName from DB
-				ComputerIP:   computer.PeerIP,
-			}
-			s.sendSignal(computerConn, smodels.TypeComputerConnected, notification, "")
-		}
+	s.broadcastNetworkEvent(req.NetworkID, smodels.TypeComputerConnected, smodels.ComputerConnectedNotification{
+		NetworkID:    req.NetworkID,
+		PublicKey:    req.PublicKey,
+		ComputerName: computer.ComputerName, // Use computer.ComputerName from DB
+		ComputerIP:   computer.PeerIP,
+		NatType:      s.connNatType(conn),
+	}, conn)
+
+	if canReplay {
+		logger.Debug("Replaying missed network events", "networkID", req.NetworkID, "lastSequence", req.LastSequence, "events", len(missed))
+		s.sendSignal(conn, smodels.TypeNetworkEvents, smodels.NetworkEventsNotification{
+			NetworkID:    req.NetworkID,
+			Events:       missed,
+			LastSequence: s.eventLog(req.NetworkID).seq,
+		}, "")
+	} else {
+		// Send existing computers' info to the newly connected client
+		s.sendNetworkMembers(conn, req.NetworkID, req.PublicKey, "")
 	}
+	s.broadcastRoster(req.NetworkID)
+}
 
-	// Send existing computers' info to the newly connected client
-	computersInNetwork, err := s.supabaseManager.GetComputersInNetwork(req.NetworkID)
+// sendNetworkMembers sends every other member of a network to conn in one TypeNetworkMembers message.
+// Members are fetched with a single query; online status comes from in-memory state.
+// originalID is set when the list answers a SyncNetwork request. Must be called with s.mu held.
+func (s *WebSocketServer) sendNetworkMembers(conn *websocket.Conn, networkID, selfPublicKey, originalID string) {
+	computersInNetwork, err := s.store.GetComputersInNetwork(networkID)
 	if err != nil {
-		logger.Error("Error fetching computers for network to notify new client", "error", err, "networkID", req.NetworkID)
-		// Continue, as this is not critical for the connecting client's own connection
-	} else {
-		for _, existingComputer := range computersInNetwork {
-			// Don't send notification about self to self
-			if existingComputer.PublicKey != req.PublicKey {
-				// Only send if the existing computer is currently online
-				if s.isComputerOnline(req.NetworkID, existingComputer.PublicKey) {
-					notification := smodels.ComputerConnectedNotification{
-						NetworkID:    existingComputer.NetworkID,
-						PublicKey:    existingComputer.PublicKey,
-						ComputerName: existingComputer.ComputerName,
-						ComputerIP:   existingComputer.PeerIP,
-					}
-					s.sendSignal(conn, smodels.TypeComputerConnected, notification, "")
-				}
-			}
+		// Not critical for the client's own connection, it will get updates as peers come and go
+		logger.Error("Error fetching computers for network to notify new client", "error", err, "networkID", networkID)
+		if originalID != "" {
+			s.sendErrorSignal(conn, smodels.ErrInternal, "Error fetching network members", originalID)
 		}
+		return
+	}
+
+	notification := smodels.NetworkMembersNotification{
+		NetworkID:    networkID,
+		Computers:    make([]smodels.ComputerInfo, 0, len(computersInNetwork)),
+		LastSequence: s.eventLog(networkID).seq,
+	}
+	for _, computer := range computersInNetwork {
+		if computer.PublicKey == selfPublicKey {
+			continue
+		}
+		notification.Computers = append(notification.Computers, smodels.ComputerInfo{
+			Name:       computer.ComputerName,
+			ComputerIP: computer.PeerIP,
+			PublicKey:  computer.PublicKey,
+			IsOnline:   s.isComputerOnline(networkID, computer.PublicKey),
+			NatType:    s.natTypeOf(networkID, computer.PublicKey),
+			Wakeable:   s.isWakeable(computer.PublicKey),
+		})
+	}
+
+	s.sendSignal(conn, smodels.TypeNetworkMembers, notification, originalID)
+}
+
+// handleSyncNetwork resends the full member list of the network the connection is attached to
+func (s *WebSocketServer) handleSyncNetwork(ctx context.Context, conn *websocket.Conn, req smodels.SyncNetworkRequest, originalID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.NetworkID == "" || s.clients[conn] != req.NetworkID {
+		s.sendErrorSignal(conn, smodels.ErrNotConnected, "Not connected to this network", originalID)
+		return
 	}
+
+	s.sendNetworkMembers(conn, req.NetworkID, s.clientToPublicKey[conn], originalID)
 }
 
-func (s *WebSocketServer) handleDisconnectNetwork(conn *websocket.Conn, req smodels.DisconnectNetworkRequest, originalID string) {
+func (s *WebSocketServer) handleDisconnectNetwork(ctx context.Context, conn *websocket.Conn, req smodels.DisconnectNetworkRequest, originalID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -735,32 +1178,32 @@ func (s *WebSocketServer) handleDisconnectNetwork(conn *websocket.Conn, req smod
 	if networkID == "" {
 		networkID = s.clients[conn]
 		if networkID == "" {
-			s.sendErrorSignal(conn, "Not connected to any network", originalID)
+			s.sendErrorSignal(conn, smodels.ErrNotConnected, "Not connected to any network", originalID)
 			return
 		}
 	}
 
 	if s.clients[conn] != networkID {
-		s.sendErrorSignal(conn, "Not connected to this network", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNotConnected, "Not connected to this network", originalID)
 		return
 	}
 
 	publicKey, hasPublicKey := s.clientToPublicKey[conn]
 	if !hasPublicKey {
-		s.sendErrorSignal(conn, "Public key not found for this connection", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNotConnected, "Public key not found for this connection", originalID)
 		return
 	}
 
-	network, err := s.supabaseManager.GetNetwork(networkID)
+	network, err := s.store.GetNetwork(networkID)
 	if err != nil {
-		s.sendErrorSignal(conn, "Network not found", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNetworkNotFound, "Network not found", originalID)
 		return
 	}
 
 	isOwner := (publicKey == network.OwnerPublicKey)
 
 	if isOwner {
-		err = s.supabaseManager.UpdateNetworkActivity(networkID)
+		err = s.store.UpdateNetworkActivity(networkID)
 		if err != nil {
 			logger.Debug("Error updating network activity", "error", err)
 		}
@@ -785,13 +1228,11 @@ func (s *WebSocketServer) handleDisconnectNetwork(conn *websocket.Conn, req smod
 		if len(s.networks[networkID]) == 0 {
 			delete(s.networks, networkID)
 		} else {
-			for _, computer := range s.networks[networkID] {
-				computerDisconnectedPayload := map[string]interface{}{
-					"network_id": networkID,
-					"public_key": publicKey,
-				}
-				s.sendSignal(computer, smodels.TypeComputerDisconnected, computerDisconnectedPayload, "")
-			}
+			s.broadcastNetworkEvent(networkID, smodels.TypeComputerDisconnected, smodels.ComputerDisconnectedNotification{
+				NetworkID: networkID,
+				PublicKey: publicKey,
+			}, nil)
+			s.broadcastRoster(networkID)
 		}
 	}
 
@@ -808,7 +1249,7 @@ func (s *WebSocketServer) handleDisconnectNetwork(conn *websocket.Conn, req smod
 	s.statsManager.UpdateStats(len(s.clients), len(s.networks))
 }
 
-func (s *WebSocketServer) handleLeaveNetwork(conn *websocket.Conn, req smodels.LeaveNetworkRequest, originalID string) {
+func (s *WebSocketServer) handleLeaveNetwork(ctx context.Context, conn *websocket.Conn, req smodels.LeaveNetworkRequest, originalID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -817,7 +1258,7 @@ func (s *WebSocketServer) handleLeaveNetwork(conn *websocket.Conn, req smodels.L
 	if networkID == "" {
 		networkID = s.clients[conn]
 		if networkID == "" {
-			s.sendErrorSignal(conn, "Not connected to any network", originalID)
+			s.sendErrorSignal(conn, smodels.ErrNotConnected, "Not connected to any network", originalID)
 			return
 		}
 	}
@@ -827,52 +1268,33 @@ func (s *WebSocketServer) handleLeaveNetwork(conn *websocket.Conn, req smodels.L
 		var ok bool
 		publicKey, ok = s.clientToPublicKey[conn]
 		if !ok || publicKey == "" {
-			s.sendErrorSignal(conn, "Public key is required", originalID)
+			s.sendErrorSignal(conn, smodels.ErrPublicKeyRequired, "Public key is required", originalID)
 			return
 		}
 	}
 
-	network, err := s.supabaseManager.GetNetwork(networkID)
+	network, err := s.store.GetNetwork(networkID)
 	if err != nil {
-		s.sendErrorSignal(conn, "Network not found", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNetworkNotFound, "Network not found", originalID)
 		return
 	}
 
 	isCreator := (publicKey == network.OwnerPublicKey)
 
-	err = s.supabaseManager.RemoveComputerFromNetwork(networkID, publicKey)
+	err = s.store.RemoveComputerFromNetwork(networkID, publicKey)
 	if err != nil {
 		logger.Error("Error removing computer from computer_networks table", "error", err)
 	}
 
 	if isCreator {
-		logger.Info("Network owner leaving", "networkID", networkID, "intentionalDelete", true)
-
-		for _, computer := range s.networks[networkID] {
-			if computer != conn {
-				deletedNotification := smodels.NetworkDeletedNotification{
-					NetworkID: networkID,
-				}
-				s.sendSignal(computer, smodels.TypeNetworkDeleted, deletedNotification, "")
-			}
-		}
-
-		err := s.supabaseManager.DeleteNetwork(networkID)
-		if err != nil {
-			logger.Error("Error deleting network from Supabase", "error", err)
-		}
-
-		delete(s.networks, networkID)
-		for c, cNetworkID := range s.clients {
-			if cNetworkID == networkID {
-				delete(s.clients, c)
-			}
-		}
+		logger.Info("Network owner leaving", "networkID", networkID)
 
-		logger.Info("Network deleted because owner left", "networkID", networkID)
+		s.detachClient(conn, networkID)
+		s.handleOwnerDeparture(networkID, network, true)
 	} else {
 		s.removeClient(conn, networkID)
 	}
+	s.broadcastRoster(networkID)
 
 	s.statsManager.UpdateStats(len(s.clients), len(s.networks))
 
@@ -888,20 +1310,25 @@ func (s *WebSocketServer) handleLeaveNetwork(conn *websocket.Conn, req smodels.L
 }
 
 // handleKick processes a request to kick a computer from the network
-func (s *WebSocketServer) handleKick(conn *websocket.Conn, req smodels.KickRequest, originalID string) {
+func (s *WebSocketServer) handleKick(ctx context.Context, conn *websocket.Conn, req smodels.KickRequest, originalID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	network, err := s.supabaseManager.GetNetwork(req.NetworkID)
+	if req.TargetPublicKey != "" {
+		s.handleKickByPublicKey(ctx, conn, req, originalID)
+		return
+	}
+
+	network, err := s.store.GetNetwork(req.NetworkID)
 	if err != nil {
-		s.sendErrorSignal(conn, "Network does not exist", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNetworkNotFound, "Network does not exist", originalID)
 		return
 	}
 
 	// Verifica se o cliente é o dono da sala
 	publicKey, hasPublicKey := s.clientToPublicKey[conn]
 	if !hasPublicKey || publicKey != network.OwnerPublicKey {
-		s.sendErrorSignal(conn, "Only network owner can kick computers", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNotOwner, "Only network owner can kick computers", originalID)
 		return
 	}
 
@@ -912,8 +1339,9 @@ func (s *WebSocketServer) handleKick(conn *websocket.Conn, req smodels.KickReque
 			}
 			s.sendSignal(computer, smodels.TypeKicked, kickedPayload, "")
 
-			computer.Close()
+			s.closeConn(computer)
 			s.removeClient(computer, req.NetworkID)
+			s.broadcastRoster(req.NetworkID)
 			logger.Info("Client kicked from network", "targetID", req.TargetID, "networkID", req.NetworkID)
 
 			s.statsManager.UpdateStats(len(s.clients), len(s.networks))
@@ -927,31 +1355,31 @@ func (s *WebSocketServer) handleKick(conn *websocket.Conn, req smodels.KickReque
 		}
 	}
 
-	s.sendErrorSignal(conn, "Target client not found", originalID)
+	s.sendErrorSignal(conn, smodels.ErrTargetNotFound, "Target client not found", originalID)
 }
 
 // handleRename processes a request to rename a network
-func (s *WebSocketServer) handleRename(conn *websocket.Conn, req smodels.RenameRequest, originalID string) {
+func (s *WebSocketServer) handleRename(ctx context.Context, conn *websocket.Conn, req smodels.RenameRequest, originalID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	network, err := s.supabaseManager.GetNetwork(req.NetworkID)
+	network, err := s.store.GetNetwork(req.NetworkID)
 	if err != nil {
-		s.sendErrorSignal(conn, "Network does not exist", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNetworkNotFound, "Network does not exist", originalID)
 		return
 	}
 
 	// Verifica se o cliente é o dono da sala
 	publicKey, hasPublicKey := s.clientToPublicKey[conn]
 	if !hasPublicKey || publicKey != network.OwnerPublicKey {
-		s.sendErrorSignal(conn, "Only network owner can rename the network", originalID)
+		s.sendErrorSignal(conn, smodels.ErrNotOwner, "Only network owner can rename the network", originalID)
 		return
 	}
 
-	err = s.supabaseManager.UpdateNetworkName(req.NetworkID, req.NetworkName)
+	err = s.store.UpdateNetworkName(req.NetworkID, req.NetworkName)
 	if err != nil {
 		logger.Error("Error updating network name", "error", err)
-		s.sendErrorSignal(conn, "Error updating network name in database", originalID)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error updating network name in database", originalID)
 		return
 	}
 
@@ -963,9 +1391,7 @@ func (s *WebSocketServer) handleRename(conn *websocket.Conn, req smodels.RenameR
 		"network_name": req.NetworkName,
 	}
 
-	for _, computer := range s.networks[req.NetworkID] {
-		s.sendSignal(computer, smodels.TypeNetworkRenamed, renamePayload, "")
-	}
+	s.broadcastNetworkEvent(req.NetworkID, smodels.TypeNetworkRenamed, renamePayload, nil)
 
 	// Additional successful rename notification to the requester
 	s.sendSignal(conn, smodels.TypeRenameResponse, renamePayload, originalID)
@@ -987,16 +1413,12 @@ func (s *WebSocketServer) handleDisconnect(conn *websocket.Conn) {
 		logger.Debug("handleDisconnect: Client was in a network", "networkID", networkID, "publicKey", publicKey)
 
 		// Notify other members in the network about this client's departure
-		for _, computer := range s.networks[networkID] {
-			if computer != conn {
-				computerLeftPayload := map[string]interface{}{
-					"network_id": networkID,
-					"public_key": publicKey,
-				}
-				logger.Debug("handleDisconnect: Notifying other clients of computer left", "networkID", networkID, "targetConn", computer.RemoteAddr().String())
-				s.sendSignal(computer, smodels.TypeComputerLeft, computerLeftPayload, "")
-			}
+		computerLeftPayload := map[string]interface{}{
+			"network_id": networkID,
+			"public_key": publicKey,
 		}
+		logger.Debug("handleDisconnect: Notifying other clients of computer left", "networkID", networkID)
+		s.broadcastNetworkEvent(networkID, smodels.TypeComputerLeft, computerLeftPayload, conn)
 
 		// Clean up client references
 		delete(s.clients, conn)
@@ -1011,28 +1433,11 @@ func (s *WebSocketServer) handleDisconnect(conn *websocket.Conn) {
 					logger.Info("handleDisconnect: Computer status set to offline", "publicKey", publicKey, "networkID", networkID)
 
 					// Notify other clients in this network about the disconnection
-					if connectionsInNetwork, ok := s.networks[networkID]; ok { // Use networkID directly
-						logger.Debug("handleDisconnect: Iterating through connections in network to notify of disconnection", "networkID", networkID, "numConnections", len(connectionsInNetwork))
-						for _, clientConn := range connectionsInNetwork {
-							if clientConn != conn { // Don't send to the disconnected client itself
-								targetPublicKey, targetOk := s.clientToPublicKey[clientConn]
-								if targetOk {
-									notification := smodels.ComputerDisconnectedNotification{
-										NetworkID: networkID, // Use networkID directly
-										PublicKey: publicKey,
-									}
-									logger.Debug("handleDisconnect: Notifying client of computer disconnected", "networkID", networkID, "disconnectedPublicKey", publicKey, "targetClientPublicKey", targetPublicKey)
-									s.sendSignal(clientConn, smodels.TypeComputerDisconnected, notification, "")
-								} else {
-									logger.Warn("handleDisconnect: Could not find public key for client connection in network", "networkID", networkID, "clientAddr", clientConn.RemoteAddr().String())
-								}
-							} else {
-								logger.Debug("handleDisconnect: Skipping notification to the disconnected client itself", "networkID", networkID, "clientAddr", clientConn.RemoteAddr().String())
-							}
-						}
-					} else {
-						logger.Debug("handleDisconnect: No other connections found in network to notify of disconnection", "networkID", networkID)
-					}
+					logger.Debug("handleDisconnect: Notifying clients of computer disconnected", "networkID", networkID, "disconnectedPublicKey", publicKey)
+					s.broadcastNetworkEvent(networkID, smodels.TypeComputerDisconnected, smodels.ComputerDisconnectedNotification{
+						NetworkID: networkID,
+						PublicKey: publicKey,
+					}, conn)
 				}
 			}
 		}
@@ -1053,11 +1458,20 @@ func (s *WebSocketServer) handleDisconnect(conn *websocket.Conn) {
 			}
 		}
 
+		// The owner dropped: apply the network's owner policy
+		if hasPublicKey {
+			network, err := s.store.GetNetwork(networkID)
+			if err == nil && network.OwnerPublicKey == publicKey {
+				s.handleOwnerDeparture(networkID, network, false)
+			}
+		}
+
 		// If no more clients in the network, remove the network from memory (but keep in DB)
-		if len(s.networks[networkID]) == 0 {
+		if conns, exists := s.networks[networkID]; exists && len(conns) == 0 {
 			delete(s.networks, networkID)
 			logger.Info("handleDisconnect: Network now empty, removed from memory", "networkID", networkID)
 		}
+		s.broadcastRoster(networkID)
 	} else {
 		logger.Info("handleDisconnect: Client not in any network", "clientAddr", clientAddr)
 		delete(s.clientToPublicKey, conn)
@@ -1068,30 +1482,243 @@ func (s *WebSocketServer) handleDisconnect(conn *websocket.Conn) {
 
 // DeleteStaleNetworks removes networks that have not been active for a specified period
 // Logic: Query for networks that haven't been active past the expiry period and delete them
-func (s *WebSocketServer) DeleteStaleNetworks() {
-	staleNetworks, err := s.supabaseManager.GetStaleNetworks(s.config.NetworkExpiryDays)
+func (s *WebSocketServer) DeleteStaleNetworks() error {
+	s.mu.RLock()
+	expiryDays := s.config.NetworkExpiryDays
+	s.mu.RUnlock()
+
+	staleNetworks, err := s.store.GetStaleNetworks(expiryDays)
 	if err != nil {
-		logger.Error("Error fetching stale networks", "error", err)
-		return
+		return fmt.Errorf("fetching stale networks: %w", err)
 	}
 
 	numRemoved := 0
 	for _, network := range staleNetworks {
-		err := s.supabaseManager.DeleteNetwork(network.ID)
+		err := s.store.DeleteNetwork(network.ID)
 		if err != nil {
 			logger.Error("Error deleting stale network", "networkID", network.ID, "error", err)
 		} else {
 			logger.Info("Deleted stale network", "networkID", network.ID)
 			numRemoved++
+
+			s.mu.Lock()
+			delete(s.eventLogs, network.ID)
+			s.mu.Unlock()
+			s.networkStats.Delete(network.ID)
+			s.relayQuota.Delete(network.ID)
 		}
 	}
 
 	s.statsManager.UpdateCleanupStats(numRemoved)
+	return nil
+}
+
+// networkDeletesAt returns when an inactive network will be removed by DeleteStaleNetworks
+func networkDeletesAt(lastActive time.Time, expiryDays int) time.Time {
+	return lastActive.Add(time.Duration(expiryDays) * 24 * time.Hour)
+}
+
+// sendExpiryWarnings tells an owner which of their networks will soon be deleted for inactivity
+func (s *WebSocketServer) sendExpiryWarnings(conn *websocket.Conn, publicKey string) {
+	s.mu.RLock()
+	expiryDays := s.config.NetworkExpiryDays
+	warningDays := s.config.ExpiryWarningDays
+	s.mu.RUnlock()
+
+	if warningDays <= 0 {
+		return
+	}
+
+	ownedNetworks, err := s.store.GetNetworksByOwner(publicKey)
+	if err != nil {
+		logger.Error("Error fetching owned networks for expiry warning", "error", err, "publicKey", publicKey)
+		return
+	}
+
+	warnAfter := time.Now().Add(time.Duration(warningDays) * 24 * time.Hour)
+	for _, network := range ownedNetworks {
+		deletesAt := networkDeletesAt(network.LastActive, expiryDays)
+		if deletesAt.After(warnAfter) {
+			continue
+		}
+
+		logger.Info("Warning owner about network expiry", "networkID", network.ID, "deletesAt", deletesAt)
+		s.sendSignal(conn, smodels.TypeNetworkExpiryWarning, smodels.NetworkExpiryWarningNotification{
+			NetworkID:   network.ID,
+			NetworkName: network.Name,
+			LastActive:  network.LastActive,
+			DeletesAt:   deletesAt,
+		}, "")
+	}
+}
+
+// handleKeepNetworkAlive bumps the last activity of an owned network so it is not deleted
+func (s *WebSocketServer) handleKeepNetworkAlive(ctx context.Context, conn *websocket.Conn, req smodels.KeepNetworkAliveRequest, originalID string) {
+	s.mu.RLock()
+	publicKey, hasPublicKey := s.clientToPublicKey[conn]
+	expiryDays := s.config.NetworkExpiryDays
+	s.mu.RUnlock()
+
+	if !hasPublicKey {
+		publicKey = req.PublicKey
+	}
+
+	network, err := s.store.GetNetwork(req.NetworkID)
+	if err != nil {
+		s.sendErrorSignal(conn, smodels.ErrNetworkNotFound, "Network does not exist", originalID)
+		return
+	}
+
+	if publicKey == "" || publicKey != network.OwnerPublicKey {
+		s.sendErrorSignal(conn, smodels.ErrNotOwner, "Only network owner can keep the network alive", originalID)
+		return
+	}
+
+	if err := s.store.UpdateNetworkActivity(req.NetworkID); err != nil {
+		logger.Error("Error updating network activity", "error", err, "networkID", req.NetworkID)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error updating network activity", originalID)
+		return
+	}
+
+	logger.Info("Network kept alive by owner", "networkID", req.NetworkID)
+	s.sendSignal(conn, smodels.TypeKeepNetworkAliveResponse, smodels.KeepNetworkAliveResponse{
+		NetworkID: req.NetworkID,
+		DeletesAt: networkDeletesAt(time.Now(), expiryDays),
+	}, originalID)
+}
+
+// ReloadConfig applies the tunables of a freshly loaded configuration without restarting.
+// Settings that are bound at startup (port, Supabase, buffer sizes) are kept and a warning is logged.
+func (s *WebSocketServer) ReloadConfig(cfg Config) {
+	s.mu.Lock()
+	old := s.config
+
+	if cfg.Port != old.Port || cfg.Listen != old.Listen || cfg.AdminGRPCListen != old.AdminGRPCListen || cfg.StoreBackend != old.StoreBackend || cfg.SupabaseURL != old.SupabaseURL || cfg.SupabaseKey != old.SupabaseKey ||
+		cfg.SupabaseNetworksTable != old.SupabaseNetworksTable ||
+		cfg.ReadBufferSize != old.ReadBufferSize || cfg.WriteBufferSize != old.WriteBufferSize {
+		logger.Warn("Port, listen address, admin gRPC address, store, Supabase and buffer size changes require a restart and were ignored")
+	}
+
+	s.config.MaxClientsPerNetwork = cfg.MaxClientsPerNetwork
+	s.config.MaxNetworksPerOwner = cfg.MaxNetworksPerOwner
+	s.config.NetworkExpiryDays = cfg.NetworkExpiryDays
+	s.config.ExpiryWarningDays = cfg.ExpiryWarningDays
+	s.config.CleanupInterval = cfg.CleanupInterval
+	s.config.RequireAuth = cfg.RequireAuth
+	s.config.MaxConnsPerIP = cfg.MaxConnsPerIP
+	s.config.MaxTotalConns = cfg.MaxTotalConns
+	s.config.TrustedProxies = cfg.TrustedProxies
+	s.config.AuthTimeout = cfg.AuthTimeout
+	s.config.LogLevel = cfg.LogLevel
+	s.config.MaintenanceMode = cfg.MaintenanceMode
+	s.config.DuplicateSessionPolicy = cfg.DuplicateSessionPolicy
+	s.config.Motd = cfg.Motd
+	s.config.AdminToken = cfg.AdminToken
+	s.config.RelayQuotaBytes = cfg.RelayQuotaBytes
+	newCfg := s.config
+	s.mu.Unlock()
+
+	if cfg.LogLevel != "" && cfg.LogLevel != old.LogLevel {
+		if err := logger.SetLevel(cfg.LogLevel); err != nil {
+			logger.Warn("Invalid log level on reload", "logLevel", cfg.LogLevel, "error", err)
+		}
+	}
+
+	if cfg.CleanupInterval != old.CleanupInterval {
+		s.jobs.SetInterval(jobStaleNetworks, cfg.CleanupInterval)
+	}
+
+	s.statsManager.SetConfig(newCfg)
+
+	logger.Info("Configuration reloaded",
+		"maxClientsPerNetwork", newCfg.MaxClientsPerNetwork,
+		"maxNetworksPerOwner", newCfg.MaxNetworksPerOwner,
+		"networkExpiryDays", newCfg.NetworkExpiryDays,
+		"cleanupInterval", newCfg.CleanupInterval.String(),
+		"requireAuth", newCfg.RequireAuth,
+		"maxConnsPerIP", newCfg.MaxConnsPerIP,
+		"trustedProxies", newCfg.TrustedProxies,
+		"maxTotalConns", newCfg.MaxTotalConns,
+		"maintenanceMode", newCfg.MaintenanceMode,
+		"duplicateSessionPolicy", newCfg.DuplicateSessionPolicy,
+		"relayQuotaBytes", newCfg.RelayQuotaBytes,
+		"logLevel", newCfg.LogLevel)
 }
 
 // Start initializes and starts the WebSocket server
-// Logic: Set up HTTP handlers, start network cleanup routine, and listen for incoming connections
+// Logic: Set up HTTP handlers, start network cleanup routine, and listen for incoming connections.
+// The server listens on every LISTEN address, or on port when LISTEN is empty.
 func (s *WebSocketServer) Start(port string) error {
+	s.mu.RLock()
+	listen := s.config.Listen
+	natProbePort := s.config.NatProbePort
+	adminGRPCListen := s.config.AdminGRPCListen
+	s.mu.RUnlock()
+
+	specs, err := parseListenerSpecs(listen)
+	if err != nil {
+		return fmt.Errorf("invalid listen address: %w", err)
+	}
+	if len(specs) == 0 {
+		specs = []listenerSpec{{network: "tcp", address: ":" + port}}
+	}
+
+	// Open every listener first so a bad address fails the start
+	listeners, err := openListeners(specs)
+	if err != nil {
+		return err
+	}
+
+	if natProbePort > 0 {
+		probe, err := startNATProbe(natProbePort)
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return err
+		}
+		s.natProbe = probe
+		logger.Info("NAT probe listening", "ports", probe.Ports())
+	}
+
+	if adminGRPCListen != "" {
+		if err := s.startAdminGRPC(adminGRPCListen); err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			if s.natProbe != nil {
+				s.natProbe.Close()
+			}
+			return err
+		}
+	}
+
+	// Create an HTTP server with the mux
+	s.httpServer = &http.Server{
+		Handler: s.Handler(),
+	}
+
+	// Run stale network cleanup and the other maintenance jobs in the background
+	s.registerJobs()
+	s.jobs.Start()
+
+	logger.Info("WebSocket server starting", "listeners", len(listeners))
+
+	// Serve each listener in a separate goroutine; Shutdown closes them all
+	for i, ln := range listeners {
+		go func(spec listenerSpec, ln net.Listener) {
+			logger.Info("WebSocket server listening", "address", spec.String())
+			if err := s.httpServer.Serve(ln); err != http.ErrServerClosed {
+				logger.Error("HTTP server error", "address", spec.String(), "error", err)
+			}
+		}(specs[i], ln)
+	}
+
+	return nil
+}
+
+// Handler returns the HTTP handler serving the WebSocket endpoint and the HTTP API
+func (s *WebSocketServer) Handler() http.Handler {
 	mux := http.NewServeMux()
 
 	// Add handlers to the mux
@@ -1106,47 +1733,21 @@ func (s *WebSocketServer) Start(port string) error {
 	// Add stats endpoint
 	mux.HandleFunc("/stats", s.handleStatsEndpoint)
 
-	// Create an HTTP server with the mux
-	s.httpServer = &http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
-	}
-
-	// Periodically delete stale networks
-	go func() {
-		ticker := time.NewTicker(s.config.CleanupInterval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				s.DeleteStaleNetworks()
-			case <-s.shutdownChan:
-				return // Stop cleanup goroutine when server shuts down
-			}
-		}
-	}()
-
-	logger.Info("WebSocket server starting", "port", port)
-
-	// Start HTTP server in a separate goroutine so we can return errors
-	go func() {
-		logger.Info("WebSocket server listening", "port", port)
-		if err := s.httpServer.ListenAndServe(); err != http.ErrServerClosed {
-			logger.Error("HTTP server error", "error", err)
-		}
-	}()
+	// Add HTTP API endpoints
+	s.registerAPIRoutes(mux)
+	s.registerAdminRoutes(mux)
 
-	return nil
+	return mux
 }
 
 // handlePing processes ping messages from clients and responds with a pong
 // This allows clients to verify their connection to the server
-func (s *WebSocketServer) handlePing(conn *websocket.Conn, payload []byte, originalID string) {
+func (s *WebSocketServer) handlePing(ctx context.Context, conn *websocket.Conn, payload []byte, originalID string) {
 	// Parse the ping message
 	var pingData map[string]interface{}
 	if err := json.Unmarshal(payload, &pingData); err != nil {
 		logger.Error("Error parsing ping payload", "error", err)
-		s.sendErrorSignal(conn, "Invalid ping format", originalID)
+		s.sendErrorSignal(conn, smodels.ErrInvalidRequest, "Invalid ping format", originalID)
 		return
 	}
 
@@ -1163,8 +1764,8 @@ func (s *WebSocketServer) handlePing(conn *websocket.Conn, payload []byte, origi
 	s.sendSignal(conn, smodels.TypePing, pongPayload, originalID)
 }
 
-// removeClient remove um cliente da sala e, se necessário, a sala do Supabase
-// Logic: Clean up client references and potentially delete network if owner leaves
+// removeClient remove um cliente da sala e aplica a política do dono se ele for o criador
+// Logic: Clean up client references and, if the owner was removed, apply the network's owner policy
 func (s *WebSocketServer) removeClient(conn *websocket.Conn, networkID string) {
 	// Se o networkID não foi fornecido, não há nada a fazer
 	if networkID == "" {
@@ -1173,73 +1774,48 @@ func (s *WebSocketServer) removeClient(conn *websocket.Conn, networkID string) {
 		return
 	}
 
-	// Recupera a chave pública do cliente antes de removê-lo
-	publicKey, hasPublicKey := s.clientToPublicKey[conn]
-
-	// Limpa as referências do cliente
-	delete(s.clients, conn)
-	delete(s.clientToPublicKey, conn)
-
-	// Se a sala não existe no networks, não há nada mais a fazer
-	network, exists := s.networks[networkID]
-	if !exists {
-		return
-	}
-
-	// Remove o cliente da lista de conexões da sala
-	for i, computer := range network {
-		if computer == conn {
-			s.networks[networkID] = append(network[:i], network[i+1:]...)
-			break
-		}
-	}
+	publicKey, hasPublicKey := s.detachClient(conn, networkID)
 
 	// Verifica se o cliente que está saindo é o dono da sala com base na chave pública
-	isCreator := false
 	if hasPublicKey {
-		// Busca a sala no banco de dados
-		network, err := s.supabaseManager.GetNetwork(networkID)
+		network, err := s.store.GetNetwork(networkID)
 		if err == nil && publicKey == network.OwnerPublicKey {
-			// A chave pública do cliente que está saindo coincide com a do criador da sala
-			isCreator = true
 			logger.Info("Network creator disconnected", "publicKey", publicKey)
+			s.handleOwnerDeparture(networkID, network, false)
 		}
 	}
 
-	// Se for o criador, deleta a sala do banco de dados
-	if isCreator {
-		err := s.supabaseManager.DeleteNetwork(networkID)
-		if err != nil {
-			logger.Debug("Error deleting network from Supabase on creator disconnect", "error", err)
-		}
+	s.statsManager.UpdateStats(len(s.clients), len(s.networks))
 
-		// Notifica todos os outros participantes da sala que ela foi excluída
-		for _, computer := range s.networks[networkID] {
-			if computer != conn {
-				// Usando o struct correto do models para TypeNetworkDeleted
-				deletedNotification := smodels.NetworkDeletedNotification{
-					NetworkID: networkID,
-				}
-				s.sendSignal(computer, smodels.TypeNetworkDeleted, deletedNotification, "")
-			}
+	logger.Info("Client left network", "clientAddr", conn.RemoteAddr().String(), "networkID", networkID)
+}
+
+// detachClient removes a connection from the in-memory state of a network.
+// It returns the public key the connection was using, if any.
+func (s *WebSocketServer) detachClient(conn *websocket.Conn, networkID string) (string, bool) {
+	publicKey, hasPublicKey := s.clientToPublicKey[conn]
+
+	delete(s.clients, conn)
+	delete(s.clientToPublicKey, conn)
+
+	if hasPublicKey {
+		if computers, ok := s.connectedComputers[networkID]; ok {
+			delete(computers, publicKey)
 		}
+	}
 
-		// Remove completamente a sala e seus clientes
-		delete(s.networks, networkID)
-		for c, cNetworkID := range s.clients {
-			if cNetworkID == networkID {
-				delete(s.clients, c)
-			}
+	conns, exists := s.networks[networkID]
+	for i, computer := range conns {
+		if computer == conn {
+			s.networks[networkID] = append(conns[:i], conns[i+1:]...)
+			break
 		}
-		logger.Info("Network deleted because owner disconnected", "networkID", networkID)
-	} else if len(s.networks[networkID]) == 0 {
-		// Se não houver mais clientes na sala, remove a referência da sala
+	}
+	if exists && len(s.networks[networkID]) == 0 {
 		delete(s.networks, networkID)
 	}
 
-	s.statsManager.UpdateStats(len(s.clients), len(s.networks))
-
-	logger.Info("Client left network", "clientAddr", conn.RemoteAddr().String(), "networkID", networkID)
+	return publicKey, hasPublicKey
 }
 
 // handleStatsEndpoint is the HTTP handler for the /stats endpoint
@@ -1253,16 +1829,22 @@ func (s *WebSocketServer) isComputerOnline(networkID, publicKey string) bool {
 func (s *WebSocketServer) handleStatsEndpoint(w http.ResponseWriter, r *http.Request) {
 	s.statsManager.UpdateStats(len(s.clients), len(s.networks))
 
+	s.mu.RLock()
+	cfg := s.config
+	s.mu.RUnlock()
+
 	w.Header().Set("Content-Type", "application/json")
 
 	// Create response with current statistics
 	statsResponse := map[string]interface{}{
-		"server_stats": s.statsManager.GetStats(),
+		"server_stats":  s.statsManager.GetStats(),
+		"message_stats": s.statsManager.GetMessageStats(),
+		"job_stats":     s.jobs.Stats(),
 		"config": map[string]interface{}{
-			"max_clients_per_network": s.config.MaxClientsPerNetwork,
-			"network_expiry_days":     s.config.NetworkExpiryDays,
-			"cleanup_interval":        s.config.CleanupInterval.String(),
-			"allow_all_origins":       s.config.AllowAllOrigins,
+			"max_clients_per_network": cfg.MaxClientsPerNetwork,
+			"network_expiry_days":     cfg.NetworkExpiryDays,
+			"cleanup_interval":        cfg.CleanupInterval.String(),
+			"allow_all_origins":       cfg.AllowAllOrigins,
 		},
 	}
 
@@ -1279,34 +1861,42 @@ func (s *WebSocketServer) handleStatsEndpoint(w http.ResponseWriter, r *http.Req
 }
 
 // handleGetComputerNetworks processes a request to get all networks a computer has joined
-func (s *WebSocketServer) handleGetComputerNetworks(conn *websocket.Conn, req smodels.GetComputerNetworksRequest, originalID string) {
-	s.handleGetComputerNetworksWithIP(conn, req, originalID)
+func (s *WebSocketServer) handleGetComputerNetworks(ctx context.Context, conn *websocket.Conn, req smodels.GetComputerNetworksRequest, originalID string) {
+	s.handleGetComputerNetworksWithIP(ctx, conn, req, originalID)
 }
 
 // handleGetComputerNetworksWithIP processes a request to get all networks a computer has joined and optionally sends IP info
-func (s *WebSocketServer) handleGetComputerNetworksWithIP(conn *websocket.Conn, req smodels.GetComputerNetworksRequest, originalID string) {
+func (s *WebSocketServer) handleGetComputerNetworksWithIP(ctx context.Context, conn *websocket.Conn, req smodels.GetComputerNetworksRequest, originalID string) {
 
 	if req.PublicKey == "" {
-		s.sendErrorSignal(conn, "Public key is required", originalID)
+		s.sendErrorSignal(conn, smodels.ErrPublicKeyRequired, "Public key is required", originalID)
 		return
 	}
 
 	// Get all networks for this computer
-	computerNetworks, err := s.supabaseManager.GetComputerNetworks(req.PublicKey)
+	computerNetworks, err := s.store.GetComputerNetworks(req.PublicKey)
 	if err != nil {
 		logger.Error("Error fetching computer networks", "error", err, "publicKey", req.PublicKey)
-		s.sendErrorSignal(conn, "Error fetching computer networks", originalID)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error fetching computer networks", originalID)
 		return
 	}
 
+	page, nextCursor := pageComputerNetworks(computerNetworks, req.Cursor, computerNetworksPageSize(req.PageSize))
+
 	// Build response with network details
 	response := smodels.ComputerNetworksResponse{
-		Networks: make([]smodels.ComputerNetworkInfo, 0, len(computerNetworks)),
+		Networks:   make([]smodels.ComputerNetworkInfo, 0, len(page)),
+		NextCursor: nextCursor,
+		Total:      len(computerNetworks),
 	}
 
-	for _, computerNetwork := range computerNetworks {
+	for _, computerNetwork := range page {
+		if requestAbandoned(ctx, conn, "get computer networks") {
+			return
+		}
+
 		// Get network details
-		network, err := s.supabaseManager.GetNetwork(computerNetwork.NetworkID)
+		network, err := s.store.GetNetwork(computerNetwork.NetworkID)
 		if err != nil {
 			// Skip networks that no longer exist
 			logger.Debug("Network no longer exists", "networkID", computerNetwork.NetworkID)
@@ -1314,13 +1904,14 @@ func (s *WebSocketServer) handleGetComputerNetworksWithIP(conn *websocket.Conn,
 		}
 
 		// Get all computers (computers) in this network
-		computersInNetwork, err := s.supabaseManager.GetComputersInNetwork(computerNetwork.NetworkID)
+		computersInNetwork, err := s.store.GetComputersInNetwork(computerNetwork.NetworkID)
 		if err != nil {
 			logger.Error("Error fetching computers for network", "error", err, "networkID", computerNetwork.NetworkID)
 			// Continue without computers for this network, or handle as an error
 		}
 
 		var computerInfos []smodels.ComputerInfo
+		s.mu.RLock()
 		for _, computer := range computersInNetwork {
 			isOnline := s.isComputerOnline(computerNetwork.NetworkID, computer.PublicKey)
 
@@ -1329,8 +1920,10 @@ func (s *WebSocketServer) handleGetComputerNetworksWithIP(conn *websocket.Conn,
 				ComputerIP: computer.PeerIP,
 				PublicKey:  computer.PublicKey,
 				IsOnline:   isOnline,
+				Wakeable:   s.isWakeable(computer.PublicKey),
 			})
 		}
+		s.mu.RUnlock()
 
 		networkInfo := smodels.ComputerNetworkInfo{
 			NetworkID:      computerNetwork.NetworkID,
@@ -1339,14 +1932,28 @@ func (s *WebSocketServer) handleGetComputerNetworksWithIP(conn *websocket.Conn,
 			LastConnected:  computerNetwork.LastConnected,
 			ComputerIP:     computerNetwork.PeerIP,
 			AdminPublicKey: network.OwnerPublicKey,
+			OwnerPolicy:    smodels.OwnerPolicy(network.OwnerPolicy),
+			Subnet:         network.Subnet,
 			Computers:      computerInfos,
 		}
+		networkInfo.Version = networkInfoVersion(networkInfo)
+
+		// The client already has this exact state, send only the version
+		if known, ok := req.KnownVersions[networkInfo.NetworkID]; ok && known == networkInfo.Version {
+			networkInfo = smodels.ComputerNetworkInfo{
+				NetworkID: networkInfo.NetworkID,
+				Version:   networkInfo.Version,
+				Unchanged: true,
+			}
+		}
 		response.Networks = append(response.Networks, networkInfo)
 	}
 
 	logger.Debug("Sending computer networks",
 		"publicKey", req.PublicKey,
-		"networkCount", len(response.Networks))
+		"networkCount", len(response.Networks),
+		"total", response.Total,
+		"nextCursor", response.NextCursor)
 
 	jsonPayload, err := json.MarshalIndent(response, "", "  ")
 	if err != nil {
@@ -1359,6 +1966,60 @@ func (s *WebSocketServer) handleGetComputerNetworksWithIP(conn *websocket.Conn,
 	s.sendSignal(conn, smodels.TypeComputerNetworks, response, originalID)
 }
 
+// publicNetworksLimit caps how many networks a single ListPublicNetworks response returns
+const publicNetworksLimit = 100
+
+// handleListPublicNetworks returns the networks their owners marked as public, without PINs
+func (s *WebSocketServer) handleListPublicNetworks(ctx context.Context, conn *websocket.Conn, req smodels.ListPublicNetworksRequest, originalID string) {
+	tag := strings.ToLower(strings.TrimSpace(req.Tag))
+
+	networks, err := s.store.ListPublicNetworks(tag, publicNetworksLimit)
+	if err != nil {
+		logger.Error("Error listing public networks", "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error listing public networks", originalID)
+		return
+	}
+
+	if requestAbandoned(ctx, conn, "list public networks") {
+		return
+	}
+
+	networkIDs := make([]string, 0, len(networks))
+	for _, network := range networks {
+		networkIDs = append(networkIDs, network.ID)
+	}
+
+	memberCounts, err := s.store.GetMemberCounts(networkIDs)
+	if err != nil {
+		logger.Error("Error counting public network members", "error", err)
+		s.sendErrorSignal(conn, smodels.ErrInternal, "Error listing public networks", originalID)
+		return
+	}
+
+	s.mu.RLock()
+	response := smodels.PublicNetworksResponse{
+		Networks: make([]smodels.PublicNetworkInfo, 0, len(networks)),
+	}
+	for _, network := range networks {
+		tags := network.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		response.Networks = append(response.Networks, smodels.PublicNetworkInfo{
+			NetworkID:   network.ID,
+			NetworkName: network.Name,
+			MemberCount: memberCounts[network.ID],
+			OnlineCount: len(s.connectedComputers[network.ID]),
+			MaxMembers:  s.config.MaxClientsPerNetwork,
+			Tags:        tags,
+		})
+	}
+	s.mu.RUnlock()
+
+	logger.Debug("Sending public networks", "tag", tag, "networkCount", len(response.Networks))
+	s.sendSignal(conn, smodels.TypePublicNetworks, response, originalID)
+}
+
 // InitiateGracefulShutdown starts the graceful shutdown process
 func (s *WebSocketServer) InitiateGracefulShutdown(timeout time.Duration, restartInfo string) {
 	staticOnce := func() func() bool {
@@ -1403,6 +2064,15 @@ func (s *WebSocketServer) InitiateGracefulShutdown(timeout time.Duration, restar
 			logger.Error("HTTP server shutdown error", "error", err)
 		}
 	}
+	if s.adminGRPC != nil {
+		stopAdminGRPC(ctx, s.adminGRPC)
+	}
+
+	// Let a cleanup in progress finish before reporting the shutdown as complete
+	s.jobs.Stop()
+	if s.natProbe != nil {
+		s.natProbe.Close()
+	}
 
 	// Signal successful shutdown (close only if not already closed)
 	select {
@@ -1452,7 +2122,7 @@ func (s *WebSocketServer) persistStateForRestart() {
 	// Update all active network timestamps in the database
 	for networkID := range s.networks {
 		// Ensure this network's activity is updated to prevent cleanup
-		err := s.supabaseManager.UpdateNetworkActivity(networkID)
+		err := s.store.UpdateNetworkActivity(networkID)
 		if err != nil {
 			logger.Error("Error updating network activity", "networkID", networkID, "error", err)
 		} else {
@@ -0,0 +1,628 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/itxtoledo/govpn/libs/logger"
+	smodels "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// testPIN matches utils.DefaultPINPattern
+const testPIN = "1234"
+
+// startTestServer starts an in-process server with the default configuration changed by
+// configure, closed when the test ends
+func startTestServer(t *testing.T, configure func(*Config)) *inProcessServer {
+	t.Helper()
+
+	cfg := defaultConfig()
+	if configure != nil {
+		configure(&cfg)
+	}
+	server, err := startInProcessServer(cfg)
+	if err != nil {
+		t.Fatalf("start server: %v", err)
+	}
+	t.Cleanup(server.Close)
+	return server
+}
+
+// dial connects an authenticated client, closed when the test ends
+func dial(t *testing.T, server *inProcessServer) *harnessClient {
+	t.Helper()
+
+	client, err := server.Dial()
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// dialKey connects an authenticated client with an existing key, closed when the test ends
+func dialKey(t *testing.T, server *inProcessServer, key ed25519.PrivateKey) *harnessClient {
+	t.Helper()
+
+	client, err := server.DialKey(key)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// createNetwork makes owner create a network and returns its ID
+func createNetwork(t *testing.T, owner *harnessClient, name string) string {
+	t.Helper()
+
+	resp, err := owner.CreateNetwork(name, testPIN)
+	if err != nil {
+		t.Fatalf("create network: %v", err)
+	}
+	if resp.NetworkID == "" {
+		t.Fatal("create network: empty network ID")
+	}
+	return resp.NetworkID
+}
+
+// connections returns how many connections the server holds in a network
+func connections(server *inProcessServer, networkID string) int {
+	server.Server.mu.RLock()
+	defer server.Server.mu.RUnlock()
+	return len(server.Server.networks[networkID])
+}
+
+// expectRoster waits for a roster that lists publicKey, skipping older rosters
+func expectRoster(t *testing.T, client *harnessClient, publicKey string) {
+	t.Helper()
+
+	for {
+		msg, err := client.Expect(smodels.TypeNetworkRoster)
+		if err != nil {
+			t.Fatalf("no roster with %s: %v", publicKey, err)
+		}
+		var roster smodels.NetworkRosterNotification
+		if err := json.Unmarshal(msg.Payload, &roster); err != nil {
+			t.Fatalf("decode roster: %v", err)
+		}
+		for _, member := range roster.Members {
+			if member.PublicKey == publicKey {
+				return
+			}
+		}
+	}
+}
+
+// logBuffer collects the server log of a test
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLog sends every log message of the test, debug included, to the returned buffer
+func captureLog(t *testing.T) *logBuffer {
+	t.Helper()
+
+	buf := &logBuffer{}
+	if err := logger.Setup(logger.Options{Level: "debug", Console: buf}); err != nil {
+		t.Fatalf("set up logger: %v", err)
+	}
+	t.Cleanup(func() { logger.Setup(logger.Options{Level: "debug", Console: os.Stdout}) })
+	return buf
+}
+
+// assertNotLogged fails the test if secret appears in the log
+func assertNotLogged(t *testing.T, log *logBuffer, what, secret string) {
+	t.Helper()
+
+	if strings.Contains(log.String(), secret) {
+		t.Errorf("%s %q found in the server log", what, secret)
+	}
+}
+
+func TestCreateNetwork(t *testing.T) {
+	server := startTestServer(t, nil)
+	owner := dial(t, server)
+
+	networkID := createNetwork(t, owner, "office")
+
+	network, err := server.Store.GetNetwork(networkID)
+	if err != nil {
+		t.Fatalf("network not stored: %v", err)
+	}
+	if network.Name != "office" {
+		t.Errorf("network name = %q, want %q", network.Name, "office")
+	}
+	if network.OwnerPublicKey != owner.PublicKey {
+		t.Errorf("owner = %q, want %q", network.OwnerPublicKey, owner.PublicKey)
+	}
+	if member, err := server.Store.IsComputerInNetwork(networkID, owner.PublicKey); err != nil || !member {
+		t.Errorf("owner is not a member of the network (err %v)", err)
+	}
+	if n := connections(server, networkID); n != 1 {
+		t.Errorf("connections in network = %d, want 1", n)
+	}
+}
+
+func TestCreateNetworkRejectsInvalidPIN(t *testing.T) {
+	server := startTestServer(t, nil)
+	owner := dial(t, server)
+
+	if _, err := owner.CreateNetwork("office", "12ab"); err == nil {
+		t.Fatal("network created with an invalid PIN")
+	}
+	if networks, _ := server.Store.ListNetworks(); len(networks) != 0 {
+		t.Errorf("stored networks = %d, want 0", len(networks))
+	}
+}
+
+func TestJoinNetwork(t *testing.T) {
+	server := startTestServer(t, nil)
+	owner := dial(t, server)
+	member := dial(t, server)
+	networkID := createNetwork(t, owner, "office")
+
+	resp, err := member.JoinNetwork(networkID, testPIN, "laptop")
+	if err != nil {
+		t.Fatalf("join network: %v", err)
+	}
+	if resp.NetworkID != networkID {
+		t.Errorf("joined network = %q, want %q", resp.NetworkID, networkID)
+	}
+	if resp.ComputerIP == "" {
+		t.Error("no IP assigned to the new member")
+	}
+
+	computer, err := server.Store.GetComputerInNetwork(networkID, member.PublicKey)
+	if err != nil {
+		t.Fatalf("member not stored: %v", err)
+	}
+	if computer.ComputerName != "laptop" {
+		t.Errorf("computer name = %q, want %q", computer.ComputerName, "laptop")
+	}
+	expectRoster(t, owner, member.PublicKey)
+}
+
+func TestJoinNetworkWrongPIN(t *testing.T) {
+	server := startTestServer(t, nil)
+	owner := dial(t, server)
+	member := dial(t, server)
+	networkID := createNetwork(t, owner, "office")
+
+	if _, err := member.JoinNetwork(networkID, "9999", "laptop"); err == nil {
+		t.Fatal("joined with a wrong PIN")
+	}
+	if joined, _ := server.Store.IsComputerInNetwork(networkID, member.PublicKey); joined {
+		t.Error("member stored after a wrong PIN")
+	}
+}
+
+func TestKick(t *testing.T) {
+	server := startTestServer(t, nil)
+	owner := dial(t, server)
+	member := dial(t, server)
+	networkID := createNetwork(t, owner, "office")
+	if _, err := member.JoinNetwork(networkID, testPIN, "laptop"); err != nil {
+		t.Fatalf("join network: %v", err)
+	}
+
+	if err := member.Kick(networkID, owner.Addr()); err == nil {
+		t.Error("a member kicked the owner")
+	}
+
+	if err := owner.Kick(networkID, member.Addr()); err != nil {
+		t.Fatalf("kick: %v", err)
+	}
+	msg, err := member.Expect(smodels.TypeKicked)
+	if err != nil {
+		t.Fatalf("kicked member not told: %v", err)
+	}
+	var kicked struct {
+		NetworkID string `json:"network_id"`
+	}
+	if err := json.Unmarshal(msg.Payload, &kicked); err != nil || kicked.NetworkID != networkID {
+		t.Errorf("kicked from %q, want %q (err %v)", kicked.NetworkID, networkID, err)
+	}
+	if n := connections(server, networkID); n != 1 {
+		t.Errorf("connections in network after kick = %d, want 1", n)
+	}
+	if err := owner.Kick(networkID, member.Addr()); err == nil {
+		t.Error("kicked a member that is no longer connected")
+	}
+}
+
+func TestOwnerLeaveDeletesNetwork(t *testing.T) {
+	server := startTestServer(t, func(cfg *Config) {
+		cfg.DefaultOwnerPolicy = string(smodels.OwnerPolicyPreserve)
+	})
+	owner := dial(t, server)
+	member := dial(t, server)
+	networkID := createNetwork(t, owner, "office")
+	if _, err := member.JoinNetwork(networkID, testPIN, "laptop"); err != nil {
+		t.Fatalf("join network: %v", err)
+	}
+
+	if err := owner.LeaveNetwork(networkID); err != nil {
+		t.Fatalf("leave network: %v", err)
+	}
+
+	msg, err := member.Expect(smodels.TypeNetworkDeleted)
+	if err != nil {
+		t.Fatalf("member not told about the deletion: %v", err)
+	}
+	var deleted smodels.NetworkDeletedNotification
+	if err := json.Unmarshal(msg.Payload, &deleted); err != nil || deleted.NetworkID != networkID {
+		t.Errorf("deleted network = %q, want %q (err %v)", deleted.NetworkID, networkID, err)
+	}
+	if exists, _ := server.Store.NetworkExists(networkID); exists {
+		t.Error("network still stored after its owner left")
+	}
+}
+
+func TestOwnerLeaveTransfersNetwork(t *testing.T) {
+	server := startTestServer(t, func(cfg *Config) {
+		cfg.DefaultOwnerPolicy = string(smodels.OwnerPolicyTransfer)
+	})
+	owner := dial(t, server)
+	member := dial(t, server)
+	networkID := createNetwork(t, owner, "office")
+	if _, err := member.JoinNetwork(networkID, testPIN, "laptop"); err != nil {
+		t.Fatalf("join network: %v", err)
+	}
+
+	if err := owner.LeaveNetwork(networkID); err != nil {
+		t.Fatalf("leave network: %v", err)
+	}
+
+	msg, err := member.Expect(smodels.TypeNetworkOwnerChanged)
+	if err != nil {
+		t.Fatalf("member not told about the transfer: %v", err)
+	}
+	var changed smodels.NetworkOwnerChangedNotification
+	if err := json.Unmarshal(msg.Payload, &changed); err != nil || changed.OwnerPublicKey != member.PublicKey {
+		t.Errorf("announced owner = %q, want %q (err %v)", changed.OwnerPublicKey, member.PublicKey, err)
+	}
+	network, err := server.Store.GetNetwork(networkID)
+	if err != nil {
+		t.Fatalf("network deleted although it could be transferred: %v", err)
+	}
+	if network.OwnerPublicKey != member.PublicKey {
+		t.Errorf("owner = %q, want the remaining member %q", network.OwnerPublicKey, member.PublicKey)
+	}
+	if joined, _ := server.Store.IsComputerInNetwork(networkID, owner.PublicKey); joined {
+		t.Error("former owner still a member after leaving")
+	}
+}
+
+func TestPINsNotLogged(t *testing.T) {
+	// Unlikely to show up in the log other than as a leaked PIN
+	const createPIN, newPIN = "7193", "4682"
+
+	log := captureLog(t)
+	server := startTestServer(t, nil)
+	owner := dial(t, server)
+	member := dial(t, server)
+
+	resp, err := owner.CreateNetwork("pins", createPIN)
+	if err != nil {
+		t.Fatalf("create network: %v", err)
+	}
+	if _, err := member.JoinNetwork(resp.NetworkID, createPIN, "laptop"); err != nil {
+		t.Fatalf("join network: %v", err)
+	}
+	if err := owner.ChangePIN(resp.NetworkID, newPIN); err != nil {
+		t.Fatalf("change PIN: %v", err)
+	}
+
+	assertNotLogged(t, log, "PIN", createPIN)
+	assertNotLogged(t, log, "new PIN", newPIN)
+}
+
+func TestRecoveryCodeNotLogged(t *testing.T) {
+	log := captureLog(t)
+	server := startTestServer(t, nil)
+	old := dial(t, server)
+	replacement := dial(t, server)
+
+	code, err := old.CreateRecoveryCode()
+	if err != nil {
+		t.Fatalf("create recovery code: %v", err)
+	}
+	if _, err := replacement.MigrateKey(old, code); err != nil {
+		t.Fatalf("migrate key: %v", err)
+	}
+
+	assertNotLogged(t, log, "recovery code", code)
+	if !strings.Contains(log.String(), string(smodels.TypeMigrateKey)) {
+		t.Error("the migration request was not logged at all")
+	}
+}
+
+// createFailingStore fails every network creation without writing anything, like a
+// database transaction that was rolled back
+type createFailingStore struct {
+	*MemoryStore
+}
+
+func (createFailingStore) CreateNetworkWithOwner(SupabaseNetwork, string, string) error {
+	return errors.New("transaction rolled back")
+}
+
+func TestCreateNetworkFailureLeavesNothing(t *testing.T) {
+	memory := NewMemoryStore()
+	server, err := startInProcessServerWithStore(defaultConfig(), memory, createFailingStore{memory})
+	if err != nil {
+		t.Fatalf("start server: %v", err)
+	}
+	t.Cleanup(server.Close)
+	owner := dial(t, server)
+
+	_, err = owner.CreateNetwork("office", testPIN)
+	if code := errorCode(err); code != smodels.ErrInternal {
+		t.Fatalf("create network error = %v, want %s", err, smodels.ErrInternal)
+	}
+	if networks, _ := server.Store.ListNetworks(); len(networks) != 0 {
+		t.Errorf("stored networks = %d, want 0", len(networks))
+	}
+
+	server.Server.mu.RLock()
+	defer server.Server.mu.RUnlock()
+	if len(server.Server.networks) != 0 || len(server.Server.clients) != 0 {
+		t.Errorf("server tracks %d networks and %d clients after a failed create, want none", len(server.Server.networks), len(server.Server.clients))
+	}
+}
+
+func TestCreateNetworkIdempotentReplay(t *testing.T) {
+	server := startTestServer(t, nil)
+	owner := dial(t, server)
+
+	req := smodels.CreateNetworkRequest{
+		BaseRequest:    smodels.BaseRequest{PublicKey: owner.PublicKey},
+		NetworkName:    "office",
+		PIN:            testPIN,
+		ComputerName:   "owner",
+		IdempotencyKey: "create-office",
+	}
+	var networkIDs []string
+	for range 2 {
+		msg, err := owner.Request(smodels.TypeCreateNetwork, req, smodels.TypeNetworkCreated)
+		if err != nil {
+			t.Fatalf("create network: %v", err)
+		}
+		var resp smodels.CreateNetworkResponse
+		if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		networkIDs = append(networkIDs, resp.NetworkID)
+	}
+
+	if networkIDs[0] != networkIDs[1] {
+		t.Errorf("retried create answered %q, want the original %q", networkIDs[1], networkIDs[0])
+	}
+	if networks, _ := server.Store.ListNetworks(); len(networks) != 1 {
+		t.Errorf("stored networks = %d, want 1", len(networks))
+	}
+	if n := connections(server, networkIDs[0]); n != 1 {
+		t.Errorf("connections in network = %d, want 1", n)
+	}
+}
+
+// joinAndLeave joins member to a network, closes its connection once the owner saw it
+// go, and returns the last network event sequence the member had seen
+func joinAndLeave(t *testing.T, owner, member *harnessClient, networkID string) uint64 {
+	t.Helper()
+
+	if _, err := member.JoinNetwork(networkID, testPIN, "laptop"); err != nil {
+		t.Fatalf("join network: %v", err)
+	}
+	msg, err := member.Expect(smodels.TypeNetworkMembers)
+	if err != nil {
+		t.Fatalf("member list: %v", err)
+	}
+	var members smodels.NetworkMembersNotification
+	if err := json.Unmarshal(msg.Payload, &members); err != nil {
+		t.Fatalf("decode member list: %v", err)
+	}
+
+	member.Close()
+	if _, err := owner.Expect(smodels.TypeComputerDisconnected); err != nil {
+		t.Fatalf("owner not told about the disconnect: %v", err)
+	}
+	return members.LastSequence
+}
+
+func TestConnectReplaysMissedEvents(t *testing.T) {
+	server := startTestServer(t, nil)
+	owner := dial(t, server)
+	member := dial(t, server)
+	networkID := createNetwork(t, owner, "office")
+	lastSequence := joinAndLeave(t, owner, member, networkID)
+
+	other := dial(t, server)
+	if _, err := other.JoinNetwork(networkID, testPIN, "desktop"); err != nil {
+		t.Fatalf("join network: %v", err)
+	}
+
+	member = dialKey(t, server, member.privateKey)
+	if err := member.ConnectNetwork(networkID, lastSequence); err != nil {
+		t.Fatalf("connect network: %v", err)
+	}
+	msg, err := member.Expect(smodels.TypeNetworkEvents)
+	if err != nil {
+		t.Fatalf("missed events not replayed: %v", err)
+	}
+	var replay smodels.NetworkEventsNotification
+	if err := json.Unmarshal(msg.Payload, &replay); err != nil {
+		t.Fatalf("decode replay: %v", err)
+	}
+
+	// The member going away, then the other computer joining
+	if len(replay.Events) < 2 {
+		t.Fatalf("replayed %d events, want at least 2", len(replay.Events))
+	}
+	for i, event := range replay.Events {
+		if want := lastSequence + uint64(i) + 1; event.Sequence != want {
+			t.Errorf("event %d has sequence %d, want %d", i, event.Sequence, want)
+		}
+	}
+	last := replay.Events[len(replay.Events)-1]
+	var connected smodels.ComputerConnectedNotification
+	if err := json.Unmarshal(last.Payload, &connected); err != nil || last.Type != smodels.TypeComputerConnected || connected.PublicKey != other.PublicKey {
+		t.Errorf("last replayed event is %s of %q, want %s of %q (err %v)", last.Type, connected.PublicKey, smodels.TypeComputerConnected, other.PublicKey, err)
+	}
+	// The member's own connect event is not replayed to it but counts as seen
+	if replay.LastSequence != last.Sequence+1 {
+		t.Errorf("replay last sequence = %d, want %d", replay.LastSequence, last.Sequence+1)
+	}
+}
+
+func TestConnectAfterGapSendsMembers(t *testing.T) {
+	server := startTestServer(t, nil)
+	owner := dial(t, server)
+	member := dial(t, server)
+	networkID := createNetwork(t, owner, "office")
+	lastSequence := joinAndLeave(t, owner, member, networkID)
+
+	for name, seq := range map[string]uint64{
+		"dropped events":        lastSequence - eventReplaySize,
+		"sequence of a restart": lastSequence + eventReplaySize,
+	} {
+		member = dialKey(t, server, member.privateKey)
+		if err := member.ConnectNetwork(networkID, seq); err != nil {
+			t.Fatalf("%s: connect network: %v", name, err)
+		}
+		if _, err := member.Expect(smodels.TypeNetworkMembers); err != nil {
+			t.Errorf("%s: no full member list: %v", name, err)
+		}
+		member.Close()
+		if _, err := owner.Expect(smodels.TypeComputerDisconnected); err != nil {
+			t.Fatalf("%s: owner not told about the disconnect: %v", name, err)
+		}
+	}
+}
+
+func TestDuplicateSessionReplacesOldConnection(t *testing.T) {
+	server := startTestServer(t, nil)
+	first := dial(t, server)
+	networkID := createNetwork(t, first, "office")
+
+	second := dialKey(t, server, first.privateKey)
+	if _, err := first.Expect(smodels.TypeSessionReplaced); err != nil {
+		t.Fatalf("old connection not told it was replaced: %v", err)
+	}
+	if err := first.ExpectClosed(); err != nil {
+		t.Fatalf("old connection: %v", err)
+	}
+
+	if err := second.ConnectNetwork(networkID, 0); err != nil {
+		t.Fatalf("connect network: %v", err)
+	}
+	if n := connections(server, networkID); n != 1 {
+		t.Errorf("connections in network = %d, want 1", n)
+	}
+}
+
+func TestDuplicateSessionRejectsNewConnection(t *testing.T) {
+	server := startTestServer(t, func(cfg *Config) {
+		cfg.DuplicateSessionPolicy = duplicateSessionReject
+	})
+	first := dial(t, server)
+
+	if _, err := server.DialKey(first.privateKey); errorCode(err) != smodels.ErrSessionActive {
+		t.Fatalf("second connection error = %v, want %s", err, smodels.ErrSessionActive)
+	}
+	if _, err := first.CreateNetwork("office", testPIN); err != nil {
+		t.Errorf("first connection unusable after the refused one: %v", err)
+	}
+}
+
+func TestMigrateKey(t *testing.T) {
+	server := startTestServer(t, nil)
+	old := dial(t, server)
+	member := dial(t, server)
+	networkID := createNetwork(t, old, "office")
+	if _, err := member.JoinNetwork(networkID, testPIN, "laptop"); err != nil {
+		t.Fatalf("join network: %v", err)
+	}
+
+	replacement := dial(t, server)
+	resp, err := replacement.MigrateKey(old, "")
+	if err != nil {
+		t.Fatalf("migrate key: %v", err)
+	}
+	if resp.Networks != 1 || resp.Memberships != 1 {
+		t.Errorf("migrated %d networks and %d memberships, want 1 and 1", resp.Networks, resp.Memberships)
+	}
+	if err := old.ExpectClosed(); err != nil {
+		t.Errorf("connection of the old key: %v", err)
+	}
+
+	network, err := server.Store.GetNetwork(networkID)
+	if err != nil {
+		t.Fatalf("network lost in the migration: %v", err)
+	}
+	if network.OwnerPublicKey != replacement.PublicKey {
+		t.Errorf("owner = %q, want the new key %q", network.OwnerPublicKey, replacement.PublicKey)
+	}
+	if joined, _ := server.Store.IsComputerInNetwork(networkID, replacement.PublicKey); !joined {
+		t.Error("membership not moved to the new key")
+	}
+	if joined, _ := server.Store.IsComputerInNetwork(networkID, old.PublicKey); joined {
+		t.Error("old key still a member after the migration")
+	}
+}
+
+func TestMigrateKeyRejectsWrongRecoveryCode(t *testing.T) {
+	server := startTestServer(t, nil)
+	old := dial(t, server)
+	networkID := createNetwork(t, old, "office")
+	if _, err := old.CreateRecoveryCode(); err != nil {
+		t.Fatalf("create recovery code: %v", err)
+	}
+
+	replacement := dial(t, server)
+	if _, err := replacement.MigrateKey(old, "not-the-code"); errorCode(err) != smodels.ErrAuthFailed {
+		t.Fatalf("migration error = %v, want %s", err, smodels.ErrAuthFailed)
+	}
+	if network, _ := server.Store.GetNetwork(networkID); network.OwnerPublicKey != old.PublicKey {
+		t.Errorf("owner = %q after a refused migration, want %q", network.OwnerPublicKey, old.PublicKey)
+	}
+}
+
+func TestRevokedKeyRejected(t *testing.T) {
+	server := startTestServer(t, nil)
+	old := dial(t, server)
+	replacement := dial(t, server)
+	if _, err := replacement.MigrateKey(old, ""); err != nil {
+		t.Fatalf("migrate key: %v", err)
+	}
+
+	if _, err := server.DialKey(old.privateKey); errorCode(err) != smodels.ErrKeyRevoked {
+		t.Errorf("revoked key authentication error = %v, want %s", err, smodels.ErrKeyRevoked)
+	}
+
+	// The old key cannot be migrated a second time, to another key
+	other := dial(t, server)
+	if _, err := other.MigrateKey(old, ""); errorCode(err) != smodels.ErrKeyRevoked {
+		t.Errorf("second migration error = %v, want %s", err, smodels.ErrKeyRevoked)
+	}
+}
@@ -0,0 +1,205 @@
+// Package logger is the leveled, structured logger shared by the server and the client.
+// Messages carry key/value fields and go to the console, a rotating file or both.
+package logger
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// Until Init or Setup runs, messages are dropped
+	logger atomic.Pointer[zap.Logger]
+	sugar  atomic.Pointer[zap.SugaredLogger]
+	once   sync.Once
+
+	// level can be changed at runtime with SetLevel
+	level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+	// setupMu serializes Setup, which replaces the outputs and closes the previous file
+	setupMu       sync.Mutex
+	file          *rotatingFile
+	restoreStdLog = func() {}
+)
+
+func init() {
+	replace(zap.NewNop())
+}
+
+// LogLevel represents the level of logging
+type LogLevel string
+
+// Log levels
+const (
+	DebugLevel LogLevel = "debug"
+	InfoLevel  LogLevel = "info"
+	WarnLevel  LogLevel = "warn"
+	ErrorLevel LogLevel = "error"
+	FatalLevel LogLevel = "fatal"
+)
+
+// Options says where the log goes
+type Options struct {
+	Level      string    // Minimum level logged, debug when empty
+	Console    io.Writer // Colored output, usually os.Stdout or os.Stderr; nil for none
+	File       string    // Log file, rotated by size; empty for none
+	MaxSize    int64     // Size in bytes that rotates the file, DefaultMaxSize when 0
+	MaxBackups int       // Rotated files kept next to the log, DefaultMaxBackups when 0
+	StdLog     bool      // Also take what other packages write with the standard log, at debug level
+}
+
+// encoderConfig returns the encoding shared by the outputs; only the console gets colors
+func encoderConfig(color bool) zapcore.EncoderConfig {
+	encodeLevel := zapcore.LowercaseLevelEncoder
+	if color {
+		encodeLevel = zapcore.LowercaseColorLevelEncoder
+	}
+	return zapcore.EncoderConfig{
+		TimeKey:        "timestamp",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "message",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    encodeLevel,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.MillisDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+// Init initializes the logger with all levels to the console, unless Setup already ran
+func Init() {
+	once.Do(func() {
+		setup(Options{Console: os.Stdout})
+
+		// Log initialization
+		Info("Logger initialized (all levels to console)",
+			"time", time.Now().Format(time.RFC3339),
+		)
+	})
+}
+
+// Setup replaces the outputs of the logger. It can run again once the options are known,
+// as when the log file lives in a directory read from the configuration; an error opening
+// the file leaves the other outputs working.
+func Setup(opts Options) error {
+	once.Do(func() {})
+	return setup(opts)
+}
+
+// setup builds the logger for the options and puts it in use
+func setup(opts Options) error {
+	setupMu.Lock()
+	defer setupMu.Unlock()
+
+	var err error
+	if opts.Level != "" {
+		err = SetLevel(opts.Level)
+	}
+
+	var cores []zapcore.Core
+	if opts.Console != nil {
+		cores = append(cores, zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig(true)), zapcore.AddSync(opts.Console), level))
+	}
+	previousFile := file
+	file = nil
+	if opts.File != "" {
+		f, openErr := openRotatingFile(opts.File, opts.MaxSize, opts.MaxBackups)
+		if openErr != nil {
+			err = openErr
+		} else {
+			file = f
+			cores = append(cores, zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig(false)), f, level))
+		}
+	}
+
+	l := zap.New(zapcore.NewTee(cores...), zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zapcore.ErrorLevel))
+	previous := replace(l)
+	previous.Sync()
+	if previousFile != nil {
+		previousFile.Close()
+	}
+
+	restoreStdLog()
+	restoreStdLog = func() {}
+	if opts.StdLog {
+		if restore, redirectErr := zap.RedirectStdLogAt(l.WithOptions(zap.AddCallerSkip(-1)), zapcore.DebugLevel); redirectErr == nil {
+			restoreStdLog = restore
+		}
+	}
+	return err
+}
+
+// replace makes l the logger in use and returns the previous one
+func replace(l *zap.Logger) *zap.Logger {
+	previous := logger.Swap(l)
+	sugar.Store(l.Sugar())
+	if previous == nil {
+		return zap.NewNop()
+	}
+	return previous
+}
+
+// SetLevel changes the minimum level that is logged (debug, info, warn, error, fatal)
+func SetLevel(lvl string) error {
+	parsed, err := zapcore.ParseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(parsed)
+	return nil
+}
+
+// Level returns the minimum level that is logged
+func Level() string {
+	return level.Level().String()
+}
+
+// Debug logs a message at debug level with structured fields
+func Debug(msg string, fields ...interface{}) {
+	sugar.Load().Debugw(msg, fields...)
+}
+
+// Info logs a message at info level with structured fields
+func Info(msg string, fields ...interface{}) {
+	sugar.Load().Infow(msg, fields...)
+}
+
+// Warn logs a message at warn level with structured fields
+func Warn(msg string, fields ...interface{}) {
+	sugar.Load().Warnw(msg, fields...)
+}
+
+// Error logs a message at error level with structured fields
+func Error(msg string, fields ...interface{}) {
+	sugar.Load().Errorw(msg, fields...)
+}
+
+// Fatal logs a message at fatal level with structured fields and exits
+func Fatal(msg string, fields ...interface{}) {
+	sugar.Load().Fatalw(msg, fields...)
+}
+
+// Sync flushes any buffered log entries
+func Sync() error {
+	return logger.Load().Sync()
+}
+
+// GetLogger returns the underlying zap logger
+func GetLogger() *zap.Logger {
+	return logger.Load()
+}
+
+// GetSugaredLogger returns the underlying sugared zap logger
+func GetSugaredLogger() *zap.SugaredLogger {
+	return sugar.Load()
+}
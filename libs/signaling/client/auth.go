@@ -0,0 +1,104 @@
+package client
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+	signaling_models "github.com/itxtoledo/govpn/libs/signaling/models"
+	"github.com/itxtoledo/govpn/libs/utils"
+)
+
+// authTimeout bounds the challenge exchange that follows the WebSocket handshake
+const authTimeout = 10 * time.Second
+
+// SetPrivateKey sets the key used to answer the server authentication challenge.
+// It must match PublicKeyStr; without it the connection stays unauthenticated.
+func (s *SignalingClient) SetPrivateKey(key ed25519.PrivateKey) {
+	s.privateKey = key
+}
+
+// authenticate answers the challenge the server sends right after the handshake.
+// Messages that arrive before the result are passed to the MessageHandler.
+func (s *SignalingClient) authenticate(conn *websocket.Conn) error {
+	conn.SetReadDeadline(time.Now().Add(authTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var msg signaling_models.SignalingMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		return fmt.Errorf("error reading authentication challenge: %v", err)
+	}
+	if msg.Type != signaling_models.TypeAuthChallenge {
+		return fmt.Errorf("expected authentication challenge, got %s", msg.Type)
+	}
+
+	var challenge signaling_models.AuthChallenge
+	if err := json.Unmarshal(msg.Payload, &challenge); err != nil {
+		return fmt.Errorf("invalid authentication challenge: %v", err)
+	}
+
+	if s.privateKey == nil {
+		if challenge.Required {
+			return errors.New("server requires authentication but no private key is set")
+		}
+		log.Printf("No private key set, continuing without authentication")
+		return nil
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(challenge.Nonce)
+	if err != nil {
+		return fmt.Errorf("invalid authentication nonce: %v", err)
+	}
+
+	payload, err := json.Marshal(signaling_models.AuthResponseRequest{
+		BaseRequest: signaling_models.BaseRequest{PublicKey: s.PublicKeyStr},
+		Signature:   base64.StdEncoding.EncodeToString(ed25519.Sign(s.privateKey, signaling_models.AuthChallengeMessage(nonce))),
+	})
+	if err != nil {
+		return fmt.Errorf("error serializing authentication response: %v", err)
+	}
+
+	messageID, err := utils.GenerateMessageID()
+	if err != nil {
+		return fmt.Errorf("error generating message ID: %v", err)
+	}
+
+	if err := conn.WriteJSON(signaling_models.SignalingMessage{
+		ID:      messageID,
+		Type:    signaling_models.TypeAuthResponse,
+		Payload: payload,
+	}); err != nil {
+		return fmt.Errorf("error sending authentication response: %v", err)
+	}
+
+	for {
+		var reply signaling_models.SignalingMessage
+		if err := conn.ReadJSON(&reply); err != nil {
+			return fmt.Errorf("error reading authentication result: %v", err)
+		}
+
+		if reply.ID != messageID {
+			s.handleUnsolicited(reply)
+			continue
+		}
+
+		switch reply.Type {
+		case signaling_models.TypeAuthResult:
+			log.Printf("Authenticated with signaling server")
+			return nil
+		case signaling_models.TypeError:
+			var errorPayload signaling_models.ErrorResponse
+			if err := json.Unmarshal(reply.Payload, &errorPayload); err == nil && errorPayload.Error != "" {
+				return &ServerError{Code: errorPayload.Code, Message: errorPayload.Error, Fields: errorPayload.Fields}
+			}
+			return errors.New("unknown server error")
+		default:
+			return fmt.Errorf("unexpected authentication reply: %s", reply.Type)
+		}
+	}
+}
@@ -1,13 +1,19 @@
 package client
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"net/url"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -24,43 +30,128 @@ type SignalingMessageHandler func(messageType signaling_models.MessageType, payl
 
 // SignalingClient representa uma conexão com o servidor de sinalização
 type SignalingClient struct {
-	Conn           *websocket.Conn
 	ServerAddress  string
-	Connected      bool
-	LastHeartbeat  time.Time
 	MessageHandler SignalingMessageHandler
 	PublicKeyStr   string // Public key string to identify this client
+	privateKey     ed25519.PrivateKey
+
+	// Pins restricts a wss:// server to certificates matching one of them, see tls.go;
+	// empty trusts any certificate the system accepts
+	Pins []Pin
+
+	// RetryPolicy governs dialing in Connect and resending create, join and connect requests
+	// that time out
+	RetryPolicy RetryPolicy
+
+	// Set by the options of NewSignalingClient
+	timeout         time.Duration
+	dialer          *websocket.Dialer
+	headers         http.Header
+	reconnectPolicy *ReconnectPolicy
+
+	heartbeatInterval  time.Duration
+	heartbeatMaxMissed int
+
+	// Reconnection in progress, and whether the server replaced this session
+	reconnectStop   chan struct{}
+	reconnectLock   sync.Mutex
+	sessionReplaced atomic.Bool
+
+	// DisconnectHandler is called with the read error when the connection drops by itself,
+	// not after Disconnect, so the caller can reconnect or, with WithReconnect, show that
+	// the client is reconnecting
+	DisconnectHandler func(err error)
+
+	// With WithReconnect, OnReconnecting is called before each attempt, OnReconnected once
+	// connected again, to connect to the networks again, and OnReconnectFailed when the
+	// policy ran out of attempts. See reconnect.go.
+	OnReconnecting    func(attempt int, delay time.Duration)
+	OnReconnected     func()
+	OnReconnectFailed func(err error)
+
+	// Current connection and when the server last answered a heartbeat. Connect, Disconnect,
+	// the read loop and the heartbeat change them from different goroutines, so they are
+	// only read and written under connLock.
+	conn          *websocket.Conn
+	connected     bool
+	lastHeartbeat time.Time
+	disconnects   uint64 // Disconnect calls, so a connect in progress can tell it lost
+	connLock      sync.Mutex
+
+	// Held by connect from the first dial until the connection is installed, so the caller
+	// and the reconnection never connect at the same time
+	connectLock sync.Mutex
+
+	// Writer goroutine of the current connection, the only one allowed to write to conn
+	writer     *writer
+	writerLock sync.Mutex
 
 	// System to track pending requests by message ID
 	pendingRequests     map[string]chan signaling_models.SignalingMessage
 	pendingRequestsLock sync.Mutex
+
+	// Latest membership event sequence seen per network, sent when reconnecting
+	lastSequences map[string]uint64
+	sequencesLock sync.Mutex
+
+	// What the server advertised right after connecting, and the address it saw
+	capabilities     *signaling_models.ServerCapabilitiesNotification
+	clientIP         *signaling_models.ClientIPInfoNotification
+	capabilitiesLock sync.Mutex
 }
 
 // NewSignalingClient cria uma nova instância do servidor de sinalização
-func NewSignalingClient(publicKey string, handler SignalingMessageHandler) *SignalingClient {
-	return &SignalingClient{
-		Connected:       false,
-		LastHeartbeat:   time.Now(),
+func NewSignalingClient(publicKey string, handler SignalingMessageHandler, opts ...Option) *SignalingClient {
+	s := &SignalingClient{
+		lastHeartbeat:   time.Now(),
 		PublicKeyStr:    publicKey,
 		MessageHandler:  handler, // Assign the passed handler
 		pendingRequests: make(map[string]chan signaling_models.SignalingMessage),
+		lastSequences:   make(map[string]uint64),
+		RetryPolicy:     DefaultRetryPolicy,
+		timeout:         DefaultTimeout,
+		dialer:          websocket.DefaultDialer,
+
+		heartbeatInterval:  DefaultHeartbeatInterval,
+		heartbeatMaxMissed: DefaultHeartbeatMaxMissed,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// Connect conecta ao servidor de sinalização
+// Connect conecta ao servidor de sinalização. Retorna ErrDisconnected se Disconnect for
+// chamado enquanto ele ainda está conectando.
 func (s *SignalingClient) Connect(serverAddress string) error {
-	if s.Connected {
-		// Já está conectado
-		return nil
-	}
+	_, err := s.connect(serverAddress, nil)
+	return err
+}
 
+// connect faz o trabalho de Connect e retorna a conexão que abriu, ou nil se o cliente já
+// estava conectado. Uma chamada de cada vez:
+// a conexão só é instalada se Disconnect não foi chamado desde o início e, quando a chamada
+// vem da reconexão que é dona de stop, se ela não foi cancelada.
+func (s *SignalingClient) connect(serverAddress string, stop chan struct{}) (*websocket.Conn, error) {
+	s.connectLock.Lock()
+	defer s.connectLock.Unlock()
+
+	s.connLock.Lock()
+	if s.connected && s.conn != nil {
+		// Já está conectado, por outra chamada
+		s.connLock.Unlock()
+		return nil, nil
+	}
 	s.ServerAddress = serverAddress
+	disconnects := s.disconnects
+	s.connLock.Unlock()
+	s.sessionReplaced.Store(false)
 
 	// Criar URL para conexão WebSocket
 	u, err := url.Parse(serverAddress)
 	if err != nil {
 		log.Printf("Error parsing server address: %v", err)
-		return err
+		return nil, err
 	}
 
 	// Ensure path is set to /ws
@@ -71,8 +162,11 @@ func (s *SignalingClient) Connect(serverAddress string) error {
 	log.Printf("Conectando ao servidor de sinalização")
 	log.Printf("Connecting to WebSocket server at %s", u.String())
 
-	// Configurar headers para o handshake inicial
-	headers := make(map[string][]string)
+	// Configurar headers para o handshake inicial, começando pelos de WithHeaders
+	headers := s.headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
 	headers["Computer-Agent"] = []string{"goVPN-Client/1.0"}
 
 	// Adicionar identificador do cliente usando a chave pública armazenada diretamente
@@ -80,348 +174,297 @@ func (s *SignalingClient) Connect(serverAddress string) error {
 		headers["X-Client-ID"] = []string{s.PublicKeyStr}
 	}
 
-	// Estabelecer conexão com o servidor WebSocket com retry
+	// Estabelecer conexão com o servidor WebSocket com retry. O dialer é uma cópia, já que o
+	// padrão é compartilhado, para receber a configuração TLS deste cliente.
 	var conn *websocket.Conn
-	dialer := websocket.DefaultDialer
-	dialer.HandshakeTimeout = 10 * time.Second
+	dialer := *s.dialer
+	if dialer.HandshakeTimeout == 0 {
+		dialer.HandshakeTimeout = DefaultTimeout
+	}
+	if len(s.Pins) > 0 {
+		if u.Scheme != "wss" {
+			return nil, ErrPinsNeedTLS
+		}
+		dialer.TLSClientConfig = pinnedTLSConfig(u.Hostname(), s.Pins)
+	}
 
-	// Try to connect up to 3 times
-	for attempts := 0; attempts < 3; attempts++ {
+	// Try to connect as many times as the retry policy allows
+	attempts := max(s.RetryPolicy.Attempts, 1)
+	backoff := s.RetryPolicy.Backoff
+	for attempt := 1; attempt <= attempts; attempt++ {
 		conn, _, err = dialer.Dial(u.String(), headers)
 		if err == nil {
 			break // Conexão bem-sucedida
 		}
 
-		log.Printf("Connection attempt %d failed: %v", attempts+1, err)
+		log.Printf("Connection attempt %d failed: %v", attempt, err)
+		if s.connectCancelled(disconnects, stop) {
+			return nil, ErrDisconnected
+		}
 
-		if attempts < 2 {
-			log.Printf("Retrying connection in 1 second...")
-			time.Sleep(1 * time.Second)
+		if attempt < attempts {
+			log.Printf("Retrying connection in %s...", backoff)
+			time.Sleep(backoff)
+			backoff *= 2
 		}
 	}
 
 	if err != nil {
-		log.Printf("Failed to connect after 3 attempts: %v", err)
-		return err
+		log.Printf("Failed to connect after %d attempts: %v", attempts, err)
+		return nil, err
 	}
 
-	s.Conn = conn
+	// Provar a posse da chave antes de liberar a conexão
+	if err := s.authenticate(conn); err != nil {
+		log.Printf("Authentication failed: %v", err)
+		conn.Close()
+		return nil, err
+	}
 
-	// Configurar handler para mensagens recebidas
-	go s.listenForMessages()
+	// Marcar como conectado antes de iniciar o listener, que para quando a conexão deixa de
+	// ser a atual. Um Disconnect durante a discagem vence: a conexão nova é descartada.
+	s.connLock.Lock()
+	if s.connectCancelledLocked(disconnects, stop) {
+		s.connLock.Unlock()
+		log.Printf("Disconnected while connecting, closing the new connection")
+		conn.Close()
+		return nil, ErrDisconnected
+	}
+	w := newWriter(conn)
+	s.conn = conn
+	s.connected = true
+	s.lastHeartbeat = time.Now()
+	s.writerLock.Lock()
+	s.writer = w
+	s.writerLock.Unlock()
+	s.connLock.Unlock()
 
-	// Marcar como conectado
-	s.Connected = true
-	s.LastHeartbeat = time.Now()
+	// Configurar handler para mensagens recebidas
+	go s.listenForMessages(conn)
 
 	// Verificar a conexão com um ping inicial
 	err = s.sendPing()
 	if err != nil {
 		log.Printf("Initial ping failed: %v", err)
-		s.Disconnect()
-		return fmt.Errorf("connected, but initial ping failed: %v", err)
+		s.closeConn(conn)
+		return nil, fmt.Errorf("connected, but initial ping failed: %v", err)
 	}
+	go s.heartbeat(conn, w)
 
 	log.Printf("Successfully connected to signaling server")
-	return nil
+	return conn, nil
 }
 
-// Disconnect desconecta do servidor de sinalização
-func (s *SignalingClient) Disconnect() error {
-	if !s.Connected {
-		// Já está desconectado
-		return nil
-	}
+// connectCancelled reports whether Disconnect was called since connect read disconnects
+// or the reconnection that owns stop was cancelled
+func (s *SignalingClient) connectCancelled(disconnects uint64, stop chan struct{}) bool {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+	return s.connectCancelledLocked(disconnects, stop)
+}
 
-	// Fechar a conexão se existir
-	if s.Conn != nil {
-		err := s.Conn.Close()
-		if err != nil {
-			return err
-		}
-		s.Conn = nil
+// connectCancelledLocked is connectCancelled for callers holding connLock
+func (s *SignalingClient) connectCancelledLocked(disconnects uint64, stop chan struct{}) bool {
+	if s.disconnects != disconnects {
+		return true
+	}
+	if stop == nil {
+		return false
 	}
 
-	// Marcar como desconectado
-	s.Connected = false
-
-	return nil
+	s.reconnectLock.Lock()
+	defer s.reconnectLock.Unlock()
+	return s.reconnectStop != stop
 }
 
-// sendPackagedMessage packages and sends a message to the backend and waits for a response.
-// Creates a BaseRequest with the client's public key,
-// generates a message ID, packages it into the SignalingMessage struct, and sends it via WebSocket.
-func (s *SignalingClient) sendPackagedMessage(msgType signaling_models.MessageType, payload interface{}) (interface{}, error) {
-	if !s.Connected || s.Conn == nil {
-		return nil, errors.New("not connected to server")
-	}
+// Disconnect desconecta do servidor de sinalização. Um Connect ou uma reconexão em
+// andamento desistem em vez de instalar a conexão que estão abrindo.
+func (s *SignalingClient) Disconnect() error {
+	s.StopReconnect()
 
-	// Automatically inject public key into BaseRequest if available
-	if s.injectPublicKey(payload) {
-		log.Printf("Automatically injected public key into payload")
+	s.connLock.Lock()
+	s.disconnects++
+	conn := s.conn
+	s.connLock.Unlock()
+	if conn == nil {
+		// Já está desconectado
+		return nil
 	}
+	return s.closeConn(conn)
+}
 
-	// Gerar ID da mensagem
-	messageID, err := utils.GenerateMessageID()
-	if err != nil {
-		return nil, fmt.Errorf("error generating message ID: %v", err)
+// closeConn closes conn if it is still the current connection, sending what is queued and
+// a close frame first
+func (s *SignalingClient) closeConn(conn *websocket.Conn) error {
+	// Marcar como desconectado antes de fechar, para o listener não tratar o fechamento
+	// como uma queda da conexão
+	w, ok := s.detach(conn)
+	if !ok {
+		// O listener tratou uma queda nesse meio tempo
+		return nil
 	}
 
-	// Register this message ID to track the response
-	responseChan := s.registerPendingRequest(messageID)
-
-	// Serializar payload para JSON
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("error serializing payload: %v", err)
+	// Enviar o que ainda está na fila e o aviso de fechamento antes de fechar
+	if w != nil {
+		w.closeAndWait()
 	}
+	return conn.Close()
+}
 
-	// Criar SignalingMessage
-	message := signaling_models.SignalingMessage{
-		ID:      messageID,
-		Type:    msgType,
-		Payload: payloadBytes,
+// detach marks the client as disconnected and takes the writer, if conn is still the
+// current connection. Only the caller that gets true closes it.
+func (s *SignalingClient) detach(conn *websocket.Conn) (*writer, bool) {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+
+	if !s.connected || s.conn != conn {
+		return nil, false
 	}
+	s.connected = false
+	s.conn = nil
+	return s.takeWriter(), true
+}
 
-	// Enviar a mensagem para o servidor
-	log.Printf("Sending message of type %s with ID %s", msgType, messageID)
-	err = s.Conn.WriteJSON(message)
+// sendPackagedMessage sends a request whose response has no structure of its own and
+// returns the response payload decoded as a generic map. Typed requests use Do.
+func (s *SignalingClient) sendPackagedMessage(msgType signaling_models.MessageType, payload interface{}) (interface{}, error) {
+	response, err := s.roundTrip(context.Background(), msgType, payload)
 	if err != nil {
-		return nil, fmt.Errorf("error sending message: %v", err)
+		return nil, err
 	}
 
-	// Wait for response with timeout
-	select {
-	case response := <-responseChan:
-		log.Printf("Received response for message ID %s of type %s", messageID, response.Type)
-
-		// Check if response is an error
-		if response.Type == signaling_models.TypeError {
-			var errorPayload map[string]string
-			if err := json.Unmarshal(response.Payload, &errorPayload); err == nil {
-				if errorMsg, ok := errorPayload["error"]; ok {
-					return nil, fmt.Errorf("server error: %s", errorMsg)
-				}
-			}
-			return nil, errors.New("unknown server error")
-		}
-
-		// Parse the response payload based on the request type
-		parsedResponse, err := s.parseResponse(msgType, response)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing response: %v", err)
+	var genericResponse map[string]interface{}
+	if len(response.Payload) > 0 {
+		if err := json.Unmarshal(response.Payload, &genericResponse); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s response: %v", response.Type, err)
 		}
-		return parsedResponse, nil
-
-	case <-time.After(10 * time.Second):
-		return nil, fmt.Errorf("timeout waiting for response to message ID %s", messageID)
 	}
+	return genericResponse, nil
 }
 
-// parseResponse parses the response payload based on the request type
-func (s *SignalingClient) parseResponse(requestType signaling_models.MessageType, response signaling_models.SignalingMessage) (interface{}, error) {
-	switch requestType {
-	case signaling_models.TypeCreateNetwork:
-		if response.Type == signaling_models.TypeNetworkCreated {
-			var resp signaling_models.CreateNetworkResponse
-			if err := json.Unmarshal(response.Payload, &resp); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal create network response: %v", err)
-			}
-			return resp, nil
-		}
-
-	case signaling_models.TypeJoinNetwork:
-		if response.Type == signaling_models.TypeNetworkJoined {
-			var resp signaling_models.JoinNetworkResponse
-			if err := json.Unmarshal(response.Payload, &resp); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal join network response: %v", err)
-			}
-			return resp, nil
-		}
-
-	case signaling_models.TypeConnectNetwork:
-		if response.Type == signaling_models.TypeNetworkConnected {
-			var resp signaling_models.ConnectNetworkResponse
-			if err := json.Unmarshal(response.Payload, &resp); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal connect network response: %v", err)
-			}
-			return resp, nil
-		}
-
-	case signaling_models.TypeDisconnectNetwork:
-		if response.Type == signaling_models.TypeDisconnectNetwork || response.Type == signaling_models.TypeNetworkDisconnected {
-			var resp signaling_models.DisconnectNetworkResponse
-			if err := json.Unmarshal(response.Payload, &resp); err != nil {
-				// If it fails, it might be because the server is using a different format
-				// Try to extract just the networkID
-				var networkData map[string]interface{}
-				if jsonErr := json.Unmarshal(response.Payload, &networkData); jsonErr != nil {
-					return nil, fmt.Errorf("failed to unmarshal disconnect network response: %v", err)
-				}
-
-				// Extract network ID from the map and return it
-				if networkID, ok := networkData["network_id"].(string); ok {
-					return map[string]interface{}{
-						"network_id": networkID,
-					}, nil
-				}
-				return nil, fmt.Errorf("failed to find network_id in response: %v", err)
-			}
-			return resp, nil
-		}
-
-	case signaling_models.TypeLeaveNetwork:
-		if response.Type == signaling_models.TypeLeaveNetwork {
-			var resp signaling_models.LeaveNetworkResponse
-			if err := json.Unmarshal(response.Payload, &resp); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal leave network response: %v", err)
-			}
-			return resp, nil
-		}
-
-	case signaling_models.TypeKick:
-		if response.Type == signaling_models.TypeKickResponse {
-			var resp signaling_models.KickResponse
-			if err := json.Unmarshal(response.Payload, &resp); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal kick response: %v", err)
-			}
-			return resp, nil
-		}
-
-	case signaling_models.TypeRename:
-		if response.Type == signaling_models.TypeRenameResponse {
-			var resp signaling_models.RenameResponse
-			if err := json.Unmarshal(response.Payload, &resp); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal rename response: %v", err)
-			}
-			return resp, nil
-		}
-
-	case signaling_models.TypeGetComputerNetworks:
-		if response.Type == signaling_models.TypeComputerNetworks {
-			var resp signaling_models.ComputerNetworksResponse
-			if err := json.Unmarshal(response.Payload, &resp); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal computer networks response: %v", err)
-			}
-			return resp, nil
-		}
+// ServerError is returned when the server answers a request with a TypeError message.
+// Use Code to branch on the kind of failure instead of matching the message text.
+type ServerError struct {
+	Code    signaling_models.ErrorCode
+	Message string
+	Fields  []signaling_models.FieldError
+}
 
-	case signaling_models.TypePing:
-		// For ping, we just return a simple success message
-		return map[string]interface{}{"status": "success"}, nil
+// Error implements the error interface
+func (e *ServerError) Error() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("server error: %s", e.Message)
+	}
 
-	case signaling_models.TypeUpdateClientInfo:
-		// The server responds with TypeUpdateClientInfoSuccess after updating client info
-		if response.Type == signaling_models.TypeUpdateClientInfoResponse {
-			var resp signaling_models.UpdateClientInfoResponse
-			if err := json.Unmarshal(response.Payload, &resp); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal update client info success response: %v", err)
-			}
-			return resp, nil
-		}
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, fmt.Sprintf("%s %s", f.Field, f.Message))
 	}
+	return fmt.Sprintf("server error: %s: %s", e.Message, strings.Join(parts, "; "))
+}
 
-	// If we can't determine the response type, return the raw payload as a map
-	var genericResponse map[string]interface{}
-	if err := json.Unmarshal(response.Payload, &genericResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal generic response: %v", err)
+// Unwrap returns the error of errors.go matching Code, or nil
+func (e *ServerError) Unwrap() error {
+	return codeErrors[e.Code]
+}
+
+// ErrorCode returns the server error code carried by err, or an empty code if err is not a ServerError
+func ErrorCode(err error) signaling_models.ErrorCode {
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.Code
 	}
-	return genericResponse, nil
+	return ""
 }
 
 // CreateNetwork cria uma nova sala no servidor
 func (s *SignalingClient) CreateNetwork(name string, pin string, computerName string) (*signaling_models.CreateNetworkResponse, error) {
-	if !s.Connected || s.Conn == nil {
-		return nil, errors.New("not connected to server")
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
 	}
 
 	log.Printf("Creating network: %s", name)
 
 	// Criar payload para a requisição
 	payload := &signaling_models.CreateNetworkRequest{
-		BaseRequest:  signaling_models.BaseRequest{},
-		NetworkName:  name,
-		PIN:          pin,
-		ComputerName: computerName,
+		BaseRequest:    signaling_models.BaseRequest{},
+		NetworkName:    name,
+		PIN:            pin,
+		ComputerName:   computerName,
+		IdempotencyKey: newIdempotencyKey(),
 	}
 
-	// Enviar solicitação de criação de sala usando a função de empacotamento
-	response, err := s.sendPackagedMessage(signaling_models.TypeCreateNetwork, payload)
-	if err != nil {
-		return nil, err
+	// Enviar solicitação de criação de sala
+	return doWithRetry[*signaling_models.CreateNetworkRequest, signaling_models.CreateNetworkResponse](context.Background(), s, signaling_models.TypeCreateNetwork, payload)
+}
+
+// CreatePublicNetwork creates a network that is listed by ListPublicNetworks
+func (s *SignalingClient) CreatePublicNetwork(name string, pin string, computerName string, tags []string) (*signaling_models.CreateNetworkResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
 	}
 
-	// Convert the response to the expected type
-	if resp, ok := response.(signaling_models.CreateNetworkResponse); ok {
-		return &resp, nil
+	log.Printf("Creating public network: %s", name)
+
+	payload := &signaling_models.CreateNetworkRequest{
+		BaseRequest:    signaling_models.BaseRequest{},
+		NetworkName:    name,
+		PIN:            pin,
+		ComputerName:   computerName,
+		IdempotencyKey: newIdempotencyKey(),
+		Public:         true,
+		Tags:           tags,
 	}
 
-	return nil, errors.New("unexpected response type")
+	return doWithRetry[*signaling_models.CreateNetworkRequest, signaling_models.CreateNetworkResponse](context.Background(), s, signaling_models.TypeCreateNetwork, payload)
 }
 
 // JoinNetwork entra em uma sala
 func (s *SignalingClient) JoinNetwork(networkID string, pin string, computername string) (*signaling_models.JoinNetworkResponse, error) {
-	if !s.Connected || s.Conn == nil {
-		return nil, errors.New("not connected to server")
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
 	}
 
 	log.Printf("Joining network: %s", networkID)
 
 	// Criar payload para join network
 	payload := &signaling_models.JoinNetworkRequest{
-		BaseRequest:  signaling_models.BaseRequest{},
-		NetworkID:    networkID,
-		PIN:          pin,
-		ComputerName: computername,
-	}
-
-	// Enviar solicitação para entrar na sala usando a função de empacotamento
-	response, err := s.sendPackagedMessage(signaling_models.TypeJoinNetwork, payload)
-	if err != nil {
-		return nil, err
-	}
-
-	// Convert the response to the expected type
-	if resp, ok := response.(signaling_models.JoinNetworkResponse); ok {
-		return &resp, nil
+		BaseRequest:    signaling_models.BaseRequest{},
+		NetworkID:      networkID,
+		PIN:            pin,
+		ComputerName:   computername,
+		IdempotencyKey: newIdempotencyKey(),
 	}
 
-	return nil, errors.New("unexpected response type")
+	// Enviar solicitação para entrar na sala
+	return doWithRetry[*signaling_models.JoinNetworkRequest, signaling_models.JoinNetworkResponse](context.Background(), s, signaling_models.TypeJoinNetwork, payload)
 }
 
 // ConnectNetwork conecta a uma sala previamente associada
 func (s *SignalingClient) ConnectNetwork(networkID string, computerName string) (*signaling_models.ConnectNetworkResponse, error) {
-	if !s.Connected || s.Conn == nil {
-		return nil, errors.New("not connected to server")
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
 	}
 
 	log.Printf("Connecting to network: %s", networkID)
 
 	// Criar payload para connect network
 	payload := &signaling_models.ConnectNetworkRequest{
-		BaseRequest:  signaling_models.BaseRequest{},
-		NetworkID:    networkID,
-		ComputerName: computerName,
-	}
-
-	// Enviar solicitação para conectar à sala usando a função de empacotamento
-	response, err := s.sendPackagedMessage(signaling_models.TypeConnectNetwork, payload)
-	if err != nil {
-		return nil, err
-	}
-
-	// Convert the response to the expected type
-	if resp, ok := response.(signaling_models.ConnectNetworkResponse); ok {
-		return &resp, nil
+		BaseRequest:    signaling_models.BaseRequest{},
+		NetworkID:      networkID,
+		ComputerName:   computerName,
+		LastSequence:   s.LastSequence(networkID),
+		IdempotencyKey: newIdempotencyKey(),
 	}
 
-	return nil, errors.New("unexpected response type")
+	// Enviar solicitação para conectar à sala
+	return doWithRetry[*signaling_models.ConnectNetworkRequest, signaling_models.ConnectNetworkResponse](context.Background(), s, signaling_models.TypeConnectNetwork, payload)
 }
 
 // DisconnectNetwork desconecta de uma sala sem sair dela
 func (s *SignalingClient) DisconnectNetwork(networkID string) (*signaling_models.DisconnectNetworkResponse, error) {
-	if !s.Connected || s.Conn == nil {
-		return nil, errors.New("not connected to server")
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
 	}
 
 	log.Printf("Disconnecting from network: %s", networkID)
@@ -432,34 +475,14 @@ func (s *SignalingClient) DisconnectNetwork(networkID string) (*signaling_models
 		NetworkID:   networkID,
 	}
 
-	// Enviar solicitação para desconectar da sala usando a função de empacotamento
-	response, err := s.sendPackagedMessage(signaling_models.TypeDisconnectNetwork, payload)
-	if err != nil {
-		return nil, err
-	}
-
-	// Convert the response to the expected type
-	// Check if we got a map response (like from TypeNetworkDisconnected)
-	if respMap, ok := response.(map[string]interface{}); ok {
-		// Extract network ID from the map
-		networkID, _ := respMap["network_id"].(string)
-		resp := signaling_models.DisconnectNetworkResponse{
-			NetworkID: networkID,
-		}
-		return &resp, nil
-	} else if resp, ok := response.(signaling_models.DisconnectNetworkResponse); ok {
-		return &resp, nil
-	}
-
-	// For debugging the response type
-	log.Printf("Unexpected response type: %T", response)
-	return nil, errors.New("unexpected response type")
+	// Enviar solicitação para desconectar da sala
+	return Do[*signaling_models.DisconnectNetworkRequest, signaling_models.DisconnectNetworkResponse](context.Background(), s, signaling_models.TypeDisconnectNetwork, payload)
 }
 
 // LeaveNetwork sai de uma sala
 func (s *SignalingClient) LeaveNetwork(networkID string) (*signaling_models.LeaveNetworkResponse, error) {
-	if !s.Connected || s.Conn == nil {
-		return nil, errors.New("not connected to server")
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
 	}
 
 	log.Printf("Leaving network: %s", networkID)
@@ -470,24 +493,14 @@ func (s *SignalingClient) LeaveNetwork(networkID string) (*signaling_models.Leav
 		NetworkID:   networkID,
 	}
 
-	// Enviar solicitação para sair da sala usando a função de empacotamento
-	response, err := s.sendPackagedMessage(signaling_models.TypeLeaveNetwork, payload)
-	if err != nil {
-		return nil, err
-	}
-
-	// Convert the response to the expected type
-	if resp, ok := response.(signaling_models.LeaveNetworkResponse); ok {
-		return &resp, nil
-	}
-
-	return nil, errors.New("unexpected response type")
+	// Enviar solicitação para sair da sala
+	return Do[*signaling_models.LeaveNetworkRequest, signaling_models.LeaveNetworkResponse](context.Background(), s, signaling_models.TypeLeaveNetwork, payload)
 }
 
 // RenameNetwork renomeia uma sala (apenas o proprietário pode fazer isso)
 func (s *SignalingClient) RenameNetwork(networkID string, newName string) (*signaling_models.RenameResponse, error) {
-	if !s.Connected || s.Conn == nil {
-		return nil, errors.New("not connected to server")
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
 	}
 
 	log.Printf("Renaming network %s to %s", networkID, newName)
@@ -499,24 +512,14 @@ func (s *SignalingClient) RenameNetwork(networkID string, newName string) (*sign
 		NetworkName: newName,
 	}
 
-	// Enviar solicitação para renomear a sala usando a função de empacotamento
-	response, err := s.sendPackagedMessage(signaling_models.TypeRename, payload)
-	if err != nil {
-		return nil, err
-	}
-
-	// Convert the response to the expected type
-	if resp, ok := response.(signaling_models.RenameResponse); ok {
-		return &resp, nil
-	}
-
-	return nil, errors.New("unexpected response type")
+	// Enviar solicitação para renomear a sala
+	return Do[*signaling_models.RenameRequest, signaling_models.RenameResponse](context.Background(), s, signaling_models.TypeRename, payload)
 }
 
 // KickComputer expulsa um usuário da sala (apenas o proprietário pode fazer isso)
 func (s *SignalingClient) KickComputer(networkID string, targetID string) (*signaling_models.KickResponse, error) {
-	if !s.Connected || s.Conn == nil {
-		return nil, errors.New("not connected to server")
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
 	}
 
 	log.Printf("Kicking computer %s from network %s", targetID, networkID)
@@ -528,51 +531,139 @@ func (s *SignalingClient) KickComputer(networkID string, targetID string) (*sign
 		TargetID:    targetID,
 	}
 
-	// Enviar solicitação para expulsar o usuário usando a função de empacotamento
-	response, err := s.sendPackagedMessage(signaling_models.TypeKick, payload)
+	// Enviar solicitação para expulsar o usuário
+	return Do[*signaling_models.KickRequest, signaling_models.KickResponse](context.Background(), s, signaling_models.TypeKick, payload)
+}
+
+// SendMessage envia uma mensagem para o servidor
+func (s *SignalingClient) SendMessage(messageType signaling_models.MessageType, payload interface{}) (interface{}, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	// Enviar a mensagem
+	return s.sendPackagedMessage(messageType, payload)
+}
+
+// SendSignal sends a WebRTC signaling message (offer, answer or ICE candidate) to be relayed
+// to another computer. The server only answers these when relaying fails, and that error
+// reaches the message handler, so SendSignal returns as soon as the message is written.
+func (s *SignalingClient) SendSignal(messageType signaling_models.MessageType, payload interface{}) error {
+	if !s.IsConnected() {
+		return ErrNotConnected
+	}
+
+	s.injectPublicKey(payload)
+
+	messageID, err := s.writeSignal(messageType, payload)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	log.Printf("Sent signal of type %s with ID %s", messageType, messageID)
+	return nil
+}
 
-	// Convert the response to the expected type
-	if resp, ok := response.(signaling_models.KickResponse); ok {
-		return &resp, nil
+// SendRelayFrame sends an encrypted peer frame through the server to another computer of
+// the connected network, for peers that could not connect directly. Like SendSignal it
+// does not wait: a quota error reaches the message handler. Frames are not logged, they
+// carry the network traffic.
+func (s *SignalingClient) SendRelayFrame(targetPublicKey string, frame []byte) error {
+	if !s.IsConnected() {
+		return ErrNotConnected
 	}
 
-	return nil, errors.New("unexpected response type")
+	_, err := s.writeSignal(signaling_models.TypeRelayFrame, signaling_models.RelayFrame{
+		SenderPublicKey: s.PublicKeyStr,
+		TargetPublicKey: targetPublicKey,
+		Data:            frame,
+	})
+	return err
 }
 
-// SendMessage envia uma mensagem para o servidor
-func (s *SignalingClient) SendMessage(messageType signaling_models.MessageType, payload interface{}) (interface{}, error) {
-	if !s.Connected || s.Conn == nil {
-		return nil, errors.New("not connected to server")
+// writeSignal writes a message that expects no response and returns its ID
+func (s *SignalingClient) writeSignal(messageType signaling_models.MessageType, payload interface{}) (string, error) {
+	messageID, err := utils.GenerateMessageID()
+	if err != nil {
+		return "", fmt.Errorf("error generating message ID: %v", err)
 	}
 
-	// Enviar a mensagem usando a função de empacotamento
-	return s.sendPackagedMessage(messageType, payload)
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("error serializing payload: %v", err)
+	}
+
+	if err := s.send(signaling_models.SignalingMessage{
+		ID:      messageID,
+		Type:    messageType,
+		Payload: payloadBytes,
+	}); err != nil {
+		return "", fmt.Errorf("error sending message: %v", err)
+	}
+	return messageID, nil
 }
 
 // IsConnected retorna se está conectado ao servidor
 func (s *SignalingClient) IsConnected() bool {
-	return s.Connected
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+	return s.connected && s.conn != nil
+}
+
+// LastHeartbeat returns when the server last answered a heartbeat ping, or when the
+// client connected
+func (s *SignalingClient) LastHeartbeat() time.Time {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+	return s.lastHeartbeat
+}
+
+// serverAddress returns the address of the last Connect
+func (s *SignalingClient) serverAddress() string {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+	return s.ServerAddress
+}
+
+// isCurrent reports whether conn is the connection the client is using
+func (s *SignalingClient) isCurrent(conn *websocket.Conn) bool {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+	return s.connected && s.conn == conn
 }
 
-// listenForMessages recebe e processa mensagens vindas do servidor
-func (s *SignalingClient) listenForMessages() {
-	if s.Conn == nil {
-		log.Println("Cannot listen for messages: no websocket connection")
-		return
+// send hands message to the writer of the current connection and waits until it is written
+func (s *SignalingClient) send(message signaling_models.SignalingMessage) error {
+	s.writerLock.Lock()
+	w := s.writer
+	s.writerLock.Unlock()
+
+	if w == nil {
+		return ErrNotConnected
 	}
+	return w.send(message)
+}
 
+// takeWriter detaches the writer of the current connection, so nothing new is sent through it
+func (s *SignalingClient) takeWriter() *writer {
+	s.writerLock.Lock()
+	defer s.writerLock.Unlock()
+
+	w := s.writer
+	s.writer = nil
+	return w
+}
+
+// listenForMessages recebe e processa mensagens vindas da conexão conn
+func (s *SignalingClient) listenForMessages(conn *websocket.Conn) {
 	for {
-		if !s.Connected || s.Conn == nil {
+		if !s.isCurrent(conn) {
 			log.Println("listenForMessages: Connection closed, stopping message listener")
 			return
 		}
 
 		log.Println("listenForMessages: Attempting to read JSON message...")
 		var sigMsg signaling_models.SignalingMessage
-		err := s.Conn.ReadJSON(&sigMsg)
+		err := conn.ReadJSON(&sigMsg)
 		if err != nil {
 			// Check if the error is due to a closed connection
 			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) || websocket.IsUnexpectedCloseError(err) {
@@ -580,8 +671,19 @@ func (s *SignalingClient) listenForMessages() {
 			} else {
 				log.Printf("listenForMessages: Unhandled error reading JSON message: %v", err)
 			}
-			s.Connected = false
-			s.Conn = nil
+			w, dropped := s.detach(conn)
+			if dropped {
+				// Quem espera na fila do writer falha agora em vez de esperar o prazo de escrita
+				if w != nil {
+					w.close()
+				}
+				conn.Close()
+				s.failPendingRequests()
+				s.startReconnect()
+			}
+			if dropped && s.DisconnectHandler != nil {
+				s.DisconnectHandler(err)
+			}
 			return
 		}
 
@@ -598,7 +700,7 @@ func (s *SignalingClient) listenForMessages() {
 			continue
 		}
 
-		s.MessageHandler(sigMsg.Type, sigMsg.Payload)
+		s.handleUnsolicited(sigMsg)
 
 		// TODO delete the code below
 		// Handle specific message types (for notifications and unsolicited messages)
@@ -817,8 +919,8 @@ func (s *SignalingClient) listenForMessages() {
 
 // sendPing envia um ping para verificar a conexão
 func (s *SignalingClient) sendPing() error {
-	if !s.Connected || s.Conn == nil {
-		return errors.New("not connected to server")
+	if !s.IsConnected() {
+		return ErrNotConnected
 	}
 
 	log.Printf("Sending ping to server")
@@ -922,27 +1024,267 @@ func (s *SignalingClient) injectPublicKey(payload interface{}) bool {
 
 // GetComputerNetworks requests all networks the computer has joined from the server
 func (s *SignalingClient) RequestComputerNetworks() (*signaling_models.ComputerNetworksResponse, error) {
-	if !s.Connected || s.Conn == nil {
-		return nil, errors.New("not connected to server")
+	return s.RequestComputerNetworksPage("", 0, nil)
+}
+
+// RequestComputerNetworksPage requests one page of the networks this computer joined.
+// Networks whose version matches knownVersions come back with Unchanged set and no details.
+func (s *SignalingClient) RequestComputerNetworksPage(cursor string, pageSize int, knownVersions map[string]string) (*signaling_models.ComputerNetworksResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
 	}
 
 	log.Printf("Requesting computer networks from server")
 
 	// Create payload for the request
 	payload := &signaling_models.GetComputerNetworksRequest{
+		BaseRequest:   signaling_models.BaseRequest{},
+		Cursor:        cursor,
+		PageSize:      pageSize,
+		KnownVersions: knownVersions,
+	}
+
+	// Send request
+	return Do[*signaling_models.GetComputerNetworksRequest, signaling_models.ComputerNetworksResponse](context.Background(), s, signaling_models.TypeGetComputerNetworks, payload)
+}
+
+// CompleteComputerNetworks turns a first page into the full list of networks: it fetches
+// the remaining pages and fills networks marked Unchanged from known, the list the caller
+// already has. Pass a nil first page to start from scratch.
+func (s *SignalingClient) CompleteComputerNetworks(first *signaling_models.ComputerNetworksResponse, known []signaling_models.ComputerNetworkInfo) ([]signaling_models.ComputerNetworkInfo, error) {
+	knownByID := make(map[string]signaling_models.ComputerNetworkInfo, len(known))
+	knownVersions := make(map[string]string, len(known))
+	for _, network := range known {
+		knownByID[network.NetworkID] = network
+		if network.Version != "" {
+			knownVersions[network.NetworkID] = network.Version
+		}
+	}
+
+	page := first
+	if page == nil {
+		var err error
+		if page, err = s.RequestComputerNetworksPage("", 0, knownVersions); err != nil {
+			return nil, err
+		}
+	}
+
+	networks := make([]signaling_models.ComputerNetworkInfo, 0, page.Total)
+	for {
+		for _, network := range page.Networks {
+			if network.Unchanged {
+				if existing, ok := knownByID[network.NetworkID]; ok {
+					network = existing
+				}
+			}
+			networks = append(networks, network)
+		}
+
+		if page.NextCursor == "" {
+			return networks, nil
+		}
+
+		var err error
+		if page, err = s.RequestComputerNetworksPage(page.NextCursor, 0, knownVersions); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ListPublicNetworks requests the public networks from the server, optionally filtered by tag
+func (s *SignalingClient) ListPublicNetworks(tag string) (*signaling_models.PublicNetworksResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.ListPublicNetworksRequest{
 		BaseRequest: signaling_models.BaseRequest{},
+		Tag:         tag,
 	}
 
-	// Send request using the packaging function
-	response, err := s.sendPackagedMessage(signaling_models.TypeGetComputerNetworks, payload)
-	if err != nil {
-		return nil, err
+	return Do[*signaling_models.ListPublicNetworksRequest, signaling_models.PublicNetworksResponse](context.Background(), s, signaling_models.TypeListPublicNetworks, payload)
+}
+
+// KeepNetworkAlive marks an owned network as active so it is not deleted for inactivity
+func (s *SignalingClient) KeepNetworkAlive(networkID string) (*signaling_models.KeepNetworkAliveResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
 	}
 
-	// Convert the response to the expected type
-	if resp, ok := response.(signaling_models.ComputerNetworksResponse); ok {
-		return &resp, nil
+	payload := &signaling_models.KeepNetworkAliveRequest{
+		BaseRequest: signaling_models.BaseRequest{},
+		NetworkID:   networkID,
+	}
+
+	return Do[*signaling_models.KeepNetworkAliveRequest, signaling_models.KeepNetworkAliveResponse](context.Background(), s, signaling_models.TypeKeepNetworkAlive, payload)
+}
+
+// SetOwnerPolicy changes what happens to an owned network when the owner disconnects
+func (s *SignalingClient) SetOwnerPolicy(networkID string, policy signaling_models.OwnerPolicy) (*signaling_models.SetOwnerPolicyResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.SetOwnerPolicyRequest{
+		BaseRequest: signaling_models.BaseRequest{},
+		NetworkID:   networkID,
+		Policy:      policy,
+	}
+
+	return Do[*signaling_models.SetOwnerPolicyRequest, signaling_models.SetOwnerPolicyResponse](context.Background(), s, signaling_models.TypeSetOwnerPolicy, payload)
+}
+
+// ChangePIN replaces the PIN of a network owned by this client. Members stay,
+// computers joining afterwards need the new PIN.
+func (s *SignalingClient) ChangePIN(networkID, pin string) (*signaling_models.ChangePINResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.ChangePINRequest{
+		BaseRequest: signaling_models.BaseRequest{},
+		NetworkID:   networkID,
+		PIN:         pin,
+	}
+
+	return Do[*signaling_models.ChangePINRequest, signaling_models.ChangePINResponse](context.Background(), s, signaling_models.TypeChangePIN, payload)
+}
+
+// GetNetworkStats fetches the activity counters of a network owned by this client
+func (s *SignalingClient) GetNetworkStats(networkID string) (*signaling_models.NetworkStatsResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.GetNetworkStatsRequest{
+		BaseRequest: signaling_models.BaseRequest{},
+		NetworkID:   networkID,
+	}
+
+	return Do[*signaling_models.GetNetworkStatsRequest, signaling_models.NetworkStatsResponse](context.Background(), s, signaling_models.TypeGetNetworkStats, payload)
+}
+
+// KickMember removes a member from a network owned by this client. Unlike KickComputer it
+// works with offline members, and the member needs the PIN to join again.
+func (s *SignalingClient) KickMember(networkID, targetPublicKey string) (*signaling_models.KickResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.KickRequest{
+		BaseRequest:     signaling_models.BaseRequest{},
+		NetworkID:       networkID,
+		TargetPublicKey: targetPublicKey,
+	}
+
+	return Do[*signaling_models.KickRequest, signaling_models.KickResponse](context.Background(), s, signaling_models.TypeKick, payload)
+}
+
+// BanMember removes a member from a network owned by this client and keeps its key from
+// joining again. The reason, which may be empty, is shown to the banned computer.
+func (s *SignalingClient) BanMember(networkID, targetPublicKey, reason string) (*signaling_models.BanResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.BanRequest{
+		BaseRequest:     signaling_models.BaseRequest{},
+		NetworkID:       networkID,
+		TargetPublicKey: targetPublicKey,
+		Reason:          reason,
+	}
+
+	return Do[*signaling_models.BanRequest, signaling_models.BanResponse](context.Background(), s, signaling_models.TypeBan, payload)
+}
+
+// ReserveIP keeps the current IP of a member of a network owned by this client for it,
+// or releases the reservation
+func (s *SignalingClient) ReserveIP(networkID, targetPublicKey string, release bool) (*signaling_models.ReserveIPResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.ReserveIPRequest{
+		BaseRequest:     signaling_models.BaseRequest{},
+		NetworkID:       networkID,
+		TargetPublicKey: targetPublicKey,
+		Release:         release,
+	}
+
+	return Do[*signaling_models.ReserveIPRequest, signaling_models.ReserveIPResponse](context.Background(), s, signaling_models.TypeReserveIP, payload)
+}
+
+// TransferOwnership hands a network owned by this client to another member
+func (s *SignalingClient) TransferOwnership(networkID, targetPublicKey string) (*signaling_models.TransferOwnershipResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.TransferOwnershipRequest{
+		BaseRequest:     signaling_models.BaseRequest{},
+		NetworkID:       networkID,
+		TargetPublicKey: targetPublicKey,
+	}
+
+	return Do[*signaling_models.TransferOwnershipRequest, signaling_models.TransferOwnershipResponse](context.Background(), s, signaling_models.TypeTransferOwnership, payload)
+}
+
+// ChangeSubnet moves a network owned by this client to another virtual subnet that
+// overlaps none of avoid, the local networks of this computer
+func (s *SignalingClient) ChangeSubnet(networkID string, avoid []string) (*signaling_models.ChangeSubnetResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.ChangeSubnetRequest{
+		BaseRequest: signaling_models.BaseRequest{},
+		NetworkID:   networkID,
+		Avoid:       avoid,
+	}
+
+	return Do[*signaling_models.ChangeSubnetRequest, signaling_models.ChangeSubnetResponse](context.Background(), s, signaling_models.TypeChangeSubnet, payload)
+}
+
+// CreateRecoveryCode asks the server for a recovery code for this client's key.
+// Store the code somewhere safe: it allows moving the key's networks to a new key
+// when the private key is lost, and the server cannot show it again.
+func (s *SignalingClient) CreateRecoveryCode() (*signaling_models.RecoveryCodeResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.CreateRecoveryCodeRequest{
+		BaseRequest: signaling_models.BaseRequest{},
+	}
+
+	return Do[*signaling_models.CreateRecoveryCodeRequest, signaling_models.RecoveryCodeResponse](context.Background(), s, signaling_models.TypeCreateRecoveryCode, payload)
+}
+
+// MigrateKey moves the networks and memberships of an old key pair to this client's key
+// and revokes the old key. The old private key signs the hand-over.
+func (s *SignalingClient) MigrateKey(oldPublicKey string, oldPrivateKey ed25519.PrivateKey) (*signaling_models.MigrateKeyResponse, error) {
+	signature := ed25519.Sign(oldPrivateKey, signaling_models.KeyMigrationMessage(s.PublicKeyStr))
+	return s.migrateKey(&signaling_models.MigrateKeyRequest{
+		BaseRequest:  signaling_models.BaseRequest{},
+		OldPublicKey: oldPublicKey,
+		Signature:    base64.StdEncoding.EncodeToString(signature),
+	})
+}
+
+// MigrateKeyWithRecoveryCode is like MigrateKey for when the old private key is lost
+func (s *SignalingClient) MigrateKeyWithRecoveryCode(oldPublicKey string, recoveryCode string) (*signaling_models.MigrateKeyResponse, error) {
+	return s.migrateKey(&signaling_models.MigrateKeyRequest{
+		BaseRequest:  signaling_models.BaseRequest{},
+		OldPublicKey: oldPublicKey,
+		RecoveryCode: recoveryCode,
+	})
+}
+
+// migrateKey sends a MigrateKey request
+func (s *SignalingClient) migrateKey(payload *signaling_models.MigrateKeyRequest) (*signaling_models.MigrateKeyResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
 	}
 
-	return nil, errors.New("unexpected response type")
+	return Do[*signaling_models.MigrateKeyRequest, signaling_models.MigrateKeyResponse](context.Background(), s, signaling_models.TypeMigrateKey, payload)
 }
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	signaling_models "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// fakeServer answers the authentication challenge and pings, and can drop its connections
+type fakeServer struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	conns []*websocket.Conn
+	dials int
+
+	// beforeUpgrade, if set, runs before each handshake is answered
+	beforeUpgrade func()
+}
+
+// newFakeServer starts a fakeServer, closed when the test ends
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	f := &fakeServer{}
+	upgrader := websocket.Upgrader{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		beforeUpgrade := f.beforeUpgrade
+		f.mu.Unlock()
+		if beforeUpgrade != nil {
+			beforeUpgrade()
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns = append(f.conns, conn)
+		f.dials++
+		f.mu.Unlock()
+		f.serve(conn)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// serve sends the challenge and answers pings until the connection closes
+func (f *fakeServer) serve(conn *websocket.Conn) {
+	defer conn.Close()
+
+	challenge, _ := json.Marshal(signaling_models.AuthChallenge{Nonce: base64.StdEncoding.EncodeToString([]byte("nonce"))})
+	if err := conn.WriteJSON(signaling_models.SignalingMessage{Type: signaling_models.TypeAuthChallenge, Payload: challenge}); err != nil {
+		return
+	}
+
+	for {
+		var msg signaling_models.SignalingMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.Type == signaling_models.TypePing {
+			if err := conn.WriteJSON(signaling_models.SignalingMessage{ID: msg.ID, Type: signaling_models.TypePing, Payload: json.RawMessage(`{}`)}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// drop closes every open connection without a close frame
+func (f *fakeServer) drop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		conn.UnderlyingConn().Close()
+	}
+	f.conns = nil
+}
+
+// dialCount returns how many connections the server accepted
+func (f *fakeServer) dialCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.dials
+}
+
+// address returns the ws:// address of the server
+func (f *fakeServer) address() string {
+	return "ws" + strings.TrimPrefix(f.URL, "http")
+}
+
+// waitFor fails the test unless cond holds within a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReconnectAfterDrop(t *testing.T) {
+	server := newFakeServer(t)
+
+	reconnected := make(chan struct{}, 1)
+	s := NewSignalingClient("", nil,
+		WithReconnect(ReconnectPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond}),
+		WithHeartbeat(5*time.Millisecond, 3),
+		WithTimeout(time.Second))
+	s.OnReconnected = func() { reconnected <- struct{}{} }
+	if err := s.Connect(server.address()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer s.Disconnect()
+
+	// Use the client from other goroutines while the connection drops and comes back
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				s.IsConnected()
+				s.LastHeartbeat()
+				s.sendPing()
+			}
+		}()
+	}
+
+	server.drop()
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not reconnect")
+	}
+	close(stop)
+	wg.Wait()
+
+	if !s.IsConnected() {
+		t.Error("client not connected after reconnecting")
+	}
+	if got := server.dialCount(); got != 2 {
+		t.Errorf("server accepted %d connections, want 2", got)
+	}
+	if err := s.sendPing(); err != nil {
+		t.Errorf("ping after reconnecting: %v", err)
+	}
+}
+
+func TestDisconnectDuringReconnectDial(t *testing.T) {
+	server := newFakeServer(t)
+
+	reconnected := make(chan struct{}, 1)
+	s := NewSignalingClient("", nil,
+		WithReconnect(ReconnectPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}),
+		WithHeartbeat(0, 1),
+		WithTimeout(time.Second))
+	s.OnReconnected = func() { reconnected <- struct{}{} }
+	if err := s.Connect(server.address()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	// Hold the handshake of the reconnection until Disconnect returned
+	dialing := make(chan struct{})
+	release := make(chan struct{})
+	server.mu.Lock()
+	server.beforeUpgrade = func() {
+		close(dialing)
+		<-release
+	}
+	server.mu.Unlock()
+
+	server.drop()
+	select {
+	case <-dialing:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not try to reconnect")
+	}
+	if err := s.Disconnect(); err != nil {
+		t.Fatalf("disconnect: %v", err)
+	}
+	close(release)
+
+	waitFor(t, "the reconnection to stop", func() bool { return !s.Reconnecting() })
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-reconnected:
+		t.Fatal("reconnected after Disconnect")
+	default:
+	}
+	if s.IsConnected() {
+		t.Error("client connected after Disconnect")
+	}
+}
+
+func TestDisconnectDuringReconnectBackoff(t *testing.T) {
+	server := newFakeServer(t)
+
+	s := NewSignalingClient("", nil,
+		WithReconnect(ReconnectPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 100 * time.Millisecond}),
+		WithHeartbeat(0, 1),
+		WithTimeout(time.Second))
+	if err := s.Connect(server.address()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	server.drop()
+	waitFor(t, "the client to reconnect", s.Reconnecting)
+	if err := s.Disconnect(); err != nil {
+		t.Fatalf("disconnect: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if s.IsConnected() {
+		t.Error("client connected after Disconnect")
+	}
+	if got := server.dialCount(); got != 1 {
+		t.Errorf("server accepted %d connections, want 1", got)
+	}
+}
+
+func TestConnectAfterDisconnect(t *testing.T) {
+	server := newFakeServer(t)
+
+	s := NewSignalingClient("", nil, WithHeartbeat(0, 1), WithTimeout(time.Second))
+	for i := 0; i < 2; i++ {
+		if err := s.Connect(server.address()); err != nil {
+			t.Fatalf("connect %d: %v", i+1, err)
+		}
+		if !s.IsConnected() {
+			t.Fatalf("not connected after connect %d", i+1)
+		}
+		if err := s.Disconnect(); err != nil {
+			t.Fatalf("disconnect %d: %v", i+1, err)
+		}
+		if s.IsConnected() {
+			t.Fatalf("connected after disconnect %d", i+1)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	signaling_models "github.com/itxtoledo/govpn/libs/signaling/models"
+	"github.com/itxtoledo/govpn/libs/utils"
+)
+
+// responseTypes lists the message types the server answers each request with. A response
+// of another type fails the request instead of being decoded into the wrong structure.
+var responseTypes = map[signaling_models.MessageType][]signaling_models.MessageType{
+	signaling_models.TypeCreateNetwork:       {signaling_models.TypeNetworkCreated},
+	signaling_models.TypeJoinNetwork:         {signaling_models.TypeNetworkJoined},
+	signaling_models.TypeConnectNetwork:      {signaling_models.TypeNetworkConnected},
+	signaling_models.TypeDisconnectNetwork:   {signaling_models.TypeDisconnectNetwork, signaling_models.TypeNetworkDisconnected},
+	signaling_models.TypeLeaveNetwork:        {signaling_models.TypeLeaveNetwork},
+	signaling_models.TypeKick:                {signaling_models.TypeKickResponse},
+	signaling_models.TypeBan:                 {signaling_models.TypeBanResponse},
+	signaling_models.TypeReserveIP:           {signaling_models.TypeReserveIPResponse},
+	signaling_models.TypeTransferOwnership:   {signaling_models.TypeOwnershipTransferred},
+	signaling_models.TypeChangeSubnet:        {signaling_models.TypeChangeSubnetResponse},
+	signaling_models.TypeRename:              {signaling_models.TypeRenameResponse},
+	signaling_models.TypeGetComputerNetworks: {signaling_models.TypeComputerNetworks},
+	signaling_models.TypeListPublicNetworks:  {signaling_models.TypePublicNetworks},
+	signaling_models.TypeKeepNetworkAlive:    {signaling_models.TypeKeepNetworkAliveResponse},
+	signaling_models.TypeSetOwnerPolicy:      {signaling_models.TypeSetOwnerPolicyResponse},
+	signaling_models.TypeChangePIN:           {signaling_models.TypePINChanged},
+	signaling_models.TypeSyncNetwork:         {signaling_models.TypeNetworkMembers},
+	signaling_models.TypeGetNetworkStats:     {signaling_models.TypeNetworkStats},
+	signaling_models.TypeNatReport:           {signaling_models.TypeNatReportResponse},
+	signaling_models.TypeWakeInfo:            {signaling_models.TypeWakeInfoResponse},
+	signaling_models.TypeWake:                {signaling_models.TypeWakeResponse},
+	signaling_models.TypeCreateRecoveryCode:  {signaling_models.TypeRecoveryCodeCreated},
+	signaling_models.TypeMigrateKey:          {signaling_models.TypeKeyMigrated},
+	signaling_models.TypeUpdateClientInfo:    {signaling_models.TypeUpdateClientInfoResponse},
+}
+
+// Do sends req as a msgType request and decodes the response into a TResp. It waits until
+// the response arrives, the client timeout passes or ctx is done. A TypeError answer comes
+// back as a *ServerError.
+func Do[TReq, TResp any](ctx context.Context, s *SignalingClient, msgType signaling_models.MessageType, req TReq) (*TResp, error) {
+	response, err := s.roundTrip(ctx, msgType, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if expected, ok := responseTypes[msgType]; ok && !containsType(expected, response.Type) {
+		return nil, fmt.Errorf("unexpected response type %s to %s", response.Type, msgType)
+	}
+
+	var resp TResp
+	if err := json.Unmarshal(response.Payload, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s response: %v", response.Type, err)
+	}
+	return &resp, nil
+}
+
+// containsType reports whether msgType is one of types
+func containsType(types []signaling_models.MessageType, msgType signaling_models.MessageType) bool {
+	for _, t := range types {
+		if t == msgType {
+			return true
+		}
+	}
+	return false
+}
+
+// roundTrip sends payload under a new message ID and waits for the message answering it.
+// The public key is filled into the payload's BaseRequest when it has one.
+func (s *SignalingClient) roundTrip(ctx context.Context, msgType signaling_models.MessageType, payload interface{}) (signaling_models.SignalingMessage, error) {
+	var none signaling_models.SignalingMessage
+	if !s.IsConnected() {
+		return none, ErrNotConnected
+	}
+
+	// Automatically inject public key into BaseRequest if available
+	if s.injectPublicKey(payload) {
+		log.Printf("Automatically injected public key into payload")
+	}
+
+	messageID, err := utils.GenerateMessageID()
+	if err != nil {
+		return none, fmt.Errorf("error generating message ID: %v", err)
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return none, fmt.Errorf("error serializing payload: %v", err)
+	}
+
+	// Register this message ID to track the response
+	responseChan := s.registerPendingRequest(messageID)
+	forget := func() {
+		s.pendingRequestsLock.Lock()
+		delete(s.pendingRequests, messageID)
+		s.pendingRequestsLock.Unlock()
+	}
+
+	log.Printf("Sending message of type %s with ID %s", msgType, messageID)
+	if err := s.send(signaling_models.SignalingMessage{ID: messageID, Type: msgType, Payload: payloadBytes}); err != nil {
+		forget()
+		return none, fmt.Errorf("error sending message: %v", err)
+	}
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	select {
+	case response, ok := <-responseChan:
+		if !ok {
+			return none, ErrConnectionLost
+		}
+		log.Printf("Received response for message ID %s of type %s", messageID, response.Type)
+
+		if response.Type == signaling_models.TypeError {
+			var errorPayload signaling_models.ErrorResponse
+			if err := json.Unmarshal(response.Payload, &errorPayload); err == nil && errorPayload.Error != "" {
+				return none, &ServerError{
+					Code:    errorPayload.Code,
+					Message: errorPayload.Error,
+					Fields:  errorPayload.Fields,
+				}
+			}
+			return none, errors.New("unknown server error")
+		}
+		return response, nil
+
+	case <-timer.C:
+		forget()
+		return none, fmt.Errorf("%w to message ID %s", ErrTimeout, messageID)
+
+	case <-ctx.Done():
+		forget()
+		return none, ctx.Err()
+	}
+}
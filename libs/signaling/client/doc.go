@@ -0,0 +1,11 @@
+// Package client is the WebSocket client of the signaling server, the only one in the
+// repository: the GUI and the CLI (through the NetworkManager) and the load generator all
+// use it, and request and response structures come from the models package, so field
+// names cannot drift between copies.
+//
+// Callers extend it instead of wrapping the connection:
+//   - MessageHandler receives every message that does not answer a request
+//   - DisconnectHandler, OnReconnecting, OnReconnected and OnReconnectFailed follow the
+//     connection, see reconnect.go
+//   - the options of NewSignalingClient tune timeouts, retries, the dialer and headers
+package client
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"errors"
+
+	signaling_models "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// Errors returned by the SignalingClient methods, to be checked with errors.Is. A
+// ServerError whose code has one of them here unwraps to it, so callers can tell a wrong
+// PIN from a missing network without looking at codes or messages.
+var (
+	ErrNotConnected    = errors.New("not connected to server")
+	ErrTimeout         = errors.New("timeout waiting for response")
+	ErrDisconnected    = errors.New("disconnected while connecting")
+	ErrNetworkNotFound = errors.New("network not found")
+	ErrWrongPIN        = errors.New("wrong PIN")
+)
+
+// codeErrors maps server error codes to the errors above
+var codeErrors = map[signaling_models.ErrorCode]error{
+	signaling_models.ErrNetworkNotFound: ErrNetworkNotFound,
+	signaling_models.ErrWrongPIN:        ErrWrongPIN,
+	signaling_models.ErrNotConnected:    ErrNotConnected,
+}
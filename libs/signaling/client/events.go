@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	signaling_models "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// LastSequence returns the sequence of the latest membership event seen for a network
+func (s *SignalingClient) LastSequence(networkID string) uint64 {
+	s.sequencesLock.Lock()
+	defer s.sequencesLock.Unlock()
+	return s.lastSequences[networkID]
+}
+
+// setLastSequence remembers the latest event sequence seen for a network
+func (s *SignalingClient) setLastSequence(networkID string, seq uint64) {
+	if networkID == "" || seq == 0 {
+		return
+	}
+
+	s.sequencesLock.Lock()
+	s.lastSequences[networkID] = seq
+	s.sequencesLock.Unlock()
+}
+
+// handleUnsolicited processes a message that does not answer a pending request
+// and passes it on to the MessageHandler
+func (s *SignalingClient) handleUnsolicited(msg signaling_models.SignalingMessage) {
+	switch msg.Type {
+	case signaling_models.TypeServerCapabilities:
+		s.setServerCapabilities(msg.Payload)
+	case signaling_models.TypeClientIPInfo:
+		s.setClientIPInfo(msg.Payload)
+	case signaling_models.TypeSessionReplaced:
+		// The server closes the connection next; reconnecting would take it back
+		s.sessionReplaced.Store(true)
+	}
+
+	if s.handleNetworkEvent(msg) || s.MessageHandler == nil {
+		return
+	}
+	s.MessageHandler(msg.Type, msg.Payload)
+}
+
+// ServerCapabilities returns what the server advertised after connecting.
+// ok is false for servers that do not send capabilities.
+func (s *SignalingClient) ServerCapabilities() (caps signaling_models.ServerCapabilitiesNotification, ok bool) {
+	s.capabilitiesLock.Lock()
+	defer s.capabilitiesLock.Unlock()
+
+	if s.capabilities == nil {
+		return caps, false
+	}
+	return *s.capabilities, true
+}
+
+// setServerCapabilities stores a TypeServerCapabilities payload
+func (s *SignalingClient) setServerCapabilities(payload []byte) {
+	var caps signaling_models.ServerCapabilitiesNotification
+	if err := json.Unmarshal(payload, &caps); err != nil {
+		log.Printf("Failed to unmarshal server capabilities: %v", err)
+		return
+	}
+
+	if mismatch := ProtocolMismatch(caps.ProtocolVersion); mismatch != "" {
+		log.Printf("Server %s speaks protocol version %d, this client version %d: %s", caps.ServerVersion, caps.ProtocolVersion, signaling_models.ProtocolVersion, mismatch)
+	}
+
+	s.capabilitiesLock.Lock()
+	s.capabilities = &caps
+	s.capabilitiesLock.Unlock()
+}
+
+// ProtocolMismatch explains what a server speaking serverProtocol means for this client,
+// or returns "" when both speak the same protocol version
+func ProtocolMismatch(serverProtocol int) string {
+	switch {
+	case serverProtocol > signaling_models.ProtocolVersion:
+		return "the server is newer than this client, update the client to use every feature"
+	case serverProtocol < signaling_models.ProtocolVersion:
+		return "the server is older than this client, some features may not work"
+	}
+	return ""
+}
+
+// ClientIPInfo returns the public address the server saw this client connect from.
+// ok is false until the server sent it.
+func (s *SignalingClient) ClientIPInfo() (info signaling_models.ClientIPInfoNotification, ok bool) {
+	s.capabilitiesLock.Lock()
+	defer s.capabilitiesLock.Unlock()
+
+	if s.clientIP == nil {
+		return info, false
+	}
+	return *s.clientIP, true
+}
+
+// setClientIPInfo stores a TypeClientIPInfo payload
+func (s *SignalingClient) setClientIPInfo(payload []byte) {
+	var info signaling_models.ClientIPInfoNotification
+	if err := json.Unmarshal(payload, &info); err != nil {
+		log.Printf("Failed to unmarshal client IP info: %v", err)
+		return
+	}
+
+	s.capabilitiesLock.Lock()
+	s.clientIP = &info
+	s.capabilitiesLock.Unlock()
+}
+
+// handleNetworkEvent tracks event sequences, unpacks replayed events and detects
+// gaps and out-of-order delivery. It reports whether the message was fully handled
+// and must not reach the MessageHandler.
+func (s *SignalingClient) handleNetworkEvent(msg signaling_models.SignalingMessage) bool {
+	switch msg.Type {
+	case signaling_models.TypeNetworkEvents:
+		var replay signaling_models.NetworkEventsNotification
+		if err := json.Unmarshal(msg.Payload, &replay); err != nil {
+			log.Printf("Failed to unmarshal network events: %v", err)
+			return true
+		}
+
+		log.Printf("Replaying %d missed events for network %s", len(replay.Events), replay.NetworkID)
+		for _, event := range replay.Events {
+			s.MessageHandler(event.Type, event.Payload)
+		}
+		s.setLastSequence(replay.NetworkID, replay.LastSequence)
+		return true
+
+	case signaling_models.TypeNetworkMembers:
+		var members signaling_models.NetworkMembersNotification
+		if err := json.Unmarshal(msg.Payload, &members); err == nil {
+			s.setLastSequence(members.NetworkID, members.LastSequence)
+		}
+
+	case signaling_models.TypeNetworkRoster:
+		var roster signaling_models.NetworkRosterNotification
+		if err := json.Unmarshal(msg.Payload, &roster); err == nil {
+			s.setLastSequence(roster.NetworkID, roster.LastSequence)
+		}
+	}
+
+	if msg.Sequence == 0 {
+		return false
+	}
+
+	var event struct {
+		NetworkID string `json:"network_id"`
+	}
+	if err := json.Unmarshal(msg.Payload, &event); err != nil || event.NetworkID == "" {
+		return false
+	}
+
+	last := s.LastSequence(event.NetworkID)
+	switch {
+	case last == 0:
+		// Nothing to compare with yet
+	case msg.Sequence <= last:
+		log.Printf("Dropping out-of-order %s for network %s (sequence %d, last %d)", msg.Type, event.NetworkID, msg.Sequence, last)
+		return true
+	case msg.Sequence > last+1:
+		log.Printf("Missed %d events for network %s, requesting a resync", msg.Sequence-last-1, event.NetworkID)
+		go func(networkID string) {
+			if _, err := s.SyncNetwork(networkID); err != nil {
+				log.Printf("Resync of network %s failed: %v", networkID, err)
+			}
+		}(event.NetworkID)
+	}
+
+	s.setLastSequence(event.NetworkID, msg.Sequence)
+	return false
+}
+
+// SyncNetwork fetches the full member list of a connected network and passes it
+// to the MessageHandler as a TypeNetworkMembers message. It is called automatically
+// when a gap in event sequences is detected.
+func (s *SignalingClient) SyncNetwork(networkID string) (*signaling_models.NetworkMembersNotification, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.SyncNetworkRequest{
+		BaseRequest: signaling_models.BaseRequest{},
+		NetworkID:   networkID,
+	}
+
+	resp, err := Do[*signaling_models.SyncNetworkRequest, signaling_models.NetworkMembersNotification](context.Background(), s, signaling_models.TypeSyncNetwork, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	s.setLastSequence(resp.NetworkID, resp.LastSequence)
+	if payloadBytes, err := json.Marshal(resp); err == nil {
+		s.MessageHandler(signaling_models.TypeNetworkMembers, payloadBytes)
+	}
+	return resp, nil
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// A connection can stop delivering messages without the socket reporting an error, after a
+// NAT mapping expires or a laptop resumes from sleep, and the read loop would wait on it
+// forever. While connected, the client pings the server every interval; after maxMissed
+// pings in a row go unanswered it closes the connection, which then goes down the same
+// path as any other drop: DisconnectHandler and, with WithReconnect, reconnecting.
+
+// Heartbeat defaults, changed with WithHeartbeat
+const (
+	DefaultHeartbeatInterval  = 30 * time.Second
+	DefaultHeartbeatMaxMissed = 2
+)
+
+// heartbeat pings the server over conn until the writer w closes with the connection
+func (s *SignalingClient) heartbeat(conn *websocket.Conn, w *writer) {
+	if s.heartbeatInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.heartbeatInterval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		err := s.sendPing()
+		switch {
+		case err == nil:
+			missed = 0
+			s.connLock.Lock()
+			s.lastHeartbeat = time.Now()
+			s.connLock.Unlock()
+			continue
+		case !errors.Is(err, ErrTimeout):
+			// The connection is already closing
+			return
+		}
+
+		missed++
+		log.Printf("No answer to heartbeat ping (%d of %d): %v", missed, s.heartbeatMaxMissed, err)
+		if missed >= s.heartbeatMaxMissed {
+			log.Printf("Signaling connection unhealthy, last heartbeat %s ago; closing it", time.Since(s.LastHeartbeat()).Round(time.Second))
+			// The read loop fails next and handles the drop
+			conn.Close()
+			return
+		}
+	}
+}
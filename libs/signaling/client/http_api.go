@@ -0,0 +1,76 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	signaling_models "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// httpAPITimeout bounds requests to the server HTTP API
+const httpAPITimeout = 10 * time.Second
+
+// FetchServerInfo asks a server for its version, capabilities and load without opening a WebSocket.
+// serverAddress is the same ws:// or wss:// address passed to Connect.
+func FetchServerInfo(serverAddress string) (*signaling_models.ServerInfoResponse, error) {
+	var info signaling_models.ServerInfoResponse
+	if err := getServerJSON(serverAddress, "/api/server-info", &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// CheckNetworkExists validates an invite code against a server without opening a WebSocket
+func CheckNetworkExists(serverAddress, networkID string) (*signaling_models.NetworkExistsResponse, error) {
+	var resp signaling_models.NetworkExistsResponse
+	if err := getServerJSON(serverAddress, "/api/networks/"+url.PathEscape(networkID)+"/exists", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// httpAPIURL returns the URL of path on the HTTP API of the server behind a WebSocket address
+func httpAPIURL(serverAddress, path string) (string, error) {
+	u, err := url.Parse(serverAddress)
+	if err != nil {
+		return "", fmt.Errorf("invalid server address: %v", err)
+	}
+
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+	u.Path = path
+	u.RawPath = ""
+	u.RawQuery = ""
+	return u.String(), nil
+}
+
+// getServerJSON performs a GET against the HTTP API of the server behind a WebSocket address
+func getServerJSON(serverAddress, path string, v interface{}) error {
+	apiURL, err := httpAPIURL(serverAddress, path)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: httpAPITimeout}
+	resp, err := client.Get(apiURL)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed: %s", path, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %v", path, err)
+	}
+	return nil
+}
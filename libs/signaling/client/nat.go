@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	signaling_models "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// natProbeAttempts is how many times a probe is sent before giving up on an answer
+const natProbeAttempts = 3
+
+// ErrNoNATProbe is returned by DetectNAT when the server did not advertise NAT probe ports
+var ErrNoNATProbe = errors.New("server has no NAT probe")
+
+// NATDetection is the outcome of a NAT probe
+type NATDetection struct {
+	Type          signaling_models.NatType
+	MappedAddress string // Public ip:port the server saw, empty when UDP is blocked
+}
+
+// DetectNAT probes the NAT in front of this computer with the UDP probe of the connected server.
+// It returns ErrNoNATProbe when the server does not run one.
+func (s *SignalingClient) DetectNAT(timeout time.Duration) (NATDetection, error) {
+	caps, ok := s.ServerCapabilities()
+	if !ok || len(caps.NatProbePorts) < 2 {
+		return NATDetection{Type: signaling_models.NatTypeUnknown}, ErrNoNATProbe
+	}
+
+	u, err := url.Parse(s.ServerAddress)
+	if err != nil {
+		return NATDetection{Type: signaling_models.NatTypeUnknown}, err
+	}
+	return DetectNAT(u.Hostname(), caps.NatProbePorts[0], caps.NatProbePorts[1], timeout)
+}
+
+// DetectNAT classifies the NAT between this computer and host, which answers probes
+// on portA and portB. Every probe is sent from the same local socket:
+//   - a probe to portA gives the public mapping, or shows UDP is blocked;
+//   - a probe to portA answered from portB, a port never contacted, shows whether
+//     the NAT filters by port;
+//   - a probe to portB shows whether the mapping changes with the destination.
+//
+// timeout bounds each of the three exchanges.
+func DetectNAT(host string, portA, portB int, timeout time.Duration) (NATDetection, error) {
+	result := NATDetection{Type: signaling_models.NatTypeUnknown}
+
+	addrA, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(portA)))
+	if err != nil {
+		return result, err
+	}
+	addrB, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(portB)))
+	if err != nil {
+		return result, err
+	}
+
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+
+	first, err := natProbeExchange(conn, addrA, false, timeout)
+	if errors.Is(err, errNATProbeTimeout) {
+		result.Type = signaling_models.NatTypeUDPBlocked
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	result.MappedAddress = net.JoinHostPort(first.IP, strconv.Itoa(first.Port))
+
+	_, err = natProbeExchange(conn, addrA, true, timeout)
+	if err != nil && !errors.Is(err, errNATProbeTimeout) {
+		return result, err
+	}
+	otherPortAllowed := err == nil
+
+	second, err := natProbeExchange(conn, addrB, false, timeout)
+	if err != nil && !errors.Is(err, errNATProbeTimeout) {
+		return result, err
+	}
+
+	switch {
+	case err == nil && (second.IP != first.IP || second.Port != first.Port):
+		result.Type = signaling_models.NatTypeSymmetric
+	case isLocalAddress(first.IP, first.Port, conn):
+		result.Type = signaling_models.NatTypeOpen
+	case otherPortAllowed:
+		result.Type = signaling_models.NatTypeRestrictedCone
+	default:
+		result.Type = signaling_models.NatTypePortRestrictedCone
+	}
+	return result, nil
+}
+
+// ReportNAT shares the detected NAT type with the members of the connected network
+func (s *SignalingClient) ReportNAT(natType signaling_models.NatType) (*signaling_models.NatReportResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.NatReportRequest{
+		BaseRequest: signaling_models.BaseRequest{},
+		NatType:     natType,
+	}
+
+	return Do[*signaling_models.NatReportRequest, signaling_models.NatReportResponse](context.Background(), s, signaling_models.TypeNatReport, payload)
+}
+
+// errNATProbeTimeout means a probe got no answer after every attempt
+var errNATProbeTimeout = errors.New("no answer to NAT probe")
+
+// natProbeExchange sends a probe to addr and waits for its answer, resending it a few times
+// within timeout since UDP may drop either datagram
+func natProbeExchange(conn *net.UDPConn, addr *net.UDPAddr, changePort bool, timeout time.Duration) (signaling_models.NatProbeResponse, error) {
+	var resp signaling_models.NatProbeResponse
+
+	txn := make([]byte, 12)
+	if _, err := rand.Read(txn); err != nil {
+		return resp, err
+	}
+	request, err := natProbeRequest(hex.EncodeToString(txn), changePort)
+	if err != nil {
+		return resp, err
+	}
+
+	buf := make([]byte, 512)
+	wait := timeout / natProbeAttempts
+	for attempt := 0; attempt < natProbeAttempts; attempt++ {
+		if _, err := conn.WriteToUDP(request, addr); err != nil {
+			return resp, err
+		}
+
+		conn.SetReadDeadline(time.Now().Add(wait))
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				return resp, err
+			}
+			// Late answers to an earlier probe carry another transaction ID
+			if !from.IP.Equal(addr.IP) || json.Unmarshal(buf[:n], &resp) != nil || resp.TransactionID != hex.EncodeToString(txn) {
+				continue
+			}
+			return resp, nil
+		}
+	}
+	return resp, errNATProbeTimeout
+}
+
+// natProbeRequest encodes a probe, padded to the minimum size the server answers
+func natProbeRequest(txn string, changePort bool) ([]byte, error) {
+	req := signaling_models.NatProbeRequest{TransactionID: txn, ChangePort: changePort}
+	unpadded, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := signaling_models.NatProbeMinSize - len(unpadded) - len(`,"pad":""`)
+	if padding > 0 {
+		req.Padding = strings.Repeat("0", padding)
+	}
+	return json.Marshal(req)
+}
+
+// isLocalAddress reports whether ip:port is the address of conn on one of this computer's
+// interfaces, meaning there is no NAT in between
+func isLocalAddress(ip string, port int, conn *net.UDPConn) bool {
+	if conn.LocalAddr().(*net.UDPAddr).Port != port {
+		return false
+	}
+
+	mapped := net.ParseIP(ip)
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(mapped) {
+			return true
+		}
+	}
+	return false
+}
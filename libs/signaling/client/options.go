@@ -0,0 +1,68 @@
+package client
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultTimeout is how long a request waits for its response unless WithTimeout says otherwise
+const DefaultTimeout = 10 * time.Second
+
+// Option configures a SignalingClient in NewSignalingClient
+type Option func(*SignalingClient)
+
+// WithTimeout sets how long each request waits for its response before failing or, for the
+// requests covered by the retry policy, being sent again
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *SignalingClient) {
+		if timeout > 0 {
+			s.timeout = timeout
+		}
+	}
+}
+
+// WithRetryPolicy sets how many times Connect dials and how many times create, join and
+// connect requests are sent before giving up, and the wait between tries
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(s *SignalingClient) {
+		s.RetryPolicy = policy
+	}
+}
+
+// WithReconnect makes the client reconnect by itself when the connection drops, following
+// policy
+func WithReconnect(policy ReconnectPolicy) Option {
+	return func(s *SignalingClient) {
+		s.reconnectPolicy = &policy
+	}
+}
+
+// WithHeartbeat sets how often the client pings the server and how many unanswered pings
+// in a row close the connection; an interval of 0 turns the heartbeat off
+func WithHeartbeat(interval time.Duration, maxMissed int) Option {
+	return func(s *SignalingClient) {
+		s.heartbeatInterval = interval
+		s.heartbeatMaxMissed = max(maxMissed, 1)
+	}
+}
+
+// WithDialer sets the WebSocket dialer used by Connect, for a proxy or a different handshake
+// timeout. The dialer is copied on each Connect; its TLS configuration is replaced when Pins
+// are set.
+func WithDialer(dialer *websocket.Dialer) Option {
+	return func(s *SignalingClient) {
+		if dialer != nil {
+			s.dialer = dialer
+		}
+	}
+}
+
+// WithHeaders adds headers to the WebSocket handshake, on top of the ones the client sends
+// itself
+func WithHeaders(headers http.Header) Option {
+	return func(s *SignalingClient) {
+		s.headers = headers.Clone()
+	}
+}
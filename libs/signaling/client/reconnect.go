@@ -0,0 +1,163 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// With WithReconnect, a connection that drops by itself is dialed again in the background,
+// with an exponential and random wait so the clients of one outage do not all come back at
+// once. Requests waiting for a response fail with ErrConnectionLost right away instead of
+// running into the timeout. The server forgets the session when it drops, so OnReconnected
+// is where the caller connects to its networks again; ConnectNetwork sends the last event
+// sequence seen, and only the missed membership events are replayed. A drop that follows
+// TypeSessionReplaced is not reconnected, or two computers with the same key would keep
+// replacing each other. Disconnect wins over a reconnection in progress: an attempt that
+// finishes dialing after it closes the new connection instead of installing it.
+
+// ErrConnectionLost is returned by requests whose connection dropped before the response
+var ErrConnectionLost = errors.New("connection to the server lost")
+
+// ReconnectPolicy says how the client reconnects after the connection drops
+type ReconnectPolicy struct {
+	BaseDelay   time.Duration // Wait before the first attempt, doubled on each one after it
+	MaxDelay    time.Duration // Longest wait between attempts
+	MaxAttempts int           // Attempts before giving up; 0 keeps trying until Disconnect
+}
+
+// DefaultReconnectPolicy keeps trying, from one second up to a minute between attempts
+var DefaultReconnectPolicy = ReconnectPolicy{BaseDelay: time.Second, MaxDelay: time.Minute}
+
+// delay returns the wait before attempt, counted from 1: twice the previous one, up to
+// MaxDelay, picked between half and the full value
+func (p ReconnectPolicy) delay(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 32 && p.BaseDelay<<(attempt-1) > 0 {
+		delay = min(p.BaseDelay<<(attempt-1), p.MaxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// Reconnecting reports whether the client is reconnecting after a drop
+func (s *SignalingClient) Reconnecting() bool {
+	s.reconnectLock.Lock()
+	defer s.reconnectLock.Unlock()
+	return s.reconnectStop != nil
+}
+
+// StopReconnect cancels a reconnection in progress. Disconnect calls it too.
+func (s *SignalingClient) StopReconnect() {
+	s.reconnectLock.Lock()
+	defer s.reconnectLock.Unlock()
+
+	if s.reconnectStop != nil {
+		close(s.reconnectStop)
+		s.reconnectStop = nil
+	}
+}
+
+// startReconnect starts reconnecting in the background, unless the client has no
+// reconnect policy, the session was replaced or a reconnection is already running
+func (s *SignalingClient) startReconnect() {
+	if s.reconnectPolicy == nil || s.sessionReplaced.Load() {
+		return
+	}
+
+	s.reconnectLock.Lock()
+	defer s.reconnectLock.Unlock()
+	if s.reconnectStop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	s.reconnectStop = stop
+	go s.reconnect(*s.reconnectPolicy, stop)
+}
+
+// reconnect dials the server again until it connects, the policy runs out of attempts or
+// stop is closed
+func (s *SignalingClient) reconnect(policy ReconnectPolicy, stop chan struct{}) {
+	address := s.serverAddress()
+
+	var err error
+	for attempt := 1; policy.MaxAttempts == 0 || attempt <= policy.MaxAttempts; attempt++ {
+		delay := policy.delay(attempt)
+		log.Printf("Reconnecting to %s in %s (attempt %d)", address, delay, attempt)
+		if s.OnReconnecting != nil {
+			s.OnReconnecting(attempt, delay)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+
+		var conn *websocket.Conn
+		if conn, err = s.connect(address, stop); err != nil {
+			if errors.Is(err, ErrDisconnected) {
+				// Disconnect or StopReconnect was called while dialing
+				return
+			}
+			log.Printf("Reconnect attempt %d failed: %v", attempt, err)
+			continue
+		}
+
+		if !s.finishReconnect(stop) {
+			// Cancelled after the connection was installed: close it, and only it, since the
+			// caller may have connected again since
+			if conn != nil {
+				s.closeConn(conn)
+			}
+			return
+		}
+		if conn == nil {
+			log.Printf("Stopped reconnecting, the client was connected in the meantime")
+			return
+		}
+		log.Printf("Reconnected to signaling server after %d attempts", attempt)
+		if s.OnReconnected != nil {
+			s.OnReconnected()
+		}
+		return
+	}
+
+	if !s.finishReconnect(stop) {
+		return
+	}
+	log.Printf("Giving up reconnecting after %d attempts: %v", policy.MaxAttempts, err)
+	if s.OnReconnectFailed != nil {
+		s.OnReconnectFailed(fmt.Errorf("reconnecting failed after %d attempts: %w", policy.MaxAttempts, err))
+	}
+}
+
+// finishReconnect marks the reconnection that owns stop as over. It returns false if it
+// was cancelled in the meantime.
+func (s *SignalingClient) finishReconnect(stop chan struct{}) bool {
+	s.reconnectLock.Lock()
+	defer s.reconnectLock.Unlock()
+
+	if s.reconnectStop != stop {
+		return false
+	}
+	s.reconnectStop = nil
+	return true
+}
+
+// failPendingRequests fails every request waiting for a response with ErrConnectionLost
+func (s *SignalingClient) failPendingRequests() {
+	s.pendingRequestsLock.Lock()
+	defer s.pendingRequestsLock.Unlock()
+
+	for id, ch := range s.pendingRequests {
+		delete(s.pendingRequests, id)
+		close(ch)
+	}
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	signaling_models "github.com/itxtoledo/govpn/libs/signaling/models"
+	"github.com/itxtoledo/govpn/libs/utils"
+)
+
+// RetryPolicy says how often Connect dials the server, and how CreateNetwork, JoinNetwork
+// and ConnectNetwork are sent again when their response times out while the connection is
+// still up. Every resend carries the same idempotency key, so a request the server already
+// handled is answered from its cache instead of creating a second network or membership.
+type RetryPolicy struct {
+	Attempts int           // Tries in total, counting the first; 1 or less disables retrying
+	Backoff  time.Duration // Wait before the first retry, doubled before each one after it
+}
+
+// DefaultRetryPolicy is the policy of a new SignalingClient
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: time.Second}
+
+// newIdempotencyKey returns a random key for one logical request
+func newIdempotencyKey() string {
+	key, err := utils.GenerateRandomID(32)
+	if err != nil {
+		// Without a key the server handles every try as new, as it did before retries
+		log.Printf("Cannot generate idempotency key: %v", err)
+		return ""
+	}
+	return key
+}
+
+// doWithRetry is Do for requests that carry an idempotency key: after a timeout while still
+// connected it sends req again, following s.RetryPolicy. Without the key a retry could
+// repeat a request the server already handled.
+func doWithRetry[TReq, TResp any](ctx context.Context, s *SignalingClient, msgType signaling_models.MessageType, req TReq) (*TResp, error) {
+	policy := s.RetryPolicy
+	backoff := policy.Backoff
+
+	for attempt := 1; ; attempt++ {
+		resp, err := Do[TReq, TResp](ctx, s, msgType, req)
+		if err == nil || !errors.Is(err, ErrTimeout) || attempt >= policy.Attempts {
+			return resp, err
+		}
+
+		log.Printf("No response to %s, retrying in %s (attempt %d of %d)", msgType, backoff, attempt+1, policy.Attempts)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if !s.IsConnected() {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	signaling_models "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// pingAttempts is how many times a server is pinged; the fastest answer counts,
+// so the connection setup of the first one does not skew the result
+const pingAttempts = 3
+
+// ServerLatency is the measured round trip to one server of a list
+type ServerLatency struct {
+	Server  signaling_models.ServerListEntry
+	Latency time.Duration
+	Err     error // Set when the server did not answer
+}
+
+// FetchServerList downloads the list of signaling servers published at listURL
+func FetchServerList(listURL string) (*signaling_models.ServerList, error) {
+	client := &http.Client{Timeout: httpAPITimeout}
+	resp, err := client.Get(listURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch server list: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch server list: %s", resp.Status)
+	}
+
+	var list signaling_models.ServerList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode server list: %v", err)
+	}
+	if len(list.Servers) == 0 {
+		return nil, errors.New("server list is empty")
+	}
+	return &list, nil
+}
+
+// PingServer measures the round trip to the /ping endpoint of the server behind a WebSocket address
+func PingServer(serverAddress string, timeout time.Duration) (time.Duration, error) {
+	pingURL, err := httpAPIURL(serverAddress, "/ping")
+	if err != nil {
+		return 0, err
+	}
+
+	// One client so the later attempts reuse the connection of the first
+	client := &http.Client{Timeout: timeout}
+	defer client.CloseIdleConnections()
+
+	best := time.Duration(-1)
+	var lastErr error
+	for attempt := 0; attempt < pingAttempts; attempt++ {
+		start := time.Now()
+		resp, err := client.Get(pingURL)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("ping failed: %s", resp.Status)
+			continue
+		}
+
+		if elapsed := time.Since(start); best < 0 || elapsed < best {
+			best = elapsed
+		}
+	}
+
+	if best < 0 {
+		return 0, lastErr
+	}
+	return best, nil
+}
+
+// RankServers pings every server at once and returns them fastest first,
+// followed by the ones that did not answer
+func RankServers(servers []signaling_models.ServerListEntry, timeout time.Duration) []ServerLatency {
+	results := make([]ServerLatency, len(servers))
+
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server signaling_models.ServerListEntry) {
+			defer wg.Done()
+			latency, err := PingServer(server.Address, timeout)
+			results[i] = ServerLatency{Server: server, Latency: latency, Err: err}
+		}(i, server)
+	}
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if (results[i].Err == nil) != (results[j].Err == nil) {
+			return results[i].Err == nil
+		}
+		return results[i].Latency < results[j].Latency
+	})
+	return results
+}
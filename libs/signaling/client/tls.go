@@ -0,0 +1,151 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Certificate pinning for wss:// servers. A pin is either the SHA-256 of a certificate's public
+// key, written "sha256/<base64>" as HPKP and curl do, which survives renewals with the same key,
+// or the SHA-256 fingerprint of the whole certificate in hex, as browsers show it. A pin of the
+// server certificate itself is trusted on its own, so self-signed servers work; a pin of an
+// intermediate or root only counts on a chain that also passes the usual verification.
+
+// ErrPinMismatch is returned when a pinned server presents a certificate matching no pin
+var ErrPinMismatch = errors.New("server certificate does not match the pinned certificates")
+
+// ErrPinsNeedTLS is returned when pins are set for a server address that is not wss://
+var ErrPinsNeedTLS = errors.New("certificate pins need a wss:// server address")
+
+// pinPrefix marks a pin of the public key
+const pinPrefix = "sha256/"
+
+// Pin is the hash a pinned certificate must match
+type Pin struct {
+	PublicKey bool // Hash of the SubjectPublicKeyInfo instead of the whole certificate
+	Hash      [sha256.Size]byte
+}
+
+// ParsePin reads a "sha256/<base64>" public key pin or a hex certificate fingerprint, with or
+// without colons
+func ParsePin(s string) (Pin, error) {
+	s = strings.TrimSpace(s)
+	var pin Pin
+	var hash []byte
+	var err error
+	if encoded, ok := strings.CutPrefix(s, pinPrefix); ok {
+		pin.PublicKey = true
+		hash, err = base64.StdEncoding.DecodeString(encoded)
+	} else {
+		hash, err = hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	}
+	if err != nil || len(hash) != sha256.Size {
+		return pin, fmt.Errorf("invalid pin %q, expected sha256/<base64 of the public key hash> or a SHA-256 certificate fingerprint", s)
+	}
+	copy(pin.Hash[:], hash)
+	return pin, nil
+}
+
+// PinOf returns the public key pin of a certificate
+func PinOf(cert *x509.Certificate) Pin {
+	return Pin{PublicKey: true, Hash: sha256.Sum256(cert.RawSubjectPublicKeyInfo)}
+}
+
+// Matches reports whether the certificate matches the pin
+func (p Pin) Matches(cert *x509.Certificate) bool {
+	if p.PublicKey {
+		return sha256.Sum256(cert.RawSubjectPublicKeyInfo) == p.Hash
+	}
+	return sha256.Sum256(cert.Raw) == p.Hash
+}
+
+// String writes the pin in the format ParsePin reads
+func (p Pin) String() string {
+	if p.PublicKey {
+		return pinPrefix + base64.StdEncoding.EncodeToString(p.Hash[:])
+	}
+	return hex.EncodeToString(p.Hash[:])
+}
+
+// pinnedTLSConfig returns a TLS configuration that accepts only servers matching one of the
+// pins. The standard verification is replaced by verifyPins, which still runs it unless the
+// server certificate itself is pinned.
+func pinnedTLSConfig(serverName string, pins []Pin) *tls.Config {
+	return &tls.Config{
+		ServerName:         serverName,
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return verifyPins(state, pins)
+		},
+	}
+}
+
+// verifyPins checks the certificates a server presented against the pins
+func verifyPins(state tls.ConnectionState, pins []Pin) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	leaf := state.PeerCertificates[0]
+	for _, pin := range pins {
+		if pin.Matches(leaf) {
+			return nil
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, err := leaf.Verify(x509.VerifyOptions{DNSName: state.ServerName, Intermediates: intermediates})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPinMismatch, err)
+	}
+	for _, chain := range chains {
+		for _, cert := range chain {
+			for _, pin := range pins {
+				if pin.Matches(cert) {
+					return nil
+				}
+			}
+		}
+	}
+	return ErrPinMismatch
+}
+
+// FetchServerPin connects to a wss:// server without verifying it and returns the public key
+// pin of its certificate, for the user to compare and pin. Only trust it on a network you trust.
+func FetchServerPin(serverAddress string) (Pin, error) {
+	u, err := url.Parse(serverAddress)
+	if err != nil {
+		return Pin{}, fmt.Errorf("invalid server address: %v", err)
+	}
+	if u.Scheme != "wss" {
+		return Pin{}, ErrPinsNeedTLS
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	dialer := &net.Dialer{Timeout: httpAPITimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: true})
+	if err != nil {
+		return Pin{}, fmt.Errorf("failed to reach %s: %v", host, err)
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return Pin{}, errors.New("server presented no certificate")
+	}
+	return PinOf(certs[0]), nil
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+
+	signaling_models "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// ReportWakeInfo lets the members of this client's networks wake this computer with
+// Wake-on-LAN through another member on its LAN. An empty mac withdraws the permission.
+func (s *SignalingClient) ReportWakeInfo(mac, subnet string) (*signaling_models.WakeInfoResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.WakeInfoRequest{
+		BaseRequest: signaling_models.BaseRequest{},
+		MAC:         mac,
+		Subnet:      subnet,
+	}
+
+	return Do[*signaling_models.WakeInfoRequest, signaling_models.WakeInfoResponse](context.Background(), s, signaling_models.TypeWakeInfo, payload)
+}
+
+// Wake asks the server to wake an offline member of the connected network through a
+// member online on the same LAN
+func (s *SignalingClient) Wake(networkID, targetPublicKey string) (*signaling_models.WakeResponse, error) {
+	if !s.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	payload := &signaling_models.WakeRequest{
+		BaseRequest:     signaling_models.BaseRequest{},
+		NetworkID:       networkID,
+		TargetPublicKey: targetPublicKey,
+	}
+
+	return Do[*signaling_models.WakeRequest, signaling_models.WakeResponse](context.Background(), s, signaling_models.TypeWake, payload)
+}
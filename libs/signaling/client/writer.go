@@ -0,0 +1,125 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	signaling_models "github.com/itxtoledo/govpn/libs/signaling/models"
+)
+
+// gorilla/websocket allows one writer at a time, and requests, signals, relay frames and
+// the initial ping are sent from whatever goroutine calls them. Every message therefore
+// goes through a queue read by a single writer goroutine per connection, which also closes
+// the connection cleanly: what is already queued is written, then a close frame.
+
+const (
+	// outboundQueueSize is how many messages may wait for the writer
+	outboundQueueSize = 256
+	// writeTimeout bounds each write, so a stalled connection fails its senders
+	writeTimeout = 10 * time.Second
+)
+
+// errWriterClosed is returned for messages sent after the connection started closing
+var errWriterClosed = errors.New("connection closed")
+
+// outboundMessage is a message waiting for the writer and where its write error goes
+type outboundMessage struct {
+	message signaling_models.SignalingMessage
+	result  chan error
+}
+
+// writer owns the writes of one WebSocket connection
+type writer struct {
+	conn      *websocket.Conn
+	queue     chan outboundMessage
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newWriter starts the writer goroutine of conn
+func newWriter(conn *websocket.Conn) *writer {
+	w := &writer{
+		conn:  conn,
+		queue: make(chan outboundMessage, outboundQueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// send queues message and waits until it is written
+func (w *writer) send(message signaling_models.SignalingMessage) error {
+	result := make(chan error, 1)
+	select {
+	case w.queue <- outboundMessage{message: message, result: result}:
+	case <-w.done:
+		return errWriterClosed
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-w.done:
+		// Queued after the writer flushed: it was never written, unless the result raced done
+		select {
+		case err := <-result:
+			return err
+		default:
+			return errWriterClosed
+		}
+	}
+}
+
+// close asks the writer to flush the queue and send a close frame; it does not wait
+func (w *writer) close() {
+	w.closeOnce.Do(func() { close(w.stop) })
+}
+
+// closeAndWait closes the writer and waits for it to finish, at most writeTimeout
+func (w *writer) closeAndWait() {
+	w.close()
+	select {
+	case <-w.done:
+	case <-time.After(writeTimeout):
+	}
+}
+
+// run writes queued messages until close
+func (w *writer) run() {
+	defer close(w.done)
+	for {
+		select {
+		case out := <-w.queue:
+			out.result <- w.write(out.message)
+		case <-w.stop:
+			w.flush()
+			return
+		}
+	}
+}
+
+// flush writes what is still queued and then the close frame
+func (w *writer) flush() {
+	for {
+		select {
+		case out := <-w.queue:
+			out.result <- w.write(out.message)
+		default:
+			closeFrame := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			_ = w.conn.WriteControl(websocket.CloseMessage, closeFrame, time.Now().Add(writeTimeout))
+			return
+		}
+	}
+}
+
+// write writes one message with the write deadline
+func (w *writer) write(message signaling_models.SignalingMessage) error {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	return w.conn.WriteJSON(message)
+}
@@ -5,3 +5,15 @@ go 1.22.0
 replace github.com/itxtoledo/govpn/libs/utils v0.0.0-00010101000000-000000000000 => ../utils
 
 replace github.com/itxtoledo/govpn/libs/signaling/models v0.0.0 => ./models
+
+require (
+	google.golang.org/grpc v1.71.1
+	google.golang.org/protobuf v1.36.4
+)
+
+require (
+	golang.org/x/net v0.34.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250115164207-1a7da9e5054f // indirect
+)
@@ -18,6 +18,22 @@ const (
 	TypePing                MessageType = "Ping"
 	TypeGetComputerNetworks MessageType = "GetComputerNetworks"
 	TypeUpdateClientInfo    MessageType = "UpdateClientInfo"
+	TypeListPublicNetworks  MessageType = "ListPublicNetworks"
+	TypeKeepNetworkAlive    MessageType = "KeepNetworkAlive"
+	TypeSetOwnerPolicy      MessageType = "SetOwnerPolicy"
+	TypeChangePIN           MessageType = "ChangePIN"
+	TypeAuthResponse        MessageType = "AuthResponse"
+	TypeCreateRecoveryCode  MessageType = "CreateRecoveryCode"
+	TypeMigrateKey          MessageType = "MigrateKey"
+	TypeSyncNetwork         MessageType = "SyncNetwork"
+	TypeNatReport           MessageType = "NatReport"
+	TypeGetNetworkStats     MessageType = "GetNetworkStats"
+	TypeBan                 MessageType = "Ban"
+	TypeReserveIP           MessageType = "ReserveIP"
+	TypeTransferOwnership   MessageType = "TransferOwnership"
+	TypeChangeSubnet        MessageType = "ChangeSubnet"
+	TypeWakeInfo            MessageType = "WakeInfo"
+	TypeWake                MessageType = "Wake"
 
 	// Server to client message types
 	TypeError                    MessageType = "Error"
@@ -39,18 +55,48 @@ const (
 	TypeServerShutdown           MessageType = "ServerShutdown"
 	TypeComputerNetworks         MessageType = "ComputerNetworks"
 	TypeUpdateClientInfoResponse MessageType = "UpdateClientInfoResponse"
+	TypeNetworkMembers           MessageType = "NetworkMembers"
+	TypeNetworkRoster            MessageType = "NetworkRoster"
+	TypeNetworkEvents            MessageType = "NetworkEvents"
+	TypePublicNetworks           MessageType = "PublicNetworks"
+	TypeNetworkExpiryWarning     MessageType = "NetworkExpiryWarning"
+	TypeKeepNetworkAliveResponse MessageType = "KeepNetworkAliveResponse"
+	TypeSetOwnerPolicyResponse   MessageType = "SetOwnerPolicyResponse"
+	TypePINChanged               MessageType = "PINChanged"
+	TypeNetworkOwnerChanged      MessageType = "NetworkOwnerChanged"
+	TypeAuthChallenge            MessageType = "AuthChallenge"
+	TypeAuthResult               MessageType = "AuthResult"
+	TypeRecoveryCodeCreated      MessageType = "RecoveryCodeCreated"
+	TypeKeyMigrated              MessageType = "KeyMigrated"
+	TypeServerCapabilities       MessageType = "ServerCapabilities"
+	TypeClientIPInfo             MessageType = "ClientIPInfo"
+	TypeNatReportResponse        MessageType = "NatReportResponse"
+	TypeComputerNatType          MessageType = "ComputerNatType"
+	TypeNetworkStats             MessageType = "NetworkStats"
+	TypeServerNotice             MessageType = "ServerNotice"
+	TypeSessionReplaced          MessageType = "SessionReplaced"
+	TypeBanResponse              MessageType = "BanResponse"
+	TypeReserveIPResponse        MessageType = "ReserveIPResponse"
+	TypeOwnershipTransferred     MessageType = "OwnershipTransferred"
+	TypeChangeSubnetResponse     MessageType = "ChangeSubnetResponse"
+	TypeNetworkSubnetChanged     MessageType = "NetworkSubnetChanged"
+	TypeWakeInfoResponse         MessageType = "WakeInfoResponse"
+	TypeWakeResponse             MessageType = "WakeResponse"
+	TypeWakeRelay                MessageType = "WakeRelay"
 
 	// WebRTC signaling message types
 	TypeSdpOffer     MessageType = "SdpOffer"
 	TypeSdpAnswer    MessageType = "SdpAnswer"
 	TypeIceCandidate MessageType = "IceCandidate"
+	TypeRelayFrame   MessageType = "RelayFrame"
 )
 
 // SignalingMessage represents the wrapper structure for WebSocket communication
 type SignalingMessage struct {
-	ID      string      `json:"message_id"`
-	Type    MessageType `json:"type"`
-	Payload []byte      `json:"payload"`
+	ID       string      `json:"message_id"`
+	Type     MessageType `json:"type"`
+	Payload  []byte      `json:"payload"`
+	Sequence uint64      `json:"sequence,omitempty"` // Per-network position of a notification, increasing by one for each notification of the network
 }
 
 // SdpOffer represents a WebRTC SDP offer message
@@ -76,14 +122,202 @@ type IceCandidate struct {
 	SDPMLineIndex   uint16 `json:"sdp_m_line_index"`
 }
 
+// RelayFrame carries an encrypted peer frame through the server when the two computers
+// cannot reach each other directly. The server forwards Data untouched to the target,
+// within the network's relay quota.
+type RelayFrame struct {
+	SenderPublicKey string `json:"sender_public_key"`
+	TargetPublicKey string `json:"target_public_key"`
+	Data            []byte `json:"data"`
+}
+
 // BaseRequest contains common fields used in all messages from client to server
 type BaseRequest struct {
 	PublicKey string `json:"public_key"` // Base64-encoded Ed25519 public key
 }
 
+// OwnerPolicy decides what happens to a network when its owner disconnects
+type OwnerPolicy string
+
+// Owner policy constants
+const (
+	OwnerPolicyPreserve OwnerPolicy = "preserve" // Keep the network until the owner returns
+	OwnerPolicyDelete   OwnerPolicy = "delete"   // Delete the network and notify its members
+	OwnerPolicyTransfer OwnerPolicy = "transfer" // Hand ownership to the oldest remaining member
+)
+
+// Valid reports whether p is a known owner policy
+func (p OwnerPolicy) Valid() bool {
+	switch p {
+	case OwnerPolicyPreserve, OwnerPolicyDelete, OwnerPolicyTransfer:
+		return true
+	}
+	return false
+}
+
+// NatType classifies the NAT in front of a computer, as detected with the server's UDP probe.
+// The probe answers from a single address, so a full cone NAT is reported as restricted_cone.
+type NatType string
+
+// NAT type constants
+const (
+	NatTypeUnknown            NatType = "unknown"              // Not detected, for example the server has no probe
+	NatTypeOpen               NatType = "open"                 // Public address, no NAT
+	NatTypeRestrictedCone     NatType = "restricted_cone"      // Same mapping for every destination, any port of a contacted host may answer
+	NatTypePortRestrictedCone NatType = "port_restricted_cone" // Same mapping for every destination, only the contacted address and port may answer
+	NatTypeSymmetric          NatType = "symmetric"            // A new mapping for every destination
+	NatTypeUDPBlocked         NatType = "udp_blocked"          // No UDP answer came back
+)
+
+// Valid reports whether t is a known NAT type
+func (t NatType) Valid() bool {
+	switch t {
+	case NatTypeUnknown, NatTypeOpen, NatTypeRestrictedCone, NatTypePortRestrictedCone, NatTypeSymmetric, NatTypeUDPBlocked:
+		return true
+	}
+	return false
+}
+
+// DirectLikely reports whether a direct peer-to-peer connection between computers behind
+// NATs of type t and other is likely to work without a relay. Unknown types are assumed to work.
+func (t NatType) DirectLikely(other NatType) bool {
+	if t == NatTypeUDPBlocked || other == NatTypeUDPBlocked {
+		return false
+	}
+	hard := func(n NatType) bool { return n == NatTypeSymmetric }
+	strict := func(n NatType) bool { return n == NatTypeSymmetric || n == NatTypePortRestrictedCone }
+	return !(hard(t) && strict(other) || hard(other) && strict(t))
+}
+
+// NatProbeMinSize is the smallest probe datagram the server answers. Requests are padded
+// to it so an answer is never larger than the request that caused it.
+const NatProbeMinSize = 128
+
+// NatProbeRequest is the JSON datagram sent to a NAT probe port of the server
+type NatProbeRequest struct {
+	TransactionID string `json:"txn"`
+	ChangePort    bool   `json:"change_port,omitempty"` // Answer from the other probe port
+	Padding       string `json:"pad,omitempty"`         // Fills the datagram up to NatProbeMinSize
+}
+
+// NatProbeResponse tells the client the address its probe arrived from
+type NatProbeResponse struct {
+	TransactionID string `json:"txn"`
+	IP            string `json:"ip"`
+	Port          int    `json:"port"`
+}
+
+// Authentication structs
+
+// AuthChallenge is sent by the server right after the WebSocket handshake.
+// The client proves it owns its public key by signing AuthChallengeMessage(nonce).
+type AuthChallenge struct {
+	Nonce     string `json:"nonce"`              // Base64-encoded random bytes
+	ExpiresIn int    `json:"expires_in_seconds"` // Time left to answer before the connection is closed
+	Required  bool   `json:"required"`           // Whether requests are rejected until the client authenticates
+}
+
+// AuthResponseRequest answers an AuthChallenge
+type AuthResponseRequest struct {
+	BaseRequest
+	Signature string `json:"signature"` // Base64-encoded Ed25519 signature of AuthChallengeMessage(nonce)
+}
+
+// AuthResult confirms the connection is authenticated as PublicKey
+type AuthResult struct {
+	PublicKey     string `json:"public_key"`
+	Authenticated bool   `json:"authenticated"`
+}
+
+// authChallengeContext separates challenge signatures from any other use of the key
+const authChallengeContext = "govpn-auth-v1:"
+
+// AuthChallengeMessage returns the bytes a client signs to answer a challenge nonce
+func AuthChallengeMessage(nonce []byte) []byte {
+	return append([]byte(authChallengeContext), nonce...)
+}
+
+// Key migration structs
+
+// CreateRecoveryCodeRequest asks the server for a recovery code for the caller's key.
+// A new code replaces the previous one.
+type CreateRecoveryCodeRequest struct {
+	BaseRequest
+}
+
+// RecoveryCodeResponse carries a recovery code. The server only keeps its hash,
+// so the code cannot be retrieved again.
+type RecoveryCodeResponse struct {
+	PublicKey    string `json:"public_key"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MigrateKeyRequest moves the networks and memberships of OldPublicKey to the key
+// of the request and revokes the old key. It is authorized either by Signature,
+// made with the old private key over KeyMigrationMessage(new key), or by RecoveryCode.
+type MigrateKeyRequest struct {
+	BaseRequest
+	OldPublicKey string `json:"old_public_key"`
+	Signature    string `json:"signature,omitempty"`     // Base64-encoded Ed25519 signature by the old key
+	RecoveryCode string `json:"recovery_code,omitempty"` // Recovery code created for the old key
+}
+
+// MigrateKeyResponse confirms a key migration
+type MigrateKeyResponse struct {
+	OldPublicKey string `json:"old_public_key"`
+	NewPublicKey string `json:"new_public_key"`
+	Networks     int    `json:"networks"`    // Networks whose ownership moved
+	Memberships  int    `json:"memberships"` // Memberships that moved
+}
+
+// keyMigrationContext separates migration signatures from any other use of the key
+const keyMigrationContext = "govpn-migrate-v1:"
+
+// KeyMigrationMessage returns the bytes the old key signs to hand over to newPublicKey
+func KeyMigrationMessage(newPublicKey string) []byte {
+	return []byte(keyMigrationContext + newPublicKey)
+}
+
+// ErrorCode is a stable, machine-readable identifier for an error returned by the server
+type ErrorCode string
+
+// Error code constants
+const (
+	ErrInvalidRequest      ErrorCode = "invalid_request"
+	ErrUnknownMessageType  ErrorCode = "unknown_message_type"
+	ErrPublicKeyRequired   ErrorCode = "public_key_required"
+	ErrNetworkNotFound     ErrorCode = "network_not_found"
+	ErrWrongPIN            ErrorCode = "wrong_pin"
+	ErrInvalidPIN          ErrorCode = "invalid_pin"
+	ErrNetworkFull         ErrorCode = "network_full"
+	ErrNotOwner            ErrorCode = "not_owner"
+	ErrNotMember           ErrorCode = "not_member"
+	ErrNotConnected        ErrorCode = "not_connected"
+	ErrTargetNotFound      ErrorCode = "target_not_found"
+	ErrNetworkLimitReached ErrorCode = "network_limit_reached"
+	ErrUnauthenticated     ErrorCode = "unauthenticated"
+	ErrAuthFailed          ErrorCode = "auth_failed"
+	ErrPublicKeyMismatch   ErrorCode = "public_key_mismatch"
+	ErrKeyRevoked          ErrorCode = "key_revoked"
+	ErrBanned              ErrorCode = "banned"
+	ErrMaintenance         ErrorCode = "maintenance"
+	ErrSessionActive       ErrorCode = "session_active"
+	ErrRelayQuotaExceeded  ErrorCode = "relay_quota_exceeded"
+	ErrNoWakeRelay         ErrorCode = "no_wake_relay"
+	ErrInternal            ErrorCode = "internal_error"
+)
+
 // ErrorResponse is sent when an error occurs
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Code   ErrorCode    `json:"code,omitempty"`   // Machine-readable error code
+	Error  string       `json:"error"`            // Human-readable message
+	Fields []FieldError `json:"fields,omitempty"` // Set when the request failed validation
+}
+
+// FieldError describes a validation problem with a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // Event-specific request structs
@@ -91,25 +325,39 @@ type ErrorResponse struct {
 // CreateNetworkRequest represents a request to create a new network
 type CreateNetworkRequest struct {
 	BaseRequest
-	NetworkName  string `json:"network_name"`
-	PIN          string `json:"pin"`
-	ComputerName string `json:"computer_name,omitempty"`
+	NetworkName    string      `json:"network_name"`
+	PIN            string      `json:"pin"`
+	ComputerName   string      `json:"computer_name,omitempty"`
+	IdempotencyKey string      `json:"idempotency_key,omitempty"` // Retries with the same key get the original response
+	Public         bool        `json:"public,omitempty"`          // Listed by ListPublicNetworks (joining still needs the PIN)
+	Tags           []string    `json:"tags,omitempty"`            // Free-form labels shown in the public listing
+	OwnerPolicy    OwnerPolicy `json:"owner_policy,omitempty"`    // What happens when the owner disconnects; server default if empty
 }
 
 // CreateNetworkResponse represents a response to a network creation request
 type CreateNetworkResponse struct {
-	NetworkID   string         `json:"network_id"`
-	NetworkName string         `json:"network_name"`
-	PublicKey   string         `json:"public_key"`
-	Computers   []ComputerInfo `json:"computers"`
+	NetworkID        string             `json:"network_id"`
+	NetworkName      string             `json:"network_name"`
+	PublicKey        string             `json:"public_key"`
+	Computers        []ComputerInfo     `json:"computers"`
+	OwnedNetworks    []OwnedNetworkInfo `json:"owned_networks,omitempty"`     // Every network owned by the creator, including this one
+	MaxOwnedNetworks int                `json:"max_owned_networks,omitempty"` // How many networks a public key may own
+}
+
+// OwnedNetworkInfo describes a network owned by the requesting public key
+type OwnedNetworkInfo struct {
+	NetworkID   string    `json:"network_id"`
+	NetworkName string    `json:"network_name"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 // JoinNetworkRequest represents a request to join an existing network
 type JoinNetworkRequest struct {
 	BaseRequest
-	NetworkID    string `json:"network_id"`
-	PIN          string `json:"pin"`
-	ComputerName string `json:"computername,omitempty"`
+	NetworkID      string `json:"network_id"`
+	PIN            string `json:"pin"`
+	ComputerName   string `json:"computername,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"` // Retries with the same key get the original response
 }
 
 // JoinNetworkResponse represents a response to a network join request
@@ -122,8 +370,10 @@ type JoinNetworkResponse struct {
 // ConnectNetworkRequest represents a request to connect to a previously joined network
 type ConnectNetworkRequest struct {
 	BaseRequest
-	NetworkID    string `json:"network_id"`
-	ComputerName string `json:"computername,omitempty"`
+	NetworkID      string `json:"network_id"`
+	ComputerName   string `json:"computername,omitempty"`
+	LastSequence   uint64 `json:"last_sequence,omitempty"`   // Last event sequence seen for this network, to receive only the missed events
+	IdempotencyKey string `json:"idempotency_key,omitempty"` // Retries with the same key get the original response
 }
 
 // ConnectNetworkResponse represents a response to a network connection request
@@ -157,17 +407,133 @@ type LeaveNetworkResponse struct {
 
 // Network management structs
 
-// KickRequest represents a request to kick a computer from a network
+// KickRequest represents a request to kick a computer from a network. With TargetPublicKey
+// the computer also loses its membership and needs the PIN to come back; TargetID, the
+// address of its connection, only disconnects it.
 type KickRequest struct {
 	BaseRequest
-	NetworkID string `json:"network_id"`
-	TargetID  string `json:"target_id"`
+	NetworkID       string `json:"network_id"`
+	TargetID        string `json:"target_id,omitempty"`
+	TargetPublicKey string `json:"target_public_key,omitempty"`
 }
 
 // KickResponse confirms a computer has been kicked
 type KickResponse struct {
+	NetworkID       string `json:"network_id"`
+	TargetID        string `json:"target_id,omitempty"`
+	TargetPublicKey string `json:"target_public_key,omitempty"`
+}
+
+// BanRequest kicks a member out of a network and keeps its public key from joining again
+type BanRequest struct {
+	BaseRequest
+	NetworkID       string `json:"network_id"`
+	TargetPublicKey string `json:"target_public_key"`
+	Reason          string `json:"reason,omitempty"` // Shown to the banned computer
+}
+
+// BanResponse confirms a computer was banned
+type BanResponse struct {
+	NetworkID       string `json:"network_id"`
+	TargetPublicKey string `json:"target_public_key"`
+}
+
+// ReserveIPRequest keeps the current IP of a member for its public key, so it gets the
+// same IP after leaving and joining again and no other computer is given it. Release
+// drops the reservation.
+type ReserveIPRequest struct {
+	BaseRequest
+	NetworkID       string `json:"network_id"`
+	TargetPublicKey string `json:"target_public_key"`
+	Release         bool   `json:"release,omitempty"`
+}
+
+// ReserveIPResponse confirms a reservation was made or released
+type ReserveIPResponse struct {
+	NetworkID       string `json:"network_id"`
+	TargetPublicKey string `json:"target_public_key"`
+	IP              string `json:"ip"`
+	Reserved        bool   `json:"reserved"`
+}
+
+// TransferOwnershipRequest hands a network to another member. Members are told with
+// TypeNetworkOwnerChanged.
+type TransferOwnershipRequest struct {
+	BaseRequest
+	NetworkID       string `json:"network_id"`
+	TargetPublicKey string `json:"target_public_key"`
+}
+
+// TransferOwnershipResponse confirms the new owner of a network
+type TransferOwnershipResponse struct {
+	NetworkID      string `json:"network_id"`
+	OwnerPublicKey string `json:"owner_public_key"`
+}
+
+// WakeInfoRequest lets members wake this computer with Wake-on-LAN while it sleeps. MAC and
+// Subnet are those of its physical network interface; an empty MAC withdraws the
+// permission. The server keeps them in memory, with the public address of the connection.
+type WakeInfoRequest struct {
+	BaseRequest
+	MAC    string `json:"mac,omitempty"`    // Like 00:11:22:33:44:55
+	Subnet string `json:"subnet,omitempty"` // Like 192.168.1.0/24, where the magic packet is broadcast
+}
+
+// WakeInfoResponse confirms whether the computer can now be woken
+type WakeInfoResponse struct {
+	Wakeable bool `json:"wakeable"`
+}
+
+// WakeRequest asks the server to wake an offline member of a network the client is
+// connected to. The server hands the request to an online member behind the same public
+// address the target last reported from, which broadcasts the magic packet on its LAN.
+type WakeRequest struct {
+	BaseRequest
+	NetworkID       string `json:"network_id"`
+	TargetPublicKey string `json:"target_public_key"`
+}
+
+// WakeResponse tells which member was asked to send the magic packet
+type WakeResponse struct {
+	NetworkID       string `json:"network_id"`
+	TargetPublicKey string `json:"target_public_key"`
+	RelayPublicKey  string `json:"relay_public_key"`
+}
+
+// WakeRelayNotification asks this computer to broadcast a magic packet for a member on
+// its LAN
+type WakeRelayNotification struct {
+	NetworkID          string `json:"network_id"`
+	TargetPublicKey    string `json:"target_public_key"`
+	MAC                string `json:"mac"`
+	Subnet             string `json:"subnet"`
+	RequesterPublicKey string `json:"requester_public_key"`
+}
+
+// DefaultSubnet is the virtual subnet of a network that never changed it
+const DefaultSubnet = "10.10.0.0/24"
+
+// ChangeSubnetRequest moves a network the client owns to another /24 inside 10.0.0.0/8,
+// for example when its subnet overlaps a member's local network. The server picks a
+// subnet that is neither the current one nor one of Avoid; every member keeps the last
+// octet of its IP. Members are told with TypeNetworkSubnetChanged.
+type ChangeSubnetRequest struct {
+	BaseRequest
+	NetworkID string   `json:"network_id"`
+	Avoid     []string `json:"avoid,omitempty"` // CIDRs in use on the requesting computer
+}
+
+// ChangeSubnetResponse confirms the new subnet of a network
+type ChangeSubnetResponse struct {
+	NetworkID string `json:"network_id"`
+	Subnet    string `json:"subnet"`
+}
+
+// NetworkSubnetChangedNotification tells members the network moved to another subnet.
+// Each member's new IP keeps the last octet of the previous one.
+type NetworkSubnetChangedNotification struct {
 	NetworkID string `json:"network_id"`
-	TargetID  string `json:"target_id"`
+	Subnet    string `json:"subnet"`
 }
 
 // RenameRequest represents a request to rename a network
@@ -201,10 +567,11 @@ type ComputerLeftNotification struct {
 
 // ComputerConnectedNotification notifies that a computer has connected to the network
 type ComputerConnectedNotification struct {
-	NetworkID    string `json:"network_id"`
-	PublicKey    string `json:"public_key"`
-	ComputerName string `json:"computername,omitempty"`
-	ComputerIP   string `json:"computer_ip,omitempty"`
+	NetworkID    string  `json:"network_id"`
+	PublicKey    string  `json:"public_key"`
+	ComputerName string  `json:"computername,omitempty"`
+	ComputerIP   string  `json:"computer_ip,omitempty"`
+	NatType      NatType `json:"nat_type,omitempty"` // Reported NAT type of the computer, if any
 }
 
 // ComputerDisconnectedNotification notifies that a computer has disconnected from the network (but not left)
@@ -220,11 +587,142 @@ type ComputerRenamedNotification struct {
 	NewComputerName string `json:"new_computer_name"`
 }
 
+// NetworkMembersNotification lists the other members of a network in a single message,
+// sent to a computer right after it joins or connects
+type NetworkMembersNotification struct {
+	NetworkID    string         `json:"network_id"`
+	Computers    []ComputerInfo `json:"computers"`
+	LastSequence uint64         `json:"last_sequence"` // Sequence of the latest network event included in this state
+}
+
+// RosterMember is one computer in a network roster
+type RosterMember struct {
+	ComputerInfo
+	IsOwner bool `json:"is_owner"`
+}
+
+// NetworkRosterNotification is the complete member list of a network, including the
+// receiving computer. It is sent on join and connect and again after every membership
+// change, so a client can replace its list instead of applying individual events.
+type NetworkRosterNotification struct {
+	NetworkID      string         `json:"network_id"`
+	OwnerPublicKey string         `json:"owner_public_key"`
+	Members        []RosterMember `json:"members"`
+	LastSequence   uint64         `json:"last_sequence"` // Sequence of the latest network event included in this roster
+}
+
+// SyncNetworkRequest asks for the full member list of a connected network, for example
+// after a gap in event sequences. The server answers with TypeNetworkMembers.
+type SyncNetworkRequest struct {
+	BaseRequest
+	NetworkID string `json:"network_id"`
+}
+
+// NetworkEventsNotification replays the membership events a reconnecting computer missed.
+// It replaces NetworkMembers when the server still has every event after the client's last sequence.
+type NetworkEventsNotification struct {
+	NetworkID    string             `json:"network_id"`
+	Events       []SignalingMessage `json:"events"`
+	LastSequence uint64             `json:"last_sequence"`
+}
+
 // NetworkDeletedNotification notifies that a network has been deleted
 type NetworkDeletedNotification struct {
 	NetworkID string `json:"network_id"`
 }
 
+// NetworkExpiryWarningNotification warns an owner that an inactive network will soon be deleted.
+// Sending KeepNetworkAlive for the network postpones the deletion.
+type NetworkExpiryWarningNotification struct {
+	NetworkID   string    `json:"network_id"`
+	NetworkName string    `json:"network_name"`
+	LastActive  time.Time `json:"last_active"`
+	DeletesAt   time.Time `json:"deletes_at"`
+}
+
+// KeepNetworkAliveRequest asks the server to mark an owned network as active
+type KeepNetworkAliveRequest struct {
+	BaseRequest
+	NetworkID string `json:"network_id"`
+}
+
+// KeepNetworkAliveResponse confirms a network was marked as active
+type KeepNetworkAliveResponse struct {
+	NetworkID string    `json:"network_id"`
+	DeletesAt time.Time `json:"deletes_at"` // New deletion date if the network stays inactive
+}
+
+// SetOwnerPolicyRequest changes what happens to a network when its owner disconnects
+type SetOwnerPolicyRequest struct {
+	BaseRequest
+	NetworkID string      `json:"network_id"`
+	Policy    OwnerPolicy `json:"policy"`
+}
+
+// SetOwnerPolicyResponse confirms the owner policy of a network was changed
+type SetOwnerPolicyResponse struct {
+	NetworkID string      `json:"network_id"`
+	Policy    OwnerPolicy `json:"policy"`
+}
+
+// ChangePINRequest replaces the PIN of a network the client owns
+type ChangePINRequest struct {
+	BaseRequest
+	NetworkID string `json:"network_id"`
+	PIN       string `json:"pin"` // The new PIN
+}
+
+// ChangePINResponse confirms the PIN of a network was replaced
+type ChangePINResponse struct {
+	NetworkID string    `json:"network_id"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// GetNetworkStatsRequest asks for the activity counters of a network the client owns
+type GetNetworkStatsRequest struct {
+	BaseRequest
+	NetworkID string `json:"network_id"`
+}
+
+// NetworkStatsResponse holds the activity counters of a network. Counters are kept in
+// memory by the server and start over when it restarts, see Since.
+type NetworkStatsResponse struct {
+	NetworkID       string    `json:"network_id"`
+	Members         int       `json:"members"`          // Computers that joined the network
+	OnlineMembers   int       `json:"online_members"`   // Computers connected right now
+	TotalJoins      int64     `json:"total_joins"`      // JoinNetwork requests accepted
+	PeakMembers     int       `json:"peak_members"`     // Most computers connected at the same time
+	LastActivity    time.Time `json:"last_activity"`    // Latest join, connection or relayed message
+	RelayedMessages int64     `json:"relayed_messages"` // WebRTC signaling messages forwarded between members
+	RelayedBytes    int64     `json:"relayed_bytes"`    // Payload bytes of those messages
+	Since           time.Time `json:"since"`            // When counting started for this network
+}
+
+// NatReportRequest reports the NAT type the client detected, shared with its network peers
+type NatReportRequest struct {
+	BaseRequest
+	NatType NatType `json:"nat_type"`
+}
+
+// NatReportResponse confirms the reported NAT type was recorded
+type NatReportResponse struct {
+	NatType NatType `json:"nat_type"`
+}
+
+// ComputerNatTypeNotification tells network members the NAT type a computer reported
+type ComputerNatTypeNotification struct {
+	NetworkID string  `json:"network_id"`
+	PublicKey string  `json:"public_key"`
+	NatType   NatType `json:"nat_type"`
+}
+
+// NetworkOwnerChangedNotification notifies members that ownership was transferred
+type NetworkOwnerChangedNotification struct {
+	NetworkID              string `json:"network_id"`
+	PreviousOwnerPublicKey string `json:"previous_owner_public_key"`
+	OwnerPublicKey         string `json:"owner_public_key"`
+}
+
 // KickedNotification notifies a computer they've been kicked
 type KickedNotification struct {
 	NetworkID string `json:"network_id"`
@@ -241,6 +739,9 @@ type ServerShutdownNotification struct {
 // GetComputerNetworksRequest represents a request to get all networks a computer has joined
 type GetComputerNetworksRequest struct {
 	BaseRequest
+	Cursor        string            `json:"cursor,omitempty"`         // NextCursor of the previous page, empty for the first page
+	PageSize      int               `json:"page_size,omitempty"`      // Networks per page, 0 uses the server default
+	KnownVersions map[string]string `json:"known_versions,omitempty"` // Version the client already has, by network ID
 }
 
 // UpdateClientInfoRequest represents a request to update the client's name
@@ -251,10 +752,13 @@ type UpdateClientInfoRequest struct {
 
 // ComputerInfo represents information about a computer in a network
 type ComputerInfo struct {
-	Name       string `json:"name"`
-	ComputerIP string `json:"computer_ip"`
-	PublicKey  string `json:"public_key"`
-	IsOnline   bool   `json:"is_online"`
+	Name       string  `json:"name"`
+	ComputerIP string  `json:"computer_ip"`
+	PublicKey  string  `json:"public_key"`
+	IsOnline   bool    `json:"is_online"`
+	NatType    NatType `json:"nat_type,omitempty"`    // As reported by the computer, empty until it does
+	IPReserved bool    `json:"ip_reserved,omitempty"` // The owner reserved ComputerIP for this computer
+	Wakeable   bool    `json:"wakeable,omitempty"`    // Reported Wake-on-LAN details the server still has
 }
 
 // ComputerNetworkInfo represents information about a network a computer has joined
@@ -265,12 +769,39 @@ type ComputerNetworkInfo struct {
 	LastConnected  time.Time      `json:"last_connected"`
 	ComputerIP     string         `json:"computer_ip,omitempty"`
 	AdminPublicKey string         `json:"admin_public_key"`
+	OwnerPolicy    OwnerPolicy    `json:"owner_policy,omitempty"`
+	Subnet         string         `json:"subnet,omitempty"` // Virtual subnet of the network, DefaultSubnet if empty
 	Computers      []ComputerInfo `json:"computers"`
+	Version        string         `json:"version,omitempty"`   // Hash of the fields above, changes whenever they do
+	Unchanged      bool           `json:"unchanged,omitempty"` // Matches the known version; only NetworkID and Version are set
 }
 
 // ComputerNetworksResponse represents a response containing all networks a computer has joined
 type ComputerNetworksResponse struct {
-	Networks []ComputerNetworkInfo `json:"networks"`
+	Networks   []ComputerNetworkInfo `json:"networks"`
+	NextCursor string                `json:"next_cursor,omitempty"` // Set when more pages follow
+	Total      int                   `json:"total"`                 // Number of networks across all pages
+}
+
+// ListPublicNetworksRequest asks for the networks marked as public
+type ListPublicNetworksRequest struct {
+	BaseRequest
+	Tag string `json:"tag,omitempty"` // Only return networks with this tag
+}
+
+// PublicNetworkInfo describes a public network; the PIN is never included
+type PublicNetworkInfo struct {
+	NetworkID   string   `json:"network_id"`
+	NetworkName string   `json:"network_name"`
+	MemberCount int      `json:"member_count"`
+	OnlineCount int      `json:"online_count"`
+	MaxMembers  int      `json:"max_members"`
+	Tags        []string `json:"tags"`
+}
+
+// PublicNetworksResponse lists public networks, most recently active first
+type PublicNetworksResponse struct {
+	Networks []PublicNetworkInfo `json:"networks"`
 }
 
 // UpdateClientInfoResponse confirms a client info update
@@ -287,3 +818,126 @@ type Computer struct {
 	PeerIP    string `json:"computer_ip"`
 	PublicKey string `json:"public_key"`
 }
+
+// HTTP API responses
+
+// NetworkExistsResponse is returned by GET /api/networks/{id}/exists
+type NetworkExistsResponse struct {
+	NetworkID   string `json:"network_id"`
+	Exists      bool   `json:"exists"`
+	NetworkName string `json:"network_name,omitempty"`
+	IsFull      bool   `json:"is_full,omitempty"`
+}
+
+// ProtocolVersion is the version of the signaling protocol described by this package
+const ProtocolVersion = 1
+
+// ServerLimits are the limits a client should respect to avoid rejected requests
+type ServerLimits struct {
+	MaxClientsPerNetwork     int   `json:"max_clients_per_network"`
+	MaxNetworksPerOwner      int   `json:"max_networks_per_owner"`
+	MaxMessageSize           int64 `json:"max_message_size"`
+	MaxPayloadSize           int   `json:"max_payload_size"`
+	MaxConnsPerIP            int   `json:"max_conns_per_ip"`
+	ComputerNetworksPageSize int   `json:"computer_networks_page_size"`
+	RelayQuotaBytes          int64 `json:"relay_quota_bytes"` // Bytes per minute a network may relay through the server, 0 when relaying is off
+}
+
+// ServerCapabilitiesNotification is sent right after connecting so clients can adapt
+// to what the server supports instead of failing at runtime
+type ServerCapabilitiesNotification struct {
+	ProtocolVersion int           `json:"protocol_version"`
+	ServerVersion   string        `json:"server_version"`
+	ServerCommit    string        `json:"server_commit,omitempty"` // Commit the server was built from, when known
+	MessageTypes    []MessageType `json:"message_types"`           // Client to server message types the server handles
+	Features        []string      `json:"features"`                // Optional protocol features, see ServerInfoResponse.Capabilities
+	Limits          ServerLimits  `json:"limits"`
+	NatProbePorts   []int         `json:"nat_probe_ports,omitempty"` // UDP ports of the NAT probe, empty when disabled
+}
+
+// HasFeature reports whether the server advertised an optional feature
+func (n ServerCapabilitiesNotification) HasFeature(feature string) bool {
+	for _, f := range n.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// Supports reports whether the server handles a client to server message type
+func (n ServerCapabilitiesNotification) Supports(msgType MessageType) bool {
+	for _, t := range n.MessageTypes {
+		if t == msgType {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIPInfoNotification tells a client the public address the server sees it
+// connecting from, for NAT diagnostics
+type ClientIPInfoNotification struct {
+	IP        string `json:"ip"`
+	IPVersion string `json:"ip_version"`     // "ipv4" or "ipv6"
+	Port      int    `json:"port,omitempty"` // Source port as seen by the server; unknown behind a proxy
+	Forwarded bool   `json:"forwarded"`      // Taken from X-Forwarded-For set by a trusted proxy
+}
+
+// NoticeLevel tells a client how prominently to show a server notice
+type NoticeLevel string
+
+// Notice levels
+const (
+	NoticeLevelInfo    NoticeLevel = "info"
+	NoticeLevelWarning NoticeLevel = "warning"
+)
+
+// ServerNoticeNotification is an operator message for the people using the client,
+// such as the message of the day sent after connecting
+type ServerNoticeNotification struct {
+	ID        string      `json:"id"` // Same for repeated deliveries of one notice, so a dismissed notice can stay hidden
+	Message   string      `json:"message"`
+	Level     NoticeLevel `json:"level"`
+	NetworkID string      `json:"network_id,omitempty"` // Set when the notice is only for the members of a network
+	SentAt    time.Time   `json:"sent_at"`
+}
+
+// SessionReplacedNotification is sent to a connection right before the server closes it
+// because the same public key connected again
+type SessionReplacedNotification struct {
+	PublicKey    string `json:"public_key"`
+	ReplacedFrom string `json:"replaced_from,omitempty"` // IP the new connection comes from
+}
+
+// VersionResponse is returned by GET /version
+type VersionResponse struct {
+	Version         string `json:"version"`
+	Commit          string `json:"commit,omitempty"`
+	BuildDate       string `json:"build_date,omitempty"`
+	GoVersion       string `json:"go_version"`
+	ProtocolVersion int    `json:"protocol_version"`
+}
+
+// ServerListEntry is one signaling server of a server list
+type ServerListEntry struct {
+	Name    string `json:"name"`
+	Region  string `json:"region"`  // Free-form label such as "eu-west" or "São Paulo"
+	Address string `json:"address"` // ws:// or wss:// address passed to Connect
+}
+
+// ServerList is the document served at a client's SERVER_LIST_URL
+type ServerList struct {
+	Servers []ServerListEntry `json:"servers"`
+}
+
+// ServerInfoResponse is returned by GET /api/server-info
+type ServerInfoResponse struct {
+	Version              string   `json:"version"`
+	Capabilities         []string `json:"capabilities"`
+	MaxClientsPerNetwork int      `json:"max_clients_per_network"`
+	MaxNetworksPerOwner  int      `json:"max_networks_per_owner"`
+	ActiveConnections    int      `json:"active_connections"`
+	ActiveNetworks       int      `json:"active_networks"`
+	Maintenance          bool     `json:"maintenance"` // New networks and joins are refused while set
+}
@@ -0,0 +1,8 @@
+// Package adminpb holds the Go bindings generated from admin.proto.
+//
+// The generated files are committed. After changing admin.proto, run
+// `go generate ./...` from libs/signaling with protoc, protoc-gen-go and
+// protoc-gen-go-grpc installed to regenerate the message and service code.
+package adminpb
+
+//go:generate protoc --proto_path=.. --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ../admin.proto
@@ -14,6 +14,12 @@ const (
 	DefaultPINPattern = `^\d{4}$`
 )
 
+// NetworkIDPattern matches the IDs made by GenerateNetworkID: 16 lowercase hex characters,
+// or 6 when it falls back to the timestamp
+const NetworkIDPattern = `^[0-9a-f]{6}([0-9a-f]{10})?$`
+
+var networkIDRegex = regexp.MustCompile(NetworkIDPattern)
+
 // Helper functions
 
 // GenerateMessageID generates a random ID in hexadecimal format based on the specified length
@@ -65,3 +71,9 @@ func ValidatePIN(pin string) bool {
 	}
 	return regex.MatchString(pin)
 }
+
+// ValidateNetworkID checks if an ID has the format of the IDs the server generates, so clients
+// can reject a mistyped ID without asking the server
+func ValidateNetworkID(networkID string) bool {
+	return networkIDRegex.MatchString(networkID)
+}
@@ -0,0 +1,7 @@
+-- Allow a public key to own more than one network.
+-- The per-owner limit is enforced by the server (MAX_NETWORKS_PER_OWNER).
+
+DROP INDEX IF EXISTS idx_networks_owner_public_key;
+
+-- Keep owner lookups fast without the uniqueness constraint
+CREATE INDEX IF NOT EXISTS idx_networks_owner_public_key ON networks(owner_public_key);
@@ -0,0 +1,12 @@
+-- Public networks: owners can list a network in the community browser.
+-- Joining a public network still requires its PIN.
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS is_public BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- Speed up the public listing (ordered by activity) and tag filtering
+CREATE INDEX IF NOT EXISTS idx_networks_public_last_active ON networks(last_active DESC) WHERE is_public;
+CREATE INDEX IF NOT EXISTS idx_networks_tags ON networks USING GIN(tags);
+
+COMMENT ON COLUMN networks.is_public IS 'Whether the network is listed by ListPublicNetworks';
+COMMENT ON COLUMN networks.tags IS 'Lowercase labels shown in the public network listing';
@@ -0,0 +1,9 @@
+-- Owner-disconnect policy: what happens to a network when its owner drops.
+--   preserve: keep the network until the owner returns
+--   delete:   delete the network and notify its members
+--   transfer: hand ownership to the oldest remaining member
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS owner_policy VARCHAR(16) NOT NULL DEFAULT 'preserve'
+  CHECK (owner_policy IN ('preserve', 'delete', 'transfer'));
+
+COMMENT ON COLUMN networks.owner_policy IS 'What happens to the network when its owner disconnects (preserve, delete, transfer)';
@@ -0,0 +1,56 @@
+-- Key revocation and device migration.
+-- A user who reinstalls a machine registers a new key pair and moves the
+-- memberships and networks of the old key to it. The old key is then revoked.
+
+-- Hashed recovery codes, one per public key
+CREATE TABLE IF NOT EXISTS key_recovery_codes (
+  public_key TEXT PRIMARY KEY,
+  code_hash VARCHAR(64) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE key_recovery_codes IS 'SHA-256 hashes of the recovery codes that allow migrating a key without its private key';
+
+-- Keys that were replaced and may no longer authenticate
+CREATE TABLE IF NOT EXISTS revoked_keys (
+  public_key TEXT PRIMARY KEY,
+  replaced_by TEXT NOT NULL,
+  revoked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE revoked_keys IS 'Public keys revoked by a key migration and the key that replaced them';
+
+-- Moves every network and membership of old_key to new_key in one transaction.
+-- Memberships of networks new_key already belongs to are dropped instead of duplicated.
+CREATE OR REPLACE FUNCTION migrate_public_key(old_key TEXT, new_key TEXT)
+RETURNS JSON
+LANGUAGE plpgsql
+AS $$
+DECLARE
+  moved_networks INTEGER;
+  moved_memberships INTEGER;
+BEGIN
+  IF EXISTS (SELECT 1 FROM revoked_keys WHERE public_key = new_key) THEN
+    RAISE EXCEPTION 'new key was revoked';
+  END IF;
+
+  UPDATE networks SET owner_public_key = new_key, last_active = CURRENT_TIMESTAMP
+    WHERE owner_public_key = old_key;
+  GET DIAGNOSTICS moved_networks = ROW_COUNT;
+
+  DELETE FROM computer_networks AS old_rows
+    WHERE old_rows.public_key = old_key
+      AND EXISTS (SELECT 1 FROM computer_networks AS new_rows
+                  WHERE new_rows.network_id = old_rows.network_id AND new_rows.public_key = new_key);
+
+  UPDATE computer_networks SET public_key = new_key WHERE public_key = old_key;
+  GET DIAGNOSTICS moved_memberships = ROW_COUNT;
+
+  DELETE FROM key_recovery_codes WHERE public_key = old_key;
+
+  INSERT INTO revoked_keys (public_key, replaced_by) VALUES (old_key, new_key)
+    ON CONFLICT (public_key) DO UPDATE SET replaced_by = EXCLUDED.replaced_by, revoked_at = CURRENT_TIMESTAMP;
+
+  RETURN json_build_object('networks', moved_networks, 'memberships', moved_memberships);
+END;
+$$;
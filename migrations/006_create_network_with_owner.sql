@@ -0,0 +1,30 @@
+-- Atomic network creation.
+-- Creating a network used to be two inserts (the network, then the owner's
+-- membership); a failure in between left networks without any member.
+
+-- Inserts a network and the owner's membership in one transaction.
+-- Either both rows are written or the call fails and neither is.
+CREATE OR REPLACE FUNCTION create_network_with_owner(
+  network_id TEXT,
+  network_name TEXT,
+  network_pin TEXT,
+  owner_key TEXT,
+  is_public BOOLEAN,
+  tags TEXT[],
+  owner_policy TEXT,
+  computer_name TEXT,
+  peer_ip TEXT
+)
+RETURNS JSON
+LANGUAGE plpgsql
+AS $$
+BEGIN
+  INSERT INTO networks (id, name, pin, owner_public_key, is_public, tags, owner_policy)
+    VALUES (network_id, network_name, network_pin, owner_key, is_public, COALESCE(tags, '{}'), owner_policy);
+
+  INSERT INTO computer_networks (network_id, public_key, computername, peer_ip)
+    VALUES (network_id, owner_key, computer_name, peer_ip);
+
+  RETURN json_build_object('network_id', network_id);
+END;
+$$;
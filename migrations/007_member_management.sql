@@ -0,0 +1,75 @@
+-- Member management by the network owner.
+-- A banned public key can no longer join the network, and a reserved IP is
+-- kept for a public key while it is out of the network and given back when
+-- it joins again.
+
+-- Public keys banned from a network
+CREATE TABLE IF NOT EXISTS network_bans (
+  network_id VARCHAR(64) NOT NULL,
+  public_key TEXT NOT NULL,
+  reason VARCHAR(255),
+  banned_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (network_id, public_key),
+  FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE network_bans IS 'Public keys the owner banned from a network';
+
+-- IPs reserved for a public key in a network
+CREATE TABLE IF NOT EXISTS network_ip_reservations (
+  network_id VARCHAR(64) NOT NULL,
+  public_key TEXT NOT NULL,
+  peer_ip VARCHAR(15) NOT NULL,
+  reserved_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (network_id, public_key),
+  UNIQUE (network_id, peer_ip),
+  FOREIGN KEY (network_id) REFERENCES networks(id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE network_ip_reservations IS 'IPs the owner reserved for a computer, kept while it is out of the network';
+
+-- Same as in 005_key_migration.sql, now also moving the bans and reservations of old_key,
+-- so a key migration neither lifts a ban nor loses a reserved IP
+CREATE OR REPLACE FUNCTION migrate_public_key(old_key TEXT, new_key TEXT)
+RETURNS JSON
+LANGUAGE plpgsql
+AS $$
+DECLARE
+  moved_networks INTEGER;
+  moved_memberships INTEGER;
+BEGIN
+  IF EXISTS (SELECT 1 FROM revoked_keys WHERE public_key = new_key) THEN
+    RAISE EXCEPTION 'new key was revoked';
+  END IF;
+
+  UPDATE networks SET owner_public_key = new_key, last_active = CURRENT_TIMESTAMP
+    WHERE owner_public_key = old_key;
+  GET DIAGNOSTICS moved_networks = ROW_COUNT;
+
+  DELETE FROM computer_networks AS old_rows
+    WHERE old_rows.public_key = old_key
+      AND EXISTS (SELECT 1 FROM computer_networks AS new_rows
+                  WHERE new_rows.network_id = old_rows.network_id AND new_rows.public_key = new_key);
+
+  UPDATE computer_networks SET public_key = new_key WHERE public_key = old_key;
+  GET DIAGNOSTICS moved_memberships = ROW_COUNT;
+
+  INSERT INTO network_bans (network_id, public_key, reason, banned_at)
+    SELECT network_id, new_key, reason, banned_at FROM network_bans WHERE public_key = old_key
+    ON CONFLICT (network_id, public_key) DO NOTHING;
+  DELETE FROM network_bans WHERE public_key = old_key;
+
+  DELETE FROM network_ip_reservations AS old_rows
+    WHERE old_rows.public_key = old_key
+      AND EXISTS (SELECT 1 FROM network_ip_reservations AS new_rows
+                  WHERE new_rows.network_id = old_rows.network_id AND new_rows.public_key = new_key);
+  UPDATE network_ip_reservations SET public_key = new_key WHERE public_key = old_key;
+
+  DELETE FROM key_recovery_codes WHERE public_key = old_key;
+
+  INSERT INTO revoked_keys (public_key, replaced_by) VALUES (old_key, new_key)
+    ON CONFLICT (public_key) DO UPDATE SET replaced_by = EXCLUDED.replaced_by, revoked_at = CURRENT_TIMESTAMP;
+
+  RETURN json_build_object('networks', moved_networks, 'memberships', moved_memberships);
+END;
+$$;
@@ -0,0 +1,34 @@
+-- Virtual subnet per network.
+-- Every network used to be 10.10.0.0/24, which breaks for members whose local
+-- network uses the same range. The owner can now move a network to another /24;
+-- NULL keeps the old default.
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS subnet VARCHAR(18);
+
+COMMENT ON COLUMN networks.subnet IS 'Virtual /24 of the network, 10.10.0.0/24 when NULL';
+
+-- Moves a network to new_subnet. Members and reservations keep the last octet of
+-- their IP, so the change either renumbers everything or nothing.
+CREATE OR REPLACE FUNCTION change_network_subnet(target_network TEXT, new_subnet TEXT)
+RETURNS JSON
+LANGUAGE plpgsql
+AS $$
+DECLARE
+  prefix TEXT := regexp_replace(host(network(new_subnet::cidr)), '[0-9]+$', '');
+BEGIN
+  UPDATE networks SET subnet = new_subnet WHERE id = target_network;
+  IF NOT FOUND THEN
+    RETURN json_build_object('message', 'network does not exist');
+  END IF;
+
+  UPDATE computer_networks
+    SET peer_ip = prefix || split_part(peer_ip, '.', 4)
+    WHERE network_id = target_network AND peer_ip <> '';
+
+  UPDATE network_ip_reservations
+    SET peer_ip = prefix || split_part(peer_ip, '.', 4)
+    WHERE network_id = target_network;
+
+  RETURN json_build_object('subnet', new_subnet);
+END;
+$$;